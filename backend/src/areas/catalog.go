@@ -136,6 +136,10 @@ func areaFieldFromModel(f database.AreaField) Field {
 		Required:    f.Required,
 		Description: f.Description,
 		Example:     decodeAny(f.Example),
+		Enum:        decodeStringSlice(f.EnumValues),
+		Min:         f.MinValue,
+		Max:         f.MaxValue,
+		Pattern:     f.Pattern,
 	}
 }
 
@@ -0,0 +1,87 @@
+package areas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// FieldTypes are the field types clients know how to render.
+var FieldTypes = []string{"string", "number", "boolean", "object", "array<string>", "array<object>"}
+
+// Check verifies defs against what the server implements: every trigger needs one of
+// triggerTypes, every action route needs a reaction pointing at it and every reaction with a
+// local action_url needs its route. It also rejects duplicate ids, unknown field types, invalid
+// patterns and examples their own field would refuse.
+func Check(defs []Service, triggerTypes, actionRoutes []string) error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	services := map[string]bool{}
+	triggers := map[string]string{}
+	routed := map[string]bool{}
+	for _, svc := range defs {
+		if services[svc.ID] {
+			fail("service %s is defined twice", svc.ID)
+		}
+		services[svc.ID] = true
+
+		capabilities := map[string]bool{}
+		for _, c := range slices.Concat(svc.Triggers, svc.Reactions) {
+			if capabilities[c.ID] {
+				fail("capability %s/%s is defined twice", svc.ID, c.ID)
+			}
+			capabilities[c.ID] = true
+			keys := map[string]bool{}
+			for _, f := range c.Fields {
+				if keys[f.Key] {
+					fail("field %s/%s.%s is defined twice", svc.ID, c.ID, f.Key)
+				}
+				keys[f.Key] = true
+				if !slices.Contains(FieldTypes, f.Type) {
+					fail("field %s/%s.%s has unknown type %q", svc.ID, c.ID, f.Key, f.Type)
+				}
+				if _, err := regexp.Compile(f.Pattern); err != nil {
+					fail("field %s/%s.%s has an invalid pattern: %v", svc.ID, c.ID, f.Key, err)
+				}
+				if f.Example != nil {
+					example, _ := json.Marshal(map[string]any{f.Key: f.Example})
+					for _, invalid := range CheckFields([]Field{f}, example) {
+						fail("field %s/%s.%s example %s", svc.ID, c.ID, f.Key, invalid.Message)
+					}
+				}
+			}
+		}
+
+		for _, c := range svc.Triggers {
+			if other, ok := triggers[c.ID]; ok {
+				fail("trigger %s is defined by both %s and %s", c.ID, other, svc.ID)
+			}
+			triggers[c.ID] = svc.ID
+			if !slices.Contains(triggerTypes, c.ID) {
+				fail("trigger %s/%s has no implementation", svc.ID, c.ID)
+			}
+		}
+		for _, c := range svc.Reactions {
+			if !strings.HasPrefix(c.ActionURL, "/") {
+				continue
+			}
+			routed[c.ActionURL] = true
+			if !slices.Contains(actionRoutes, c.ActionURL) {
+				fail("reaction %s/%s points to unknown route %s", svc.ID, c.ID, c.ActionURL)
+			}
+		}
+	}
+
+	for _, route := range actionRoutes {
+		if !routed[route] {
+			fail("route %s has no catalog entry", route)
+		}
+	}
+	return errors.Join(errs...)
+}
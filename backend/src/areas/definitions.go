@@ -0,0 +1,573 @@
+package areas
+
+// Definitions is the catalog served by /about.json and /areas. Sync writes it to the database at
+// startup and Check verifies it against the triggers and reaction routes the server implements.
+var Definitions = []Service{
+	{
+		ID:      "core",
+		Name:    "Core",
+		Enabled: true,
+		Triggers: []Capability{
+			{
+				ID:          "manual",
+				Name:        "Manual trigger",
+				Description: "Trigger launched manually from the UI.",
+			},
+			{
+				ID:          "interval",
+				Name:        "Timer (interval)",
+				Description: "Runs every N minutes.",
+				Fields: []Field{
+					{Key: "interval_minutes", Type: "number", Required: true, Description: "Delay between runs in minutes", Example: 5, Min: bound(1)},
+				},
+			},
+			{
+				ID:          "reddit_new_post",
+				Name:        "Reddit new post",
+				Description: "Triggers when a new post appears in a subreddit.",
+				Fields: []Field{
+					{Key: "subreddit", Type: "string", Required: true, Description: "Subreddit name (without r/)", Example: "golang", Pattern: `^[A-Za-z0-9_]{2,21}$`},
+					{Key: "interval_minutes", Type: "number", Description: "Polling interval in minutes", Example: 5, Min: bound(1)},
+					{Key: "digest", Type: "object", Description: "Batch posts into a digest (every_minutes or cron)", Example: map[string]any{"every_minutes": 60}},
+				},
+			},
+			{
+				ID:          "youtube_new_video",
+				Name:        "YouTube new video",
+				Description: "Triggers when a channel publishes a new video.",
+				Fields: []Field{
+					{Key: "channel", Type: "string", Description: "YouTube channel name, handle, or ID", Example: "@GoogleDevelopers"},
+					{Key: "interval_minutes", Type: "number", Description: "Polling interval in minutes", Example: 5, Min: bound(1)},
+					{Key: "digest", Type: "object", Description: "Batch videos into a digest (every_minutes or cron)", Example: map[string]any{"cron": "0 18 * * *"}},
+				},
+			},
+		},
+		Reactions: []Capability{
+			{
+				ID:          "http_webhook",
+				Name:        "HTTP POST",
+				Description: "Send raw JSON to a custom HTTP endpoint.",
+				Fields: []Field{
+					{Key: "url", Type: "string", Required: true, Description: "Target URL", Example: "https://example.com/hook"},
+					{Key: "payload", Type: "object", Description: "JSON payload to send"},
+				},
+			},
+		},
+	},
+	{
+		ID:      "discord",
+		Name:    "Discord",
+		Enabled: true,
+		Reactions: []Capability{
+			{
+				ID:             "discord_message",
+				Name:           "Send message",
+				Description:    "Send a message to a channel using the bot.",
+				ActionURL:      "/actions/discord/message",
+				DefaultPayload: map[string]any{"content": "Hello from Area"},
+				Fields: []Field{
+					{Key: "channel_id", Type: "string", Required: true, Description: "Target channel ID", Example: "123456789012345678"},
+					{Key: "content", Type: "string", Required: true, Description: "Message content", Example: "Hello from Area"},
+					{Key: "bot_token", Type: "string", Required: true, Description: "Discord bot token"},
+				},
+			},
+			{
+				ID:             "discord_embed",
+				Name:           "Send embed",
+				Description:    "Send an embed to a channel.",
+				ActionURL:      "/actions/discord/embed",
+				DefaultPayload: map[string]any{"title": "Area update", "description": "Something happened"},
+				Fields: []Field{
+					{Key: "channel_id", Type: "string", Required: true, Description: "Target channel ID", Example: "123456789012345678"},
+					{Key: "title", Type: "string", Description: "Embed title"},
+					{Key: "description", Type: "string", Description: "Embed description"},
+					{Key: "url", Type: "string", Description: "Embed URL"},
+					{Key: "color", Type: "string", Description: "Hex color (e.g. #5865F2)", Pattern: `^#?[0-9A-Fa-f]{6}$`},
+					{Key: "content", Type: "string", Description: "Optional message content"},
+					{Key: "bot_token", Type: "string", Required: true, Description: "Discord bot token"},
+				},
+			},
+			{
+				ID:          "discord_edit_message",
+				Name:        "Edit message",
+				Description: "Edit a previously sent message.",
+				ActionURL:   "/actions/discord/message/edit",
+				Fields: []Field{
+					{Key: "channel_id", Type: "string", Required: true, Description: "Target channel ID"},
+					{Key: "message_id", Type: "string", Required: true, Description: "Message ID to edit"},
+					{Key: "content", Type: "string", Required: true, Description: "New message content"},
+					{Key: "bot_token", Type: "string", Required: true, Description: "Discord bot token"},
+				},
+			},
+			{
+				ID:          "discord_delete_message",
+				Name:        "Delete message",
+				Description: "Delete a message by ID.",
+				ActionURL:   "/actions/discord/message/delete",
+				Fields: []Field{
+					{Key: "channel_id", Type: "string", Required: true, Description: "Target channel ID"},
+					{Key: "message_id", Type: "string", Required: true, Description: "Message ID to delete"},
+					{Key: "bot_token", Type: "string", Required: true, Description: "Discord bot token"},
+				},
+			},
+			{
+				ID:          "discord_add_reaction",
+				Name:        "Add reaction",
+				Description: "Add a reaction emoji to a message.",
+				ActionURL:   "/actions/discord/message/react",
+				Fields: []Field{
+					{Key: "channel_id", Type: "string", Required: true, Description: "Target channel ID"},
+					{Key: "message_id", Type: "string", Required: true, Description: "Message ID to react to"},
+					{Key: "emoji", Type: "string", Required: true, Description: "Emoji (e.g. 😀 or name:id)"},
+					{Key: "bot_token", Type: "string", Required: true, Description: "Discord bot token"},
+				},
+			},
+		},
+	},
+	{
+		ID:      "google",
+		Name:    "Google",
+		Enabled: true,
+		OAuthScope: []string{
+			"https://www.googleapis.com/auth/gmail.send",
+			"https://www.googleapis.com/auth/calendar.events",
+			"https://www.googleapis.com/auth/userinfo.email",
+		},
+		Triggers: []Capability{
+			{
+				ID:          "gmail_inbound",
+				Name:        "When a Gmail is received",
+				Description: "Triggers on new unread messages in Gmail inbox.",
+				Fields: []Field{
+					{Key: "digest", Type: "object", Description: "Batch emails into a digest (every_minutes or cron)", Example: map[string]any{"every_minutes": 30}},
+				},
+			},
+		},
+		Reactions: []Capability{
+			{
+				ID:          "google_gmail_send",
+				Name:        "Send Gmail",
+				Description: "Send an email from the authenticated Google account.",
+				ActionURL:   "/actions/google/email",
+				Fields: []Field{
+					{Key: "token_id", Type: "number", Description: "Stored Google token id", Min: bound(1)},
+					{Key: "to", Type: "string", Required: true, Description: "Recipient email", Example: "dest@example.com", Pattern: `^[^@\s]+@[^@\s]+$`},
+					{Key: "subject", Type: "string", Required: true, Description: "Email subject", Example: "Hello"},
+					{Key: "body", Type: "string", Description: "Email body", Example: "Hello from Area"},
+				},
+			},
+			{
+				ID:          "google_calendar_event",
+				Name:        "Create Calendar event",
+				Description: "Create an event in the primary calendar.",
+				ActionURL:   "/actions/google/calendar",
+				Fields: []Field{
+					{Key: "token_id", Type: "number", Description: "Stored Google token id", Min: bound(1)},
+					{Key: "summary", Type: "string", Required: true, Description: "Event title", Example: "Area event"},
+					{Key: "start", Type: "string", Required: true, Description: "Start datetime (RFC3339)", Example: "2025-12-09T14:00:00Z"},
+					{Key: "end", Type: "string", Required: true, Description: "End datetime (RFC3339)", Example: "2025-12-09T15:00:00Z"},
+					{Key: "attendees", Type: "array<string>", Description: "Attendee emails", Example: []any{"a@example.com"}},
+				},
+			},
+		},
+	},
+	{
+		ID:      "github",
+		Name:    "GitHub",
+		Enabled: true,
+		Triggers: []Capability{
+			{
+				ID:          "github_commit",
+				Name:        "When a GitHub commit is pushed",
+				Description: "Triggers on new commits on a branch.",
+				Fields: []Field{
+					{Key: "token_id", Type: "number", Required: true, Description: "Stored GitHub token id", Min: bound(1)},
+					{Key: "repo", Type: "string", Required: true, Description: "Repository in owner/name format", Example: "owner/repo", Pattern: `^[\w.-]+/[\w.-]+$`},
+					{Key: "branch", Type: "string", Required: true, Description: "Branch to watch", Example: "main"},
+					{Key: "digest", Type: "object", Description: "Batch commits into a digest (every_minutes or cron)", Example: map[string]any{"cron": "0 9 * * 1-5"}},
+				},
+			},
+			{
+				ID:          "github_pull_request",
+				Name:        "When a GitHub pull request changes",
+				Description: "Triggers on PR updates (opened/closed/merged).",
+				Fields: []Field{
+					{Key: "token_id", Type: "number", Required: true, Description: "Stored GitHub token id", Min: bound(1)},
+					{Key: "repo", Type: "string", Required: true, Description: "Repository in owner/name format", Example: "owner/repo", Pattern: `^[\w.-]+/[\w.-]+$`},
+					{Key: "actions", Type: "array<string>", Description: "Actions to watch (opened,closed,merged)", Example: []any{"opened", "closed", "merged"}, Enum: []string{"opened", "closed", "merged"}},
+				},
+			},
+			{
+				ID:          "github_issue",
+				Name:        "When a GitHub issue changes",
+				Description: "Triggers on issue updates (opened/closed/reopened).",
+				Fields: []Field{
+					{Key: "token_id", Type: "number", Required: true, Description: "Stored GitHub token id", Min: bound(1)},
+					{Key: "repo", Type: "string", Required: true, Description: "Repository in owner/name format", Example: "owner/repo", Pattern: `^[\w.-]+/[\w.-]+$`},
+					{Key: "actions", Type: "array<string>", Description: "Actions to watch (opened,closed,reopened)", Example: []any{"opened", "closed"}, Enum: []string{"opened", "closed", "reopened"}},
+				},
+			},
+		},
+		Reactions: []Capability{
+			{
+				ID:          "github_create_issue",
+				Name:        "Create issue",
+				Description: "Create a new issue in a repository.",
+				ActionURL:   "/actions/github/issue",
+				Fields: []Field{
+					{Key: "token_id", Type: "number", Required: true, Description: "Stored GitHub token id", Min: bound(1)},
+					{Key: "repo", Type: "string", Required: true, Description: "Repository in owner/name format", Example: "owner/repo", Pattern: `^[\w.-]+/[\w.-]+$`},
+					{Key: "title", Type: "string", Required: true, Description: "Issue title"},
+					{Key: "body", Type: "string", Description: "Issue body"},
+					{Key: "labels", Type: "array<string>", Description: "Labels to add"},
+				},
+			},
+			{
+				ID:          "github_create_pull_request",
+				Name:        "Create pull request",
+				Description: "Create a pull request from a branch.",
+				ActionURL:   "/actions/github/pr",
+				Fields: []Field{
+					{Key: "token_id", Type: "number", Required: true, Description: "Stored GitHub token id", Min: bound(1)},
+					{Key: "repo", Type: "string", Required: true, Description: "Repository in owner/name format", Example: "owner/repo", Pattern: `^[\w.-]+/[\w.-]+$`},
+					{Key: "title", Type: "string", Required: true, Description: "Pull request title"},
+					{Key: "head", Type: "string", Required: true, Description: "Source branch (or owner:branch)", Example: "feature-branch"},
+					{Key: "base", Type: "string", Required: true, Description: "Base branch", Example: "main"},
+					{Key: "body", Type: "string", Description: "Pull request body"},
+				},
+			},
+		},
+	},
+	{
+		ID:      "slack",
+		Name:    "Slack",
+		Enabled: true,
+		Reactions: []Capability{
+			{
+				ID:             "slack_message",
+				Name:           "Send message",
+				Description:    "Send a message to a Slack channel.",
+				ActionURL:      "/actions/slack/message",
+				DefaultPayload: map[string]any{"text": "Hello from Area"},
+				Fields: []Field{
+					{Key: "channel_id", Type: "string", Required: true, Description: "Target channel ID", Example: "C1234567890"},
+					{Key: "text", Type: "string", Required: true, Description: "Message text", Example: "Hello from Area"},
+					{Key: "bot_token", Type: "string", Required: true, Description: "Slack bot token"},
+				},
+			},
+			{
+				ID:          "slack_blocks",
+				Name:        "Send blocks message",
+				Description: "Send a message with Block Kit payload.",
+				ActionURL:   "/actions/slack/blocks",
+				Fields: []Field{
+					{Key: "channel_id", Type: "string", Required: true, Description: "Target channel ID", Example: "C1234567890"},
+					{Key: "text", Type: "string", Description: "Fallback text"},
+					{Key: "blocks", Type: "array<object>", Required: true, Description: "Block Kit JSON array"},
+					{Key: "bot_token", Type: "string", Required: true, Description: "Slack bot token"},
+				},
+			},
+			{
+				ID:          "slack_update",
+				Name:        "Update message",
+				Description: "Update an existing message.",
+				ActionURL:   "/actions/slack/message/update",
+				Fields: []Field{
+					{Key: "channel_id", Type: "string", Required: true, Description: "Target channel ID", Example: "C1234567890"},
+					{Key: "message_ts", Type: "string", Required: true, Description: "Message timestamp"},
+					{Key: "text", Type: "string", Required: true, Description: "New message text"},
+					{Key: "bot_token", Type: "string", Required: true, Description: "Slack bot token"},
+				},
+			},
+			{
+				ID:          "slack_delete",
+				Name:        "Delete message",
+				Description: "Delete a message by timestamp.",
+				ActionURL:   "/actions/slack/message/delete",
+				Fields: []Field{
+					{Key: "channel_id", Type: "string", Required: true, Description: "Target channel ID", Example: "C1234567890"},
+					{Key: "message_ts", Type: "string", Required: true, Description: "Message timestamp"},
+					{Key: "bot_token", Type: "string", Required: true, Description: "Slack bot token"},
+				},
+			},
+			{
+				ID:          "slack_reaction",
+				Name:        "Add reaction",
+				Description: "Add an emoji reaction to a message.",
+				ActionURL:   "/actions/slack/message/react",
+				Fields: []Field{
+					{Key: "channel_id", Type: "string", Required: true, Description: "Target channel ID", Example: "C1234567890"},
+					{Key: "message_ts", Type: "string", Required: true, Description: "Message timestamp"},
+					{Key: "emoji", Type: "string", Required: true, Description: "Emoji"},
+					{Key: "bot_token", Type: "string", Required: true, Description: "Slack bot token"},
+				},
+			},
+		},
+	},
+	{
+		ID:      "notion",
+		Name:    "Notion",
+		Enabled: true,
+		Reactions: []Capability{
+			{
+				ID:          "notion_create_page",
+				Name:        "Create page",
+				Description: "Create a new Notion page.",
+				ActionURL:   "/actions/notion/page",
+				Fields: []Field{
+					{Key: "parent_page_id", Type: "string", Required: true, Description: "Parent page ID"},
+					{Key: "title", Type: "string", Required: true, Description: "Page title"},
+					{Key: "content", Type: "string", Description: "Page content"},
+					{Key: "blocks", Type: "array<object>", Description: "Optional blocks (JSON array)"},
+					{Key: "bot_token", Type: "string", Required: true, Description: "Notion token"},
+				},
+			},
+			{
+				ID:          "notion_append_blocks",
+				Name:        "Append blocks",
+				Description: "Append blocks to a page or block.",
+				ActionURL:   "/actions/notion/blocks",
+				Fields: []Field{
+					{Key: "block_id", Type: "string", Required: true, Description: "Block ID"},
+					{Key: "blocks", Type: "array<object>", Required: true, Description: "Blocks JSON array"},
+					{Key: "bot_token", Type: "string", Required: true, Description: "Notion token"},
+				},
+			},
+			{
+				ID:          "notion_create_database_row",
+				Name:        "Create database row",
+				Description: "Create a new page in a database.",
+				ActionURL:   "/actions/notion/database",
+				Fields: []Field{
+					{Key: "database_id", Type: "string", Required: true, Description: "Database ID"},
+					{Key: "properties", Type: "object", Required: true, Description: "Notion properties JSON object"},
+					{Key: "children", Type: "array<object>", Description: "Optional blocks (JSON array)"},
+					{Key: "bot_token", Type: "string", Required: true, Description: "Notion token"},
+				},
+			},
+			{
+				ID:          "notion_update_page",
+				Name:        "Update page",
+				Description: "Update page properties.",
+				ActionURL:   "/actions/notion/page/update",
+				Fields: []Field{
+					{Key: "page_id", Type: "string", Required: true, Description: "Page ID"},
+					{Key: "properties", Type: "object", Required: true, Description: "Notion properties JSON object"},
+					{Key: "bot_token", Type: "string", Required: true, Description: "Notion token"},
+				},
+			},
+		},
+	},
+	{
+		ID:      "weather",
+		Name:    "Weather",
+		Enabled: true,
+		Triggers: []Capability{
+			{
+				ID:          "weather_temp",
+				Name:        "When temperature crosses a threshold",
+				Description: "Triggers when current temperature crosses above/below a threshold.",
+				Fields: []Field{
+					{Key: "city", Type: "string", Required: true, Description: "City name (e.g. Paris)", Example: "Paris"},
+					{Key: "threshold", Type: "number", Required: true, Description: "Temperature threshold (°C)", Example: 20},
+					{Key: "direction", Type: "string", Required: true, Description: "above or below", Example: "above", Enum: []string{"above", "below"}},
+					{Key: "interval_minutes", Type: "number", Description: "Minimum polling interval in minutes", Example: 5, Min: bound(1)},
+				},
+			},
+			{
+				ID:          "weather_report",
+				Name:        "Weather report (interval)",
+				Description: "Sends current weather for a city every X minutes.",
+				Fields: []Field{
+					{Key: "city", Type: "string", Required: true, Description: "City name (e.g. Paris)", Example: "Paris"},
+					{Key: "interval_minutes", Type: "number", Required: true, Description: "Polling interval in minutes", Example: 10, Min: bound(1)},
+				},
+			},
+		},
+	},
+	{
+		ID:      "steam",
+		Name:    "Steam",
+		Enabled: true,
+		Triggers: []Capability{
+			{
+				ID:          "steam_player_online",
+				Name:        "Steam player online",
+				Description: "Triggers when a Steam user becomes online.",
+				Fields: []Field{
+					{Key: "steam_id", Type: "string", Required: true, Description: "SteamID64 of the user", Example: "76561198000000000", Pattern: `^\d{17}$`},
+					{Key: "interval_minutes", Type: "number", Description: "Polling interval in minutes", Example: 5, Min: bound(1)},
+				},
+			},
+			{
+				ID:          "steam_game_sale",
+				Name:        "Steam game on sale",
+				Description: "Triggers when a game goes on sale.",
+				Fields: []Field{
+					{Key: "app_id", Type: "number", Required: true, Description: "Steam app ID", Example: 570, Min: bound(1)},
+					{Key: "country", Type: "string", Description: "Country code for pricing (e.g. us, fr)", Example: "us", Pattern: `^[A-Za-z]{2}$`},
+					{Key: "interval_minutes", Type: "number", Description: "Polling interval in minutes", Example: 10, Min: bound(1)},
+				},
+			},
+			{
+				ID:          "steam_price_change",
+				Name:        "Steam price change",
+				Description: "Triggers when a game price changes.",
+				Fields: []Field{
+					{Key: "app_id", Type: "number", Required: true, Description: "Steam app ID", Example: 570, Min: bound(1)},
+					{Key: "country", Type: "string", Description: "Country code for pricing (e.g. us, fr)", Example: "us", Pattern: `^[A-Za-z]{2}$`},
+					{Key: "interval_minutes", Type: "number", Description: "Polling interval in minutes", Example: 10, Min: bound(1)},
+				},
+			},
+		},
+	},
+	{
+		ID:      "crypto",
+		Name:    "Crypto",
+		Enabled: true,
+		Triggers: []Capability{
+			{
+				ID:          "crypto_price_threshold",
+				Name:        "Crypto price threshold",
+				Description: "Triggers when a crypto price crosses a threshold.",
+				Fields: []Field{
+					{Key: "coin_id", Type: "string", Required: true, Description: "Coin id (CoinGecko, e.g. bitcoin)", Example: "bitcoin"},
+					{Key: "currency", Type: "string", Description: "Currency (e.g. usd, eur)", Example: "usd"},
+					{Key: "threshold", Type: "number", Required: true, Description: "Price threshold", Example: 50000},
+					{Key: "direction", Type: "string", Required: true, Description: "above or below", Example: "above", Enum: []string{"above", "below"}},
+					{Key: "interval_minutes", Type: "number", Description: "Polling interval in minutes", Example: 5, Min: bound(1)},
+				},
+			},
+			{
+				ID:          "crypto_percent_change",
+				Name:        "Crypto percent change",
+				Description: "Triggers when a crypto changes by a % over 1h or 24h.",
+				Fields: []Field{
+					{Key: "coin_id", Type: "string", Required: true, Description: "Coin id (CoinGecko, e.g. bitcoin)", Example: "bitcoin"},
+					{Key: "currency", Type: "string", Description: "Currency (e.g. usd, eur)", Example: "usd"},
+					{Key: "percent", Type: "number", Required: true, Description: "Percent change threshold", Example: 5},
+					{Key: "period", Type: "string", Required: true, Description: "1h or 24h", Example: "1h", Enum: []string{"1h", "24h"}},
+					{Key: "direction", Type: "string", Description: "above, below, or any", Example: "any", Enum: []string{"any", "above", "below"}},
+					{Key: "interval_minutes", Type: "number", Description: "Polling interval in minutes", Example: 5, Min: bound(1)},
+				},
+			},
+		},
+	},
+	{
+		ID:      "nasa",
+		Name:    "NASA",
+		Enabled: true,
+		Triggers: []Capability{
+			{
+				ID:          "nasa_apod",
+				Name:        "NASA APOD",
+				Description: "Triggers when the Astronomy Picture of the Day updates.",
+				Fields: []Field{
+					{Key: "interval_minutes", Type: "number", Description: "Polling interval in minutes", Example: 60, Min: bound(1)},
+				},
+			},
+			{
+				ID:          "nasa_mars_photo",
+				Name:        "NASA Mars rover photo",
+				Description: "Triggers on latest Mars rover photos.",
+				Fields: []Field{
+					{Key: "rover", Type: "string", Required: true, Description: "Rover name (curiosity, perseverance, opportunity, spirit)", Example: "curiosity", Enum: []string{"curiosity", "perseverance", "opportunity", "spirit"}},
+					{Key: "camera", Type: "string", Description: "Camera name (e.g. FHAZ, RHAZ, NAVCAM)", Example: "FHAZ"},
+					{Key: "interval_minutes", Type: "number", Description: "Polling interval in minutes", Example: 60, Min: bound(1)},
+				},
+			},
+			{
+				ID:          "nasa_neo_close_approach",
+				Name:        "NASA NEO close approach",
+				Description: "Triggers when a near-earth object passes within a distance.",
+				Fields: []Field{
+					{Key: "threshold_km", Type: "number", Required: true, Description: "Distance threshold in km", Example: 500000, Min: bound(1)},
+					{Key: "days_ahead", Type: "number", Description: "Number of days to look ahead", Example: 1, Min: bound(1), Max: bound(7)},
+					{Key: "interval_minutes", Type: "number", Description: "Polling interval in minutes", Example: 60, Min: bound(1)},
+				},
+			},
+		},
+	},
+	{
+		ID:      "air_quality",
+		Name:    "Air Quality",
+		Enabled: true,
+		Triggers: []Capability{
+			{
+				ID:          "air_quality_aqi_threshold",
+				Name:        "Air Quality AQI threshold",
+				Description: "Triggers when AQI crosses a threshold.",
+				Fields: []Field{
+					{Key: "city", Type: "string", Required: true, Description: "City name (e.g. Paris)", Example: "Paris"},
+					{Key: "index", Type: "string", Description: "AQI index (us_aqi or european_aqi)", Example: "us_aqi", Enum: []string{"us_aqi", "european_aqi"}},
+					{Key: "threshold", Type: "number", Required: true, Description: "AQI threshold", Example: 100},
+					{Key: "direction", Type: "string", Required: true, Description: "above or below", Example: "above", Enum: []string{"above", "below"}},
+					{Key: "interval_minutes", Type: "number", Description: "Polling interval in minutes", Example: 10, Min: bound(1)},
+				},
+			},
+			{
+				ID:          "air_quality_pm25_threshold",
+				Name:        "Air Quality PM2.5 threshold",
+				Description: "Triggers when PM2.5 crosses a threshold.",
+				Fields: []Field{
+					{Key: "city", Type: "string", Required: true, Description: "City name (e.g. Paris)", Example: "Paris"},
+					{Key: "threshold", Type: "number", Required: true, Description: "PM2.5 threshold (µg/m³)", Example: 15},
+					{Key: "direction", Type: "string", Required: true, Description: "above or below", Example: "above", Enum: []string{"above", "below"}},
+					{Key: "interval_minutes", Type: "number", Description: "Polling interval in minutes", Example: 10, Min: bound(1)},
+				},
+			},
+		},
+	},
+	{
+		ID:      "trello",
+		Name:    "Trello",
+		Enabled: true,
+		Reactions: []Capability{
+			{
+				ID:          "trello_create_card",
+				Name:        "Create card",
+				Description: "Create a Trello card in a list.",
+				ActionURL:   "/actions/trello/card",
+				Fields: []Field{
+					{Key: "api_key", Type: "string", Description: "Trello API key"},
+					{Key: "token", Type: "string", Description: "Trello user token"},
+					{Key: "list_id", Type: "string", Required: true, Description: "Target list ID"},
+					{Key: "name", Type: "string", Required: true, Description: "Card name"},
+					{Key: "desc", Type: "string", Description: "Card description"},
+					{Key: "pos", Type: "string", Description: "Card position (top, bottom, or numeric)", Example: "bottom", Pattern: `^(top|bottom|\d+(\.\d+)?)$`},
+				},
+			},
+			{
+				ID:          "trello_move_card",
+				Name:        "Move card",
+				Description: "Move a Trello card to another list.",
+				ActionURL:   "/actions/trello/card/move",
+				Fields: []Field{
+					{Key: "api_key", Type: "string", Description: "Trello API key"},
+					{Key: "token", Type: "string", Description: "Trello user token"},
+					{Key: "card_id", Type: "string", Required: true, Description: "Card ID to move"},
+					{Key: "list_id", Type: "string", Required: true, Description: "Destination list ID"},
+					{Key: "pos", Type: "string", Description: "Card position (top, bottom, or numeric)", Example: "bottom", Pattern: `^(top|bottom|\d+(\.\d+)?)$`},
+				},
+			},
+			{
+				ID:          "trello_create_list",
+				Name:        "Create list",
+				Description: "Create a Trello list on a board.",
+				ActionURL:   "/actions/trello/list",
+				Fields: []Field{
+					{Key: "api_key", Type: "string", Description: "Trello API key"},
+					{Key: "token", Type: "string", Description: "Trello user token"},
+					{Key: "board_id", Type: "string", Required: true, Description: "Board ID"},
+					{Key: "name", Type: "string", Required: true, Description: "List name"},
+					{Key: "pos", Type: "string", Description: "List position (top, bottom, or numeric)", Example: "bottom", Pattern: `^(top|bottom|\d+(\.\d+)?)$`},
+				},
+			},
+		},
+	},
+}
+
+// bound returns a pointer to v, for the Min and Max of a field.
+func bound(v float64) *float64 {
+	return &v
+}
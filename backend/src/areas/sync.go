@@ -0,0 +1,146 @@
+package areas
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"area/src/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sync upserts defs into the catalog tables and deletes the services, capabilities and fields
+// they no longer define, in one transaction.
+func Sync(ctx context.Context, db *gorm.DB, defs []Service) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		serviceIDs := make([]string, 0, len(defs))
+		for _, svc := range defs {
+			serviceIDs = append(serviceIDs, svc.ID)
+			if err := syncService(tx, svc); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("id NOT IN ?", serviceIDs).Delete(&database.AreaService{}).Error; err != nil {
+			return fmt.Errorf("delete stale services: %w", err)
+		}
+		return nil
+	})
+}
+
+func syncService(tx *gorm.DB, svc Service) error {
+	scopes, err := encodeJSON(svc.OAuthScope)
+	if err != nil {
+		return fmt.Errorf("service %s: %w", svc.ID, err)
+	}
+	model := database.AreaService{
+		ID:          svc.ID,
+		Name:        svc.Name,
+		Enabled:     svc.Enabled,
+		MoreInfo:    svc.MoreInfo,
+		OAuthScopes: scopes,
+	}
+	err = tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "enabled", "more_info", "oauth_scopes", "updated_at"}),
+	}).Create(&model).Error
+	if err != nil {
+		return fmt.Errorf("upsert service %s: %w", svc.ID, err)
+	}
+
+	var capIDs []string
+	for _, c := range svc.Triggers {
+		capIDs = append(capIDs, c.ID)
+		if err := syncCapability(tx, svc.ID, "trigger", c); err != nil {
+			return err
+		}
+	}
+	for _, c := range svc.Reactions {
+		capIDs = append(capIDs, c.ID)
+		if err := syncCapability(tx, svc.ID, "reaction", c); err != nil {
+			return err
+		}
+	}
+	stale := tx.Where("service_id = ?", svc.ID)
+	if len(capIDs) > 0 {
+		stale = stale.Where("id NOT IN ?", capIDs)
+	}
+	if err := stale.Delete(&database.AreaCapability{}).Error; err != nil {
+		return fmt.Errorf("delete stale capabilities of %s: %w", svc.ID, err)
+	}
+	return nil
+}
+
+func syncCapability(tx *gorm.DB, serviceID, kind string, c Capability) error {
+	payload, err := encodeJSON(c.DefaultPayload)
+	if err != nil {
+		return fmt.Errorf("capability %s/%s: %w", serviceID, c.ID, err)
+	}
+	model := database.AreaCapability{
+		ID:             c.ID,
+		ServiceID:      serviceID,
+		Kind:           kind,
+		Name:           c.Name,
+		Description:    c.Description,
+		ActionURL:      c.ActionURL,
+		DefaultPayload: payload,
+	}
+	err = tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "service_id"}, {Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"kind", "name", "description", "action_url", "default_payload", "updated_at"}),
+	}).Create(&model).Error
+	if err != nil {
+		return fmt.Errorf("upsert capability %s/%s: %w", serviceID, c.ID, err)
+	}
+
+	keys := make([]string, 0, len(c.Fields))
+	for _, f := range c.Fields {
+		keys = append(keys, f.Key)
+		example, err := encodeJSON(f.Example)
+		if err != nil {
+			return fmt.Errorf("field %s/%s.%s: %w", serviceID, c.ID, f.Key, err)
+		}
+		enum, err := encodeJSON(f.Enum)
+		if err != nil {
+			return fmt.Errorf("field %s/%s.%s: %w", serviceID, c.ID, f.Key, err)
+		}
+		field := database.AreaField{
+			ServiceID:    serviceID,
+			CapabilityID: c.ID,
+			Key:          f.Key,
+			Type:         f.Type,
+			Required:     f.Required,
+			Description:  f.Description,
+			Example:      example,
+			EnumValues:   enum,
+			MinValue:     f.Min,
+			MaxValue:     f.Max,
+			Pattern:      f.Pattern,
+		}
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "service_id"}, {Name: "capability_id"}, {Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"type", "required", "description", "example", "enum_values", "min_value", "max_value", "pattern", "updated_at"}),
+		}).Create(&field).Error
+		if err != nil {
+			return fmt.Errorf("upsert field %s/%s.%s: %w", serviceID, c.ID, f.Key, err)
+		}
+	}
+	stale := tx.Where("service_id = ? AND capability_id = ?", serviceID, c.ID)
+	if len(keys) > 0 {
+		stale = stale.Where("key NOT IN ?", keys)
+	}
+	if err := stale.Delete(&database.AreaField{}).Error; err != nil {
+		return fmt.Errorf("delete stale fields of %s/%s: %w", serviceID, c.ID, err)
+	}
+	return nil
+}
+
+// encodeJSON marshals v for a jsonb column, keeping NULL for nil values.
+func encodeJSON[T any](v T) (json.RawMessage, error) {
+	raw, err := json.Marshal(v)
+	if err != nil || string(raw) == "null" {
+		return nil, err
+	}
+	return raw, nil
+}
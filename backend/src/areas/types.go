@@ -1,12 +1,17 @@
 package areas
 
-// Field describes an input required to use a trigger or reaction.
+// Field describes an input required to use a trigger or reaction. Enum, Min, Max and Pattern
+// constrain its value, see CheckFields.
 type Field struct {
 	Key         string      `json:"key"`
 	Type        string      `json:"type"`
 	Required    bool        `json:"required"`
 	Description string      `json:"description,omitempty"`
 	Example     interface{} `json:"example,omitempty"`
+	Enum        []string    `json:"enum,omitempty"`
+	Min         *float64    `json:"min,omitempty"`
+	Max         *float64    `json:"max,omitempty"`
+	Pattern     string      `json:"pattern,omitempty"`
 }
 
 // Capability represents either a trigger or a reaction.
@@ -0,0 +1,151 @@
+package areas
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// FieldError tells why the value of one field is invalid. Field is empty when the whole
+// document is.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a trigger config or reaction payload.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		if f.Field == "" {
+			parts = append(parts, f.Message)
+		} else {
+			parts = append(parts, f.Field+" "+f.Message)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// FindTrigger returns the catalog trigger with the given id.
+func FindTrigger(id string) (*Capability, bool) {
+	for i := range Definitions {
+		for j := range Definitions[i].Triggers {
+			if Definitions[i].Triggers[j].ID == id {
+				return &Definitions[i].Triggers[j], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// FindReaction returns the catalog reaction served at the given action route.
+func FindReaction(actionURL string) (*Capability, bool) {
+	for i := range Definitions {
+		for j := range Definitions[i].Reactions {
+			if Definitions[i].Reactions[j].ActionURL == actionURL {
+				return &Definitions[i].Reactions[j], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// CheckFields validates raw, a JSON object, against fields and returns one error per invalid
+// field. Keys the fields do not describe are left alone; an empty raw is an empty object.
+func CheckFields(fields []Field, raw json.RawMessage) []FieldError {
+	values := map[string]any{}
+	if len(strings.TrimSpace(string(raw))) > 0 {
+		if err := json.Unmarshal(raw, &values); err != nil || values == nil {
+			return []FieldError{{Message: "must be a JSON object"}}
+		}
+	}
+	var errs []FieldError
+	for _, f := range fields {
+		if msg := checkField(f, values[f.Key]); msg != "" {
+			errs = append(errs, FieldError{Field: f.Key, Message: msg})
+		}
+	}
+	return errs
+}
+
+// checkField returns why value does not fit f, or "" when it does.
+func checkField(f Field, value any) string {
+	if s, ok := value.(string); ok && strings.TrimSpace(s) == "" {
+		value = nil
+	}
+	if value == nil {
+		if f.Required {
+			return "is required"
+		}
+		return ""
+	}
+	switch f.Type {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return "must be a string"
+		}
+		return checkString(f, s)
+	case "number":
+		n, ok := value.(float64)
+		if !ok {
+			return "must be a number"
+		}
+		if f.Min != nil && n < *f.Min {
+			return fmt.Sprintf("must be at least %g", *f.Min)
+		}
+		if f.Max != nil && n > *f.Max {
+			return fmt.Sprintf("must be at most %g", *f.Max)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return "must be true or false"
+		}
+	case "object":
+		if _, ok := value.(map[string]any); !ok {
+			return "must be an object"
+		}
+	case "array<string>", "array<object>":
+		items, ok := value.([]any)
+		if !ok {
+			return "must be an array"
+		}
+		for i, item := range items {
+			if f.Type == "array<object>" {
+				if _, ok := item.(map[string]any); !ok {
+					return fmt.Sprintf("item %d must be an object", i)
+				}
+				continue
+			}
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Sprintf("item %d must be a string", i)
+			}
+			if msg := checkString(f, s); msg != "" {
+				return fmt.Sprintf("item %d %s", i, msg)
+			}
+		}
+	}
+	return ""
+}
+
+// checkString applies the Enum and Pattern of f to s; enum values match case-insensitively.
+func checkString(f Field, s string) string {
+	matches := func(allowed string) bool { return strings.EqualFold(strings.TrimSpace(s), allowed) }
+	if len(f.Enum) > 0 && !slices.ContainsFunc(f.Enum, matches) {
+		return "must be one of " + strings.Join(f.Enum, ", ")
+	}
+	if f.Pattern != "" {
+		re, err := regexp.Compile(f.Pattern)
+		if err != nil || !re.MatchString(s) {
+			return "must match " + f.Pattern
+		}
+	}
+	return ""
+}
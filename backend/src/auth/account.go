@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"area/src/mail"
+)
+
+const (
+	PasswordResetTTL     = time.Hour
+	EmailVerificationTTL = 48 * time.Hour
+
+	purposePasswordReset     = "password_reset"
+	purposeEmailVerification = "email_verification"
+	accountTokenPrefix       = "acc_"
+
+	// passwordResetMailTimeout bounds the background lookup and email of ForgotPassword.
+	passwordResetMailTimeout = time.Minute
+)
+
+var ErrNoAccountStore = errors.New("account recovery is not configured")
+
+// AccountStore persists single-use account tokens by hash, and the account changes they allow.
+type AccountStore interface {
+	CreateAccountToken(ctx context.Context, userID int64, purpose, hash string, expiresAt time.Time) error
+	// ConsumeAccountToken marks the unused, unexpired token used and returns its user, or
+	// ErrInvalidToken.
+	ConsumeAccountToken(ctx context.Context, purpose, hash string, now time.Time) (int64, error)
+	SetPassword(ctx context.Context, userID int64, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, userID int64, at time.Time) error
+	UserByID(ctx context.Context, id int64) (*User, error)
+}
+
+// ForgotPassword mails a password reset link to the user with the given email, asked from the
+// given IP address. The request counts against the email and the address, and is refused with a
+// *PasswordResetLimitedError past their limits. The user is then looked up and mailed in the
+// background, so the answer takes as long and does not depend on the mail server whether or not
+// the account exists. Wait blocks until those emails are out.
+func (s *Service) ForgotPassword(ctx context.Context, email, ip string) error {
+	if s.Accounts == nil || s.Mailer == nil {
+		return ErrNoAccountStore
+	}
+	if err := s.passwordResetRequested(ctx, email, ip); err != nil {
+		return err
+	}
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), passwordResetMailTimeout)
+		defer cancel()
+		if err := s.mailPasswordReset(ctx, email); err != nil {
+			slog.ErrorContext(ctx, "send password reset", "error", err)
+		}
+	}()
+	return nil
+}
+
+// mailPasswordReset mails a password reset link to the user with the given email, if any.
+func (s *Service) mailPasswordReset(ctx context.Context, email string) error {
+	user, _, err := s.store.GetByEmail(email)
+	switch {
+	case errors.Is(err, ErrInvalidCredentials), err == nil && user == nil:
+		return nil
+	case err != nil:
+		return err
+	}
+	link, err := s.mailToken(ctx, user.ID, purposePasswordReset, PasswordResetTTL, "/reset-password")
+	if err != nil {
+		return err
+	}
+	return s.Mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your KiKonect password",
+		Body: fmt.Sprintf("Hello %s,\n\nFollow this link within %s to choose a new password:\n\n%s\n\n"+
+			"If you did not ask for a new password, ignore this email.\n", user.FirstName, PasswordResetTTL, link),
+	})
+}
+
+// Wait blocks until the emails ForgotPassword sends in the background are out, or ctx is done.
+func (s *Service) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword and ends the sessions of
+// the user. The link proves the user reads that mailbox, so it also verifies their email.
+func (s *Service) ResetPassword(ctx context.Context, token, password string) error {
+	if s.Accounts == nil {
+		return ErrNoAccountStore
+	}
+	now := s.now()
+	userID, err := s.Accounts.ConsumeAccountToken(ctx, purposePasswordReset, HashToken(token), now)
+	if err != nil {
+		return err
+	}
+	hashed, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.Accounts.SetPassword(ctx, userID, hashed); err != nil {
+		return err
+	}
+	if err := s.Accounts.MarkEmailVerified(ctx, userID, now); err != nil {
+		return err
+	}
+	if s.Sessions != nil {
+		return s.Sessions.RevokeUserSessions(ctx, userID, now)
+	}
+	return nil
+}
+
+// SendEmailVerification mails a link verifying the email address of user.
+func (s *Service) SendEmailVerification(ctx context.Context, user *User) error {
+	if s.Accounts == nil || s.Mailer == nil {
+		return ErrNoAccountStore
+	}
+	link, err := s.mailToken(ctx, user.ID, purposeEmailVerification, EmailVerificationTTL, "/verify-email")
+	if err != nil {
+		return err
+	}
+	return s.Mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your KiKonect email address",
+		Body: fmt.Sprintf("Hello %s,\n\nFollow this link within %s to verify your email address:\n\n%s\n",
+			user.FirstName, EmailVerificationTTL, link),
+	})
+}
+
+// ResendEmailVerification mails a new verification link unless the user is already verified.
+func (s *Service) ResendEmailVerification(ctx context.Context, userID int64) error {
+	if s.Accounts == nil {
+		return ErrNoAccountStore
+	}
+	user, err := s.Accounts.UserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return nil
+	}
+	return s.SendEmailVerification(ctx, user)
+}
+
+// VerifyEmail marks the email of a user verified with a token from SendEmailVerification.
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	if s.Accounts == nil {
+		return ErrNoAccountStore
+	}
+	now := s.now()
+	userID, err := s.Accounts.ConsumeAccountToken(ctx, purposeEmailVerification, HashToken(token), now)
+	if err != nil {
+		return err
+	}
+	return s.Accounts.MarkEmailVerified(ctx, userID, now)
+}
+
+// EmailVerified reports whether a user has verified their email address.
+func (s *Service) EmailVerified(ctx context.Context, userID int64) (bool, error) {
+	if s.Accounts == nil {
+		return false, ErrNoAccountStore
+	}
+	user, err := s.Accounts.UserByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.EmailVerified, nil
+}
+
+// mailToken stores a new account token and returns the link of the web app page using it.
+func (s *Service) mailToken(ctx context.Context, userID int64, purpose string, ttl time.Duration, page string) (string, error) {
+	token, err := newToken(accountTokenPrefix)
+	if err != nil {
+		return "", err
+	}
+	if err := s.Accounts.CreateAccountToken(ctx, userID, purpose, HashToken(token), s.now().Add(ttl)); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(s.AppURL, "/") + page + "?token=" + url.QueryEscape(token), nil
+}
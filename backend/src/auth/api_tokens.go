@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scopes of personal access tokens. Sessions are not scoped.
+const (
+	ScopeWorkflowsRead    = "workflows:read"
+	ScopeWorkflowsWrite   = "workflows:write"
+	ScopeWorkflowsTrigger = "workflows:trigger"
+
+	// APITokenPrefix starts every personal access token, telling them apart from session tokens.
+	APITokenPrefix = "pat_"
+
+	maxAPITokenName = 100
+	// lastUsedResolution bounds how often verifying a token writes its last use.
+	lastUsedResolution = time.Minute
+)
+
+// Scopes lists every scope a personal access token may hold.
+var Scopes = []string{ScopeWorkflowsRead, ScopeWorkflowsWrite, ScopeWorkflowsTrigger}
+
+var (
+	ErrAPITokenNotFound = errors.New("api token not found")
+	ErrNoAPITokenStore  = errors.New("api tokens are not configured")
+)
+
+// APIToken is a long-lived credential of a user for scripts and CI. The token itself is only
+// returned when it is created.
+type APIToken struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Allows tells whether the token holds scope.
+func (t *APIToken) Allows(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// APITokenStore persists personal access tokens by hash. Lookups of unknown hashes return
+// ErrInvalidToken; deleting a token the user does not own returns ErrAPITokenNotFound.
+type APITokenStore interface {
+	CreateAPIToken(ctx context.Context, token *APIToken, hash string) error
+	ListAPITokens(ctx context.Context, userID int64) ([]APIToken, error)
+	APITokenByHash(ctx context.Context, hash string) (*APIToken, error)
+	TouchAPIToken(ctx context.Context, id int64, at time.Time) error
+	DeleteAPIToken(ctx context.Context, userID, id int64) error
+}
+
+// ValidationError reports an invalid field of a request.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// CreateAPIToken issues a personal access token with the given scopes, expiring at expiresAt
+// unless it is nil. It returns the token, shown only this once, and its stored description.
+func (s *Service) CreateAPIToken(ctx context.Context, userID int64, name string, scopes []string, expiresAt *time.Time) (string, *APIToken, error) {
+	if s.APITokens == nil {
+		return "", nil, ErrNoAPITokenStore
+	}
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return "", nil, &ValidationError{Field: "name", Message: "is required"}
+	case len(name) > maxAPITokenName:
+		return "", nil, &ValidationError{Field: "name", Message: fmt.Sprintf("must be at most %d characters", maxAPITokenName)}
+	case len(scopes) == 0:
+		return "", nil, &ValidationError{Field: "scopes", Message: "must not be empty"}
+	}
+	var granted []string
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return "", nil, &ValidationError{Field: "scopes", Message: fmt.Sprintf("must be among %s", strings.Join(Scopes, ", "))}
+		}
+		if !slices.Contains(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	now := s.now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return "", nil, &ValidationError{Field: "expires_at", Message: "must be in the future"}
+	}
+	secret, err := newToken(APITokenPrefix)
+	if err != nil {
+		return "", nil, err
+	}
+	token := &APIToken{UserID: userID, Name: name, Scopes: granted, ExpiresAt: expiresAt, CreatedAt: now}
+	if err := s.APITokens.CreateAPIToken(ctx, token, HashToken(secret)); err != nil {
+		return "", nil, err
+	}
+	return secret, token, nil
+}
+
+// ListAPITokens returns the personal access tokens of a user.
+func (s *Service) ListAPITokens(ctx context.Context, userID int64) ([]APIToken, error) {
+	if s.APITokens == nil {
+		return nil, ErrNoAPITokenStore
+	}
+	return s.APITokens.ListAPITokens(ctx, userID)
+}
+
+// DeleteAPIToken revokes a personal access token of a user.
+func (s *Service) DeleteAPIToken(ctx context.Context, userID, id int64) error {
+	if s.APITokens == nil {
+		return ErrNoAPITokenStore
+	}
+	return s.APITokens.DeleteAPIToken(ctx, userID, id)
+}
+
+// VerifyAPIToken returns the unexpired personal access token matching token and records its use.
+func (s *Service) VerifyAPIToken(ctx context.Context, token string) (*APIToken, error) {
+	if s.APITokens == nil {
+		return nil, ErrNoAPITokenStore
+	}
+	stored, err := s.APITokens.APITokenByHash(ctx, HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if stored.ExpiresAt != nil && !now.Before(*stored.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	if stored.LastUsedAt == nil || now.Sub(*stored.LastUsedAt) >= lastUsedResolution {
+		if err := s.APITokens.TouchAPIToken(ctx, stored.ID, now); err != nil {
+			return nil, err
+		}
+		stored.LastUsedAt = &now
+	}
+	return stored, nil
+}
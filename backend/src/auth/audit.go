@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// maxUserAgent bounds the user agent recorded with an event, in bytes.
+const maxUserAgent = 512
+
+var ErrNoAuditStore = errors.New("the audit log is not configured")
+
+// AuditFilter selects audit events. Zero fields match every event; an Action ending in "."
+// matches every action it prefixes, such as "workflow.". BeforeID pages back through the log.
+type AuditFilter struct {
+	ActorID     int64
+	WorkspaceID int64
+	Action      string
+	Target      string
+	Since       time.Time
+	Until       time.Time
+	BeforeID    int64
+	Limit       int
+}
+
+// AuditStore persists the append-only audit log. It is the workspace audit log of WorkspaceStore
+// grown to every user: the events of workspaces are among those ListAuditEvents reads.
+type AuditStore interface {
+	CreateAuditEvent(ctx context.Context, event *AuditEvent) error
+	ListAuditEvents(ctx context.Context, filter AuditFilter) ([]AuditEvent, error)
+}
+
+type clientKey struct{}
+
+// client is the origin of a request, as recorded in the audit log.
+type client struct {
+	ip        string
+	userAgent string
+}
+
+// WithClient returns a copy of ctx carrying the address and user agent of the request it serves,
+// for the events recorded while serving it.
+func WithClient(ctx context.Context, ip, userAgent string) context.Context {
+	if len(userAgent) > maxUserAgent {
+		userAgent = userAgent[:maxUserAgent]
+	}
+	return context.WithValue(ctx, clientKey{}, client{ip: ip, userAgent: userAgent})
+}
+
+func clientFromContext(ctx context.Context) client {
+	c, _ := ctx.Value(clientKey{}).(client)
+	return c
+}
+
+// AuditEvents returns the latest events matching filter, newest first.
+func (s *Service) AuditEvents(ctx context.Context, filter AuditFilter) ([]AuditEvent, error) {
+	if s.AuditLog == nil {
+		return nil, ErrNoAuditStore
+	}
+	if filter.Limit <= 0 || filter.Limit > auditPageSize {
+		filter.Limit = auditPageSize
+	}
+	return s.AuditLog.ListAuditEvents(ctx, filter)
+}
+
+// UserAudit returns the latest events userID is the actor of, newest first.
+func (s *Service) UserAudit(ctx context.Context, userID int64, filter AuditFilter) ([]AuditEvent, error) {
+	filter.ActorID = userID
+	return s.AuditEvents(ctx, filter)
+}
+
+// RecordChange appends a change by actorID to the audit log. A zero workspaceID records a change
+// outside any workspace. before and after are stored as JSON; nil ones are left out.
+func (s *Service) RecordChange(ctx context.Context, workspaceID, actorID int64, action, target, detail string, before, after any) error {
+	event := &AuditEvent{ActorID: &actorID, Action: action, Target: target, Detail: detail}
+	if workspaceID != 0 {
+		event.WorkspaceID = &workspaceID
+	}
+	var err error
+	if event.Before, err = auditState(before); err != nil {
+		return err
+	}
+	if event.After, err = auditState(after); err != nil {
+		return err
+	}
+	return s.RecordAuditEvent(ctx, event)
+}
+
+// RecordAuditEvent appends an event to the audit log, stamped with the current time and, unless
+// already set, the client of the request ctx belongs to.
+func (s *Service) RecordAuditEvent(ctx context.Context, event *AuditEvent) error {
+	if s.AuditLog == nil {
+		return ErrNoAuditStore
+	}
+	s.stampEvent(ctx, event)
+	return s.AuditLog.CreateAuditEvent(ctx, event)
+}
+
+// stampEvent sets the time of an event and, unless already set, the client of the request ctx
+// belongs to.
+func (s *Service) stampEvent(ctx context.Context, event *AuditEvent) {
+	c := clientFromContext(ctx)
+	if event.IP == "" {
+		event.IP = c.ip
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = c.userAgent
+	}
+	event.CreatedAt = s.now()
+}
+
+// audit records an event when the audit log is configured. What it describes has already
+// happened, so a failure to record it is logged rather than failing the request.
+func (s *Service) audit(ctx context.Context, event *AuditEvent) {
+	if s.AuditLog == nil {
+		return
+	}
+	if err := s.RecordAuditEvent(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "record audit event", "error", err, "action", event.Action)
+	}
+}
+
+// auditUser records an event of a user about their own account.
+func (s *Service) auditUser(ctx context.Context, userID int64, action, target, detail string) {
+	s.audit(ctx, &AuditEvent{ActorID: &userID, Action: action, Target: target, Detail: detail})
+}
+
+func auditState(state any) (json.RawMessage, error) {
+	if state == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("encode audit state: %w", err)
+	}
+	return encoded, nil
+}
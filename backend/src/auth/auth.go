@@ -2,24 +2,29 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"os"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"area/src/config"
+	"area/src/mail"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
 type User struct {
-	ID        int64  `json:"id"`
-	Email     string `json:"email"`
-	FirstName string `json:"firstname"`
-	LastName  string `json:"lastname"`
+	ID            int64  `json:"id"`
+	Email         string `json:"email"`
+	FirstName     string `json:"firstname"`
+	LastName      string `json:"lastname"`
+	EmailVerified bool   `json:"email_verified"`
 }
 
 var (
@@ -29,17 +34,34 @@ var (
 
 const oauthGoogleUrlAPI = "https://www.googleapis.com/oauth2/v2/userinfo?access_token="
 
-var bcryptCost int
+var bcryptCost = bcrypt.DefaultCost
 
-// init configures the bcrypt cost from environment variables.
-func init() {
-	costStr := os.Getenv("BCRYPT_COST")
-	cost, err := strconv.Atoi(costStr)
-	if err != nil {
-		bcryptCost = bcrypt.DefaultCost
-	} else {
-		bcryptCost = cost
+// SetBcryptCost sets the cost of new password hashes.
+func SetBcryptCost(cost int) {
+	bcryptCost = cost
+}
+
+// dummyHash is a hash of the current cost that no password matches, compared against when
+// there is no user so that the lookup costs as much as a real one.
+var dummyHash struct {
+	sync.Mutex
+	cost int
+	hash string
+}
+
+func dummyPasswordHash() string {
+	dummyHash.Lock()
+	defer dummyHash.Unlock()
+	if dummyHash.cost != bcryptCost || dummyHash.hash == "" {
+		raw := make([]byte, 32)
+		_, _ = rand.Read(raw)
+		hashed, err := bcrypt.GenerateFromPassword(raw, bcryptCost)
+		if err != nil {
+			return ""
+		}
+		dummyHash.cost, dummyHash.hash = bcryptCost, string(hashed)
 	}
+	return dummyHash.hash
 }
 
 // HashPassword hashes a plaintext password with bcrypt.
@@ -62,26 +84,56 @@ type UserStore interface {
 	GetByEmail(email string) (*User, string, error)
 }
 
-// Service handles authentication and user creation against a user store.
+// Service handles authentication and user creation against a user store, the sessions of
+// logged-in users when Sessions is set, personal access tokens when APITokens is set,
+// password resets and email verification when Accounts and Mailer are set, two-factor
+// authentication when Accounts and TwoFactor are set, login throttling when Throttle is set,
+// shared workspaces when Workspaces is set, their invites also needing Mailer, and the secrets
+// vault of workflows when Secrets is set.
 type Service struct {
 	store UserStore
+
+	Sessions       SessionStore
+	APITokens      APITokenStore
+	Accounts       AccountStore
+	TwoFactor      TwoFactorStore
+	Throttle       LoginThrottleStore
+	Workspaces     WorkspaceStore
+	Secrets        SecretStore
+	Connections    ConnectionStore
+	AuditLog       AuditStore
+	ThrottlePolicy ThrottlePolicy
+	Mailer         mail.Mailer
+	// AppURL is the web app base URL that emailed links point to.
+	AppURL     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	// Now replaces time.Now in tests.
+	Now func() time.Time
+
+	background sync.WaitGroup // emails sent after ForgotPassword returned
 }
 
 // NewService wires the auth service with a backing store (DB, memory, etc.).
 func NewService(store UserStore) *Service {
-	return &Service{store: store}
+	return &Service{
+		store:          store,
+		AccessTTL:      DefaultAccessTTL,
+		RefreshTTL:     DefaultRefreshTTL,
+		ThrottlePolicy: DefaultThrottlePolicy,
+	}
 }
 
-// Authenticate verifies email/password against the stored hash.
+// Authenticate verifies email/password against the stored hash. Unknown emails take as long as
+// wrong passwords, so response times do not reveal which accounts exist.
 func (s *Service) Authenticate(email, password string) (*User, error) {
 	user, hashed, err := s.store.GetByEmail(email)
 	switch {
-	case errors.Is(err, ErrInvalidCredentials):
+	case errors.Is(err, ErrInvalidCredentials), err == nil && user == nil:
+		_ = CheckPassword(dummyPasswordHash(), password)
 		return nil, ErrInvalidCredentials
 	case err != nil:
 		return nil, err
-	case user == nil:
-		return nil, ErrInvalidCredentials
 	}
 	if err := CheckPassword(hashed, password); err != nil {
 		return nil, ErrInvalidCredentials
@@ -90,7 +142,7 @@ func (s *Service) Authenticate(email, password string) (*User, error) {
 }
 
 // Register creates a new user with a hashed password in the store.
-func (s *Service) Register(email, password, firstName, lastName string) (*User, error) {
+func (s *Service) Register(ctx context.Context, email, password, firstName, lastName string) (*User, error) {
 	hashed, err := HashPassword(password)
 	if err != nil {
 		return nil, err
@@ -106,12 +158,14 @@ func (s *Service) Register(email, password, firstName, lastName string) (*User,
 		}
 		return nil, err
 	}
+	after, _ := auditState(map[string]string{"email": user.Email})
+	s.audit(ctx, &AuditEvent{ActorID: &user.ID, Action: "user.registered", Target: userTarget(user.ID), After: after})
 	return user, nil
 }
 
 // GetUserDataFromGoogle exchanges an auth code for user info (email/profile) via Google APIs.
-func GetUserDataFromGoogle(code string) ([]byte, error) {
-	redirectURI := os.Getenv("GOOGLE_OAUTH_REDIRECT_URI")
+func GetUserDataFromGoogle(code string, app config.OAuth) ([]byte, error) {
+	redirectURI := app.RedirectURI
 	if redirectURI == "" {
 		return nil, fmt.Errorf("missing GOOGLE_OAUTH_REDIRECT_URI")
 	}
@@ -119,8 +173,8 @@ func GetUserDataFromGoogle(code string) ([]byte, error) {
 	values.Set("grant_type", "authorization_code")
 	values.Set("code", code)
 	values.Set("redirect_uri", redirectURI)
-	values.Set("client_id", os.Getenv("GOOGLE_OAUTH_CLIENT_ID"))
-	values.Set("client_secret", os.Getenv("GOOGLE_OAUTH_CLIENT_SECRET"))
+	values.Set("client_id", app.ClientID)
+	values.Set("client_secret", app.ClientSecret)
 
 	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "https://oauth2.googleapis.com/token", strings.NewReader(values.Encode()))
 	if err != nil {
@@ -155,12 +209,12 @@ func GetUserDataFromGoogle(code string) ([]byte, error) {
 }
 
 // GetUserDataFromGithub exchanges an auth code for user info via GitHub APIs.
-func GetUserDataFromGithub(code string) ([]byte, error) {
+func GetUserDataFromGithub(code string, app config.OAuth) ([]byte, error) {
 	values := url.Values{}
-	values.Set("client_id", os.Getenv("GITHUB_OAUTH_CLIENT_ID"))
-	values.Set("client_secret", os.Getenv("GITHUB_OAUTH_CLIENT_SECRET"))
+	values.Set("client_id", app.ClientID)
+	values.Set("client_secret", app.ClientSecret)
 	values.Set("code", code)
-	if redirect := os.Getenv("GITHUB_OAUTH_REDIRECT_URI"); redirect != "" {
+	if redirect := app.RedirectURI; redirect != "" {
 		values.Set("redirect_uri", redirect)
 	}
 
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// OAuthStateTTL bounds the time between starting an OAuth connection and its callback.
+	OAuthStateTTL = 10 * time.Minute
+	// ReactionTokenTTL bounds the time between the executor issuing a reaction token and the
+	// reaction route receiving it.
+	ReactionTokenTTL = time.Minute
+	// ReactionTokenPrefix marks the tokens of reaction calls made by the executor.
+	ReactionTokenPrefix = "rxn_"
+
+	purposeOAuthState = "oauth_state"
+	purposeReaction   = "reaction"
+	oauthStatePrefix  = "ost_"
+)
+
+var ErrNoConnectionStore = errors.New("connections are not configured")
+
+// ConnectionStore removes the OAuth connections of users.
+type ConnectionStore interface {
+	// DeleteConnection deletes an OAuth connection of userID with its credentials, or returns
+	// ErrConnectionNotFound.
+	DeleteConnection(ctx context.Context, provider string, id, userID int64) error
+}
+
+// RecordConnection records that userID connected an account of provider, stored as the
+// connection id.
+func (s *Service) RecordConnection(ctx context.Context, userID int64, provider string, id int64) {
+	s.auditUser(ctx, userID, "oauth.connected", connectionTarget(provider, id), "")
+}
+
+// Disconnect deletes an OAuth connection of userID. Workflows using it fail until they are given
+// another one.
+func (s *Service) Disconnect(ctx context.Context, userID int64, provider string, id int64) error {
+	if s.Connections == nil {
+		return ErrNoConnectionStore
+	}
+	if !slices.Contains(sharedProviders, provider) {
+		return &ValidationError{Field: "provider", Message: fmt.Sprintf("must be among %s", strings.Join(sharedProviders, ", "))}
+	}
+	if err := s.Connections.DeleteConnection(ctx, provider, id, userID); err != nil {
+		return err
+	}
+	s.auditUser(ctx, userID, "oauth.disconnected", connectionTarget(provider, id), "")
+	return nil
+}
+
+// StartConnection returns the state of an OAuth connection userID starts. The provider
+// redirects to the callback without credentials, so the callback trades the state for the user
+// with FinishConnection, once.
+func (s *Service) StartConnection(ctx context.Context, userID int64) (string, error) {
+	return s.singleUseToken(ctx, userID, purposeOAuthState, oauthStatePrefix, OAuthStateTTL)
+}
+
+// FinishConnection returns the user who started the OAuth connection of state, or
+// ErrInvalidToken for an unknown, expired or already used state.
+func (s *Service) FinishConnection(ctx context.Context, state string) (int64, error) {
+	if s.Accounts == nil {
+		return 0, ErrNoAccountStore
+	}
+	return s.Accounts.ConsumeAccountToken(ctx, purposeOAuthState, HashToken(state), s.now())
+}
+
+// IssueReactionToken returns a token the executor authenticates one reaction call of a workflow
+// of userID with, for the reactions that act with the OAuth connections of the user.
+func (s *Service) IssueReactionToken(ctx context.Context, userID int64) (string, error) {
+	return s.singleUseToken(ctx, userID, purposeReaction, ReactionTokenPrefix, ReactionTokenTTL)
+}
+
+// VerifyReactionToken returns the user of a token from IssueReactionToken, once, or
+// ErrInvalidToken.
+func (s *Service) VerifyReactionToken(ctx context.Context, token string) (int64, error) {
+	if s.Accounts == nil {
+		return 0, ErrNoAccountStore
+	}
+	return s.Accounts.ConsumeAccountToken(ctx, purposeReaction, HashToken(token), s.now())
+}
+
+// singleUseToken stores a new account token of userID for purpose and returns it.
+func (s *Service) singleUseToken(ctx context.Context, userID int64, purpose, prefix string, ttl time.Duration) (string, error) {
+	if s.Accounts == nil {
+		return "", ErrNoAccountStore
+	}
+	token, err := newToken(prefix)
+	if err != nil {
+		return "", err
+	}
+	if err := s.Accounts.CreateAccountToken(ctx, userID, purpose, HashToken(token), s.now().Add(ttl)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func connectionTarget(provider string, id int64) string {
+	return fmt.Sprintf("%s:%d", provider, id)
+}
@@ -1,15 +1,21 @@
 package auth
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"area/src/database"
+	"area/src/security"
 
 	"gorm.io/gorm"
 )
 
-// DBStore implements UserStore backed by PostgreSQL.
+// DBStore implements UserStore, SessionStore, APITokenStore, AccountStore, TwoFactorStore,
+// LoginThrottleStore, WorkspaceStore and SecretStore backed by the database.
 type DBStore struct{}
 
 // NewDBStore returns a UserStore using the shared database connection.
@@ -39,10 +45,593 @@ func (DBStore) GetByEmail(email string) (*User, string, error) {
 		}
 		return nil, "", err
 	}
+	return userFromRow(&dbUser), dbUser.PasswordHash, nil
+}
+
+func userFromRow(row *database.User) *User {
 	return &User{
-		ID:        int64(dbUser.ID),
-		Email:     dbUser.Email,
-		FirstName: dbUser.Firstname,
-		LastName:  dbUser.Lastname,
-	}, dbUser.PasswordHash, nil
+		ID:            int64(row.ID),
+		Email:         row.Email,
+		FirstName:     row.Firstname,
+		LastName:      row.Lastname,
+		EmailVerified: row.EmailVerifiedAt != nil,
+	}
+}
+
+// CreateSession inserts a session and sets its ID.
+func (DBStore) CreateSession(ctx context.Context, session *Session, accessHash, refreshHash string) error {
+	row := &database.Session{
+		UserID:           uint(session.UserID),
+		AccessTokenHash:  accessHash,
+		RefreshTokenHash: refreshHash,
+		AccessExpiresAt:  session.AccessExpiresAt,
+		RefreshExpiresAt: session.RefreshExpiresAt,
+	}
+	if err := database.CreateSession(ctx, row); err != nil {
+		return err
+	}
+	session.ID = int64(row.ID)
+	return nil
+}
+
+// SessionByAccessHash returns the session of an access token hash.
+func (DBStore) SessionByAccessHash(ctx context.Context, hash string) (*Session, error) {
+	return sessionFromRow(database.GetSessionByAccessHash(ctx, hash))
+}
+
+// SessionByRefreshHash returns the session of a refresh token hash.
+func (DBStore) SessionByRefreshHash(ctx context.Context, hash string) (*Session, error) {
+	return sessionFromRow(database.GetSessionByRefreshHash(ctx, hash))
+}
+
+// RotateSession stores the new token hashes and expiries of session.
+func (DBStore) RotateSession(ctx context.Context, session *Session, oldRefreshHash, accessHash, refreshHash string) (bool, error) {
+	return database.RotateSession(ctx, uint(session.ID), oldRefreshHash, database.Session{
+		AccessTokenHash:  accessHash,
+		RefreshTokenHash: refreshHash,
+		AccessExpiresAt:  session.AccessExpiresAt,
+		RefreshExpiresAt: session.RefreshExpiresAt,
+	})
+}
+
+// RevokeSession marks a session revoked.
+func (DBStore) RevokeSession(ctx context.Context, id int64, at time.Time) error {
+	return database.RevokeSession(ctx, uint(id), at)
+}
+
+// RevokeUserSessions marks every live session of a user revoked.
+func (DBStore) RevokeUserSessions(ctx context.Context, userID int64, at time.Time) error {
+	return database.RevokeUserSessions(ctx, uint(userID), at)
+}
+
+func sessionFromRow(row *database.Session, err error) (*Session, error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Session{
+		ID:               int64(row.ID),
+		UserID:           int64(row.UserID),
+		AccessExpiresAt:  row.AccessExpiresAt,
+		RefreshExpiresAt: row.RefreshExpiresAt,
+		RevokedAt:        row.RevokedAt,
+	}, nil
+}
+
+// CreateAPIToken inserts a personal access token and sets its ID.
+func (DBStore) CreateAPIToken(ctx context.Context, token *APIToken, hash string) error {
+	row := &database.APIToken{
+		UserID:    uint(token.UserID),
+		Name:      token.Name,
+		TokenHash: hash,
+		Scopes:    strings.Join(token.Scopes, " "),
+		ExpiresAt: token.ExpiresAt,
+		CreatedAt: token.CreatedAt,
+	}
+	if err := database.CreateAPIToken(ctx, row); err != nil {
+		return err
+	}
+	token.ID = int64(row.ID)
+	return nil
+}
+
+// ListAPITokens returns the personal access tokens of a user, newest first.
+func (DBStore) ListAPITokens(ctx context.Context, userID int64) ([]APIToken, error) {
+	rows, err := database.ListAPITokens(ctx, uint(userID))
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]APIToken, 0, len(rows))
+	for i := range rows {
+		tokens = append(tokens, *apiTokenFromRow(&rows[i]))
+	}
+	return tokens, nil
+}
+
+// APITokenByHash returns the personal access token of a hash.
+func (DBStore) APITokenByHash(ctx context.Context, hash string) (*APIToken, error) {
+	row, err := database.GetAPITokenByHash(ctx, hash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return apiTokenFromRow(row), nil
+}
+
+// TouchAPIToken records the last use of a token.
+func (DBStore) TouchAPIToken(ctx context.Context, id int64, at time.Time) error {
+	return database.TouchAPIToken(ctx, uint(id), at)
+}
+
+// DeleteAPIToken deletes a token of a user.
+func (DBStore) DeleteAPIToken(ctx context.Context, userID, id int64) error {
+	deleted, err := database.DeleteAPIToken(ctx, uint(userID), uint(id))
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+func apiTokenFromRow(row *database.APIToken) *APIToken {
+	return &APIToken{
+		ID:         int64(row.ID),
+		UserID:     int64(row.UserID),
+		Name:       row.Name,
+		Scopes:     strings.Fields(row.Scopes),
+		ExpiresAt:  row.ExpiresAt,
+		LastUsedAt: row.LastUsedAt,
+		CreatedAt:  row.CreatedAt,
+	}
+}
+
+// CreateAccountToken inserts a single-use account token.
+func (DBStore) CreateAccountToken(ctx context.Context, userID int64, purpose, hash string, expiresAt time.Time) error {
+	return database.CreateAccountToken(ctx, &database.AccountToken{
+		UserID:    uint(userID),
+		Purpose:   purpose,
+		TokenHash: hash,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// ConsumeAccountToken marks an account token used and returns its user.
+func (DBStore) ConsumeAccountToken(ctx context.Context, purpose, hash string, now time.Time) (int64, error) {
+	userID, err := database.ConsumeAccountToken(ctx, purpose, hash, now)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrInvalidToken
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int64(userID), nil
+}
+
+// SetPassword replaces the password hash of a user.
+func (DBStore) SetPassword(ctx context.Context, userID int64, passwordHash string) error {
+	return database.SetPasswordHash(ctx, uint(userID), passwordHash)
+}
+
+// MarkEmailVerified records that a user verified their email address.
+func (DBStore) MarkEmailVerified(ctx context.Context, userID int64, at time.Time) error {
+	return database.MarkEmailVerified(ctx, uint(userID), at)
+}
+
+// UserByID returns a user.
+func (DBStore) UserByID(ctx context.Context, id int64) (*User, error) {
+	row, err := database.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return userFromRow(&row), nil
+}
+
+// TwoFactor returns the TOTP enrollment of a user with their unused recovery code count.
+func (DBStore) TwoFactor(ctx context.Context, userID int64) (*TwoFactor, error) {
+	row, err := database.GetTwoFactor(ctx, uint(userID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	secret, err := security.DecryptString(row.Secret)
+	if err != nil {
+		return nil, err
+	}
+	left, err := database.CountRecoveryCodes(ctx, uint(userID))
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactor{
+		Secret:            secret,
+		Enabled:           row.EnabledAt != nil,
+		LastUsedStep:      row.LastUsedStep,
+		RecoveryCodesLeft: int(left),
+	}, nil
+}
+
+// SaveTwoFactorSecret stores a pending TOTP secret, encrypted when APP_SECRET_KEY is set.
+func (DBStore) SaveTwoFactorSecret(ctx context.Context, userID int64, secret string) error {
+	if security.Enabled() {
+		encrypted, err := security.EncryptString(secret)
+		if err != nil {
+			return err
+		}
+		secret = encrypted
+	}
+	return database.SaveTwoFactorSecret(ctx, uint(userID), secret)
+}
+
+// ReencryptSecrets re-encrypts the stored TOTP secrets with the current key, returning how many
+// changed.
+func (DBStore) ReencryptSecrets(ctx context.Context) (int, error) {
+	rows, err := database.ListTwoFactors(ctx)
+	if err != nil {
+		return 0, err
+	}
+	changed := 0
+	for _, row := range rows {
+		secret, rotated, err := security.Reencrypt(row.Secret)
+		if err != nil {
+			return changed, fmt.Errorf("two factor secret of user %d: %w", row.UserID, err)
+		}
+		if !rotated {
+			continue
+		}
+		// A secret replaced meanwhile was encrypted with the current key already.
+		replaced, err := database.ReplaceTwoFactorSecret(ctx, row.UserID, row.Secret, secret)
+		if err != nil {
+			return changed, err
+		}
+		if replaced {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// EnableTwoFactor enables the pending TOTP enrollment of a user with new recovery codes.
+func (DBStore) EnableTwoFactor(ctx context.Context, userID int64, at time.Time, step int64, recoveryHashes []string) (bool, error) {
+	return database.EnableTwoFactor(ctx, uint(userID), at, step, recoveryHashes)
+}
+
+// UseTwoFactorStep records the last used TOTP time step of a user.
+func (DBStore) UseTwoFactorStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	return database.UseTwoFactorStep(ctx, uint(userID), step)
+}
+
+// UseRecoveryCode marks a recovery code of a user used.
+func (DBStore) UseRecoveryCode(ctx context.Context, userID int64, hash string, at time.Time) (bool, error) {
+	return database.UseRecoveryCode(ctx, uint(userID), hash, at)
+}
+
+// DeleteTwoFactor turns two-factor authentication off for a user.
+func (DBStore) DeleteTwoFactor(ctx context.Context, userID int64) error {
+	return database.DeleteTwoFactor(ctx, uint(userID))
+}
+
+// LoginLockedUntil returns the latest lockout end among keys.
+func (DBStore) LoginLockedUntil(ctx context.Context, keys []string) (time.Time, error) {
+	return database.LoginLockedUntil(ctx, keys)
+}
+
+// AddLoginFailure counts a failed login for key.
+func (DBStore) AddLoginFailure(ctx context.Context, key string, at, forgetBefore time.Time) (int, error) {
+	return database.AddLoginFailure(ctx, key, at, forgetBefore)
+}
+
+// LockLogin locks key out until the given time.
+func (DBStore) LockLogin(ctx context.Context, key string, until time.Time) error {
+	return database.LockLogin(ctx, key, until)
+}
+
+// ResetLoginFailures forgets the failed logins of key.
+func (DBStore) ResetLoginFailures(ctx context.Context, key string) error {
+	return database.ResetLoginFailures(ctx, key)
+}
+
+// LogLoginFailure records a failed login.
+func (DBStore) LogLoginFailure(ctx context.Context, email, ip string, at time.Time) error {
+	return database.CreateLoginFailure(ctx, &database.LoginFailure{Email: email, IP: ip, CreatedAt: at})
+}
+
+// CreateWorkspace inserts a workspace owned by ownerID.
+func (DBStore) CreateWorkspace(ctx context.Context, name string, ownerID int64) (*Workspace, error) {
+	row, err := database.CreateWorkspace(ctx, name, uint(ownerID))
+	if err != nil {
+		return nil, err
+	}
+	return &Workspace{ID: int64(row.ID), Name: row.Name, Role: RoleOwner, CreatedAt: row.CreatedAt}, nil
+}
+
+// ListWorkspaces returns the workspaces of a user, oldest first.
+func (DBStore) ListWorkspaces(ctx context.Context, userID int64) ([]Workspace, error) {
+	rows, err := database.ListWorkspacesForUser(ctx, uint(userID))
+	if err != nil {
+		return nil, err
+	}
+	workspaces := make([]Workspace, 0, len(rows))
+	for i := range rows {
+		workspaces = append(workspaces, *workspaceFromRow(&rows[i]))
+	}
+	return workspaces, nil
+}
+
+// Workspace returns a workspace of a user with their role in it.
+func (DBStore) Workspace(ctx context.Context, id, userID int64) (*Workspace, error) {
+	row, err := database.GetWorkspaceForUser(ctx, uint(id), uint(userID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWorkspaceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return workspaceFromRow(row), nil
+}
+
+func workspaceFromRow(row *database.WorkspaceWithRole) *Workspace {
+	return &Workspace{ID: int64(row.ID), Name: row.Name, Role: row.Role, CreatedAt: row.CreatedAt}
+}
+
+// WorkspaceRoles returns the role of a user by the ID of each workspace they belong to.
+func (DBStore) WorkspaceRoles(ctx context.Context, userID int64) (map[int64]string, error) {
+	rows, err := database.WorkspaceRolesForUser(ctx, uint(userID))
+	if err != nil {
+		return nil, err
+	}
+	roles := make(map[int64]string, len(rows))
+	for id, role := range rows {
+		roles[int64(id)] = role
+	}
+	return roles, nil
+}
+
+// WorkspaceMembers returns the members of a workspace in the order they joined.
+func (DBStore) WorkspaceMembers(ctx context.Context, id int64) ([]WorkspaceMember, error) {
+	rows, err := database.ListWorkspaceMembers(ctx, uint(id))
+	if err != nil {
+		return nil, err
+	}
+	members := make([]WorkspaceMember, 0, len(rows))
+	for _, row := range rows {
+		members = append(members, WorkspaceMember{UserID: int64(row.UserID), Email: row.Email, Role: row.Role, JoinedAt: row.CreatedAt})
+	}
+	return members, nil
+}
+
+// SetWorkspaceRole changes the role of a member.
+func (DBStore) SetWorkspaceRole(ctx context.Context, id, userID int64, role string) error {
+	return memberChange(database.SetWorkspaceMemberRole(ctx, uint(id), uint(userID), role))
+}
+
+// RemoveWorkspaceMember removes a member from a workspace.
+func (DBStore) RemoveWorkspaceMember(ctx context.Context, id, userID int64) error {
+	return memberChange(database.DeleteWorkspaceMember(ctx, uint(id), uint(userID)))
+}
+
+func memberChange(kept bool, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrMemberNotFound
+	case err != nil:
+		return err
+	case !kept:
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// CreateWorkspaceInvite inserts an invite and sets its ID.
+func (DBStore) CreateWorkspaceInvite(ctx context.Context, invite *WorkspaceInvite, invitedBy int64, hash string) error {
+	inviter := uint(invitedBy)
+	row := &database.WorkspaceInvite{
+		WorkspaceID: uint(invite.WorkspaceID),
+		Email:       invite.Email,
+		Role:        invite.Role,
+		TokenHash:   hash,
+		InvitedBy:   &inviter,
+		ExpiresAt:   invite.ExpiresAt,
+		CreatedAt:   invite.CreatedAt,
+	}
+	if err := database.CreateWorkspaceInvite(ctx, row); err != nil {
+		return err
+	}
+	invite.ID = int64(row.ID)
+	return nil
+}
+
+// AcceptWorkspaceInvite accepts the invite of a hash for the user it is addressed to.
+func (DBStore) AcceptWorkspaceInvite(ctx context.Context, hash string, userID int64, now time.Time) (*WorkspaceInvite, error) {
+	user, err := database.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	row, err := database.AcceptWorkspaceInvite(ctx, hash, uint(userID), user.Email, now)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &WorkspaceInvite{
+		ID:          int64(row.ID),
+		WorkspaceID: int64(row.WorkspaceID),
+		Email:       row.Email,
+		Role:        row.Role,
+		ExpiresAt:   row.ExpiresAt,
+		AcceptedAt:  row.AcceptedAt,
+		CreatedAt:   row.CreatedAt,
+	}, nil
+}
+
+// ShareConnection shares a Google or GitHub connection of a user with a workspace.
+func (DBStore) ShareConnection(ctx context.Context, provider string, id, userID, workspaceID int64) error {
+	var shared bool
+	var err error
+	switch provider {
+	case "google":
+		shared, err = database.ShareGoogleToken(ctx, id, userID, workspaceID)
+	case "github":
+		shared, err = database.ShareGithubToken(ctx, id, userID, workspaceID)
+	default:
+		return fmt.Errorf("unknown connection provider %q", provider)
+	}
+	if err != nil {
+		return err
+	}
+	if !shared {
+		return ErrConnectionNotFound
+	}
+	return nil
+}
+
+// DeleteConnection deletes an OAuth connection of userID with its credentials.
+func (DBStore) DeleteConnection(ctx context.Context, provider string, id, userID int64) error {
+	var deleted bool
+	var err error
+	switch provider {
+	case "google":
+		deleted, err = database.DeleteGoogleToken(ctx, id, userID)
+	case "github":
+		deleted, err = database.DeleteGithubToken(ctx, id, userID)
+	default:
+		return fmt.Errorf("unknown connection provider %q", provider)
+	}
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrConnectionNotFound
+	}
+	return nil
+}
+
+// CreateAuditEvent appends an event to the audit log.
+func (DBStore) CreateAuditEvent(ctx context.Context, event *AuditEvent) error {
+	row := &database.AuditEvent{
+		WorkspaceID: optionalID(event.WorkspaceID),
+		ActorID:     optionalID(event.ActorID),
+		Action:      event.Action,
+		Target:      event.Target,
+		Detail:      event.Detail,
+		IP:          event.IP,
+		UserAgent:   event.UserAgent,
+		BeforeState: event.Before,
+		AfterState:  event.After,
+		CreatedAt:   event.CreatedAt,
+	}
+	if err := database.CreateAuditEvent(ctx, row); err != nil {
+		return err
+	}
+	event.ID = int64(row.ID)
+	return nil
+}
+
+// WorkspaceAudit returns the latest events of a workspace, newest first.
+func (DBStore) WorkspaceAudit(ctx context.Context, id int64, limit int) ([]AuditEvent, error) {
+	rows, err := database.ListWorkspaceAuditEvents(ctx, uint(id), limit)
+	if err != nil {
+		return nil, err
+	}
+	return auditEvents(rows), nil
+}
+
+// ListAuditEvents returns the latest events matching filter, newest first.
+func (DBStore) ListAuditEvents(ctx context.Context, filter AuditFilter) ([]AuditEvent, error) {
+	rows, err := database.ListAuditEvents(ctx, database.AuditFilter{
+		ActorID:     uint(filter.ActorID),
+		WorkspaceID: uint(filter.WorkspaceID),
+		Action:      filter.Action,
+		Target:      filter.Target,
+		Since:       filter.Since,
+		Until:       filter.Until,
+		BeforeID:    uint(filter.BeforeID),
+		Limit:       filter.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return auditEvents(rows), nil
+}
+
+func auditEvents(rows []database.AuditEvent) []AuditEvent {
+	events := make([]AuditEvent, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, AuditEvent{
+			ID:          int64(row.ID),
+			WorkspaceID: rowID(row.WorkspaceID),
+			ActorID:     rowID(row.ActorID),
+			Action:      row.Action,
+			Target:      row.Target,
+			Detail:      row.Detail,
+			IP:          row.IP,
+			UserAgent:   row.UserAgent,
+			Before:      row.BeforeState,
+			After:       row.AfterState,
+			CreatedAt:   row.CreatedAt,
+		})
+	}
+	return events
+}
+
+func optionalID(id *int64) *uint {
+	if id == nil {
+		return nil
+	}
+	v := uint(*id)
+	return &v
+}
+
+func rowID(id *uint) *int64 {
+	if id == nil {
+		return nil
+	}
+	v := int64(*id)
+	return &v
+}
+
+// ListSecrets returns the secrets of a user by name, without their values.
+func (DBStore) ListSecrets(ctx context.Context, userID int64) ([]Secret, error) {
+	rows, err := database.ListUserSecrets(ctx, uint(userID))
+	if err != nil {
+		return nil, err
+	}
+	secrets := make([]Secret, 0, len(rows))
+	for _, row := range rows {
+		secrets = append(secrets, Secret{Name: row.Name, CreatedAt: row.CreatedAt, UpdatedAt: row.UpdatedAt})
+	}
+	return secrets, nil
+}
+
+// PutSecret stores the value of a secret of a user and reports whether it is new.
+func (DBStore) PutSecret(ctx context.Context, userID int64, name, value string, at time.Time) (bool, error) {
+	return database.PutUserSecret(ctx, uint(userID), name, value, at)
+}
+
+// SecretValue returns the value of a secret of a user.
+func (DBStore) SecretValue(ctx context.Context, userID int64, name string) (string, error) {
+	value, err := database.GetUserSecretValue(ctx, uint(userID), name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrSecretNotFound
+	}
+	return value, err
+}
+
+// DeleteSecret deletes a secret of a user.
+func (DBStore) DeleteSecret(ctx context.Context, userID int64, name string) error {
+	deleted, err := database.DeleteUserSecret(ctx, uint(userID), name)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrSecretNotFound
+	}
+	return nil
 }
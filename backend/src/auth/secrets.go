@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+)
+
+// maxSecretValue bounds the size of a stored secret, in bytes.
+const maxSecretValue = 8 << 10
+
+// validSecretName matches the names workflows can reference as {{secrets.<name>}}.
+var validSecretName = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+
+var (
+	ErrSecretNotFound = errors.New("secret not found")
+	ErrNoSecretStore  = errors.New("secrets are not configured")
+)
+
+// Secret describes a stored secret of a user. Its value is never returned by the API.
+type Secret struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SecretStore persists the named secrets of users. Reading or deleting a secret the user does
+// not have returns ErrSecretNotFound.
+type SecretStore interface {
+	ListSecrets(ctx context.Context, userID int64) ([]Secret, error)
+	PutSecret(ctx context.Context, userID int64, name, value string, at time.Time) (bool, error)
+	SecretValue(ctx context.Context, userID int64, name string) (string, error)
+	DeleteSecret(ctx context.Context, userID int64, name string) error
+}
+
+// ListSecrets returns the secrets of a user by name, without their values.
+func (s *Service) ListSecrets(ctx context.Context, userID int64) ([]Secret, error) {
+	if s.Secrets == nil {
+		return nil, ErrNoSecretStore
+	}
+	return s.Secrets.ListSecrets(ctx, userID)
+}
+
+// PutSecret sets the value of a secret of a user, creating it or replacing its value, and
+// reports whether it is new.
+func (s *Service) PutSecret(ctx context.Context, userID int64, name, value string) (bool, error) {
+	if s.Secrets == nil {
+		return false, ErrNoSecretStore
+	}
+	switch {
+	case !validSecretName.MatchString(name):
+		return false, &ValidationError{Field: "name", Message: "must be 1 to 64 letters, digits or _"}
+	case value == "":
+		return false, &ValidationError{Field: "value", Message: "is required"}
+	case len(value) > maxSecretValue:
+		return false, &ValidationError{Field: "value", Message: "must be at most 8 KiB"}
+	}
+	created, err := s.Secrets.PutSecret(ctx, userID, name, value, s.now())
+	if err != nil {
+		return false, err
+	}
+	action := "secret.updated"
+	if created {
+		action = "secret.created"
+	}
+	s.auditUser(ctx, userID, action, secretTarget(name), "")
+	return created, nil
+}
+
+// DeleteSecret deletes a secret of a user. Workflows still referencing it fail until it is set
+// again.
+func (s *Service) DeleteSecret(ctx context.Context, userID int64, name string) error {
+	if s.Secrets == nil {
+		return ErrNoSecretStore
+	}
+	if err := s.Secrets.DeleteSecret(ctx, userID, name); err != nil {
+		return err
+	}
+	s.auditUser(ctx, userID, "secret.deleted", secretTarget(name), "")
+	return nil
+}
+
+// ResolveSecret returns the value of a secret of a user, for the executor to substitute in a
+// reaction payload.
+func (s *Service) ResolveSecret(ctx context.Context, userID int64, name string) (string, error) {
+	if s.Secrets == nil {
+		return "", ErrNoSecretStore
+	}
+	return s.Secrets.SecretValue(ctx, userID, name)
+}
+
+// secretTarget names a secret in the audit log, which never records its value.
+func secretTarget(name string) string {
+	return "secret:" + name
+}
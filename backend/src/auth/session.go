@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidToken   = errors.New("invalid or expired token")
+	ErrNoSessionStore = errors.New("sessions are not configured")
+)
+
+const (
+	DefaultAccessTTL  = 15 * time.Minute
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+
+	sessionTokenBytes  = 32
+	sessionTokenPrefix = "ses_"
+)
+
+// Session is a login of a user, identified by the hashes of its current tokens.
+type Session struct {
+	ID               int64
+	UserID           int64
+	AccessExpiresAt  time.Time
+	RefreshExpiresAt time.Time
+	RevokedAt        *time.Time
+}
+
+// Tokens are the credentials of a session. Only their hashes are stored, so they are shown once.
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// SessionStore persists sessions by token hash. Lookups of unknown hashes return ErrInvalidToken.
+type SessionStore interface {
+	CreateSession(ctx context.Context, session *Session, accessHash, refreshHash string) error
+	SessionByAccessHash(ctx context.Context, hash string) (*Session, error)
+	SessionByRefreshHash(ctx context.Context, hash string) (*Session, error)
+	// RotateSession swaps the token hashes of a live session whose refresh hash is still
+	// oldRefreshHash and reports whether it did.
+	RotateSession(ctx context.Context, session *Session, oldRefreshHash, accessHash, refreshHash string) (bool, error)
+	RevokeSession(ctx context.Context, id int64, at time.Time) error
+	RevokeUserSessions(ctx context.Context, userID int64, at time.Time) error
+}
+
+// HashToken returns the hex SHA-256 of a token; tokens are random, so no salt is needed.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newToken returns a random token with the given prefix.
+func newToken(prefix string) (string, error) {
+	raw := make([]byte, sessionTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// newTokens draws the tokens of a session and sets its expiries from now.
+func (s *Service) newTokens(session *Session, now time.Time) (*Tokens, error) {
+	access, err := newToken(sessionTokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := newToken(sessionTokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+	session.AccessExpiresAt = now.Add(s.AccessTTL)
+	session.RefreshExpiresAt = now.Add(s.RefreshTTL)
+	return &Tokens{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.AccessTTL / time.Second),
+	}, nil
+}
+
+// StartSession opens a session for an authenticated user.
+func (s *Service) StartSession(ctx context.Context, userID int64) (*Tokens, error) {
+	if s.Sessions == nil {
+		return nil, ErrNoSessionStore
+	}
+	session := &Session{UserID: userID}
+	tokens, err := s.newTokens(session, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Sessions.CreateSession(ctx, session, HashToken(tokens.AccessToken), HashToken(tokens.RefreshToken)); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Refresh trades a refresh token for new tokens; the old ones stop working.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	if s.Sessions == nil {
+		return nil, ErrNoSessionStore
+	}
+	oldHash := HashToken(refreshToken)
+	session, err := s.Sessions.SessionByRefreshHash(ctx, oldHash)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if session.RevokedAt != nil || !now.Before(session.RefreshExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	tokens, err := s.newTokens(session, now)
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := s.Sessions.RotateSession(ctx, session, oldHash, HashToken(tokens.AccessToken), HashToken(tokens.RefreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, ErrInvalidToken
+	}
+	return tokens, nil
+}
+
+// VerifyAccessToken returns the live session an access token belongs to.
+func (s *Service) VerifyAccessToken(ctx context.Context, accessToken string) (*Session, error) {
+	if s.Sessions == nil {
+		return nil, ErrNoSessionStore
+	}
+	session, err := s.Sessions.SessionByAccessHash(ctx, HashToken(accessToken))
+	if err != nil {
+		return nil, err
+	}
+	if session.RevokedAt != nil || !s.now().Before(session.AccessExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	return session, nil
+}
+
+// Logout revokes the session of an access token.
+func (s *Service) Logout(ctx context.Context, accessToken string) error {
+	session, err := s.VerifyAccessToken(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+	return s.Sessions.RevokeSession(ctx, session.ID, s.now())
+}
+
+func (s *Service) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
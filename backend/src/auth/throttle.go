@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// forgetLoginFailuresAfter is how long after the last failed login the count starts over.
+const forgetLoginFailuresAfter = 24 * time.Hour
+
+// DefaultThrottlePolicy locks an account out after 5 failed logins in a row and an IP address
+// after 20, for 30 seconds doubling with each further failure up to an hour.
+var DefaultThrottlePolicy = ThrottlePolicy{
+	AccountAttempts: 5,
+	IPAttempts:      20,
+	Lockout:         30 * time.Second,
+	MaxLockout:      time.Hour,
+}
+
+// ThrottlePolicy sets how many failed logins in a row lock an account or IP address out, and
+// for how long: Lockout for the last allowed failure, doubling with each further one up to
+// MaxLockout.
+type ThrottlePolicy struct {
+	AccountAttempts int
+	IPAttempts      int
+	Lockout         time.Duration
+	MaxLockout      time.Duration
+}
+
+// lockout returns the lockout after the given failures in a row, or zero when limit is not reached.
+func (p ThrottlePolicy) lockout(failures, limit int) time.Duration {
+	if failures < limit {
+		return 0
+	}
+	lockout := p.Lockout
+	for range failures - limit {
+		if lockout >= p.MaxLockout {
+			break
+		}
+		lockout *= 2
+	}
+	return min(lockout, p.MaxLockout)
+}
+
+// LoginThrottleStore persists failed logins and lockouts by key where every replica sees them.
+type LoginThrottleStore interface {
+	// LoginLockedUntil returns the latest lockout end among keys, or the zero time.
+	LoginLockedUntil(ctx context.Context, keys []string) (time.Time, error)
+	// AddLoginFailure counts a failure for key and returns the failures in a row, starting over
+	// when the previous one is before forgetBefore.
+	AddLoginFailure(ctx context.Context, key string, at, forgetBefore time.Time) (int, error)
+	LockLogin(ctx context.Context, key string, until time.Time) error
+	ResetLoginFailures(ctx context.Context, key string) error
+	LogLoginFailure(ctx context.Context, email, ip string, at time.Time) error
+}
+
+// LoginLockedError reports a login refused because of too many failed attempts. It is returned
+// for unknown emails too, so lockouts do not reveal which accounts exist.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return "too many failed logins, try again later"
+}
+
+// Login authenticates a user from the given IP address, refusing with a *LoginLockedError while
+// the account or the address is locked out. When the user has two-factor authentication enabled
+// it also returns the token for CompleteLogin, and the login only counts as successful there.
+func (s *Service) Login(ctx context.Context, email, password, ip string) (*User, string, error) {
+	if err := s.checkLoginThrottle(ctx, email, ip); err != nil {
+		s.auditLogin(ctx, nil, email, ip, "login.failed", loginFailureDetail(err))
+		return nil, "", err
+	}
+	user, err := s.Authenticate(email, password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		s.auditLogin(ctx, nil, email, ip, "login.failed", "invalid credentials")
+		if err := s.loginFailed(ctx, email, ip); err != nil {
+			return nil, "", err
+		}
+		return nil, "", ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	challenge, err := s.LoginChallenge(ctx, user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if challenge == "" {
+		if err := s.loginSucceeded(ctx, email); err != nil {
+			return nil, "", err
+		}
+		s.auditLogin(ctx, user, email, ip, "login.succeeded", "password")
+	}
+	return user, challenge, nil
+}
+
+// auditLogin records a login attempt on the account of email, looking the account up when user
+// is nil. Attempts on unknown addresses have no actor.
+func (s *Service) auditLogin(ctx context.Context, user *User, email, ip, action, detail string) {
+	if s.AuditLog == nil {
+		return
+	}
+	if user == nil {
+		user, _, _ = s.store.GetByEmail(email)
+	}
+	event := &AuditEvent{Action: action, Target: accountThrottleKey(email), Detail: detail, IP: ip}
+	if user != nil {
+		event.ActorID = &user.ID
+		event.Target = userTarget(user.ID)
+	}
+	s.audit(ctx, event)
+}
+
+// loginFailureDetail describes why a login was refused before its credentials were checked.
+func loginFailureDetail(err error) string {
+	var locked *LoginLockedError
+	if errors.As(err, &locked) {
+		return "locked out"
+	}
+	return err.Error()
+}
+
+// PasswordResetLimitedError reports a password reset refused because the email or the IP address
+// asked for too many in a row.
+type PasswordResetLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *PasswordResetLimitedError) Error() string {
+	return "too many password reset requests, try again later"
+}
+
+func accountThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// checkLoginThrottle returns a *LoginLockedError while the account or the IP address is locked out.
+func (s *Service) checkLoginThrottle(ctx context.Context, email, ip string) error {
+	if s.Throttle == nil {
+		return nil
+	}
+	keys := []string{accountThrottleKey(email)}
+	if ip != "" {
+		keys = append(keys, ipThrottleKey(ip))
+	}
+	until, err := s.Throttle.LoginLockedUntil(ctx, keys)
+	if err != nil {
+		return err
+	}
+	if now := s.now(); now.Before(until) {
+		return &LoginLockedError{RetryAfter: until.Sub(now)}
+	}
+	return nil
+}
+
+// loginFailed logs a failed login and locks the account or the IP address out once they reach
+// their limit.
+func (s *Service) loginFailed(ctx context.Context, email, ip string) error {
+	if s.Throttle == nil {
+		return nil
+	}
+	now := s.now()
+	if err := s.Throttle.LogLoginFailure(ctx, strings.ToLower(strings.TrimSpace(email)), ip, now); err != nil {
+		return err
+	}
+	limits := map[string]int{accountThrottleKey(email): s.ThrottlePolicy.AccountAttempts}
+	if ip != "" {
+		limits[ipThrottleKey(ip)] = s.ThrottlePolicy.IPAttempts
+	}
+	for key, limit := range limits {
+		failures, err := s.Throttle.AddLoginFailure(ctx, key, now, now.Add(-forgetLoginFailuresAfter))
+		if err != nil {
+			return err
+		}
+		if lockout := s.ThrottlePolicy.lockout(failures, limit); lockout > 0 {
+			if err := s.Throttle.LockLogin(ctx, key, now.Add(lockout)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loginSucceeded forgets the failed logins of the account. Those of the IP address stay, so one
+// valid account does not clear the way for guessing the passwords of others.
+func (s *Service) loginSucceeded(ctx context.Context, email string) error {
+	if s.Throttle == nil {
+		return nil
+	}
+	return s.Throttle.ResetLoginFailures(ctx, accountThrottleKey(email))
+}
+
+// passwordResetRequested counts a password reset request against the email and the IP address,
+// which are limited like failed logins under keys of their own. It returns a
+// *PasswordResetLimitedError, without counting the request, while either is locked out.
+func (s *Service) passwordResetRequested(ctx context.Context, email, ip string) error {
+	if s.Throttle == nil {
+		return nil
+	}
+	limits := map[string]int{"reset:" + accountThrottleKey(email): s.ThrottlePolicy.AccountAttempts}
+	if ip != "" {
+		limits["reset:"+ipThrottleKey(ip)] = s.ThrottlePolicy.IPAttempts
+	}
+	now := s.now()
+	until, err := s.Throttle.LoginLockedUntil(ctx, slices.Collect(maps.Keys(limits)))
+	if err != nil {
+		return err
+	}
+	if now.Before(until) {
+		return &PasswordResetLimitedError{RetryAfter: until.Sub(now)}
+	}
+	for key, limit := range limits {
+		requests, err := s.Throttle.AddLoginFailure(ctx, key, now, now.Add(-forgetLoginFailuresAfter))
+		if err != nil {
+			return err
+		}
+		if lockout := s.ThrottlePolicy.lockout(requests, limit); lockout > 0 {
+			if err := s.Throttle.LockLogin(ctx, key, now.Add(lockout)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
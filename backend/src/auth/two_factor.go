@@ -0,0 +1,376 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// TwoFactorLoginTTL bounds the time between the password and the code of a login.
+	TwoFactorLoginTTL = 5 * time.Minute
+	// RecoveryCodeCount is how many recovery codes enabling two-factor authentication yields.
+	RecoveryCodeCount = 10
+
+	totpIssuer      = "KiKonect"
+	totpPeriod      = 30
+	totpDigits      = 6
+	totpSecretBytes = 20
+	// totpSkew is how many steps a code may lag or lead the clock.
+	totpSkew          = 1
+	recoveryCodeBytes = 10
+
+	purposeTwoFactorLogin = "two_factor_login"
+)
+
+var (
+	ErrNoTwoFactorStore     = errors.New("two-factor authentication is not configured")
+	ErrTwoFactorNotEnrolled = errors.New("two-factor authentication is not enrolled")
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactor is the TOTP enrollment of a user. Secret is base32 encoded.
+type TwoFactor struct {
+	Secret            string
+	Enabled           bool
+	LastUsedStep      int64
+	RecoveryCodesLeft int
+}
+
+// TwoFactorEnrollment is what an authenticator app needs to generate codes.
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// TwoFactorStatus tells whether a user has two-factor authentication enabled.
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// TwoFactorStore persists TOTP enrollments and hashed recovery codes. Looking up a user without
+// an enrollment returns ErrTwoFactorNotEnrolled.
+type TwoFactorStore interface {
+	TwoFactor(ctx context.Context, userID int64) (*TwoFactor, error)
+	// SaveTwoFactorSecret starts an enrollment, replacing one not yet enabled.
+	SaveTwoFactorSecret(ctx context.Context, userID int64, secret string) error
+	// EnableTwoFactor enables the pending enrollment with step used and the given recovery code
+	// hashes, and reports whether there was a pending enrollment.
+	EnableTwoFactor(ctx context.Context, userID int64, at time.Time, step int64, recoveryHashes []string) (bool, error)
+	// UseTwoFactorStep records step used unless a later or equal one already was, and reports
+	// whether it did.
+	UseTwoFactorStep(ctx context.Context, userID int64, step int64) (bool, error)
+	// UseRecoveryCode marks the unused recovery code with the given hash used and reports
+	// whether there was one.
+	UseRecoveryCode(ctx context.Context, userID int64, hash string, at time.Time) (bool, error)
+	DeleteTwoFactor(ctx context.Context, userID int64) error
+}
+
+// TOTPCode returns the RFC 6238 code of a base32 secret at t, with SHA-1, 6 digits and a 30
+// second period as authenticator apps expect.
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, totpStep(t)), nil
+}
+
+func totpStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	key, err := base32NoPadding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return nil, fmt.Errorf("decode totp secret: %w", err)
+	}
+	return key, nil
+}
+
+// hotp returns the RFC 4226 code of key at counter.
+func hotp(key []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1_000_000)
+}
+
+func (s *Service) twoFactorReady() error {
+	if s.TwoFactor == nil || s.Accounts == nil {
+		return ErrNoTwoFactorStore
+	}
+	return nil
+}
+
+// TwoFactorStatus returns whether a user has two-factor authentication enabled.
+func (s *Service) TwoFactorStatus(ctx context.Context, userID int64) (*TwoFactorStatus, error) {
+	if err := s.twoFactorReady(); err != nil {
+		return nil, err
+	}
+	enrollment, err := s.TwoFactor.TwoFactor(ctx, userID)
+	switch {
+	case errors.Is(err, ErrTwoFactorNotEnrolled):
+		return &TwoFactorStatus{}, nil
+	case err != nil:
+		return nil, err
+	case !enrollment.Enabled:
+		return &TwoFactorStatus{}, nil
+	}
+	return &TwoFactorStatus{Enabled: true, RecoveryCodesLeft: enrollment.RecoveryCodesLeft}, nil
+}
+
+// EnrollTwoFactor draws a new TOTP secret for a user. It only takes effect once
+// ActivateTwoFactor confirms a code generated from it.
+func (s *Service) EnrollTwoFactor(ctx context.Context, userID int64) (*TwoFactorEnrollment, error) {
+	if err := s.twoFactorReady(); err != nil {
+		return nil, err
+	}
+	enrollment, err := s.TwoFactor.TwoFactor(ctx, userID)
+	switch {
+	case errors.Is(err, ErrTwoFactorNotEnrolled):
+	case err != nil:
+		return nil, err
+	case enrollment.Enabled:
+		return nil, ErrTwoFactorEnabled
+	}
+	user, err := s.Accounts.UserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	raw := make([]byte, totpSecretBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	secret := base32NoPadding.EncodeToString(raw)
+	if err := s.TwoFactor.SaveTwoFactorSecret(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", totpIssuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + totpIssuer + ":" + user.Email,
+		RawQuery: query.Encode(),
+	}
+	return &TwoFactorEnrollment{Secret: secret, OTPAuthURI: uri.String()}, nil
+}
+
+// ActivateTwoFactor enables the pending enrollment of a user with a first code from their
+// authenticator app. It returns the recovery codes, shown only this once.
+func (s *Service) ActivateTwoFactor(ctx context.Context, userID int64, code string) ([]string, error) {
+	if err := s.twoFactorReady(); err != nil {
+		return nil, err
+	}
+	enrollment, err := s.TwoFactor.TwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	step, ok, err := matchTOTP(enrollment, code, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	codes := make([]string, 0, RecoveryCodeCount)
+	hashes := make([]string, 0, RecoveryCodeCount)
+	for range RecoveryCodeCount {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, HashToken(normalizeRecoveryCode(code)))
+	}
+	enabled, err := s.TwoFactor.EnableTwoFactor(ctx, userID, s.now(), step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off for a user, given a current code or an
+// unused recovery code.
+func (s *Service) DisableTwoFactor(ctx context.Context, userID int64, code string) error {
+	if err := s.twoFactorReady(); err != nil {
+		return err
+	}
+	if err := s.checkTwoFactorCode(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.TwoFactor.DeleteTwoFactor(ctx, userID)
+}
+
+// LoginChallenge returns a token for the second login step when the user has two-factor
+// authentication enabled, and an empty string when the password is enough.
+func (s *Service) LoginChallenge(ctx context.Context, userID int64) (string, error) {
+	if s.TwoFactor == nil {
+		return "", nil
+	}
+	if err := s.twoFactorReady(); err != nil {
+		return "", err
+	}
+	enrollment, err := s.TwoFactor.TwoFactor(ctx, userID)
+	switch {
+	case errors.Is(err, ErrTwoFactorNotEnrolled):
+		return "", nil
+	case err != nil:
+		return "", err
+	case !enrollment.Enabled:
+		return "", nil
+	}
+	token, err := newToken(accountTokenPrefix)
+	if err != nil {
+		return "", err
+	}
+	if err := s.Accounts.CreateAccountToken(ctx, userID, purposeTwoFactorLogin, HashToken(token), s.now().Add(TwoFactorLoginTTL)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// CompleteLogin finishes a login from the given IP address with the token of LoginChallenge and
+// a current code or an unused recovery code. The token works once, whether or not the code is
+// right, so guessing codes takes the password each time, and wrong codes count as failed logins.
+func (s *Service) CompleteLogin(ctx context.Context, challenge, code, ip string) (*User, error) {
+	if err := s.twoFactorReady(); err != nil {
+		return nil, err
+	}
+	userID, err := s.Accounts.ConsumeAccountToken(ctx, purposeTwoFactorLogin, HashToken(challenge), s.now())
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.Accounts.UserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLoginThrottle(ctx, user.Email, ip); err != nil {
+		s.auditLogin(ctx, user, user.Email, ip, "login.failed", loginFailureDetail(err))
+		return nil, err
+	}
+	err = s.checkTwoFactorCode(ctx, userID, code)
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		s.auditLogin(ctx, user, user.Email, ip, "login.failed", "invalid two-factor code")
+		if err := s.loginFailed(ctx, user.Email, ip); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidTwoFactorCode
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loginSucceeded(ctx, user.Email); err != nil {
+		return nil, err
+	}
+	s.auditLogin(ctx, user, user.Email, ip, "login.succeeded", "two-factor")
+	return user, nil
+}
+
+// checkTwoFactorCode accepts a TOTP code not used before or an unused recovery code of a user
+// with two-factor authentication enabled, and uses it up.
+func (s *Service) checkTwoFactorCode(ctx context.Context, userID int64, code string) error {
+	enrollment, err := s.TwoFactor.TwoFactor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enrollment.Enabled {
+		return ErrTwoFactorNotEnrolled
+	}
+	step, ok, err := matchTOTP(enrollment, code, s.now())
+	if err != nil {
+		return err
+	}
+	if ok {
+		used, err := s.TwoFactor.UseTwoFactorStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return ErrInvalidTwoFactorCode
+	}
+	used, err := s.TwoFactor.UseRecoveryCode(ctx, userID, HashToken(normalized), s.now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// matchTOTP returns the time step, within the allowed skew of now and after the last used one,
+// whose code is code.
+func matchTOTP(enrollment *TwoFactor, code string, now time.Time) (int64, bool, error) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != totpDigits {
+		return 0, false, nil
+	}
+	key, err := decodeTOTPSecret(enrollment.Secret)
+	if err != nil {
+		return 0, false, err
+	}
+	current := totpStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= enrollment.LastUsedStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step)), []byte(code)) == 1 {
+			return step, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// newRecoveryCode returns a random code formatted as four groups of four characters.
+func newRecoveryCode() (string, error) {
+	raw := make([]byte, recoveryCodeBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	encoded := base32NoPadding.EncodeToString(raw)
+	groups := make([]string, 0, len(encoded)/4)
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:i+4])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// normalizeRecoveryCode drops the separators and case of a recovery code as typed.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,317 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"area/src/mail"
+)
+
+// Roles of workspace members. Owners manage the members, editors change the workflows and
+// viewers only read them.
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+
+	WorkspaceInviteTTL = 7 * 24 * time.Hour
+
+	inviteTokenPrefix = "inv_"
+	maxWorkspaceName  = 100
+	// auditPageSize bounds the events returned by WorkspaceAudit and AuditEvents.
+	auditPageSize = 200
+)
+
+// Roles lists every role a workspace member may hold.
+var Roles = []string{RoleOwner, RoleEditor, RoleViewer}
+
+// Providers of OAuth connections that can be shared with a workspace or disconnected.
+var sharedProviders = []string{"google", "github"}
+
+var (
+	ErrNoWorkspaceStore   = errors.New("workspaces are not configured")
+	ErrWorkspaceNotFound  = errors.New("workspace not found")
+	ErrWorkspaceForbidden = errors.New("your workspace role does not allow this")
+	ErrMemberNotFound     = errors.New("workspace member not found")
+	ErrLastOwner          = errors.New("a workspace needs at least one owner")
+	ErrConnectionNotFound = errors.New("connection not found")
+)
+
+// Workspace is a workspace as one of its members sees it.
+type Workspace struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WorkspaceMember is a user with a role in a workspace.
+type WorkspaceMember struct {
+	UserID   int64     `json:"user_id"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// WorkspaceInvite invites an email address into a workspace. Its token is only mailed.
+type WorkspaceInvite struct {
+	ID          int64      `json:"id"`
+	WorkspaceID int64      `json:"workspace_id"`
+	Email       string     `json:"email"`
+	Role        string     `json:"role"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	AcceptedAt  *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// AuditEvent records who changed what in a workspace, or in their own account outside any, and
+// from where. Before and After hold the parts of the target the change touched, when it has any;
+// secret values and tokens are never among them.
+type AuditEvent struct {
+	ID          int64           `json:"id"`
+	WorkspaceID *int64          `json:"workspace_id"`
+	ActorID     *int64          `json:"actor_id"`
+	Action      string          `json:"action"`
+	Target      string          `json:"target"`
+	Detail      string          `json:"detail"`
+	IP          string          `json:"ip"`
+	UserAgent   string          `json:"user_agent"`
+	Before      json.RawMessage `json:"before,omitempty"`
+	After       json.RawMessage `json:"after,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// WorkspaceStore persists workspaces, their members, invites and audit log. Lookups of a
+// workspace the user does not belong to return ErrWorkspaceNotFound.
+type WorkspaceStore interface {
+	CreateWorkspace(ctx context.Context, name string, ownerID int64) (*Workspace, error)
+	ListWorkspaces(ctx context.Context, userID int64) ([]Workspace, error)
+	Workspace(ctx context.Context, id, userID int64) (*Workspace, error)
+	WorkspaceMembers(ctx context.Context, id int64) ([]WorkspaceMember, error)
+	// SetWorkspaceRole and RemoveWorkspaceMember return ErrMemberNotFound, or ErrLastOwner when
+	// the workspace would be left without an owner.
+	SetWorkspaceRole(ctx context.Context, id, userID int64, role string) error
+	RemoveWorkspaceMember(ctx context.Context, id, userID int64) error
+	CreateWorkspaceInvite(ctx context.Context, invite *WorkspaceInvite, invitedBy int64, hash string) error
+	// AcceptWorkspaceInvite marks the unused, unexpired invite addressed to the email of userID
+	// accepted and adds them to its workspace, or returns ErrInvalidToken.
+	AcceptWorkspaceInvite(ctx context.Context, hash string, userID int64, now time.Time) (*WorkspaceInvite, error)
+	// ShareConnection shares an OAuth connection of userID with a workspace, or returns
+	// ErrConnectionNotFound.
+	ShareConnection(ctx context.Context, provider string, id, userID, workspaceID int64) error
+	CreateAuditEvent(ctx context.Context, event *AuditEvent) error
+	WorkspaceAudit(ctx context.Context, id int64, limit int) ([]AuditEvent, error)
+}
+
+// CreateWorkspace creates a workspace owned by userID.
+func (s *Service) CreateWorkspace(ctx context.Context, userID int64, name string) (*Workspace, error) {
+	if s.Workspaces == nil {
+		return nil, ErrNoWorkspaceStore
+	}
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return nil, &ValidationError{Field: "name", Message: "is required"}
+	case len(name) > maxWorkspaceName:
+		return nil, &ValidationError{Field: "name", Message: fmt.Sprintf("must be at most %d characters", maxWorkspaceName)}
+	}
+	workspace, err := s.Workspaces.CreateWorkspace(ctx, name, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.recordEvent(ctx, workspace.ID, userID, "workspace.created", workspaceTarget(workspace.ID), name)
+	return workspace, nil
+}
+
+// ListWorkspaces returns the workspaces a user belongs to with their role in each.
+func (s *Service) ListWorkspaces(ctx context.Context, userID int64) ([]Workspace, error) {
+	if s.Workspaces == nil {
+		return nil, ErrNoWorkspaceStore
+	}
+	return s.Workspaces.ListWorkspaces(ctx, userID)
+}
+
+// WorkspaceMembers returns the members of a workspace userID belongs to.
+func (s *Service) WorkspaceMembers(ctx context.Context, userID, workspaceID int64) ([]WorkspaceMember, error) {
+	if _, err := s.requireRole(ctx, workspaceID, userID, Roles...); err != nil {
+		return nil, err
+	}
+	return s.Workspaces.WorkspaceMembers(ctx, workspaceID)
+}
+
+// InviteToWorkspace mails an invite to join a workspace with role to email. Only owners invite.
+func (s *Service) InviteToWorkspace(ctx context.Context, userID, workspaceID int64, email, role string) (*WorkspaceInvite, error) {
+	if s.Mailer == nil {
+		return nil, ErrNoWorkspaceStore
+	}
+	workspace, err := s.requireRole(ctx, workspaceID, userID, RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	email = strings.TrimSpace(email)
+	if email == "" || !strings.Contains(email, "@") {
+		return nil, &ValidationError{Field: "email", Message: "must be an email address"}
+	}
+	if err := validateRole(role); err != nil {
+		return nil, err
+	}
+	token, err := newToken(inviteTokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	invite := &WorkspaceInvite{
+		WorkspaceID: workspaceID,
+		Email:       email,
+		Role:        role,
+		ExpiresAt:   now.Add(WorkspaceInviteTTL),
+		CreatedAt:   now,
+	}
+	if err := s.Workspaces.CreateWorkspaceInvite(ctx, invite, userID, HashToken(token)); err != nil {
+		return nil, err
+	}
+	link := strings.TrimRight(s.AppURL, "/") + "/accept-invite?token=" + url.QueryEscape(token)
+	err = s.Mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: fmt.Sprintf("Join %s on KiKonect", workspace.Name),
+		Body: fmt.Sprintf("Hello,\n\nYou are invited to join the %s workspace as %s. Follow this link within %s "+
+			"and sign in with this email address to accept:\n\n%s\n", workspace.Name, role, WorkspaceInviteTTL, link),
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.recordEvent(ctx, workspaceID, userID, "member.invited", "invite:"+email, role)
+	return invite, nil
+}
+
+// AcceptWorkspaceInvite adds userID to the workspace of an invite addressed to their email.
+// A user who already belongs to the workspace keeps their role.
+func (s *Service) AcceptWorkspaceInvite(ctx context.Context, userID int64, token string) (*Workspace, error) {
+	if s.Workspaces == nil {
+		return nil, ErrNoWorkspaceStore
+	}
+	invite, err := s.Workspaces.AcceptWorkspaceInvite(ctx, HashToken(token), userID, s.now())
+	if err != nil {
+		return nil, err
+	}
+	s.recordEvent(ctx, invite.WorkspaceID, userID, "member.joined", userTarget(userID), invite.Role)
+	return s.Workspaces.Workspace(ctx, invite.WorkspaceID, userID)
+}
+
+// SetWorkspaceRole changes the role of a member. Only owners change roles.
+func (s *Service) SetWorkspaceRole(ctx context.Context, actorID, workspaceID, userID int64, role string) error {
+	if _, err := s.requireRole(ctx, workspaceID, actorID, RoleOwner); err != nil {
+		return err
+	}
+	if err := validateRole(role); err != nil {
+		return err
+	}
+	if err := s.Workspaces.SetWorkspaceRole(ctx, workspaceID, userID, role); err != nil {
+		return err
+	}
+	s.recordEvent(ctx, workspaceID, actorID, "member.role_changed", userTarget(userID), role)
+	return nil
+}
+
+// RemoveWorkspaceMember removes userID from a workspace. Owners remove anyone; any member may
+// leave.
+func (s *Service) RemoveWorkspaceMember(ctx context.Context, actorID, workspaceID, userID int64) error {
+	roles := []string{RoleOwner}
+	if actorID == userID {
+		roles = Roles
+	}
+	if _, err := s.requireRole(ctx, workspaceID, actorID, roles...); err != nil {
+		return err
+	}
+	if err := s.Workspaces.RemoveWorkspaceMember(ctx, workspaceID, userID); err != nil {
+		return err
+	}
+	s.recordEvent(ctx, workspaceID, actorID, "member.removed", userTarget(userID), "")
+	return nil
+}
+
+// ShareConnection shares an OAuth connection of userID with a workspace they edit, letting its
+// workflows run with it.
+func (s *Service) ShareConnection(ctx context.Context, userID, workspaceID int64, provider string, connectionID int64) error {
+	if !slices.Contains(sharedProviders, provider) {
+		return &ValidationError{Field: "provider", Message: fmt.Sprintf("must be among %s", strings.Join(sharedProviders, ", "))}
+	}
+	if _, err := s.requireRole(ctx, workspaceID, userID, RoleOwner, RoleEditor); err != nil {
+		return err
+	}
+	if err := s.Workspaces.ShareConnection(ctx, provider, connectionID, userID, workspaceID); err != nil {
+		return err
+	}
+	s.recordEvent(ctx, workspaceID, userID, "connection.shared", connectionTarget(provider, connectionID), "")
+	return nil
+}
+
+// WorkspaceAudit returns the latest events of a workspace userID belongs to, newest first.
+func (s *Service) WorkspaceAudit(ctx context.Context, userID, workspaceID int64) ([]AuditEvent, error) {
+	if _, err := s.requireRole(ctx, workspaceID, userID, Roles...); err != nil {
+		return nil, err
+	}
+	return s.Workspaces.WorkspaceAudit(ctx, workspaceID, auditPageSize)
+}
+
+// RecordWorkspaceEvent appends an event to the audit log of a workspace.
+func (s *Service) RecordWorkspaceEvent(ctx context.Context, workspaceID, actorID int64, action, target, detail string) error {
+	if s.Workspaces == nil {
+		return ErrNoWorkspaceStore
+	}
+	event := &AuditEvent{
+		WorkspaceID: &workspaceID,
+		ActorID:     &actorID,
+		Action:      action,
+		Target:      target,
+		Detail:      detail,
+	}
+	s.stampEvent(ctx, event)
+	return s.Workspaces.CreateAuditEvent(ctx, event)
+}
+
+// recordEvent records a workspace event. The change it describes is already made, so a failure
+// to record it is logged rather than failing the request.
+func (s *Service) recordEvent(ctx context.Context, workspaceID, actorID int64, action, target, detail string) {
+	if err := s.RecordWorkspaceEvent(ctx, workspaceID, actorID, action, target, detail); err != nil {
+		slog.ErrorContext(ctx, "record audit event", "error", err, "action", action, "workspace_id", workspaceID)
+	}
+}
+
+// requireRole returns a workspace of userID when their role in it is among roles,
+// ErrWorkspaceForbidden when it is not.
+func (s *Service) requireRole(ctx context.Context, workspaceID, userID int64, roles ...string) (*Workspace, error) {
+	if s.Workspaces == nil {
+		return nil, ErrNoWorkspaceStore
+	}
+	workspace, err := s.Workspaces.Workspace(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(roles, workspace.Role) {
+		return nil, ErrWorkspaceForbidden
+	}
+	return workspace, nil
+}
+
+func validateRole(role string) error {
+	if !slices.Contains(Roles, role) {
+		return &ValidationError{Field: "role", Message: fmt.Sprintf("must be among %s", strings.Join(Roles, ", "))}
+	}
+	return nil
+}
+
+func workspaceTarget(id int64) string {
+	return fmt.Sprintf("workspace:%d", id)
+}
+
+func userTarget(id int64) string {
+	return fmt.Sprintf("user:%d", id)
+}
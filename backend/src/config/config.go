@@ -0,0 +1,475 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds every server setting. Values come from Default, then an optional JSON file,
+// then environment variables; the env tag of a field names its variable (nested structs add a
+// prefix) and fields tagged secret are hidden by Redacted.
+type Config struct {
+	Port       string `json:"port" env:"PORT"`
+	BcryptCost int    `json:"bcrypt_cost" env:"BCRYPT_COST"`
+	SecretKey  string `json:"secret_key" env:"APP_SECRET_KEY" secret:"true"`
+	AdminToken string `json:"admin_token" env:"ADMIN_TOKEN" secret:"true"`
+	// SecretKeyID tags the values encrypted with SecretKey; PreviousSecretKeys lists the id:key
+	// pairs of retired keys, comma-separated, still accepted for decryption.
+	SecretKeyID        string `json:"secret_key_id" env:"APP_SECRET_KEY_ID"`
+	PreviousSecretKeys string `json:"previous_secret_keys" env:"APP_PREVIOUS_SECRET_KEYS" secret:"true"`
+	// ServerURL is the base URL at which workflows call the reactions of this server, by default
+	// http://localhost:<Port>. Only calls under it carry the reaction token of the workflow's user.
+	ServerURL string `json:"server_url" env:"SERVER_URL"`
+	// TrustedProxies lists the addresses or CIDR ranges, comma-separated, of the reverse proxies
+	// whose X-Forwarded-For header tells the client address.
+	TrustedProxies string `json:"trusted_proxies" env:"TRUSTED_PROXIES"`
+
+	Database     Database     `json:"database"`
+	Auth         Auth         `json:"auth" env:"AUTH"`
+	Mail         Mail         `json:"mail" env:"MAIL"`
+	Log          Log          `json:"log" env:"LOG"`
+	Workflows    Workflows    `json:"workflows"`
+	Google       OAuth        `json:"google" env:"GOOGLE_OAUTH"`
+	GitHub       OAuth        `json:"github" env:"GITHUB_OAUTH"`
+	GitHubMobile OAuth        `json:"github_mobile" env:"GITHUB_MOBILE_OAUTH"`
+	Integrations Integrations `json:"integrations"`
+}
+
+// Database selects the storage backend: PostgreSQL (the default) or SQLite, a single file
+// needing no database server, for local development and tests.
+type Database struct {
+	Driver     string `json:"driver" env:"DATABASE_DRIVER"`
+	SQLitePath string `json:"sqlite_path" env:"SQLITE_PATH"`
+
+	Host     string `json:"host" env:"POSTGRES_HOST"`
+	Port     string `json:"port" env:"POSTGRES_PORT"`
+	User     string `json:"user" env:"POSTGRES_USER"`
+	Password string `json:"password" env:"POSTGRES_PASSWORD" secret:"true"`
+	Name     string `json:"name" env:"POSTGRES_DB"`
+	SSLMode  string `json:"sslmode" env:"POSTGRES_SSLMODE"`
+}
+
+// Database drivers.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Auth holds the lifetimes of session tokens and the login throttling policy. DevUserHeader
+// trusts the X-User-ID header and the user_id query parameter instead, so any caller can act as
+// any user; it is for local development only.
+type Auth struct {
+	AccessTokenMinutes int  `json:"access_token_minutes" env:"ACCESS_TOKEN_MINUTES"`
+	RefreshTokenDays   int  `json:"refresh_token_days" env:"REFRESH_TOKEN_DAYS"`
+	DevUserHeader      bool `json:"dev_user_header" env:"DEV_USER_HEADER"`
+	// RequireVerifiedEmail blocks users from creating workflows until they verify their email.
+	RequireVerifiedEmail bool `json:"require_verified_email" env:"REQUIRE_VERIFIED_EMAIL"`
+	// An account or IP address is locked out after this many failed logins in a row, for
+	// LoginLockoutSeconds doubling with each further failure up to LoginMaxLockoutMinutes.
+	LoginAccountAttempts   int `json:"login_account_attempts" env:"LOGIN_ACCOUNT_ATTEMPTS"`
+	LoginIPAttempts        int `json:"login_ip_attempts" env:"LOGIN_IP_ATTEMPTS"`
+	LoginLockoutSeconds    int `json:"login_lockout_seconds" env:"LOGIN_LOCKOUT_SECONDS"`
+	LoginMaxLockoutMinutes int `json:"login_max_lockout_minutes" env:"LOGIN_MAX_LOCKOUT_MINUTES"`
+}
+
+// AccessTTL is how long an access token is valid.
+func (a Auth) AccessTTL() time.Duration {
+	return time.Duration(a.AccessTokenMinutes) * time.Minute
+}
+
+// RefreshTTL is how long a refresh token is valid.
+func (a Auth) RefreshTTL() time.Duration {
+	return time.Duration(a.RefreshTokenDays) * 24 * time.Hour
+}
+
+// LoginLockout is the first lockout after too many failed logins.
+func (a Auth) LoginLockout() time.Duration {
+	return time.Duration(a.LoginLockoutSeconds) * time.Second
+}
+
+// LoginMaxLockout bounds the lockout after failed logins.
+func (a Auth) LoginMaxLockout() time.Duration {
+	return time.Duration(a.LoginMaxLockoutMinutes) * time.Minute
+}
+
+// Mail selects how emails are sent: through an SMTP server, written as .eml files to FileDir, or
+// logged, for development. Emailed links point to pages of the web app at AppURL.
+type Mail struct {
+	Driver       string `json:"driver" env:"DRIVER"`
+	From         string `json:"from" env:"FROM"`
+	AppURL       string `json:"app_url" env:"APP_URL"`
+	FileDir      string `json:"file_dir" env:"FILE_DIR"`
+	SMTPHost     string `json:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     string `json:"smtp_port" env:"SMTP_PORT"`
+	SMTPUsername string `json:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `json:"smtp_password" env:"SMTP_PASSWORD" secret:"true"`
+}
+
+// Mail drivers.
+const (
+	MailSMTP = "smtp"
+	MailFile = "file"
+	MailLog  = "log"
+)
+
+// Log selects the level (debug, info, warn, error) and format (json, text) of the logs.
+type Log struct {
+	Level  string `json:"level" env:"LEVEL"`
+	Format string `json:"format" env:"FORMAT"`
+}
+
+// Workflows holds the limits of the executor and of the shutdown sequence, in seconds.
+type Workflows struct {
+	MaxTimeoutSeconds    int `json:"max_timeout_seconds" env:"WORKFLOW_MAX_TIMEOUT_SECONDS"`
+	ShutdownGraceSeconds int `json:"shutdown_grace_seconds" env:"SHUTDOWN_GRACE_SECONDS"`
+}
+
+// MaxTimeout caps the timeout_seconds of a workflow.
+func (w Workflows) MaxTimeout() time.Duration {
+	return time.Duration(w.MaxTimeoutSeconds) * time.Second
+}
+
+// ShutdownGrace is how long in-flight work gets to finish on shutdown.
+func (w Workflows) ShutdownGrace() time.Duration {
+	return time.Duration(w.ShutdownGraceSeconds) * time.Second
+}
+
+// OAuth holds the credentials of an OAuth application.
+type OAuth struct {
+	ClientID     string `json:"client_id" env:"CLIENT_ID"`
+	ClientSecret string `json:"client_secret" env:"CLIENT_SECRET" secret:"true"`
+	RedirectURI  string `json:"redirect_uri" env:"REDIRECT_URI"`
+}
+
+// Enabled reports whether the application is configured; integrations relying on it are off otherwise.
+func (o OAuth) Enabled() bool {
+	return o.ClientID != "" && o.ClientSecret != ""
+}
+
+// Integrations holds the server-wide credentials of optional integrations.
+// Actions of bot integrations may still pass their own token per request.
+type Integrations struct {
+	DiscordBotToken string `json:"discord_bot_token" env:"DISCORD_BOT_TOKEN" secret:"true"`
+	SlackBotToken   string `json:"slack_bot_token" env:"SLACK_BOT_TOKEN" secret:"true"`
+	NotionToken     string `json:"notion_token" env:"NOTION_TOKEN" secret:"true"`
+	TrelloAPIKey    string `json:"trello_api_key" env:"TRELLO_API_KEY" secret:"true"`
+	TrelloToken     string `json:"trello_token" env:"TRELLO_TOKEN" secret:"true"`
+	SteamAPIKey     string `json:"steam_api_key" env:"STEAM_API_KEY" secret:"true"`
+	NASAAPIKey      string `json:"nasa_api_key" env:"NASA_API_KEY" secret:"true"`
+}
+
+// ServerBaseURL returns ServerURL, or the local address of the server when it is unset.
+func (c *Config) ServerBaseURL() string {
+	if c.ServerURL != "" {
+		return strings.TrimRight(c.ServerURL, "/")
+	}
+	return "http://localhost:" + c.Port
+}
+
+// IsServerURL reports whether target is under ServerBaseURL: same scheme and host, and a path
+// below its path.
+func (c *Config) IsServerURL(target *url.URL) bool {
+	base, err := url.Parse(c.ServerBaseURL())
+	if err != nil || target == nil {
+		return false
+	}
+	if !strings.EqualFold(base.Scheme, target.Scheme) || !strings.EqualFold(base.Host, target.Host) {
+		return false
+	}
+	return target.Path == base.Path || strings.HasPrefix(target.Path, base.Path+"/")
+}
+
+// TrustedProxyPrefixes parses TrustedProxies; a bare address is a range of one.
+func (c *Config) TrustedProxyPrefixes() ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(c.TrustedProxies, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: %q is not an IP address or CIDR range", entry)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// Default returns the settings used when neither the file nor the environment sets a value.
+func Default() *Config {
+	return &Config{
+		Port:       "8080",
+		BcryptCost: 10,
+		Database:   Database{Driver: DriverPostgres, SQLitePath: "area.db", SSLMode: "disable"},
+		Auth: Auth{
+			AccessTokenMinutes:     15,
+			RefreshTokenDays:       30,
+			LoginAccountAttempts:   5,
+			LoginIPAttempts:        20,
+			LoginLockoutSeconds:    30,
+			LoginMaxLockoutMinutes: 60,
+		},
+		Mail: Mail{
+			Driver:   MailLog,
+			From:     "KiKonect <no-reply@localhost>",
+			AppURL:   "http://localhost:8081",
+			FileDir:  "mail",
+			SMTPPort: "587",
+		},
+		Log: Log{Level: "info", Format: "json"},
+		Workflows: Workflows{
+			MaxTimeoutSeconds:    120,
+			ShutdownGraceSeconds: 30,
+		},
+	}
+}
+
+// Load reads the settings like Read and validates them. The error lists every problem found.
+func Load(path string) (*Config, error) {
+	cfg, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Read reads the JSON file at path (skipped when path is empty) and applies the environment on
+// top, without validating the result.
+func Read(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnv(reflect.ValueOf(cfg).Elem(), ""); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides the fields of v whose variable is set and not empty.
+func applyEnv(v reflect.Value, prefix string) error {
+	var errs []error
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		name := field.Tag.Get("env")
+		if field.Type.Kind() == reflect.Struct {
+			nested := prefix
+			if name != "" {
+				nested = prefix + name + "_"
+			}
+			if err := applyEnv(value, nested); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if name == "" {
+			continue
+		}
+		key := prefix + name
+		raw := strings.TrimSpace(os.Getenv(key))
+		if raw == "" {
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.String:
+			value.SetString(raw)
+		case reflect.Int:
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a whole number", key, raw))
+				continue
+			}
+			value.SetInt(int64(n))
+		case reflect.Bool:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not true or false", key, raw))
+				continue
+			}
+			value.SetBool(b)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the settings and returns every problem found.
+func (c *Config) Validate() error {
+	var errs []error
+	require := func(value, key string) {
+		if strings.TrimSpace(value) == "" {
+			errs = append(errs, fmt.Errorf("%s is required", key))
+		}
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port <= 0 || port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT: %q is not a valid port", c.Port))
+	}
+	switch c.Database.Driver {
+	case DriverPostgres:
+		require(c.Database.Host, "POSTGRES_HOST")
+		require(c.Database.Port, "POSTGRES_PORT")
+		require(c.Database.User, "POSTGRES_USER")
+		require(c.Database.Password, "POSTGRES_PASSWORD")
+		require(c.Database.Name, "POSTGRES_DB")
+	case DriverSQLite:
+		require(c.Database.SQLitePath, "SQLITE_PATH")
+	default:
+		errs = append(errs, fmt.Errorf("DATABASE_DRIVER: %q is not one of postgres, sqlite", c.Database.Driver))
+	}
+
+	if c.BcryptCost < 4 || c.BcryptCost > 31 {
+		errs = append(errs, fmt.Errorf("BCRYPT_COST: %d is outside 4..31", c.BcryptCost))
+	}
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_LEVEL: %q is not one of debug, info, warn, error", c.Log.Level))
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "json", "text":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_FORMAT: %q is not one of json, text", c.Log.Format))
+	}
+	if c.Auth.AccessTokenMinutes <= 0 {
+		errs = append(errs, errors.New("AUTH_ACCESS_TOKEN_MINUTES must be positive"))
+	}
+	if c.Auth.RefreshTokenDays <= 0 {
+		errs = append(errs, errors.New("AUTH_REFRESH_TOKEN_DAYS must be positive"))
+	}
+	for _, setting := range []struct {
+		key   string
+		value int
+	}{
+		{"AUTH_LOGIN_ACCOUNT_ATTEMPTS", c.Auth.LoginAccountAttempts},
+		{"AUTH_LOGIN_IP_ATTEMPTS", c.Auth.LoginIPAttempts},
+		{"AUTH_LOGIN_LOCKOUT_SECONDS", c.Auth.LoginLockoutSeconds},
+		{"AUTH_LOGIN_MAX_LOCKOUT_MINUTES", c.Auth.LoginMaxLockoutMinutes},
+	} {
+		if setting.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", setting.key))
+		}
+	}
+	if c.ServerURL != "" {
+		if u, err := url.Parse(c.ServerURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("SERVER_URL: %q is not an http(s) URL", c.ServerURL))
+		}
+	}
+	if _, err := c.TrustedProxyPrefixes(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Auth.LoginMaxLockout() < c.Auth.LoginLockout() {
+		errs = append(errs, errors.New("AUTH_LOGIN_MAX_LOCKOUT_MINUTES must not be shorter than AUTH_LOGIN_LOCKOUT_SECONDS"))
+	}
+	switch c.Mail.Driver {
+	case MailSMTP:
+		require(c.Mail.SMTPHost, "MAIL_SMTP_HOST")
+		require(c.Mail.SMTPPort, "MAIL_SMTP_PORT")
+	case MailFile:
+		require(c.Mail.FileDir, "MAIL_FILE_DIR")
+	case MailLog:
+	default:
+		errs = append(errs, fmt.Errorf("MAIL_DRIVER: %q is not one of smtp, file, log", c.Mail.Driver))
+	}
+	require(c.Mail.From, "MAIL_FROM")
+	if u, err := url.Parse(c.Mail.AppURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("MAIL_APP_URL: %q is not an http(s) URL", c.Mail.AppURL))
+	}
+	if c.Workflows.MaxTimeoutSeconds <= 0 {
+		errs = append(errs, errors.New("WORKFLOW_MAX_TIMEOUT_SECONDS must be positive"))
+	}
+	if c.Workflows.ShutdownGraceSeconds <= 0 {
+		errs = append(errs, errors.New("SHUTDOWN_GRACE_SECONDS must be positive"))
+	}
+
+	for _, app := range []struct {
+		prefix string
+		oauth  OAuth
+	}{
+		{"GOOGLE_OAUTH", c.Google},
+		{"GITHUB_OAUTH", c.GitHub},
+		{"GITHUB_MOBILE_OAUTH", c.GitHubMobile},
+	} {
+		if (app.oauth.ClientID == "") != (app.oauth.ClientSecret == "") {
+			errs = append(errs, fmt.Errorf("%s_CLIENT_ID and %s_CLIENT_SECRET must be set together", app.prefix, app.prefix))
+		}
+	}
+	if (c.Integrations.TrelloAPIKey == "") != (c.Integrations.TrelloToken == "") {
+		errs = append(errs, errors.New("TRELLO_API_KEY and TRELLO_TOKEN must be set together"))
+	}
+	return errors.Join(errs...)
+}
+
+// redactedValue replaces a secret that is set.
+const redactedValue = "[redacted]"
+
+// Redacted returns the effective settings keyed like the config file, with secrets hidden.
+// An unset secret shows as an empty string so a missing credential stays visible.
+func (c *Config) Redacted() map[string]any {
+	return redact(reflect.ValueOf(c).Elem())
+}
+
+func redact(v reflect.Value) map[string]any {
+	out := make(map[string]any)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		key := strings.Split(field.Tag.Get("json"), ",")[0]
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			out[key] = redact(value)
+		case field.Tag.Get("secret") == "true" && !value.IsZero():
+			out[key] = redactedValue
+		default:
+			out[key] = value.Interface()
+		}
+	}
+	return out
+}
+
+// EnabledIntegrations reports which optional integrations have server-side credentials.
+func (c *Config) EnabledIntegrations() map[string]bool {
+	return map[string]bool{
+		"google":        c.Google.Enabled(),
+		"github":        c.GitHub.Enabled(),
+		"github_mobile": c.GitHubMobile.Enabled(),
+		"discord":       c.Integrations.DiscordBotToken != "",
+		"slack":         c.Integrations.SlackBotToken != "",
+		"notion":        c.Integrations.NotionToken != "",
+		"trello":        c.Integrations.TrelloAPIKey != "",
+		"steam":         c.Integrations.SteamAPIKey != "",
+		"nasa":          c.Integrations.NASAAPIKey != "",
+	}
+}
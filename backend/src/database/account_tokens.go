@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreateAccountToken inserts an account token and sets its ID.
+func CreateAccountToken(ctx context.Context, token *AccountToken) error {
+	if err := gorm.G[AccountToken](Db).Create(ctx, token); err != nil {
+		return fmt.Errorf("create account token: %w", err)
+	}
+	return nil
+}
+
+// ConsumeAccountToken marks the unused, unexpired token with the given purpose and hash used and
+// returns its user. It returns gorm.ErrRecordNotFound when there is no such token, so a token
+// works once even under concurrent requests.
+func ConsumeAccountToken(ctx context.Context, purpose, hash string, now time.Time) (uint, error) {
+	var userID uint
+	err := Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		token, err := gorm.G[AccountToken](tx).
+			Where("purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?", purpose, hash, now).
+			First(ctx)
+		if err != nil {
+			return err
+		}
+		rows, err := gorm.G[AccountToken](tx).Where("id = ? AND used_at IS NULL", token.ID).Update(ctx, "used_at", now)
+		if err != nil {
+			return err
+		}
+		if rows != 1 {
+			return gorm.ErrRecordNotFound
+		}
+		userID = token.UserID
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("consume account token: %w", err)
+	}
+	return userID, nil
+}
+
+// SetPasswordHash replaces the password hash of a user.
+func SetPasswordHash(ctx context.Context, userID uint, hash string) error {
+	if _, err := gorm.G[User](Db).Where("id = ?", userID).Update(ctx, "password_hash", hash); err != nil {
+		return fmt.Errorf("set password hash: %w", err)
+	}
+	return nil
+}
+
+// MarkEmailVerified records when the email address of a user was verified, keeping the first time.
+func MarkEmailVerified(ctx context.Context, userID uint, at time.Time) error {
+	_, err := gorm.G[User](Db).Where("id = ? AND email_verified_at IS NULL", userID).Update(ctx, "email_verified_at", at)
+	if err != nil {
+		return fmt.Errorf("mark email verified: %w", err)
+	}
+	return nil
+}
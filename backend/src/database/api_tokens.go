@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreateAPIToken inserts a personal access token and sets its ID.
+func CreateAPIToken(ctx context.Context, token *APIToken) error {
+	if err := gorm.G[APIToken](Db).Create(ctx, token); err != nil {
+		return fmt.Errorf("create api token: %w", err)
+	}
+	return nil
+}
+
+// ListAPITokens returns the personal access tokens of a user, newest first.
+func ListAPITokens(ctx context.Context, userID uint) ([]APIToken, error) {
+	tokens, err := gorm.G[APIToken](Db).Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list api tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// GetAPITokenByHash returns the personal access token with the given hash.
+func GetAPITokenByHash(ctx context.Context, hash string) (*APIToken, error) {
+	token, err := gorm.G[APIToken](Db).Where("token_hash = ?", hash).First(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get api token: %w", err)
+	}
+	return &token, nil
+}
+
+// TouchAPIToken records that a token was used at the given time.
+func TouchAPIToken(ctx context.Context, id uint, at time.Time) error {
+	if _, err := gorm.G[APIToken](Db).Where("id = ?", id).Update(ctx, "last_used_at", at); err != nil {
+		return fmt.Errorf("touch api token: %w", err)
+	}
+	return nil
+}
+
+// DeleteAPIToken deletes a token of a user and reports whether it existed.
+func DeleteAPIToken(ctx context.Context, userID, id uint) (bool, error) {
+	rows, err := gorm.G[APIToken](Db).Where("id = ? AND user_id = ?", id, userID).Delete(ctx)
+	if err != nil {
+		return false, fmt.Errorf("delete api token: %w", err)
+	}
+	return rows > 0, nil
+}
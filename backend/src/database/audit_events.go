@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AuditFilter selects audit events. Zero fields match every event; an Action ending in "."
+// matches every action it prefixes, such as "workflow.".
+type AuditFilter struct {
+	ActorID     uint
+	WorkspaceID uint
+	Action      string
+	Target      string
+	Since       time.Time
+	Until       time.Time
+	// BeforeID pages back through the log: only events older than it match.
+	BeforeID uint
+	Limit    int
+}
+
+// ListAuditEvents returns the latest events matching filter, newest first.
+func ListAuditEvents(ctx context.Context, filter AuditFilter) ([]AuditEvent, error) {
+	query := Db.WithContext(ctx).Model(&AuditEvent{})
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.WorkspaceID != 0 {
+		query = query.Where("workspace_id = ?", filter.WorkspaceID)
+	}
+	if prefix, ok := strings.CutSuffix(filter.Action, "."); ok && prefix != "" {
+		query = query.Where("action LIKE ?", prefix+".%")
+	} else if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Target != "" {
+		query = query.Where("target = ?", filter.Target)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+	if filter.BeforeID != 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+	var events []AuditEvent
+	if err := query.Order("id DESC").Limit(filter.Limit).Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("list audit events: %w", err)
+	}
+	return events, nil
+}
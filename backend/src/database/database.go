@@ -8,6 +8,9 @@ import (
 	"os"
 	"time"
 
+	"area/src/config"
+
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -17,18 +20,9 @@ var sqlDB *sql.DB
 var Db *gorm.DB
 var dbContext context.Context
 
-// Connect initializes the shared PostgreSQL connection using environment variables.
-func Connect() {
-	host := mustEnv("POSTGRES_HOST")
-	port := mustEnv("POSTGRES_PORT")
-	user := mustEnv("POSTGRES_USER")
-	password := mustEnv("POSTGRES_PASSWORD")
-	dbname := mustEnv("POSTGRES_DB")
-	sslmode := FirstNonEmpty(os.Getenv("POSTGRES_SSLMODE"), "disable")
-
-	dsn := fmt.Sprintf("host=%s port=%s user=%s "+
-		"password=%s dbname=%s sslmode=%s",
-		host, port, user, password, dbname, sslmode)
+// Connect initializes the shared connection to the configured PostgreSQL or SQLite database.
+// The schema is managed by the migrations in migrate.go, see CheckMigrated.
+func Connect(cfg config.Database) {
 	var err error
 
 	gormLogger := logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
@@ -38,7 +32,7 @@ func Connect() {
 		Colorful:                  true,
 	})
 
-	Db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
+	Db, err = gorm.Open(Dialector(cfg), &gorm.Config{
 		Logger: gormLogger,
 	})
 	if err != nil {
@@ -49,17 +43,34 @@ func Connect() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if cfg.Driver == config.DriverSQLite && cfg.SQLitePath == ":memory:" {
+		// Every connection to ":memory:" opens a database of its own.
+		sqlDB.SetMaxOpenConns(1)
+	}
 
 	dbContext = context.Background()
-	Db.AutoMigrate(&User{})
-	Db.AutoMigrate(&GoogleToken{})
-	Db.AutoMigrate(&GithubToken{})
-	Db.AutoMigrate(&AreaService{})
-	Db.AutoMigrate(&AreaCapability{})
-	Db.AutoMigrate(&AreaField{})
-	Db.AutoMigrate(&Job{})
-	Db.AutoMigrate(&Run{})
-	Db.AutoMigrate(&Workflow{})
+}
+
+// Dialector returns the GORM dialector of the configured driver.
+//
+// SQLite runs in WAL mode with a busy timeout, and its transactions take the write lock as
+// they begin: a claim done with SELECT ... FOR UPDATE SKIP LOCKED on PostgreSQL is serialized
+// by the transaction instead, see IsSQLite.
+func Dialector(cfg config.Database) gorm.Dialector {
+	if cfg.Driver == config.DriverSQLite {
+		dsn := cfg.SQLitePath + "?_txlock=immediate" +
+			"&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+		return sqlite.Open(dsn)
+	}
+	dsn := fmt.Sprintf("host=%s port=%s user=%s "+
+		"password=%s dbname=%s sslmode=%s",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name, FirstNonEmpty(cfg.SSLMode, "disable"))
+	return postgres.Open(dsn)
+}
+
+// IsSQLite reports whether db talks to SQLite, which lacks row locks and the jsonb operators.
+func IsSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == "sqlite"
 }
 
 // Disconnect closes the database connection if it is open.
@@ -90,15 +101,6 @@ func GetDBContext() context.Context {
 	return dbContext
 }
 
-// mustEnv reads an environment variable or exits if it is missing.
-func mustEnv(key string) string {
-	val := os.Getenv(key)
-	if val == "" {
-		log.Fatalf("missing required env var %s", key)
-	}
-	return val
-}
-
 // FirstNonEmpty returns the first non-empty string in the provided list.
 func FirstNonEmpty(values ...string) string {
 	for _, v := range values {
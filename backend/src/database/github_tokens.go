@@ -7,8 +7,13 @@ import (
 	"gorm.io/gorm"
 )
 
-// InsertGithubToken persists a GitHub access token and returns its ID.
+// InsertGithubToken persists a GitHub access token, encrypted when APP_SECRET_KEY is set, and
+// returns its ID.
 func InsertGithubToken(userID *int64, accessToken, tokenType, scope string) (int64, error) {
+	accessToken, err := sealToken(accessToken)
+	if err != nil {
+		return -1, fmt.Errorf("insert github token: %w", err)
+	}
 	token := &GithubToken{
 		UserID:      userID,
 		AccessToken: accessToken,
@@ -16,7 +21,7 @@ func InsertGithubToken(userID *int64, accessToken, tokenType, scope string) (int
 		Scope:       scope,
 	}
 
-	err := gorm.G[GithubToken](Db).Create(GetDBContext(), token)
+	err = gorm.G[GithubToken](Db).Create(GetDBContext(), token)
 	if err != nil {
 		return -1, fmt.Errorf("insert github token: %w", err)
 	}
@@ -31,17 +36,25 @@ func GetGithubToken(id int64) (*GithubToken, error) {
 	if err != nil {
 		return nil, fmt.Errorf("get github token: %w", err)
 	}
+	if err := openGithubToken(&token); err != nil {
+		return nil, fmt.Errorf("get github token: %w", err)
+	}
 	return &token, nil
 }
 
-// GetGithubTokenForUser fetches a token if it belongs to the given user.
+// GetGithubTokenForUser fetches a token if it belongs to the given user or is shared with one of
+// the workspaces they own or edit. Viewers only see the workflows of a workspace and cannot act
+// with its tokens.
 func GetGithubTokenForUser(id int64, userID int64) (*GithubToken, error) {
 	var token GithubToken
 
-	token, err := gorm.G[GithubToken](Db).Where("id = ? AND user_id = ?", id, userID).First(GetDBContext())
+	token, err := gorm.G[GithubToken](Db).Where("id = ? AND (user_id = ? OR workspace_id IN (?))", id, userID, memberWorkspaces(userID, RoleOwner, RoleEditor)).First(GetDBContext())
 	if err != nil {
 		return nil, fmt.Errorf("get github token for user: %w", err)
 	}
+	if err := openGithubToken(&token); err != nil {
+		return nil, fmt.Errorf("get github token for user: %w", err)
+	}
 	return &token, nil
 }
 
@@ -53,5 +66,28 @@ func GetLatestGithubTokenForUser(ctx context.Context, userID int64) (*GithubToke
 	if err != nil {
 		return nil, fmt.Errorf("get latest github token for user: %w", err)
 	}
+	if err := openGithubToken(&token); err != nil {
+		return nil, fmt.Errorf("get latest github token for user: %w", err)
+	}
 	return &token, nil
 }
+
+// DeleteGithubToken deletes a token of a user for good, dropping its credentials, and reports
+// whether the user had it.
+func DeleteGithubToken(ctx context.Context, id, userID int64) (bool, error) {
+	result := Db.WithContext(ctx).Unscoped().Where("id = ? AND user_id = ?", id, userID).Delete(&GithubToken{})
+	if result.Error != nil {
+		return false, fmt.Errorf("delete github token: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ShareGithubToken shares a token of a user with a workspace, reporting false when the user has
+// no such token.
+func ShareGithubToken(ctx context.Context, id, userID, workspaceID int64) (bool, error) {
+	rows, err := gorm.G[GithubToken](Db).Where("id = ? AND user_id = ?", id, userID).Update(ctx, "workspace_id", workspaceID)
+	if err != nil {
+		return false, fmt.Errorf("share github token: %w", err)
+	}
+	return rows == 1, nil
+}
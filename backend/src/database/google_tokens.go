@@ -8,8 +8,12 @@ import (
 	"gorm.io/gorm"
 )
 
-// InsertGoogleToken persists a new token and returns its ID.
+// InsertGoogleToken persists a new token, encrypted when APP_SECRET_KEY is set, and returns its ID.
 func InsertGoogleToken(userID *int64, access, refresh string, expiry time.Time) (int64, error) {
+	access, refresh, err := sealGoogleTokens(access, refresh)
+	if err != nil {
+		return -1, fmt.Errorf("insert google token: %w", err)
+	}
 	token := &GoogleToken{
 		UserID:       userID,
 		AccessToken:  access,
@@ -17,7 +21,7 @@ func InsertGoogleToken(userID *int64, access, refresh string, expiry time.Time)
 		Expiry:       expiry,
 	}
 
-	err := gorm.G[GoogleToken](Db).Create(GetDBContext(), token)
+	err = gorm.G[GoogleToken](Db).Create(GetDBContext(), token)
 	if err != nil {
 		return -1, fmt.Errorf("insert google token: %w", err)
 	}
@@ -32,29 +36,41 @@ func GetGoogleToken(id int64) (*GoogleToken, error) {
 	if err != nil {
 		return nil, fmt.Errorf("get google token: %w", err)
 	}
+	if err := openGoogleToken(&t); err != nil {
+		return nil, fmt.Errorf("get google token: %w", err)
+	}
 	return &t, nil
 }
 
-// GetGoogleTokenForUser fetches a token only if it matches the provided user id.
+// GetGoogleTokenForUser fetches a token only if it belongs to the user or is shared with one of
+// the workspaces they own or edit. Viewers only see the workflows of a workspace and cannot act
+// with its tokens.
 func GetGoogleTokenForUser(id int64, userID int64) (*GoogleToken, error) {
 	var t GoogleToken
 
-	t, err := gorm.G[GoogleToken](Db).Where("id = ? AND user_id = ?", id, userID).First(GetDBContext())
+	t, err := gorm.G[GoogleToken](Db).Where("id = ? AND (user_id = ? OR workspace_id IN (?))", id, userID, memberWorkspaces(userID, RoleOwner, RoleEditor)).First(GetDBContext())
 	if err != nil {
 		return nil, fmt.Errorf("get google token for user: %w", err)
 	}
+	if err := openGoogleToken(&t); err != nil {
+		return nil, fmt.Errorf("get google token for user: %w", err)
+	}
 	return &t, nil
 }
 
 // UpdateGoogleToken stores new access/refresh tokens and expiry for a token row.
 func UpdateGoogleToken(id int64, access, refresh string, expiry time.Time) error {
+	access, refresh, err := sealGoogleTokens(access, refresh)
+	if err != nil {
+		return fmt.Errorf("update google token: %w", err)
+	}
 	token := &GoogleToken{
 		AccessToken:  access,
 		RefreshToken: refresh,
 		Expiry:       expiry,
 	}
 
-	_, err := gorm.G[GoogleToken](Db).Where("id = ?", id).Updates(GetDBContext(), *token)
+	_, err = gorm.G[GoogleToken](Db).Where("id = ?", id).Updates(GetDBContext(), *token)
 	if err != nil {
 		return fmt.Errorf("update google token: %w", err)
 	}
@@ -69,5 +85,41 @@ func GetLatestGoogleTokenForUser(ctx context.Context, userID int64) (*GoogleToke
 	if err != nil {
 		return nil, fmt.Errorf("get latest google token for user: %w", err)
 	}
+	if err := openGoogleToken(&t); err != nil {
+		return nil, fmt.Errorf("get latest google token for user: %w", err)
+	}
 	return &t, nil
 }
+
+// DeleteGoogleToken deletes a token of a user for good, dropping its credentials, and reports
+// whether the user had it.
+func DeleteGoogleToken(ctx context.Context, id, userID int64) (bool, error) {
+	result := Db.WithContext(ctx).Unscoped().Where("id = ? AND user_id = ?", id, userID).Delete(&GoogleToken{})
+	if result.Error != nil {
+		return false, fmt.Errorf("delete google token: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ShareGoogleToken shares a token of a user with a workspace, reporting false when the user has
+// no such token.
+func ShareGoogleToken(ctx context.Context, id, userID, workspaceID int64) (bool, error) {
+	rows, err := gorm.G[GoogleToken](Db).Where("id = ? AND user_id = ?", id, userID).Update(ctx, "workspace_id", workspaceID)
+	if err != nil {
+		return false, fmt.Errorf("share google token: %w", err)
+	}
+	return rows == 1, nil
+}
+
+// sealGoogleTokens encrypts an access and refresh token pair for storage.
+func sealGoogleTokens(access, refresh string) (string, string, error) {
+	access, err := sealToken(access)
+	if err != nil {
+		return "", "", err
+	}
+	refresh, err = sealToken(refresh)
+	if err != nil {
+		return "", "", err
+	}
+	return access, refresh, nil
+}
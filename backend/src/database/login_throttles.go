@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginLockedUntil returns the latest lockout end among the throttles of keys, or the zero time
+// when none is locked.
+func LoginLockedUntil(ctx context.Context, keys []string) (time.Time, error) {
+	rows, err := gorm.G[LoginThrottle](Db).Where("throttle_key IN ? AND locked_until IS NOT NULL", keys).Find(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("get login throttles: %w", err)
+	}
+	var until time.Time
+	for _, row := range rows {
+		if row.LockedUntil.After(until) {
+			until = *row.LockedUntil
+		}
+	}
+	return until, nil
+}
+
+// AddLoginFailure counts a failed login for key at the given time and returns the failures in a
+// row, starting over when the previous one is before forgetBefore. The count is updated in one
+// statement, so concurrent failures on several replicas all count.
+func AddLoginFailure(ctx context.Context, key string, at, forgetBefore time.Time) (int, error) {
+	row := LoginThrottle{ThrottleKey: key, Failures: 1, LastFailureAt: at}
+	err := Db.WithContext(ctx).Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "throttle_key"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"failures":        gorm.Expr("CASE WHEN login_throttles.last_failure_at < ? THEN 1 ELSE login_throttles.failures + 1 END", forgetBefore),
+				"last_failure_at": at,
+			}),
+		},
+		clause.Returning{Columns: []clause.Column{{Name: "failures"}}},
+	).Create(&row).Error
+	if err != nil {
+		return 0, fmt.Errorf("add login failure: %w", err)
+	}
+	return row.Failures, nil
+}
+
+// LockLogin locks out the throttle of key until the given time.
+func LockLogin(ctx context.Context, key string, until time.Time) error {
+	if _, err := gorm.G[LoginThrottle](Db).Where("throttle_key = ?", key).Update(ctx, "locked_until", until); err != nil {
+		return fmt.Errorf("lock login: %w", err)
+	}
+	return nil
+}
+
+// ResetLoginFailures forgets the failed logins of key.
+func ResetLoginFailures(ctx context.Context, key string) error {
+	if _, err := gorm.G[LoginThrottle](Db).Where("throttle_key = ?", key).Delete(ctx); err != nil {
+		return fmt.Errorf("reset login failures: %w", err)
+	}
+	return nil
+}
+
+// CreateLoginFailure logs a failed login.
+func CreateLoginFailure(ctx context.Context, failure *LoginFailure) error {
+	if err := gorm.G[LoginFailure](Db).Create(ctx, failure); err != nil {
+		return fmt.Errorf("log login failure: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package database
+
+import (
+	"cmp"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"area/src/security"
+
+	"gorm.io/gorm"
+)
+
+// migrationFiles holds the versioned migrations, named <version>_<name>[.<dialect>].<up|down>.sql.
+// A file naming a dialect (postgres, sqlite) replaces the shared file of the same version and
+// direction on that dialect.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID serializes concurrent migrators on PostgreSQL; SQLite transactions already do.
+const migrationLockID = 7_241_001
+
+// ErrNotMigrated is returned by CheckMigrated when migrations are pending.
+var ErrNotMigrated = errors.New("database schema is not up to date")
+
+// Migration is one versioned schema or data change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// UpFunc and DownFunc replace Up and Down for data migrations written in Go. They run in
+	// the migration transaction.
+	UpFunc   func(tx *gorm.DB) error
+	DownFunc func(tx *gorm.DB) error
+	// Ready, when set, tells whether a Go migration can do its work yet. Until it can, it stays
+	// pending without holding back the later migrations or CheckMigrated, and a later MigrateUp
+	// applies it.
+	Ready func() bool
+}
+
+// Waiting reports whether m cannot be applied yet, see Ready.
+func (m Migration) Waiting() bool {
+	return m.Ready != nil && !m.Ready()
+}
+
+// MigrationStatus tells whether a migration is applied, and when.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// SchemaMigration records an applied migration.
+type SchemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (SchemaMigration) TableName() string { return "schema_migrations" }
+
+// goMigrations are the data migrations SQL cannot express, such as encrypting columns with
+// APP_SECRET_KEY. They share the versions of the files and run on every dialect.
+var goMigrations = []Migration{
+	{Version: 10, Name: "encrypt_oauth_tokens", UpFunc: encryptOAuthTokens, DownFunc: decryptOAuthTokens, Ready: security.Enabled},
+}
+
+// Migrations returns the migrations of the given dialect ordered by version.
+func Migrations(dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	// specific records which scripts came from a dialect file, so shared ones do not replace them.
+	specific := make(map[string]bool)
+	for _, entry := range entries {
+		version, name, fileDialect, direction, err := parseMigrationName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if fileDialect != "" && fileDialect != dialect {
+			continue
+		}
+		key := fmt.Sprintf("%d.%s", version, direction)
+		if fileDialect == "" && specific[key] {
+			continue
+		}
+		body, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d is named both %q and %q", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+		specific[key] = specific[key] || fileDialect != ""
+	}
+
+	for _, m := range goMigrations {
+		if _, dup := byVersion[m.Version]; dup {
+			return nil, fmt.Errorf("migration %d is both a file and Go code", m.Version)
+		}
+		byVersion[m.Version] = &m
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" && m.UpFunc == nil {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	slices.SortFunc(out, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+	return out, nil
+}
+
+// parseMigrationName splits a migration file name into its parts.
+func parseMigrationName(file string) (version int64, name, dialect, direction string, err error) {
+	base, ok := strings.CutSuffix(file, ".sql")
+	parts := strings.Split(base, ".")
+	if !ok || len(parts) < 2 || len(parts) > 3 {
+		return 0, "", "", "", fmt.Errorf("migration file %q: want <version>_<name>[.<dialect>].<up|down>.sql", file)
+	}
+	direction = parts[len(parts)-1]
+	if direction != "up" && direction != "down" {
+		return 0, "", "", "", fmt.Errorf("migration file %q: direction must be up or down", file)
+	}
+	if len(parts) == 3 {
+		dialect = parts[1]
+	}
+	rawVersion, name, _ := strings.Cut(parts[0], "_")
+	version, err = strconv.ParseInt(rawVersion, 10, 64)
+	if err != nil || version <= 0 || name == "" {
+		return 0, "", "", "", fmt.Errorf("migration file %q: want a positive version and a name", file)
+	}
+	return version, name, dialect, direction, nil
+}
+
+// MigrationStatuses lists every migration of db's dialect with its applied time, if any.
+func MigrationStatuses(db *gorm.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		out[i] = MigrationStatus{Migration: m}
+		if at, ok := applied[m.Version]; ok {
+			out[i].AppliedAt = &at
+		}
+	}
+	return out, nil
+}
+
+// appliedMigrations returns the applied versions; none when schema_migrations does not exist yet.
+func appliedMigrations(db *gorm.DB) (map[int64]time.Time, error) {
+	applied := make(map[int64]time.Time)
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return applied, nil
+	}
+	var rows []SchemaMigration
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return applied, nil
+}
+
+// CheckMigrated returns an error wrapping ErrNotMigrated when migrations are pending, those
+// waiting to be able to run aside.
+func CheckMigrated(db *gorm.DB) error {
+	statuses, err := MigrationStatuses(db)
+	if err != nil {
+		return err
+	}
+	var pending []string
+	for _, s := range statuses {
+		if s.AppliedAt == nil && !s.Waiting() {
+			pending = append(pending, fmt.Sprintf("%d_%s", s.Version, s.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: pending %s, run the migrate up command", ErrNotMigrated, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// MigrateUp applies every pending migration that can run, each in its own transaction, and
+// returns them.
+func MigrateUp(db *gorm.DB) ([]Migration, error) {
+	if err := db.Migrator().AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+	migrations, err := Migrations(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, m := range migrations {
+		applied, err := applyMigration(db, m)
+		if err != nil {
+			return done, err
+		}
+		if applied {
+			done = append(done, m)
+		}
+	}
+	return done, nil
+}
+
+// applyMigration runs m unless another migrator already did or it is waiting.
+func applyMigration(db *gorm.DB, m Migration) (applied bool, err error) {
+	if m.Waiting() {
+		return false, nil
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := lockMigrations(tx); err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&SchemaMigration{}).Where("version = ?", m.Version).Count(&count).Error; err != nil {
+			return fmt.Errorf("read schema_migrations: %w", err)
+		}
+		if count > 0 {
+			return nil
+		}
+		if err := runMigration(tx, m.Up, m.UpFunc); err != nil {
+			return fmt.Errorf("migration %d_%s up: %w", m.Version, m.Name, err)
+		}
+		applied = true
+		return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+	})
+	return applied, err
+}
+
+// MigrateDown reverts the last steps applied migrations, newest first, and returns them.
+func MigrateDown(db *gorm.DB, steps int) ([]Migration, error) {
+	statuses, err := MigrationStatuses(db)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for i := len(statuses) - 1; i >= 0 && len(done) < steps; i-- {
+		m := statuses[i]
+		if m.AppliedAt == nil {
+			continue
+		}
+		if m.Down == "" && m.DownFunc == nil {
+			return done, fmt.Errorf("migration %d_%s cannot be reverted: no down script", m.Version, m.Name)
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := lockMigrations(tx); err != nil {
+				return err
+			}
+			if err := runMigration(tx, m.Down, m.DownFunc); err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", m.Version, m.Name, err)
+			}
+			return tx.Where("version = ?", m.Version).Delete(&SchemaMigration{}).Error
+		})
+		if err != nil {
+			return done, err
+		}
+		done = append(done, m.Migration)
+	}
+	return done, nil
+}
+
+// runMigration runs the Go function of a migration direction, or its script when it has none.
+func runMigration(tx *gorm.DB, script string, fn func(*gorm.DB) error) error {
+	if fn != nil {
+		return fn(tx)
+	}
+	return tx.Exec(script).Error
+}
+
+// lockMigrations holds the migration lock until tx ends.
+func lockMigrations(tx *gorm.DB) error {
+	if IsSQLite(tx) {
+		return nil
+	}
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockID).Error; err != nil {
+		return fmt.Errorf("lock migrations: %w", err)
+	}
+	return nil
+}
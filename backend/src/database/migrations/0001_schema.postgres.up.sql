@@ -0,0 +1,232 @@
+-- Baseline schema. Databases created from the former resources/database_scheme.sql and
+-- AutoMigrate are brought to the same shape, hence the IF NOT EXISTS guards.
+
+---------------------------
+-- USERS
+---------------------------
+CREATE TABLE IF NOT EXISTS users (
+    id              SERIAL PRIMARY KEY,
+    email           VARCHAR(255) NOT NULL CONSTRAINT uni_users_email UNIQUE,
+    firstname       VARCHAR(255) NOT NULL,
+    lastname        VARCHAR(255) NOT NULL,
+    password_hash   TEXT NOT NULL,
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at      TIMESTAMPTZ
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ DEFAULT NOW();
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+---------------------------
+-- WORKFLOWS
+---------------------------
+CREATE TABLE IF NOT EXISTS workflows (
+    id               SERIAL PRIMARY KEY,
+    user_id          INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name             VARCHAR(255) NOT NULL,
+    trigger_type     VARCHAR(64) NOT NULL,
+    trigger_config   JSONB DEFAULT '{}'::jsonb,
+    action_url       TEXT NOT NULL,
+    enabled          BOOLEAN NOT NULL DEFAULT FALSE,
+    next_run_at      TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at       TIMESTAMPTZ
+);
+
+ALTER TABLE workflows ALTER COLUMN enabled SET DEFAULT FALSE;
+-- Manual workflows are stored without a trigger config.
+ALTER TABLE workflows ALTER COLUMN trigger_config DROP NOT NULL;
+ALTER TABLE workflows ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ DEFAULT NOW();
+ALTER TABLE workflows ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_workflows_user_id ON workflows (user_id);
+CREATE INDEX IF NOT EXISTS idx_workflows_deleted_at ON workflows (deleted_at);
+
+---------------------------
+-- WORKFLOW RUNS
+---------------------------
+CREATE TABLE IF NOT EXISTS workflow_runs (
+    id           SERIAL PRIMARY KEY,
+    workflow_id  INTEGER NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    status       VARCHAR(32) NOT NULL DEFAULT 'pending',
+    created_at   TIMESTAMPTZ DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at   TIMESTAMPTZ,
+    started_at   TIMESTAMPTZ,
+    ended_at     TIMESTAMPTZ,
+    error        TEXT
+);
+
+ALTER TABLE workflow_runs ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ DEFAULT NOW();
+ALTER TABLE workflow_runs ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_workflow_runs_workflow_id ON workflow_runs (workflow_id);
+CREATE INDEX IF NOT EXISTS idx_workflow_runs_deleted_at ON workflow_runs (deleted_at);
+
+---------------------------
+-- JOBS
+---------------------------
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'job_status') THEN
+        CREATE TYPE job_status AS ENUM ('pending', 'processing', 'succeeded', 'failed', 'cancelled');
+    END IF;
+END
+$$;
+
+ALTER TYPE job_status ADD VALUE IF NOT EXISTS 'cancelled';
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id              SERIAL PRIMARY KEY,
+    workflow_id     INTEGER NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    run_id          INTEGER NOT NULL REFERENCES workflow_runs(id) ON DELETE CASCADE,
+    payload         JSONB,
+    status          job_status NOT NULL DEFAULT 'pending',
+    error           TEXT,
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at      TIMESTAMPTZ,
+    started_at      TIMESTAMPTZ,
+    ended_at        TIMESTAMPTZ,
+    response_status INTEGER,
+    response_body   TEXT,
+    latency_ms      BIGINT,
+    request_id      TEXT
+);
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS response_status INTEGER;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS response_body TEXT;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS latency_ms BIGINT;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS request_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_jobs_workflow_id ON jobs (workflow_id);
+CREATE INDEX IF NOT EXISTS idx_jobs_run_id ON jobs (run_id);
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs (status);
+CREATE INDEX IF NOT EXISTS idx_jobs_deleted_at ON jobs (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_status_created_at ON jobs (status, created_at);
+
+---------------------------
+-- DIGEST BUFFER
+---------------------------
+CREATE TABLE IF NOT EXISTS digest_items (
+    id           SERIAL PRIMARY KEY,
+    workflow_id  INTEGER NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    payload      JSONB NOT NULL,
+    created_at   TIMESTAMPTZ DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_digest_items_workflow_id ON digest_items (workflow_id);
+CREATE INDEX IF NOT EXISTS idx_digest_items_deleted_at ON digest_items (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_digest_items_workflow_created_at ON digest_items (workflow_id, created_at);
+
+---------------------------
+-- POLLER STATES
+---------------------------
+CREATE TABLE IF NOT EXISTS poller_states (
+    name        TEXT PRIMARY KEY,
+    state       JSONB NOT NULL,
+    updated_at  TIMESTAMPTZ DEFAULT NOW()
+);
+
+---------------------------
+-- LEASES
+---------------------------
+CREATE TABLE IF NOT EXISTS leases (
+    name        TEXT PRIMARY KEY,
+    holder      TEXT NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL
+);
+
+---------------------------
+-- GOOGLE TOKENS
+---------------------------
+CREATE TABLE IF NOT EXISTS google_tokens (
+    id             SERIAL PRIMARY KEY,
+    user_id        INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    access_token   TEXT NOT NULL,
+    refresh_token  TEXT NOT NULL,
+    expiry         TIMESTAMPTZ NOT NULL,
+    created_at     TIMESTAMPTZ DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at     TIMESTAMPTZ
+);
+
+ALTER TABLE google_tokens ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ DEFAULT NOW();
+ALTER TABLE google_tokens ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_google_tokens_deleted_at ON google_tokens (deleted_at);
+
+---------------------------
+-- GITHUB TOKENS
+---------------------------
+CREATE TABLE IF NOT EXISTS github_tokens (
+    id             SERIAL PRIMARY KEY,
+    user_id        INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    access_token   TEXT NOT NULL,
+    token_type     TEXT NOT NULL,
+    scope          TEXT NOT NULL,
+    created_at     TIMESTAMPTZ DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ DEFAULT NOW(),
+    deleted_at     TIMESTAMPTZ
+);
+
+ALTER TABLE github_tokens ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ DEFAULT NOW();
+ALTER TABLE github_tokens ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_github_tokens_deleted_at ON github_tokens (deleted_at);
+
+---------------------------
+-- AREA CATALOG
+---------------------------
+CREATE TABLE IF NOT EXISTS area_services (
+    id           TEXT PRIMARY KEY,
+    name         TEXT NOT NULL,
+    enabled      BOOLEAN NOT NULL DEFAULT TRUE,
+    more_info    TEXT,
+    oauth_scopes JSONB,
+    created_at   TIMESTAMPTZ DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- AutoMigrate added this misnamed copy of oauth_scopes; nothing ever wrote to it.
+ALTER TABLE area_services DROP COLUMN IF EXISTS o_auth_scopes;
+
+CREATE TABLE IF NOT EXISTS area_capabilities (
+    service_id      TEXT NOT NULL REFERENCES area_services(id) ON DELETE CASCADE,
+    id              TEXT NOT NULL,
+    kind            TEXT NOT NULL,
+    name            TEXT NOT NULL,
+    description     TEXT,
+    action_url      TEXT,
+    default_payload JSONB,
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (service_id, id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_area_capabilities_service_id ON area_capabilities (service_id);
+CREATE INDEX IF NOT EXISTS idx_area_capabilities_kind ON area_capabilities (kind);
+CREATE INDEX IF NOT EXISTS idx_area_capabilities_service_kind ON area_capabilities (service_id, kind);
+
+CREATE TABLE IF NOT EXISTS area_fields (
+    id             SERIAL PRIMARY KEY,
+    service_id     TEXT NOT NULL,
+    capability_id  TEXT NOT NULL,
+    key            TEXT NOT NULL,
+    type           TEXT NOT NULL,
+    required       BOOLEAN NOT NULL DEFAULT FALSE,
+    description    TEXT,
+    example        JSONB,
+    created_at     TIMESTAMPTZ DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ DEFAULT NOW(),
+    FOREIGN KEY (service_id, capability_id) REFERENCES area_capabilities(service_id, id) ON DELETE CASCADE
+);
+
+-- The former seed inserted some fields twice.
+DELETE FROM area_fields
+WHERE id NOT IN (SELECT MIN(id) FROM area_fields GROUP BY service_id, capability_id, key);
+
+CREATE INDEX IF NOT EXISTS idx_area_fields_service_id ON area_fields (service_id);
+CREATE INDEX IF NOT EXISTS idx_area_fields_capability_id ON area_fields (capability_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_area_fields_capability_key ON area_fields (service_id, capability_id, key);
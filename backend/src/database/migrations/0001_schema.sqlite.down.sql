@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS area_fields;
+DROP TABLE IF EXISTS area_capabilities;
+DROP TABLE IF EXISTS area_services;
+DROP TABLE IF EXISTS github_tokens;
+DROP TABLE IF EXISTS google_tokens;
+DROP TABLE IF EXISTS leases;
+DROP TABLE IF EXISTS poller_states;
+DROP TABLE IF EXISTS digest_items;
+DROP TABLE IF EXISTS jobs;
+DROP TABLE IF EXISTS workflow_runs;
+DROP TABLE IF EXISTS workflows;
+DROP TABLE IF EXISTS users;
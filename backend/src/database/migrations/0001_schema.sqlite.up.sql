@@ -0,0 +1,159 @@
+-- Baseline schema, kept column for column in line with 0001_schema.postgres.up.sql.
+-- JSON is stored as TEXT and read back with json_extract.
+
+CREATE TABLE users (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    email           TEXT NOT NULL CONSTRAINT uni_users_email UNIQUE,
+    firstname       TEXT NOT NULL,
+    lastname        TEXT NOT NULL,
+    password_hash   TEXT NOT NULL,
+    created_at      DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at      DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at      DATETIME
+);
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE workflows (
+    id               INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id          INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name             TEXT NOT NULL,
+    trigger_type     TEXT NOT NULL,
+    trigger_config   TEXT DEFAULT '{}',
+    action_url       TEXT NOT NULL,
+    enabled          BOOLEAN NOT NULL DEFAULT FALSE,
+    next_run_at      DATETIME,
+    created_at       DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at       DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at       DATETIME
+);
+CREATE INDEX idx_workflows_user_id ON workflows (user_id);
+CREATE INDEX idx_workflows_deleted_at ON workflows (deleted_at);
+
+CREATE TABLE workflow_runs (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    workflow_id  INTEGER NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    status       TEXT NOT NULL DEFAULT 'pending',
+    created_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at   DATETIME,
+    started_at   DATETIME,
+    ended_at     DATETIME,
+    error        TEXT
+);
+CREATE INDEX idx_workflow_runs_workflow_id ON workflow_runs (workflow_id);
+CREATE INDEX idx_workflow_runs_deleted_at ON workflow_runs (deleted_at);
+
+CREATE TABLE jobs (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    workflow_id     INTEGER NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    run_id          INTEGER NOT NULL REFERENCES workflow_runs(id) ON DELETE CASCADE,
+    payload         TEXT,
+    status          TEXT NOT NULL DEFAULT 'pending'
+                    CHECK (status IN ('pending', 'processing', 'succeeded', 'failed', 'cancelled')),
+    error           TEXT,
+    created_at      DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at      DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at      DATETIME,
+    started_at      DATETIME,
+    ended_at        DATETIME,
+    response_status INTEGER,
+    response_body   TEXT,
+    latency_ms      INTEGER,
+    request_id      TEXT
+);
+CREATE INDEX idx_jobs_workflow_id ON jobs (workflow_id);
+CREATE INDEX idx_jobs_run_id ON jobs (run_id);
+CREATE INDEX idx_jobs_status ON jobs (status);
+CREATE INDEX idx_jobs_deleted_at ON jobs (deleted_at);
+CREATE INDEX idx_jobs_status_created_at ON jobs (status, created_at);
+
+CREATE TABLE digest_items (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    workflow_id  INTEGER NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    payload      TEXT NOT NULL,
+    created_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at   DATETIME
+);
+CREATE INDEX idx_digest_items_workflow_id ON digest_items (workflow_id);
+CREATE INDEX idx_digest_items_deleted_at ON digest_items (deleted_at);
+CREATE INDEX idx_digest_items_workflow_created_at ON digest_items (workflow_id, created_at);
+
+CREATE TABLE poller_states (
+    name        TEXT PRIMARY KEY,
+    state       TEXT NOT NULL,
+    updated_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE leases (
+    name        TEXT PRIMARY KEY,
+    holder      TEXT NOT NULL,
+    expires_at  DATETIME NOT NULL
+);
+
+CREATE TABLE google_tokens (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id        INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    access_token   TEXT NOT NULL,
+    refresh_token  TEXT NOT NULL,
+    expiry         DATETIME NOT NULL,
+    created_at     DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at     DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at     DATETIME
+);
+CREATE INDEX idx_google_tokens_deleted_at ON google_tokens (deleted_at);
+
+CREATE TABLE github_tokens (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id        INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    access_token   TEXT NOT NULL,
+    token_type     TEXT NOT NULL,
+    scope          TEXT NOT NULL,
+    created_at     DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at     DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at     DATETIME
+);
+CREATE INDEX idx_github_tokens_deleted_at ON github_tokens (deleted_at);
+
+CREATE TABLE area_services (
+    id           TEXT PRIMARY KEY,
+    name         TEXT NOT NULL,
+    enabled      BOOLEAN NOT NULL DEFAULT TRUE,
+    more_info    TEXT,
+    oauth_scopes TEXT,
+    created_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at   DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE area_capabilities (
+    service_id      TEXT NOT NULL REFERENCES area_services(id) ON DELETE CASCADE,
+    id              TEXT NOT NULL,
+    kind            TEXT NOT NULL,
+    name            TEXT NOT NULL,
+    description     TEXT,
+    action_url      TEXT,
+    default_payload TEXT,
+    created_at      DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at      DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (service_id, id)
+);
+CREATE INDEX idx_area_capabilities_service_id ON area_capabilities (service_id);
+CREATE INDEX idx_area_capabilities_kind ON area_capabilities (kind);
+CREATE INDEX idx_area_capabilities_service_kind ON area_capabilities (service_id, kind);
+
+CREATE TABLE area_fields (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    service_id     TEXT NOT NULL,
+    capability_id  TEXT NOT NULL,
+    key            TEXT NOT NULL,
+    type           TEXT NOT NULL,
+    required       BOOLEAN NOT NULL DEFAULT FALSE,
+    description    TEXT,
+    example        TEXT,
+    created_at     DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at     DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (service_id, capability_id) REFERENCES area_capabilities(service_id, id) ON DELETE CASCADE
+);
+CREATE INDEX idx_area_fields_service_id ON area_fields (service_id);
+CREATE INDEX idx_area_fields_capability_id ON area_fields (capability_id);
+CREATE UNIQUE INDEX idx_area_fields_capability_key ON area_fields (service_id, capability_id, key);
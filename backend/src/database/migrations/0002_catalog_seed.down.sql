@@ -0,0 +1,3 @@
+DELETE FROM area_fields WHERE service_id IN ('core', 'discord', 'google', 'github', 'slack', 'notion', 'weather', 'steam', 'crypto', 'nasa', 'air_quality', 'trello');
+DELETE FROM area_capabilities WHERE service_id IN ('core', 'discord', 'google', 'github', 'slack', 'notion', 'weather', 'steam', 'crypto', 'nasa', 'air_quality', 'trello');
+DELETE FROM area_services WHERE id IN ('core', 'discord', 'google', 'github', 'slack', 'notion', 'weather', 'steam', 'crypto', 'nasa', 'air_quality', 'trello');
@@ -1,147 +1,11 @@
----------------------------
--- USERS
----------------------------
-CREATE TABLE IF NOT EXISTS users (
-    id              SERIAL PRIMARY KEY,
-    email           VARCHAR(255) UNIQUE NOT NULL,
-    firstname       VARCHAR(255) NOT NULL,
-    lastname        VARCHAR(255) NOT NULL,
-    password_hash   TEXT NOT NULL,
-    created_at      TIMESTAMPTZ DEFAULT NOW()
-);
-
----------------------------
--- WORKFLOWS
----------------------------
-CREATE TABLE IF NOT EXISTS workflows (
-    id               SERIAL PRIMARY KEY,
-    user_id          INTEGER REFERENCES users(id) ON DELETE CASCADE,
-    name             VARCHAR(255) NOT NULL,
-    trigger_type     VARCHAR(64) NOT NULL,
-    trigger_config   JSONB NOT NULL DEFAULT '{}'::jsonb,
-    action_url       TEXT NOT NULL,
-    enabled          BOOLEAN NOT NULL DEFAULT TRUE,
-    next_run_at      TIMESTAMPTZ,
-    created_at       TIMESTAMPTZ DEFAULT NOW()
-);
-
-UPDATE workflows
-SET enabled = FALSE
-WHERE trigger_type <> 'manual' AND enabled = TRUE;
-
----------------------------
--- WORKFLOW RUNS
----------------------------
-CREATE TABLE IF NOT EXISTS workflow_runs (
-    id           SERIAL PRIMARY KEY,
-    workflow_id  INTEGER NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
-    status       VARCHAR(32) NOT NULL DEFAULT 'pending',
-    created_at   TIMESTAMPTZ DEFAULT NOW(),
-    started_at   TIMESTAMPTZ,
-    ended_at     TIMESTAMPTZ,
-    error        TEXT
-);
-
----------------------------
--- JOBS
----------------------------
-DO $$
-BEGIN
-    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'job_status') THEN
-        CREATE TYPE job_status AS ENUM ('pending', 'processing', 'succeeded', 'failed');
-    END IF;
-END
-$$;
-
-CREATE TABLE IF NOT EXISTS jobs (
-    id           SERIAL PRIMARY KEY,
-    workflow_id  INTEGER NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
-    run_id       INTEGER NOT NULL REFERENCES workflow_runs(id) ON DELETE CASCADE,
-    payload      JSONB,
-    status       job_status NOT NULL DEFAULT 'pending',
-    error        TEXT,
-    created_at   TIMESTAMPTZ DEFAULT NOW(),
-    updated_at   TIMESTAMPTZ DEFAULT NOW(),
-    started_at   TIMESTAMPTZ,
-    ended_at     TIMESTAMPTZ
-);
-
-CREATE INDEX IF NOT EXISTS idx_jobs_status_created_at ON jobs (status, created_at);
-
----------------------------
--- GOOGLE TOKENS
----------------------------
-CREATE TABLE IF NOT EXISTS google_tokens (
-    id             SERIAL PRIMARY KEY,
-    user_id        INTEGER REFERENCES users(id) ON DELETE CASCADE,
-    access_token   TEXT NOT NULL,
-    refresh_token  TEXT NOT NULL,
-    expiry         TIMESTAMPTZ NOT NULL,
-    created_at     TIMESTAMPTZ DEFAULT NOW()
-);
-
----------------------------
--- GITHUB TOKENS
----------------------------
-CREATE TABLE IF NOT EXISTS github_tokens (
-    id             SERIAL PRIMARY KEY,
-    user_id        INTEGER REFERENCES users(id) ON DELETE CASCADE,
-    access_token   TEXT NOT NULL,
-    token_type     TEXT NOT NULL,
-    scope          TEXT NOT NULL,
-    created_at     TIMESTAMPTZ DEFAULT NOW()
-);
-
----------------------------
--- AREA CATALOG
----------------------------
-CREATE TABLE IF NOT EXISTS area_services (
-    id           TEXT PRIMARY KEY,
-    name         TEXT NOT NULL,
-    enabled      BOOLEAN NOT NULL DEFAULT TRUE,
-    more_info    TEXT,
-    oauth_scopes JSONB,
-    created_at   TIMESTAMPTZ DEFAULT NOW(),
-    updated_at   TIMESTAMPTZ DEFAULT NOW()
-);
-
-CREATE TABLE IF NOT EXISTS area_capabilities (
-    service_id      TEXT NOT NULL REFERENCES area_services(id) ON DELETE CASCADE,
-    id              TEXT NOT NULL,
-    kind            TEXT NOT NULL,
-    name            TEXT NOT NULL,
-    description     TEXT,
-    action_url      TEXT,
-    default_payload JSONB,
-    created_at      TIMESTAMPTZ DEFAULT NOW(),
-    updated_at      TIMESTAMPTZ DEFAULT NOW(),
-    PRIMARY KEY (service_id, id)
-);
-
-CREATE INDEX IF NOT EXISTS idx_area_capabilities_service_kind ON area_capabilities (service_id, kind);
-
-CREATE TABLE IF NOT EXISTS area_fields (
-    id             SERIAL PRIMARY KEY,
-    service_id     TEXT NOT NULL,
-    capability_id  TEXT NOT NULL,
-    key            TEXT NOT NULL,
-    type           TEXT NOT NULL,
-    required       BOOLEAN NOT NULL DEFAULT FALSE,
-    description    TEXT,
-    example        JSONB,
-    created_at     TIMESTAMPTZ DEFAULT NOW(),
-    updated_at     TIMESTAMPTZ DEFAULT NOW(),
-    FOREIGN KEY (service_id, capability_id) REFERENCES area_capabilities(service_id, id) ON DELETE CASCADE
-);
-
----------------------------
--- AREA CATALOG SEED
----------------------------
+-- Catalog of services, triggers, reactions and their fields.
+-- Every insert skips rows that already exist, so re-running it never duplicates or overwrites.
+
 INSERT INTO area_services (id, name, enabled, more_info, oauth_scopes)
 VALUES
     ('core', 'Core', TRUE, NULL, NULL),
     ('discord', 'Discord', TRUE, NULL, NULL),
-    ('google', 'Google', TRUE, NULL, '["https://www.googleapis.com/auth/gmail.send","https://www.googleapis.com/auth/calendar.events","https://www.googleapis.com/auth/userinfo.email"]'::jsonb),
+    ('google', 'Google', TRUE, NULL, '["https://www.googleapis.com/auth/gmail.send","https://www.googleapis.com/auth/calendar.events","https://www.googleapis.com/auth/userinfo.email"]'),
     ('github', 'GitHub', TRUE, NULL, NULL),
     ('slack', 'Slack', TRUE, NULL, NULL),
     ('notion', 'Notion', TRUE, NULL, NULL),
@@ -180,8 +44,8 @@ VALUES
     ('trello_move_card', 'trello', 'reaction', 'Move card', 'Move a Trello card to another list.', '/actions/trello/card/move', NULL),
     ('trello_create_list', 'trello', 'reaction', 'Create list', 'Create a Trello list on a board.', '/actions/trello/list', NULL),
 
-    ('discord_message', 'discord', 'reaction', 'Send message', 'Send a message to a channel using the bot.', '/actions/discord/message', '{"content":"Hello from Area"}'::jsonb),
-    ('discord_embed', 'discord', 'reaction', 'Send embed', 'Send an embed to a channel.', '/actions/discord/embed', '{"title":"Area update","description":"Something happened"}'::jsonb),
+    ('discord_message', 'discord', 'reaction', 'Send message', 'Send a message to a channel using the bot.', '/actions/discord/message', '{"content":"Hello from Area"}'),
+    ('discord_embed', 'discord', 'reaction', 'Send embed', 'Send an embed to a channel.', '/actions/discord/embed', '{"title":"Area update","description":"Something happened"}'),
     ('discord_edit_message', 'discord', 'reaction', 'Edit message', 'Edit a previously sent message.', '/actions/discord/message/edit', NULL),
     ('discord_delete_message', 'discord', 'reaction', 'Delete message', 'Delete a message by ID.', '/actions/discord/message/delete', NULL),
     ('discord_add_reaction', 'discord', 'reaction', 'Add reaction', 'Add a reaction emoji to a message.', '/actions/discord/message/react', NULL),
@@ -192,7 +56,7 @@ VALUES
     ('github_issue', 'github', 'reaction', 'Create issue', 'Create a new issue in a repository.', '/actions/github/issue', NULL),
     ('github_pull_request', 'github', 'reaction', 'Create pull request', 'Create a pull request from a branch.', '/actions/github/pr', NULL),
 
-    ('slack_message', 'slack', 'reaction', 'Send message', 'Send a message to a Slack channel.', '/actions/slack/message', '{"text":"Hello from Area"}'::jsonb),
+    ('slack_message', 'slack', 'reaction', 'Send message', 'Send a message to a Slack channel.', '/actions/slack/message', '{"text":"Hello from Area"}'),
     ('slack_blocks', 'slack', 'reaction', 'Send blocks message', 'Send a message with Block Kit payload.', '/actions/slack/blocks', NULL),
     ('slack_update', 'slack', 'reaction', 'Update message', 'Update an existing message.', '/actions/slack/message/update', NULL),
     ('slack_delete', 'slack', 'reaction', 'Delete message', 'Delete a message by timestamp.', '/actions/slack/message/delete', NULL),
@@ -208,103 +72,108 @@ ON CONFLICT (service_id, id) DO NOTHING;
 
 INSERT INTO area_fields (service_id, capability_id, key, type, required, description, example)
 VALUES
-    ('core', 'interval', 'interval_minutes', 'number', TRUE, 'Delay between runs in minutes', '5'::jsonb),
+    ('core', 'interval', 'interval_minutes', 'number', TRUE, 'Delay between runs in minutes', '5'),
+
+    ('google', 'gmail_inbound', 'digest', 'object', FALSE, 'Batch emails into a digest (every_minutes or cron)', '{"every_minutes":30}'),
 
     ('github', 'github_commit', 'token_id', 'number', TRUE, 'Stored GitHub token id', NULL),
-    ('github', 'github_commit', 'repo', 'string', TRUE, 'Repository in owner/name format', '"owner/repo"'::jsonb),
-    ('github', 'github_commit', 'branch', 'string', TRUE, 'Branch to watch', '"main"'::jsonb),
+    ('github', 'github_commit', 'repo', 'string', TRUE, 'Repository in owner/name format', '"owner/repo"'),
+    ('github', 'github_commit', 'branch', 'string', TRUE, 'Branch to watch', '"main"'),
+    ('github', 'github_commit', 'digest', 'object', FALSE, 'Batch commits into a digest (every_minutes or cron)', '{"cron":"0 9 * * 1-5"}'),
 
     ('github', 'github_pull_request', 'token_id', 'number', TRUE, 'Stored GitHub token id', NULL),
-    ('github', 'github_pull_request', 'repo', 'string', TRUE, 'Repository in owner/name format', '"owner/repo"'::jsonb),
-    ('github', 'github_pull_request', 'actions', 'array<string>', FALSE, 'Actions to watch (opened,closed,merged)', '["opened","closed","merged"]'::jsonb),
+    ('github', 'github_pull_request', 'repo', 'string', TRUE, 'Repository in owner/name format', '"owner/repo"'),
+    ('github', 'github_pull_request', 'actions', 'array<string>', FALSE, 'Actions to watch (opened,closed,merged)', '["opened","closed","merged"]'),
 
     ('github', 'github_issue', 'token_id', 'number', TRUE, 'Stored GitHub token id', NULL),
-    ('github', 'github_issue', 'repo', 'string', TRUE, 'Repository in owner/name format', '"owner/repo"'::jsonb),
-    ('github', 'github_issue', 'actions', 'array<string>', FALSE, 'Actions to watch (opened,closed,reopened)', '["opened","closed"]'::jsonb),
-
-    ('weather', 'weather_temp', 'city', 'string', TRUE, 'City name (e.g. Paris)', '"Paris"'::jsonb),
-    ('weather', 'weather_temp', 'threshold', 'number', TRUE, 'Temperature threshold (°C)', '20'::jsonb),
-    ('weather', 'weather_temp', 'direction', 'string', TRUE, 'above or below', '"above"'::jsonb),
-    ('weather', 'weather_temp', 'interval_minutes', 'number', FALSE, 'Minimum polling interval in minutes', '5'::jsonb),
-
-    ('weather', 'weather_report', 'city', 'string', TRUE, 'City name (e.g. Paris)', '"Paris"'::jsonb),
-    ('weather', 'weather_report', 'interval_minutes', 'number', TRUE, 'Polling interval in minutes', '10'::jsonb),
-
-    ('core', 'reddit_new_post', 'subreddit', 'string', TRUE, 'Subreddit name (without r/)', '"golang"'::jsonb),
-    ('core', 'reddit_new_post', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '5'::jsonb),
-
-    ('core', 'youtube_new_video', 'channel', 'string', TRUE, 'YouTube channel name, handle, or ID', '"@GoogleDevelopers"'::jsonb),
-    ('core', 'youtube_new_video', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '5'::jsonb),
-
-    ('steam', 'steam_player_online', 'steam_id', 'string', TRUE, 'SteamID64 of the user', '"76561198000000000"'::jsonb),
-    ('steam', 'steam_player_online', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '5'::jsonb),
-
-    ('steam', 'steam_game_sale', 'app_id', 'number', TRUE, 'Steam app ID', '570'::jsonb),
-    ('steam', 'steam_game_sale', 'country', 'string', FALSE, 'Country code for pricing (e.g. us, fr)', '"us"'::jsonb),
-    ('steam', 'steam_game_sale', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '10'::jsonb),
-
-    ('steam', 'steam_price_change', 'app_id', 'number', TRUE, 'Steam app ID', '570'::jsonb),
-    ('steam', 'steam_price_change', 'country', 'string', FALSE, 'Country code for pricing (e.g. us, fr)', '"us"'::jsonb),
-    ('steam', 'steam_price_change', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '10'::jsonb),
-
-    ('crypto', 'crypto_price_threshold', 'coin_id', 'string', TRUE, 'Coin id (CoinGecko, e.g. bitcoin)', '"bitcoin"'::jsonb),
-    ('crypto', 'crypto_price_threshold', 'currency', 'string', FALSE, 'Currency (e.g. usd, eur)', '"usd"'::jsonb),
-    ('crypto', 'crypto_price_threshold', 'threshold', 'number', TRUE, 'Price threshold', '50000'::jsonb),
-    ('crypto', 'crypto_price_threshold', 'direction', 'string', TRUE, 'above or below', '"above"'::jsonb),
-    ('crypto', 'crypto_price_threshold', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '5'::jsonb),
-
-    ('crypto', 'crypto_percent_change', 'coin_id', 'string', TRUE, 'Coin id (CoinGecko, e.g. bitcoin)', '"bitcoin"'::jsonb),
-    ('crypto', 'crypto_percent_change', 'currency', 'string', FALSE, 'Currency (e.g. usd, eur)', '"usd"'::jsonb),
-    ('crypto', 'crypto_percent_change', 'percent', 'number', TRUE, 'Percent change threshold', '5'::jsonb),
-    ('crypto', 'crypto_percent_change', 'period', 'string', TRUE, '1h or 24h', '"1h"'::jsonb),
-    ('crypto', 'crypto_percent_change', 'direction', 'string', FALSE, 'above, below, or any', '"any"'::jsonb),
-    ('crypto', 'crypto_percent_change', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '5'::jsonb),
-
-    ('nasa', 'nasa_apod', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '60'::jsonb),
-
-    ('nasa', 'nasa_mars_photo', 'rover', 'string', TRUE, 'Rover name (curiosity, perseverance, opportunity, spirit)', '"curiosity"'::jsonb),
-    ('nasa', 'nasa_mars_photo', 'camera', 'string', FALSE, 'Camera name (e.g. FHAZ, RHAZ, NAVCAM)', '"FHAZ"'::jsonb),
-    ('nasa', 'nasa_mars_photo', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '60'::jsonb),
-
-    ('nasa', 'nasa_neo_close_approach', 'threshold_km', 'number', TRUE, 'Distance threshold in km', '500000'::jsonb),
-    ('nasa', 'nasa_neo_close_approach', 'days_ahead', 'number', FALSE, 'Number of days to look ahead', '1'::jsonb),
-    ('nasa', 'nasa_neo_close_approach', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '60'::jsonb),
-
-    ('air_quality', 'air_quality_aqi_threshold', 'city', 'string', TRUE, 'City name (e.g. Paris)', '"Paris"'::jsonb),
-    ('air_quality', 'air_quality_aqi_threshold', 'index', 'string', FALSE, 'AQI index (us_aqi or european_aqi)', '"us_aqi"'::jsonb),
-    ('air_quality', 'air_quality_aqi_threshold', 'threshold', 'number', TRUE, 'AQI threshold', '100'::jsonb),
-    ('air_quality', 'air_quality_aqi_threshold', 'direction', 'string', TRUE, 'above or below', '"above"'::jsonb),
-    ('air_quality', 'air_quality_aqi_threshold', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '10'::jsonb),
-
-    ('air_quality', 'air_quality_pm25_threshold', 'city', 'string', TRUE, 'City name (e.g. Paris)', '"Paris"'::jsonb),
-    ('air_quality', 'air_quality_pm25_threshold', 'threshold', 'number', TRUE, 'PM2.5 threshold (µg/m³)', '15'::jsonb),
-    ('air_quality', 'air_quality_pm25_threshold', 'direction', 'string', TRUE, 'above or below', '"above"'::jsonb),
-    ('air_quality', 'air_quality_pm25_threshold', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '10'::jsonb),
+    ('github', 'github_issue', 'repo', 'string', TRUE, 'Repository in owner/name format', '"owner/repo"'),
+    ('github', 'github_issue', 'actions', 'array<string>', FALSE, 'Actions to watch (opened,closed,reopened)', '["opened","closed"]'),
+
+    ('weather', 'weather_temp', 'city', 'string', TRUE, 'City name (e.g. Paris)', '"Paris"'),
+    ('weather', 'weather_temp', 'threshold', 'number', TRUE, 'Temperature threshold (°C)', '20'),
+    ('weather', 'weather_temp', 'direction', 'string', TRUE, 'above or below', '"above"'),
+    ('weather', 'weather_temp', 'interval_minutes', 'number', FALSE, 'Minimum polling interval in minutes', '5'),
+
+    ('weather', 'weather_report', 'city', 'string', TRUE, 'City name (e.g. Paris)', '"Paris"'),
+    ('weather', 'weather_report', 'interval_minutes', 'number', TRUE, 'Polling interval in minutes', '10'),
+
+    ('core', 'reddit_new_post', 'subreddit', 'string', TRUE, 'Subreddit name (without r/)', '"golang"'),
+    ('core', 'reddit_new_post', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '5'),
+    ('core', 'reddit_new_post', 'digest', 'object', FALSE, 'Batch posts into a digest (every_minutes or cron)', '{"every_minutes":60}'),
+
+    ('core', 'youtube_new_video', 'channel', 'string', TRUE, 'YouTube channel name, handle, or ID', '"@GoogleDevelopers"'),
+    ('core', 'youtube_new_video', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '5'),
+    ('core', 'youtube_new_video', 'digest', 'object', FALSE, 'Batch videos into a digest (every_minutes or cron)', '{"cron":"0 18 * * *"}'),
+
+    ('steam', 'steam_player_online', 'steam_id', 'string', TRUE, 'SteamID64 of the user', '"76561198000000000"'),
+    ('steam', 'steam_player_online', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '5'),
+
+    ('steam', 'steam_game_sale', 'app_id', 'number', TRUE, 'Steam app ID', '570'),
+    ('steam', 'steam_game_sale', 'country', 'string', FALSE, 'Country code for pricing (e.g. us, fr)', '"us"'),
+    ('steam', 'steam_game_sale', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '10'),
+
+    ('steam', 'steam_price_change', 'app_id', 'number', TRUE, 'Steam app ID', '570'),
+    ('steam', 'steam_price_change', 'country', 'string', FALSE, 'Country code for pricing (e.g. us, fr)', '"us"'),
+    ('steam', 'steam_price_change', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '10'),
+
+    ('crypto', 'crypto_price_threshold', 'coin_id', 'string', TRUE, 'Coin id (CoinGecko, e.g. bitcoin)', '"bitcoin"'),
+    ('crypto', 'crypto_price_threshold', 'currency', 'string', FALSE, 'Currency (e.g. usd, eur)', '"usd"'),
+    ('crypto', 'crypto_price_threshold', 'threshold', 'number', TRUE, 'Price threshold', '50000'),
+    ('crypto', 'crypto_price_threshold', 'direction', 'string', TRUE, 'above or below', '"above"'),
+    ('crypto', 'crypto_price_threshold', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '5'),
+
+    ('crypto', 'crypto_percent_change', 'coin_id', 'string', TRUE, 'Coin id (CoinGecko, e.g. bitcoin)', '"bitcoin"'),
+    ('crypto', 'crypto_percent_change', 'currency', 'string', FALSE, 'Currency (e.g. usd, eur)', '"usd"'),
+    ('crypto', 'crypto_percent_change', 'percent', 'number', TRUE, 'Percent change threshold', '5'),
+    ('crypto', 'crypto_percent_change', 'period', 'string', TRUE, '1h or 24h', '"1h"'),
+    ('crypto', 'crypto_percent_change', 'direction', 'string', FALSE, 'above, below, or any', '"any"'),
+    ('crypto', 'crypto_percent_change', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '5'),
+
+    ('nasa', 'nasa_apod', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '60'),
+
+    ('nasa', 'nasa_mars_photo', 'rover', 'string', TRUE, 'Rover name (curiosity, perseverance, opportunity, spirit)', '"curiosity"'),
+    ('nasa', 'nasa_mars_photo', 'camera', 'string', FALSE, 'Camera name (e.g. FHAZ, RHAZ, NAVCAM)', '"FHAZ"'),
+    ('nasa', 'nasa_mars_photo', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '60'),
+
+    ('nasa', 'nasa_neo_close_approach', 'threshold_km', 'number', TRUE, 'Distance threshold in km', '500000'),
+    ('nasa', 'nasa_neo_close_approach', 'days_ahead', 'number', FALSE, 'Number of days to look ahead', '1'),
+    ('nasa', 'nasa_neo_close_approach', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '60'),
+
+    ('air_quality', 'air_quality_aqi_threshold', 'city', 'string', TRUE, 'City name (e.g. Paris)', '"Paris"'),
+    ('air_quality', 'air_quality_aqi_threshold', 'index', 'string', FALSE, 'AQI index (us_aqi or european_aqi)', '"us_aqi"'),
+    ('air_quality', 'air_quality_aqi_threshold', 'threshold', 'number', TRUE, 'AQI threshold', '100'),
+    ('air_quality', 'air_quality_aqi_threshold', 'direction', 'string', TRUE, 'above or below', '"above"'),
+    ('air_quality', 'air_quality_aqi_threshold', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '10'),
+
+    ('air_quality', 'air_quality_pm25_threshold', 'city', 'string', TRUE, 'City name (e.g. Paris)', '"Paris"'),
+    ('air_quality', 'air_quality_pm25_threshold', 'threshold', 'number', TRUE, 'PM2.5 threshold (µg/m³)', '15'),
+    ('air_quality', 'air_quality_pm25_threshold', 'direction', 'string', TRUE, 'above or below', '"above"'),
+    ('air_quality', 'air_quality_pm25_threshold', 'interval_minutes', 'number', FALSE, 'Polling interval in minutes', '10'),
 
     ('trello', 'trello_create_card', 'api_key', 'string', TRUE, 'Trello API key', NULL),
     ('trello', 'trello_create_card', 'token', 'string', TRUE, 'Trello user token', NULL),
     ('trello', 'trello_create_card', 'list_id', 'string', TRUE, 'Target list ID', NULL),
     ('trello', 'trello_create_card', 'name', 'string', TRUE, 'Card name', NULL),
     ('trello', 'trello_create_card', 'desc', 'string', FALSE, 'Card description', NULL),
-    ('trello', 'trello_create_card', 'pos', 'string', FALSE, 'Card position (top, bottom, or numeric)', '"bottom"'::jsonb),
+    ('trello', 'trello_create_card', 'pos', 'string', FALSE, 'Card position (top, bottom, or numeric)', '"bottom"'),
 
     ('trello', 'trello_move_card', 'api_key', 'string', TRUE, 'Trello API key', NULL),
     ('trello', 'trello_move_card', 'token', 'string', TRUE, 'Trello user token', NULL),
     ('trello', 'trello_move_card', 'card_id', 'string', TRUE, 'Card ID to move', NULL),
     ('trello', 'trello_move_card', 'list_id', 'string', TRUE, 'Destination list ID', NULL),
-    ('trello', 'trello_move_card', 'pos', 'string', FALSE, 'Card position (top, bottom, or numeric)', '"bottom"'::jsonb),
+    ('trello', 'trello_move_card', 'pos', 'string', FALSE, 'Card position (top, bottom, or numeric)', '"bottom"'),
 
     ('trello', 'trello_create_list', 'api_key', 'string', TRUE, 'Trello API key', NULL),
     ('trello', 'trello_create_list', 'token', 'string', TRUE, 'Trello user token', NULL),
     ('trello', 'trello_create_list', 'board_id', 'string', TRUE, 'Board ID', NULL),
     ('trello', 'trello_create_list', 'name', 'string', TRUE, 'List name', NULL),
-    ('trello', 'trello_create_list', 'pos', 'string', FALSE, 'List position (top, bottom, or numeric)', '"bottom"'::jsonb),
+    ('trello', 'trello_create_list', 'pos', 'string', FALSE, 'List position (top, bottom, or numeric)', '"bottom"'),
 
-    ('discord', 'discord_message', 'channel_id', 'string', TRUE, 'Target channel ID', '"123456789012345678"'::jsonb),
-    ('discord', 'discord_message', 'content', 'string', TRUE, 'Message content', '"Hello from Area"'::jsonb),
+    ('discord', 'discord_message', 'channel_id', 'string', TRUE, 'Target channel ID', '"123456789012345678"'),
+    ('discord', 'discord_message', 'content', 'string', TRUE, 'Message content', '"Hello from Area"'),
     ('discord', 'discord_message', 'bot_token', 'string', TRUE, 'Discord bot token', NULL),
 
-    ('discord', 'discord_embed', 'channel_id', 'string', TRUE, 'Target channel ID', '"123456789012345678"'::jsonb),
+    ('discord', 'discord_embed', 'channel_id', 'string', TRUE, 'Target channel ID', '"123456789012345678"'),
     ('discord', 'discord_embed', 'title', 'string', TRUE, 'Embed title', NULL),
     ('discord', 'discord_embed', 'description', 'string', TRUE, 'Embed description', NULL),
     ('discord', 'discord_embed', 'url', 'string', FALSE, 'Embed URL', NULL),
@@ -327,48 +196,48 @@ VALUES
     ('discord', 'discord_add_reaction', 'bot_token', 'string', TRUE, 'Discord bot token', NULL),
 
     ('google', 'google_gmail_send', 'token_id', 'number', TRUE, 'Stored Google token id', NULL),
-    ('google', 'google_gmail_send', 'to', 'string', TRUE, 'Recipient email', '"dest@example.com"'::jsonb),
-    ('google', 'google_gmail_send', 'subject', 'string', TRUE, 'Email subject', '"Hello"'::jsonb),
-    ('google', 'google_gmail_send', 'body', 'string', TRUE, 'Email body', '"Hello from Area"'::jsonb),
+    ('google', 'google_gmail_send', 'to', 'string', TRUE, 'Recipient email', '"dest@example.com"'),
+    ('google', 'google_gmail_send', 'subject', 'string', TRUE, 'Email subject', '"Hello"'),
+    ('google', 'google_gmail_send', 'body', 'string', TRUE, 'Email body', '"Hello from Area"'),
 
     ('google', 'google_calendar_event', 'token_id', 'number', TRUE, 'Stored Google token id', NULL),
-    ('google', 'google_calendar_event', 'summary', 'string', TRUE, 'Event title', '"Area event"'::jsonb),
-    ('google', 'google_calendar_event', 'start', 'string', TRUE, 'Start datetime (RFC3339)', '"2025-12-09T14:00:00Z"'::jsonb),
-    ('google', 'google_calendar_event', 'end', 'string', TRUE, 'End datetime (RFC3339)', '"2025-12-09T15:00:00Z"'::jsonb),
-    ('google', 'google_calendar_event', 'attendees', 'array<string>', FALSE, 'Attendee emails', '["a@example.com"]'::jsonb),
+    ('google', 'google_calendar_event', 'summary', 'string', TRUE, 'Event title', '"Area event"'),
+    ('google', 'google_calendar_event', 'start', 'string', TRUE, 'Start datetime (RFC3339)', '"2025-12-09T14:00:00Z"'),
+    ('google', 'google_calendar_event', 'end', 'string', TRUE, 'End datetime (RFC3339)', '"2025-12-09T15:00:00Z"'),
+    ('google', 'google_calendar_event', 'attendees', 'array<string>', FALSE, 'Attendee emails', '["a@example.com"]'),
 
     ('github', 'github_issue', 'token_id', 'number', TRUE, 'Stored GitHub token id', NULL),
-    ('github', 'github_issue', 'repo', 'string', TRUE, 'Repository in owner/name format', '"owner/repo"'::jsonb),
+    ('github', 'github_issue', 'repo', 'string', TRUE, 'Repository in owner/name format', '"owner/repo"'),
     ('github', 'github_issue', 'title', 'string', TRUE, 'Issue title', NULL),
     ('github', 'github_issue', 'body', 'string', FALSE, 'Issue body', NULL),
     ('github', 'github_issue', 'labels', 'array<string>', FALSE, 'Labels to add', NULL),
 
     ('github', 'github_pull_request', 'token_id', 'number', TRUE, 'Stored GitHub token id', NULL),
-    ('github', 'github_pull_request', 'repo', 'string', TRUE, 'Repository in owner/name format', '"owner/repo"'::jsonb),
+    ('github', 'github_pull_request', 'repo', 'string', TRUE, 'Repository in owner/name format', '"owner/repo"'),
     ('github', 'github_pull_request', 'title', 'string', TRUE, 'Pull request title', NULL),
-    ('github', 'github_pull_request', 'head', 'string', TRUE, 'Source branch (or owner:branch)', '"feature-branch"'::jsonb),
-    ('github', 'github_pull_request', 'base', 'string', TRUE, 'Base branch', '"main"'::jsonb),
+    ('github', 'github_pull_request', 'head', 'string', TRUE, 'Source branch (or owner:branch)', '"feature-branch"'),
+    ('github', 'github_pull_request', 'base', 'string', TRUE, 'Base branch', '"main"'),
     ('github', 'github_pull_request', 'body', 'string', FALSE, 'Pull request body', NULL),
 
-    ('slack', 'slack_message', 'channel_id', 'string', TRUE, 'Target channel ID', '"C1234567890"'::jsonb),
-    ('slack', 'slack_message', 'text', 'string', TRUE, 'Message text', '"Hello from Area"'::jsonb),
+    ('slack', 'slack_message', 'channel_id', 'string', TRUE, 'Target channel ID', '"C1234567890"'),
+    ('slack', 'slack_message', 'text', 'string', TRUE, 'Message text', '"Hello from Area"'),
     ('slack', 'slack_message', 'bot_token', 'string', TRUE, 'Slack bot token', NULL),
 
-    ('slack', 'slack_blocks', 'channel_id', 'string', TRUE, 'Target channel ID', '"C1234567890"'::jsonb),
+    ('slack', 'slack_blocks', 'channel_id', 'string', TRUE, 'Target channel ID', '"C1234567890"'),
     ('slack', 'slack_blocks', 'text', 'string', FALSE, 'Fallback text', NULL),
     ('slack', 'slack_blocks', 'blocks', 'array<object>', TRUE, 'Block Kit JSON array', NULL),
     ('slack', 'slack_blocks', 'bot_token', 'string', TRUE, 'Slack bot token', NULL),
 
-    ('slack', 'slack_update', 'channel_id', 'string', TRUE, 'Target channel ID', '"C1234567890"'::jsonb),
+    ('slack', 'slack_update', 'channel_id', 'string', TRUE, 'Target channel ID', '"C1234567890"'),
     ('slack', 'slack_update', 'message_ts', 'string', TRUE, 'Message timestamp', NULL),
     ('slack', 'slack_update', 'text', 'string', TRUE, 'New message text', NULL),
     ('slack', 'slack_update', 'bot_token', 'string', TRUE, 'Slack bot token', NULL),
 
-    ('slack', 'slack_delete', 'channel_id', 'string', TRUE, 'Target channel ID', '"C1234567890"'::jsonb),
+    ('slack', 'slack_delete', 'channel_id', 'string', TRUE, 'Target channel ID', '"C1234567890"'),
     ('slack', 'slack_delete', 'message_ts', 'string', TRUE, 'Message timestamp', NULL),
     ('slack', 'slack_delete', 'bot_token', 'string', TRUE, 'Slack bot token', NULL),
 
-    ('slack', 'slack_reaction', 'channel_id', 'string', TRUE, 'Target channel ID', '"C1234567890"'::jsonb),
+    ('slack', 'slack_reaction', 'channel_id', 'string', TRUE, 'Target channel ID', '"C1234567890"'),
     ('slack', 'slack_reaction', 'message_ts', 'string', TRUE, 'Message timestamp', NULL),
     ('slack', 'slack_reaction', 'emoji', 'string', TRUE, 'Emoji', NULL),
     ('slack', 'slack_reaction', 'bot_token', 'string', TRUE, 'Slack bot token', NULL),
@@ -392,5 +261,6 @@ VALUES
     ('notion', 'notion_update_page', 'properties', 'object', TRUE, 'Notion properties JSON object', NULL),
     ('notion', 'notion_update_page', 'bot_token', 'string', TRUE, 'Notion token', NULL),
 
-    ('core', 'http_webhook', 'url', 'string', TRUE, 'Target URL', '"https://example.com/hook"'::jsonb),
-    ('core', 'http_webhook', 'payload', 'object', FALSE, 'JSON payload to send', NULL);
+    ('core', 'http_webhook', 'url', 'string', TRUE, 'Target URL', '"https://example.com/hook"'),
+    ('core', 'http_webhook', 'payload', 'object', FALSE, 'JSON payload to send', NULL)
+ON CONFLICT (service_id, capability_id, key) DO NOTHING;
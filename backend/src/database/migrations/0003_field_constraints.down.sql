@@ -0,0 +1,4 @@
+ALTER TABLE area_fields DROP COLUMN pattern;
+ALTER TABLE area_fields DROP COLUMN max_value;
+ALTER TABLE area_fields DROP COLUMN min_value;
+ALTER TABLE area_fields DROP COLUMN enum_values;
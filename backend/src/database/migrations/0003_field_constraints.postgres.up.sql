@@ -0,0 +1,5 @@
+-- Value constraints of catalog fields, checked on trigger configs and reaction payloads.
+ALTER TABLE area_fields ADD COLUMN IF NOT EXISTS enum_values JSONB;
+ALTER TABLE area_fields ADD COLUMN IF NOT EXISTS min_value DOUBLE PRECISION;
+ALTER TABLE area_fields ADD COLUMN IF NOT EXISTS max_value DOUBLE PRECISION;
+ALTER TABLE area_fields ADD COLUMN IF NOT EXISTS pattern TEXT;
@@ -0,0 +1,5 @@
+-- Value constraints of catalog fields, checked on trigger configs and reaction payloads.
+ALTER TABLE area_fields ADD COLUMN enum_values TEXT;
+ALTER TABLE area_fields ADD COLUMN min_value REAL;
+ALTER TABLE area_fields ADD COLUMN max_value REAL;
+ALTER TABLE area_fields ADD COLUMN pattern TEXT;
@@ -0,0 +1,15 @@
+-- Login sessions. Only SHA-256 hashes of the access and refresh tokens are stored.
+CREATE TABLE IF NOT EXISTS sessions (
+    id                  SERIAL PRIMARY KEY,
+    user_id             INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    access_token_hash   TEXT NOT NULL,
+    refresh_token_hash  TEXT NOT NULL,
+    access_expires_at   TIMESTAMPTZ NOT NULL,
+    refresh_expires_at  TIMESTAMPTZ NOT NULL,
+    revoked_at          TIMESTAMPTZ,
+    created_at          TIMESTAMPTZ DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_access_token_hash ON sessions (access_token_hash);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_refresh_token_hash ON sessions (refresh_token_hash);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
@@ -0,0 +1,15 @@
+-- Login sessions. Only SHA-256 hashes of the access and refresh tokens are stored.
+CREATE TABLE sessions (
+    id                  INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id             INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    access_token_hash   TEXT NOT NULL,
+    refresh_token_hash  TEXT NOT NULL,
+    access_expires_at   DATETIME NOT NULL,
+    refresh_expires_at  DATETIME NOT NULL,
+    revoked_at          DATETIME,
+    created_at          DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at          DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_sessions_access_token_hash ON sessions (access_token_hash);
+CREATE UNIQUE INDEX idx_sessions_refresh_token_hash ON sessions (refresh_token_hash);
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
@@ -0,0 +1,14 @@
+-- Personal access tokens for scripts and CI. Only the SHA-256 hash of a token is stored; scopes
+-- are space-separated.
+CREATE TABLE api_tokens (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id       INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name          TEXT NOT NULL,
+    token_hash    TEXT NOT NULL,
+    scopes        TEXT NOT NULL,
+    expires_at    DATETIME,
+    last_used_at  DATETIME,
+    created_at    DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_api_tokens_token_hash ON api_tokens (token_hash);
+CREATE INDEX idx_api_tokens_user_id ON api_tokens (user_id);
@@ -0,0 +1,14 @@
+-- Email verification, and single-use tokens for password resets and email verification. Only
+-- the SHA-256 hash of a token is stored.
+ALTER TABLE users ADD COLUMN email_verified_at DATETIME;
+CREATE TABLE account_tokens (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id     INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose     TEXT NOT NULL,
+    token_hash  TEXT NOT NULL,
+    expires_at  DATETIME NOT NULL,
+    used_at     DATETIME,
+    created_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_account_tokens_token_hash ON account_tokens (token_hash);
+CREATE INDEX idx_account_tokens_user_id ON account_tokens (user_id);
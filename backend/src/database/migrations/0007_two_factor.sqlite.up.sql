@@ -0,0 +1,18 @@
+-- TOTP two-factor authentication. A row without enabled_at is an enrollment waiting for its
+-- first code; last_used_step keeps a code from being accepted twice. Recovery codes are stored
+-- as SHA-256 hashes.
+CREATE TABLE two_factor (
+    user_id         INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret          TEXT NOT NULL,
+    enabled_at      DATETIME,
+    last_used_step  INTEGER NOT NULL DEFAULT 0,
+    created_at      DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE recovery_codes (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id     INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash   TEXT NOT NULL,
+    used_at     DATETIME,
+    created_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes (user_id);
@@ -0,0 +1,17 @@
+-- Login throttling shared by every replica: the failed logins in a row of an account or IP
+-- address, keyed "email:<address>" or "ip:<address>", and the end of their lockout. Failed
+-- logins are also logged one row each.
+CREATE TABLE IF NOT EXISTS login_throttles (
+    throttle_key     TEXT PRIMARY KEY,
+    failures         INTEGER NOT NULL DEFAULT 0,
+    locked_until     TIMESTAMPTZ,
+    last_failure_at  TIMESTAMPTZ NOT NULL
+);
+CREATE TABLE IF NOT EXISTS login_failures (
+    id          SERIAL PRIMARY KEY,
+    email       TEXT NOT NULL,
+    ip          TEXT NOT NULL,
+    created_at  TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_login_failures_email ON login_failures (email);
+CREATE INDEX IF NOT EXISTS idx_login_failures_created_at ON login_failures (created_at);
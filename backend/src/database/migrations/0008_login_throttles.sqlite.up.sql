@@ -0,0 +1,17 @@
+-- Login throttling shared by every replica: the failed logins in a row of an account or IP
+-- address, keyed "email:<address>" or "ip:<address>", and the end of their lockout. Failed
+-- logins are also logged one row each.
+CREATE TABLE login_throttles (
+    throttle_key     TEXT PRIMARY KEY,
+    failures         INTEGER NOT NULL DEFAULT 0,
+    locked_until     DATETIME,
+    last_failure_at  DATETIME NOT NULL
+);
+CREATE TABLE login_failures (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    email       TEXT NOT NULL,
+    ip          TEXT NOT NULL,
+    created_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_login_failures_email ON login_failures (email);
+CREATE INDEX idx_login_failures_created_at ON login_failures (created_at);
@@ -0,0 +1,8 @@
+ALTER TABLE github_tokens DROP COLUMN workspace_id;
+ALTER TABLE google_tokens DROP COLUMN workspace_id;
+DROP INDEX IF EXISTS idx_workflows_workspace_id;
+ALTER TABLE workflows DROP COLUMN workspace_id;
+DROP TABLE IF EXISTS audit_events;
+DROP TABLE IF EXISTS workspace_invites;
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
@@ -0,0 +1,44 @@
+-- Shared workspaces. Members hold a role: owners manage the members, editors change the
+-- workflows and viewers only read them. Invites are mailed and stored as SHA-256 hashes.
+-- Workflows and OAuth connections with a workspace_id belong to that workspace; audit_events
+-- records who changed what there.
+CREATE TABLE IF NOT EXISTS workspaces (
+    id          SERIAL PRIMARY KEY,
+    name        VARCHAR(255) NOT NULL,
+    created_at  TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id  INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id       INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role          VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    created_at    TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (workspace_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user_id ON workspace_members (user_id);
+CREATE TABLE IF NOT EXISTS workspace_invites (
+    id            SERIAL PRIMARY KEY,
+    workspace_id  INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    email         TEXT NOT NULL,
+    role          VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    token_hash    TEXT NOT NULL UNIQUE,
+    invited_by    INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    accepted_at   TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_workspace_invites_workspace_id ON workspace_invites (workspace_id);
+CREATE TABLE IF NOT EXISTS audit_events (
+    id            SERIAL PRIMARY KEY,
+    workspace_id  INTEGER,
+    actor_id      INTEGER,
+    action        TEXT NOT NULL,
+    target        TEXT NOT NULL,
+    detail        TEXT NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_workspace_id ON audit_events (workspace_id, created_at);
+
+ALTER TABLE workflows ADD COLUMN IF NOT EXISTS workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_workflows_workspace_id ON workflows (workspace_id);
+ALTER TABLE google_tokens ADD COLUMN IF NOT EXISTS workspace_id INTEGER REFERENCES workspaces(id) ON DELETE SET NULL;
+ALTER TABLE github_tokens ADD COLUMN IF NOT EXISTS workspace_id INTEGER REFERENCES workspaces(id) ON DELETE SET NULL;
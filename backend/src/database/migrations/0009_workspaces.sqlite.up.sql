@@ -0,0 +1,45 @@
+-- Shared workspaces. Members hold a role: owners manage the members, editors change the
+-- workflows and viewers only read them. Invites are mailed and stored as SHA-256 hashes.
+-- Workflows and OAuth connections with a workspace_id belong to that workspace; audit_events
+-- records who changed what there.
+CREATE TABLE workspaces (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    name        VARCHAR(255) NOT NULL,
+    created_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE workspace_members (
+    workspace_id  INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id       INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role          VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    created_at    DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace_id, user_id)
+);
+CREATE INDEX idx_workspace_members_user_id ON workspace_members (user_id);
+CREATE TABLE workspace_invites (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id  INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    email         TEXT NOT NULL,
+    role          VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    token_hash    TEXT NOT NULL UNIQUE,
+    invited_by    INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    expires_at    DATETIME NOT NULL,
+    accepted_at   DATETIME,
+    created_at    DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_workspace_invites_workspace_id ON workspace_invites (workspace_id);
+CREATE TABLE audit_events (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id  INTEGER,
+    actor_id      INTEGER,
+    action        TEXT NOT NULL,
+    target        TEXT NOT NULL,
+    detail        TEXT NOT NULL DEFAULT '',
+    created_at    DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_audit_events_workspace_id ON audit_events (workspace_id, created_at);
+
+-- SQLite cannot drop a column with a foreign key, so the added columns go without one.
+ALTER TABLE workflows ADD COLUMN workspace_id INTEGER;
+CREATE INDEX idx_workflows_workspace_id ON workflows (workspace_id);
+ALTER TABLE google_tokens ADD COLUMN workspace_id INTEGER;
+ALTER TABLE github_tokens ADD COLUMN workspace_id INTEGER;
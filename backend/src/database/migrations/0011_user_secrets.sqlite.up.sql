@@ -0,0 +1,11 @@
+-- Named secrets of a user, referenced from workflows as {{secrets.<name>}}. Values are encrypted
+-- with APP_SECRET_KEY when it is set.
+CREATE TABLE user_secrets (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id     INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name        TEXT NOT NULL,
+    value       TEXT NOT NULL,
+    created_at  DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_user_secrets_user_name ON user_secrets (user_id, name);
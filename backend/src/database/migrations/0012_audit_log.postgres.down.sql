@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+DROP FUNCTION IF EXISTS audit_events_append_only();
+DROP INDEX IF EXISTS idx_audit_events_created_at;
+DROP INDEX IF EXISTS idx_audit_events_actor_id;
+ALTER TABLE audit_events DROP COLUMN IF EXISTS after_state;
+ALTER TABLE audit_events DROP COLUMN IF EXISTS before_state;
+ALTER TABLE audit_events DROP COLUMN IF EXISTS user_agent;
+ALTER TABLE audit_events DROP COLUMN IF EXISTS ip;
@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS audit_events_no_delete;
+DROP TRIGGER IF EXISTS audit_events_no_update;
+DROP INDEX IF EXISTS idx_audit_events_created_at;
+DROP INDEX IF EXISTS idx_audit_events_actor_id;
+ALTER TABLE audit_events DROP COLUMN after_state;
+ALTER TABLE audit_events DROP COLUMN before_state;
+ALTER TABLE audit_events DROP COLUMN user_agent;
+ALTER TABLE audit_events DROP COLUMN ip;
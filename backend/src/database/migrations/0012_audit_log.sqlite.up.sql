@@ -0,0 +1,18 @@
+-- The audit log covers security and configuration changes of every user, not only those of
+-- workspaces: it records the address and user agent of the request and the state of the target
+-- before and after the change. Rows can be added but never changed or removed.
+ALTER TABLE audit_events ADD COLUMN ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE audit_events ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE audit_events ADD COLUMN before_state TEXT;
+ALTER TABLE audit_events ADD COLUMN after_state TEXT;
+CREATE INDEX idx_audit_events_actor_id ON audit_events (actor_id, created_at);
+CREATE INDEX idx_audit_events_created_at ON audit_events (created_at);
+
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+CREATE TRIGGER audit_events_no_delete BEFORE DELETE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
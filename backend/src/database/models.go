@@ -13,6 +13,8 @@ type User struct {
 	Lastname     string
 	Email        string `gorm:"uniqueIndex:uni_users_email"`
 	PasswordHash string
+	// EmailVerifiedAt is set once the user follows the link of a verification email.
+	EmailVerifiedAt *time.Time
 }
 
 type Job struct {
@@ -24,6 +26,12 @@ type Job struct {
 	Error      string
 	StartedAt  *time.Time
 	EndedAt    *time.Time
+	// Outcome of the reaction call, kept for diagnosing runs.
+	ResponseStatus int
+	ResponseBody   string
+	LatencyMs      int64
+	// RequestID correlates the job's logs with the request that enqueued it.
+	RequestID string
 }
 
 type Run struct {
@@ -38,6 +46,33 @@ type Run struct {
 // TableName aligns with legacy schema initialized from SQL files.
 func (Run) TableName() string { return "workflow_runs" }
 
+// DigestItem buffers a trigger event until the workflow's digest is flushed.
+type DigestItem struct {
+	gorm.Model
+	WorkflowID uint            `gorm:"not null;index"`
+	Payload    json.RawMessage `gorm:"type:jsonb"`
+}
+
+func (DigestItem) TableName() string { return "digest_items" }
+
+// PollerState keeps a poller's bookkeeping (last seen items, last checks) across restarts.
+type PollerState struct {
+	Name      string          `gorm:"primaryKey"`
+	State     json.RawMessage `gorm:"type:jsonb"`
+	UpdatedAt time.Time
+}
+
+func (PollerState) TableName() string { return "poller_states" }
+
+// Lease grants one replica exclusive ownership of a named background task until it expires.
+type Lease struct {
+	Name      string `gorm:"primaryKey"`
+	Holder    string `gorm:"not null"`
+	ExpiresAt time.Time
+}
+
+func (Lease) TableName() string { return "leases" }
+
 type Workflow struct {
 	gorm.Model
 	UserID        uint            `gorm:"not null;index"`
@@ -47,6 +82,8 @@ type Workflow struct {
 	ActionURL     string          `gorm:"not null"`
 	Enabled       bool            `gorm:"default:false"`
 	NextRunAt     *time.Time
+	// WorkspaceID is set on workflows shared in a workspace; UserID stays their creator.
+	WorkspaceID *uint `gorm:"index"`
 }
 
 type GoogleToken struct {
@@ -56,6 +93,8 @@ type GoogleToken struct {
 	RefreshToken string
 	Expiry       time.Time
 	CreatedAt    time.Time
+	// WorkspaceID is set once the user shares the connection with a workspace.
+	WorkspaceID *int64
 }
 
 type GithubToken struct {
@@ -65,6 +104,8 @@ type GithubToken struct {
 	TokenType   string
 	Scope       string
 	CreatedAt   time.Time
+	// WorkspaceID is set once the user shares the connection with a workspace.
+	WorkspaceID *int64
 }
 
 type AreaService struct {
@@ -72,7 +113,7 @@ type AreaService struct {
 	Name        string
 	Enabled     bool
 	MoreInfo    string
-	OAuthScopes json.RawMessage `gorm:"type:jsonb"`
+	OAuthScopes json.RawMessage `gorm:"column:oauth_scopes;type:jsonb"`
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 }
@@ -102,8 +143,175 @@ type AreaField struct {
 	Required     bool
 	Description  string
 	Example      json.RawMessage `gorm:"type:jsonb"`
+	EnumValues   json.RawMessage `gorm:"type:jsonb"`
+	MinValue     *float64
+	MaxValue     *float64
+	Pattern      string
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
 
 func (AreaField) TableName() string { return "area_fields" }
+
+// Session is a login. It holds the hashes of its current access and refresh tokens; refreshing
+// replaces both, logging out sets RevokedAt.
+type Session struct {
+	ID               uint   `gorm:"primaryKey"`
+	UserID           uint   `gorm:"not null;index"`
+	AccessTokenHash  string `gorm:"uniqueIndex"`
+	RefreshTokenHash string `gorm:"uniqueIndex"`
+	AccessExpiresAt  time.Time
+	RefreshExpiresAt time.Time
+	RevokedAt        *time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+func (Session) TableName() string { return "sessions" }
+
+// APIToken is a personal access token. It holds the hash of the token and its space-separated
+// scopes; a nil ExpiresAt never expires.
+type APIToken struct {
+	ID         uint   `gorm:"primaryKey"`
+	UserID     uint   `gorm:"not null;index"`
+	Name       string `gorm:"not null"`
+	TokenHash  string `gorm:"uniqueIndex"`
+	Scopes     string `gorm:"not null"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+func (APIToken) TableName() string { return "api_tokens" }
+
+// AccountToken is a single-use token mailed to a user, to reset their password or verify their
+// email address depending on Purpose.
+type AccountToken struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	Purpose   string `gorm:"not null"`
+	TokenHash string `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (AccountToken) TableName() string { return "account_tokens" }
+
+// TwoFactor is the TOTP enrollment of a user. EnabledAt stays nil until the user confirms a
+// first code, and LastUsedStep is the time step of the last accepted code.
+type TwoFactor struct {
+	UserID       uint `gorm:"primaryKey;autoIncrement:false"`
+	Secret       string
+	EnabledAt    *time.Time
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+func (TwoFactor) TableName() string { return "two_factor" }
+
+// RecoveryCode is the hash of a single-use code that stands in for a TOTP code.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (RecoveryCode) TableName() string { return "recovery_codes" }
+
+// LoginThrottle counts the failed logins in a row of an account or IP address, keyed
+// "email:<address>" or "ip:<address>", and holds the end of their lockout.
+type LoginThrottle struct {
+	ThrottleKey   string `gorm:"primaryKey"`
+	Failures      int
+	LockedUntil   *time.Time
+	LastFailureAt time.Time
+}
+
+func (LoginThrottle) TableName() string { return "login_throttles" }
+
+// LoginFailure logs a failed login.
+type LoginFailure struct {
+	ID        uint   `gorm:"primaryKey"`
+	Email     string `gorm:"not null;index"`
+	IP        string `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+func (LoginFailure) TableName() string { return "login_failures" }
+
+// Roles of workspace members, from the most to the least privileged.
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// Workspace is shared by its members, with the workflows and OAuth connections in it.
+type Workspace struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+func (Workspace) TableName() string { return "workspaces" }
+
+// WorkspaceMember gives a user a role in a workspace.
+type WorkspaceMember struct {
+	WorkspaceID uint   `gorm:"primaryKey;autoIncrement:false"`
+	UserID      uint   `gorm:"primaryKey;autoIncrement:false;index"`
+	Role        string `gorm:"not null"`
+	CreatedAt   time.Time
+}
+
+func (WorkspaceMember) TableName() string { return "workspace_members" }
+
+// WorkspaceInvite is a mailed invitation to join a workspace with a role. It holds the hash of
+// its token and AcceptedAt once used.
+type WorkspaceInvite struct {
+	ID          uint   `gorm:"primaryKey"`
+	WorkspaceID uint   `gorm:"not null;index"`
+	Email       string `gorm:"not null"`
+	Role        string `gorm:"not null"`
+	TokenHash   string `gorm:"uniqueIndex"`
+	InvitedBy   *uint
+	ExpiresAt   time.Time
+	AcceptedAt  *time.Time
+	CreatedAt   time.Time
+}
+
+func (WorkspaceInvite) TableName() string { return "workspace_invites" }
+
+// AuditEvent records that ActorID made a change, named by Action, to Target, from the address
+// IP. BeforeState and AfterState hold the parts of Target the change touched. Rows are only
+// ever added.
+type AuditEvent struct {
+	ID          uint `gorm:"primaryKey"`
+	WorkspaceID *uint
+	ActorID     *uint
+	Action      string          `gorm:"not null"`
+	Target      string          `gorm:"not null"`
+	Detail      string          `gorm:"not null"`
+	IP          string          `gorm:"not null"`
+	UserAgent   string          `gorm:"not null"`
+	BeforeState json.RawMessage `gorm:"type:jsonb"`
+	AfterState  json.RawMessage `gorm:"type:jsonb"`
+	CreatedAt   time.Time
+}
+
+func (AuditEvent) TableName() string { return "audit_events" }
+
+// UserSecret is a named secret of a user that workflows reference as {{secrets.<name>}}. Value is
+// encrypted with APP_SECRET_KEY when it is set.
+type UserSecret struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_user_secrets_user_name"`
+	Name      string `gorm:"not null;uniqueIndex:idx_user_secrets_user_name"`
+	Value     string `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (UserSecret) TableName() string { return "user_secrets" }
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"area/src/security"
+
+	"gorm.io/gorm"
+)
+
+// rewriteBatch is how many rows the rewrites of encrypted columns load at once.
+const rewriteBatch = 100
+
+// sealToken encrypts a provider token or user secret for storage when APP_SECRET_KEY is set. A
+// value already encrypted with a previous key is re-encrypted with the current one.
+func sealToken(value string) (string, error) {
+	if !security.Enabled() || value == "" {
+		return value, nil
+	}
+	if security.IsEncrypted(value) {
+		rotated, _, err := security.Reencrypt(value)
+		return rotated, err
+	}
+	return security.EncryptString(value)
+}
+
+// openGoogleToken decrypts the tokens of a stored Google connection in place.
+func openGoogleToken(t *GoogleToken) error {
+	access, err := security.DecryptString(t.AccessToken)
+	if err != nil {
+		return fmt.Errorf("decrypt google access token: %w", err)
+	}
+	refresh, err := security.DecryptString(t.RefreshToken)
+	if err != nil {
+		return fmt.Errorf("decrypt google refresh token: %w", err)
+	}
+	t.AccessToken, t.RefreshToken = access, refresh
+	return nil
+}
+
+// openGithubToken decrypts the token of a stored GitHub connection in place.
+func openGithubToken(t *GithubToken) error {
+	access, err := security.DecryptString(t.AccessToken)
+	if err != nil {
+		return fmt.Errorf("decrypt github access token: %w", err)
+	}
+	t.AccessToken = access
+	return nil
+}
+
+// ReencryptOAuthTokens encrypts every stored provider token with the current key, those stored
+// in plaintext included, and returns how many connections changed.
+func ReencryptOAuthTokens(ctx context.Context) (int, error) {
+	return rewriteOAuthTokens(Db.WithContext(ctx), sealToken)
+}
+
+// encryptOAuthTokens is the up step of the encrypt_oauth_tokens migration. It waits for
+// APP_SECRET_KEY: without it nothing would be encrypted.
+func encryptOAuthTokens(tx *gorm.DB) error {
+	_, err := rewriteOAuthTokens(tx, sealToken)
+	return err
+}
+
+// decryptOAuthTokens is the down step of the encrypt_oauth_tokens migration, storing the tokens
+// in plaintext again.
+func decryptOAuthTokens(tx *gorm.DB) error {
+	_, err := rewriteOAuthTokens(tx, security.DecryptString)
+	return err
+}
+
+// rewriteOAuthTokens replaces the token columns of every Google and GitHub connection, deleted
+// ones included, with their image by fn and returns how many rows changed.
+func rewriteOAuthTokens(db *gorm.DB, fn func(string) (string, error)) (int, error) {
+	changed := 0
+	var google []GoogleToken
+	err := db.Unscoped().Select("id", "access_token", "refresh_token").
+		FindInBatches(&google, rewriteBatch, func(*gorm.DB, int) error {
+			for _, t := range google {
+				access, err := fn(t.AccessToken)
+				if err != nil {
+					return fmt.Errorf("google token %d: %w", t.ID, err)
+				}
+				refresh, err := fn(t.RefreshToken)
+				if err != nil {
+					return fmt.Errorf("google token %d: %w", t.ID, err)
+				}
+				if access == t.AccessToken && refresh == t.RefreshToken {
+					continue
+				}
+				if err := db.Unscoped().Model(&GoogleToken{}).Where("id = ?", t.ID).
+					UpdateColumns(map[string]any{"access_token": access, "refresh_token": refresh}).Error; err != nil {
+					return fmt.Errorf("google token %d: %w", t.ID, err)
+				}
+				changed++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return changed, fmt.Errorf("rewrite google tokens: %w", err)
+	}
+
+	var github []GithubToken
+	err = db.Unscoped().Select("id", "access_token").
+		FindInBatches(&github, rewriteBatch, func(*gorm.DB, int) error {
+			for _, t := range github {
+				access, err := fn(t.AccessToken)
+				if err != nil {
+					return fmt.Errorf("github token %d: %w", t.ID, err)
+				}
+				if access == t.AccessToken {
+					continue
+				}
+				if err := db.Unscoped().Model(&GithubToken{}).Where("id = ?", t.ID).
+					UpdateColumn("access_token", access).Error; err != nil {
+					return fmt.Errorf("github token %d: %w", t.ID, err)
+				}
+				changed++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return changed, fmt.Errorf("rewrite github tokens: %w", err)
+	}
+	return changed, nil
+}
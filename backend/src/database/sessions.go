@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreateSession inserts a session and sets its ID.
+func CreateSession(ctx context.Context, session *Session) error {
+	if err := gorm.G[Session](Db).Create(ctx, session); err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+	return nil
+}
+
+// GetSessionByAccessHash returns the session whose current access token has the given hash.
+func GetSessionByAccessHash(ctx context.Context, hash string) (*Session, error) {
+	session, err := gorm.G[Session](Db).Where("access_token_hash = ?", hash).First(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get session by access token: %w", err)
+	}
+	return &session, nil
+}
+
+// GetSessionByRefreshHash returns the session whose current refresh token has the given hash.
+func GetSessionByRefreshHash(ctx context.Context, hash string) (*Session, error) {
+	session, err := gorm.G[Session](Db).Where("refresh_token_hash = ?", hash).First(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get session by refresh token: %w", err)
+	}
+	return &session, nil
+}
+
+// RotateSession replaces the tokens of a live session, provided its refresh token is still
+// oldRefreshHash, so two concurrent refreshes cannot both succeed. It reports whether it did.
+func RotateSession(ctx context.Context, id uint, oldRefreshHash string, next Session) (bool, error) {
+	rows, err := gorm.G[Session](Db).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", id, oldRefreshHash).
+		Select("access_token_hash", "refresh_token_hash", "access_expires_at", "refresh_expires_at", "updated_at").
+		Updates(ctx, Session{
+			AccessTokenHash:  next.AccessTokenHash,
+			RefreshTokenHash: next.RefreshTokenHash,
+			AccessExpiresAt:  next.AccessExpiresAt,
+			RefreshExpiresAt: next.RefreshExpiresAt,
+			UpdatedAt:        time.Now(),
+		})
+	if err != nil {
+		return false, fmt.Errorf("rotate session: %w", err)
+	}
+	return rows == 1, nil
+}
+
+// RevokeSession ends a session; its tokens stop working.
+func RevokeSession(ctx context.Context, id uint, at time.Time) error {
+	_, err := gorm.G[Session](Db).Where("id = ? AND revoked_at IS NULL", id).Update(ctx, "revoked_at", at)
+	if err != nil {
+		return fmt.Errorf("revoke session: %w", err)
+	}
+	return nil
+}
+
+// RevokeUserSessions ends every live session of a user.
+func RevokeUserSessions(ctx context.Context, userID uint, at time.Time) error {
+	_, err := gorm.G[Session](Db).Where("user_id = ? AND revoked_at IS NULL", userID).Update(ctx, "revoked_at", at)
+	if err != nil {
+		return fmt.Errorf("revoke user sessions: %w", err)
+	}
+	return nil
+}
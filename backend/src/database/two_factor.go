@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetTwoFactor returns the TOTP enrollment of a user.
+func GetTwoFactor(ctx context.Context, userID uint) (*TwoFactor, error) {
+	row, err := gorm.G[TwoFactor](Db).Where("user_id = ?", userID).First(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get two factor: %w", err)
+	}
+	return &row, nil
+}
+
+// SaveTwoFactorSecret starts a TOTP enrollment of a user, replacing one not yet enabled.
+func SaveTwoFactorSecret(ctx context.Context, userID uint, secret string) error {
+	err := Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := gorm.G[TwoFactor](tx).Where("user_id = ? AND enabled_at IS NULL", userID).Delete(ctx); err != nil {
+			return err
+		}
+		return gorm.G[TwoFactor](tx).Create(ctx, &TwoFactor{UserID: userID, Secret: secret})
+	})
+	if err != nil {
+		return fmt.Errorf("save two factor secret: %w", err)
+	}
+	return nil
+}
+
+// EnableTwoFactor enables the pending TOTP enrollment of a user, recording step as used, and
+// replaces their recovery codes. It reports false when there is no pending enrollment.
+func EnableTwoFactor(ctx context.Context, userID uint, at time.Time, step int64, codeHashes []string) (bool, error) {
+	enabled := false
+	err := Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		rows, err := gorm.G[TwoFactor](tx).
+			Where("user_id = ? AND enabled_at IS NULL", userID).
+			Updates(ctx, TwoFactor{EnabledAt: &at, LastUsedStep: step})
+		if err != nil || rows != 1 {
+			return err
+		}
+		if _, err := gorm.G[RecoveryCode](tx).Where("user_id = ?", userID).Delete(ctx); err != nil {
+			return err
+		}
+		codes := make([]RecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, RecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		if err := gorm.G[RecoveryCode](tx).CreateInBatches(ctx, &codes, len(codes)); err != nil {
+			return err
+		}
+		enabled = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("enable two factor: %w", err)
+	}
+	return enabled, nil
+}
+
+// UseTwoFactorStep records step as the last used TOTP time step of a user, provided it is later
+// than the previous one, so a code is accepted once even under concurrent logins. It reports
+// whether it did.
+func UseTwoFactorStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	rows, err := gorm.G[TwoFactor](Db).
+		Where("user_id = ? AND enabled_at IS NOT NULL AND last_used_step < ?", userID, step).
+		Update(ctx, "last_used_step", step)
+	if err != nil {
+		return false, fmt.Errorf("use two factor step: %w", err)
+	}
+	return rows == 1, nil
+}
+
+// UseRecoveryCode marks the unused recovery code of a user with the given hash used and reports
+// whether there was one.
+func UseRecoveryCode(ctx context.Context, userID uint, hash string, at time.Time) (bool, error) {
+	rows, err := gorm.G[RecoveryCode](Db).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update(ctx, "used_at", at)
+	if err != nil {
+		return false, fmt.Errorf("use recovery code: %w", err)
+	}
+	return rows == 1, nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left.
+func CountRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	count, err := gorm.G[RecoveryCode](Db).Where("user_id = ? AND used_at IS NULL", userID).Count(ctx, "*")
+	if err != nil {
+		return 0, fmt.Errorf("count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteTwoFactor removes the TOTP enrollment and recovery codes of a user.
+func DeleteTwoFactor(ctx context.Context, userID uint) error {
+	err := Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := gorm.G[RecoveryCode](tx).Where("user_id = ?", userID).Delete(ctx); err != nil {
+			return err
+		}
+		_, err := gorm.G[TwoFactor](tx).Where("user_id = ?", userID).Delete(ctx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("delete two factor: %w", err)
+	}
+	return nil
+}
+
+// ListTwoFactors returns every TOTP enrollment.
+func ListTwoFactors(ctx context.Context) ([]TwoFactor, error) {
+	rows, err := gorm.G[TwoFactor](Db).Order("user_id").Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list two factor: %w", err)
+	}
+	return rows, nil
+}
+
+// ReplaceTwoFactorSecret stores secret for a user whose stored secret is still old. It reports
+// false when the secret changed in between.
+func ReplaceTwoFactorSecret(ctx context.Context, userID uint, old, secret string) (bool, error) {
+	rows, err := gorm.G[TwoFactor](Db).Where("user_id = ? AND secret = ?", userID, old).Update(ctx, "secret", secret)
+	if err != nil {
+		return false, fmt.Errorf("replace two factor secret: %w", err)
+	}
+	return rows == 1, nil
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"area/src/security"
+
+	"gorm.io/gorm"
+)
+
+// ListUserSecrets returns the secrets of a user by name, without their values.
+func ListUserSecrets(ctx context.Context, userID uint) ([]UserSecret, error) {
+	rows, err := gorm.G[UserSecret](Db).Select("id", "user_id", "name", "created_at", "updated_at").
+		Where("user_id = ?", userID).Order("name").Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list user secrets: %w", err)
+	}
+	return rows, nil
+}
+
+// PutUserSecret stores the value of a secret of a user, encrypted when APP_SECRET_KEY is set,
+// replacing the value of a secret of the same name. It reports whether the secret is new.
+func PutUserSecret(ctx context.Context, userID uint, name, value string, at time.Time) (bool, error) {
+	sealed, err := sealToken(value)
+	if err != nil {
+		return false, fmt.Errorf("put user secret: %w", err)
+	}
+	created := false
+	err = Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		rows, err := gorm.G[UserSecret](tx).Where("user_id = ? AND name = ?", userID, name).
+			Updates(ctx, UserSecret{Value: sealed, UpdatedAt: at})
+		if err != nil || rows > 0 {
+			return err
+		}
+		created = true
+		return gorm.G[UserSecret](tx).Create(ctx, &UserSecret{UserID: userID, Name: name, Value: sealed, CreatedAt: at, UpdatedAt: at})
+	})
+	if err != nil {
+		return false, fmt.Errorf("put user secret: %w", err)
+	}
+	return created, nil
+}
+
+// GetUserSecretValue returns the decrypted value of a secret of a user.
+func GetUserSecretValue(ctx context.Context, userID uint, name string) (string, error) {
+	row, err := gorm.G[UserSecret](Db).Where("user_id = ? AND name = ?", userID, name).First(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get user secret: %w", err)
+	}
+	value, err := security.DecryptString(row.Value)
+	if err != nil {
+		return "", fmt.Errorf("decrypt user secret %q: %w", name, err)
+	}
+	return value, nil
+}
+
+// DeleteUserSecret deletes a secret of a user and reports whether it existed.
+func DeleteUserSecret(ctx context.Context, userID uint, name string) (bool, error) {
+	rows, err := gorm.G[UserSecret](Db).Where("user_id = ? AND name = ?", userID, name).Delete(ctx)
+	if err != nil {
+		return false, fmt.Errorf("delete user secret: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// ReencryptUserSecrets encrypts every stored user secret with the current key, those stored in
+// plaintext included, and returns how many changed.
+func ReencryptUserSecrets(ctx context.Context) (int, error) {
+	db := Db.WithContext(ctx)
+	changed := 0
+	var rows []UserSecret
+	err := db.Select("id", "value").FindInBatches(&rows, rewriteBatch, func(*gorm.DB, int) error {
+		for _, row := range rows {
+			sealed, err := sealToken(row.Value)
+			if err != nil {
+				return fmt.Errorf("user secret %d: %w", row.ID, err)
+			}
+			if sealed == row.Value {
+				continue
+			}
+			// Only replace the value read, not one written meanwhile with the current key.
+			result := db.Model(&UserSecret{}).Where("id = ? AND value = ?", row.ID, row.Value).UpdateColumn("value", sealed)
+			if result.Error != nil {
+				return fmt.Errorf("user secret %d: %w", row.ID, result.Error)
+			}
+			changed += int(result.RowsAffected)
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return changed, fmt.Errorf("re-encrypt user secrets: %w", err)
+	}
+	return changed, nil
+}
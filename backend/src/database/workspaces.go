@@ -0,0 +1,219 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WorkspaceWithRole is a workspace as one of its members sees it.
+type WorkspaceWithRole struct {
+	ID        uint
+	Name      string
+	Role      string
+	CreatedAt time.Time
+}
+
+// WorkspaceMemberRow is a member of a workspace with their email address.
+type WorkspaceMemberRow struct {
+	UserID    uint
+	Email     string
+	Role      string
+	CreatedAt time.Time
+}
+
+// memberWorkspaces is the subquery of the IDs of the workspaces a user belongs to, restricted to
+// the given roles when there are any.
+func memberWorkspaces(userID int64, roles ...string) *gorm.DB {
+	query := Db.Model(&WorkspaceMember{}).Select("workspace_id").Where("user_id = ?", userID)
+	if len(roles) > 0 {
+		query = query.Where("role IN ?", roles)
+	}
+	return query
+}
+
+// CreateWorkspace inserts a workspace with ownerID as its owner.
+func CreateWorkspace(ctx context.Context, name string, ownerID uint) (*Workspace, error) {
+	workspace := &Workspace{Name: name}
+	err := Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := gorm.G[Workspace](tx).Create(ctx, workspace); err != nil {
+			return err
+		}
+		return gorm.G[WorkspaceMember](tx).Create(ctx, &WorkspaceMember{WorkspaceID: workspace.ID, UserID: ownerID, Role: RoleOwner})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create workspace: %w", err)
+	}
+	return workspace, nil
+}
+
+// workspacesWithRole selects the workspaces of a user with their role in each.
+func workspacesWithRole(ctx context.Context, userID uint) *gorm.DB {
+	return Db.WithContext(ctx).Table("workspaces").
+		Select("workspaces.id, workspaces.name, workspaces.created_at, workspace_members.role").
+		Joins("JOIN workspace_members ON workspace_members.workspace_id = workspaces.id").
+		Where("workspace_members.user_id = ?", userID)
+}
+
+// ListWorkspacesForUser returns the workspaces a user belongs to, oldest first.
+func ListWorkspacesForUser(ctx context.Context, userID uint) ([]WorkspaceWithRole, error) {
+	var rows []WorkspaceWithRole
+	if err := workspacesWithRole(ctx, userID).Order("workspaces.id").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("list workspaces: %w", err)
+	}
+	return rows, nil
+}
+
+// GetWorkspaceForUser returns a workspace with the role of the user in it, or
+// gorm.ErrRecordNotFound when they do not belong to it.
+func GetWorkspaceForUser(ctx context.Context, id, userID uint) (*WorkspaceWithRole, error) {
+	var rows []WorkspaceWithRole
+	if err := workspacesWithRole(ctx, userID).Where("workspaces.id = ?", id).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("get workspace: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("get workspace: %w", gorm.ErrRecordNotFound)
+	}
+	return &rows[0], nil
+}
+
+// WorkspaceRolesForUser returns the role of a user by the ID of each workspace they belong to.
+func WorkspaceRolesForUser(ctx context.Context, userID uint) (map[uint]string, error) {
+	members, err := gorm.G[WorkspaceMember](Db).Where("user_id = ?", userID).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("workspace roles: %w", err)
+	}
+	roles := make(map[uint]string, len(members))
+	for _, member := range members {
+		roles[member.WorkspaceID] = member.Role
+	}
+	return roles, nil
+}
+
+// ListWorkspaceMembers returns the members of a workspace in the order they joined.
+func ListWorkspaceMembers(ctx context.Context, workspaceID uint) ([]WorkspaceMemberRow, error) {
+	var rows []WorkspaceMemberRow
+	err := Db.WithContext(ctx).Table("workspace_members").
+		Select("workspace_members.user_id, users.email, workspace_members.role, workspace_members.created_at").
+		Joins("JOIN users ON users.id = workspace_members.user_id").
+		Where("workspace_members.workspace_id = ?", workspaceID).
+		Order("workspace_members.created_at, workspace_members.user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("list workspace members: %w", err)
+	}
+	return rows, nil
+}
+
+// errNoOwnerLeft rolls back a change that would leave a workspace without an owner.
+var errNoOwnerLeft = errors.New("workspace left without an owner")
+
+// changeMembers runs change on the members of a workspace and keeps it only when the workspace
+// still has an owner afterwards, reporting false otherwise. change returns the rows it affected;
+// none is gorm.ErrRecordNotFound.
+func changeMembers(ctx context.Context, workspaceID uint, change func(tx *gorm.DB) (int, error)) (bool, error) {
+	err := Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		rows, err := change(tx)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		owners, err := gorm.G[WorkspaceMember](tx).Where("workspace_id = ? AND role = ?", workspaceID, RoleOwner).Count(ctx, "*")
+		if err != nil {
+			return err
+		}
+		if owners == 0 {
+			return errNoOwnerLeft
+		}
+		return nil
+	})
+	if errors.Is(err, errNoOwnerLeft) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// SetWorkspaceMemberRole changes the role of a member. It reports false, changing nothing, when
+// the workspace would be left without an owner.
+func SetWorkspaceMemberRole(ctx context.Context, workspaceID, userID uint, role string) (bool, error) {
+	kept, err := changeMembers(ctx, workspaceID, func(tx *gorm.DB) (int, error) {
+		return gorm.G[WorkspaceMember](tx).Where("workspace_id = ? AND user_id = ?", workspaceID, userID).Update(ctx, "role", role)
+	})
+	if err != nil {
+		return false, fmt.Errorf("set workspace member role: %w", err)
+	}
+	return kept, nil
+}
+
+// DeleteWorkspaceMember removes a member from a workspace. It reports false, changing nothing,
+// when the workspace would be left without an owner.
+func DeleteWorkspaceMember(ctx context.Context, workspaceID, userID uint) (bool, error) {
+	kept, err := changeMembers(ctx, workspaceID, func(tx *gorm.DB) (int, error) {
+		return gorm.G[WorkspaceMember](tx).Where("workspace_id = ? AND user_id = ?", workspaceID, userID).Delete(ctx)
+	})
+	if err != nil {
+		return false, fmt.Errorf("delete workspace member: %w", err)
+	}
+	return kept, nil
+}
+
+// CreateWorkspaceInvite inserts an invite and sets its ID.
+func CreateWorkspaceInvite(ctx context.Context, invite *WorkspaceInvite) error {
+	if err := gorm.G[WorkspaceInvite](Db).Create(ctx, invite); err != nil {
+		return fmt.Errorf("create workspace invite: %w", err)
+	}
+	return nil
+}
+
+// AcceptWorkspaceInvite marks the unused, unexpired invite with the given hash and addressed to
+// email accepted and adds userID to its workspace, keeping their role if they already belong to
+// it. It returns gorm.ErrRecordNotFound when there is no such invite.
+func AcceptWorkspaceInvite(ctx context.Context, hash string, userID uint, email string, now time.Time) (*WorkspaceInvite, error) {
+	var invite WorkspaceInvite
+	err := Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		invite, err = gorm.G[WorkspaceInvite](tx).
+			Where("token_hash = ? AND accepted_at IS NULL AND expires_at > ? AND LOWER(email) = LOWER(?)", hash, now, email).
+			First(ctx)
+		if err != nil {
+			return err
+		}
+		rows, err := gorm.G[WorkspaceInvite](tx).Where("id = ? AND accepted_at IS NULL", invite.ID).Update(ctx, "accepted_at", now)
+		if err != nil {
+			return err
+		}
+		if rows != 1 {
+			return gorm.ErrRecordNotFound
+		}
+		invite.AcceptedAt = &now
+		member := WorkspaceMember{WorkspaceID: invite.WorkspaceID, UserID: userID, Role: invite.Role}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&member).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("accept workspace invite: %w", err)
+	}
+	return &invite, nil
+}
+
+// CreateAuditEvent appends an event to the audit log.
+func CreateAuditEvent(ctx context.Context, event *AuditEvent) error {
+	if err := gorm.G[AuditEvent](Db).Create(ctx, event); err != nil {
+		return fmt.Errorf("create audit event: %w", err)
+	}
+	return nil
+}
+
+// ListWorkspaceAuditEvents returns the latest events of a workspace, newest first.
+func ListWorkspaceAuditEvents(ctx context.Context, workspaceID uint, limit int) ([]AuditEvent, error) {
+	events, err := gorm.G[AuditEvent](Db).Where("workspace_id = ?", workspaceID).Order("id DESC").Limit(limit).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list audit events: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,153 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"area/src/auth"
+	"area/src/workflows"
+)
+
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// decodeBody decodes the JSON body of r into payload, answering 400 and returning false when it
+// is not a single JSON object of known fields.
+func decodeBody(w http.ResponseWriter, r *http.Request, payload any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+		return false
+	}
+	if err := EnsureNoTrailingData(decoder); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "unexpected data in payload"})
+		return false
+	}
+	return true
+}
+
+// forgotPassword handles POST /password/forgot, mailing a reset link in the background. It
+// answers the same whether or not the account exists, and 429 once the email or the client IP
+// asked too often.
+func (h *Handler) forgotPassword() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var payload forgotPasswordRequest
+		if !decodeBody(w, r, &payload) {
+			return
+		}
+		payload.Email = strings.TrimSpace(payload.Email)
+		if payload.Email == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "email is required"})
+			return
+		}
+		err := h.Auth.ForgotPassword(r.Context(), payload.Email, h.clientIP(r))
+		var limited *auth.PasswordResetLimitedError
+		if errors.As(err, &limited) {
+			writeTooManyRequests(w, limited.RetryAfter, limited.Error())
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "request password reset", "error", err)
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not request password reset"})
+			return
+		}
+		writeJSON(w, http.StatusAccepted, statusResponse{Status: "if the account exists, a reset link was sent"})
+	})
+}
+
+// resetPassword handles POST /password/reset, setting a new password with a mailed token.
+func (h *Handler) resetPassword() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var payload resetPasswordRequest
+		if !decodeBody(w, r, &payload) {
+			return
+		}
+		if payload.Token == "" || payload.Password == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "token and password are required"})
+			return
+		}
+		err := h.Auth.ResetPassword(r.Context(), payload.Token, payload.Password)
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken):
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		case err != nil:
+			slog.ErrorContext(r.Context(), "reset password", "error", err)
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not reset password"})
+			return
+		}
+		writeJSON(w, http.StatusOK, statusResponse{Status: "password reset"})
+	})
+}
+
+// verifyEmail handles POST /email/verify, verifying the email address of a mailed token.
+func (h *Handler) verifyEmail() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var payload verifyEmailRequest
+		if !decodeBody(w, r, &payload) {
+			return
+		}
+		if payload.Token == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "token is required"})
+			return
+		}
+		err := h.Auth.VerifyEmail(r.Context(), payload.Token)
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken):
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		case err != nil:
+			slog.ErrorContext(r.Context(), "verify email", "error", err)
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not verify email"})
+			return
+		}
+		writeJSON(w, http.StatusOK, statusResponse{Status: "email verified"})
+	})
+}
+
+// resendVerification handles POST /email/verify/resend, mailing the caller a new verification link.
+func (h *Handler) resendVerification() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		}
+		if err := h.Auth.ResendEmailVerification(r.Context(), userID); err != nil {
+			slog.ErrorContext(r.Context(), "resend email verification", "error", err)
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not send verification email"})
+			return
+		}
+		writeJSON(w, http.StatusAccepted, statusResponse{Status: "verification email sent unless already verified"})
+	})
+}
@@ -0,0 +1,145 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"area/src/auth"
+	"area/src/workflows"
+)
+
+// WithClient passes the address and user agent of each request down to the audit log, reading
+// the address from X-Forwarded-For when the request comes through one of the trusted proxies.
+func WithClient(next http.Handler, trusted []netip.Prefix) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := auth.WithClient(r.Context(), clientIP(r, trusted), r.UserAgent())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// auditFilter reads the filters of an audit log listing from the query of r: actor_id,
+// workspace_id, action (a trailing "." matches a prefix), target, since and until (RFC 3339),
+// before (an event ID, to page back) and limit.
+func auditFilter(r *http.Request) (auth.AuditFilter, error) {
+	query := r.URL.Query()
+	var filter auth.AuditFilter
+	ids := []struct {
+		name string
+		dest *int64
+	}{
+		{"actor_id", &filter.ActorID},
+		{"workspace_id", &filter.WorkspaceID},
+		{"before", &filter.BeforeID},
+	}
+	for _, id := range ids {
+		raw := query.Get(id.name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v <= 0 {
+			return filter, fmt.Errorf("invalid %s", id.name)
+		}
+		*id.dest = v
+	}
+	times := []struct {
+		name string
+		dest *time.Time
+	}{
+		{"since", &filter.Since},
+		{"until", &filter.Until},
+	}
+	for _, t := range times {
+		raw := query.Get(t.name)
+		if raw == "" {
+			continue
+		}
+		v, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s: want an RFC 3339 time", t.name)
+		}
+		*t.dest = v
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return filter, errors.New("invalid limit")
+		}
+		filter.Limit = limit
+	}
+	filter.Action = strings.TrimSpace(query.Get("action"))
+	filter.Target = strings.TrimSpace(query.Get("target"))
+	return filter, nil
+}
+
+// writeAuditEvents answers an audit log listing with the events list returns for the filter of
+// the request.
+func writeAuditEvents(w http.ResponseWriter, r *http.Request, list func(context.Context, auth.AuditFilter) ([]auth.AuditEvent, error)) {
+	filter, err := auditFilter(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	events, err := list(r.Context(), filter)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "read audit log", "error", err)
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not read audit log"})
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+// meAudit handles GET /me/audit, listing the events the caller is the actor of, newest first.
+func (h *Handler) meAudit() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeAuditEvents(w, r, func(ctx context.Context, filter auth.AuditFilter) ([]auth.AuditEvent, error) {
+			return h.Auth.UserAudit(ctx, userID, filter)
+		})
+	})
+}
+
+// adminAudit handles GET /admin/audit, listing the events of every user, newest first. Like
+// /admin/config it requires the admin token.
+func (h *Handler) adminAudit() http.Handler {
+	return h.adminOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeAuditEvents(w, r, h.Auth.AuditEvents)
+	}))
+}
+
+// adminOnly serves GET requests bearing "Authorization: Bearer <ADMIN_TOKEN>" and answers 404
+// when no admin token is set.
+func (h *Handler) adminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if h.config.AdminToken == "" {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "admin endpoints are disabled"})
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.config.AdminToken)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "invalid admin token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,511 @@
+package httpapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"area/src/areas"
+)
+
+//go:generate go run ./openapigen -o openapi.json
+
+// openAPIJSON is the specification BuildOpenAPI generates, served at /resources/openapi.json.
+// Run go generate ./src/httpapi after changing apiOperations, a request or response type, or the
+// catalog; a test fails while it is stale.
+//
+//go:embed openapi.json
+var openAPIJSON []byte
+
+// OpenAPISpec returns the embedded specification.
+func OpenAPISpec() []byte {
+	return openAPIJSON
+}
+
+// openAPISpec serves the embedded OpenAPI specification.
+func (h *Handler) openAPISpec() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openAPIJSON)
+	})
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Servers    []openAPIServer                         `json:"servers"`
+	Tags       []openAPITag                            `json:"tags"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+	Contact     struct {
+		Name string `json:"name"`
+	} `json:"contact"`
+	License struct {
+		Name string `json:"name"`
+	} `json:"license"`
+}
+
+type openAPIServer struct {
+	URL         string `json:"url"`
+	Description string `json:"description"`
+}
+
+type openAPITag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*schema               `json:"schemas"`
+	SecuritySchemes map[string]openAPISecurityScheme `json:"securitySchemes"`
+}
+
+type openAPISecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme"`
+	Description string `json:"description,omitempty"`
+}
+
+type openAPIOperation struct {
+	Tags        []string                   `json:"tags,omitempty"`
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+}
+
+type openAPIParameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *schema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                    `json:"required,omitempty"`
+	Content  map[string]openAPIMedia `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                  `json:"description"`
+	Content     map[string]openAPIMedia `json:"content,omitempty"`
+}
+
+type openAPIMedia struct {
+	Schema *schema `json:"schema"`
+}
+
+type openAPIDiscriminator struct {
+	PropertyName string            `json:"propertyName"`
+	Mapping      map[string]string `json:"mapping,omitempty"`
+}
+
+// schema is the subset of the OpenAPI 3.0 schema object the generator emits.
+type schema struct {
+	Ref                  string                `json:"$ref,omitempty"`
+	Type                 string                `json:"type,omitempty"`
+	Format               string                `json:"format,omitempty"`
+	Description          string                `json:"description,omitempty"`
+	Properties           map[string]*schema    `json:"properties,omitempty"`
+	Required             []string              `json:"required,omitempty"`
+	Items                *schema               `json:"items,omitempty"`
+	AdditionalProperties any                   `json:"additionalProperties,omitempty"`
+	Enum                 []string              `json:"enum,omitempty"`
+	Minimum              *float64              `json:"minimum,omitempty"`
+	Maximum              *float64              `json:"maximum,omitempty"`
+	Pattern              string                `json:"pattern,omitempty"`
+	Example              any                   `json:"example,omitempty"`
+	Nullable             bool                  `json:"nullable,omitempty"`
+	OneOf                []*schema             `json:"oneOf,omitempty"`
+	Discriminator        *openAPIDiscriminator `json:"discriminator,omitempty"`
+}
+
+// bearerScheme names the security scheme of authenticated operations.
+const bearerScheme = "bearerAuth"
+
+func refTo(name string) *schema {
+	return &schema{Ref: "#/components/schemas/" + name}
+}
+
+// tagDescriptions describes the operation tags; tags are listed in the order apiOperations
+// first uses them.
+var tagDescriptions = map[string]string{
+	"Authentication": "User authentication and registration",
+	"Workflows":      "Workflow management operations",
+	"Secrets":        "Named secrets that workflows reference as {{secrets.<name>}}, never read back",
+	"Audit":          "Append-only log of logins, registrations, OAuth connections and changes to workflows and secrets",
+	"Workspaces":     "Workspaces shared by their members, with invites and an audit log",
+	"Webhooks":       "External webhook triggers",
+	"OAuth":          "OAuth integration endpoints",
+	"System":         "System health and monitoring",
+}
+
+// BuildOpenAPI generates the specification from apiOperations, the request and response types
+// they name, and the catalog: each trigger's trigger_config and each reaction's payload get a
+// schema built from their catalog fields.
+func BuildOpenAPI() ([]byte, error) {
+	doc := openAPIDocument{
+		OpenAPI: "3.0.3",
+		Servers: []openAPIServer{{URL: "http://localhost:8080", Description: "Development server"}},
+		Paths:   make(map[string]map[string]*openAPIOperation),
+	}
+	doc.Info.Title = "KiKonect API"
+	doc.Info.Description = "KiKonect workflow automation API for managing workflows, authentication, and integrations."
+	doc.Info.Version = "1.0.0"
+	doc.Info.Contact.Name = "KiKonect Team"
+	doc.Info.License.Name = "MIT"
+
+	gen := newSchemaGenerator()
+	gen.components["WorkflowRequest"] = gen.workflowRequestSchema()
+	ops := append(apiOperations(), reactionOperations(gen)...)
+	for _, op := range ops {
+		method := strings.ToLower(op.method)
+		if doc.Paths[op.path] == nil {
+			doc.Paths[op.path] = make(map[string]*openAPIOperation)
+		}
+		if doc.Paths[op.path][method] != nil {
+			return nil, fmt.Errorf("openapi: %s %s is documented twice", op.method, op.path)
+		}
+		doc.Paths[op.path][method] = gen.operation(op)
+		if !slices.ContainsFunc(doc.Tags, func(t openAPITag) bool { return t.Name == op.tag }) {
+			doc.Tags = append(doc.Tags, openAPITag{Name: op.tag, Description: tagDescriptions[op.tag]})
+		}
+	}
+	if gen.err != nil {
+		return nil, gen.err
+	}
+	doc.Components.Schemas = gen.components
+	doc.Components.SecuritySchemes = map[string]openAPISecurityScheme{
+		bearerScheme: {Type: "http", Scheme: "bearer", Description: "Access token returned by /login, or a personal access " +
+			"token from /me/tokens holding workflows:trigger to trigger a workflow, workflows:read for other reads and " +
+			"workflows:write for other changes"},
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// operation turns a documented route method into its OpenAPI form.
+func (g *schemaGenerator) operation(op operation) *openAPIOperation {
+	out := &openAPIOperation{
+		Tags:        []string{op.tag},
+		Summary:     op.summary,
+		Description: op.description,
+		Responses:   make(map[string]openAPIResponse),
+	}
+	if op.authenticated {
+		out.Security = []map[string][]string{{bearerScheme: {}}}
+	}
+	for _, p := range op.params {
+		out.Parameters = append(out.Parameters, openAPIParameter{
+			Name:        p.name,
+			In:          p.in,
+			Description: p.description,
+			Required:    p.required || p.in == "path",
+			Schema:      g.schemaOf(p.value),
+		})
+	}
+	if op.request != nil {
+		contentType := op.requestType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		out.RequestBody = &openAPIRequestBody{
+			Required: !op.requestOptional,
+			Content:  map[string]openAPIMedia{contentType: {Schema: g.schemaOf(op.request)}},
+		}
+	}
+	for _, resp := range op.responses {
+		r := openAPIResponse{Description: resp.description}
+		if resp.body != nil {
+			contentType := resp.contentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			r.Content = map[string]openAPIMedia{contentType: {Schema: g.schemaOf(resp.body)}}
+		}
+		out.Responses[strconv.Itoa(resp.status)] = r
+	}
+	if op.authenticated {
+		for status, description := range map[int]string{
+			http.StatusUnauthorized: "Missing or invalid access token",
+			http.StatusForbidden:    "Personal access token not accepted here or lacking the scope",
+		} {
+			if _, ok := out.Responses[strconv.Itoa(status)]; !ok {
+				out.Responses[strconv.Itoa(status)] = openAPIResponse{
+					Description: description,
+					Content:     map[string]openAPIMedia{"application/json": {Schema: g.schemaOf(errorResponse{})}},
+				}
+			}
+		}
+	}
+	return out
+}
+
+// schemaGenerator reflects Go types into schemas, collecting named structs as components.
+type schemaGenerator struct {
+	components map[string]*schema
+	names      map[reflect.Type]string
+	err        error
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		components: make(map[string]*schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+// schemaOf returns the schema of value: a *schema as is, oneOf alternatives, or the reflected
+// schema of its Go type.
+func (g *schemaGenerator) schemaOf(value any) *schema {
+	switch v := value.(type) {
+	case *schema:
+		return v
+	case oneOf:
+		s := &schema{}
+		for _, alt := range v {
+			s.OneOf = append(s.OneOf, g.schemaOf(alt))
+		}
+		return s
+	}
+	return g.schemaFor(reflect.TypeOf(value))
+}
+
+func (g *schemaGenerator) schemaFor(t reflect.Type) *schema {
+	switch t {
+	case timeType:
+		return &schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &schema{Description: "Any JSON value"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := g.schemaFor(t.Elem())
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return s
+	case reflect.String:
+		return &schema{Type: "string"}
+	case reflect.Bool:
+		return &schema{Type: "boolean"}
+	case reflect.Int64, reflect.Uint64:
+		return &schema{Type: "integer", Format: "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return &schema{Type: "object", AdditionalProperties: true}
+		}
+		return &schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Interface:
+		return &schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return g.component(t)
+	}
+	if g.err == nil {
+		g.err = fmt.Errorf("openapi: cannot describe Go type %s", t)
+	}
+	return &schema{}
+}
+
+// component registers the named struct t under its exported name and refers to it.
+func (g *schemaGenerator) component(t reflect.Type) *schema {
+	if name, ok := g.names[t]; ok {
+		return refTo(name)
+	}
+	name := []rune(t.Name())
+	name[0] = unicode.ToUpper(name[0])
+	key := string(name)
+	if _, taken := g.components[key]; taken && g.err == nil {
+		g.err = fmt.Errorf("openapi: schema %s is defined by both %s and another type", key, t)
+	}
+	g.names[t] = key
+	g.components[key] = &schema{} // placeholder for recursive types
+	g.components[key] = g.structSchema(t)
+	return refTo(key)
+}
+
+// structSchema describes the JSON form of a struct, following encoding/json field rules for
+// tags, unexported fields and embedded structs.
+func (g *schemaGenerator) structSchema(t reflect.Type) *schema {
+	s := &schema{Type: "object", Properties: make(map[string]*schema)}
+	for field := range fieldsOf(t) {
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		embedded := field.Type
+		if embedded.Kind() == reflect.Pointer {
+			embedded = embedded.Elem()
+		}
+		if field.Anonymous && name == "" && embedded.Kind() == reflect.Struct {
+			for key, prop := range g.structSchema(embedded).Properties {
+				s.Properties[key] = prop
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = g.schemaFor(field.Type)
+	}
+	return s
+}
+
+func fieldsOf(t reflect.Type) func(func(reflect.StructField) bool) {
+	return func(yield func(reflect.StructField) bool) {
+		for i := range t.NumField() {
+			if !yield(t.Field(i)) {
+				return
+			}
+		}
+	}
+}
+
+// workflowRequestSchema is workflowRequest with one variant per catalog trigger, chosen by
+// trigger_type, whose trigger_config lists that trigger's fields.
+func (g *schemaGenerator) workflowRequestSchema() *schema {
+	base := g.structSchema(reflect.TypeFor[workflowRequest]())
+	out := &schema{
+		Description:   "The trigger_config schema depends on trigger_type.",
+		Discriminator: &openAPIDiscriminator{PropertyName: "trigger_type", Mapping: make(map[string]string)},
+	}
+	for _, svc := range areas.Definitions {
+		for _, trigger := range svc.Triggers {
+			variant := &schema{Type: "object", Properties: make(map[string]*schema), Required: []string{"name", "trigger_type", "action_url"}}
+			for key, prop := range base.Properties {
+				variant.Properties[key] = prop
+			}
+			variant.Description = trigger.Name + ": " + trigger.Description
+			variant.Properties["trigger_type"] = &schema{Type: "string", Enum: []string{trigger.ID}}
+			variant.Properties["trigger_config"] = fieldsSchema(trigger.Fields)
+
+			name := "WorkflowRequest." + trigger.ID
+			g.components[name] = variant
+			out.OneOf = append(out.OneOf, refTo(name))
+			out.Discriminator.Mapping[trigger.ID] = refTo(name).Ref
+		}
+	}
+	return out
+}
+
+// reactionOperations documents every reaction route, its payload built from the catalog fields
+// validateReaction checks.
+func reactionOperations(g *schemaGenerator) []operation {
+	var ops []operation
+	for _, path := range ActionRoutes() {
+		reaction, ok := areas.FindReaction(path)
+		if !ok {
+			continue
+		}
+		tag := path
+		for _, svc := range areas.Definitions {
+			if slices.ContainsFunc(svc.Reactions, func(c areas.Capability) bool { return c.ID == reaction.ID }) {
+				tag = svc.Name + " Actions"
+			}
+		}
+		name := "Reaction." + reaction.ID
+		g.components[name] = fieldsSchema(reaction.Fields)
+		op := operation{
+			method:      http.MethodPost,
+			path:        path,
+			tag:         tag,
+			summary:     reaction.Name,
+			description: reaction.Description,
+			request:     refTo(name),
+			responses: []response{
+				{status: http.StatusOK, description: "Reaction performed", body: map[string]any{}},
+				{status: http.StatusBadRequest, description: "Invalid payload; catalog violations list the invalid fields", body: oneOf{errorResponse{}, validationErrorResponse{}}},
+				failure(http.StatusInternalServerError, "Provider error"),
+			},
+		}
+		if ReactionNeedsUser(path) {
+			op.description += " Acts with an OAuth connection of the caller; the executor calls it with a single-use reaction token of the workflow's user."
+			op.authenticated = true
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// fieldsSchema describes an object with the given catalog fields.
+func fieldsSchema(fields []areas.Field) *schema {
+	s := &schema{Type: "object", Properties: make(map[string]*schema)}
+	for _, f := range fields {
+		s.Properties[f.Key] = fieldSchema(f)
+		if f.Required {
+			s.Required = append(s.Required, f.Key)
+		}
+	}
+	return s
+}
+
+// fieldSchema mirrors the checks areas.CheckFields applies to one field; for arrays the enum and
+// pattern constrain each item.
+func fieldSchema(f areas.Field) *schema {
+	value := &schema{Enum: f.Enum, Minimum: f.Min, Maximum: f.Max, Pattern: f.Pattern}
+	var s *schema
+	switch f.Type {
+	case "string", "number", "boolean":
+		value.Type = f.Type
+		s = value
+	case "object":
+		value.Type = "object"
+		value.AdditionalProperties = true
+		s = value
+	case "array<string>":
+		value.Type = "string"
+		s = &schema{Type: "array", Items: value}
+	case "array<object>":
+		value.Type = "object"
+		value.AdditionalProperties = true
+		s = &schema{Type: "array", Items: value}
+	default:
+		s = value
+	}
+	s.Description = f.Description
+	s.Example = f.Example
+	return s
+}
@@ -0,0 +1,25 @@
+// Command openapigen writes the OpenAPI specification generated by httpapi.BuildOpenAPI; it runs
+// through go generate ./src/httpapi.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"area/src/httpapi"
+)
+
+func main() {
+	out := flag.String("o", "openapi.json", "output file")
+	flag.Parse()
+	spec, err := httpapi.BuildOpenAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*out, spec, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,796 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"area/src/auth"
+	"area/src/workflows"
+)
+
+// operation documents one method of a route for BuildOpenAPI. Bodies and parameter values are Go
+// values whose types are reflected into schemas, or a *schema when no Go type fits.
+type operation struct {
+	method      string
+	path        string // may hold {name} templates, e.g. /workflows/{id}/runs
+	tag         string
+	summary     string
+	description string
+	params      []param
+	// authenticated operations need a session access token.
+	authenticated bool
+	// request is the request body; requestType defaults to application/json.
+	request         any
+	requestType     string
+	requestOptional bool
+	responses       []response
+}
+
+type param struct {
+	name        string
+	in          string // path, query or header
+	description string
+	required    bool
+	value       any
+}
+
+type response struct {
+	status      int
+	description string
+	body        any // nil when the response has no body
+	contentType string
+}
+
+// oneOf documents a body that takes one of several shapes.
+type oneOf []any
+
+func failure(status int, description string) response {
+	return response{status: status, description: description, body: errorResponse{}}
+}
+
+// oauthExchangeRequest is the JSON body of POST /oauth/google/exchange.
+type oauthExchangeRequest struct {
+	Code        string `json:"code"`
+	State       string `json:"state,omitempty"`
+	RedirectURI string `json:"redirect_uri,omitempty"`
+}
+
+// githubExchangeRequest is the form body of POST /oauth/github/exchange.
+type githubExchangeRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+// oauthStartResponse is returned by GET /oauth/google/start.
+type oauthStartResponse struct {
+	AuthURL string `json:"auth_url"`
+	State   string `json:"state"`
+}
+
+var (
+	oauthLoginParams = []param{
+		{name: "redirect_uri", in: "query", description: "OAuth redirect URI", value: ""},
+		{name: "ui_redirect", in: "query", description: "UI redirect URL after OAuth completion", value: ""},
+		{name: "access_token", in: "query", description: "Access token of the session the account is connected to, since browsers navigate here", value: ""},
+	}
+	oauthCallbackParams = []param{
+		{name: "code", in: "query", description: "Authorization code from the provider", required: true, value: ""},
+		{name: "state", in: "query", description: "OAuth state issued by the login route; it names the user the account is connected to", required: true, value: ""},
+		{name: "redirect_uri", in: "query", description: "OAuth redirect URI", value: ""},
+	}
+	auditFilterParams = []param{
+		{name: "action", in: "query", description: "Only this action, or every action it prefixes when it ends in \".\", e.g. workflow.", value: ""},
+		{name: "target", in: "query", description: "Only events about this target, e.g. workflow:12 or secret:slack_bot", value: ""},
+		{name: "since", in: "query", description: "Only events at or after this RFC 3339 time", value: ""},
+		{name: "until", in: "query", description: "Only events before this RFC 3339 time", value: ""},
+		{name: "before", in: "query", description: "Only events older than this event ID, to page back", value: int64(0)},
+		{name: "limit", in: "query", description: "Maximum events to return (at most 200)", value: 0},
+	}
+)
+
+// apiOperations documents every route of NewMux except the reaction routes, which
+// reactionOperations documents from the catalog.
+func apiOperations() []operation {
+	workflowID := param{name: "id", in: "path", description: "Workflow ID", value: int64(0)}
+	workspaceID := param{name: "id", in: "path", description: "Workspace ID", value: int64(0)}
+	memberID := param{name: "user_id", in: "path", description: "User ID of the member", value: int64(0)}
+	return []operation{
+		{
+			method: http.MethodPost, path: "/login", tag: "Authentication",
+			summary: "User login", description: "Authenticate user with email and password",
+			request: loginRequest{},
+			responses: []response{
+				{
+					status:      http.StatusOK,
+					description: "Login successful; the user with the tokens of a new session, or a token for POST /login/2fa when the user has two-factor authentication enabled",
+					body:        oneOf{loginResponse{}, twoFactorChallengeResponse{}},
+				},
+				failure(http.StatusBadRequest, "Invalid request"),
+				failure(http.StatusUnauthorized, "Invalid credentials"),
+				failure(http.StatusTooManyRequests, "Too many failed logins for the account or IP address; retry after the Retry-After header's seconds"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/login/2fa", tag: "Authentication",
+			summary:     "Complete two-factor login",
+			description: "Finish a login with the token of POST /login and a code of the authenticator app or a recovery code. The token works once, right code or not.",
+			request:     twoFactorLoginRequest{},
+			responses: []response{
+				{status: http.StatusOK, description: "Login successful; the user with the tokens of a new session", body: loginResponse{}},
+				failure(http.StatusBadRequest, "Invalid request"),
+				failure(http.StatusUnauthorized, "Invalid, used or expired token, or wrong code"),
+				failure(http.StatusTooManyRequests, "Too many failed logins for the account or IP address"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/refresh", tag: "Authentication",
+			summary: "Refresh session", description: "Trade a refresh token for new session tokens; the old tokens stop working",
+			request: refreshRequest{},
+			responses: []response{
+				{status: http.StatusOK, description: "New session tokens", body: auth.Tokens{}},
+				failure(http.StatusBadRequest, "Invalid request"),
+				failure(http.StatusUnauthorized, "Invalid or expired refresh token"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/logout", tag: "Authentication",
+			summary: "Logout", description: "Revoke the session of the access token",
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Session revoked", body: statusResponse{}},
+				failure(http.StatusUnauthorized, "Missing or invalid access token"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/me/tokens", tag: "Authentication",
+			summary: "List personal access tokens", description: "Personal access tokens of the session's user, without the tokens themselves",
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Tokens, newest first", body: []auth.APIToken{}},
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/me/tokens", tag: "Authentication",
+			summary:       "Create personal access token",
+			description:   "Issue a long-lived token for scripts and CI, used as a bearer token on the workflow routes its scopes allow. The token is only returned here.",
+			authenticated: true,
+			request:       createAPITokenRequest{},
+			responses: []response{
+				{status: http.StatusCreated, description: "Token created", body: createdAPITokenResponse{}},
+				failure(http.StatusBadRequest, "Invalid name, scopes or expiry"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodDelete, path: "/me/tokens/{id}", tag: "Authentication",
+			summary:       "Revoke personal access token",
+			params:        []param{{name: "id", in: "path", description: "Token ID", value: int64(0)}},
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Deleted", body: statusResponse{}},
+				failure(http.StatusBadRequest, "Invalid token id"),
+				failure(http.StatusNotFound, "Token not found"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/me/2fa", tag: "Authentication",
+			summary: "Two-factor status", description: "Whether the session's user has two-factor authentication enabled, and how many recovery codes are left",
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Two-factor status", body: auth.TwoFactorStatus{}},
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/me/2fa/enroll", tag: "Authentication",
+			summary:       "Enroll two-factor authentication",
+			description:   "Draw a TOTP secret for an authenticator app, replacing a previous enrollment not yet verified. Nothing changes at login until POST /me/2fa/verify.",
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Secret and otpauth URI to show as a QR code", body: auth.TwoFactorEnrollment{}},
+				failure(http.StatusConflict, "Two-factor authentication is already enabled"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/me/2fa/verify", tag: "Authentication",
+			summary:       "Enable two-factor authentication",
+			description:   "Confirm the enrolled secret with a code of the authenticator app. Returns single-use recovery codes, shown only here.",
+			authenticated: true,
+			request:       twoFactorCodeRequest{},
+			responses: []response{
+				{status: http.StatusOK, description: "Two-factor authentication enabled", body: recoveryCodesResponse{}},
+				failure(http.StatusBadRequest, "Invalid request or wrong code"),
+				failure(http.StatusConflict, "Not enrolled, or already enabled"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/me/2fa/disable", tag: "Authentication",
+			summary:       "Disable two-factor authentication",
+			description:   "Turn two-factor authentication off with a code of the authenticator app or a recovery code",
+			authenticated: true,
+			request:       twoFactorCodeRequest{},
+			responses: []response{
+				{status: http.StatusOK, description: "Two-factor authentication disabled", body: statusResponse{}},
+				failure(http.StatusBadRequest, "Invalid request or wrong code"),
+				failure(http.StatusConflict, "Two-factor authentication is not enabled"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/register", tag: "Authentication",
+			summary: "User registration", description: "Create a new user account and mail a link verifying its email address",
+			request: registerRequest{},
+			responses: []response{
+				{status: http.StatusCreated, description: "User created successfully", body: auth.User{}},
+				failure(http.StatusBadRequest, "Invalid request"),
+				failure(http.StatusConflict, "User already exists"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/password/forgot", tag: "Authentication",
+			summary:     "Forgot password",
+			description: "Mail a single-use password reset link, valid for an hour. The answer is the same whether or not the account exists.",
+			request:     forgotPasswordRequest{},
+			responses: []response{
+				{status: http.StatusAccepted, description: "Reset link sent if the account exists", body: statusResponse{}},
+				failure(http.StatusBadRequest, "Invalid request"),
+				failure(http.StatusTooManyRequests, "Too many reset requests for the email or IP address; retry after the Retry-After header's seconds"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/password/reset", tag: "Authentication",
+			summary:     "Reset password",
+			description: "Set a new password with the token of a reset link; every session of the user ends and their email counts as verified",
+			request:     resetPasswordRequest{},
+			responses: []response{
+				{status: http.StatusOK, description: "Password reset", body: statusResponse{}},
+				failure(http.StatusBadRequest, "Invalid request, or invalid, used or expired token"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/email/verify", tag: "Authentication",
+			summary:     "Verify email",
+			description: "Verify the email address of a user with the token of the link mailed at registration",
+			request:     verifyEmailRequest{},
+			responses: []response{
+				{status: http.StatusOK, description: "Email verified", body: statusResponse{}},
+				failure(http.StatusBadRequest, "Invalid request, or invalid, used or expired token"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/email/verify/resend", tag: "Authentication",
+			summary:       "Resend verification email",
+			description:   "Mail the session's user a new verification link, unless their email is already verified",
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusAccepted, description: "Verification email sent unless already verified", body: statusResponse{}},
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/workflows", tag: "Workflows",
+			summary: "List workflows", description: "Get the workflows of the current user and of the workspaces they belong to",
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "List of workflows", body: []workflows.Workflow{}},
+				failure(http.StatusUnauthorized, "Missing or invalid user"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/workflows", tag: "Workflows",
+			summary: "Create workflow", description: "Create a new workflow, in a workspace the user edits when workspace_id is set",
+			authenticated: true,
+			request:       refTo("WorkflowRequest"),
+			responses: []response{
+				{status: http.StatusCreated, description: "Workflow created successfully", body: workflows.Workflow{}},
+				{status: http.StatusBadRequest, description: "Invalid request; an invalid trigger_config lists its invalid fields", body: oneOf{errorResponse{}, validationErrorResponse{}}},
+				failure(http.StatusForbidden, "Email not verified while AUTH_REQUIRE_VERIFIED_EMAIL is set, token lacking workflows:write, or viewer of the workspace"),
+				failure(http.StatusNotFound, "Workspace not found"),
+				failure(http.StatusUnauthorized, "Missing or invalid user"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodDelete, path: "/workflows/{id}", tag: "Workflows",
+			summary:       "Delete workflow",
+			params:        []param{workflowID},
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Deleted", body: statusResponse{}},
+				failure(http.StatusForbidden, "Workspace viewers cannot change workflows"),
+				failure(http.StatusNotFound, "Workflow not found"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/workflows/{id}/enabled", tag: "Workflows",
+			summary: "Enable/disable workflow",
+			params: []param{
+				workflowID,
+				{name: "action", in: "query", required: true, value: &schema{Type: "string", Enum: []string{"enable", "disable"}}},
+			},
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Updated", body: statusResponse{}},
+				failure(http.StatusBadRequest, "Invalid action"),
+				failure(http.StatusForbidden, "Workspace viewers cannot change workflows"),
+				failure(http.StatusNotFound, "Workflow not found"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/workflows/{id}/trigger", tag: "Workflows",
+			summary: "Trigger workflow", description: "Manually trigger a workflow execution",
+			params:        []param{workflowID},
+			authenticated: true,
+			request:       map[string]any{}, requestOptional: true,
+			responses: []response{
+				{status: http.StatusAccepted, description: "Workflow triggered; digest workflows answer {\"status\":\"buffered\"}", body: oneOf{workflows.Run{}, statusResponse{}}},
+				failure(http.StatusBadRequest, "Invalid request or workflow disabled"),
+				failure(http.StatusForbidden, "Workspace viewers cannot change workflows"),
+				failure(http.StatusNotFound, "Workflow not found"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/workflows/{id}/runs", tag: "Workflows",
+			summary:     "List runs",
+			description: "List the most recent runs of a workflow with the status, latency and truncated response of each reaction call",
+			params: []param{
+				workflowID,
+				{name: "limit", in: "query", description: "Maximum number of runs (default and max 50)", value: 0},
+			},
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Runs, newest first", body: []workflows.Run{}},
+				failure(http.StatusNotFound, "Workflow not found"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/workflows/{id}/cancel", tag: "Workflows",
+			summary: "Cancel pending runs", description: "Cancel every run of the workflow that has not started yet",
+			params:        []param{workflowID},
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Pending runs cancelled", body: cancelledResponse{}},
+				failure(http.StatusForbidden, "Workspace viewers cannot change workflows"),
+				failure(http.StatusNotFound, "Workflow not found"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/runs/{id}/cancel", tag: "Workflows",
+			summary: "Cancel run", description: "Cancel a pending or running run and interrupt its in-flight reaction",
+			params:        []param{{name: "id", in: "path", description: "Run ID", value: int64(0)}},
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Run cancelled", body: workflows.Run{}},
+				failure(http.StatusBadRequest, "Invalid run id"),
+				failure(http.StatusForbidden, "Workspace viewers cannot change workflows"),
+				failure(http.StatusNotFound, "Run not found"),
+				failure(http.StatusConflict, "Run already finished"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/events/stream", tag: "Workflows",
+			summary: "Stream run events",
+			description: "Server-Sent Events stream of the authenticated user's events: run.created, run.started, run.succeeded, " +
+				"run.failed, run.cancelled, job.succeeded, job.failed, workflow.enabled and workflow.disabled. Each message's " +
+				"event name is the type and its data is a workflows Event. EventSource clients, which cannot set headers, may " +
+				"pass the access token as the access_token query parameter.",
+			params: []param{
+				{name: "access_token", in: "query", description: "Access token when the Authorization header cannot be set", value: ""},
+			},
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Event stream", body: workflows.Event{}, contentType: "text/event-stream"},
+				failure(http.StatusUnauthorized, "Missing or invalid user"),
+				failure(http.StatusServiceUnavailable, "Events not configured"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/me/secrets", tag: "Secrets",
+			summary: "List secrets", description: "Names of the session user's secrets, without their values",
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Secrets by name", body: []auth.Secret{}},
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPut, path: "/me/secrets/{name}", tag: "Secrets",
+			summary: "Set secret",
+			description: "Create a secret or replace its value. Workflows created by the user reference it as {{secrets.<name>}} in their trigger config, " +
+				"and the executor substitutes the value just before sending the reaction.",
+			params:        []param{{name: "name", in: "path", description: "Secret name: 1 to 64 letters, digits or _", value: ""}},
+			authenticated: true,
+			request:       putSecretRequest{},
+			responses: []response{
+				{status: http.StatusCreated, description: "Created", body: statusResponse{}},
+				{status: http.StatusOK, description: "Value replaced", body: statusResponse{}},
+				failure(http.StatusBadRequest, "Invalid name or value"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodDelete, path: "/me/secrets/{name}", tag: "Secrets",
+			summary:       "Delete secret",
+			description:   "Runs of workflows still referencing the secret fail until it is set again",
+			params:        []param{{name: "name", in: "path", description: "Secret name", value: ""}},
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Deleted", body: statusResponse{}},
+				failure(http.StatusNotFound, "Secret not found"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/me/audit", tag: "Audit",
+			summary: "Read own audit log",
+			description: "Logins, registration, OAuth connections and changes to workflows and secrets made by the session user, " +
+				"newest first, each with the IP address, user agent and the state before and after the change",
+			params:        auditFilterParams,
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Audit events, newest first", body: []auth.AuditEvent{}},
+				failure(http.StatusBadRequest, "Invalid filter"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/workspaces", tag: "Workspaces",
+			summary: "List workspaces", description: "Workspaces of the session's user with their role in each",
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Workspaces, oldest first", body: []auth.Workspace{}},
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/workspaces", tag: "Workspaces",
+			summary: "Create workspace", description: "Create a workspace owned by the session's user",
+			authenticated: true,
+			request:       createWorkspaceRequest{},
+			responses: []response{
+				{status: http.StatusCreated, description: "Workspace created", body: auth.Workspace{}},
+				failure(http.StatusBadRequest, "Invalid name"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/workspaces/{id}/members", tag: "Workspaces",
+			summary:       "List members",
+			params:        []param{workspaceID},
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Members in the order they joined", body: []auth.WorkspaceMember{}},
+				failure(http.StatusBadRequest, "Invalid workspace id"),
+				failure(http.StatusNotFound, "Workspace not found"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPut, path: "/workspaces/{id}/members/{user_id}", tag: "Workspaces",
+			summary: "Change member role", description: "Give a member another role. Only owners change roles, and a workspace keeps at least one owner.",
+			params:        []param{workspaceID, memberID},
+			authenticated: true,
+			request:       memberRoleRequest{},
+			responses: []response{
+				{status: http.StatusOK, description: "Role changed", body: statusResponse{}},
+				failure(http.StatusBadRequest, "Invalid id or role"),
+				failure(http.StatusForbidden, "Not an owner of the workspace"),
+				failure(http.StatusNotFound, "Workspace or member not found"),
+				failure(http.StatusConflict, "The workspace would be left without an owner"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodDelete, path: "/workspaces/{id}/members/{user_id}", tag: "Workspaces",
+			summary: "Remove member", description: "Remove a member from the workspace. Owners remove anyone; any member may remove themselves to leave.",
+			params:        []param{workspaceID, memberID},
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Member removed", body: statusResponse{}},
+				failure(http.StatusBadRequest, "Invalid id"),
+				failure(http.StatusForbidden, "Not an owner of the workspace"),
+				failure(http.StatusNotFound, "Workspace or member not found"),
+				failure(http.StatusConflict, "The workspace would be left without an owner"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/workspaces/{id}/invites", tag: "Workspaces",
+			summary:       "Invite member",
+			description:   "Mail an invite to join the workspace with a role, valid for 7 days. Only owners invite.",
+			params:        []param{workspaceID},
+			authenticated: true,
+			request:       inviteRequest{},
+			responses: []response{
+				{status: http.StatusCreated, description: "Invite sent", body: auth.WorkspaceInvite{}},
+				failure(http.StatusBadRequest, "Invalid id, email or role"),
+				failure(http.StatusForbidden, "Not an owner of the workspace"),
+				failure(http.StatusNotFound, "Workspace not found"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/workspaces/{id}/connections", tag: "Workspaces",
+			summary:       "Share connection",
+			description:   "Share a Google or GitHub connection of the session's user with a workspace they edit, so that its workflows can use it",
+			params:        []param{workspaceID},
+			authenticated: true,
+			request:       shareConnectionRequest{},
+			responses: []response{
+				{status: http.StatusOK, description: "Connection shared", body: statusResponse{}},
+				failure(http.StatusBadRequest, "Invalid id or provider"),
+				failure(http.StatusForbidden, "Viewer of the workspace"),
+				failure(http.StatusNotFound, "Workspace or connection not found"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/workspaces/{id}/audit", tag: "Workspaces",
+			summary:       "Read audit log",
+			description:   "Latest changes to the workspace, its members and its workflows, newest first",
+			params:        []param{workspaceID},
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Audit events, newest first", body: []auth.AuditEvent{}},
+				failure(http.StatusBadRequest, "Invalid workspace id"),
+				failure(http.StatusNotFound, "Workspace not found"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/invites/accept", tag: "Workspaces",
+			summary:       "Accept invite",
+			description:   "Join the workspace of a mailed invite addressed to the session user's email. Members already in the workspace keep their role.",
+			authenticated: true,
+			request:       acceptInviteRequest{},
+			responses: []response{
+				{status: http.StatusOK, description: "Joined; the workspace with the user's role", body: auth.Workspace{}},
+				failure(http.StatusBadRequest, "Invalid request, or invalid, used or expired invite"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/hooks/{token}", tag: "Webhooks",
+			summary: "Webhook trigger", description: "Trigger a workflow via webhook",
+			params:  []param{{name: "token", in: "path", description: "Webhook token", value: ""}},
+			request: map[string]any{}, requestOptional: true,
+			responses: []response{
+				{status: http.StatusAccepted, description: "Webhook processed successfully", body: workflows.Run{}},
+				failure(http.StatusBadRequest, "Invalid request"),
+				failure(http.StatusNotFound, "Webhook not found"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/oauth/google/login", tag: "OAuth",
+			summary: "Google OAuth login", description: "Initiate Google OAuth login flow connecting an account to the session user",
+			params:        oauthLoginParams,
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusFound, description: "Redirect to Google OAuth consent page"},
+				failure(http.StatusBadRequest, "Invalid request"),
+				failure(http.StatusServiceUnavailable, "Google integration not configured"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/oauth/google/mobile/login", tag: "OAuth",
+			summary: "Google OAuth login (mobile)", description: "Initiate Google OAuth login flow for mobile clients connecting an account to the session user",
+			params:        oauthLoginParams,
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusFound, description: "Redirect to Google OAuth consent page"},
+				failure(http.StatusBadRequest, "Invalid request"),
+				failure(http.StatusServiceUnavailable, "Google integration not configured"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/oauth/google/callback", tag: "OAuth",
+			summary: "Google OAuth callback", description: "Handle Google OAuth callback",
+			params: oauthCallbackParams,
+			responses: []response{
+				{status: http.StatusOK, description: "OAuth callback processed successfully", body: map[string]any{}},
+				{status: http.StatusFound, description: "Redirect to UI application"},
+				failure(http.StatusBadRequest, "Invalid request"),
+				failure(http.StatusServiceUnavailable, "Google integration not configured"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/oauth/google/mobile/callback", tag: "OAuth",
+			summary: "Google OAuth callback (mobile)", description: "Handle Google OAuth callback for mobile clients",
+			params: oauthCallbackParams,
+			responses: []response{
+				{status: http.StatusOK, description: "OAuth callback processed successfully", body: map[string]any{}},
+				{status: http.StatusFound, description: "Redirect to UI application"},
+				failure(http.StatusBadRequest, "Invalid request"),
+				failure(http.StatusServiceUnavailable, "Google integration not configured"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/oauth/google/exchange", tag: "OAuth",
+			summary:       "Exchange Google OAuth code",
+			description:   "Exchange a Google OAuth authorization code for a token stored for the session user. A state must come from GET /oauth/google/start of the same user.",
+			request:       oauthExchangeRequest{},
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Code exchange successful", body: map[string]any{}},
+				failure(http.StatusBadRequest, "Invalid request"),
+				failure(http.StatusServiceUnavailable, "Google integration not configured"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/oauth/google/start", tag: "OAuth",
+			summary: "Google OAuth start", description: "Return an auth URL and a state bound to the session user for Google OAuth",
+			params:        []param{{name: "redirect_uri", in: "query", description: "OAuth redirect URI", value: ""}},
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Auth URL response", body: oauthStartResponse{}},
+				failure(http.StatusBadRequest, "Invalid request"),
+				failure(http.StatusServiceUnavailable, "Google integration not configured"),
+			},
+		},
+		{
+			method: http.MethodPost, path: "/oauth/github/exchange", tag: "OAuth",
+			summary: "Exchange GitHub OAuth code", description: "Exchange GitHub OAuth authorization code for user data",
+			request: githubExchangeRequest{}, requestType: "application/x-www-form-urlencoded",
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusAccepted, description: "Code exchange successful", body: dataResponse{}},
+				failure(http.StatusBadRequest, "Invalid request"),
+				failure(http.StatusServiceUnavailable, "GitHub integration not configured"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/oauth/github/login", tag: "OAuth",
+			summary:       "GitHub OAuth login",
+			params:        oauthLoginParams,
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusFound, description: "Redirect to GitHub"},
+				failure(http.StatusServiceUnavailable, "GitHub integration not configured"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/oauth/github/callback", tag: "OAuth",
+			summary: "GitHub OAuth callback",
+			params:  oauthCallbackParams,
+			responses: []response{
+				{status: http.StatusOK, description: "OAuth result", body: map[string]any{}},
+				{status: http.StatusFound, description: "Redirect to UI application"},
+				failure(http.StatusServiceUnavailable, "GitHub integration not configured"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/oauth/github/mobile/login", tag: "OAuth",
+			summary:       "GitHub OAuth login (mobile)",
+			params:        oauthLoginParams,
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusFound, description: "Redirect to GitHub"},
+				failure(http.StatusServiceUnavailable, "GitHub mobile integration not configured"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/oauth/github/mobile/callback", tag: "OAuth",
+			summary: "GitHub OAuth callback (mobile)",
+			params:  oauthCallbackParams,
+			responses: []response{
+				{status: http.StatusOK, description: "OAuth result", body: map[string]any{}},
+				{status: http.StatusFound, description: "Redirect to UI application"},
+				failure(http.StatusServiceUnavailable, "GitHub mobile integration not configured"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/oauth/status", tag: "OAuth",
+			summary: "OAuth token status", description: "Return the latest token ids for a user",
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "OAuth status", body: oauthStatusResponse{}},
+				failure(http.StatusUnauthorized, "Missing or invalid user"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodDelete, path: "/oauth/connections/{provider}/{id}", tag: "OAuth",
+			summary:     "Disconnect account",
+			description: "Delete an OAuth connection of the session user with its tokens. Workflows using it fail until they are given another one.",
+			params: []param{
+				{name: "provider", in: "path", description: "google or github", value: ""},
+				{name: "id", in: "path", description: "ID of the stored OAuth token", value: int64(0)},
+			},
+			authenticated: true,
+			responses: []response{
+				{status: http.StatusOK, description: "Disconnected", body: statusResponse{}},
+				failure(http.StatusBadRequest, "Invalid provider or connection id"),
+				failure(http.StatusNotFound, "Connection not found"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/healthz", tag: "System",
+			summary: "Health check", description: "Check API health status",
+			responses: []response{{status: http.StatusOK, description: "Service is healthy", body: statusResponse{}}},
+		},
+		{
+			method: http.MethodGet, path: "/metrics", tag: "System",
+			summary: "Prometheus metrics",
+			description: "Metrics in the Prometheus text exposition format: jobs by outcome and reaction host, job queue depth, " +
+				"reaction latency, poller cycle durations and errors per integration, outbound status codes and runs per trigger type.",
+			responses: []response{{status: http.StatusOK, description: "Metrics", body: "", contentType: "text/plain"}},
+		},
+		{
+			method: http.MethodGet, path: "/admin/config", tag: "System",
+			summary: "Effective configuration",
+			description: "Settings loaded from the optional config file and the environment, with secrets redacted, and which " +
+				"optional integrations have credentials. Requires `Authorization: Bearer <ADMIN_TOKEN>`; disabled when ADMIN_TOKEN is not set.",
+			params: []param{{name: "Authorization", in: "header", description: "Bearer <ADMIN_TOKEN>", required: true, value: ""}},
+			responses: []response{
+				{status: http.StatusOK, description: "Redacted configuration", body: adminConfigResponse{}},
+				failure(http.StatusUnauthorized, "Invalid admin token"),
+				failure(http.StatusNotFound, "Admin endpoints disabled"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/admin/audit", tag: "Audit",
+			summary:     "Read audit log",
+			description: "Events of every user, newest first. Requires `Authorization: Bearer <ADMIN_TOKEN>`; disabled when ADMIN_TOKEN is not set.",
+			params: append([]param{
+				{name: "Authorization", in: "header", description: "Bearer <ADMIN_TOKEN>", required: true, value: ""},
+				{name: "actor_id", in: "query", description: "Only events by this user", value: int64(0)},
+				{name: "workspace_id", in: "query", description: "Only events of this workspace", value: int64(0)},
+			}, auditFilterParams...),
+			responses: []response{
+				{status: http.StatusOK, description: "Audit events, newest first", body: []auth.AuditEvent{}},
+				failure(http.StatusBadRequest, "Invalid filter"),
+				failure(http.StatusUnauthorized, "Invalid admin token"),
+				failure(http.StatusNotFound, "Admin endpoints disabled"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/about.json", tag: "System",
+			summary: "About server", description: "Returns client host, server time and supported services",
+			responses: []response{
+				{status: http.StatusOK, description: "About response", body: aboutResponse{}},
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/areas", tag: "System",
+			summary: "List services", description: "Get supported services, triggers and reactions",
+			params: []param{{name: "service_id", in: "query", description: "Only this service (id is accepted too)", value: ""}},
+			responses: []response{
+				{status: http.StatusOK, description: "Services list", body: areasResponse{}},
+				failure(http.StatusNotFound, "Service not found"),
+				failure(http.StatusInternalServerError, "Internal server error"),
+			},
+		},
+		{
+			method: http.MethodGet, path: "/resources/openapi.json", tag: "System",
+			summary: "OpenAPI spec", description: "This document",
+			responses: []response{{status: http.StatusOK, description: "Spec", body: map[string]any{}}},
+		},
+		{
+			method: http.MethodGet, path: "/docs/", tag: "System",
+			summary: "API reference", description: "Swagger UI rendering this document",
+			responses: []response{{status: http.StatusOK, description: "HTML page", body: "", contentType: "text/html"}},
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package httpapi
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"area/src/auth"
+	"area/src/workflows"
+)
+
+type putSecretRequest struct {
+	Value string `json:"value"`
+}
+
+// meSecrets handles GET /me/secrets, listing the names of the caller's secrets.
+func (h *Handler) meSecrets() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		secrets, err := h.Auth.ListSecrets(r.Context(), userID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "list secrets", "error", err)
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not list secrets"})
+			return
+		}
+		writeJSON(w, http.StatusOK, secrets)
+	})
+}
+
+// meSecret handles PUT /me/secrets/{name}, setting the value of one of the caller's secrets, and
+// DELETE /me/secrets/{name}. Values are never returned.
+func (h *Handler) meSecret() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, "/me/secrets/")
+		switch r.Method {
+		case http.MethodPut:
+			var payload putSecretRequest
+			if !decodeBody(w, r, &payload) {
+				return
+			}
+			created, err := h.Auth.PutSecret(r.Context(), userID, name, payload.Value)
+			var invalid *auth.ValidationError
+			switch {
+			case errors.As(err, &invalid):
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: invalid.Error()})
+			case err != nil:
+				slog.ErrorContext(r.Context(), "put secret", "error", err)
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not store secret"})
+			case created:
+				writeJSON(w, http.StatusCreated, statusResponse{Status: "created"})
+			default:
+				writeJSON(w, http.StatusOK, statusResponse{Status: "updated"})
+			}
+		case http.MethodDelete:
+			err := h.Auth.DeleteSecret(r.Context(), userID, name)
+			switch {
+			case errors.Is(err, auth.ErrSecretNotFound):
+				writeJSON(w, http.StatusNotFound, errorResponse{Error: err.Error()})
+			case err != nil:
+				slog.ErrorContext(r.Context(), "delete secret", "error", err)
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not delete secret"})
+			default:
+				writeJSON(w, http.StatusOK, statusResponse{Status: "deleted"})
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
@@ -1,13 +1,19 @@
 package httpapi
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
+	"math"
 	"net"
 	"net/http"
+	"net/netip"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -17,6 +23,7 @@ import (
 
 	"area/src/areas"
 	"area/src/auth"
+	"area/src/config"
 	"area/src/database"
 	"area/src/integrations/discord"
 	gh "area/src/integrations/github"
@@ -24,77 +31,222 @@ import (
 	"area/src/integrations/notion"
 	"area/src/integrations/slack"
 	"area/src/integrations/trello"
+	"area/src/logging"
+	"area/src/metrics"
 	"area/src/workflows"
 )
 
-// NewMux wires HTTP routes that the frontend can call.
-func NewMux(authService *auth.Service, wfService *workflows.Service) http.Handler {
+// NewMux wires HTTP routes that the frontend can call. A nil cfg uses config.Default, which
+// leaves every optional integration disabled.
+func NewMux(authService *auth.Service, wfService *workflows.Service, cfg *config.Config) http.Handler {
+	if cfg == nil {
+		cfg = config.Default()
+	}
+	// config.Load has rejected invalid ranges already.
+	trusted, _ := cfg.TrustedProxyPrefixes()
 	server := &Handler{
-		Auth:      authService,
-		workflows: wfService,
+		Auth:           authService,
+		workflows:      wfService,
+		config:         cfg,
+		trustedProxies: trusted,
 	}
-
-	googleHTTP := goog.NewHTTPHandlers(nil)
-	githubHTTP := gh.NewHTTPHandlers(nil)
-	githubMobileHTTP := gh.NewHTTPHandlers(gh.NewMobileClient())
-	discordHTTP := discord.NewHTTPHandlers(nil)
-	slackHTTP := slack.NewHTTPHandlers(nil)
-	notionHTTP := notion.NewHTTPHandlers(nil)
-	trelloHTTP := trello.NewHTTPHandlers(nil)
 	mux := http.NewServeMux()
-	mux.Handle("/login", server.Login())
-	mux.Handle("/register", server.Register())
-	mux.Handle("/healthz", server.Health())
-	mux.Handle("/workflows", server.workflowsHandler())
-	mux.Handle("/workflows/", server.workflowResource())
-	mux.Handle("/hooks/", server.webhook())
-	mux.Handle("/oauth/google/start", googleHTTP.Start())
-	mux.Handle("/oauth/google/exchange", googleHTTP.Exchange())
-	mux.Handle("/oauth/github/exchange", server.exchangeGithubToken())
-	mux.Handle("/oauth/google/login", googleHTTP.Login())
-	mux.Handle("/oauth/google/callback", googleHTTP.Callback())
-	mux.Handle("/oauth/google/mobile/login", googleHTTP.Login())
-	mux.Handle("/oauth/google/mobile/callback", googleHTTP.Callback())
-	mux.Handle("/oauth/status", server.oauthStatus())
-	mux.Handle("/oauth/github/login", githubHTTP.Login())
-	mux.Handle("/oauth/github/callback", githubHTTP.Callback())
-	mux.Handle("/oauth/github/mobile/login", githubMobileHTTP.LoginMobile())
-	mux.Handle("/oauth/github/mobile/callback", githubMobileHTTP.CallbackMobile())
-	mux.Handle("/actions/github/issue", githubHTTP.Issue())
-	mux.Handle("/actions/github/pr", githubHTTP.PullRequest())
-	mux.Handle("/actions/google/email", googleHTTP.SendEmail())
-	mux.Handle("/actions/google/calendar", googleHTTP.CreateEvent())
-	mux.Handle("/actions/discord/message", discordHTTP.Message())
-	mux.Handle("/actions/discord/embed", discordHTTP.Embed())
-	mux.Handle("/actions/discord/message/edit", discordHTTP.Edit())
-	mux.Handle("/actions/discord/message/delete", discordHTTP.Delete())
-	mux.Handle("/actions/discord/message/react", discordHTTP.React())
-	mux.Handle("/actions/slack/message", slackHTTP.Message())
-	mux.Handle("/actions/slack/blocks", slackHTTP.Blocks())
-	mux.Handle("/actions/slack/message/update", slackHTTP.Update())
-	mux.Handle("/actions/slack/message/delete", slackHTTP.Delete())
-	mux.Handle("/actions/slack/message/react", slackHTTP.React())
-	mux.Handle("/actions/notion/page", notionHTTP.Page())
-	mux.Handle("/actions/notion/blocks", notionHTTP.AppendBlocks())
-	mux.Handle("/actions/notion/database", notionHTTP.Database())
-	mux.Handle("/actions/notion/page/update", notionHTTP.UpdatePage())
-	mux.Handle("/actions/trello/card", trelloHTTP.CreateCard())
-	mux.Handle("/actions/trello/card/move", trelloHTTP.MoveCard())
-	mux.Handle("/actions/trello/list", trelloHTTP.CreateList())
-	mux.Handle("/about.json", server.about())
-	mux.Handle("/areas", server.listAreas())
-	mux.Handle("/resources/openapi.json", server.openAPISpec())
-	mux.Handle("/docs/", v5emb.New(
-		"KiKonect API Reference",
-		"/resources/openapi.json",
-		"/docs/",
-	))
-	return WithCORS(mux)
+	for _, rt := range server.routes() {
+		mux.Handle(rt.pattern, rt.handler)
+	}
+	return WithCORS(WithRequestID(WithClient(mux, trusted)))
+}
+
+// route is one pattern registered on the mux; every route must be documented in apiOperations.
+type route struct {
+	pattern string
+	handler http.Handler
+}
+
+// routes returns every route served by NewMux.
+func (h *Handler) routes() []route {
+	cfg := h.config
+	googleHTTP := goog.NewHTTPHandlers(goog.NewClient(cfg.Google))
+	githubHTTP := gh.NewHTTPHandlers(gh.NewClient(cfg.GitHub))
+	githubMobileHTTP := gh.NewHTTPHandlers(gh.NewClient(cfg.GitHubMobile))
+	googleHTTP.Connected = h.oauthConnected("google")
+	githubHTTP.Connected = h.oauthConnected("github")
+	githubMobileHTTP.Connected = h.oauthConnected("github")
+	if h.Auth != nil {
+		googleHTTP.States = h.Auth
+		githubHTTP.States = h.Auth
+		githubMobileHTTP.States = h.Auth
+	}
+	google := integrationGate("google", cfg.Google.Enabled())
+	github := integrationGate("github", cfg.GitHub.Enabled())
+	githubMobile := integrationGate("github mobile", cfg.GitHubMobile.Enabled())
+	routes := []route{
+		{"/login", h.Login()},
+		{"/login/2fa", h.loginTwoFactor()},
+		{"/refresh", h.refresh()},
+		{"/logout", h.logout()},
+		{"/me/tokens", h.sessionOnly(h.meTokens())},
+		{"/me/tokens/", h.sessionOnly(h.meToken())},
+		{"/me/2fa", h.sessionOnly(h.meTwoFactor())},
+		{"/me/2fa/enroll", h.sessionOnly(h.meTwoFactorEnroll())},
+		{"/me/2fa/verify", h.sessionOnly(h.meTwoFactorVerify())},
+		{"/me/2fa/disable", h.sessionOnly(h.meTwoFactorDisable())},
+		{"/me/secrets", h.sessionOnly(h.meSecrets())},
+		{"/me/secrets/", h.sessionOnly(h.meSecret())},
+		{"/me/audit", h.sessionOnly(h.meAudit())},
+		{"/workspaces", h.sessionOnly(h.workspaces())},
+		{"/workspaces/", h.sessionOnly(h.workspaceResource())},
+		{"/invites/accept", h.sessionOnly(h.acceptInvite())},
+		{"/register", h.Register()},
+		{"/password/forgot", h.forgotPassword()},
+		{"/password/reset", h.resetPassword()},
+		{"/email/verify", h.verifyEmail()},
+		{"/email/verify/resend", h.sessionOnly(h.resendVerification())},
+		{"/healthz", h.Health()},
+		{"/metrics", metrics.Default.Handler()},
+		{"/admin/config", h.adminConfig()},
+		{"/admin/audit", h.adminAudit()},
+		{"/workflows", h.authenticated(h.workflowsHandler())},
+		{"/workflows/", h.authenticated(h.workflowResource())},
+		{"/runs/", h.authenticated(h.runResource())},
+		{"/events/stream", tokenFromQuery(h.authenticated(h.eventStream()))},
+		{"/hooks/", h.webhook()},
+		// Browsers navigate to the login routes, so they take the access token from the query too.
+		// Providers redirect to the callbacks without credentials: the OAuth state names the user.
+		{"/oauth/google/start", google(h.sessionOnly(googleHTTP.Start()))},
+		{"/oauth/google/exchange", google(h.sessionOnly(googleHTTP.Exchange()))},
+		{"/oauth/github/exchange", github(h.sessionOnly(h.exchangeGithubToken()))},
+		{"/oauth/google/login", google(tokenFromQuery(h.sessionOnly(googleHTTP.Login())))},
+		{"/oauth/google/callback", google(googleHTTP.Callback())},
+		{"/oauth/google/mobile/login", google(tokenFromQuery(h.sessionOnly(googleHTTP.Login())))},
+		{"/oauth/google/mobile/callback", google(googleHTTP.Callback())},
+		{"/oauth/status", h.authenticated(h.oauthStatus())},
+		{"/oauth/connections/", h.sessionOnly(h.oauthConnection())},
+		{"/oauth/github/login", github(tokenFromQuery(h.sessionOnly(githubHTTP.Login())))},
+		{"/oauth/github/callback", github(githubHTTP.Callback())},
+		{"/oauth/github/mobile/login", githubMobile(tokenFromQuery(h.sessionOnly(githubMobileHTTP.LoginMobile())))},
+		{"/oauth/github/mobile/callback", githubMobile(githubMobileHTTP.CallbackMobile())},
+		{"/about.json", h.about()},
+		{"/areas", h.listAreas()},
+		{"/resources/openapi.json", h.openAPISpec()},
+		{"/docs/", v5emb.New(
+			"KiKonect API Reference",
+			"/resources/openapi.json",
+			"/docs/",
+		)},
+	}
+	actions := actionHandlers(cfg)
+	for _, path := range slices.Sorted(maps.Keys(actions)) {
+		handler := validateReaction(path, actions[path])
+		if ReactionNeedsUser(path) {
+			handler = h.reactionCaller(handler)
+		}
+		routes = append(routes, route{path, withDeadline(cfg.Workflows.MaxTimeout(), handler)})
+	}
+	return routes
+}
+
+// Routes returns the patterns NewMux registers, sorted.
+func Routes() []string {
+	h := &Handler{config: config.Default()}
+	var patterns []string
+	for _, rt := range h.routes() {
+		patterns = append(patterns, rt.pattern)
+	}
+	slices.Sort(patterns)
+	return patterns
+}
+
+// actionHandlers returns the reaction endpoints by route; each route must have a catalog entry,
+// see areas.Check.
+func actionHandlers(cfg *config.Config) map[string]http.Handler {
+	googleHTTP := goog.NewHTTPHandlers(goog.NewClient(cfg.Google))
+	githubHTTP := gh.NewHTTPHandlers(gh.NewClient(cfg.GitHub))
+	discordHTTP := discord.NewHTTPHandlers(discord.NewClientWithToken(cfg.Integrations.DiscordBotToken))
+	slackHTTP := slack.NewHTTPHandlers(slack.NewClientWithToken(cfg.Integrations.SlackBotToken))
+	notionHTTP := notion.NewHTTPHandlers(notion.NewClientWithToken(cfg.Integrations.NotionToken))
+	trelloHTTP := trello.NewHTTPHandlers(trello.NewClientWithCredentials(cfg.Integrations.TrelloAPIKey, cfg.Integrations.TrelloToken))
+	google := integrationGate("google", cfg.Google.Enabled())
+	github := integrationGate("github", cfg.GitHub.Enabled())
+	return map[string]http.Handler{
+		"/actions/github/issue":           github(githubHTTP.Issue()),
+		"/actions/github/pr":              github(githubHTTP.PullRequest()),
+		"/actions/google/email":           google(googleHTTP.SendEmail()),
+		"/actions/google/calendar":        google(googleHTTP.CreateEvent()),
+		"/actions/discord/message":        discordHTTP.Message(),
+		"/actions/discord/embed":          discordHTTP.Embed(),
+		"/actions/discord/message/edit":   discordHTTP.Edit(),
+		"/actions/discord/message/delete": discordHTTP.Delete(),
+		"/actions/discord/message/react":  discordHTTP.React(),
+		"/actions/slack/message":          slackHTTP.Message(),
+		"/actions/slack/blocks":           slackHTTP.Blocks(),
+		"/actions/slack/message/update":   slackHTTP.Update(),
+		"/actions/slack/message/delete":   slackHTTP.Delete(),
+		"/actions/slack/message/react":    slackHTTP.React(),
+		"/actions/notion/page":            notionHTTP.Page(),
+		"/actions/notion/blocks":          notionHTTP.AppendBlocks(),
+		"/actions/notion/database":        notionHTTP.Database(),
+		"/actions/notion/page/update":     notionHTTP.UpdatePage(),
+		"/actions/trello/card":            trelloHTTP.CreateCard(),
+		"/actions/trello/card/move":       trelloHTTP.MoveCard(),
+		"/actions/trello/list":            trelloHTTP.CreateList(),
+	}
+}
+
+// ReactionNeedsUser reports whether the reaction route at path acts with an OAuth connection of
+// its caller, and so only serves authenticated users, see reactionCaller.
+func ReactionNeedsUser(path string) bool {
+	return strings.HasPrefix(path, "/actions/google/") || strings.HasPrefix(path, "/actions/github/")
+}
+
+// validateReaction rejects a payload that does not match the catalog fields of the reaction
+// served at route, listing the invalid fields.
+func validateReaction(route string, next http.Handler) http.Handler {
+	reaction, ok := areas.FindReaction(route)
+	if !ok {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+			return
+		}
+		if invalid := areas.CheckFields(reaction.Fields, body); len(invalid) > 0 {
+			writeJSON(w, http.StatusBadRequest, validationErrorResponse{Error: "invalid payload", Fields: invalid})
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withDeadline bounds the calls a reaction makes to its provider by max. The integration
+// clients have no timeout of their own: the executor's request already carries the deadline of
+// the workflow, and max caps it for every caller.
+func withDeadline(max time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), max)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ActionRoutes returns the routes of the reaction endpoints, sorted.
+func ActionRoutes() []string {
+	return slices.Sorted(maps.Keys(actionHandlers(config.Default())))
 }
 
 type Handler struct {
 	Auth      *auth.Service
 	workflows *workflows.Service
+	config    *config.Config
+	// trustedProxies are the reverse proxies whose X-Forwarded-For header clientIP believes.
+	trustedProxies []netip.Prefix
 }
 
 type loginRequest struct {
@@ -115,29 +267,24 @@ type workflowRequest struct {
 	ActionURL       string          `json:"action_url"`
 	TriggerConfig   json.RawMessage `json:"trigger_config"`
 	IntervalMinutes *int            `json:"interval_minutes,omitempty"`
+	// WorkspaceID creates the workflow in a workspace the caller edits instead of their own.
+	WorkspaceID *int64 `json:"workspace_id,omitempty"`
 }
 
 type OAuthAccessResponse struct {
 	AccessToken string `json:"access_token"`
 }
 
-// userContext extracts the user id from headers/query and enriches the context.
+// userContext returns the request context once authenticated has stored the caller in it.
 func userContext(r *http.Request) (context.Context, error) {
-	user := r.Header.Get("X-User-ID")
-	if user == "" {
-		user = r.URL.Query().Get("user_id")
-	}
-	if user == "" {
-		return r.Context(), fmt.Errorf("missing user id")
+	if _, err := workflows.UserIDFromContext(r.Context()); err != nil {
+		return r.Context(), err
 	}
-	id, err := strconv.ParseInt(user, 10, 64)
-	if err != nil || id <= 0 {
-		return r.Context(), fmt.Errorf("invalid user id")
-	}
-	return workflows.WithUserID(r.Context(), id), nil
+	return r.Context(), nil
 }
 
-// Login handles POST /login authentication requests.
+// Login handles POST /login: it checks the credentials and opens a session, answering the user
+// with its access and refresh tokens.
 func (h *Handler) Login() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -163,20 +310,95 @@ func (h *Handler) Login() http.Handler {
 			return
 		}
 
-		user, err := h.Auth.Authenticate(payload.Email, payload.Password)
+		user, challenge, err := h.Auth.Login(r.Context(), payload.Email, payload.Password, h.clientIP(r))
+		var locked *auth.LoginLockedError
 		switch {
 		case errors.Is(err, auth.ErrInvalidCredentials):
 			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "invalid email or password"})
 			return
+		case errors.As(err, &locked):
+			writeLoginLocked(w, locked)
+			return
 		case err != nil:
+			slog.ErrorContext(r.Context(), "login", "error", err)
 			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not complete login"})
 			return
 		}
-
-		writeJSON(w, http.StatusOK, user)
+		if challenge != "" {
+			twoFactorChallenge(w, challenge)
+			return
+		}
+		h.startSession(w, r, user)
 	})
 }
 
+// writeLoginLocked answers a login refused after too many failures, telling when to try again.
+func writeLoginLocked(w http.ResponseWriter, locked *auth.LoginLockedError) {
+	writeTooManyRequests(w, locked.RetryAfter, locked.Error())
+}
+
+// writeTooManyRequests answers 429 with message, telling the client when to retry.
+func writeTooManyRequests(w http.ResponseWriter, retryAfter time.Duration, message string) {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	writeJSON(w, http.StatusTooManyRequests, errorResponse{Error: message})
+}
+
+// clientIP returns the address of the client behind r, trusting the configured proxies.
+func (h *Handler) clientIP(r *http.Request) string {
+	return clientIP(r, h.trustedProxies)
+}
+
+// clientIP returns the address of the client behind r. Any client can set X-Forwarded-For, so
+// it is only read when the peer is a trusted proxy: its addresses are walked from the right,
+// skipping the trusted proxies the request went through, to the first one that is not.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !isTrusted(peer, trusted) {
+		return host
+	}
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		host = addr.Unmap().String()
+		if !isTrusted(addr, trusted) {
+			break
+		}
+	}
+	return host
+}
+
+// isTrusted reports whether addr belongs to one of the trusted ranges.
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// startSession answers a completed login with the user and the tokens of a new session.
+func (h *Handler) startSession(w http.ResponseWriter, r *http.Request, user *auth.User) {
+	tokens, err := h.Auth.StartSession(r.Context(), user.ID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not start session"})
+		return
+	}
+	writeJSON(w, http.StatusOK, loginResponse{User: user, Tokens: tokens})
+}
+
 // Register handles POST /register user creation requests.
 func (h *Handler) Register() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -205,7 +427,7 @@ func (h *Handler) Register() http.Handler {
 			return
 		}
 
-		user, err := h.Auth.Register(payload.Email, payload.Password, payload.FirstName, payload.LastName)
+		user, err := h.Auth.Register(r.Context(), payload.Email, payload.Password, payload.FirstName, payload.LastName)
 		switch {
 		case errors.Is(err, auth.ErrUserExists):
 			writeJSON(w, http.StatusConflict, errorResponse{Error: "user already exists"})
@@ -214,6 +436,9 @@ func (h *Handler) Register() http.Handler {
 			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not complete registration"})
 			return
 		}
+		if err := h.Auth.SendEmailVerification(r.Context(), user); err != nil && !errors.Is(err, auth.ErrNoAccountStore) {
+			slog.ErrorContext(r.Context(), "send email verification", "error", err)
+		}
 
 		writeJSON(w, http.StatusCreated, user)
 	})
@@ -226,29 +451,21 @@ func (h *Handler) oauthStatus() http.Handler {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		user := r.Header.Get("X-User-ID")
-		if user == "" {
-			user = r.URL.Query().Get("user_id")
-		}
-		if user == "" {
-			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "missing user id"})
-			return
-		}
-		userID, err := strconv.ParseInt(user, 10, 64)
-		if err != nil || userID <= 0 {
-			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid user id"})
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
 			return
 		}
 
-		resp := map[string]any{"user_id": userID}
+		resp := oauthStatusResponse{UserID: userID}
 		if token, err := database.GetLatestGoogleTokenForUser(r.Context(), userID); err == nil && token != nil {
-			resp["google_token_id"] = token.ID
+			resp.GoogleTokenID = &token.ID
 		} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not fetch google token"})
 			return
 		}
 		if token, err := database.GetLatestGithubTokenForUser(r.Context(), userID); err == nil && token != nil {
-			resp["github_token_id"] = token.ID
+			resp.GithubTokenID = &token.ID
 		} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not fetch github token"})
 			return
@@ -258,6 +475,40 @@ func (h *Handler) oauthStatus() http.Handler {
 	})
 }
 
+// oauthConnected records the connections of provider stored by its OAuth callbacks.
+func (h *Handler) oauthConnected(provider string) func(context.Context, int64, int64) {
+	return func(ctx context.Context, userID, tokenID int64) {
+		h.Auth.RecordConnection(ctx, userID, provider, tokenID)
+	}
+}
+
+// oauthConnection handles DELETE /oauth/connections/{provider}/{id}, disconnecting an OAuth
+// account of the caller.
+func (h *Handler) oauthConnection() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		}
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		provider, rawID, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/oauth/connections/"), "/")
+		id, err := strconv.ParseInt(rawID, 10, 64)
+		if !ok || err != nil || id <= 0 {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid connection id"})
+			return
+		}
+		if err := h.Auth.Disconnect(r.Context(), userID, provider, id); err != nil {
+			writeWorkspaceError(w, r, err, "disconnect")
+			return
+		}
+		writeJSON(w, http.StatusOK, statusResponse{Status: "disconnected"})
+	})
+}
+
 // Health serves a simple Health-check endpoint.
 func (h *Handler) Health() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -265,10 +516,33 @@ func (h *Handler) Health() http.Handler {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		writeJSON(w, http.StatusOK, statusResponse{Status: "ok"})
 	})
 }
 
+// adminConfig serves GET /admin/config: the effective settings with secrets redacted.
+// It requires "Authorization: Bearer <ADMIN_TOKEN>" and is off when no admin token is set.
+func (h *Handler) adminConfig() http.Handler {
+	return h.adminOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, adminConfigResponse{
+			Config:       h.config.Redacted(),
+			Integrations: h.config.EnabledIntegrations(),
+		})
+	}))
+}
+
+// integrationGate wraps the routes of an optional integration, answering 503 when it is not configured.
+func integrationGate(name string, enabled bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: name + " integration is not configured"})
+		})
+	}
+}
+
 // listAreas exposes the catalog of available services/triggers/reactions for the clients.
 func (h *Handler) listAreas() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -304,10 +578,7 @@ func (h *Handler) listAreas() http.Handler {
 		if count, err := database.CountUsers(); err == nil {
 			userCount = count
 		}
-		writeJSON(w, http.StatusOK, map[string]any{
-			"services":   services,
-			"user_count": userCount,
-		})
+		writeJSON(w, http.StatusOK, areasResponse{Services: services, UserCount: userCount})
 	})
 }
 
@@ -315,6 +586,40 @@ type errorResponse struct {
 	Error string `json:"error"`
 }
 
+type statusResponse struct {
+	Status string `json:"status"`
+}
+
+type cancelledResponse struct {
+	Cancelled int64 `json:"cancelled"`
+}
+
+type dataResponse struct {
+	Data string `json:"data"`
+}
+
+type oauthStatusResponse struct {
+	UserID        int64 `json:"user_id"`
+	GoogleTokenID *uint `json:"google_token_id,omitempty"`
+	GithubTokenID *uint `json:"github_token_id,omitempty"`
+}
+
+type adminConfigResponse struct {
+	Config       map[string]any  `json:"config"`
+	Integrations map[string]bool `json:"integrations"`
+}
+
+type areasResponse struct {
+	Services  []areas.Service `json:"services"`
+	UserCount int64           `json:"user_count"`
+}
+
+// validationErrorResponse lists the invalid fields of a trigger config or reaction payload.
+type validationErrorResponse struct {
+	Error  string             `json:"error"`
+	Fields []areas.FieldError `json:"fields"`
+}
+
 type aboutResponse struct {
 	Client struct {
 		Host string `json:"host"`
@@ -350,10 +655,7 @@ func (h *Handler) about() http.Handler {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		host, _, err := net.SplitHostPort(r.RemoteAddr)
-		if err != nil {
-			host = r.RemoteAddr
-		}
+		host := h.clientIP(r)
 		services, err := areas.List(r.Context())
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
@@ -393,7 +695,8 @@ func WithCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,OPTIONS,DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type,Authorization,X-User-ID")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type,Authorization,X-User-ID,X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
@@ -404,6 +707,36 @@ func WithCORS(next http.Handler) http.Handler {
 	})
 }
 
+// maxRequestIDLen bounds client-supplied request IDs.
+const maxRequestIDLen = 64
+
+// WithRequestID tags each request with an X-Request-ID (the client's, if well-formed) for log correlation.
+func WithRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID accepts short IDs made of letters, digits, '-', '_' and '.'.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // EnsureNoTrailingData rejects payloads that contain multiple JSON objects.
 func EnsureNoTrailingData(decoder *json.Decoder) error {
 	if decoder.More() {
@@ -440,6 +773,19 @@ func (h *Handler) createWorkflow(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
 		return
 	}
+	if h.config.Auth.RequireVerifiedEmail {
+		userID, _ := workflows.UserIDFromContext(ctx)
+		verified, err := h.Auth.EmailVerified(ctx, userID)
+		if err != nil {
+			slog.ErrorContext(ctx, "check email verification", "error", err)
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not check email verification"})
+			return
+		}
+		if !verified {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "verify your email address before creating workflows"})
+			return
+		}
+	}
 
 	var payload workflowRequest
 	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
@@ -457,7 +803,23 @@ func (h *Handler) createWorkflow(w http.ResponseWriter, r *http.Request) {
 	if len(cfg) == 0 && payload.IntervalMinutes != nil && payload.TriggerType == "interval" {
 		cfg, _ = json.Marshal(map[string]int{"interval_minutes": *payload.IntervalMinutes})
 	}
-	wf, err := h.workflows.CreateWorkflow(ctx, payload.Name, payload.TriggerType, payload.ActionURL, cfg)
+	var workspaceID int64
+	if payload.WorkspaceID != nil {
+		workspaceID = *payload.WorkspaceID
+	}
+	wf, err := h.workflows.CreateWorkspaceWorkflow(ctx, workspaceID, payload.Name, payload.TriggerType, payload.ActionURL, cfg)
+	var invalid *areas.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		writeJSON(w, http.StatusBadRequest, validationErrorResponse{Error: "invalid trigger_config", Fields: invalid.Fields})
+		return
+	case errors.Is(err, workflows.ErrWorkspaceNotFound):
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "workspace not found"})
+		return
+	case errors.Is(err, workflows.ErrWorkflowReadOnly):
+		writeJSON(w, http.StatusForbidden, errorResponse{Error: err.Error()})
+		return
+	}
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
 		return
@@ -486,6 +848,8 @@ func (h *Handler) listWorkflows(w http.ResponseWriter, r *http.Request) {
 
 // workflowResource handles:
 // - POST /workflows/{id}/trigger to enqueue a run
+// - POST /workflows/{id}/cancel to cancel all pending runs
+// - GET /workflows/{id}/runs to list recent runs with their reaction outcomes
 // - DELETE /workflows/{id} to delete a workflow
 func (h *Handler) workflowResource() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -524,10 +888,14 @@ func (h *Handler) workflowResource() http.Handler {
 						writeJSON(w, http.StatusNotFound, errorResponse{Error: "workflow not found"})
 						return
 					}
+					if errors.Is(err, workflows.ErrWorkflowReadOnly) {
+						writeJSON(w, http.StatusForbidden, errorResponse{Error: err.Error()})
+						return
+					}
 					writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not enable workflow"})
 					return
 				}
-				writeJSON(w, http.StatusOK, map[string]string{"status": "enabled"})
+				writeJSON(w, http.StatusOK, statusResponse{Status: "enabled"})
 				return
 			case "disable":
 				if err := h.workflows.SetEnabled(ctx, workflowID, false, time.Now()); err != nil {
@@ -535,10 +903,14 @@ func (h *Handler) workflowResource() http.Handler {
 						writeJSON(w, http.StatusNotFound, errorResponse{Error: "workflow not found"})
 						return
 					}
+					if errors.Is(err, workflows.ErrWorkflowReadOnly) {
+						writeJSON(w, http.StatusForbidden, errorResponse{Error: err.Error()})
+						return
+					}
 					writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not disable workflow"})
 					return
 				}
-				writeJSON(w, http.StatusOK, map[string]string{"status": "disabled"})
+				writeJSON(w, http.StatusOK, statusResponse{Status: "disabled"})
 				return
 			default:
 				writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid action"})
@@ -546,6 +918,41 @@ func (h *Handler) workflowResource() http.Handler {
 			}
 		}
 
+		// GET /workflows/{id}/runs?limit=N
+		if len(parts) == 3 && parts[2] == "runs" && r.Method == http.MethodGet {
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			runs, err := h.workflows.ListRuns(ctx, workflowID, limit)
+			if err != nil {
+				if errors.Is(err, workflows.ErrWorkflowNotFound) {
+					writeJSON(w, http.StatusNotFound, errorResponse{Error: "workflow not found"})
+					return
+				}
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not list runs"})
+				return
+			}
+			writeJSON(w, http.StatusOK, runs)
+			return
+		}
+
+		// POST /workflows/{id}/cancel
+		if len(parts) == 3 && parts[2] == "cancel" && r.Method == http.MethodPost {
+			cancelled, err := h.workflows.CancelPendingRuns(ctx, workflowID, time.Now())
+			if err != nil {
+				if errors.Is(err, workflows.ErrWorkflowNotFound) {
+					writeJSON(w, http.StatusNotFound, errorResponse{Error: "workflow not found"})
+					return
+				}
+				if errors.Is(err, workflows.ErrWorkflowReadOnly) {
+					writeJSON(w, http.StatusForbidden, errorResponse{Error: err.Error()})
+					return
+				}
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not cancel runs"})
+				return
+			}
+			writeJSON(w, http.StatusOK, cancelledResponse{Cancelled: cancelled})
+			return
+		}
+
 		// DELETE /workflows/{id}
 		if len(parts) == 2 && r.Method == http.MethodDelete {
 			if err := h.workflows.DeleteWorkflow(ctx, workflowID); err != nil {
@@ -553,10 +960,14 @@ func (h *Handler) workflowResource() http.Handler {
 					writeJSON(w, http.StatusNotFound, errorResponse{Error: "workflow not found"})
 					return
 				}
+				if errors.Is(err, workflows.ErrWorkflowReadOnly) {
+					writeJSON(w, http.StatusForbidden, errorResponse{Error: err.Error()})
+					return
+				}
 				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not delete workflow"})
 				return
 			}
-			writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+			writeJSON(w, http.StatusOK, statusResponse{Status: "deleted"})
 			return
 		}
 
@@ -578,22 +989,140 @@ func (h *Handler) workflowResource() http.Handler {
 			return
 		}
 
-		run, err := h.workflows.Trigger(ctx, workflowID, payload)
+		run, err := h.workflows.TriggerManually(ctx, workflowID, payload)
 		if err != nil {
 			switch {
 			case errors.Is(err, workflows.ErrWorkflowNotFound):
 				writeJSON(w, http.StatusNotFound, errorResponse{Error: "workflow not found"})
 			case errors.Is(err, workflows.ErrWorkflowDisabled):
 				writeJSON(w, http.StatusBadRequest, errorResponse{Error: "workflow disabled"})
+			case errors.Is(err, workflows.ErrWorkflowReadOnly):
+				writeJSON(w, http.StatusForbidden, errorResponse{Error: err.Error()})
 			default:
 				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not trigger workflow"})
 			}
 			return
 		}
+		if run == nil {
+			// Digest-mode workflows buffer the event until the next flush.
+			writeJSON(w, http.StatusAccepted, statusResponse{Status: "buffered"})
+			return
+		}
 		writeJSON(w, http.StatusAccepted, run)
 	})
 }
 
+// runResource handles POST /runs/{id}/cancel to stop a pending or running run.
+func (h *Handler) runResource() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.workflows == nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "workflows not configured"})
+			return
+		}
+		ctx, err := userContext(r)
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		}
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) != 3 || parts[0] != "runs" || parts[2] != "cancel" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		runID, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid run id"})
+			return
+		}
+		run, err := h.workflows.CancelRun(ctx, runID, time.Now())
+		if err != nil {
+			switch {
+			case errors.Is(err, workflows.ErrRunNotFound):
+				writeJSON(w, http.StatusNotFound, errorResponse{Error: "run not found"})
+			case errors.Is(err, workflows.ErrRunFinished):
+				writeJSON(w, http.StatusConflict, errorResponse{Error: "run already finished"})
+			case errors.Is(err, workflows.ErrWorkflowReadOnly):
+				writeJSON(w, http.StatusForbidden, errorResponse{Error: err.Error()})
+			default:
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not cancel run"})
+			}
+			return
+		}
+		writeJSON(w, http.StatusOK, run)
+	})
+}
+
+// sseHeartbeat keeps idle event streams alive through proxies.
+const sseHeartbeat = 25 * time.Second
+
+// eventStream handles GET /events/stream, pushing the user's run and workflow events as Server-Sent Events.
+func (h *Handler) eventStream() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if h.workflows == nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "workflows not configured"})
+			return
+		}
+		ctx, err := userContext(r)
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "streaming unsupported"})
+			return
+		}
+		events, unsubscribe, err := h.workflows.SubscribeEvents(ctx)
+		if err != nil {
+			if errors.Is(err, workflows.ErrEventsUnavailable) {
+				writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "events not configured"})
+				return
+			}
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		}
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, ": connected\n\n")
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(sseHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+				flusher.Flush()
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(ev)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+				flusher.Flush()
+			}
+		}
+	})
+}
+
 // webhook handles external POST /hooks/{token} to trigger a webhook workflow.
 func (h *Handler) webhook() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -655,12 +1184,12 @@ func (h *Handler) exchangeGoogleToken() http.Handler {
 			return
 		}
 
-		data, err := auth.GetUserDataFromGoogle(r.FormValue("code"))
+		data, err := auth.GetUserDataFromGoogle(r.FormValue("code"), h.config.Google)
 		if err != nil {
 			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
 			return
 		}
-		writeJSON(w, http.StatusAccepted, map[string]string{"data": string(data)})
+		writeJSON(w, http.StatusAccepted, dataResponse{Data: string(data)})
 	})
 }
 
@@ -677,24 +1206,11 @@ func (h *Handler) exchangeGithubToken() http.Handler {
 			return
 		}
 
-		data, err := auth.GetUserDataFromGithub(r.FormValue("code"))
+		data, err := auth.GetUserDataFromGithub(r.FormValue("code"), h.config.GitHub)
 		if err != nil {
 			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
 			return
 		}
-		writeJSON(w, http.StatusAccepted, map[string]string{"data": string(data)})
-	})
-}
-
-// openAPISpec serves the OpenAPI specification JSON file
-func (h *Handler) openAPISpec() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
-		w.Header().Set("Content-Type", "application/json")
-
-		http.ServeFile(w, r, "resources/openapi.json")
+		writeJSON(w, http.StatusAccepted, dataResponse{Data: string(data)})
 	})
 }
@@ -0,0 +1,219 @@
+package httpapi
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"area/src/auth"
+	"area/src/logging"
+	"area/src/workflows"
+)
+
+// loginResponse is the user, as before sessions, plus the tokens of the new session.
+type loginResponse struct {
+	*auth.User
+	*auth.Tokens
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+var (
+	errMissingToken      = errors.New("missing bearer token")
+	errInvalidUserHeader = errors.New("invalid user id")
+)
+
+// authenticated resolves the caller of a user route and stores it with workflows.WithUserID:
+// the session of the bearer access token, a personal access token holding the scope the request
+// needs (see requiredScope) or, with the dev user header setting, the X-User-ID header or user_id
+// query parameter. Other callers get 401, or 403 for a token without the scope.
+func (h *Handler) authenticated(next http.Handler) http.Handler {
+	return h.authenticate(next, true)
+}
+
+// sessionOnly is authenticated without personal access tokens, for the routes managing them.
+func (h *Handler) sessionOnly(next http.Handler) http.Handler {
+	return h.authenticate(next, false)
+}
+
+func (h *Handler) authenticate(next http.Handler, allowAPITokens bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, apiToken, err := h.caller(r)
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken), errors.Is(err, errMissingToken), errors.Is(err, errInvalidUserHeader):
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		case err != nil:
+			slog.ErrorContext(r.Context(), "verify access token", "error", err)
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not verify token"})
+			return
+		}
+		if apiToken != nil {
+			if !allowAPITokens {
+				writeJSON(w, http.StatusForbidden, errorResponse{Error: "personal access tokens cannot be used here"})
+				return
+			}
+			if scope := requiredScope(r); !apiToken.Allows(scope) {
+				writeJSON(w, http.StatusForbidden, errorResponse{Error: "token lacks scope " + scope})
+				return
+			}
+		}
+		ctx := logging.With(r.Context(), "user_id", userID)
+		next.ServeHTTP(w, r.WithContext(workflows.WithUserID(ctx, userID)))
+	})
+}
+
+// reactionCaller authenticates the reaction routes acting with the OAuth connections of their
+// caller: the executor calls them with a reaction token of the workflow's user, and users with
+// their session. Personal access tokens get 403: no scope lets them act as their user's OAuth
+// connections.
+func (h *Handler) reactionCaller(next http.Handler) http.Handler {
+	user := h.sessionOnly(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if !strings.HasPrefix(token, auth.ReactionTokenPrefix) {
+			user.ServeHTTP(w, r)
+			return
+		}
+		if h.Auth == nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not verify token"})
+			return
+		}
+		userID, err := h.Auth.VerifyReactionToken(r.Context(), token)
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken):
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		case err != nil:
+			slog.ErrorContext(r.Context(), "verify reaction token", "error", err)
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not verify token"})
+			return
+		}
+		ctx := logging.With(r.Context(), "user_id", userID)
+		next.ServeHTTP(w, r.WithContext(workflows.WithUserID(ctx, userID)))
+	})
+}
+
+// caller returns the id of the user making r and, when r carries one, its personal access token.
+func (h *Handler) caller(r *http.Request) (int64, *auth.APIToken, error) {
+	if token := bearerToken(r); token != "" {
+		if h.Auth == nil {
+			return 0, nil, auth.ErrNoSessionStore
+		}
+		if strings.HasPrefix(token, auth.APITokenPrefix) {
+			apiToken, err := h.Auth.VerifyAPIToken(r.Context(), token)
+			if err != nil {
+				return 0, nil, err
+			}
+			return apiToken.UserID, apiToken, nil
+		}
+		session, err := h.Auth.VerifyAccessToken(r.Context(), token)
+		if err != nil {
+			return 0, nil, err
+		}
+		return session.UserID, nil, nil
+	}
+	if !h.config.Auth.DevUserHeader {
+		return 0, nil, errMissingToken
+	}
+	user := r.Header.Get("X-User-ID")
+	if user == "" {
+		user = r.URL.Query().Get("user_id")
+	}
+	id, err := strconv.ParseInt(user, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, nil, errInvalidUserHeader
+	}
+	return id, nil, nil
+}
+
+// requiredScope is the personal access token scope a request needs: triggering a workflow needs
+// workflows:trigger, reading needs workflows:read and anything else workflows:write.
+func requiredScope(r *http.Request) string {
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/workflows/") && strings.HasSuffix(r.URL.Path, "/trigger"):
+		return auth.ScopeWorkflowsTrigger
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return auth.ScopeWorkflowsRead
+	default:
+		return auth.ScopeWorkflowsWrite
+	}
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// tokenFromQuery lets EventSource clients, which cannot set headers, pass the access token as the
+// access_token query parameter.
+func tokenFromQuery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+			r = r.Clone(r.Context())
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// refresh handles POST /refresh, trading a refresh token for new session tokens.
+func (h *Handler) refresh() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var payload refreshRequest
+		if !decodeBody(w, r, &payload) {
+			return
+		}
+		if payload.RefreshToken == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "refresh_token is required"})
+			return
+		}
+		tokens, err := h.Auth.Refresh(r.Context(), payload.RefreshToken)
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken):
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		case err != nil:
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not refresh session"})
+			return
+		}
+		writeJSON(w, http.StatusOK, tokens)
+	})
+}
+
+// logout handles POST /logout, revoking the session of the bearer access token.
+func (h *Handler) logout() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		token := bearerToken(r)
+		if token == "" {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: errMissingToken.Error()})
+			return
+		}
+		err := h.Auth.Logout(r.Context(), token)
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken):
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		case err != nil:
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not end session"})
+			return
+		}
+		writeJSON(w, http.StatusOK, statusResponse{Status: "logged out"})
+	})
+}
@@ -0,0 +1,98 @@
+package httpapi
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"area/src/auth"
+	"area/src/workflows"
+)
+
+type createAPITokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresAt is optional; the token never expires without it.
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// createdAPITokenResponse describes a new personal access token along with the token itself,
+// which is not shown again.
+type createdAPITokenResponse struct {
+	*auth.APIToken
+	Token string `json:"token"`
+}
+
+// meTokens handles GET and POST /me/tokens, listing and creating the caller's personal access tokens.
+func (h *Handler) meTokens() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			tokens, err := h.Auth.ListAPITokens(r.Context(), userID)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "list api tokens", "error", err)
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not list tokens"})
+				return
+			}
+			writeJSON(w, http.StatusOK, tokens)
+		case http.MethodPost:
+			var payload createAPITokenRequest
+			if !decodeBody(w, r, &payload) {
+				return
+			}
+			secret, token, err := h.Auth.CreateAPIToken(r.Context(), userID, payload.Name, payload.Scopes, payload.ExpiresAt)
+			var invalid *auth.ValidationError
+			switch {
+			case errors.As(err, &invalid):
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: invalid.Error()})
+				return
+			case err != nil:
+				slog.ErrorContext(r.Context(), "create api token", "error", err)
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not create token"})
+				return
+			}
+			writeJSON(w, http.StatusCreated, createdAPITokenResponse{APIToken: token, Token: secret})
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// meToken handles DELETE /me/tokens/{id}, revoking one of the caller's personal access tokens.
+func (h *Handler) meToken() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		}
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/me/tokens/"), 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid token id"})
+			return
+		}
+		err = h.Auth.DeleteAPIToken(r.Context(), userID, id)
+		switch {
+		case errors.Is(err, auth.ErrAPITokenNotFound):
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: err.Error()})
+			return
+		case err != nil:
+			slog.ErrorContext(r.Context(), "delete api token", "error", err)
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not delete token"})
+			return
+		}
+		writeJSON(w, http.StatusOK, statusResponse{Status: "deleted"})
+	})
+}
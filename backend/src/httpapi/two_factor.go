@@ -0,0 +1,177 @@
+package httpapi
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"area/src/auth"
+	"area/src/workflows"
+)
+
+type twoFactorCodeRequest struct {
+	// Code is a code of the authenticator app or, to disable, an unused recovery code.
+	Code string `json:"code"`
+}
+
+type twoFactorLoginRequest struct {
+	TwoFactorToken string `json:"two_factor_token"`
+	// Code is a code of the authenticator app or an unused recovery code.
+	Code string `json:"code"`
+}
+
+// twoFactorChallengeResponse answers POST /login when the user has two-factor authentication
+// enabled; the login goes on at POST /login/2fa.
+type twoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	TwoFactorToken    string `json:"two_factor_token"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// writeTwoFactorError answers the errors of the two-factor service methods, with status for a
+// wrong code.
+func writeTwoFactorError(w http.ResponseWriter, r *http.Request, err error, status int, action string) {
+	var locked *auth.LoginLockedError
+	switch {
+	case errors.As(err, &locked):
+		writeLoginLocked(w, locked)
+	case errors.Is(err, auth.ErrInvalidTwoFactorCode), errors.Is(err, auth.ErrInvalidToken):
+		writeJSON(w, status, errorResponse{Error: err.Error()})
+	case errors.Is(err, auth.ErrTwoFactorEnabled), errors.Is(err, auth.ErrTwoFactorNotEnrolled):
+		writeJSON(w, http.StatusConflict, errorResponse{Error: err.Error()})
+	default:
+		slog.ErrorContext(r.Context(), action, "error", err)
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not " + action})
+	}
+}
+
+// meTwoFactor handles GET /me/2fa, telling whether the caller has two-factor authentication enabled.
+func (h *Handler) meTwoFactor() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		}
+		status, err := h.Auth.TwoFactorStatus(r.Context(), userID)
+		if err != nil {
+			writeTwoFactorError(w, r, err, http.StatusBadRequest, "read two-factor status")
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	})
+}
+
+// meTwoFactorEnroll handles POST /me/2fa/enroll, drawing a TOTP secret for the caller.
+func (h *Handler) meTwoFactorEnroll() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		}
+		enrollment, err := h.Auth.EnrollTwoFactor(r.Context(), userID)
+		if err != nil {
+			writeTwoFactorError(w, r, err, http.StatusBadRequest, "enroll two-factor authentication")
+			return
+		}
+		writeJSON(w, http.StatusOK, enrollment)
+	})
+}
+
+// meTwoFactorVerify handles POST /me/2fa/verify, enabling two-factor authentication with a first
+// code of the enrolled secret.
+func (h *Handler) meTwoFactorVerify() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		}
+		var payload twoFactorCodeRequest
+		if !decodeBody(w, r, &payload) {
+			return
+		}
+		codes, err := h.Auth.ActivateTwoFactor(r.Context(), userID, payload.Code)
+		if err != nil {
+			writeTwoFactorError(w, r, err, http.StatusBadRequest, "enable two-factor authentication")
+			return
+		}
+		writeJSON(w, http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+	})
+}
+
+// meTwoFactorDisable handles POST /me/2fa/disable, turning two-factor authentication off.
+func (h *Handler) meTwoFactorDisable() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		}
+		var payload twoFactorCodeRequest
+		if !decodeBody(w, r, &payload) {
+			return
+		}
+		if err := h.Auth.DisableTwoFactor(r.Context(), userID, payload.Code); err != nil {
+			writeTwoFactorError(w, r, err, http.StatusBadRequest, "disable two-factor authentication")
+			return
+		}
+		writeJSON(w, http.StatusOK, statusResponse{Status: "two-factor authentication disabled"})
+	})
+}
+
+// loginTwoFactor handles POST /login/2fa, the second step of a login with two-factor
+// authentication.
+func (h *Handler) loginTwoFactor() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var payload twoFactorLoginRequest
+		if !decodeBody(w, r, &payload) {
+			return
+		}
+		if payload.TwoFactorToken == "" || payload.Code == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "two_factor_token and code are required"})
+			return
+		}
+		user, err := h.Auth.CompleteLogin(r.Context(), payload.TwoFactorToken, payload.Code, h.clientIP(r))
+		if err != nil {
+			writeTwoFactorError(w, r, err, http.StatusUnauthorized, "complete login")
+			return
+		}
+		h.startSession(w, r, user)
+	})
+}
+
+// twoFactorChallenge answers a login whose password was right but that needs a code.
+func twoFactorChallenge(w http.ResponseWriter, token string) {
+	writeJSON(w, http.StatusOK, twoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		TwoFactorToken:    token,
+		ExpiresIn:         int64(auth.TwoFactorLoginTTL / time.Second),
+	})
+}
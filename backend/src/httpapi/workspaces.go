@@ -0,0 +1,217 @@
+package httpapi
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"area/src/auth"
+	"area/src/workflows"
+)
+
+type createWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+type inviteRequest struct {
+	Email string `json:"email"`
+	// Role is owner, editor or viewer.
+	Role string `json:"role"`
+}
+
+type memberRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type acceptInviteRequest struct {
+	Token string `json:"token"`
+}
+
+type shareConnectionRequest struct {
+	// Provider is google or github.
+	Provider string `json:"provider"`
+	// ConnectionID is the ID of the stored OAuth token of the caller.
+	ConnectionID int64 `json:"connection_id"`
+}
+
+// writeWorkspaceError answers the errors of the workspace service methods.
+func writeWorkspaceError(w http.ResponseWriter, r *http.Request, err error, action string) {
+	var invalid *auth.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: invalid.Error()})
+	case errors.Is(err, auth.ErrInvalidToken):
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid, used or expired invite"})
+	case errors.Is(err, auth.ErrWorkspaceForbidden):
+		writeJSON(w, http.StatusForbidden, errorResponse{Error: err.Error()})
+	case errors.Is(err, auth.ErrWorkspaceNotFound), errors.Is(err, auth.ErrMemberNotFound), errors.Is(err, auth.ErrConnectionNotFound):
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: err.Error()})
+	case errors.Is(err, auth.ErrLastOwner):
+		writeJSON(w, http.StatusConflict, errorResponse{Error: err.Error()})
+	default:
+		slog.ErrorContext(r.Context(), action, "error", err)
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "could not " + action})
+	}
+}
+
+// workspaces handles GET and POST /workspaces, listing the caller's workspaces and creating one
+// they own.
+func (h *Handler) workspaces() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			items, err := h.Auth.ListWorkspaces(r.Context(), userID)
+			if err != nil {
+				writeWorkspaceError(w, r, err, "list workspaces")
+				return
+			}
+			writeJSON(w, http.StatusOK, items)
+		case http.MethodPost:
+			var payload createWorkspaceRequest
+			if !decodeBody(w, r, &payload) {
+				return
+			}
+			workspace, err := h.Auth.CreateWorkspace(r.Context(), userID, payload.Name)
+			if err != nil {
+				writeWorkspaceError(w, r, err, "create workspace")
+				return
+			}
+			writeJSON(w, http.StatusCreated, workspace)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// workspaceResource handles:
+// - GET /workspaces/{id}/members to list the members
+// - PUT /workspaces/{id}/members/{user_id} to change the role of a member
+// - DELETE /workspaces/{id}/members/{user_id} to remove a member, or leave
+// - POST /workspaces/{id}/invites to mail an invite
+// - POST /workspaces/{id}/connections to share an OAuth connection
+// - GET /workspaces/{id}/audit to read the audit log
+func (h *Handler) workspaceResource() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		}
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) < 3 || parts[0] != "workspaces" {
+			http.NotFound(w, r)
+			return
+		}
+		workspaceID, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid workspace id"})
+			return
+		}
+		ctx := r.Context()
+
+		switch {
+		case len(parts) == 3 && parts[2] == "members" && r.Method == http.MethodGet:
+			members, err := h.Auth.WorkspaceMembers(ctx, userID, workspaceID)
+			if err != nil {
+				writeWorkspaceError(w, r, err, "list members")
+				return
+			}
+			writeJSON(w, http.StatusOK, members)
+
+		case len(parts) == 4 && parts[2] == "members" && (r.Method == http.MethodPut || r.Method == http.MethodDelete):
+			memberID, err := strconv.ParseInt(parts[3], 10, 64)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid user id"})
+				return
+			}
+			if r.Method == http.MethodDelete {
+				if err := h.Auth.RemoveWorkspaceMember(ctx, userID, workspaceID, memberID); err != nil {
+					writeWorkspaceError(w, r, err, "remove member")
+					return
+				}
+				writeJSON(w, http.StatusOK, statusResponse{Status: "removed"})
+				return
+			}
+			var payload memberRoleRequest
+			if !decodeBody(w, r, &payload) {
+				return
+			}
+			if err := h.Auth.SetWorkspaceRole(ctx, userID, workspaceID, memberID, payload.Role); err != nil {
+				writeWorkspaceError(w, r, err, "change role")
+				return
+			}
+			writeJSON(w, http.StatusOK, statusResponse{Status: "updated"})
+
+		case len(parts) == 3 && parts[2] == "invites" && r.Method == http.MethodPost:
+			var payload inviteRequest
+			if !decodeBody(w, r, &payload) {
+				return
+			}
+			invite, err := h.Auth.InviteToWorkspace(ctx, userID, workspaceID, payload.Email, payload.Role)
+			if err != nil {
+				writeWorkspaceError(w, r, err, "send invite")
+				return
+			}
+			writeJSON(w, http.StatusCreated, invite)
+
+		case len(parts) == 3 && parts[2] == "connections" && r.Method == http.MethodPost:
+			var payload shareConnectionRequest
+			if !decodeBody(w, r, &payload) {
+				return
+			}
+			if err := h.Auth.ShareConnection(ctx, userID, workspaceID, payload.Provider, payload.ConnectionID); err != nil {
+				writeWorkspaceError(w, r, err, "share connection")
+				return
+			}
+			writeJSON(w, http.StatusOK, statusResponse{Status: "shared"})
+
+		case len(parts) == 3 && parts[2] == "audit" && r.Method == http.MethodGet:
+			events, err := h.Auth.WorkspaceAudit(ctx, userID, workspaceID)
+			if err != nil {
+				writeWorkspaceError(w, r, err, "read audit log")
+				return
+			}
+			writeJSON(w, http.StatusOK, events)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// acceptInvite handles POST /invites/accept, adding the caller to the workspace of a mailed
+// invite addressed to their email.
+func (h *Handler) acceptInvite() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+			return
+		}
+		var payload acceptInviteRequest
+		if !decodeBody(w, r, &payload) {
+			return
+		}
+		if payload.Token == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "token is required"})
+			return
+		}
+		workspace, err := h.Auth.AcceptWorkspaceInvite(r.Context(), userID, payload.Token)
+		if err != nil {
+			writeWorkspaceError(w, r, err, "accept invite")
+			return
+		}
+		writeJSON(w, http.StatusOK, workspace)
+	})
+}
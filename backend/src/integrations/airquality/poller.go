@@ -5,51 +5,75 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
+	"area/src/metrics"
 	"area/src/workflows"
 )
 
 const defaultInterval = 10 * time.Minute
 
+// pollerLogger returns the logger of the airquality poller.
+func pollerLogger() *slog.Logger {
+	return slog.With("integration", "airquality")
+}
+
 // StartAirQualityPoller checks air quality workflows and triggers on threshold crossings.
-func StartAirQualityPoller(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service) {
+// The returned channel is closed once the poller has saved its state and stopped.
+func StartAirQualityPoller(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service) <-chan struct{} {
+	done := make(chan struct{})
 	if wfStore == nil || wfService == nil {
-		log.Println("air quality poller: missing dependencies, skipping")
-		return
+		pollerLogger().Warn("missing dependencies, skipping")
+		close(done)
+		return done
 	}
 	go func() {
+		defer close(done)
 		ticker := time.NewTicker(1 * time.Minute)
 		defer ticker.Stop()
 
 		lastAQI := make(map[int64]bool)
 		lastPM25 := make(map[int64]bool)
 		lastCheck := make(map[int64]time.Time)
+
+		state := wfStore.PollerState("airquality", map[string]any{
+			"last_aqi":   &lastAQI,
+			"last_pm25":  &lastPM25,
+			"last_check": &lastCheck,
+		})
 		cityCache := make(map[string][2]float64)
 
 		for {
 			select {
 			case <-ctx.Done():
-				log.Printf("air quality poller stopped: %v", ctx.Err())
+				state.Stop(ctx)
+				pollerLogger().Info("poller stopped", "reason", ctx.Err())
 				return
 			case <-ticker.C:
 			}
+			if !state.Lead(ctx) {
+				continue
+			}
 
+			start := time.Now()
 			pollAQI(ctx, wfStore, wfService, lastAQI, lastCheck, cityCache)
 			pollPM25(ctx, wfStore, wfService, lastPM25, lastCheck, cityCache)
+			metrics.ObservePollerCycle("airquality", start)
 		}
 	}()
+	return done
 }
 
 // pollAQI checks AQI workflows and triggers on threshold crossings.
-func pollAQI(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service, lastState map[int64]bool, lastCheck map[int64]time.Time, cityCache map[string][2]float64) {
+func pollAQI(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service, lastState map[int64]bool, lastCheck map[int64]time.Time, cityCache map[string][2]float64) {
 	wfs, err := wfStore.ListWorkflowsByTrigger(ctx, "air_quality_aqi_threshold")
 	if err != nil {
-		log.Printf("air quality poller: list aqi workflows: %v", err)
+		metrics.PollerErrorsTotal.Inc("airquality")
+		pollerLogger().Error("list aqi workflows", "error", err)
 		return
 	}
 	last := make(map[int64]bool)
@@ -62,7 +86,8 @@ func pollAQI(ctx context.Context, wfStore *workflows.Store, wfService *workflows
 		}
 		cfg, err := workflows.AirQualityAQIConfigFromJSON(wf.TriggerConfig)
 		if err != nil || strings.TrimSpace(cfg.City) == "" {
-			log.Printf("air quality poller wf %d: bad config: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("airquality")
+			pollerLogger().Warn("bad config", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		interval := defaultInterval
@@ -78,7 +103,8 @@ func pollAQI(ctx context.Context, wfStore *workflows.Store, wfService *workflows
 		if !ok {
 			lat, lon, err := geocodeCity(ctx, cfg.City)
 			if err != nil {
-				log.Printf("air quality poller wf %d: geocode %q: %v", wf.ID, cfg.City, err)
+				metrics.PollerErrorsTotal.Inc("airquality")
+				pollerLogger().Error("geocode", "workflow_id", wf.ID, "city", cfg.City, "error", err)
 				continue
 			}
 			coords = [2]float64{lat, lon}
@@ -86,7 +112,8 @@ func pollAQI(ctx context.Context, wfStore *workflows.Store, wfService *workflows
 		}
 		snap, err := fetchAirQuality(ctx, coords[0], coords[1])
 		if err != nil {
-			log.Printf("air quality poller wf %d: fetch: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("airquality")
+			pollerLogger().Error("fetch", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		index := strings.ToLower(strings.TrimSpace(cfg.Index))
@@ -121,7 +148,8 @@ func pollAQI(ctx context.Context, wfStore *workflows.Store, wfService *workflows
 		if !hasPrev {
 			payload["event"] = "current"
 			if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-				log.Printf("air quality poller trigger wf %d: %v", wf.ID, err)
+				metrics.PollerErrorsTotal.Inc("airquality")
+				pollerLogger().Error("trigger failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 			}
 			continue
 		}
@@ -132,16 +160,18 @@ func pollAQI(ctx context.Context, wfStore *workflows.Store, wfService *workflows
 		}
 		payload["event"] = "threshold"
 		if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-			log.Printf("air quality poller trigger wf %d: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("airquality")
+			pollerLogger().Error("trigger failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 		}
 	}
 }
 
 // pollPM25 checks PM2.5 workflows and triggers on threshold crossings.
-func pollPM25(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service, lastState map[int64]bool, lastCheck map[int64]time.Time, cityCache map[string][2]float64) {
+func pollPM25(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service, lastState map[int64]bool, lastCheck map[int64]time.Time, cityCache map[string][2]float64) {
 	wfs, err := wfStore.ListWorkflowsByTrigger(ctx, "air_quality_pm25_threshold")
 	if err != nil {
-		log.Printf("air quality poller: list pm2_5 workflows: %v", err)
+		metrics.PollerErrorsTotal.Inc("airquality")
+		pollerLogger().Error("list pm2_5 workflows", "error", err)
 		return
 	}
 	last := make(map[int64]bool)
@@ -154,7 +184,8 @@ func pollPM25(ctx context.Context, wfStore *workflows.Store, wfService *workflow
 		}
 		cfg, err := workflows.AirQualityPM25ConfigFromJSON(wf.TriggerConfig)
 		if err != nil || strings.TrimSpace(cfg.City) == "" {
-			log.Printf("air quality poller wf %d: bad config: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("airquality")
+			pollerLogger().Warn("bad config", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		interval := defaultInterval
@@ -170,7 +201,8 @@ func pollPM25(ctx context.Context, wfStore *workflows.Store, wfService *workflow
 		if !ok {
 			lat, lon, err := geocodeCity(ctx, cfg.City)
 			if err != nil {
-				log.Printf("air quality poller wf %d: geocode %q: %v", wf.ID, cfg.City, err)
+				metrics.PollerErrorsTotal.Inc("airquality")
+				pollerLogger().Error("geocode", "workflow_id", wf.ID, "city", cfg.City, "error", err)
 				continue
 			}
 			coords = [2]float64{lat, lon}
@@ -178,7 +210,8 @@ func pollPM25(ctx context.Context, wfStore *workflows.Store, wfService *workflow
 		}
 		snap, err := fetchAirQuality(ctx, coords[0], coords[1])
 		if err != nil {
-			log.Printf("air quality poller wf %d: fetch: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("airquality")
+			pollerLogger().Error("fetch", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		pm25 := snap.PM25
@@ -206,7 +239,8 @@ func pollPM25(ctx context.Context, wfStore *workflows.Store, wfService *workflow
 		if !hasPrev {
 			payload["event"] = "current"
 			if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-				log.Printf("air quality poller trigger wf %d: %v", wf.ID, err)
+				metrics.PollerErrorsTotal.Inc("airquality")
+				pollerLogger().Error("trigger failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 			}
 			continue
 		}
@@ -217,7 +251,8 @@ func pollPM25(ctx context.Context, wfStore *workflows.Store, wfService *workflow
 		}
 		payload["event"] = "threshold"
 		if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-			log.Printf("air quality poller trigger wf %d: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("airquality")
+			pollerLogger().Error("trigger failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 		}
 	}
 }
@@ -5,24 +5,34 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
+	"area/src/metrics"
 	"area/src/workflows"
 )
 
 const defaultInterval = 2 * time.Minute
 
+// pollerLogger returns the logger of the crypto poller.
+func pollerLogger() *slog.Logger {
+	return slog.With("integration", "crypto")
+}
+
 // StartCryptoPoller checks crypto workflows and triggers on price or change thresholds.
-func StartCryptoPoller(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service) {
+// The returned channel is closed once the poller has saved its state and stopped.
+func StartCryptoPoller(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service) <-chan struct{} {
+	done := make(chan struct{})
 	if wfStore == nil || wfService == nil {
-		log.Println("crypto poller: missing dependencies, skipping")
-		return
+		pollerLogger().Warn("missing dependencies, skipping")
+		close(done)
+		return done
 	}
 	go func() {
+		defer close(done)
 		ticker := time.NewTicker(1 * time.Minute)
 		defer ticker.Stop()
 
@@ -31,25 +41,40 @@ func StartCryptoPoller(ctx context.Context, wfStore *workflows.Store, wfService
 		lastCheckPrice := make(map[int64]time.Time)
 		lastCheckChange := make(map[int64]time.Time)
 
+		state := wfStore.PollerState("crypto", map[string]any{
+			"last_price_state":  &lastPriceState,
+			"last_change_state": &lastChangeState,
+			"last_check_price":  &lastCheckPrice,
+			"last_check_change": &lastCheckChange,
+		})
+
 		for {
 			select {
 			case <-ctx.Done():
-				log.Printf("crypto poller stopped: %v", ctx.Err())
+				state.Stop(ctx)
+				pollerLogger().Info("poller stopped", "reason", ctx.Err())
 				return
 			case <-ticker.C:
 			}
+			if !state.Lead(ctx) {
+				continue
+			}
 
+			start := time.Now()
 			pollPriceThreshold(ctx, wfStore, wfService, lastPriceState, lastCheckPrice)
 			pollPercentChange(ctx, wfStore, wfService, lastChangeState, lastCheckChange)
+			metrics.ObservePollerCycle("crypto", start)
 		}
 	}()
+	return done
 }
 
 // pollPriceThreshold checks price threshold workflows and triggers on crossings.
-func pollPriceThreshold(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service, lastState map[int64]bool, lastCheck map[int64]time.Time) {
+func pollPriceThreshold(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service, lastState map[int64]bool, lastCheck map[int64]time.Time) {
 	wfs, err := wfStore.ListWorkflowsByTrigger(ctx, "crypto_price_threshold")
 	if err != nil {
-		log.Printf("crypto poller: list price workflows: %v", err)
+		metrics.PollerErrorsTotal.Inc("crypto")
+		pollerLogger().Error("list price workflows", "error", err)
 		return
 	}
 	for _, wf := range wfs {
@@ -59,7 +84,8 @@ func pollPriceThreshold(ctx context.Context, wfStore *workflows.Store, wfService
 
 		cfg, err := workflows.CryptoPriceThresholdConfigFromJSON(wf.TriggerConfig)
 		if err != nil || strings.TrimSpace(cfg.CoinID) == "" {
-			log.Printf("crypto poller wf %d: bad config: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("crypto")
+			pollerLogger().Warn("bad config", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		interval := defaultInterval
@@ -73,7 +99,8 @@ func pollPriceThreshold(ctx context.Context, wfStore *workflows.Store, wfService
 
 		coin, err := fetchMarket(ctx, cfg.CoinID, cfg.Currency)
 		if err != nil {
-			log.Printf("crypto poller wf %d: market: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("crypto")
+			pollerLogger().Error("market", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 
@@ -83,7 +110,8 @@ func pollPriceThreshold(ctx context.Context, wfStore *workflows.Store, wfService
 		if !hasPrev {
 			payload := buildPricePayload(cfg, coin, "current")
 			if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-				log.Printf("crypto poller trigger wf %d: %v", wf.ID, err)
+				metrics.PollerErrorsTotal.Inc("crypto")
+				pollerLogger().Error("trigger failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 			}
 			continue
 		}
@@ -95,16 +123,18 @@ func pollPriceThreshold(ctx context.Context, wfStore *workflows.Store, wfService
 		}
 		payload := buildPricePayload(cfg, coin, "threshold")
 		if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-			log.Printf("crypto poller trigger wf %d: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("crypto")
+			pollerLogger().Error("trigger failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 		}
 	}
 }
 
 // pollPercentChange checks percent change workflows and triggers on threshold crossings.
-func pollPercentChange(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service, lastState map[int64]bool, lastCheck map[int64]time.Time) {
+func pollPercentChange(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service, lastState map[int64]bool, lastCheck map[int64]time.Time) {
 	wfs, err := wfStore.ListWorkflowsByTrigger(ctx, "crypto_percent_change")
 	if err != nil {
-		log.Printf("crypto poller: list change workflows: %v", err)
+		metrics.PollerErrorsTotal.Inc("crypto")
+		pollerLogger().Error("list change workflows", "error", err)
 		return
 	}
 	for _, wf := range wfs {
@@ -114,7 +144,8 @@ func pollPercentChange(ctx context.Context, wfStore *workflows.Store, wfService
 
 		cfg, err := workflows.CryptoPercentChangeConfigFromJSON(wf.TriggerConfig)
 		if err != nil || strings.TrimSpace(cfg.CoinID) == "" {
-			log.Printf("crypto poller wf %d: bad config: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("crypto")
+			pollerLogger().Warn("bad config", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		interval := defaultInterval
@@ -128,7 +159,8 @@ func pollPercentChange(ctx context.Context, wfStore *workflows.Store, wfService
 
 		coin, err := fetchMarket(ctx, cfg.CoinID, cfg.Currency)
 		if err != nil {
-			log.Printf("crypto poller wf %d: market: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("crypto")
+			pollerLogger().Error("market", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		change := coin.Change1H
@@ -142,7 +174,8 @@ func pollPercentChange(ctx context.Context, wfStore *workflows.Store, wfService
 			if state {
 				payload := buildChangePayload(cfg, coin, change, "current")
 				if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-					log.Printf("crypto poller trigger wf %d: %v", wf.ID, err)
+					metrics.PollerErrorsTotal.Inc("crypto")
+					pollerLogger().Error("trigger failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 				}
 			}
 			continue
@@ -152,7 +185,8 @@ func pollPercentChange(ctx context.Context, wfStore *workflows.Store, wfService
 		}
 		payload := buildChangePayload(cfg, coin, change, "threshold")
 		if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-			log.Printf("crypto poller trigger wf %d: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("crypto")
+			pollerLogger().Error("trigger failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 		}
 	}
 }
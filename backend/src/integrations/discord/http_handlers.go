@@ -16,7 +16,7 @@ type HTTPHandlers struct {
 // NewHTTPHandlers builds Discord HTTP handlers with a default client.
 func NewHTTPHandlers(client *Client) *HTTPHandlers {
 	if client == nil {
-		client = NewClient()
+		client = NewClientWithToken("")
 	}
 	return &HTTPHandlers{client: client}
 }
@@ -8,9 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
-	"time"
 )
 
 const apiBase = "https://discord.com/api/v10"
@@ -20,16 +18,11 @@ type Client struct {
 	client *http.Client
 }
 
-// NewClient builds a Discord API client.
-func NewClient() *Client {
-	return NewClientWithToken(os.Getenv("DISCORD_BOT_TOKEN"))
-}
-
 // NewClientWithToken builds a Discord API client with an explicit token.
 func NewClientWithToken(token string) *Client {
 	return &Client{
 		token:  strings.TrimSpace(token),
-		client: &http.Client{Timeout: 10 * time.Second},
+		client: &http.Client{},
 	}
 }
 
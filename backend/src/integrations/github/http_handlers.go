@@ -1,33 +1,66 @@
 package github
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
-	"time"
+
+	"area/src/config"
+	"area/src/workflows"
 )
 
-// HTTPHandlers exposes minimal GitHub OAuth endpoints (no third-party libs).
+// StateStore binds the state of an OAuth flow to the user who started it. GitHub redirects to
+// the callback without credentials, so the callback learns the user from the state.
+type StateStore interface {
+	StartConnection(ctx context.Context, userID int64) (string, error)
+	FinishConnection(ctx context.Context, state string) (int64, error)
+}
+
+// HTTPHandlers exposes minimal GitHub OAuth endpoints (no third-party libs). Every handler but
+// the callbacks serves an authenticated user, read with workflows.UserIDFromContext.
 type HTTPHandlers struct {
 	client *Client
+	// States binds the OAuth flows started by Login and LoginMobile to their user.
+	States StateStore
+	// Connected, when set, is told of every connection the handlers store for the verified
+	// caller, for the audit log.
+	Connected func(ctx context.Context, userID, tokenID int64)
 }
 
 // NewHTTPHandlers builds GitHub HTTP handlers with a default client.
 func NewHTTPHandlers(client *Client) *HTTPHandlers {
 	if client == nil {
-		client = NewClient()
+		client = NewClient(config.OAuth{})
 	}
 	return &HTTPHandlers{client: client}
 }
 
-// Login redirects to GitHub OAuth consent.
+// Login redirects the user to GitHub OAuth consent.
 func (h *HTTPHandlers) Login() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		state := randomState()
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
+		redirectURI := r.URL.Query().Get("redirect_uri")
+		if redirectURI == "" {
+			redirectURI = h.client.redirectURI
+		}
+		if redirectURI == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "redirect_uri is required"})
+			return
+		}
+		state, err := h.startState(r.Context(), userID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "could not start oauth"})
+			return
+		}
 		http.SetCookie(w, &http.Cookie{
 			Name:     "oauthstate",
 			Value:    state,
@@ -42,31 +75,32 @@ func (h *HTTPHandlers) Login() http.Handler {
 				HttpOnly: true,
 			})
 		}
-		if userID := r.URL.Query().Get("user_id"); userID != "" {
-			http.SetCookie(w, &http.Cookie{
-				Name:     "oauthuserid",
-				Value:    userID,
-				Path:     "/",
-				HttpOnly: true,
-			})
+		authURL := h.client.AuthURL(state, redirectURI)
+		http.Redirect(w, r, authURL, http.StatusFound)
+	})
+}
+
+// LoginMobile redirects the user to GitHub OAuth consent using the mobile OAuth app.
+func (h *HTTPHandlers) LoginMobile() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
 		}
 		redirectURI := r.URL.Query().Get("redirect_uri")
 		if redirectURI == "" {
-			redirectURI = os.Getenv("GITHUB_OAUTH_REDIRECT_URI")
+			redirectURI = h.client.redirectURI
 		}
 		if redirectURI == "" {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "redirect_uri is required"})
 			return
 		}
-		authURL := h.client.AuthURL(state, redirectURI)
-		http.Redirect(w, r, authURL, http.StatusFound)
-	})
-}
-
-// LoginMobile redirects to GitHub OAuth consent using the mobile OAuth app.
-func (h *HTTPHandlers) LoginMobile() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		state := randomState()
+		state, err := h.startState(r.Context(), userID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "could not start oauth"})
+			return
+		}
 		http.SetCookie(w, &http.Cookie{
 			Name:     "oauthstate",
 			Value:    state,
@@ -81,28 +115,13 @@ func (h *HTTPHandlers) LoginMobile() http.Handler {
 				HttpOnly: true,
 			})
 		}
-		if userID := r.URL.Query().Get("user_id"); userID != "" {
-			http.SetCookie(w, &http.Cookie{
-				Name:     "oauthuserid",
-				Value:    userID,
-				Path:     "/",
-				HttpOnly: true,
-			})
-		}
-		redirectURI := r.URL.Query().Get("redirect_uri")
-		if redirectURI == "" {
-			redirectURI = os.Getenv("GITHUB_MOBILE_OAUTH_REDIRECT_URI")
-		}
-		if redirectURI == "" {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "redirect_uri is required"})
-			return
-		}
 		authURL := h.client.AuthURL(state, redirectURI)
 		http.Redirect(w, r, authURL, http.StatusFound)
 	})
 }
 
-// Callback exchanges code for token, stores it, and returns token_id/email/user_id/login.
+// Callback exchanges code for token, stores it for the user who started the flow, and returns
+// token_id/email/user_id/login.
 func (h *HTTPHandlers) Callback() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		stateCookie, _ := r.Cookie("oauthstate")
@@ -110,14 +129,6 @@ func (h *HTTPHandlers) Callback() http.Handler {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid oauth state"})
 			return
 		}
-		userID := optionalUserID(r)
-		if userID == nil {
-			if userCookie, _ := r.Cookie("oauthuserid"); userCookie != nil {
-				if id, err := strconv.ParseInt(userCookie.Value, 10, 64); err == nil && id > 0 {
-					userID = &id
-				}
-			}
-		}
 		code := r.URL.Query().Get("code")
 		if code == "" {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "missing code"})
@@ -125,18 +136,24 @@ func (h *HTTPHandlers) Callback() http.Handler {
 		}
 		redirectURI := r.URL.Query().Get("redirect_uri")
 		if redirectURI == "" {
-			redirectURI = os.Getenv("GITHUB_OAUTH_REDIRECT_URI")
+			redirectURI = h.client.redirectURI
 		}
 		if redirectURI == "" {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "redirect_uri is required"})
 			return
 		}
 
-		tokenID, resolvedUserID, email, login, err := h.client.ExchangeAndStore(r.Context(), code, redirectURI, userID)
+		userID, err := h.finishState(r.Context(), stateCookie.Value)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid oauth state"})
+			return
+		}
+		tokenID, resolvedUserID, email, login, err := h.client.ExchangeAndStore(r.Context(), code, redirectURI, &userID)
 		if err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 			return
 		}
+		h.connected(r.Context(), userID, resolvedUserID, tokenID)
 		if uiCookie, _ := r.Cookie("oauthredirect"); uiCookie != nil && uiCookie.Value != "" {
 			if dest, err := url.QueryUnescape(uiCookie.Value); err == nil {
 				if redir, err := url.Parse(dest); err == nil && (redir.Scheme == "http" || redir.Scheme == "https") {
@@ -179,14 +196,6 @@ func (h *HTTPHandlers) CallbackMobile() http.Handler {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid oauth state"})
 			return
 		}
-		userID := optionalUserID(r)
-		if userID == nil {
-			if userCookie, _ := r.Cookie("oauthuserid"); userCookie != nil {
-				if id, err := strconv.ParseInt(userCookie.Value, 10, 64); err == nil && id > 0 {
-					userID = &id
-				}
-			}
-		}
 		code := r.URL.Query().Get("code")
 		if code == "" {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "missing code"})
@@ -194,18 +203,24 @@ func (h *HTTPHandlers) CallbackMobile() http.Handler {
 		}
 		redirectURI := r.URL.Query().Get("redirect_uri")
 		if redirectURI == "" {
-			redirectURI = os.Getenv("GITHUB_MOBILE_OAUTH_REDIRECT_URI")
+			redirectURI = h.client.redirectURI
 		}
 		if redirectURI == "" {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "redirect_uri is required"})
 			return
 		}
 
-		tokenID, resolvedUserID, email, login, err := h.client.ExchangeAndStore(r.Context(), code, redirectURI, userID)
+		userID, err := h.finishState(r.Context(), stateCookie.Value)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid oauth state"})
+			return
+		}
+		tokenID, resolvedUserID, email, login, err := h.client.ExchangeAndStore(r.Context(), code, redirectURI, &userID)
 		if err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 			return
 		}
+		h.connected(r.Context(), userID, resolvedUserID, tokenID)
 		if uiCookie, _ := r.Cookie("oauthredirect"); uiCookie != nil && uiCookie.Value != "" {
 			if dest, err := url.QueryUnescape(uiCookie.Value); err == nil {
 				if redir, err := url.Parse(dest); err == nil && (redir.Scheme == "http" || redir.Scheme == "https") {
@@ -254,7 +269,11 @@ func (h *HTTPHandlers) Issue() http.Handler {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		userID := optionalUserID(r)
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
 		var p payload
 		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
 		if err := decoder.Decode(&p); err != nil {
@@ -275,7 +294,7 @@ func (h *HTTPHandlers) Issue() http.Handler {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "labels must be an array or comma-separated string"})
 			return
 		}
-		if err := h.client.CreateIssue(r.Context(), userID, p.TokenID, parts[0], parts[1], p.Title, p.Body, labels); err != nil {
+		if err := h.client.CreateIssue(r.Context(), &userID, p.TokenID, parts[0], parts[1], p.Title, p.Body, labels); err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 			return
 		}
@@ -298,7 +317,11 @@ func (h *HTTPHandlers) PullRequest() http.Handler {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		userID := optionalUserID(r)
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
 		var p payload
 		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
 		if err := decoder.Decode(&p); err != nil {
@@ -314,7 +337,7 @@ func (h *HTTPHandlers) PullRequest() http.Handler {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "repo must be owner/name"})
 			return
 		}
-		if err := h.client.CreatePullRequest(r.Context(), userID, p.TokenID, parts[0], parts[1], p.Title, p.Head, p.Base, p.Body); err != nil {
+		if err := h.client.CreatePullRequest(r.Context(), &userID, p.TokenID, parts[0], parts[1], p.Title, p.Head, p.Base, p.Body); err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 			return
 		}
@@ -329,25 +352,20 @@ func writeJSON(w http.ResponseWriter, status int, value any) {
 	_ = json.NewEncoder(w).Encode(value)
 }
 
-// optionalUserID extracts an optional user ID from the request headers or query parameters.
-func optionalUserID(r *http.Request) *int64 {
-	user := r.Header.Get("X-User-ID")
-	if user == "" {
-		user = r.URL.Query().Get("user_id")
-	}
-	if user == "" {
-		return nil
-	}
-	id, err := strconv.ParseInt(user, 10, 64)
-	if err != nil || id <= 0 {
-		return nil
+// startState issues the state of an OAuth flow userID starts.
+func (h *HTTPHandlers) startState(ctx context.Context, userID int64) (string, error) {
+	if h.States == nil {
+		return "", errors.New("oauth states are not configured")
 	}
-	return &id
+	return h.States.StartConnection(ctx, userID)
 }
 
-// randomState generates a random string for OAuth state parameter.
-func randomState() string {
-	return fmt.Sprintf("%d", time.Now().UnixNano())
+// finishState returns the user who started the OAuth flow of state, once.
+func (h *HTTPHandlers) finishState(ctx context.Context, state string) (int64, error) {
+	if h.States == nil {
+		return 0, errors.New("oauth states are not configured")
+	}
+	return h.States.FinishConnection(ctx, state)
 }
 
 // parseStringList accepts a JSON array of strings or a single comma-separated string.
@@ -373,3 +391,12 @@ func parseStringList(raw json.RawMessage) ([]string, error) {
 	}
 	return nil, fmt.Errorf("invalid string list")
 }
+
+// connected reports a connection stored for userID, the verified caller, to Connected. A token
+// the exchange stored for another user, found by the email of the account, is not theirs and is
+// not reported.
+func (h *HTTPHandlers) connected(ctx context.Context, userID, storedFor, tokenID int64) {
+	if h.Connected != nil && userID > 0 && storedFor == userID {
+		h.Connected(ctx, userID, tokenID)
+	}
+}
@@ -4,20 +4,30 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 
+	"area/src/metrics"
 	"area/src/workflows"
 )
 
+// pollerLogger returns the logger of the github poller.
+func pollerLogger() *slog.Logger {
+	return slog.With("integration", "github")
+}
+
 // StartGithubPoller launches goroutines that watch GitHub workflows (commit/PR/issue) and trigger on changes.
-func StartGithubPoller(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service, client *Client) {
+// The returned channel is closed once the poller has saved its state and stopped.
+func StartGithubPoller(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service, client *Client) <-chan struct{} {
+	done := make(chan struct{})
 	if wfStore == nil || wfService == nil || client == nil {
-		log.Println("github poller: missing dependencies, skipping")
-		return
+		pollerLogger().Warn("missing dependencies, skipping")
+		close(done)
+		return done
 	}
 	go func() {
+		defer close(done)
 		ticker := time.NewTicker(45 * time.Second)
 		defer ticker.Stop()
 
@@ -25,26 +35,40 @@ func StartGithubPoller(ctx context.Context, wfStore *workflows.Store, wfService
 		lastPR := make(map[int64]string)
 		lastIssue := make(map[int64]string)
 
+		state := wfStore.PollerState("github", map[string]any{
+			"last_commit": &lastCommit,
+			"last_pr":     &lastPR,
+			"last_issue":  &lastIssue,
+		})
+
 		for {
 			select {
 			case <-ctx.Done():
-				log.Printf("github poller stopped: %v", ctx.Err())
+				state.Stop(ctx)
+				pollerLogger().Info("poller stopped", "reason", ctx.Err())
 				return
 			case <-ticker.C:
 			}
+			if !state.Lead(ctx) {
+				continue
+			}
 
+			start := time.Now()
 			pollCommits(ctx, wfStore, wfService, client, lastCommit)
 			pollPullRequests(ctx, wfStore, wfService, client, lastPR)
 			pollIssues(ctx, wfStore, wfService, client, lastIssue)
+			metrics.ObservePollerCycle("github", start)
 		}
 	}()
+	return done
 }
 
 // pollCommits checks for new commits and triggers workflows accordingly.
-func pollCommits(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service, client *Client, lastSeen map[int64]string) {
+func pollCommits(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service, client *Client, lastSeen map[int64]string) {
 	wfs, err := wfStore.ListWorkflowsByTrigger(ctx, "github_commit")
 	if err != nil {
-		log.Printf("github poller: list commit workflows: %v", err)
+		metrics.PollerErrorsTotal.Inc("github")
+		pollerLogger().Error("list commit workflows", "error", err)
 		return
 	}
 
@@ -55,18 +79,20 @@ func pollCommits(ctx context.Context, wfStore *workflows.Store, wfService *workf
 
 		var cfg workflows.GithubCommitConfig
 		if err := json.Unmarshal(wf.TriggerConfig, &cfg); err != nil {
-			log.Printf("github poller wf %d: bad config: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("github")
+			pollerLogger().Warn("bad config", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		repoParts := strings.Split(cfg.Repo, "/")
 		if len(repoParts) != 2 {
-			log.Printf("github poller wf %d: invalid repo %q", wf.ID, cfg.Repo)
+			pollerLogger().Warn("invalid repo", "workflow_id", wf.ID, "repo", cfg.Repo)
 			continue
 		}
 
 		commits, err := client.ListRecentCommits(ctx, &wf.UserID, cfg.TokenID, repoParts[0], repoParts[1], cfg.Branch, 5)
 		if err != nil {
-			log.Printf("github poller wf %d: list commits: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("github")
+			pollerLogger().Error("list commits", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		if len(commits) == 0 {
@@ -104,7 +130,8 @@ func pollCommits(ctx context.Context, wfStore *workflows.Store, wfService *workf
 				payload["content"] = fmt.Sprintf("New commit on %s (%s): %s", cfg.Repo, cfg.Branch, cmt.Message)
 			}
 			if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-				log.Printf("github poller trigger wf %d: %v", wf.ID, err)
+				metrics.PollerErrorsTotal.Inc("github")
+				pollerLogger().Error("trigger failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 				continue
 			}
 		}
@@ -113,10 +140,11 @@ func pollCommits(ctx context.Context, wfStore *workflows.Store, wfService *workf
 }
 
 // pollPullRequests checks for new or updated pull requests and triggers workflows accordingly.
-func pollPullRequests(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service, client *Client, lastSeen map[int64]string) {
+func pollPullRequests(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service, client *Client, lastSeen map[int64]string) {
 	wfs, err := wfStore.ListWorkflowsByTrigger(ctx, "github_pull_request")
 	if err != nil {
-		log.Printf("github poller: list PR workflows: %v", err)
+		metrics.PollerErrorsTotal.Inc("github")
+		pollerLogger().Error("list PR workflows", "error", err)
 		return
 	}
 	for _, wf := range wfs {
@@ -125,17 +153,19 @@ func pollPullRequests(ctx context.Context, wfStore *workflows.Store, wfService *
 		}
 		var cfg workflows.GithubPullRequestConfig
 		if err := json.Unmarshal(wf.TriggerConfig, &cfg); err != nil {
-			log.Printf("github poller wf %d: bad PR config: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("github")
+			pollerLogger().Warn("bad PR config", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		repoParts := strings.Split(cfg.Repo, "/")
 		if len(repoParts) != 2 {
-			log.Printf("github poller wf %d: invalid repo %q", wf.ID, cfg.Repo)
+			pollerLogger().Warn("invalid repo", "workflow_id", wf.ID, "repo", cfg.Repo)
 			continue
 		}
 		prs, err := client.ListRecentPullRequests(ctx, &wf.UserID, cfg.TokenID, repoParts[0], repoParts[1], 5)
 		if err != nil {
-			log.Printf("github poller wf %d: list PRs: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("github")
+			pollerLogger().Error("list PRs", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		if len(prs) == 0 {
@@ -191,7 +221,8 @@ func pollPullRequests(ctx context.Context, wfStore *workflows.Store, wfService *
 				payload["content"] = fmt.Sprintf("PR #%d %s on %s: %s", pr.Number, action, cfg.Repo, pr.Title)
 			}
 			if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-				log.Printf("github poller trigger PR wf %d: %v", wf.ID, err)
+				metrics.PollerErrorsTotal.Inc("github")
+				pollerLogger().Error("trigger PR failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 				continue
 			}
 		}
@@ -200,10 +231,11 @@ func pollPullRequests(ctx context.Context, wfStore *workflows.Store, wfService *
 }
 
 // pollPullRequests checks for new or updated pull requests and triggers workflows accordingly.
-func pollIssues(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service, client *Client, lastSeen map[int64]string) {
+func pollIssues(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service, client *Client, lastSeen map[int64]string) {
 	wfs, err := wfStore.ListWorkflowsByTrigger(ctx, "github_issue")
 	if err != nil {
-		log.Printf("github poller: list issue workflows: %v", err)
+		metrics.PollerErrorsTotal.Inc("github")
+		pollerLogger().Error("list issue workflows", "error", err)
 		return
 	}
 	for _, wf := range wfs {
@@ -212,17 +244,19 @@ func pollIssues(ctx context.Context, wfStore *workflows.Store, wfService *workfl
 		}
 		var cfg workflows.GithubIssueConfig
 		if err := json.Unmarshal(wf.TriggerConfig, &cfg); err != nil {
-			log.Printf("github poller wf %d: bad issue config: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("github")
+			pollerLogger().Warn("bad issue config", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		repoParts := strings.Split(cfg.Repo, "/")
 		if len(repoParts) != 2 {
-			log.Printf("github poller wf %d: invalid repo %q", wf.ID, cfg.Repo)
+			pollerLogger().Warn("invalid repo", "workflow_id", wf.ID, "repo", cfg.Repo)
 			continue
 		}
 		issues, err := client.ListRecentIssues(ctx, &wf.UserID, cfg.TokenID, repoParts[0], repoParts[1], 5)
 		if err != nil {
-			log.Printf("github poller wf %d: list issues: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("github")
+			pollerLogger().Error("list issues", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		if len(issues) == 0 {
@@ -274,7 +308,8 @@ func pollIssues(ctx context.Context, wfStore *workflows.Store, wfService *workfl
 				payload["content"] = fmt.Sprintf("Issue #%d %s on %s: %s", iss.Number, action, cfg.Repo, iss.Title)
 			}
 			if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-				log.Printf("github poller trigger issue wf %d: %v", wf.ID, err)
+				metrics.PollerErrorsTotal.Inc("github")
+				pollerLogger().Error("trigger issue failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 				continue
 			}
 		}
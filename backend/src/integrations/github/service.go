@@ -9,39 +9,36 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
 	"time"
 
+	"area/src/config"
 	"area/src/database"
 
 	"gorm.io/gorm"
 )
 
+// ErrUserRequired is returned for API calls made without a user: tokens are only ever used on
+// behalf of a user allowed to use them.
+var ErrUserRequired = errors.New("a user is required to use a GitHub token")
+
 // Client handles GitHub OAuth without third-party helpers.
 type Client struct {
 	clientID     string
 	clientSecret string
+	redirectURI  string
 	scopes       []string
 	httpClient   *http.Client
 }
 
-// NewClient builds a GitHub API client using environment credentials.
-func NewClient() *Client {
+// NewClient builds a GitHub API client for an OAuth application (web or mobile).
+func NewClient(app config.OAuth) *Client {
 	return &Client{
-		clientID:     mustEnv("GITHUB_OAUTH_CLIENT_ID"),
-		clientSecret: mustEnv("GITHUB_OAUTH_CLIENT_SECRET"),
+		clientID:     app.ClientID,
+		clientSecret: app.ClientSecret,
+		redirectURI:  app.RedirectURI,
 		scopes:       []string{"read:user", "user:email", "repo"},
-		httpClient:   &http.Client{Timeout: 10 * time.Second},
-	}
-}
-
-func NewMobileClient() *Client {
-	return &Client{
-		clientID:     mustEnv("GITHUB_MOBILE_OAUTH_CLIENT_ID"),
-		clientSecret: mustEnv("GITHUB_MOBILE_OAUTH_CLIENT_SECRET"),
-		scopes:       []string{"read:user", "user:email", "repo"},
-		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		httpClient:   &http.Client{},
 	}
 }
 
@@ -359,18 +356,15 @@ func (c *Client) ListRecentCommits(ctx context.Context, userID *int64, tokenID i
 	return commits, nil
 }
 
-// ensureToken retrieves and verifies a GitHub token.
+// ensureToken retrieves a GitHub token userID may use; a request without a user fails.
 func (c *Client) ensureToken(ctx context.Context, userID *int64, tokenID int64) (*database.GithubToken, error) {
 	if tokenID == 0 {
 		return nil, fmt.Errorf("missing github token id")
 	}
-	var t *database.GithubToken
-	var err error
-	if userID != nil && *userID > 0 {
-		t, err = database.GetGithubTokenForUser(tokenID, *userID)
-	} else {
-		t, err = database.GetGithubToken(tokenID)
+	if userID == nil || *userID <= 0 {
+		return nil, ErrUserRequired
 	}
+	t, err := database.GetGithubTokenForUser(tokenID, *userID)
 	if err != nil {
 		return nil, err
 	}
@@ -496,12 +490,3 @@ func (c *Client) ListRecentIssues(ctx context.Context, userID *int64, tokenID in
 	}
 	return result, nil
 }
-
-// mustEnv fetches a required environment variable or panics.
-func mustEnv(key string) string {
-	val := os.Getenv(key)
-	if val == "" {
-		panic(fmt.Sprintf("missing env %s", key))
-	}
-	return val
-}
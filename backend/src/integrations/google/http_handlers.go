@@ -1,35 +1,68 @@
 package google
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
 	"time"
+
+	"area/src/config"
+	"area/src/workflows"
 )
 
-// HTTPHandlers groups the HTTP handlers related to Google OAuth and actions.
+// StateStore binds the state of an OAuth flow to the user who started it. Google redirects to
+// the callback without credentials, so the callback learns the user from the state.
+type StateStore interface {
+	StartConnection(ctx context.Context, userID int64) (string, error)
+	FinishConnection(ctx context.Context, state string) (int64, error)
+}
+
+// HTTPHandlers groups the HTTP handlers related to Google OAuth and actions. Every handler but
+// Callback serves an authenticated user, read with workflows.UserIDFromContext.
 type HTTPHandlers struct {
 	client *Client
+	// States binds the OAuth flows started by Login and Start to their user.
+	States StateStore
+	// Connected, when set, is told of every connection the handlers store for the verified
+	// caller, for the audit log.
+	Connected func(ctx context.Context, userID, tokenID int64)
 }
 
 // NewHTTPHandlers builds a helper with the given client (or a default one if nil).
 func NewHTTPHandlers(client *Client) *HTTPHandlers {
 	if client == nil {
-		client = NewClient()
+		client = NewClient(config.OAuth{})
 	}
 	return &HTTPHandlers{client: client}
 }
 
-// Login redirects to Google OAuth consent.
+// Login redirects the user to Google OAuth consent.
 func (h *HTTPHandlers) Login() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		state := randomState()
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
+		redirectURI := r.URL.Query().Get("redirect_uri")
+		if redirectURI == "" {
+			redirectURI = h.client.redirectURI
+		}
+		if redirectURI == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "redirect_uri is required"})
+			return
+		}
+		state, err := h.startState(r.Context(), userID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "could not start oauth"})
+			return
+		}
 		http.SetCookie(w, &http.Cookie{
 			Name:     "oauthstate",
 			Value:    state,
@@ -44,22 +77,6 @@ func (h *HTTPHandlers) Login() http.Handler {
 				HttpOnly: true,
 			})
 		}
-		if userID := r.URL.Query().Get("user_id"); userID != "" {
-			http.SetCookie(w, &http.Cookie{
-				Name:     "oauthuserid",
-				Value:    userID,
-				Path:     "/",
-				HttpOnly: true,
-			})
-		}
-		redirectURI := r.URL.Query().Get("redirect_uri")
-		if redirectURI == "" {
-			redirectURI = os.Getenv("GOOGLE_OAUTH_REDIRECT_URI")
-		}
-		if redirectURI == "" {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "redirect_uri is required"})
-			return
-		}
 		authURL := h.client.AuthURL(state, redirectURI)
 		http.Redirect(w, r, authURL, http.StatusFound)
 	})
@@ -72,21 +89,31 @@ func (h *HTTPHandlers) Start() http.Handler {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		state := randomState()
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
 		redirectURI := r.URL.Query().Get("redirect_uri")
 		if redirectURI == "" {
-			redirectURI = os.Getenv("GOOGLE_OAUTH_REDIRECT_URI")
+			redirectURI = h.client.redirectURI
 		}
 		if redirectURI == "" {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "redirect_uri is required"})
 			return
 		}
+		state, err := h.startState(r.Context(), userID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "could not start oauth"})
+			return
+		}
 		authURL := h.client.AuthURL(state, redirectURI)
 		writeJSON(w, http.StatusOK, map[string]string{"auth_url": authURL, "state": state})
 	})
 }
 
-// Callback exchanges code for token, stores it, and returns token_id/email.
+// Callback exchanges code for token, stores it for the user who started the flow, and returns
+// token_id/email.
 func (h *HTTPHandlers) Callback() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		stateCookie, _ := r.Cookie("oauthstate")
@@ -94,14 +121,6 @@ func (h *HTTPHandlers) Callback() http.Handler {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid oauth state"})
 			return
 		}
-		userID := optionalUserID(r)
-		if userID == nil {
-			if userCookie, _ := r.Cookie("oauthuserid"); userCookie != nil {
-				if id, err := strconv.ParseInt(userCookie.Value, 10, 64); err == nil && id > 0 {
-					userID = &id
-				}
-			}
-		}
 		code := r.URL.Query().Get("code")
 		if code == "" {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "missing code"})
@@ -109,17 +128,23 @@ func (h *HTTPHandlers) Callback() http.Handler {
 		}
 		redirectURI := r.URL.Query().Get("redirect_uri")
 		if redirectURI == "" {
-			redirectURI = os.Getenv("GOOGLE_OAUTH_REDIRECT_URI")
+			redirectURI = h.client.redirectURI
 		}
 		if redirectURI == "" {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "redirect_uri is required"})
 			return
 		}
-		tokenID, resolvedUserID, email, err := h.client.ExchangeAndStore(r.Context(), code, redirectURI, userID)
+		userID, err := h.finishState(r.Context(), stateCookie.Value)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid oauth state"})
+			return
+		}
+		tokenID, resolvedUserID, email, err := h.client.ExchangeAndStore(r.Context(), code, redirectURI, &userID)
 		if err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 			return
 		}
+		h.connected(r.Context(), userID, resolvedUserID, tokenID)
 		if uiCookie, _ := r.Cookie("oauthredirect"); uiCookie != nil && uiCookie.Value != "" {
 			if dest, err := url.QueryUnescape(uiCookie.Value); err == nil {
 				if redir, err := url.Parse(dest); err == nil && (redir.Scheme == "http" || redir.Scheme == "https") {
@@ -148,7 +173,8 @@ func (h *HTTPHandlers) Callback() http.Handler {
 	})
 }
 
-// Exchange handles JSON exchanges from mobile clients.
+// Exchange handles JSON exchanges from mobile clients, storing the token for the caller. A state
+// from Start must have been issued to the caller.
 func (h *HTTPHandlers) Exchange() http.Handler {
 	type payload struct {
 		Code        string `json:"code"`
@@ -160,6 +186,11 @@ func (h *HTTPHandlers) Exchange() http.Handler {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
 		var p payload
 		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
 		if err := decoder.Decode(&p); err != nil {
@@ -180,18 +211,24 @@ func (h *HTTPHandlers) Exchange() http.Handler {
 		}
 		redirectURI := p.RedirectURI
 		if redirectURI == "" {
-			redirectURI = os.Getenv("GOOGLE_OAUTH_REDIRECT_URI")
+			redirectURI = h.client.redirectURI
 		}
 		if redirectURI == "" {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "redirect_uri is required"})
 			return
 		}
-		userID := optionalUserID(r)
-		tokenID, resolvedUserID, email, err := h.client.ExchangeAndStore(r.Context(), p.Code, redirectURI, userID)
+		if p.State != "" {
+			if owner, err := h.finishState(r.Context(), p.State); err != nil || owner != userID {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid oauth state"})
+				return
+			}
+		}
+		tokenID, resolvedUserID, email, err := h.client.ExchangeAndStore(r.Context(), p.Code, redirectURI, &userID)
 		if err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 			return
 		}
+		h.connected(r.Context(), userID, resolvedUserID, tokenID)
 		resp := map[string]any{
 			"token":    tokenID,
 			"token_id": tokenID,
@@ -219,7 +256,11 @@ func (h *HTTPHandlers) SendEmail() http.Handler {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		userID := optionalUserID(r)
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
 		var p payload
 		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
 		if err := decoder.Decode(&p); err != nil {
@@ -234,7 +275,7 @@ func (h *HTTPHandlers) SendEmail() http.Handler {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "to and subject are required"})
 			return
 		}
-		if err := h.client.SendEmail(r.Context(), userID, p.TokenID, p.To, p.Subject, p.Body); err != nil {
+		if err := h.client.SendEmail(r.Context(), &userID, p.TokenID, p.To, p.Subject, p.Body); err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 			return
 		}
@@ -256,7 +297,11 @@ func (h *HTTPHandlers) CreateEvent() http.Handler {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		userID := optionalUserID(r)
+		userID, err := workflows.UserIDFromContext(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
 		var p payload
 		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
 		if err := decoder.Decode(&p); err != nil {
@@ -286,7 +331,7 @@ func (h *HTTPHandlers) CreateEvent() http.Handler {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 			return
 		}
-		if err := h.client.CreateCalendarEvent(r.Context(), userID, p.TokenID, p.Summary, start, end, attendees); err != nil {
+		if err := h.client.CreateCalendarEvent(r.Context(), &userID, p.TokenID, p.Summary, start, end, attendees); err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 			return
 		}
@@ -339,23 +384,27 @@ func ensureNoTrailingData(decoder *json.Decoder) error {
 	return nil
 }
 
-// randomState generates a random state string for OAuth.
-func randomState() string {
-	return fmt.Sprintf("%d", time.Now().UnixNano())
+// startState issues the state of an OAuth flow userID starts.
+func (h *HTTPHandlers) startState(ctx context.Context, userID int64) (string, error) {
+	if h.States == nil {
+		return "", errors.New("oauth states are not configured")
+	}
+	return h.States.StartConnection(ctx, userID)
 }
 
-// optionalUserID extracts an optional user ID from headers or query parameters.
-func optionalUserID(r *http.Request) *int64 {
-	user := r.Header.Get("X-User-ID")
-	if user == "" {
-		user = r.URL.Query().Get("user_id")
+// finishState returns the user who started the OAuth flow of state, once.
+func (h *HTTPHandlers) finishState(ctx context.Context, state string) (int64, error) {
+	if h.States == nil {
+		return 0, errors.New("oauth states are not configured")
 	}
-	if user == "" {
-		return nil
-	}
-	id, err := strconv.ParseInt(user, 10, 64)
-	if err != nil || id <= 0 {
-		return nil
+	return h.States.FinishConnection(ctx, state)
+}
+
+// connected reports a connection stored for userID, the verified caller, to Connected. A token
+// the exchange stored for another user, found by the email of the account, is not theirs and is
+// not reported.
+func (h *HTTPHandlers) connected(ctx context.Context, userID, storedFor, tokenID int64) {
+	if h.Connected != nil && userID > 0 && storedFor == userID {
+		h.Connected(ctx, userID, tokenID)
 	}
-	return &id
 }
@@ -4,29 +4,55 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
+	"area/src/metrics"
 	"area/src/workflows"
 )
 
+// pollerLogger returns the logger of the gmail poller.
+func pollerLogger() *slog.Logger {
+	return slog.With("integration", "gmail")
+}
+
 // StartGmailPoller polls Gmail for inbound messages and triggers workflows.
-func StartGmailPoller(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service, client *Client) {
-	lastSeen := make(map[int64]string) // workflowID -> last message id (in-memory)
+// The returned channel is closed once the poller has saved its state and stopped.
+func StartGmailPoller(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service, client *Client) <-chan struct{} {
+	done := make(chan struct{})
+	if wfStore == nil || wfService == nil || client == nil {
+		pollerLogger().Warn("missing dependencies, skipping")
+		close(done)
+		return done
+	}
 	go func() {
+		defer close(done)
+		lastSeen := make(map[int64]string) // workflowID -> last message id
+
+		state := wfStore.PollerState("gmail", map[string]any{
+			"last_seen": &lastSeen,
+		})
+
 		ticker := time.NewTicker(30 * time.Second)
 		defer ticker.Stop()
 		for {
 			select {
 			case <-ctx.Done():
-				log.Printf("gmail poller stopped: %v", ctx.Err())
+				state.Stop(ctx)
+				pollerLogger().Info("poller stopped", "reason", ctx.Err())
 				return
 			case <-ticker.C:
 			}
+			if !state.Lead(ctx) {
+				continue
+			}
 
+			start := time.Now()
 			wfs, err := wfStore.ListWorkflowsByTrigger(ctx, "gmail_inbound")
 			if err != nil {
-				log.Printf("gmail poller: list workflows: %v", err)
+				metrics.PollerErrorsTotal.Inc("gmail")
+				pollerLogger().Error("list workflows", "error", err)
+				metrics.ObservePollerCycle("gmail", start)
 				continue
 			}
 			for _, wf := range wfs {
@@ -42,7 +68,8 @@ func StartGmailPoller(ctx context.Context, wfStore *workflows.Store, wfService *
 				if lastID == "" {
 					msgs, err := client.ListRecentMessages(ctx, &wf.UserID, cfg.TokenID, 1, "")
 					if err != nil {
-						log.Printf("gmail poller wf %d (init cursor): %v", wf.ID, err)
+						metrics.PollerErrorsTotal.Inc("gmail")
+						pollerLogger().Error("init cursor", "workflow_id", wf.ID, "error", err)
 						continue
 					}
 					if len(msgs) > 0 {
@@ -53,7 +80,8 @@ func StartGmailPoller(ctx context.Context, wfStore *workflows.Store, wfService *
 
 				msgs, err := client.ListRecentMessages(ctx, &wf.UserID, cfg.TokenID, 5, lastID)
 				if err != nil {
-					log.Printf("gmail poller wf %d: %v", wf.ID, err)
+					metrics.PollerErrorsTotal.Inc("gmail")
+					pollerLogger().Error("poll failed", "workflow_id", wf.ID, "error", err)
 					continue
 				}
 				for i := len(msgs) - 1; i >= 0; i-- {
@@ -82,12 +110,15 @@ func StartGmailPoller(ctx context.Context, wfStore *workflows.Store, wfService *
 					}
 					_, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload)
 					if err != nil {
-						log.Printf("gmail poller trigger wf %d: %v", wf.ID, err)
+						metrics.PollerErrorsTotal.Inc("gmail")
+						pollerLogger().Error("trigger failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 						continue
 					}
 					lastSeen[wf.ID] = msg.ID
 				}
 			}
+			metrics.ObservePollerCycle("gmail", start)
 		}
 	}()
+	return done
 }
@@ -10,15 +10,19 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
 	"time"
 
+	"area/src/config"
 	"area/src/database"
 
 	"gorm.io/gorm"
 )
 
+// ErrUserRequired is returned for API calls made without a user: tokens are only ever used on
+// behalf of a user allowed to use them.
+var ErrUserRequired = errors.New("a user is required to use a Google token")
+
 // OAuthToken stores the access credentials we use to call Google APIs.
 type OAuthToken struct {
 	AccessToken  string
@@ -30,22 +34,24 @@ type OAuthToken struct {
 type Client struct {
 	clientID     string
 	clientSecret string
+	redirectURI  string
 	scopes       []string
 	httpClient   *http.Client
 }
 
-// NewClient builds a Google API client using environment credentials.
-func NewClient() *Client {
+// NewClient builds a Google API client for an OAuth application.
+func NewClient(app config.OAuth) *Client {
 	return &Client{
-		clientID:     mustEnv("GOOGLE_OAUTH_CLIENT_ID"),
-		clientSecret: mustEnv("GOOGLE_OAUTH_CLIENT_SECRET"),
+		clientID:     app.ClientID,
+		clientSecret: app.ClientSecret,
+		redirectURI:  app.RedirectURI,
 		scopes: []string{
 			"https://www.googleapis.com/auth/gmail.send",
 			"https://www.googleapis.com/auth/gmail.readonly",
 			"https://www.googleapis.com/auth/calendar.events",
 			"https://www.googleapis.com/auth/userinfo.email",
 		},
-		httpClient: &http.Client{Timeout: 10 * time.Second},
+		httpClient: &http.Client{},
 	}
 }
 
@@ -271,19 +277,19 @@ func buildRawEmail(to, subject, body string) string {
 	return fmt.Sprintf("To: %s\r\nSubject: %s\r\n\r\n%s", to, subject, body)
 }
 
-// ensureToken retrieves and refreshes the OAuth token as needed.
+// ensureToken retrieves and refreshes the OAuth token as needed. Only tokens userID may use
+// are found, so a request without a user fails.
 func (c *Client) ensureToken(ctx context.Context, userID *int64, tokenID int64) (*OAuthToken, error) {
+	if userID == nil || *userID <= 0 {
+		return nil, ErrUserRequired
+	}
 	var t *database.GoogleToken
 	var err error
-	// If no tokenID provided but user is known, pick the latest token for that user.
-	if tokenID == 0 && userID != nil && *userID > 0 {
+	// If no tokenID provided, pick the latest token of the user.
+	if tokenID == 0 {
 		t, err = database.GetLatestGoogleTokenForUser(ctx, *userID)
 	} else {
-		if userID != nil && *userID > 0 {
-			t, err = database.GetGoogleTokenForUser(tokenID, *userID)
-		} else {
-			t, err = database.GetGoogleToken(tokenID)
-		}
+		t, err = database.GetGoogleTokenForUser(tokenID, *userID)
 	}
 	if err != nil {
 		return nil, err
@@ -405,12 +411,3 @@ func (c *Client) fetchMessage(ctx context.Context, accessToken, msgID string) (G
 	}
 	return msg, nil
 }
-
-// mustEnv retrieves an environment variable or panics if not set.
-func mustEnv(key string) string {
-	v := os.Getenv(key)
-	if v == "" {
-		panic("missing env " + key)
-	}
-	return v
-}
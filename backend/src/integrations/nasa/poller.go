@@ -5,31 +5,40 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"area/src/metrics"
 	"area/src/workflows"
 )
 
 const defaultInterval = 30 * time.Minute
 
-// StartNasaPoller checks NASA workflows and triggers on new space data.
-func StartNasaPoller(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service) {
+// pollerLogger returns the logger of the nasa poller.
+func pollerLogger() *slog.Logger {
+	return slog.With("integration", "nasa")
+}
+
+// StartNasaPoller checks NASA workflows and triggers on new space data; an empty apiKey uses DEMO_KEY.
+// The returned channel is closed once the poller has saved its state and stopped.
+func StartNasaPoller(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service, apiKey string) <-chan struct{} {
+	done := make(chan struct{})
 	if wfStore == nil || wfService == nil {
-		log.Println("nasa poller: missing dependencies, skipping")
-		return
+		pollerLogger().Warn("missing dependencies, skipping")
+		close(done)
+		return done
 	}
-	apiKey := strings.TrimSpace(os.Getenv("NASA_API_KEY"))
+	apiKey = strings.TrimSpace(apiKey)
 	if apiKey == "" {
 		apiKey = "DEMO_KEY"
 	}
 	go func() {
+		defer close(done)
 		ticker := time.NewTicker(1 * time.Minute)
 		defer ticker.Stop()
 
@@ -38,26 +47,41 @@ func StartNasaPoller(ctx context.Context, wfStore *workflows.Store, wfService *w
 		lastNEO := make(map[int64]string)
 		lastCheck := make(map[int64]time.Time)
 
+		state := wfStore.PollerState("nasa", map[string]any{
+			"last_apod":  &lastAPOD,
+			"last_mars":  &lastMars,
+			"last_neo":   &lastNEO,
+			"last_check": &lastCheck,
+		})
+
 		for {
 			select {
 			case <-ctx.Done():
-				log.Printf("nasa poller stopped: %v", ctx.Err())
+				state.Stop(ctx)
+				pollerLogger().Info("poller stopped", "reason", ctx.Err())
 				return
 			case <-ticker.C:
 			}
+			if !state.Lead(ctx) {
+				continue
+			}
 
+			start := time.Now()
 			pollAPOD(ctx, wfStore, wfService, apiKey, lastAPOD, lastCheck)
 			pollMarsPhotos(ctx, wfStore, wfService, apiKey, lastMars, lastCheck)
 			pollNEO(ctx, wfStore, wfService, apiKey, lastNEO, lastCheck)
+			metrics.ObservePollerCycle("nasa", start)
 		}
 	}()
+	return done
 }
 
 // pollAPOD checks NASA APOD and triggers workflows on new images.
-func pollAPOD(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service, apiKey string, lastAPOD map[int64]string, lastCheck map[int64]time.Time) {
+func pollAPOD(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service, apiKey string, lastAPOD map[int64]string, lastCheck map[int64]time.Time) {
 	wfs, err := wfStore.ListWorkflowsByTrigger(ctx, "nasa_apod")
 	if err != nil {
-		log.Printf("nasa poller: list apod workflows: %v", err)
+		metrics.PollerErrorsTotal.Inc("nasa")
+		pollerLogger().Error("list apod workflows", "error", err)
 		return
 	}
 	for _, wf := range wfs {
@@ -66,7 +90,8 @@ func pollAPOD(ctx context.Context, wfStore *workflows.Store, wfService *workflow
 		}
 		cfg, err := workflows.NasaApodConfigFromJSON(wf.TriggerConfig)
 		if err != nil {
-			log.Printf("nasa poller wf %d: bad config: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("nasa")
+			pollerLogger().Warn("bad config", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		interval := defaultInterval
@@ -80,7 +105,8 @@ func pollAPOD(ctx context.Context, wfStore *workflows.Store, wfService *workflow
 
 		apod, err := fetchAPOD(ctx, apiKey)
 		if err != nil {
-			log.Printf("nasa poller wf %d: apod: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("nasa")
+			pollerLogger().Error("apod", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		if last, ok := lastAPOD[wf.ID]; ok && last == apod.Date {
@@ -102,16 +128,18 @@ func pollAPOD(ctx context.Context, wfStore *workflows.Store, wfService *workflow
 		}
 		augmentContent(payload, fmt.Sprintf("APOD: %s", apod.Title), apod.URL)
 		if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-			log.Printf("nasa poller trigger apod wf %d: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("nasa")
+			pollerLogger().Error("trigger apod failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 		}
 	}
 }
 
 // pollMarsPhotos checks NASA Mars photos and triggers workflows on new images.
-func pollMarsPhotos(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service, apiKey string, lastMars map[int64]int, lastCheck map[int64]time.Time) {
+func pollMarsPhotos(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service, apiKey string, lastMars map[int64]int, lastCheck map[int64]time.Time) {
 	wfs, err := wfStore.ListWorkflowsByTrigger(ctx, "nasa_mars_photo")
 	if err != nil {
-		log.Printf("nasa poller: list mars workflows: %v", err)
+		metrics.PollerErrorsTotal.Inc("nasa")
+		pollerLogger().Error("list mars workflows", "error", err)
 		return
 	}
 	for _, wf := range wfs {
@@ -120,7 +148,8 @@ func pollMarsPhotos(ctx context.Context, wfStore *workflows.Store, wfService *wo
 		}
 		cfg, err := workflows.NasaMarsPhotoConfigFromJSON(wf.TriggerConfig)
 		if err != nil || strings.TrimSpace(cfg.Rover) == "" {
-			log.Printf("nasa poller wf %d: bad config: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("nasa")
+			pollerLogger().Warn("bad config", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		interval := defaultInterval
@@ -134,7 +163,8 @@ func pollMarsPhotos(ctx context.Context, wfStore *workflows.Store, wfService *wo
 
 		photo, err := fetchLatestMarsPhoto(ctx, apiKey, cfg.Rover, cfg.Camera)
 		if err != nil {
-			log.Printf("nasa poller wf %d: mars photo: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("nasa")
+			pollerLogger().Error("mars photo", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		if photo == nil {
@@ -159,16 +189,18 @@ func pollMarsPhotos(ctx context.Context, wfStore *workflows.Store, wfService *wo
 		}
 		augmentContent(payload, fmt.Sprintf("Mars photo (%s)", photo.Rover.Name), photo.ImgSrc)
 		if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-			log.Printf("nasa poller trigger mars wf %d: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("nasa")
+			pollerLogger().Error("trigger mars failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 		}
 	}
 }
 
 // pollNEO checks NASA NEOs and triggers workflows on close approaches.
-func pollNEO(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service, apiKey string, lastNEO map[int64]string, lastCheck map[int64]time.Time) {
+func pollNEO(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service, apiKey string, lastNEO map[int64]string, lastCheck map[int64]time.Time) {
 	wfs, err := wfStore.ListWorkflowsByTrigger(ctx, "nasa_neo_close_approach")
 	if err != nil {
-		log.Printf("nasa poller: list neo workflows: %v", err)
+		metrics.PollerErrorsTotal.Inc("nasa")
+		pollerLogger().Error("list neo workflows", "error", err)
 		return
 	}
 	for _, wf := range wfs {
@@ -177,7 +209,8 @@ func pollNEO(ctx context.Context, wfStore *workflows.Store, wfService *workflows
 		}
 		cfg, err := workflows.NasaNeoConfigFromJSON(wf.TriggerConfig)
 		if err != nil || cfg.ThresholdKM <= 0 {
-			log.Printf("nasa poller wf %d: bad config: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("nasa")
+			pollerLogger().Warn("bad config", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		interval := defaultInterval
@@ -195,7 +228,8 @@ func pollNEO(ctx context.Context, wfStore *workflows.Store, wfService *workflows
 		}
 		neo, err := fetchNearestNEO(ctx, apiKey, cfg.ThresholdKM, days)
 		if err != nil {
-			log.Printf("nasa poller wf %d: neo: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("nasa")
+			pollerLogger().Error("neo", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		if neo == nil {
@@ -221,7 +255,8 @@ func pollNEO(ctx context.Context, wfStore *workflows.Store, wfService *workflows
 		}
 		augmentContent(payload, fmt.Sprintf("NEO %s at %.0f km", neo.Name, neo.MissDistanceKM), "")
 		if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-			log.Printf("nasa poller trigger neo wf %d: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("nasa")
+			pollerLogger().Error("trigger neo failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 		}
 	}
 }
@@ -14,7 +14,7 @@ type HTTPHandlers struct {
 // NewHTTPHandlers builds Notion HTTP handlers with a default client.
 func NewHTTPHandlers(client *Client) *HTTPHandlers {
 	if client == nil {
-		client = NewClient()
+		client = NewClientWithToken("")
 	}
 	return &HTTPHandlers{client: client}
 }
@@ -7,9 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strings"
-	"time"
 )
 
 const apiBase = "https://api.notion.com/v1"
@@ -20,16 +18,11 @@ type Client struct {
 	client *http.Client
 }
 
-// NewClient builds a Notion API client.
-func NewClient() *Client {
-	return NewClientWithToken(os.Getenv("NOTION_TOKEN"))
-}
-
 // NewClientWithToken builds a Notion API client with an explicit token.
 func NewClientWithToken(token string) *Client {
 	return &Client{
 		token:  strings.TrimSpace(token),
-		client: &http.Client{Timeout: 10 * time.Second},
+		client: &http.Client{},
 	}
 }
 
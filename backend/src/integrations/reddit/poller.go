@@ -5,40 +5,62 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
+	"area/src/metrics"
 	"area/src/workflows"
 )
 
 const defaultInterval = 5 * time.Minute
 
+// pollerLogger returns the logger of the reddit poller.
+func pollerLogger() *slog.Logger {
+	return slog.With("integration", "reddit")
+}
+
 // StartRedditPoller checks reddit_new_post workflows and triggers on new posts.
-func StartRedditPoller(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service) {
+// The returned channel is closed once the poller has saved its state and stopped.
+func StartRedditPoller(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service) <-chan struct{} {
+	done := make(chan struct{})
 	if wfStore == nil || wfService == nil {
-		log.Println("reddit poller: missing dependencies, skipping")
-		return
+		pollerLogger().Warn("missing dependencies, skipping")
+		close(done)
+		return done
 	}
 	go func() {
+		defer close(done)
 		ticker := time.NewTicker(1 * time.Minute)
 		defer ticker.Stop()
 
 		lastSeen := make(map[int64]string)
 		lastCheck := make(map[int64]time.Time)
 
+		state := wfStore.PollerState("reddit", map[string]any{
+			"last_seen":  &lastSeen,
+			"last_check": &lastCheck,
+		})
+
 		for {
 			select {
 			case <-ctx.Done():
-				log.Printf("reddit poller stopped: %v", ctx.Err())
+				state.Stop(ctx)
+				pollerLogger().Info("poller stopped", "reason", ctx.Err())
 				return
 			case <-ticker.C:
 			}
+			if !state.Lead(ctx) {
+				continue
+			}
 
+			start := time.Now()
 			wfs, err := wfStore.ListWorkflowsByTrigger(ctx, "reddit_new_post")
 			if err != nil {
-				log.Printf("reddit poller: list workflows: %v", err)
+				metrics.PollerErrorsTotal.Inc("reddit")
+				pollerLogger().Error("list workflows", "error", err)
+				metrics.ObservePollerCycle("reddit", start)
 				continue
 			}
 
@@ -49,7 +71,8 @@ func StartRedditPoller(ctx context.Context, wfStore *workflows.Store, wfService
 
 				cfg, err := workflows.RedditNewPostConfigFromJSON(wf.TriggerConfig)
 				if err != nil || strings.TrimSpace(cfg.Subreddit) == "" {
-					log.Printf("reddit poller wf %d: bad config: %v", wf.ID, err)
+					metrics.PollerErrorsTotal.Inc("reddit")
+					pollerLogger().Warn("bad config", "workflow_id", wf.ID, "error", err)
 					continue
 				}
 
@@ -64,7 +87,8 @@ func StartRedditPoller(ctx context.Context, wfStore *workflows.Store, wfService
 
 				posts, err := fetchNewPosts(ctx, cfg.Subreddit, 5)
 				if err != nil {
-					log.Printf("reddit poller wf %d: fetch posts: %v", wf.ID, err)
+					metrics.PollerErrorsTotal.Inc("reddit")
+					pollerLogger().Error("fetch posts", "workflow_id", wf.ID, "error", err)
 					continue
 				}
 				if len(posts) == 0 {
@@ -103,14 +127,17 @@ func StartRedditPoller(ctx context.Context, wfStore *workflows.Store, wfService
 						payload["content"] = fmt.Sprintf("New Reddit post in r/%s: %s", cfg.Subreddit, p.Title)
 					}
 					if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-						log.Printf("reddit poller trigger wf %d: %v", wf.ID, err)
+						metrics.PollerErrorsTotal.Inc("reddit")
+						pollerLogger().Error("trigger failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 						continue
 					}
 				}
 				lastSeen[key] = posts[0].ID
 			}
+			metrics.ObservePollerCycle("reddit", start)
 		}
 	}()
+	return done
 }
 
 type redditPost struct {
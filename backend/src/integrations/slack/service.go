@@ -7,9 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strings"
-	"time"
 )
 
 const apiBase = "https://slack.com/api"
@@ -19,16 +17,11 @@ type Client struct {
 	client *http.Client
 }
 
-// NewClient builds a Slack API client.
-func NewClient() *Client {
-	return NewClientWithToken(os.Getenv("SLACK_BOT_TOKEN"))
-}
-
 // NewClientWithToken builds a Slack API client with an explicit token.
 func NewClientWithToken(token string) *Client {
 	return &Client{
 		token:  strings.TrimSpace(token),
-		client: &http.Client{Timeout: 10 * time.Second},
+		client: &http.Client{},
 	}
 }
 
@@ -5,30 +5,40 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"area/src/metrics"
 	"area/src/workflows"
 )
 
 const defaultInterval = 2 * time.Minute
 
-// StartSteamPoller checks steam workflows and triggers on status or price changes.
-func StartSteamPoller(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service) {
+// pollerLogger returns the logger of the steam poller.
+func pollerLogger() *slog.Logger {
+	return slog.With("integration", "steam")
+}
+
+// StartSteamPoller checks steam workflows and triggers on status or price changes;
+// player status needs a Steam Web API key.
+// The returned channel is closed once the poller has saved its state and stopped.
+func StartSteamPoller(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service, apiKey string) <-chan struct{} {
+	done := make(chan struct{})
 	if wfStore == nil || wfService == nil {
-		log.Println("steam poller: missing dependencies, skipping")
-		return
+		pollerLogger().Warn("missing dependencies, skipping")
+		close(done)
+		return done
 	}
-	apiKey := strings.TrimSpace(os.Getenv("STEAM_API_KEY"))
+	apiKey = strings.TrimSpace(apiKey)
 	if apiKey == "" {
-		log.Println("steam poller: missing STEAM_API_KEY, player status disabled")
+		pollerLogger().Warn("missing STEAM_API_KEY, player status disabled")
 	}
 	go func() {
+		defer close(done)
 		ticker := time.NewTicker(1 * time.Minute)
 		defer ticker.Stop()
 
@@ -39,29 +49,46 @@ func StartSteamPoller(ctx context.Context, wfStore *workflows.Store, wfService *
 		lastCheckSale := make(map[int64]time.Time)
 		lastCheckPrice := make(map[int64]time.Time)
 
+		state := wfStore.PollerState("steam", map[string]any{
+			"last_online":       &lastOnline,
+			"last_sale":         &lastSale,
+			"last_price":        &lastPrice,
+			"last_check_online": &lastCheckOnline,
+			"last_check_sale":   &lastCheckSale,
+			"last_check_price":  &lastCheckPrice,
+		})
+
 		for {
 			select {
 			case <-ctx.Done():
-				log.Printf("steam poller stopped: %v", ctx.Err())
+				state.Stop(ctx)
+				pollerLogger().Info("poller stopped", "reason", ctx.Err())
 				return
 			case <-ticker.C:
 			}
+			if !state.Lead(ctx) {
+				continue
+			}
 
+			start := time.Now()
 			pollPlayerOnline(ctx, wfStore, wfService, apiKey, lastOnline, lastCheckOnline)
 			pollGameSales(ctx, wfStore, wfService, lastSale, lastCheckSale)
 			pollPriceChanges(ctx, wfStore, wfService, lastPrice, lastCheckPrice)
+			metrics.ObservePollerCycle("steam", start)
 		}
 	}()
+	return done
 }
 
 // pollPlayerOnline checks Steam player online status and triggers workflows on status changes.
-func pollPlayerOnline(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service, apiKey string, lastOnline map[int64]int, lastCheck map[int64]time.Time) {
+func pollPlayerOnline(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service, apiKey string, lastOnline map[int64]int, lastCheck map[int64]time.Time) {
 	if strings.TrimSpace(apiKey) == "" {
 		return
 	}
 	wfs, err := wfStore.ListWorkflowsByTrigger(ctx, "steam_player_online")
 	if err != nil {
-		log.Printf("steam poller: list player workflows: %v", err)
+		metrics.PollerErrorsTotal.Inc("steam")
+		pollerLogger().Error("list player workflows", "error", err)
 		return
 	}
 	for _, wf := range wfs {
@@ -70,7 +97,8 @@ func pollPlayerOnline(ctx context.Context, wfStore *workflows.Store, wfService *
 		}
 		cfg, err := workflows.SteamPlayerOnlineConfigFromJSON(wf.TriggerConfig)
 		if err != nil || strings.TrimSpace(cfg.SteamID) == "" {
-			log.Printf("steam poller wf %d: bad config: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("steam")
+			pollerLogger().Warn("bad config", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		interval := defaultInterval
@@ -84,7 +112,8 @@ func pollPlayerOnline(ctx context.Context, wfStore *workflows.Store, wfService *
 
 		player, err := fetchPlayerSummary(ctx, apiKey, cfg.SteamID)
 		if err != nil {
-			log.Printf("steam poller wf %d: player summary: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("steam")
+			pollerLogger().Error("player summary", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 
@@ -112,16 +141,18 @@ func pollPlayerOnline(ctx context.Context, wfStore *workflows.Store, wfService *
 			payload["content"] = fmt.Sprintf("Steam: %s is online", player.PersonaName)
 		}
 		if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-			log.Printf("steam poller trigger wf %d: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("steam")
+			pollerLogger().Error("trigger failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 		}
 	}
 }
 
 // pollGameSales checks Steam game sales and triggers workflows on new sales.
-func pollGameSales(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service, lastSale map[int64]int, lastCheck map[int64]time.Time) {
+func pollGameSales(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service, lastSale map[int64]int, lastCheck map[int64]time.Time) {
 	wfs, err := wfStore.ListWorkflowsByTrigger(ctx, "steam_game_sale")
 	if err != nil {
-		log.Printf("steam poller: list sale workflows: %v", err)
+		metrics.PollerErrorsTotal.Inc("steam")
+		pollerLogger().Error("list sale workflows", "error", err)
 		return
 	}
 	for _, wf := range wfs {
@@ -130,7 +161,8 @@ func pollGameSales(ctx context.Context, wfStore *workflows.Store, wfService *wor
 		}
 		cfg, err := workflows.SteamGameSaleConfigFromJSON(wf.TriggerConfig)
 		if err != nil || cfg.AppID <= 0 {
-			log.Printf("steam poller wf %d: bad config: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("steam")
+			pollerLogger().Warn("bad config", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		interval := defaultInterval
@@ -144,7 +176,8 @@ func pollGameSales(ctx context.Context, wfStore *workflows.Store, wfService *wor
 
 		app, err := fetchAppDetails(ctx, cfg.AppID, cfg.Country)
 		if err != nil {
-			log.Printf("steam poller wf %d: app details: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("steam")
+			pollerLogger().Error("app details", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		if app.PriceOverview == nil {
@@ -175,16 +208,18 @@ func pollGameSales(ctx context.Context, wfStore *workflows.Store, wfService *wor
 			payload["content"] = fmt.Sprintf("Steam sale: %s (-%d%%)", app.Name, app.PriceOverview.DiscountPercent)
 		}
 		if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-			log.Printf("steam poller trigger sale wf %d: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("steam")
+			pollerLogger().Error("trigger sale failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 		}
 	}
 }
 
 // pollPriceChanges checks Steam game prices and triggers workflows on price changes.
-func pollPriceChanges(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service, lastPrice map[int64]int, lastCheck map[int64]time.Time) {
+func pollPriceChanges(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service, lastPrice map[int64]int, lastCheck map[int64]time.Time) {
 	wfs, err := wfStore.ListWorkflowsByTrigger(ctx, "steam_price_change")
 	if err != nil {
-		log.Printf("steam poller: list price workflows: %v", err)
+		metrics.PollerErrorsTotal.Inc("steam")
+		pollerLogger().Error("list price workflows", "error", err)
 		return
 	}
 	for _, wf := range wfs {
@@ -193,7 +228,8 @@ func pollPriceChanges(ctx context.Context, wfStore *workflows.Store, wfService *
 		}
 		cfg, err := workflows.SteamPriceChangeConfigFromJSON(wf.TriggerConfig)
 		if err != nil || cfg.AppID <= 0 {
-			log.Printf("steam poller wf %d: bad config: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("steam")
+			pollerLogger().Warn("bad config", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		interval := defaultInterval
@@ -207,7 +243,8 @@ func pollPriceChanges(ctx context.Context, wfStore *workflows.Store, wfService *
 
 		app, err := fetchAppDetails(ctx, cfg.AppID, cfg.Country)
 		if err != nil {
-			log.Printf("steam poller wf %d: app details: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("steam")
+			pollerLogger().Error("app details", "workflow_id", wf.ID, "error", err)
 			continue
 		}
 		if app.PriceOverview == nil {
@@ -238,7 +275,8 @@ func pollPriceChanges(ctx context.Context, wfStore *workflows.Store, wfService *
 			payload["content"] = fmt.Sprintf("Steam price change: %s (%d -> %d %s)", app.Name, prev, app.PriceOverview.Final, app.PriceOverview.Currency)
 		}
 		if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-			log.Printf("steam poller trigger price wf %d: %v", wf.ID, err)
+			metrics.PollerErrorsTotal.Inc("steam")
+			pollerLogger().Error("trigger price failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 		}
 	}
 }
@@ -15,7 +15,7 @@ type HTTPHandlers struct {
 // NewHTTPHandlers builds Trello HTTP handlers with a default client.
 func NewHTTPHandlers(client *Client) *HTTPHandlers {
 	if client == nil {
-		client = NewClient()
+		client = NewClientWithCredentials("", "")
 	}
 	return &HTTPHandlers{client: client}
 }
@@ -6,9 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
-	"time"
 )
 
 const apiBase = "https://api.trello.com/1"
@@ -20,17 +18,12 @@ type Client struct {
 	client *http.Client
 }
 
-// NewClient builds a Trello client from environment credentials.
-func NewClient() *Client {
-	return NewClientWithCredentials(os.Getenv("TRELLO_API_KEY"), os.Getenv("TRELLO_TOKEN"))
-}
-
 // NewClientWithCredentials builds a Trello client with explicit credentials.
 func NewClientWithCredentials(key, token string) *Client {
 	return &Client{
 		key:    strings.TrimSpace(key),
 		token:  strings.TrimSpace(token),
-		client: &http.Client{Timeout: 10 * time.Second},
+		client: &http.Client{},
 	}
 }
 
@@ -5,40 +5,63 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"time"
 
+	"area/src/metrics"
 	"area/src/workflows"
 )
 
+// pollerLogger returns the logger of the weather poller.
+func pollerLogger() *slog.Logger {
+	return slog.With("integration", "weather")
+}
+
 // StartWeatherPoller checks weather_temp workflows and triggers on threshold crossings.
-func StartWeatherPoller(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service) {
+// The returned channel is closed once the poller has saved its state and stopped.
+func StartWeatherPoller(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service) <-chan struct{} {
+	done := make(chan struct{})
 	if wfStore == nil || wfService == nil {
-		log.Println("weather poller: missing dependencies, skipping")
-		return
+		pollerLogger().Warn("missing dependencies, skipping")
+		close(done)
+		return done
 	}
 	go func() {
+		defer close(done)
 		ticker := time.NewTicker(2 * time.Minute)
 		defer ticker.Stop()
 
 		lastState := make(map[int64]bool)
 		lastCheck := make(map[int64]time.Time)
 		lastReport := make(map[int64]time.Time)
+
+		state := wfStore.PollerState("weather", map[string]any{
+			"last_state":  &lastState,
+			"last_check":  &lastCheck,
+			"last_report": &lastReport,
+		})
 		cityCache := make(map[string][2]float64)
 
 		for {
 			select {
 			case <-ctx.Done():
-				log.Printf("weather poller stopped: %v", ctx.Err())
+				state.Stop(ctx)
+				pollerLogger().Info("poller stopped", "reason", ctx.Err())
 				return
 			case <-ticker.C:
 			}
+			if !state.Lead(ctx) {
+				continue
+			}
 
+			start := time.Now()
 			wfs, err := wfStore.ListWorkflowsByTrigger(ctx, "weather_temp")
 			if err != nil {
-				log.Printf("weather poller: list workflows: %v", err)
+				metrics.PollerErrorsTotal.Inc("weather")
+				pollerLogger().Error("list workflows", "error", err)
+				metrics.ObservePollerCycle("weather", start)
 				continue
 			}
 			for _, wf := range wfs {
@@ -47,18 +70,20 @@ func StartWeatherPoller(ctx context.Context, wfStore *workflows.Store, wfService
 				}
 				var cfg workflows.WeatherTempConfig
 				if err := json.Unmarshal(wf.TriggerConfig, &cfg); err != nil {
-					log.Printf("weather poller wf %d: bad config: %v", wf.ID, err)
+					metrics.PollerErrorsTotal.Inc("weather")
+					pollerLogger().Warn("bad config", "workflow_id", wf.ID, "error", err)
 					continue
 				}
 				if cfg.City == "" {
-					log.Printf("weather poller wf %d: missing city", wf.ID)
+					pollerLogger().Warn("missing city", "workflow_id", wf.ID)
 					continue
 				}
 				coords, ok := cityCache[cfg.City]
 				if !ok {
 					lat, lon, err := geocodeCity(ctx, cfg.City)
 					if err != nil {
-						log.Printf("weather poller wf %d: geocode %q: %v", wf.ID, cfg.City, err)
+						metrics.PollerErrorsTotal.Inc("weather")
+						pollerLogger().Error("geocode", "workflow_id", wf.ID, "city", cfg.City, "error", err)
 						continue
 					}
 					coords = [2]float64{lat, lon}
@@ -73,7 +98,8 @@ func StartWeatherPoller(ctx context.Context, wfStore *workflows.Store, wfService
 				}
 				temp, err := fetchCurrentTemp(ctx, cfg.Lat, cfg.Lon)
 				if err != nil {
-					log.Printf("weather poller wf %d: fetch: %v", wf.ID, err)
+					metrics.PollerErrorsTotal.Inc("weather")
+					pollerLogger().Error("fetch", "workflow_id", wf.ID, "error", err)
 					continue
 				}
 				lastCheck[wf.ID] = time.Now()
@@ -100,7 +126,8 @@ func StartWeatherPoller(ctx context.Context, wfStore *workflows.Store, wfService
 						payload["content"] = fmt.Sprintf("Temp: %.1f°C (%s %g)", temp, cfg.Direction, cfg.Threshold)
 					}
 					if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-						log.Printf("weather poller trigger wf %d: %v", wf.ID, err)
+						metrics.PollerErrorsTotal.Inc("weather")
+						pollerLogger().Error("trigger failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 					}
 					continue
 				}
@@ -130,13 +157,16 @@ func StartWeatherPoller(ctx context.Context, wfStore *workflows.Store, wfService
 					payload["content"] = fmt.Sprintf("Temp: %.1f°C (%s %g)", temp, cfg.Direction, cfg.Threshold)
 				}
 				if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-					log.Printf("weather poller trigger wf %d: %v", wf.ID, err)
+					metrics.PollerErrorsTotal.Inc("weather")
+					pollerLogger().Error("trigger failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 				}
 			}
 
 			reports, err := wfStore.ListWorkflowsByTrigger(ctx, "weather_report")
 			if err != nil {
-				log.Printf("weather poller: list report workflows: %v", err)
+				metrics.PollerErrorsTotal.Inc("weather")
+				pollerLogger().Error("list report workflows", "error", err)
+				metrics.ObservePollerCycle("weather", start)
 				continue
 			}
 			for _, wf := range reports {
@@ -145,11 +175,12 @@ func StartWeatherPoller(ctx context.Context, wfStore *workflows.Store, wfService
 				}
 				var cfg workflows.WeatherReportConfig
 				if err := json.Unmarshal(wf.TriggerConfig, &cfg); err != nil {
-					log.Printf("weather poller wf %d: bad report config: %v", wf.ID, err)
+					metrics.PollerErrorsTotal.Inc("weather")
+					pollerLogger().Warn("bad report config", "workflow_id", wf.ID, "error", err)
 					continue
 				}
 				if cfg.City == "" {
-					log.Printf("weather poller wf %d: missing city", wf.ID)
+					pollerLogger().Warn("missing city", "workflow_id", wf.ID)
 					continue
 				}
 				if cfg.IntervalMin <= 0 {
@@ -162,7 +193,8 @@ func StartWeatherPoller(ctx context.Context, wfStore *workflows.Store, wfService
 				if !ok {
 					lat, lon, err := geocodeCity(ctx, cfg.City)
 					if err != nil {
-						log.Printf("weather poller wf %d: geocode %q: %v", wf.ID, cfg.City, err)
+						metrics.PollerErrorsTotal.Inc("weather")
+						pollerLogger().Error("geocode", "workflow_id", wf.ID, "city", cfg.City, "error", err)
 						continue
 					}
 					coords = [2]float64{lat, lon}
@@ -170,7 +202,8 @@ func StartWeatherPoller(ctx context.Context, wfStore *workflows.Store, wfService
 				}
 				temp, err := fetchCurrentTemp(ctx, coords[0], coords[1])
 				if err != nil {
-					log.Printf("weather poller wf %d: fetch report temp: %v", wf.ID, err)
+					metrics.PollerErrorsTotal.Inc("weather")
+					pollerLogger().Error("fetch report temp", "workflow_id", wf.ID, "error", err)
 					continue
 				}
 				lastReport[wf.ID] = time.Now()
@@ -191,11 +224,14 @@ func StartWeatherPoller(ctx context.Context, wfStore *workflows.Store, wfService
 					payload["content"] = fmt.Sprintf("Temp: %.1f°C (%s)", temp, cfg.City)
 				}
 				if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-					log.Printf("weather poller trigger wf %d: %v", wf.ID, err)
+					metrics.PollerErrorsTotal.Inc("weather")
+					pollerLogger().Error("trigger failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 				}
 			}
+			metrics.ObservePollerCycle("weather", start)
 		}
 	}()
+	return done
 }
 
 // fetchCurrentTemp retrieves the current temperature for given latitude and longitude.
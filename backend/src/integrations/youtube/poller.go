@@ -5,41 +5,63 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"regexp"
 	"strings"
 	"time"
 
+	"area/src/metrics"
 	"area/src/workflows"
 )
 
 const defaultInterval = 5 * time.Minute
 
+// pollerLogger returns the logger of the youtube poller.
+func pollerLogger() *slog.Logger {
+	return slog.With("integration", "youtube")
+}
+
 // StartYouTubePoller checks youtube_new_video workflows and triggers on new videos.
-func StartYouTubePoller(ctx context.Context, wfStore *workflows.Store, wfService *workflows.Service) {
+// The returned channel is closed once the poller has saved its state and stopped.
+func StartYouTubePoller(ctx context.Context, wfStore workflows.WorkflowStore, wfService *workflows.Service) <-chan struct{} {
+	done := make(chan struct{})
 	if wfStore == nil || wfService == nil {
-		log.Println("youtube poller: missing dependencies, skipping")
-		return
+		pollerLogger().Warn("missing dependencies, skipping")
+		close(done)
+		return done
 	}
 	go func() {
+		defer close(done)
 		ticker := time.NewTicker(1 * time.Minute)
 		defer ticker.Stop()
 
 		lastSeen := make(map[int64]string)
 		lastCheck := make(map[int64]time.Time)
 
+		state := wfStore.PollerState("youtube", map[string]any{
+			"last_seen":  &lastSeen,
+			"last_check": &lastCheck,
+		})
+
 		for {
 			select {
 			case <-ctx.Done():
-				log.Printf("youtube poller stopped: %v", ctx.Err())
+				state.Stop(ctx)
+				pollerLogger().Info("poller stopped", "reason", ctx.Err())
 				return
 			case <-ticker.C:
 			}
+			if !state.Lead(ctx) {
+				continue
+			}
 
+			start := time.Now()
 			wfs, err := wfStore.ListWorkflowsByTrigger(ctx, "youtube_new_video")
 			if err != nil {
-				log.Printf("youtube poller: list workflows: %v", err)
+				metrics.PollerErrorsTotal.Inc("youtube")
+				pollerLogger().Error("list workflows", "error", err)
+				metrics.ObservePollerCycle("youtube", start)
 				continue
 			}
 
@@ -50,13 +72,15 @@ func StartYouTubePoller(ctx context.Context, wfStore *workflows.Store, wfService
 
 				cfg, err := workflows.YouTubeNewVideoConfigFromJSON(wf.TriggerConfig)
 				if err != nil || (strings.TrimSpace(cfg.ChannelID) == "" && strings.TrimSpace(cfg.Channel) == "") {
-					log.Printf("youtube poller wf %d: bad config: %v", wf.ID, err)
+					metrics.PollerErrorsTotal.Inc("youtube")
+					pollerLogger().Warn("bad config", "workflow_id", wf.ID, "error", err)
 					continue
 				}
 
 				channelID, err := resolveChannelID(ctx, cfg)
 				if err != nil {
-					log.Printf("youtube poller wf %d: resolve channel: %v", wf.ID, err)
+					metrics.PollerErrorsTotal.Inc("youtube")
+					pollerLogger().Error("resolve channel", "workflow_id", wf.ID, "error", err)
 					continue
 				}
 
@@ -71,7 +95,8 @@ func StartYouTubePoller(ctx context.Context, wfStore *workflows.Store, wfService
 
 				videos, err := fetchNewVideos(ctx, channelID, 5)
 				if err != nil {
-					log.Printf("youtube poller wf %d: fetch videos: %v", wf.ID, err)
+					metrics.PollerErrorsTotal.Inc("youtube")
+					pollerLogger().Error("fetch videos", "workflow_id", wf.ID, "error", err)
 					continue
 				}
 				if len(videos) == 0 {
@@ -110,14 +135,17 @@ func StartYouTubePoller(ctx context.Context, wfStore *workflows.Store, wfService
 						payload["content"] = fmt.Sprintf("New YouTube video: %s", v.Title)
 					}
 					if _, err := wfService.Trigger(workflows.WithUserID(ctx, wf.UserID), wf.ID, payload); err != nil {
-						log.Printf("youtube poller trigger wf %d: %v", wf.ID, err)
+						metrics.PollerErrorsTotal.Inc("youtube")
+						pollerLogger().Error("trigger failed", "workflow_id", wf.ID, "trigger_type", wf.TriggerType, "error", err)
 						continue
 					}
 				}
 				lastSeen[key] = videos[0].ID
 			}
+			metrics.ObservePollerCycle("youtube", start)
 		}
 	}()
+	return done
 }
 
 type youtubeFeed struct {
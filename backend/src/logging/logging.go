@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Setup installs the default slog logger with a level (debug, info, warn, error) and a format
+// (json or text). Output of the standard log package goes through it too.
+func Setup(level, format string) {
+	slog.SetDefault(New(os.Stdout, level, format))
+}
+
+// New builds a logger writing to w; unknown levels default to info and unknown formats to JSON.
+func New(w io.Writer, level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(level)}
+	var h slog.Handler
+	if strings.EqualFold(strings.TrimSpace(format), "text") {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(&contextHandler{next: h})
+}
+
+// ParseLevel maps a level name to a slog level, defaulting to info.
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+type ctxAttrsKey struct{}
+type ctxRequestIDKey struct{}
+
+// With returns a context whose log records carry the given key/value pairs.
+func With(ctx context.Context, args ...any) context.Context {
+	if len(args) == 0 {
+		return ctx
+	}
+	prev, _ := ctx.Value(ctxAttrsKey{}).([]any)
+	attrs := make([]any, 0, len(prev)+len(args))
+	attrs = append(attrs, prev...)
+	attrs = append(attrs, args...)
+	return context.WithValue(ctx, ctxAttrsKey{}, attrs)
+}
+
+// WithRequestID tags ctx, and the log records written with it, with a request ID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, ctxRequestIDKey{}, requestID)
+	return With(ctx, "request_id", requestID)
+}
+
+// RequestID returns the request ID carried by ctx, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(ctxRequestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID generates a random request ID.
+func NewRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// contextHandler adds the attributes stored by With to every record logged with a context.
+type contextHandler struct {
+	next slog.Handler
+}
+
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if attrs, ok := ctx.Value(ctxAttrsKey{}).([]any); ok {
+			r.Add(attrs...)
+		}
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{next: h.next.WithGroup(name)}
+}
@@ -0,0 +1,146 @@
+// Package mail sends the emails of the server, such as password resets, through a Mailer chosen
+// by configuration.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"area/src/config"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+var errHeaderInjection = errors.New("mail: line break in header")
+
+// New returns the Mailer selected by cfg.Driver.
+func New(cfg config.Mail) (Mailer, error) {
+	switch cfg.Driver {
+	case config.MailSMTP:
+		return &SMTPMailer{
+			Addr:     net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort),
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		}, nil
+	case config.MailFile:
+		return &FileMailer{Dir: cfg.FileDir, From: cfg.From}, nil
+	case config.MailLog:
+		return LogMailer{}, nil
+	default:
+		return nil, fmt.Errorf("mail: unknown driver %q", cfg.Driver)
+	}
+}
+
+// SMTPMailer sends messages through an SMTP server, authenticating with PLAIN when Username is
+// set. The connection is upgraded with STARTTLS when the server offers it.
+type SMTPMailer struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers msg to the SMTP server.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := format(m.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, _ := net.SplitHostPort(m.Addr)
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	if err := smtp.SendMail(m.Addr, auth, envelopeAddress(m.From), []string{msg.To}, data); err != nil {
+		return fmt.Errorf("mail: send to %s: %w", m.Addr, err)
+	}
+	return nil
+}
+
+// FileMailer writes each message as an .eml file in Dir, for development.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// Send writes msg to a new file in Dir.
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := format(m.From, msg, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+		return fmt.Errorf("mail: %w", err)
+	}
+	file, err := os.CreateTemp(m.Dir, now.UTC().Format("20060102T150405")+"-*.eml")
+	if err != nil {
+		return fmt.Errorf("mail: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("mail: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("mail: %w", err)
+	}
+	slog.InfoContext(ctx, "mail written", "to", msg.To, "subject", msg.Subject, "file", filepath.Base(file.Name()))
+	return nil
+}
+
+// LogMailer logs messages, links included, instead of sending them, for development.
+type LogMailer struct{}
+
+// Send logs msg.
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	slog.InfoContext(ctx, "mail", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// format renders msg as an RFC 5322 message.
+func format(from string, msg Message, date time.Time) ([]byte, error) {
+	for _, header := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, errHeaderInjection
+		}
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return buf.Bytes(), nil
+}
+
+// envelopeAddress returns the bare address of "Name <address>".
+func envelopeAddress(from string) string {
+	if start, end := strings.LastIndex(from, "<"), strings.LastIndex(from, ">"); start >= 0 && end > start {
+		return from[start+1 : end]
+	}
+	return from
+}
@@ -3,16 +3,22 @@ package main
 import (
 	"bytes"
 	"context"
+	"flag"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"maps"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
+	"area/src/areas"
 	"area/src/auth"
+	"area/src/config"
 	"area/src/database"
 	"area/src/httpapi"
 	"area/src/integrations/airquality"
@@ -24,6 +30,10 @@ import (
 	"area/src/integrations/steam"
 	"area/src/integrations/weather"
 	"area/src/integrations/youtube"
+	"area/src/logging"
+	"area/src/mail"
+	"area/src/metrics"
+	"area/src/security"
 	"area/src/workflows"
 
 	"github.com/joho/godotenv"
@@ -31,73 +41,232 @@ import (
 
 type httpSender struct {
 	client *http.Client
+	// reactionToken authenticates the calls to the reactions acting with the OAuth connections of
+	// the workflow's user, see httpapi.ReactionNeedsUser.
+	reactionToken func(ctx context.Context, userID int64) (string, error)
+	// ownURL tells the URLs served by this server, the only ones given a reaction token.
+	ownURL func(target *url.URL) bool
 }
 
-// newHTTPSender builds an httpSender with a default timeout.
+// maxSenderBodyBytes bounds how much of a reaction response is read back.
+const maxSenderBodyBytes = 64 << 10
+
+// newHTTPSender builds an httpSender; the executor's per-workflow context bounds each call.
 func newHTTPSender() *httpSender {
-	return &httpSender{client: &http.Client{Timeout: 10 * time.Second}}
+	return &httpSender{client: &http.Client{}}
 }
 
-// Send posts the given payload as JSON to the target URL.
-func (s *httpSender) Send(ctx context.Context, url string, payload []byte) error {
+// Send posts the given payload as JSON to the target URL and returns the response status and body.
+func (s *httpSender) Send(ctx context.Context, url string, payload []byte) (*workflows.SendResult, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if userID, err := workflows.UserIDFromContext(ctx); err == nil && s.needsReactionToken(req.URL) {
+		token, err := s.reactionToken(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("http sender: reaction token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxSenderBodyBytes))
+	result := &workflows.SendResult{StatusCode: resp.StatusCode, Body: body}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("http sender: status %d: %s", resp.StatusCode, string(body))
+		return result, fmt.Errorf("http sender: status %d: %s", resp.StatusCode, string(body))
 	}
-	return nil
+	return result, nil
+}
+
+// needsReactionToken reports whether target is one of this server's reactions acting as the
+// workflow's user. Any other host with the same path gets no token.
+func (s *httpSender) needsReactionToken(target *url.URL) bool {
+	return s.reactionToken != nil && s.ownURL != nil && s.ownURL(target) && httpapi.ReactionNeedsUser(target.Path)
 }
 
 // main boots the API server, background workers, and graceful shutdown handling.
+// "area migrate up|down [n]|status" manages the database schema instead, and
+// "area secrets reencrypt" rewrites stored secrets with the current key.
 func main() {
-	err := godotenv.Load()
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "optional JSON config file; environment variables override it")
+	ephemeral := flag.Bool("ephemeral", false, "keep everything in memory, for demos; nothing survives a restart")
+	flag.Parse()
+
+	envErr := godotenv.Load()
+	cfg, err := config.Read(*configFile)
+	if err == nil {
+		if *ephemeral {
+			// Workflows live in a MemoryStore; users, tokens and the catalog in an in-memory SQLite database.
+			cfg.Database = config.Database{Driver: config.DriverSQLite, SQLitePath: ":memory:"}
+		}
+		err = cfg.Validate()
+	}
 	if err != nil {
-		log.Printf("Error loading .env file, ignoring it.")
+		logging.Setup("", "")
+		slog.Error("invalid configuration", "error", err)
+		os.Exit(1)
 	}
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	logging.Setup(cfg.Log.Level, cfg.Log.Format)
+	if envErr != nil {
+		slog.Info("no .env file loaded, ignoring it")
+	}
+	if err := security.Configure(cfg.SecretKeyID, cfg.SecretKey, cfg.PreviousSecretKeys); err != nil {
+		slog.Error("invalid configuration", "error", err)
+		os.Exit(1)
 	}
+	auth.SetBcryptCost(cfg.BcryptCost)
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(cfg.Database, flag.Args()[1:], os.Stdout); err != nil {
+			slog.Error("migrate", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if flag.Arg(0) == "secrets" {
+		if err := runSecrets(context.Background(), cfg.Database, flag.Args()[1:], os.Stdout); err != nil {
+			slog.Error("secrets", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	integrations := cfg.EnabledIntegrations()
+	for _, name := range slices.Sorted(maps.Keys(integrations)) {
+		if !integrations[name] {
+			slog.Info("integration disabled, credentials not configured", "integration", name)
+		}
+	}
+
+	// Count outbound API status codes of every client using the default transport.
+	http.DefaultTransport = metrics.InstrumentTransport(http.DefaultTransport)
 
-	database.Connect()
+	// ctx is cancelled on SIGINT/SIGTERM and stops every background worker.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	grace := cfg.Workflows.ShutdownGrace()
+
+	database.Connect(cfg.Database)
 	defer database.Disconnect()
+	if *ephemeral {
+		if _, err := database.MigrateUp(database.GetDB()); err != nil {
+			slog.Error("migrate in-memory database", "error", err)
+			os.Exit(1)
+		}
+	} else if err := database.CheckMigrated(database.GetDB()); err != nil {
+		slog.Error("refusing to start", "error", err)
+		os.Exit(1)
+	}
+	if err := areas.Check(areas.Definitions, workflows.TriggerTypes(), httpapi.ActionRoutes()); err != nil {
+		slog.Error("catalog does not match the implemented triggers and routes", "error", err)
+		os.Exit(1)
+	}
+	if err := areas.Sync(ctx, database.GetDB(), areas.Definitions); err != nil {
+		slog.Error("sync catalog", "error", err)
+		os.Exit(1)
+	}
 	userStore := auth.NewDBStore()
 	authService := auth.NewService(userStore)
+	authService.Sessions = userStore
+	authService.APITokens = userStore
+	authService.AccessTTL = cfg.Auth.AccessTTL()
+	authService.RefreshTTL = cfg.Auth.RefreshTTL()
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		slog.Error("configure mail", "error", err)
+		os.Exit(1)
+	}
+	authService.Accounts = userStore
+	authService.TwoFactor = userStore
+	authService.Throttle = userStore
+	authService.Workspaces = userStore
+	authService.Secrets = userStore
+	authService.Connections = userStore
+	authService.AuditLog = userStore
+	authService.ThrottlePolicy = auth.ThrottlePolicy{
+		AccountAttempts: cfg.Auth.LoginAccountAttempts,
+		IPAttempts:      cfg.Auth.LoginIPAttempts,
+		Lockout:         cfg.Auth.LoginLockout(),
+		MaxLockout:      cfg.Auth.LoginMaxLockout(),
+	}
+	authService.Mailer = mailer
+	authService.AppURL = cfg.Mail.AppURL
+	if cfg.Auth.DevUserHeader {
+		slog.Warn("AUTH_DEV_USER_HEADER is set, requests may act as any user through X-User-ID; never enable it in production")
+	}
 
-	wfStore := workflows.NewDefaultStore()
+	events := workflows.NewEventBus()
+	var wfStore workflows.WorkflowStore
+	if *ephemeral {
+		slog.Warn("ephemeral mode, workflows and runs are kept in memory only")
+		memStore := workflows.NewMemoryStore()
+		memStore.Events = events
+		memStore.Workspaces = userStore
+		wfStore = memStore
+	} else {
+		dbStore := workflows.NewDefaultStore()
+		dbStore.Events = events
+		wfStore = dbStore
+		// Replicas share a Postgres database, so event streams hear of the changes of them all.
+		if cfg.Database.Driver == config.DriverPostgres {
+			events.Relay(ctx, workflows.NewPostgresRelay(database.Db))
+		}
+	}
+	metrics.Default.NewGaugeFunc("area_job_queue_depth", "Pending jobs waiting for the executor.", func() (float64, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		n, err := wfStore.CountPendingJobs(ctx)
+		return float64(n), err
+	})
 	triggerer := workflows.NewTriggerer(wfStore)
 	wfService := workflows.NewService(wfStore, triggerer)
-	googleClient := google.NewClient()
-	githubClient := github.NewClient()
+	wfService.Audit = authService
+	// Pollers of integrations without credentials skip themselves on a nil client.
+	var googleClient *google.Client
+	if cfg.Google.Enabled() {
+		googleClient = google.NewClient(cfg.Google)
+	}
+	var githubClient *github.Client
+	if cfg.GitHub.Enabled() {
+		githubClient = github.NewClient(cfg.GitHub)
+	}
 
 	// Start a simple executor loop in background for outgoing webhooks.
 	sender := newHTTPSender()
+	sender.reactionToken = authService.IssueReactionToken
+	sender.ownURL = cfg.IsServerURL
 	executor := workflows.NewExecutor(wfStore, sender, 2*time.Second)
-	go executor.RunLoop(context.Background())
+	executor.MaxTimeout = cfg.Workflows.MaxTimeout()
+	executor.Secrets = authService
+	wfService.Canceller = executor
+	go executor.RunLoop(ctx)
+
+	// Digest flusher: sends buffered events of digest-mode workflows as one run.
+	digestFlusher := workflows.NewDigestFlusher(wfStore, triggerer, 30*time.Second)
+	go digestFlusher.RunLoop(ctx)
 
 	// Interval scheduler: triggers workflows of type "interval" when due.
 	go func() {
 		ticker := time.NewTicker(30 * time.Second)
 		defer ticker.Stop()
-		for range ticker.C {
-			now := time.Now()
-			due, err := wfStore.ClaimDueIntervalWorkflows(context.Background(), now)
+		for {
+			var now time.Time
+			select {
+			case <-ctx.Done():
+				slog.Info("scheduler stopped", "reason", ctx.Err())
+				return
+			case now = <-ticker.C:
+			}
+			due, err := wfStore.ClaimDueIntervalWorkflows(ctx, now)
 			if err != nil {
-				log.Printf("scheduler: %v", err)
+				slog.Error("scheduler: claim due workflows", "error", err)
 				continue
 			}
 			for _, wf := range due {
-				ctx := workflows.WithUserID(context.Background(), wf.UserID)
+				ctx := workflows.WithUserID(ctx, wf.UserID)
 				cfg, err := workflows.IntervalConfigFromJSON(wf.TriggerConfig)
 				payload := map[string]any{}
 				if err == nil && len(cfg.Payload) > 0 {
@@ -107,51 +276,74 @@ func main() {
 				}
 				_, err = wfService.Trigger(ctx, wf.ID, payload)
 				if err != nil {
-					log.Printf("scheduler trigger wf %d: %v", wf.ID, err)
+					slog.ErrorContext(ctx, "scheduler: trigger", "workflow_id", wf.ID, "error", err)
 				}
 			}
 		}
 	}()
 
+	// Pollers save their state and close their channel once ctx is cancelled.
+	pollers := []<-chan struct{}{}
 	// Gmail inbound poller (simple polling of new messages).
-	google.StartGmailPoller(context.Background(), wfStore, wfService, googleClient)
+	pollers = append(pollers, google.StartGmailPoller(ctx, wfStore, wfService, googleClient))
 	// GitHub commits poller (new commits on watched branches).
-	github.StartGithubPoller(context.Background(), wfStore, wfService, githubClient)
+	pollers = append(pollers, github.StartGithubPoller(ctx, wfStore, wfService, githubClient))
 	// Weather poller (Open-Meteo thresholds).
-	weather.StartWeatherPoller(context.Background(), wfStore, wfService)
+	pollers = append(pollers, weather.StartWeatherPoller(ctx, wfStore, wfService))
 	// Reddit poller (new posts in subreddits).
-	reddit.StartRedditPoller(context.Background(), wfStore, wfService)
+	pollers = append(pollers, reddit.StartRedditPoller(ctx, wfStore, wfService))
 	// YouTube poller (new videos from channel feed).
-	youtube.StartYouTubePoller(context.Background(), wfStore, wfService)
+	pollers = append(pollers, youtube.StartYouTubePoller(ctx, wfStore, wfService))
 	// NASA poller (APOD, Mars photos, NEO).
-	nasa.StartNasaPoller(context.Background(), wfStore, wfService)
+	pollers = append(pollers, nasa.StartNasaPoller(ctx, wfStore, wfService, cfg.Integrations.NASAAPIKey))
 	// Air quality poller (AQI, PM2.5 thresholds).
-	airquality.StartAirQualityPoller(context.Background(), wfStore, wfService)
+	pollers = append(pollers, airquality.StartAirQualityPoller(ctx, wfStore, wfService))
 	// Crypto poller (CoinGecko).
-	crypto.StartCryptoPoller(context.Background(), wfStore, wfService)
+	pollers = append(pollers, crypto.StartCryptoPoller(ctx, wfStore, wfService))
 	// Steam poller (player status and store price changes).
-	steam.StartSteamPoller(context.Background(), wfStore, wfService)
+	pollers = append(pollers, steam.StartSteamPoller(ctx, wfStore, wfService, cfg.Integrations.SteamAPIKey))
 
 	server := &http.Server{
-		Addr:              ":" + port,
-		Handler:           httpapi.NewMux(authService, wfService),
+		Addr:              ":" + cfg.Port,
+		Handler:           httpapi.NewMux(authService, wfService, cfg),
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 
-	log.Printf("listening on http://0.0.0.0:%s", port)
+	slog.Info("listening", "addr", "http://0.0.0.0:"+cfg.Port)
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	// Event streams never end on their own; close them so Shutdown does not wait on them.
+	server.RegisterOnShutdown(events.Close)
 
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("server error: %v", err)
+			slog.Error("server error", "error", err)
+			os.Exit(1)
 		}
 	}()
 
-	<-quit
-	log.Println("shutting down server")
-	if err := server.Close(); err != nil {
-		log.Fatalf("server error: %v", err)
+	<-ctx.Done()
+	slog.Info("shutting down", "grace", grace)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	// Reactions under /actions/* are served by this server, so it keeps listening until the
+	// executor has drained its in-flight job.
+	if err := executor.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("executor did not finish in time, in-flight job released", "error", err)
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("server shutdown", "error", err)
+	}
+	if err := authService.Wait(shutdownCtx); err != nil {
+		slog.Warn("password reset emails not sent in time", "error", err)
+	}
+	for _, done := range pollers {
+		select {
+		case <-done:
+		case <-shutdownCtx.Done():
+			slog.Warn("pollers did not stop in time", "error", shutdownCtx.Err())
+			return
+		}
 	}
+	slog.Info("shutdown complete")
 }
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Application metrics, registered on Default.
+var (
+	JobsTotal = Default.NewCounter("area_jobs_total",
+		"Jobs handled by the executor, by event (claimed, succeeded, failed, cancelled, released) and reaction host.",
+		"event", "host")
+	JobDuration = Default.NewHistogram("area_job_duration_seconds",
+		"Duration of reaction calls made by the executor.",
+		DefBuckets, "host", "status")
+	RunsTotal = Default.NewCounter("area_runs_total",
+		"Runs enqueued, by trigger type.",
+		"trigger_type")
+	PollerCycleDuration = Default.NewHistogram("area_poller_cycle_duration_seconds",
+		"Duration of one polling cycle, by integration.",
+		DefBuckets, "integration")
+	PollerErrorsTotal = Default.NewCounter("area_poller_errors_total",
+		"Errors met while polling, by integration.",
+		"integration")
+	OutboundRequestsTotal = Default.NewCounter("area_outbound_requests_total",
+		"Outbound HTTP requests, by host and status code (\"error\" when no response was received).",
+		"host", "code")
+)
+
+// ObservePollerCycle records the duration of a polling cycle that began at start.
+func ObservePollerCycle(integration string, start time.Time) {
+	PollerCycleDuration.Observe(time.Since(start).Seconds(), integration)
+}
+
+// HostOf returns the host of a URL for use as a label, or "unknown".
+func HostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return u.Host
+}
+
+// InstrumentTransport wraps next so every response status is counted in OutboundRequestsTotal.
+func InstrumentTransport(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := next.RoundTrip(req)
+		code := "error"
+		if err == nil {
+			code = strconv.Itoa(resp.StatusCode)
+		}
+		OutboundRequestsTotal.Inc(req.URL.Host, code)
+		return resp, err
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
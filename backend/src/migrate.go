@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"area/src/config"
+	"area/src/database"
+)
+
+// runMigrate implements the migrate subcommand: up, down [n] or status.
+func runMigrate(cfg config.Database, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up | down [n] | status")
+	}
+	database.Connect(cfg)
+	defer database.Disconnect()
+	db := database.GetDB()
+
+	switch args[0] {
+	case "up":
+		done, err := database.MigrateUp(db)
+		for _, m := range done {
+			fmt.Fprintf(out, "applied %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Fprintln(out, "already up to date")
+		}
+		statuses, err := database.MigrationStatuses(db)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			if s.AppliedAt == nil && s.Waiting() {
+				fmt.Fprintf(out, "waiting %d_%s, it cannot run yet\n", s.Version, s.Name)
+			}
+		}
+		return nil
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("migrate down: %q is not a positive number of steps", args[1])
+			}
+			steps = n
+		}
+		done, err := database.MigrateDown(db, steps)
+		for _, m := range done {
+			fmt.Fprintf(out, "reverted %d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := database.MigrationStatuses(db)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Waiting() {
+				state = "waiting"
+			}
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(out, "%04d_%-20s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("migrate: unknown command %q, want up, down or status", args[0])
+	}
+}
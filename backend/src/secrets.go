@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"area/src/auth"
+	"area/src/config"
+	"area/src/database"
+	"area/src/security"
+	"area/src/workflows"
+)
+
+// secretRotation re-encrypts one kind of stored secret, returning how many rows changed.
+type secretRotation struct {
+	name string
+	run  func(context.Context) (int, error)
+}
+
+// runSecrets implements the secrets subcommand: reencrypt rewrites every stored secret with the
+// current key, after which retired keys can leave APP_PREVIOUS_SECRET_KEYS.
+func runSecrets(ctx context.Context, cfg config.Database, args []string, out io.Writer) error {
+	if len(args) != 1 || args[0] != "reencrypt" {
+		return fmt.Errorf("usage: secrets reencrypt")
+	}
+	if !security.Enabled() {
+		return fmt.Errorf("secrets reencrypt: APP_SECRET_KEY is not set")
+	}
+	database.Connect(cfg)
+	defer database.Disconnect()
+	if err := database.CheckMigrated(database.GetDB()); err != nil {
+		return err
+	}
+
+	rotations := []secretRotation{
+		{"workflow trigger configs", workflows.NewDefaultStore().ReencryptSecrets},
+		{"pending job and digest payloads", workflows.NewDefaultStore().ReencryptPayloads},
+		{"two-factor secrets", auth.NewDBStore().ReencryptSecrets},
+		{"OAuth connections", database.ReencryptOAuthTokens},
+		{"user secrets", database.ReencryptUserSecrets},
+	}
+	for _, rotation := range rotations {
+		changed, err := rotation.run(ctx)
+		if err != nil {
+			return fmt.Errorf("re-encrypt %s: %w", rotation.name, err)
+		}
+		fmt.Fprintf(out, "re-encrypted %d %s with key %s\n", changed, rotation.name, security.CurrentKeyID())
+	}
+	return nil
+}
@@ -5,31 +5,70 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
-	"os"
+	"regexp"
 	"strings"
 )
 
 const encryptedPrefix = "enc:"
 
+// DefaultKeyID identifies APP_SECRET_KEY when APP_SECRET_KEY_ID is not set.
+const DefaultKeyID = "v1"
+
+var validKeyID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// ErrUnknownKey is returned when decrypting a value tagged with a key ID missing from the keyring.
+var ErrUnknownKey = errors.New("ciphertext encrypted with an unknown key")
+
+// keyring holds the current encryption key and the previous ones still accepted for decryption.
+type keyring struct {
+	currentID string
+	keys      map[string][]byte
+	// order lists the key IDs, current first, for values carrying no key ID.
+	order []string
+}
+
+// configured is the keyring in use; nil disables encryption.
+var configured *keyring
+
 // Enabled reports whether encryption is configured.
 func Enabled() bool {
-	_, err := loadKey()
-	return err == nil
+	return configured != nil
 }
 
-// EncryptString encrypts a string with AES-GCM and returns a tagged ciphertext.
-func EncryptString(plain string) (string, error) {
-	key, err := loadKey()
-	if err != nil {
-		return "", err
+// CurrentKeyID returns the ID of the key new values are encrypted with, empty when encryption is
+// disabled.
+func CurrentKeyID() string {
+	if configured == nil {
+		return ""
 	}
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", err
+	return configured.currentID
+}
+
+// IsEncrypted reports whether value is a tagged ciphertext.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// KeyID returns the key ID of a tagged ciphertext: empty for values written before keys had IDs.
+func KeyID(cipherText string) string {
+	rest := strings.TrimPrefix(cipherText, encryptedPrefix)
+	// Base64 never holds a colon, so one ends a key ID.
+	if id, _, found := strings.Cut(rest, ":"); found {
+		return id
+	}
+	return ""
+}
+
+// EncryptString encrypts a string with AES-GCM under the current key and returns a ciphertext
+// tagged with its key ID, e.g. "enc:v2:...".
+func EncryptString(plain string) (string, error) {
+	if configured == nil {
+		return "", fmt.Errorf("missing APP_SECRET_KEY")
 	}
-	gcm, err := cipher.NewGCM(block)
+	gcm, err := newGCM(configured.keys[configured.currentID])
 	if err != nil {
 		return "", err
 	}
@@ -39,27 +78,71 @@ func EncryptString(plain string) (string, error) {
 	}
 	ciphertext := gcm.Seal(nil, nonce, []byte(plain), nil)
 	raw := append(nonce, ciphertext...)
-	return encryptedPrefix + base64.StdEncoding.EncodeToString(raw), nil
+	return encryptedPrefix + configured.currentID + ":" + base64.StdEncoding.EncodeToString(raw), nil
 }
 
-// DecryptString decrypts a tagged ciphertext and returns the plaintext.
+// DecryptString decrypts a tagged ciphertext with the key it names and returns the plaintext.
+// Values without a key ID are tried with every key of the keyring. Other strings are returned
+// as they are.
 func DecryptString(cipherText string) (string, error) {
-	if !strings.HasPrefix(cipherText, encryptedPrefix) {
+	if !IsEncrypted(cipherText) {
 		return cipherText, nil
 	}
-	key, err := loadKey()
+	if configured == nil {
+		return "", fmt.Errorf("missing APP_SECRET_KEY")
+	}
+	id := KeyID(cipherText)
+	encoded := strings.TrimPrefix(cipherText, encryptedPrefix)
+	if id != "" {
+		encoded = strings.TrimPrefix(encoded, id+":")
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
 		return "", err
 	}
-	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(cipherText, encryptedPrefix))
+	if id != "" {
+		key, ok := configured.keys[id]
+		if !ok {
+			return "", fmt.Errorf("%w %q", ErrUnknownKey, id)
+		}
+		return open(key, raw)
+	}
+	for _, id := range configured.order {
+		if plain, err := open(configured.keys[id], raw); err == nil {
+			return plain, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no key of the keyring opens it", ErrUnknownKey)
+}
+
+// Reencrypt returns value encrypted with the current key, and whether that changed it. Values
+// already tagged with the current key, and strings that are not encrypted, are left alone.
+func Reencrypt(value string) (string, bool, error) {
+	if !IsEncrypted(value) || configured == nil || KeyID(value) == configured.currentID {
+		return value, false, nil
+	}
+	plain, err := DecryptString(value)
 	if err != nil {
-		return "", err
+		return "", false, err
+	}
+	encrypted, err := EncryptString(plain)
+	if err != nil {
+		return "", false, err
 	}
+	return encrypted, true, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	gcm, err := cipher.NewGCM(block)
+	return cipher.NewGCM(block)
+}
+
+// open decrypts a nonce followed by an AES-GCM ciphertext.
+func open(key, raw []byte) (string, error) {
+	gcm, err := newGCM(key)
 	if err != nil {
 		return "", err
 	}
@@ -75,12 +158,56 @@ func DecryptString(cipherText string) (string, error) {
 	return string(plain), nil
 }
 
-// loadKey loads and decodes the encryption key from the environment variable.
-func loadKey() ([]byte, error) {
-	val := strings.TrimSpace(os.Getenv("APP_SECRET_KEY"))
-	if val == "" {
-		return nil, fmt.Errorf("missing APP_SECRET_KEY")
+// Configure sets the keyring: the current key from its raw setting (32 bytes, raw or base64,
+// optionally prefixed with "base64:") identified by id, DefaultKeyID when empty, and the
+// previous keys still accepted for decryption from a comma-separated list of id:key entries.
+// An empty current key disables encryption.
+func Configure(id, raw, previous string) error {
+	if strings.TrimSpace(raw) == "" {
+		if strings.TrimSpace(previous) != "" {
+			return fmt.Errorf("APP_PREVIOUS_SECRET_KEYS needs APP_SECRET_KEY")
+		}
+		configured = nil
+		return nil
 	}
+	id = strings.TrimSpace(id)
+	if id == "" {
+		id = DefaultKeyID
+	}
+	if !validKeyID.MatchString(id) {
+		return fmt.Errorf("APP_SECRET_KEY_ID: %q must be 1 to 32 letters, digits, _ or -", id)
+	}
+	current, err := parseKey(raw)
+	if err != nil {
+		return err
+	}
+	ring := &keyring{currentID: id, keys: map[string][]byte{id: current}, order: []string{id}}
+	for _, entry := range strings.Split(previous, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prevID, prevRaw, found := strings.Cut(entry, ":")
+		if !found || !validKeyID.MatchString(prevID) {
+			return fmt.Errorf("APP_PREVIOUS_SECRET_KEYS: entries must be id:key")
+		}
+		if _, dup := ring.keys[prevID]; dup {
+			return fmt.Errorf("APP_PREVIOUS_SECRET_KEYS: key ID %q is used twice", prevID)
+		}
+		key, err := parseKey(prevRaw)
+		if err != nil {
+			return fmt.Errorf("APP_PREVIOUS_SECRET_KEYS: key %q: %w", prevID, err)
+		}
+		ring.keys[prevID] = key
+		ring.order = append(ring.order, prevID)
+	}
+	configured = ring
+	return nil
+}
+
+// parseKey decodes an encryption key setting.
+func parseKey(raw string) ([]byte, error) {
+	val := strings.TrimSpace(raw)
 	if strings.HasPrefix(val, "base64:") {
 		decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(val, "base64:"))
 		if err != nil {
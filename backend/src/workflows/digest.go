@@ -0,0 +1,259 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"area/src/metrics"
+)
+
+// maxDigestLines caps how many events are listed in a rendered digest summary.
+const maxDigestLines = 50
+
+// DigestConfig batches trigger events into periodic summaries instead of one run per event.
+type DigestConfig struct {
+	EveryMinutes int    `json:"every_minutes,omitempty"`
+	Cron         string `json:"cron,omitempty"`
+	Title        string `json:"title,omitempty"`
+}
+
+// digestTriggers lists the high-volume trigger types that can run in digest mode.
+var digestTriggers = map[string]struct{}{
+	"reddit_new_post":     {},
+	"youtube_new_video":   {},
+	"github_commit":       {},
+	"github_pull_request": {},
+	"github_issue":        {},
+	"gmail_inbound":       {},
+}
+
+// SupportsDigest reports whether a trigger type can run in digest mode.
+func SupportsDigest(triggerType string) bool {
+	_, ok := digestTriggers[triggerType]
+	return ok
+}
+
+// DigestConfigFromJSON extracts the optional "digest" section of a trigger config (nil when absent).
+func DigestConfigFromJSON(raw json.RawMessage) (*DigestConfig, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var wrapper struct {
+		Digest *DigestConfig `json:"digest"`
+	}
+	if err := json.Unmarshal(raw, &wrapper); err != nil {
+		return nil, err
+	}
+	return wrapper.Digest, nil
+}
+
+// validateDigestConfig checks the digest section of a trigger config, if any, for the given trigger type.
+func validateDigestConfig(triggerType string, raw json.RawMessage) error {
+	cfg, err := DigestConfigFromJSON(raw)
+	if err != nil {
+		return errors.New("digest config is invalid")
+	}
+	if cfg == nil {
+		return nil
+	}
+	if !SupportsDigest(triggerType) {
+		return fmt.Errorf("%s does not support digest mode", triggerType)
+	}
+	hasCron := strings.TrimSpace(cfg.Cron) != ""
+	switch {
+	case cfg.EveryMinutes < 0:
+		return errors.New("digest every_minutes must be > 0")
+	case cfg.EveryMinutes > 0 && hasCron:
+		return errors.New("digest accepts either every_minutes or cron, not both")
+	case cfg.EveryMinutes == 0 && !hasCron:
+		return errors.New("digest requires every_minutes or cron")
+	}
+	if hasCron {
+		if _, err := ParseCron(cfg.Cron); err != nil {
+			return fmt.Errorf("digest %v", err)
+		}
+	}
+	return nil
+}
+
+// DueAt returns when a digest whose oldest buffered event arrived at oldest should be flushed.
+func (c *DigestConfig) DueAt(oldest time.Time) (time.Time, error) {
+	if c.EveryMinutes > 0 {
+		return oldest.Add(time.Duration(c.EveryMinutes) * time.Minute), nil
+	}
+	sched, err := ParseCron(c.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return sched.Next(oldest), nil
+}
+
+// BuildDigestPayload merges buffered events into a single job payload with an items array and a summary content.
+func BuildDigestPayload(cfg *DigestConfig, template map[string]any, items []json.RawMessage) map[string]any {
+	payload := make(map[string]any, len(template)+3)
+	for k, v := range template {
+		payload[k] = v
+	}
+
+	decoded := make([]any, 0, len(items))
+	lines := make([]string, 0, len(items))
+	for _, raw := range items {
+		var item map[string]any
+		if err := json.Unmarshal(raw, &item); err != nil {
+			continue
+		}
+		lines = append(lines, digestLine(item))
+		// Reaction settings live at the top level; keep each item down to its event data.
+		for k := range template {
+			delete(item, k)
+		}
+		decoded = append(decoded, item)
+	}
+
+	title := ""
+	if cfg != nil {
+		title = strings.TrimSpace(cfg.Title)
+	}
+	if title == "" {
+		noun := "events"
+		if len(decoded) == 1 {
+			noun = "event"
+		}
+		title = fmt.Sprintf("%d new %s", len(decoded), noun)
+	}
+
+	var b strings.Builder
+	b.WriteString(title)
+	for i, line := range lines {
+		if i == maxDigestLines {
+			fmt.Fprintf(&b, "\n… and %d more", len(lines)-maxDigestLines)
+			break
+		}
+		b.WriteString("\n- ")
+		b.WriteString(line)
+	}
+
+	payload["items"] = decoded
+	payload["count"] = len(decoded)
+	payload["content"] = b.String()
+	return payload
+}
+
+// digestLine picks the most readable one-line description of a buffered event.
+func digestLine(item map[string]any) string {
+	for _, key := range []string{"content", "title", "subject", "message"} {
+		if s, ok := item[key].(string); ok && strings.TrimSpace(s) != "" {
+			return strings.ReplaceAll(strings.TrimSpace(s), "\n", " ")
+		}
+	}
+	encoded, _ := json.Marshal(item)
+	return string(encoded)
+}
+
+// payloadTemplateFromJSON extracts the optional payload_template of a trigger config.
+func payloadTemplateFromJSON(raw json.RawMessage) map[string]any {
+	if len(raw) == 0 {
+		return nil
+	}
+	var cfg struct {
+		PayloadTemplate map[string]any `json:"payload_template"`
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil
+	}
+	return cfg.PayloadTemplate
+}
+
+// DigestFlusher periodically turns buffered digest events into regular runs.
+type DigestFlusher struct {
+	store     WorkflowStore
+	triggerer *Triggerer
+	interval  time.Duration
+}
+
+// NewDigestFlusher constructs a DigestFlusher that checks for due digests at the given interval.
+func NewDigestFlusher(store WorkflowStore, triggerer *Triggerer, interval time.Duration) *DigestFlusher {
+	return &DigestFlusher{
+		store:     store,
+		triggerer: triggerer,
+		interval:  interval,
+	}
+}
+
+// RunLoop flushes due digests until ctx is canceled.
+func (f *DigestFlusher) RunLoop(ctx context.Context) {
+	t := time.NewTicker(f.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("digest flusher stopped", "reason", ctx.Err())
+			return
+		case now := <-t.C:
+			if _, err := f.FlushDue(ctx, now); err != nil {
+				slog.Error("digest flusher: flush", "error", err)
+			}
+		}
+	}
+}
+
+// FlushDue enqueues one run per workflow whose digest is due at now and returns how many were flushed.
+func (f *DigestFlusher) FlushDue(ctx context.Context, now time.Time) (int, error) {
+	pending, err := f.store.ListPendingDigests(ctx)
+	if err != nil {
+		return 0, err
+	}
+	flushed := 0
+	for _, p := range pending {
+		wf, err := f.store.GetWorkflow(ctx, p.WorkflowID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// The workflow is gone: drop its buffer so it does not linger forever.
+			if _, claimErr := f.store.ClaimDigestItems(ctx, p.WorkflowID); claimErr != nil {
+				slog.ErrorContext(ctx, "digest flusher: drop items", "workflow_id", p.WorkflowID, "error", claimErr)
+			}
+			continue
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "digest flusher: get workflow", "workflow_id", p.WorkflowID, "error", err)
+			continue
+		}
+		if !wf.Enabled {
+			// Keep the events for when the workflow is enabled again.
+			continue
+		}
+		cfg, err := DigestConfigFromJSON(wf.TriggerConfig)
+		if err != nil {
+			cfg = nil
+		}
+		if cfg != nil {
+			due, err := cfg.DueAt(p.OldestAt)
+			if err != nil {
+				slog.ErrorContext(ctx, "digest flusher: due time", "workflow_id", wf.ID, "error", err)
+			} else if now.Before(due) {
+				continue
+			}
+		}
+		template := payloadTemplateFromJSON(wf.TriggerConfig)
+		run, err := f.triggerer.EnqueueDigest(ctx, wf.ID, func(items []json.RawMessage) map[string]any {
+			return BuildDigestPayload(cfg, template, items)
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "digest flusher: enqueue", "workflow_id", wf.ID, "error", err)
+			continue
+		}
+		if run == nil {
+			continue
+		}
+		metrics.RunsTotal.Inc(wf.TriggerType)
+		flushed++
+	}
+	return flushed, nil
+}
@@ -0,0 +1,278 @@
+package workflows
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"area/src/database"
+)
+
+// Event types pushed to clients.
+const (
+	EventRunCreated       = "run.created"
+	EventRunStarted       = "run.started"
+	EventRunSucceeded     = "run.succeeded"
+	EventRunFailed        = "run.failed"
+	EventRunCancelled     = "run.cancelled"
+	EventJobSucceeded     = "job.succeeded"
+	EventJobFailed        = "job.failed"
+	EventWorkflowEnabled  = "workflow.enabled"
+	EventWorkflowDisabled = "workflow.disabled"
+)
+
+// eventBufferSize is how many events a slow subscriber may lag behind before events are dropped.
+const eventBufferSize = 64
+
+// Event describes a change to a user's workflows or runs.
+type Event struct {
+	Type       string    `json:"type"`
+	UserID     int64     `json:"-"`
+	WorkflowID int64     `json:"workflow_id"`
+	RunID      int64     `json:"run_id,omitempty"`
+	JobID      int64     `json:"job_id,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// EventBus fans out events to the subscribers of the event's user.
+// Publishing never blocks on subscribers: one whose buffer is full misses the event.
+// Alone the bus lives in one process; Relay shares it with the other replicas.
+type EventBus struct {
+	mu     sync.RWMutex
+	subs   map[int64]map[chan Event]struct{}
+	closed bool
+	relay  EventRelay
+}
+
+// NewEventBus constructs an empty EventBus.
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[int64]map[chan Event]struct{})}
+}
+
+// Subscribe registers a listener for a user's events until the returned cancel is called.
+// The channel is closed on cancel or when the bus is closed.
+func (b *EventBus) Subscribe(userID int64) (<-chan Event, func()) {
+	ch := make(chan Event, eventBufferSize)
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	if b.subs[userID] == nil {
+		b.subs[userID] = make(map[chan Event]struct{})
+	}
+	b.subs[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, ok := b.subs[userID][ch]; !ok {
+				return // already closed by Close
+			}
+			delete(b.subs[userID], ch)
+			if len(b.subs[userID]) == 0 {
+				delete(b.subs, userID)
+			}
+			close(ch)
+		})
+	}
+}
+
+// Close ends every subscription, e.g. so event streams let the server shut down.
+func (b *EventBus) Close() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for _, chans := range b.subs {
+		for ch := range chans {
+			close(ch)
+		}
+	}
+	b.subs = make(map[int64]map[chan Event]struct{})
+}
+
+// Active reports whether anyone may be listening, so publishers can skip lookups otherwise.
+// A relayed bus is always active, as subscribers of other replicas are not known here.
+func (b *EventBus) Active() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.relay != nil || len(b.subs) > 0
+}
+
+// Publish delivers an event to the subscribers of its user.
+func (b *EventBus) Publish(ev Event) {
+	b.publishTo(ev, []int64{ev.UserID})
+}
+
+// publishTo delivers a copy of ev to the subscribers of each of userIDs, on every replica when
+// the bus is relayed. Should the relay fail, the local subscribers still get it.
+func (b *EventBus) publishTo(ev Event, userIDs []int64) {
+	if b == nil {
+		return
+	}
+	if ev.At.IsZero() {
+		ev.At = time.Now()
+	}
+	b.mu.RLock()
+	relay := b.relay
+	b.mu.RUnlock()
+	if relay != nil {
+		err := b.send(relay, ev, userIDs)
+		if err == nil {
+			return
+		}
+		slog.Warn("event relay: notify", "type", ev.Type, "error", err)
+	}
+	b.deliver(ev, userIDs)
+}
+
+// deliver hands a copy of ev to the local subscribers of each of userIDs.
+func (b *EventBus) deliver(ev Event, userIDs []int64) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, userID := range userIDs {
+		ev.UserID = userID
+		for ch := range b.subs[userID] {
+			select {
+			case ch <- ev:
+			default:
+			}
+		}
+	}
+}
+
+// listeners returns the users with at least one subscription.
+func (b *EventBus) listeners() []int64 {
+	if b == nil {
+		return nil
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	users := make([]int64, 0, len(b.subs))
+	for userID := range b.subs {
+		users = append(users, userID)
+	}
+	return users
+}
+
+// runEventType maps a run status to the event announcing it.
+func runEventType(status string) string {
+	switch status {
+	case RunStatusPending:
+		return EventRunCreated
+	case RunStatusRunning:
+		return EventRunStarted
+	case RunStatusSucceeded:
+		return EventRunSucceeded
+	case RunStatusFailed:
+		return EventRunFailed
+	case RunStatusCancelled:
+		return EventRunCancelled
+	default:
+		return ""
+	}
+}
+
+// audience returns who sees the events of a workflow: the members of its workspace, or its
+// creator when it is personal.
+func (s *Store) audience(ctx context.Context, userID int64, workspaceID *uint) []int64 {
+	if workspaceID == nil {
+		return []int64{userID}
+	}
+	var members []int64
+	if err := s.db.WithContext(ctx).Model(&database.WorkspaceMember{}).
+		Where("workspace_id = ?", *workspaceID).
+		Pluck("user_id", &members).Error; err != nil {
+		return nil
+	}
+	return members
+}
+
+// publishWorkflowEvent announces a workflow change to the audience of the workflow.
+func (s *Store) publishWorkflowEvent(ctx context.Context, workflowID int64, eventType string) {
+	if !s.Events.Active() {
+		return
+	}
+	var owner struct {
+		UserID      int64
+		WorkspaceID *uint
+	}
+	err := s.db.WithContext(ctx).Model(&database.Workflow{}).
+		Select("user_id, workspace_id").
+		Where("id = ?", uint(workflowID)).
+		Scan(&owner).Error
+	if err != nil || owner.UserID == 0 {
+		return
+	}
+	s.Events.publishTo(Event{Type: eventType, WorkflowID: workflowID}, s.audience(ctx, owner.UserID, owner.WorkspaceID))
+}
+
+// publishRunEvent announces a run status change to the audience of the run's workflow.
+func (s *Store) publishRunEvent(ctx context.Context, runID int64, status, errMsg string) {
+	eventType := runEventType(status)
+	if eventType == "" || !s.Events.Active() {
+		return
+	}
+	var owner struct {
+		WorkflowID  uint
+		UserID      int64
+		WorkspaceID *uint
+	}
+	err := s.db.WithContext(ctx).Model(&database.Run{}).
+		Select("workflow_runs.workflow_id, workflows.user_id, workflows.workspace_id").
+		Joins("JOIN workflows ON workflows.id = workflow_runs.workflow_id").
+		Where("workflow_runs.id = ?", uint(runID)).
+		Scan(&owner).Error
+	if err != nil || owner.WorkflowID == 0 {
+		return
+	}
+	s.Events.publishTo(Event{
+		Type:       eventType,
+		WorkflowID: int64(owner.WorkflowID),
+		RunID:      runID,
+		Status:     status,
+		Error:      errMsg,
+	}, s.audience(ctx, owner.UserID, owner.WorkspaceID))
+}
+
+// publishJobEvent announces a finished job to the audience of its workflow.
+func (s *Store) publishJobEvent(ctx context.Context, jobID int64, eventType, status, errMsg string) {
+	if !s.Events.Active() {
+		return
+	}
+	var owner struct {
+		WorkflowID  uint
+		RunID       uint
+		UserID      int64
+		WorkspaceID *uint
+	}
+	err := s.db.WithContext(ctx).Model(&database.Job{}).
+		Select("jobs.workflow_id, jobs.run_id, workflows.user_id, workflows.workspace_id").
+		Joins("JOIN workflows ON workflows.id = jobs.workflow_id").
+		Where("jobs.id = ?", uint(jobID)).
+		Scan(&owner).Error
+	if err != nil || owner.WorkflowID == 0 {
+		return
+	}
+	s.Events.publishTo(Event{
+		Type:       eventType,
+		WorkflowID: int64(owner.WorkflowID),
+		RunID:      int64(owner.RunID),
+		JobID:      jobID,
+		Status:     status,
+		Error:      errMsg,
+	}, s.audience(ctx, owner.UserID, owner.WorkspaceID))
+}
@@ -4,57 +4,173 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"area/src/logging"
+	"area/src/metrics"
+
 	"gorm.io/gorm"
 )
 
 // OutboundSender is implemented by integrations that can send actions (webhooks, email, etc.).
+// ctx carries the user of the workflow, see UserIDFromContext. The result may be non-nil even
+// when err is set, e.g. for a non-2xx response.
 type OutboundSender interface {
-	Send(ctx context.Context, url string, payload []byte) error
+	Send(ctx context.Context, url string, payload []byte) (*SendResult, error)
+}
+
+// SendResult is what an OutboundSender got back from the reaction target.
+type SendResult struct {
+	StatusCode int
+	Body       []byte
 }
 
+const (
+	// DefaultJobTimeout applies to workflows without a timeout_seconds setting.
+	DefaultJobTimeout = 15 * time.Second
+	// DefaultMaxJobTimeout caps timeout_seconds unless the server overrides it.
+	DefaultMaxJobTimeout = 2 * time.Minute
+	// maxResponseBodyBytes caps the reaction response body stored on a job.
+	maxResponseBodyBytes = 2048
+)
+
+// ErrRunCancelled is the cancellation cause of sends interrupted by a run cancellation.
+var ErrRunCancelled = errors.New("run cancelled")
+
+// ErrExecutorShutdown is the cancellation cause of sends interrupted because the shutdown grace period ran out.
+var ErrExecutorShutdown = errors.New("executor shutting down")
+
+// staleJobMargin is added to MaxTimeout before a processing job is considered abandoned.
+const staleJobMargin = time.Minute
+
 // Executor pulls pending jobs from the store and executes them via an outbound sender.
 type Executor struct {
-	store    *Store
+	store    WorkflowStore
 	sender   OutboundSender
 	interval time.Duration
+
+	// DefaultTimeout and MaxTimeout bound how long a single reaction call may take.
+	DefaultTimeout time.Duration
+	MaxTimeout     time.Duration
+	// Secrets resolves the {{secrets.<name>}} references of payloads; without it they fail.
+	Secrets SecretResolver
+
+	mu       sync.Mutex
+	inFlight map[int64]context.CancelCauseFunc // run id -> cancel of the in-flight send
+	aborting bool                              // set once Shutdown gave up waiting
+
+	loops sync.WaitGroup // running RunLoop calls
 }
 
 // NewExecutor constructs an Executor that polls at the given interval.
-func NewExecutor(store *Store, sender OutboundSender, interval time.Duration) *Executor {
+func NewExecutor(store WorkflowStore, sender OutboundSender, interval time.Duration) *Executor {
 	return &Executor{
 		store:    store,
 		sender:   sender,
 		interval: interval,
+		inFlight: make(map[int64]context.CancelCauseFunc),
+
+		DefaultTimeout: DefaultJobTimeout,
+		MaxTimeout:     DefaultMaxJobTimeout,
 	}
 }
 
-// RunLoop polls for jobs until ctx is canceled.
+// CancelRun interrupts the in-flight send of a run, if this executor is processing it. It only
+// speeds things up: the run is cancelled in the store, and the executor processing it, on this
+// replica or another, notices within its polling interval.
+func (e *Executor) CancelRun(runID int64) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	cancel, ok := e.inFlight[runID]
+	if ok {
+		cancel(ErrRunCancelled)
+	}
+	return ok
+}
+
+// track registers the cancel function of a run's send until the returned release is called.
+func (e *Executor) track(runID int64, cancel context.CancelCauseFunc) (release func()) {
+	e.mu.Lock()
+	e.inFlight[runID] = cancel
+	if e.aborting {
+		cancel(ErrExecutorShutdown)
+	}
+	e.mu.Unlock()
+	return func() {
+		e.mu.Lock()
+		delete(e.inFlight, runID)
+		e.mu.Unlock()
+	}
+}
+
+// Shutdown waits for RunLoop to return once its context is cancelled, letting the claimed job
+// finish. If ctx expires first, the in-flight send is interrupted and its job released back to
+// the queue for another executor to pick up.
+func (e *Executor) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		e.loops.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	e.mu.Lock()
+	e.aborting = true
+	for _, cancel := range e.inFlight {
+		cancel(ErrExecutorShutdown)
+	}
+	e.mu.Unlock()
+	<-done
+	return ctx.Err()
+}
+
+// RunLoop polls for jobs until ctx is canceled. A job claimed before that keeps running; see Shutdown.
 func (e *Executor) RunLoop(ctx context.Context) {
+	e.loops.Add(1)
+	defer e.loops.Done()
 	t := time.NewTicker(e.interval)
 	defer t.Stop()
 
+	e.requeueStale(ctx)
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("executor: stop:", ctx.Err())
+			slog.Info("executor stopped", "reason", ctx.Err())
 			return
 		default:
-			e.processOne(ctx)
+			e.processOne(context.WithoutCancel(ctx))
 		}
 
 		select {
 		case <-ctx.Done():
-			log.Println("executor: stop:", ctx.Err())
+			slog.Info("executor stopped", "reason", ctx.Err())
 			return
 		case <-t.C:
 		}
 	}
 }
 
+// requeueStale releases jobs left in processing by an executor that died without releasing them.
+// A job cannot legitimately run longer than MaxTimeout, so older ones are abandoned.
+func (e *Executor) requeueStale(ctx context.Context) {
+	n, err := e.store.RequeueStaleJobs(ctx, time.Now().Add(-e.MaxTimeout-staleJobMargin))
+	if err != nil {
+		slog.ErrorContext(ctx, "executor: requeue stale jobs", "error", err)
+		return
+	}
+	if n > 0 {
+		slog.InfoContext(ctx, "executor: requeued stale jobs", "count", n)
+	}
+}
+
 // processOne fetches and executes the next pending job if available.
 func (e *Executor) processOne(ctx context.Context) {
 	job, err := e.store.FetchNextPendingJob(ctx)
@@ -62,22 +178,35 @@ func (e *Executor) processOne(ctx context.Context) {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return
 		}
-		log.Println("executor: fetch job:", err)
+		slog.ErrorContext(ctx, "executor: fetch job", "error", err)
 		return
 	}
+	// Tag every log line of this job with its IDs and the request that enqueued it.
+	ctx = logging.WithRequestID(ctx, job.RequestID)
+	ctx = logging.With(ctx, "job_id", job.ID, "run_id", job.RunID, "workflow_id", job.WorkflowID)
 
 	wf, err := e.store.GetWorkflow(ctx, job.WorkflowID)
 	if err != nil {
-		log.Printf("executor: workflow %d missing for job %d: %v", job.WorkflowID, job.ID, err)
-		_ = e.store.MarkJobFailed(ctx, job.ID, "workflow missing")
+		slog.ErrorContext(ctx, "executor: workflow missing", "error", err)
+		metrics.JobsTotal.Inc("claimed", "unknown")
+		metrics.JobsTotal.Inc("failed", "unknown")
+		_ = e.store.MarkJobFailed(ctx, job.ID, "workflow missing", nil)
 		return
 	}
+	host := metrics.HostOf(wf.ActionURL)
+	metrics.JobsTotal.Inc("claimed", host)
+	ctx = logging.With(ctx, "trigger_type", wf.TriggerType)
 
 	started := time.Now()
-	_ = e.store.UpdateRun(ctx, job.RunID, RunUpdate{
+	if err := e.store.UpdateRun(ctx, job.RunID, RunUpdate{
 		Status:    RunStatusRunning,
 		StartedAt: &started,
-	})
+	}); errors.Is(err, ErrRunCancelled) {
+		// Cancelled between the claim and now: the store already marked the job cancelled.
+		slog.InfoContext(ctx, "executor: job cancelled")
+		metrics.JobsTotal.Inc("cancelled", host)
+		return
+	}
 
 	payload := job.Payload
 	if len(payload) == 0 {
@@ -85,32 +214,177 @@ func (e *Executor) processOne(ctx context.Context) {
 	}
 	payload = normalizeReactionPayload(payload, wf.ActionURL)
 	payload = decryptPayload(payload)
+	payload, err = resolveSecrets(ctx, e.Secrets, wf, payload)
+	if err != nil {
+		metrics.JobsTotal.Inc("failed", host)
+		slog.WarnContext(ctx, "executor: resolve secrets", "error", err)
+		e.fail(ctx, job, err.Error(), nil)
+		return
+	}
 
-	actionCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	cancelCtx, cancelSend := context.WithCancelCause(ctx)
+	defer cancelSend(nil)
+	release := e.track(job.RunID, cancelSend)
+	defer release()
+	actionCtx, cancel := context.WithTimeout(cancelCtx, e.timeoutFor(wf))
 	defer cancel()
+	watchCtx, stopWatch := context.WithCancel(actionCtx)
+	go e.watchCancellation(watchCtx, job.RunID, cancelSend)
 
-	if err := e.sender.Send(actionCtx, wf.ActionURL, payload); err != nil {
-		log.Printf("executor: job %d failed: %v", job.ID, err)
-		_ = e.store.MarkJobFailed(ctx, job.ID, err.Error())
-		failed := time.Now()
-		msg := err.Error()
-		_ = e.store.UpdateRun(ctx, job.RunID, RunUpdate{
-			Status:  RunStatusFailed,
-			EndedAt: &failed,
-			Error:   &msg,
-		})
+	sendStart := time.Now()
+	result, err := e.sender.Send(WithUserID(actionCtx, wf.UserID), wf.ActionURL, payload)
+	stopWatch()
+	resp := newJobResponse(result, time.Since(sendStart))
+	metrics.JobDuration.Observe(resp.Latency.Seconds(), host, statusLabel(result))
+	if err != nil {
+		if errors.Is(context.Cause(cancelCtx), ErrRunCancelled) {
+			// The store already marked the run and job cancelled.
+			slog.InfoContext(ctx, "executor: job cancelled")
+			metrics.JobsTotal.Inc("cancelled", host)
+			return
+		}
+		if errors.Is(context.Cause(cancelCtx), ErrExecutorShutdown) {
+			slog.WarnContext(ctx, "executor: job released on shutdown")
+			metrics.JobsTotal.Inc("released", host)
+			if err := e.store.ReleaseJob(ctx, job.ID, job.RunID); err != nil {
+				slog.ErrorContext(ctx, "executor: release job", "error", err)
+			}
+			return
+		}
+		metrics.JobsTotal.Inc("failed", host)
+		slog.WarnContext(ctx, "executor: job failed", "error", err, "status", resp.StatusCode, "latency_ms", resp.Latency.Milliseconds())
+		e.fail(ctx, job, err.Error(), resp)
 		return
 	}
 
-	if err := e.store.MarkJobSuccess(ctx, job.ID); err != nil {
-		log.Printf("executor: mark success job %d: %v", job.ID, err)
+	if err := e.store.MarkJobSuccess(ctx, job.ID, resp); errors.Is(err, ErrRunCancelled) {
+		slog.InfoContext(ctx, "executor: job cancelled during send")
+		metrics.JobsTotal.Inc("cancelled", host)
+		return
+	} else if err != nil {
+		slog.ErrorContext(ctx, "executor: mark job succeeded", "error", err)
 	}
+	metrics.JobsTotal.Inc("succeeded", host)
 	ended := time.Now()
 	_ = e.store.UpdateRun(ctx, job.RunID, RunUpdate{
 		Status:  RunStatusSucceeded,
 		EndedAt: &ended,
 	})
-	log.Printf("executor: job %d succeeded (workflow %d)", job.ID, job.WorkflowID)
+	slog.InfoContext(ctx, "executor: job succeeded", "status", resp.StatusCode, "latency_ms", resp.Latency.Milliseconds())
+}
+
+// watchCancellation polls the status of a run while its send is in flight, until ctx is done,
+// and interrupts the send once the run is cancelled.
+func (e *Executor) watchCancellation(ctx context.Context, runID int64, cancel context.CancelCauseFunc) {
+	interval := e.interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		status, err := e.store.RunStatus(ctx, runID)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.WarnContext(ctx, "executor: read run status", "error", err)
+			}
+			continue
+		}
+		if status == RunStatusCancelled {
+			cancel(ErrRunCancelled)
+			return
+		}
+	}
+}
+
+// fail marks a job and its run failed with reason, recording resp when non-nil. A job cancelled
+// meanwhile stays cancelled.
+func (e *Executor) fail(ctx context.Context, job *Job, reason string, resp *JobResponse) {
+	if err := e.store.MarkJobFailed(ctx, job.ID, reason, resp); errors.Is(err, ErrRunCancelled) {
+		return
+	}
+	failed := time.Now()
+	_ = e.store.UpdateRun(ctx, job.RunID, RunUpdate{
+		Status:  RunStatusFailed,
+		EndedAt: &failed,
+		Error:   &reason,
+	})
+}
+
+// timeoutFor returns the reaction timeout of a workflow, clamped to the server maximum.
+func (e *Executor) timeoutFor(wf *Workflow) time.Duration {
+	timeout := e.DefaultTimeout
+	if configured := TimeoutFromJSON(wf.TriggerConfig); configured > 0 {
+		timeout = configured
+	}
+	if e.MaxTimeout > 0 && timeout > e.MaxTimeout {
+		timeout = e.MaxTimeout
+	}
+	return timeout
+}
+
+// TimeoutFromJSON reads the optional timeout_seconds of a trigger config (0 when absent).
+func TimeoutFromJSON(raw json.RawMessage) time.Duration {
+	if len(raw) == 0 {
+		return 0
+	}
+	var cfg struct {
+		TimeoutSeconds int `json:"timeout_seconds"`
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil || cfg.TimeoutSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(cfg.TimeoutSeconds) * time.Second
+}
+
+// validateTimeoutConfig rejects a timeout_seconds that is not a positive integer.
+func validateTimeoutConfig(raw json.RawMessage) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	var cfg struct {
+		TimeoutSeconds *json.Number `json:"timeout_seconds"`
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil || cfg.TimeoutSeconds == nil {
+		return nil
+	}
+	if n, err := cfg.TimeoutSeconds.Int64(); err != nil || n <= 0 {
+		return errors.New("timeout_seconds must be a positive integer")
+	}
+	return nil
+}
+
+// statusLabel is the metrics label for a reaction call's HTTP status ("error" without a response).
+func statusLabel(result *SendResult) string {
+	if result == nil || result.StatusCode == 0 {
+		return "error"
+	}
+	return strconv.Itoa(result.StatusCode)
+}
+
+// newJobResponse builds the stored outcome of a reaction call, truncating its body.
+func newJobResponse(result *SendResult, latency time.Duration) *JobResponse {
+	resp := &JobResponse{Latency: latency}
+	if result == nil {
+		return resp
+	}
+	resp.StatusCode = result.StatusCode
+	body := result.Body
+	truncated := len(body) > maxResponseBodyBytes
+	if truncated {
+		body = body[:maxResponseBodyBytes]
+	}
+	// Postgres text columns reject NUL bytes and invalid UTF-8.
+	resp.Body = strings.ToValidUTF8(strings.ReplaceAll(string(body), "\x00", ""), "")
+	if truncated {
+		resp.Body += "…"
+	}
+	return resp
 }
 
 // DecodePayload helper for handlers to decode job payload into a typed struct.
@@ -0,0 +1,52 @@
+package workflows
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"area/src/database"
+
+	"gorm.io/gorm/clause"
+)
+
+// replicaID identifies this process as a lease holder.
+var replicaID = newReplicaID()
+
+func newReplicaID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "area"
+	}
+	var b [4]byte
+	_, _ = rand.Read(b[:])
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b[:]))
+}
+
+// AcquireLease takes or renews the named lease for holder until now+ttl. It succeeds when the
+// lease is free, expired, or already held by holder, and reports whether holder owns it.
+func (s *Store) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration, now time.Time) (bool, error) {
+	lease := database.Lease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl)}
+	result := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"holder", "expires_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "leases.holder = ? OR leases.expires_at < ?", Vars: []interface{}{holder, now}},
+		}},
+	}).Create(&lease)
+	if result.Error != nil {
+		return false, fmt.Errorf("acquire lease: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ReleaseLease gives up the named lease if holder owns it, so another replica can take over at once.
+func (s *Store) ReleaseLease(ctx context.Context, name, holder string) error {
+	if err := s.db.WithContext(ctx).Where("name = ? AND holder = ?", name, holder).Delete(&database.Lease{}).Error; err != nil {
+		return fmt.Errorf("release lease: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,796 @@
+package workflows
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"area/src/logging"
+
+	"gorm.io/gorm"
+)
+
+// MemoryStore is a WorkflowStore keeping everything in process memory, safe for concurrent use.
+// It mirrors the behaviour of Store, errors included, and loses its content on restart.
+type MemoryStore struct {
+	mu     sync.Mutex
+	nextID int64
+
+	workflows map[int64]*Workflow
+	// owners maps workflow IDs to their user and survives deletion, like the database join
+	// used to address events of runs whose workflow is gone.
+	owners map[int64]int64
+	// workspaceOf maps the IDs of workspace workflows to their workspace, surviving deletion too.
+	workspaceOf map[int64]int64
+	runs        map[int64]*Run
+	jobs        map[int64]*Job
+	digests     []memoryDigestItem
+	states      map[string]json.RawMessage
+	leases      map[string]memoryLease
+
+	// Events, when set, receives run, job and workflow state changes.
+	Events *EventBus
+	// Workspaces, when set, tells the workspace roles of users; without it nobody belongs to a
+	// workspace.
+	Workspaces WorkspaceRoles
+}
+
+// WorkspaceRoles tells the role of a user by the ID of each workspace they belong to.
+type WorkspaceRoles interface {
+	WorkspaceRoles(ctx context.Context, userID int64) (map[int64]string, error)
+}
+
+type memoryDigestItem struct {
+	workflowID int64
+	payload    json.RawMessage
+	createdAt  time.Time
+}
+
+type memoryLease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+// NewMemoryStore constructs an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		workflows:   make(map[int64]*Workflow),
+		owners:      make(map[int64]int64),
+		workspaceOf: make(map[int64]int64),
+		runs:        make(map[int64]*Run),
+		jobs:        make(map[int64]*Job),
+		states:      make(map[string]json.RawMessage),
+		leases:      make(map[string]memoryLease),
+	}
+}
+
+// EventBus returns m.Events.
+func (m *MemoryStore) EventBus() *EventBus {
+	return m.Events
+}
+
+// id returns the next identifier; IDs are unique across kinds of records.
+func (m *MemoryStore) id() int64 {
+	m.nextID++
+	return m.nextID
+}
+
+// visibleWorkflow returns a copy of wf as the store reports it: interval workflows without a
+// next run show as disabled.
+func visibleWorkflow(wf *Workflow) Workflow {
+	out := *wf
+	if out.TriggerType == "interval" && out.Enabled && out.NextRunAt == nil {
+		out.Enabled = false
+	}
+	return out
+}
+
+// workspaceRoles returns the roles of a user from m.Workspaces; m.mu must not be held.
+func (m *MemoryStore) workspaceRoles(ctx context.Context, userID int64) (map[int64]string, error) {
+	if m.Workspaces == nil {
+		return nil, nil
+	}
+	return m.Workspaces.WorkspaceRoles(ctx, userID)
+}
+
+// canRead reports whether a user with the given workspace roles can read workflow id: their
+// personal workflows and those of their workspaces. m.mu is held.
+func (m *MemoryStore) canRead(id, userID int64, roles map[int64]string) bool {
+	if workspaceID, ok := m.workspaceOf[id]; ok {
+		_, member := roles[workspaceID]
+		return member
+	}
+	return m.owners[id] == userID
+}
+
+// canChange is canRead restricted to the workspace roles allowed to change workflows. m.mu is held.
+func (m *MemoryStore) canChange(id, userID int64, roles map[int64]string) bool {
+	if workspaceID, ok := m.workspaceOf[id]; ok {
+		return canEdit(roles[workspaceID])
+	}
+	return m.owners[id] == userID
+}
+
+// changeable returns workflow id when the user can change it, sql.ErrNoRows when they cannot read
+// it and ErrWorkflowReadOnly when they can only read it. m.mu is held.
+func (m *MemoryStore) changeable(id, userID int64, roles map[int64]string) (*Workflow, error) {
+	wf, ok := m.workflows[id]
+	switch {
+	case !ok || !m.canRead(id, userID, roles):
+		return nil, sql.ErrNoRows
+	case !m.canChange(id, userID, roles):
+		return nil, ErrWorkflowReadOnly
+	}
+	return wf, nil
+}
+
+// WorkspaceRole returns the role of a user in a workspace, empty when they do not belong to it.
+func (m *MemoryStore) WorkspaceRole(ctx context.Context, workspaceID, userID int64) (string, error) {
+	roles, err := m.workspaceRoles(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return roles[workspaceID], nil
+}
+
+// sortedWorkflows returns the workflows matching keep, newest first.
+func (m *MemoryStore) sortedWorkflows(keep func(*Workflow) bool) []Workflow {
+	out := []Workflow{}
+	for _, wf := range m.workflows {
+		if keep(wf) {
+			out = append(out, visibleWorkflow(wf))
+		}
+	}
+	slices.SortFunc(out, func(a, b Workflow) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return out
+}
+
+// CreateWorkflow stores a new workflow, in the workspace when workspaceID is set; only manual
+// workflows start enabled.
+func (m *MemoryStore) CreateWorkflow(ctx context.Context, userID, workspaceID int64, name, triggerType, actionURL string, triggerConfig json.RawMessage) (*Workflow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wf := &Workflow{
+		ID:            m.id(),
+		UserID:        userID,
+		Name:          name,
+		TriggerType:   triggerType,
+		TriggerConfig: triggerConfig,
+		ActionURL:     actionURL,
+		Enabled:       triggerType == "manual",
+		CreatedAt:     time.Now(),
+	}
+	if workspaceID != 0 {
+		wf.WorkspaceID = &workspaceID
+		m.workspaceOf[wf.ID] = workspaceID
+	}
+	m.workflows[wf.ID] = wf
+	m.owners[wf.ID] = userID
+	out := *wf
+	return &out, nil
+}
+
+// ListWorkflows returns the workflows a user can read ordered by creation date.
+func (m *MemoryStore) ListWorkflows(ctx context.Context, userID int64) ([]Workflow, error) {
+	roles, err := m.workspaceRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sortedWorkflows(func(wf *Workflow) bool { return m.canRead(wf.ID, userID, roles) }), nil
+}
+
+// ListWorkflowsByTrigger returns workflows filtered by trigger type (all users).
+func (m *MemoryStore) ListWorkflowsByTrigger(ctx context.Context, triggerType string) ([]Workflow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sortedWorkflows(func(wf *Workflow) bool { return wf.TriggerType == triggerType }), nil
+}
+
+// GetWorkflow fetches a workflow by ID (no user check).
+func (m *MemoryStore) GetWorkflow(ctx context.Context, id int64) (*Workflow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wf, ok := m.workflows[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	out := visibleWorkflow(wf)
+	return &out, nil
+}
+
+// DeleteWorkflow removes a workflow; its runs and jobs are kept.
+func (m *MemoryStore) DeleteWorkflow(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.workflows[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(m.workflows, id)
+	return nil
+}
+
+// GetWorkflowForUser fetches a workflow by ID constrained to those the user can read.
+func (m *MemoryStore) GetWorkflowForUser(ctx context.Context, id int64, userID int64) (*Workflow, error) {
+	roles, err := m.workspaceRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wf, ok := m.workflows[id]
+	if !ok || !m.canRead(id, userID, roles) {
+		return nil, sql.ErrNoRows
+	}
+	out := visibleWorkflow(wf)
+	return &out, nil
+}
+
+// DeleteWorkflowForUser deletes a workflow if the user can change it.
+func (m *MemoryStore) DeleteWorkflowForUser(ctx context.Context, id int64, userID int64) error {
+	roles, err := m.workspaceRoles(ctx, userID)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.changeable(id, userID, roles); err != nil {
+		return err
+	}
+	delete(m.workflows, id)
+	return nil
+}
+
+// SetEnabledForUser toggles the enabled flag of a workflow the user can change; interval gets next_run_at.
+func (m *MemoryStore) SetEnabledForUser(ctx context.Context, id int64, userID int64, enabled bool, now time.Time) error {
+	roles, err := m.workspaceRoles(ctx, userID)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wf, err := m.changeable(id, userID, roles)
+	if err != nil {
+		return err
+	}
+
+	if !enabled {
+		wf.Enabled = false
+		wf.NextRunAt = nil
+		m.Events.publishTo(Event{Type: EventWorkflowDisabled, WorkflowID: id}, m.audience(id))
+		return nil
+	}
+	if wf.TriggerType == "interval" {
+		cfg, err := intervalConfigFromJSON(wf.TriggerConfig)
+		if err != nil || cfg.IntervalMinutes <= 0 {
+			return fmt.Errorf("invalid interval config")
+		}
+		nextRun := now.Add(time.Duration(cfg.IntervalMinutes) * time.Minute)
+		wf.NextRunAt = &nextRun
+	}
+	wf.Enabled = true
+	m.Events.publishTo(Event{Type: EventWorkflowEnabled, WorkflowID: id}, m.audience(id))
+	return nil
+}
+
+// FindWorkflowByToken returns the enabled webhook workflow whose trigger_config holds token.
+func (m *MemoryStore) FindWorkflowByToken(ctx context.Context, token string) (*Workflow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var found *Workflow
+	for _, wf := range m.workflows {
+		if wf.TriggerType != "webhook" || !wf.Enabled || (found != nil && found.ID < wf.ID) {
+			continue
+		}
+		var cfg struct {
+			Token string `json:"token"`
+		}
+		if json.Unmarshal(wf.TriggerConfig, &cfg) == nil && cfg.Token != "" && cfg.Token == token {
+			found = wf
+		}
+	}
+	if found == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	out := visibleWorkflow(found)
+	return &out, nil
+}
+
+// ClaimDueIntervalWorkflows returns interval workflows whose next_run_at <= now, and advances next_run_at.
+func (m *MemoryStore) ClaimDueIntervalWorkflows(ctx context.Context, now time.Time) ([]Workflow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []Workflow
+	for _, wf := range m.workflows {
+		if wf.TriggerType != "interval" || !wf.Enabled || wf.NextRunAt == nil || wf.NextRunAt.After(now) {
+			continue
+		}
+		cfg, err := intervalConfigFromJSON(wf.TriggerConfig)
+		if err != nil || cfg.IntervalMinutes <= 0 {
+			continue
+		}
+		nextRun := now.Add(time.Duration(cfg.IntervalMinutes) * time.Minute)
+		wf.NextRunAt = &nextRun
+		due = append(due, *wf)
+	}
+	return due, nil
+}
+
+// audience returns who sees the events of a workflow: the subscribed members of its workspace,
+// or its creator when it is personal. m.mu is held; the roles of each listener are looked up
+// under it, which only the ephemeral mode pays for.
+func (m *MemoryStore) audience(workflowID int64) []int64 {
+	workspaceID, ok := m.workspaceOf[workflowID]
+	if !ok {
+		return []int64{m.owners[workflowID]}
+	}
+	if m.Workspaces == nil {
+		return nil
+	}
+	var members []int64
+	for _, userID := range m.Events.listeners() {
+		roles, err := m.Workspaces.WorkspaceRoles(context.Background(), userID)
+		if err != nil {
+			continue
+		}
+		if _, member := roles[workspaceID]; member {
+			members = append(members, userID)
+		}
+	}
+	return members
+}
+
+// publishRun announces a run status change to the audience of the run's workflow. m.mu is held.
+func (m *MemoryStore) publishRun(run *Run, errMsg string) {
+	eventType := runEventType(run.Status)
+	if eventType == "" || !m.Events.Active() {
+		return
+	}
+	m.Events.publishTo(Event{
+		Type:       eventType,
+		WorkflowID: run.WorkflowID,
+		RunID:      run.ID,
+		Status:     run.Status,
+		Error:      errMsg,
+	}, m.audience(run.WorkflowID))
+}
+
+// publishJob announces a finished job to the audience of its workflow. m.mu is held.
+func (m *MemoryStore) publishJob(job *Job, eventType string) {
+	if !m.Events.Active() {
+		return
+	}
+	m.Events.publishTo(Event{
+		Type:       eventType,
+		WorkflowID: job.WorkflowID,
+		RunID:      job.RunID,
+		JobID:      job.ID,
+		Status:     job.Status,
+		Error:      job.Error,
+	}, m.audience(job.WorkflowID))
+}
+
+// CreateRun creates a new pending run for a workflow.
+func (m *MemoryStore) CreateRun(ctx context.Context, workflowID int64) (*Run, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run := &Run{
+		ID:         m.id(),
+		WorkflowID: workflowID,
+		Status:     RunStatusPending,
+		CreatedAt:  time.Now(),
+	}
+	m.runs[run.ID] = run
+	m.publishRun(run, "")
+	out := *run
+	return &out, nil
+}
+
+// UpdateRun updates run metadata such as status or timestamps. A cancelled run is left as is and
+// ErrRunCancelled returned.
+func (m *MemoryStore) UpdateRun(ctx context.Context, runID int64, upd RunUpdate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run, ok := m.runs[runID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if run.Status == RunStatusCancelled {
+		return ErrRunCancelled
+	}
+	if upd.Status != "" {
+		run.Status = upd.Status
+	}
+	if upd.StartedAt != nil {
+		startedAt := *upd.StartedAt
+		run.StartedAt = &startedAt
+	}
+	if upd.EndedAt != nil {
+		endedAt := *upd.EndedAt
+		run.EndedAt = &endedAt
+	}
+	if upd.Error != nil {
+		run.Error = *upd.Error
+	}
+	if upd.Status != "" {
+		errMsg := ""
+		if upd.Error != nil {
+			errMsg = *upd.Error
+		}
+		m.publishRun(run, errMsg)
+	}
+	return nil
+}
+
+// RunStatus returns the status of a run, sql.ErrNoRows when it does not exist.
+func (m *MemoryStore) RunStatus(ctx context.Context, runID int64) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run, ok := m.runs[runID]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return run.Status, nil
+}
+
+// GetRunForUser fetches a run by ID constrained to those of workflows the user can read.
+func (m *MemoryStore) GetRunForUser(ctx context.Context, runID int64, userID int64) (*Run, error) {
+	roles, err := m.workspaceRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run, ok := m.runs[runID]
+	if !ok || !m.canRead(run.WorkflowID, userID, roles) {
+		return nil, sql.ErrNoRows
+	}
+	out := *run
+	return &out, nil
+}
+
+// ListRunsForWorkflow returns the most recent runs of a workflow, newest first, with their jobs.
+func (m *MemoryStore) ListRunsForWorkflow(ctx context.Context, workflowID int64, limit int) ([]Run, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	runs := []Run{}
+	for _, run := range m.runs {
+		if run.WorkflowID == workflowID {
+			runs = append(runs, *run)
+		}
+	}
+	slices.SortFunc(runs, func(a, b Run) int { return cmp.Compare(b.ID, a.ID) })
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	for i := range runs {
+		for _, job := range m.sortedJobs(func(j *Job) bool { return j.RunID == runs[i].ID }) {
+			runs[i].Jobs = append(runs[i].Jobs, *job)
+		}
+	}
+	return runs, nil
+}
+
+// CancelRun marks a run and its unfinished jobs as cancelled.
+func (m *MemoryStore) CancelRun(ctx context.Context, runID int64, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.RunID == runID && (job.Status == JobStatusPending || job.Status == JobStatusProcessing) {
+			m.endJob(job, JobStatusCancelled, now)
+		}
+	}
+	run, ok := m.runs[runID]
+	if !ok {
+		return nil
+	}
+	if run.Status == RunStatusPending || run.Status == RunStatusRunning {
+		run.Status = RunStatusCancelled
+		run.EndedAt = &now
+	}
+	m.publishRun(&Run{ID: run.ID, WorkflowID: run.WorkflowID, Status: RunStatusCancelled}, "")
+	return nil
+}
+
+// CancelPendingRuns cancels every run of a workflow that has not started yet and returns how many were cancelled.
+func (m *MemoryStore) CancelPendingRuns(ctx context.Context, workflowID int64, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.WorkflowID == workflowID && job.Status == JobStatusPending {
+			m.endJob(job, JobStatusCancelled, now)
+		}
+	}
+	var cancelled int64
+	for _, run := range m.runs {
+		if run.WorkflowID == workflowID && run.Status == RunStatusPending {
+			run.Status = RunStatusCancelled
+			run.EndedAt = &now
+			cancelled++
+			m.publishRun(run, "")
+		}
+	}
+	return cancelled, nil
+}
+
+// sortedJobs returns the jobs matching keep, oldest first. m.mu is held.
+func (m *MemoryStore) sortedJobs(keep func(*Job) bool) []*Job {
+	var out []*Job
+	for _, job := range m.jobs {
+		if keep(job) {
+			out = append(out, job)
+		}
+	}
+	slices.SortFunc(out, func(a, b *Job) int { return cmp.Compare(a.ID, b.ID) })
+	return out
+}
+
+// endJob moves a job to a final status. m.mu is held.
+func (m *MemoryStore) endJob(job *Job, status string, now time.Time) {
+	job.Status = status
+	job.UpdatedAt = now
+	job.EndedAt = &now
+}
+
+// CreateJob stores a pending job belonging to a workflow run.
+func (m *MemoryStore) CreateJob(ctx context.Context, workflowID, runID int64, payload json.RawMessage) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	job := &Job{
+		ID:         m.id(),
+		WorkflowID: workflowID,
+		RunID:      runID,
+		Payload:    payload,
+		Status:     JobStatusPending,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		RequestID:  logging.RequestID(ctx),
+	}
+	m.jobs[job.ID] = job
+	out := *job
+	return &out, nil
+}
+
+// FetchNextPendingJob claims and returns the oldest pending job.
+func (m *MemoryStore) FetchNextPendingJob(ctx context.Context) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pending := m.sortedJobs(func(j *Job) bool { return j.Status == JobStatusPending })
+	if len(pending) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	job := pending[0]
+	now := time.Now()
+	job.Status = JobStatusProcessing
+	job.StartedAt = &now
+	job.UpdatedAt = now
+	out := *job
+	return &out, nil
+}
+
+// CountPendingJobs returns how many jobs wait for the executor.
+func (m *MemoryStore) CountPendingJobs(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var count int64
+	for _, job := range m.jobs {
+		if job.Status == JobStatusPending {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// MarkJobSuccess marks a job as succeeded and closes its timestamps, recording resp when non-nil.
+func (m *MemoryStore) MarkJobSuccess(ctx context.Context, jobID int64, resp *JobResponse) error {
+	return m.finishJob(jobID, JobStatusSucceeded, "", resp, EventJobSucceeded)
+}
+
+// MarkJobFailed marks a job as failed with the provided reason, recording resp when non-nil.
+func (m *MemoryStore) MarkJobFailed(ctx context.Context, jobID int64, reason string, resp *JobResponse) error {
+	return m.finishJob(jobID, JobStatusFailed, reason, resp, EventJobFailed)
+}
+
+func (m *MemoryStore) finishJob(jobID int64, status, reason string, resp *JobResponse, eventType string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[jobID]
+	if !ok {
+		return nil
+	}
+	if job.Status == JobStatusCancelled {
+		return ErrRunCancelled
+	}
+	m.endJob(job, status, time.Now())
+	job.Error = reason
+	if resp != nil {
+		job.ResponseStatus = resp.StatusCode
+		job.ResponseBody = resp.Body
+		job.LatencyMs = resp.Latency.Milliseconds()
+	}
+	m.publishJob(job, eventType)
+	return nil
+}
+
+// ReleaseJob hands a claimed job back to the queue, e.g. when the executor shuts down mid-send.
+func (m *MemoryStore) ReleaseJob(ctx context.Context, jobID, runID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if job, ok := m.jobs[jobID]; ok && job.Status == JobStatusProcessing {
+		m.requeueJob(job)
+	}
+	if run, ok := m.runs[runID]; ok && run.Status == RunStatusRunning {
+		run.Status = RunStatusPending
+		run.StartedAt = nil
+	}
+	return nil
+}
+
+// RequeueStaleJobs releases jobs stuck in processing since before and returns how many were requeued.
+func (m *MemoryStore) RequeueStaleJobs(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var requeued int64
+	for _, job := range m.jobs {
+		if job.Status != JobStatusProcessing || job.StartedAt == nil || !job.StartedAt.Before(before) {
+			continue
+		}
+		m.requeueJob(job)
+		requeued++
+		if run, ok := m.runs[job.RunID]; ok && run.Status == RunStatusRunning {
+			run.Status = RunStatusPending
+			run.StartedAt = nil
+		}
+	}
+	return requeued, nil
+}
+
+// requeueJob puts a claimed job back to pending. m.mu is held.
+func (m *MemoryStore) requeueJob(job *Job) {
+	job.Status = JobStatusPending
+	job.StartedAt = nil
+	job.UpdatedAt = time.Now()
+}
+
+// BufferDigestItem stores a trigger event until the workflow's digest is flushed.
+func (m *MemoryStore) BufferDigestItem(ctx context.Context, workflowID int64, payload json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.digests = append(m.digests, memoryDigestItem{workflowID: workflowID, payload: payload, createdAt: time.Now()})
+	return nil
+}
+
+// ListPendingDigests returns, per workflow, how many events are buffered and when the oldest arrived.
+func (m *MemoryStore) ListPendingDigests(ctx context.Context) ([]PendingDigest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []PendingDigest
+	index := make(map[int64]int)
+	for _, item := range m.digests {
+		i, ok := index[item.workflowID]
+		if !ok {
+			index[item.workflowID] = len(out)
+			out = append(out, PendingDigest{WorkflowID: item.workflowID, Count: 1, OldestAt: item.createdAt})
+			continue
+		}
+		out[i].Count++
+	}
+	return out, nil
+}
+
+// ClaimDigestItems removes and returns the buffered events of a workflow in arrival order.
+func (m *MemoryStore) ClaimDigestItems(ctx context.Context, workflowID int64) ([]json.RawMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var items []json.RawMessage
+	kept := m.digests[:0]
+	for _, item := range m.digests {
+		if item.workflowID == workflowID {
+			items = append(items, item.payload)
+			continue
+		}
+		kept = append(kept, item)
+	}
+	m.digests = kept
+	return items, nil
+}
+
+// FlushDigest claims the buffered events of a workflow and enqueues the run built from them; the
+// events are buffered again, ahead of newer ones, when that fails.
+func (m *MemoryStore) FlushDigest(ctx context.Context, workflowID int64, build func(items []json.RawMessage) (json.RawMessage, error)) (*Run, error) {
+	m.mu.Lock()
+	var claimed []memoryDigestItem
+	kept := m.digests[:0]
+	for _, item := range m.digests {
+		if item.workflowID == workflowID {
+			claimed = append(claimed, item)
+			continue
+		}
+		kept = append(kept, item)
+	}
+	m.digests = kept
+	m.mu.Unlock()
+	if len(claimed) == 0 {
+		return nil, nil
+	}
+
+	items := make([]json.RawMessage, len(claimed))
+	for i, item := range claimed {
+		items[i] = item.payload
+	}
+	run, err := m.enqueueDigest(ctx, workflowID, items, build)
+	if err != nil {
+		m.mu.Lock()
+		m.digests = append(claimed, m.digests...)
+		m.mu.Unlock()
+		return nil, err
+	}
+	return run, nil
+}
+
+// enqueueDigest creates the run and job of a digest of items.
+func (m *MemoryStore) enqueueDigest(ctx context.Context, workflowID int64, items []json.RawMessage, build func(items []json.RawMessage) (json.RawMessage, error)) (*Run, error) {
+	payload, err := build(items)
+	if err != nil {
+		return nil, err
+	}
+	run, err := m.CreateRun(ctx, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.CreateJob(ctx, workflowID, run.ID, payload); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// PollerState binds a poller's fields, given as pointers keyed by a stable name.
+func (m *MemoryStore) PollerState(name string, fields map[string]any) *PollerState {
+	return &PollerState{store: m, name: name, fields: fields}
+}
+
+func (m *MemoryStore) loadPollerState(ctx context.Context, name string) (json.RawMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.states[name], nil
+}
+
+func (m *MemoryStore) savePollerState(ctx context.Context, name string, state json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.states[name] = state
+	return nil
+}
+
+// AcquireLease takes or renews the named lease for holder until now+ttl, as Store.AcquireLease.
+func (m *MemoryStore) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if lease, ok := m.leases[name]; ok && lease.holder != holder && !lease.expiresAt.Before(now) {
+		return false, nil
+	}
+	m.leases[name] = memoryLease{holder: holder, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+// ReleaseLease gives up the named lease if holder owns it.
+func (m *MemoryStore) ReleaseLease(ctx context.Context, name, holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if lease, ok := m.leases[name]; ok && lease.holder == holder {
+		delete(m.leases, name)
+	}
+	return nil
+}
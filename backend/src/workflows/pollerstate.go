@@ -0,0 +1,187 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"area/src/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// pollerStateSaveTimeout bounds the final save of a stopping poller.
+	pollerStateSaveTimeout = 5 * time.Second
+	// pollerLeaseTTL is how long a poller leader stays in charge without renewing its lease,
+	// i.e. how long until another replica takes over from a dead one.
+	pollerLeaseTTL = 45 * time.Second
+	// pollerLeaseRenewInterval is how often the leader renews, and followers bid for, the lease.
+	pollerLeaseRenewInterval = 15 * time.Second
+)
+
+// PollerState persists the bookkeeping maps of a poller (last seen items, last checks) under
+// its name, so a restart neither re-triggers old items nor loses track of new ones. It also
+// elects which replica runs the poller: only the holder of the poller's lease polls.
+type PollerState struct {
+	store  pollerStateStore
+	name   string
+	fields map[string]any
+
+	once       sync.Once
+	leaseUntil atomic.Int64 // unix nanoseconds until which this replica holds the lease
+	wasLeading bool         // leadership seen by the previous Lead call
+}
+
+// pollerStateStore keeps poller states and leases; Store and MemoryStore implement it.
+type pollerStateStore interface {
+	// loadPollerState returns the saved state of a poller, nil when there is none.
+	loadPollerState(ctx context.Context, name string) (json.RawMessage, error)
+	savePollerState(ctx context.Context, name string, state json.RawMessage) error
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration, now time.Time) (bool, error)
+	ReleaseLease(ctx context.Context, name, holder string) error
+}
+
+// PollerState binds a poller's fields, given as pointers keyed by a stable name.
+func (s *Store) PollerState(name string, fields map[string]any) *PollerState {
+	return &PollerState{store: s, name: name, fields: fields}
+}
+
+func (s *Store) loadPollerState(ctx context.Context, name string) (json.RawMessage, error) {
+	var model database.PollerState
+	err := s.db.WithContext(ctx).Where("name = ?", name).Take(&model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load poller state: %w", err)
+	}
+	return model.State, nil
+}
+
+func (s *Store) savePollerState(ctx context.Context, name string, state json.RawMessage) error {
+	model := database.PollerState{Name: name, State: state, UpdatedAt: time.Now()}
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"state", "updated_at"}),
+	}).Create(&model).Error
+	if err != nil {
+		return fmt.Errorf("save poller state: %w", err)
+	}
+	return nil
+}
+
+// Load merges the saved state into the bound fields; a missing state leaves them untouched.
+func (p *PollerState) Load(ctx context.Context) error {
+	state, err := p.store.loadPollerState(ctx, p.name)
+	if err != nil || state == nil {
+		return err
+	}
+
+	var saved map[string]json.RawMessage
+	if err := json.Unmarshal(state, &saved); err != nil {
+		return fmt.Errorf("decode poller state: %w", err)
+	}
+	for key, dst := range p.fields {
+		raw, ok := saved[key]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(raw, dst); err != nil {
+			return fmt.Errorf("decode poller state %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// Save writes the bound fields.
+func (p *PollerState) Save(ctx context.Context) error {
+	state, err := json.Marshal(p.fields)
+	if err != nil {
+		return fmt.Errorf("encode poller state: %w", err)
+	}
+	return p.store.savePollerState(ctx, p.name, state)
+}
+
+// Lead is called by the poller before each cycle and reports whether this replica should poll.
+// On becoming leader it loads the state saved by the previous one; while leading it saves the
+// state of the previous cycle, so a successor resumes from there if this replica dies.
+func (p *PollerState) Lead(ctx context.Context) bool {
+	p.once.Do(func() {
+		p.renewLease(ctx)
+		go p.keepLease(ctx)
+	})
+
+	leading := time.Now().UnixNano() < p.leaseUntil.Load()
+	switch {
+	case leading && !p.wasLeading:
+		slog.InfoContext(ctx, "poller: became leader", "integration", p.name, "replica", replicaID)
+		if err := p.Load(ctx); err != nil {
+			slog.ErrorContext(ctx, "poller: load state", "integration", p.name, "error", err)
+		}
+	case leading:
+		if err := p.Save(ctx); err != nil {
+			slog.ErrorContext(ctx, "poller: save state", "integration", p.name, "error", err)
+		}
+	case p.wasLeading:
+		slog.WarnContext(ctx, "poller: lost leadership", "integration", p.name, "replica", replicaID)
+	}
+	p.wasLeading = leading
+	return leading
+}
+
+// keepLease renews or bids for the lease until ctx is cancelled.
+func (p *PollerState) keepLease(ctx context.Context) {
+	t := time.NewTicker(pollerLeaseRenewInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			p.renewLease(ctx)
+		}
+	}
+}
+
+// renewLease extends leadership on success; on failure it lets the current lease run out.
+func (p *PollerState) renewLease(ctx context.Context) {
+	now := time.Now()
+	ok, err := p.store.AcquireLease(ctx, p.leaseName(), replicaID, pollerLeaseTTL, now)
+	if err != nil {
+		slog.ErrorContext(ctx, "poller: renew lease", "integration", p.name, "error", err)
+		return
+	}
+	if ok {
+		p.leaseUntil.Store(now.Add(pollerLeaseTTL).UnixNano())
+	} else {
+		p.leaseUntil.Store(0)
+	}
+}
+
+func (p *PollerState) leaseName() string {
+	return "poller:" + p.name
+}
+
+// Stop is called when the poller's ctx was cancelled: the leader saves its state and releases
+// the lease so another replica takes over at once. It uses a short deadline of its own.
+func (p *PollerState) Stop(ctx context.Context) {
+	if !p.wasLeading {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), pollerStateSaveTimeout)
+	defer cancel()
+	if err := p.Save(ctx); err != nil {
+		slog.ErrorContext(ctx, "poller: save state", "integration", p.name, "error", err)
+	}
+	p.leaseUntil.Store(0)
+	if err := p.store.ReleaseLease(ctx, p.leaseName(), replicaID); err != nil {
+		slog.ErrorContext(ctx, "poller: release lease", "integration", p.name, "error", err)
+	}
+}
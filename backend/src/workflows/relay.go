@@ -0,0 +1,158 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+const (
+	// eventChannel is the Postgres channel events are relayed on.
+	eventChannel = "workflow_events"
+	// maxRelayPayload is the largest notification Postgres accepts, in bytes.
+	maxRelayPayload = 7999
+	// maxRelayedError bounds the error message of a relayed event, in bytes.
+	maxRelayedError = 1024
+	// relayNotifyTimeout bounds sending one event to the relay.
+	relayNotifyTimeout = 5 * time.Second
+	// relayRetryDelay is how long Relay waits before listening again after the relay failed.
+	relayRetryDelay = 5 * time.Second
+)
+
+// EventRelay carries events between the replicas sharing a database.
+type EventRelay interface {
+	// Notify sends payload to the listeners of every replica, this one included.
+	Notify(ctx context.Context, payload []byte) error
+	// Listen calls deliver with every payload notified until ctx is done or the relay fails.
+	Listen(ctx context.Context, deliver func(payload []byte)) error
+}
+
+// relayedEvent is the payload of an event sent through an EventRelay.
+type relayedEvent struct {
+	Event   Event   `json:"event"`
+	UserIDs []int64 `json:"user_ids"`
+}
+
+// Relay shares the bus with the other replicas through r until ctx is done: events are published
+// to r rather than to the local subscribers, who get those of every replica from r instead. A
+// failed relay is listened to again after relayRetryDelay, and the events notified meanwhile are
+// missed, like those of a full subscriber buffer.
+func (b *EventBus) Relay(ctx context.Context, r EventRelay) {
+	b.mu.Lock()
+	b.relay = r
+	b.mu.Unlock()
+	go func() {
+		defer func() {
+			b.mu.Lock()
+			b.relay = nil
+			b.mu.Unlock()
+		}()
+		for {
+			err := r.Listen(ctx, b.receive)
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Warn("event relay: listen", "error", err, "retry_in", relayRetryDelay)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(relayRetryDelay):
+			}
+		}
+	}()
+}
+
+// send notifies ev for userIDs through r, splitting the users over several notifications when
+// they do not fit in one.
+func (b *EventBus) send(r EventRelay, ev Event, userIDs []int64) error {
+	if len(ev.Error) > maxRelayedError {
+		ev.Error = strings.ToValidUTF8(ev.Error[:maxRelayedError], "")
+	}
+	ev.UserID = 0
+	payload, err := json.Marshal(relayedEvent{Event: ev, UserIDs: userIDs})
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxRelayPayload {
+		if len(userIDs) < 2 {
+			return fmt.Errorf("event of %d bytes is too large to relay", len(payload))
+		}
+		half := len(userIDs) / 2
+		if err := b.send(r, ev, userIDs[:half]); err != nil {
+			return err
+		}
+		return b.send(r, ev, userIDs[half:])
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), relayNotifyTimeout)
+	defer cancel()
+	return r.Notify(ctx, payload)
+}
+
+// receive delivers an event notified by any replica to the local subscribers.
+func (b *EventBus) receive(payload []byte) {
+	var msg relayedEvent
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		slog.Warn("event relay: decode event", "error", err)
+		return
+	}
+	b.deliver(msg.Event, msg.UserIDs)
+}
+
+// PostgresRelay is an EventRelay over LISTEN/NOTIFY on a Postgres database. Listening holds one
+// connection of the pool.
+type PostgresRelay struct {
+	db *gorm.DB
+}
+
+// NewPostgresRelay relays events through the Postgres database db.
+func NewPostgresRelay(db *gorm.DB) *PostgresRelay {
+	return &PostgresRelay{db: db}
+}
+
+// Notify sends payload on the event channel.
+func (r *PostgresRelay) Notify(ctx context.Context, payload []byte) error {
+	return r.db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", eventChannel, string(payload)).Error
+}
+
+// Listen listens on the event channel with a connection of its own until ctx is done or the
+// connection fails.
+func (r *PostgresRelay) Listen(ctx context.Context, deliver func(payload []byte)) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn any) error {
+		stdConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("listen needs a pgx connection, got %T", driverConn)
+		}
+		pgConn := stdConn.Conn()
+		if _, err := pgConn.Exec(ctx, "LISTEN "+eventChannel); err != nil {
+			return err
+		}
+		// The connection goes back to the pool, which must not keep collecting notifications.
+		defer func() {
+			if !pgConn.IsClosed() {
+				pgConn.Exec(context.Background(), "UNLISTEN "+eventChannel)
+			}
+		}()
+		for {
+			notification, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			deliver([]byte(notification.Payload))
+		}
+	})
+}
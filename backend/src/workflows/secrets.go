@@ -1,7 +1,10 @@
 package workflows
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"regexp"
 	"strings"
 
 	"area/src/security"
@@ -53,7 +56,7 @@ func encryptSensitiveFields(value any) any {
 		for key, val := range v {
 			_, isSensitive := sensitiveKeys[strings.ToLower(key)]
 			if isSensitive {
-				if s, ok := val.(string); ok && s != "" && !strings.HasPrefix(s, "enc:") {
+				if s, ok := val.(string); ok && s != "" && !security.IsEncrypted(s) && !isSecretRef(s) {
 					if enc, err := security.EncryptString(s); err == nil {
 						v[key] = enc
 						continue
@@ -80,7 +83,7 @@ func decryptSensitiveFields(value any) any {
 		for key, val := range v {
 			_, isSensitive := sensitiveKeys[strings.ToLower(key)]
 			if isSensitive {
-				if s, ok := val.(string); ok && strings.HasPrefix(s, "enc:") {
+				if s, ok := val.(string); ok && security.IsEncrypted(s) {
 					if dec, err := security.DecryptString(s); err == nil {
 						v[key] = dec
 						continue
@@ -99,3 +102,183 @@ func decryptSensitiveFields(value any) any {
 		return v
 	}
 }
+
+// reencryptTriggerConfig re-encrypts the encrypted sensitive fields of a trigger configuration
+// with the current key, returning how many fields changed.
+func reencryptTriggerConfig(raw json.RawMessage) (json.RawMessage, int, error) {
+	if !security.Enabled() || len(raw) == 0 {
+		return raw, 0, nil
+	}
+	var cfg any
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return raw, 0, nil
+	}
+	changed, err := reencryptSensitiveFields(cfg)
+	if err != nil || changed == 0 {
+		return raw, 0, err
+	}
+	encoded, err := json.Marshal(cfg)
+	if err != nil {
+		return raw, 0, err
+	}
+	return encoded, changed, nil
+}
+
+// reencryptSensitiveFields recursively re-encrypts sensitive fields in place.
+func reencryptSensitiveFields(value any) (int, error) {
+	changed := 0
+	switch v := value.(type) {
+	case map[string]any:
+		for key, val := range v {
+			if _, isSensitive := sensitiveKeys[strings.ToLower(key)]; isSensitive {
+				if s, ok := val.(string); ok {
+					rotated, ok, err := security.Reencrypt(s)
+					if err != nil {
+						return changed, fmt.Errorf("%s: %w", key, err)
+					}
+					if ok {
+						v[key] = rotated
+						changed++
+					}
+					continue
+				}
+			}
+			n, err := reencryptSensitiveFields(val)
+			changed += n
+			if err != nil {
+				return changed, err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			n, err := reencryptSensitiveFields(item)
+			changed += n
+			if err != nil {
+				return changed, err
+			}
+		}
+	}
+	return changed, nil
+}
+
+// secretRef matches a reference to a secret of the workflow creator, e.g. {{secrets.slack_bot}}.
+var secretRef = regexp.MustCompile(`\{\{\s*secrets\.([A-Za-z0-9_]+)\s*\}\}`)
+
+// SecretResolver returns the value of a named secret of a user.
+type SecretResolver interface {
+	ResolveSecret(ctx context.Context, userID int64, name string) (string, error)
+}
+
+// resolveSecrets replaces the secret references of a reaction payload with the values of the
+// secrets of the workflow creator. Only references the workflow's own trigger config holds under
+// the same key are resolved, so a webhook body or a trigger payload cannot read secrets into
+// another field; other references are sent as they are.
+func resolveSecrets(ctx context.Context, resolver SecretResolver, wf *Workflow, raw json.RawMessage) (json.RawMessage, error) {
+	if !secretRef.Match(raw) {
+		return raw, nil
+	}
+	var cfg any
+	if err := json.Unmarshal(decryptPayload(wf.TriggerConfig), &cfg); err != nil {
+		return raw, nil
+	}
+	trusted := make(map[string]map[string]bool)
+	collectSecretRefs(cfg, "", trusted)
+	if len(trusted) == 0 {
+		return raw, nil
+	}
+	var payload any
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return raw, nil
+	}
+	r := &secretResolution{ctx: ctx, resolver: resolver, userID: wf.UserID, trusted: trusted, values: map[string]string{}}
+	payload = r.resolve(payload, "")
+	if r.err != nil {
+		return nil, r.err
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return encoded, nil
+}
+
+// collectSecretRefs records, by the key holding them, the strings of value that reference
+// secrets. Strings in arrays count under the key of the array.
+func collectSecretRefs(value any, key string, refs map[string]map[string]bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, val := range v {
+			collectSecretRefs(val, k, refs)
+		}
+	case []any:
+		for _, item := range v {
+			collectSecretRefs(item, key, refs)
+		}
+	case string:
+		if key != "" && secretRef.MatchString(v) {
+			if refs[key] == nil {
+				refs[key] = make(map[string]bool)
+			}
+			refs[key][v] = true
+		}
+	}
+}
+
+// secretResolution substitutes the trusted secret references of one payload, fetching each
+// secret once. err is the first lookup failure.
+type secretResolution struct {
+	ctx      context.Context
+	resolver SecretResolver
+	userID   int64
+	trusted  map[string]map[string]bool
+	values   map[string]string
+	err      error
+}
+
+func (r *secretResolution) resolve(value any, key string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, val := range v {
+			v[k] = r.resolve(val, k)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = r.resolve(item, key)
+		}
+		return v
+	case string:
+		if !r.trusted[key][v] {
+			return v
+		}
+		return secretRef.ReplaceAllStringFunc(v, func(ref string) string {
+			name := secretRef.FindStringSubmatch(ref)[1]
+			return r.lookup(name)
+		})
+	default:
+		return v
+	}
+}
+
+func (r *secretResolution) lookup(name string) string {
+	if value, ok := r.values[name]; ok || r.err != nil {
+		return value
+	}
+	if r.resolver == nil {
+		r.err = fmt.Errorf("secret %q: secrets are not configured", name)
+		return ""
+	}
+	value, err := r.resolver.ResolveSecret(r.ctx, r.userID, name)
+	if err != nil {
+		r.err = fmt.Errorf("secret %q: %w", name, err)
+		return ""
+	}
+	r.values[name] = value
+	return value
+}
+
+// isSecretRef reports whether value is a single secret reference, which needs no encryption.
+func isSecretRef(value string) bool {
+	loc := secretRef.FindStringIndex(value)
+	return loc != nil && loc[0] == 0 && loc[1] == len(value)
+}
@@ -6,34 +6,91 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
 	"time"
+
+	"area/src/logging"
+	"area/src/metrics"
 )
 
 var ErrTriggerUnavailable = errors.New("workflow triggerer not configured")
 var ErrWorkflowNotFound = errors.New("workflow not found")
 var ErrWorkflowDisabled = errors.New("workflow disabled")
+var ErrRunNotFound = errors.New("run not found")
+var ErrRunFinished = errors.New("run already finished")
+var ErrEventsUnavailable = errors.New("event bus not configured")
+var ErrWorkflowReadOnly = errors.New("workspace viewers cannot change workflows")
+var ErrWorkspaceNotFound = errors.New("workspace not found")
+
+// RunCanceller interrupts in-flight work of a run on this process right away (implemented by
+// Executor). Executors on other replicas notice the cancelled run in the store instead.
+type RunCanceller interface {
+	CancelRun(runID int64) bool
+}
+
+// WorkflowAuditor records who changed or triggered a workflow, in the audit log. A zero
+// workspaceID marks a personal workflow; before and after are the states the change went between.
+type WorkflowAuditor interface {
+	RecordChange(ctx context.Context, workspaceID, actorID int64, action, target, detail string, before, after any) error
+}
+
+// workflowState is the part of a workflow the audit log records. Trigger configs are left out,
+// since they may hold credentials.
+type workflowState struct {
+	Name        string `json:"name"`
+	TriggerType string `json:"trigger_type"`
+	ActionURL   string `json:"action_url"`
+	Enabled     bool   `json:"enabled"`
+}
+
+func stateOf(wf *Workflow) *workflowState {
+	return &workflowState{Name: wf.Name, TriggerType: wf.TriggerType, ActionURL: wf.ActionURL, Enabled: wf.Enabled}
+}
+
+// enabledState is the change SetEnabled makes to a workflow.
+type enabledState struct {
+	Enabled bool `json:"enabled"`
+}
 
 // Service orchestrates workflow CRUD and triggering.
 type Service struct {
-	Store     *Store
+	Store     WorkflowStore
 	Triggerer *Triggerer
+	Canceller RunCanceller
+	// Audit, when set, records the changes to workflows and their manual triggers.
+	Audit WorkflowAuditor
 }
 
 // NewService constructs a workflow service with its store and triggerer.
-func NewService(store *Store, triggerer *Triggerer) *Service {
+func NewService(store WorkflowStore, triggerer *Triggerer) *Service {
 	return &Service{
 		Store:     store,
 		Triggerer: triggerer,
 	}
 }
 
-// Trigger enqueues a workflow run with the provided payload.
+// Trigger enqueues a workflow run with the provided payload, for the automations that fire
+// workflows: pollers, the interval scheduler and webhooks. ctx carries the user of the workflow.
+// A workspace workflow is authorized by its workspace rather than by the role of its creator
+// there, so it keeps firing when they are downgraded or leave.
+// Workflows in digest mode buffer the event instead and return a nil run.
 func (s *Service) Trigger(ctx context.Context, workflowID int64, payload map[string]any) (*Run, error) {
-	if s.Triggerer == nil {
-		return nil, ErrTriggerUnavailable
+	userID, err := UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	wf, err := s.Store.GetWorkflow(ctx, workflowID)
+	if err != nil || (wf.WorkspaceID == nil && wf.UserID != userID) {
+		return nil, ErrWorkflowNotFound
 	}
-	userID, err := userIDFromContext(ctx)
+	return s.trigger(ctx, userID, wf, payload)
+}
+
+// TriggerManually triggers a workflow on behalf of the user of ctx, who must be allowed to change
+// it, and records it in the audit log. Triggers by pollers and webhooks are not recorded.
+func (s *Service) TriggerManually(ctx context.Context, workflowID int64, payload map[string]any) (*Run, error) {
+	userID, err := UserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -41,163 +98,100 @@ func (s *Service) Trigger(ctx context.Context, workflowID int64, payload map[str
 	if err != nil {
 		return nil, ErrWorkflowNotFound
 	}
+	if err := s.requireChange(ctx, wf, userID); err != nil {
+		return nil, err
+	}
+	run, err := s.trigger(ctx, userID, wf, payload)
+	if err != nil {
+		return nil, err
+	}
+	var after any
+	if run != nil {
+		after = map[string]int64{"run_id": run.ID}
+	}
+	s.audit(ctx, userID, wf, "workflow.triggered", nil, after)
+	return run, nil
+}
+
+func (s *Service) trigger(ctx context.Context, userID int64, wf *Workflow, payload map[string]any) (*Run, error) {
+	if s.Triggerer == nil {
+		return nil, ErrTriggerUnavailable
+	}
 	if !wf.Enabled && wf.TriggerType != "manual" {
 		return nil, ErrWorkflowDisabled
 	}
-	return s.Triggerer.EnqueueRun(ctx, workflowID, payload)
+	if digest, err := DigestConfigFromJSON(wf.TriggerConfig); err == nil && digest != nil && SupportsDigest(wf.TriggerType) {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("encode payload: %w", err)
+		}
+		return nil, s.Store.BufferDigestItem(ctx, wf.ID, encoded)
+	}
+	// Runs started outside an HTTP request (pollers, scheduler) still get a correlation ID.
+	if logging.RequestID(ctx) == "" {
+		ctx = logging.WithRequestID(ctx, logging.NewRequestID())
+	}
+	ctx = logging.With(ctx, "user_id", userID, "workflow_id", wf.ID, "trigger_type", wf.TriggerType)
+	run, err := s.Triggerer.EnqueueRun(ctx, wf.ID, payload)
+	if err != nil {
+		slog.ErrorContext(ctx, "enqueue run", "error", err)
+		return nil, err
+	}
+	metrics.RunsTotal.Inc(wf.TriggerType)
+	slog.InfoContext(ctx, "run enqueued", "run_id", run.ID)
+	return run, nil
 }
 
-// CreateWorkflow validates input and stores a new workflow.
+// CreateWorkflow validates input and stores a new personal workflow.
 func (s *Service) CreateWorkflow(ctx context.Context, name, triggerType, actionURL string, triggerConfig json.RawMessage) (*Workflow, error) {
+	return s.CreateWorkspaceWorkflow(ctx, 0, name, triggerType, actionURL, triggerConfig)
+}
+
+// CreateWorkspaceWorkflow validates input and stores a new workflow in a workspace where the
+// caller is an owner or editor. A zero workspaceID stores a personal workflow.
+func (s *Service) CreateWorkspaceWorkflow(ctx context.Context, workspaceID int64, name, triggerType, actionURL string, triggerConfig json.RawMessage) (*Workflow, error) {
 	name = strings.TrimSpace(name)
 	triggerType = strings.TrimSpace(triggerType)
 	actionURL = strings.TrimSpace(actionURL)
 	if name == "" || triggerType == "" || actionURL == "" {
 		return nil, errors.New("name, triggerType and actionURL are required")
 	}
-	switch triggerType {
-	case "interval":
-		cfg, err := intervalConfigFromJSON(triggerConfig)
-		if err != nil || cfg.IntervalMinutes <= 0 {
-			return nil, errors.New("interval_minutes must be > 0 for interval trigger")
-		}
-	case "webhook", "manual", "gmail_inbound":
-		if len(triggerConfig) == 0 {
-			triggerConfig = []byte(`{}`)
-		}
-	case "github_commit":
-		cfg, err := githubCommitConfigFromJSON(triggerConfig)
-		if err != nil || cfg.TokenID <= 0 || cfg.Repo == "" || cfg.Branch == "" {
-			return nil, errors.New("github_commit requires token_id, repo and branch")
-		}
-	case "github_pull_request":
-		cfg, err := githubPRConfigFromJSON(triggerConfig)
-		if err != nil || cfg.TokenID <= 0 || cfg.Repo == "" {
-			return nil, errors.New("github_pull_request requires token_id and repo")
-		}
-	case "github_issue":
-		cfg, err := githubIssueConfigFromJSON(triggerConfig)
-		if err != nil || cfg.TokenID <= 0 || cfg.Repo == "" {
-			return nil, errors.New("github_issue requires token_id and repo")
-		}
-	case "weather_temp":
-		cfg, err := weatherTempConfigFromJSON(triggerConfig)
-		if err != nil || cfg.Direction == "" || cfg.Threshold == 0 || cfg.City == "" {
-			return nil, errors.New("weather_temp requires city, threshold and direction")
-		}
-		switch strings.ToLower(cfg.Direction) {
-		case "above", "below":
-		default:
-			return nil, errors.New("weather_temp direction must be above or below")
-		}
-	case "weather_report":
-		cfg, err := weatherReportConfigFromJSON(triggerConfig)
-		if err != nil || cfg.IntervalMin <= 0 || cfg.City == "" {
-			return nil, errors.New("weather_report requires city and interval_minutes")
-		}
-	case "reddit_new_post":
-		cfg, err := redditNewPostConfigFromJSON(triggerConfig)
-		if err != nil || strings.TrimSpace(cfg.Subreddit) == "" {
-			return nil, errors.New("reddit_new_post requires subreddit")
-		}
-	case "youtube_new_video":
-		cfg, err := youtubeNewVideoConfigFromJSON(triggerConfig)
-		if err != nil || (strings.TrimSpace(cfg.ChannelID) == "" && strings.TrimSpace(cfg.Channel) == "") {
-			return nil, errors.New("youtube_new_video requires channel")
-		}
-	case "steam_player_online":
-		cfg, err := steamPlayerOnlineConfigFromJSON(triggerConfig)
-		if err != nil || strings.TrimSpace(cfg.SteamID) == "" {
-			return nil, errors.New("steam_player_online requires steam_id")
-		}
-	case "steam_game_sale":
-		cfg, err := steamGameSaleConfigFromJSON(triggerConfig)
-		if err != nil || cfg.AppID <= 0 {
-			return nil, errors.New("steam_game_sale requires app_id")
-		}
-	case "steam_price_change":
-		cfg, err := steamPriceChangeConfigFromJSON(triggerConfig)
-		if err != nil || cfg.AppID <= 0 {
-			return nil, errors.New("steam_price_change requires app_id")
-		}
-	case "crypto_price_threshold":
-		cfg, err := cryptoPriceThresholdConfigFromJSON(triggerConfig)
-		if err != nil || strings.TrimSpace(cfg.CoinID) == "" || cfg.Threshold == 0 || cfg.Direction == "" {
-			return nil, errors.New("crypto_price_threshold requires coin_id, threshold and direction")
-		}
-		switch strings.ToLower(cfg.Direction) {
-		case "above", "below":
-		default:
-			return nil, errors.New("crypto_price_threshold direction must be above or below")
-		}
-	case "crypto_percent_change":
-		cfg, err := cryptoPercentChangeConfigFromJSON(triggerConfig)
-		if err != nil || strings.TrimSpace(cfg.CoinID) == "" || cfg.Percent == 0 || cfg.Period == "" {
-			return nil, errors.New("crypto_percent_change requires coin_id, percent and period")
-		}
-		switch strings.ToLower(cfg.Period) {
-		case "1h", "24h":
-		default:
-			return nil, errors.New("crypto_percent_change period must be 1h or 24h")
-		}
-		switch strings.ToLower(strings.TrimSpace(cfg.Direction)) {
-		case "", "any", "above", "below":
-		default:
-			return nil, errors.New("crypto_percent_change direction must be above, below, or any")
-		}
-	case "nasa_apod":
-		if _, err := nasaApodConfigFromJSON(triggerConfig); err != nil {
-			return nil, errors.New("nasa_apod config is invalid")
-		}
-	case "nasa_mars_photo":
-		cfg, err := nasaMarsPhotoConfigFromJSON(triggerConfig)
-		if err != nil || strings.TrimSpace(cfg.Rover) == "" {
-			return nil, errors.New("nasa_mars_photo requires rover")
-		}
-	case "nasa_neo_close_approach":
-		cfg, err := nasaNeoConfigFromJSON(triggerConfig)
-		if err != nil || cfg.ThresholdKM <= 0 {
-			return nil, errors.New("nasa_neo_close_approach requires threshold_km")
-		}
-	case "air_quality_aqi_threshold":
-		cfg, err := airQualityAQIConfigFromJSON(triggerConfig)
-		if err != nil || strings.TrimSpace(cfg.City) == "" || cfg.Threshold == 0 || cfg.Direction == "" {
-			return nil, errors.New("air_quality_aqi_threshold requires city, threshold and direction")
-		}
-		switch strings.ToLower(cfg.Direction) {
-		case "above", "below":
-		default:
-			return nil, errors.New("air_quality_aqi_threshold direction must be above or below")
-		}
-		switch strings.ToLower(cfg.Index) {
-		case "", "us_aqi", "european_aqi":
-		default:
-			return nil, errors.New("air_quality_aqi_threshold index must be us_aqi or european_aqi")
-		}
-	case "air_quality_pm25_threshold":
-		cfg, err := airQualityPM25ConfigFromJSON(triggerConfig)
-		if err != nil || strings.TrimSpace(cfg.City) == "" || cfg.Threshold == 0 || cfg.Direction == "" {
-			return nil, errors.New("air_quality_pm25_threshold requires city, threshold and direction")
-		}
-		switch strings.ToLower(cfg.Direction) {
-		case "above", "below":
-		default:
-			return nil, errors.New("air_quality_pm25_threshold direction must be above or below")
-		}
-	default:
+	if _, ok := triggerValidators[triggerType]; !ok {
 		return nil, fmt.Errorf("unsupported trigger_type %s", triggerType)
 	}
-	userID, err := userIDFromContext(ctx)
+	if len(triggerConfig) == 0 {
+		triggerConfig = []byte(`{}`)
+	}
+	if err := validateTriggerConfig(triggerType, triggerConfig); err != nil {
+		return nil, err
+	}
+	if err := validateDigestConfig(triggerType, triggerConfig); err != nil {
+		return nil, err
+	}
+	if err := validateTimeoutConfig(triggerConfig); err != nil {
+		return nil, err
+	}
+	userID, err := UserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if workspaceID != 0 {
+		if err := s.requireEditor(ctx, workspaceID, userID); err != nil {
+			return nil, err
+		}
+	}
 	triggerConfig = encryptTriggerConfig(triggerConfig)
-	return s.Store.CreateWorkflow(ctx, userID, name, triggerType, actionURL, triggerConfig)
+	wf, err := s.Store.CreateWorkflow(ctx, userID, workspaceID, name, triggerType, actionURL, triggerConfig)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, userID, wf, "workflow.created", nil, stateOf(wf))
+	return wf, nil
 }
 
-// ListWorkflows returns all persisted workflows.
+// ListWorkflows returns the caller's personal workflows and those of their workspaces.
 func (s *Service) ListWorkflows(ctx context.Context) ([]Workflow, error) {
-	userID, err := userIDFromContext(ctx)
+	userID, err := UserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -206,7 +200,7 @@ func (s *Service) ListWorkflows(ctx context.Context) ([]Workflow, error) {
 
 // GetWorkflow fetches a workflow by ID or returns ErrWorkflowNotFound.
 func (s *Service) GetWorkflow(ctx context.Context, id int64) (*Workflow, error) {
-	userID, err := userIDFromContext(ctx)
+	userID, err := UserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -219,31 +213,45 @@ func (s *Service) GetWorkflow(ctx context.Context, id int64) (*Workflow, error)
 
 // DeleteWorkflow removes a workflow and its related runs/jobs.
 func (s *Service) DeleteWorkflow(ctx context.Context, id int64) error {
-	userID, err := userIDFromContext(ctx)
+	userID, err := UserIDFromContext(ctx)
 	if err != nil {
 		return err
 	}
+	wf, err := s.Store.GetWorkflowForUser(ctx, id, userID)
+	if err != nil {
+		return ErrWorkflowNotFound
+	}
 	if err := s.Store.DeleteWorkflowForUser(ctx, id, userID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrWorkflowNotFound
 		}
 		return err
 	}
+	s.audit(ctx, userID, wf, "workflow.deleted", stateOf(wf), nil)
 	return nil
 }
 
 // SetEnabled toggles a workflow (non-manual can be paused); interval workflows are rescheduled on enable.
 func (s *Service) SetEnabled(ctx context.Context, id int64, enabled bool, now time.Time) error {
-	userID, err := userIDFromContext(ctx)
+	userID, err := UserIDFromContext(ctx)
 	if err != nil {
 		return err
 	}
+	wf, err := s.Store.GetWorkflowForUser(ctx, id, userID)
+	if err != nil {
+		return ErrWorkflowNotFound
+	}
 	if err := s.Store.SetEnabledForUser(ctx, id, userID, enabled, now); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrWorkflowNotFound
 		}
 		return err
 	}
+	action := "workflow.disabled"
+	if enabled {
+		action = "workflow.enabled"
+	}
+	s.audit(ctx, userID, wf, action, enabledState{Enabled: wf.Enabled}, enabledState{Enabled: enabled})
 	return nil
 }
 
@@ -260,6 +268,133 @@ func (s *Service) TriggerWebhook(ctx context.Context, token string, payload map[
 	return s.Trigger(ctx, wf.ID, payload)
 }
 
+// SubscribeEvents streams the events of the caller's workflows and of those of their workspaces
+// until the returned cancel is called.
+func (s *Service) SubscribeEvents(ctx context.Context) (<-chan Event, func(), error) {
+	userID, err := UserIDFromContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.Store == nil || s.Store.EventBus() == nil {
+		return nil, nil, ErrEventsUnavailable
+	}
+	events, cancel := s.Store.EventBus().Subscribe(userID)
+	return events, cancel, nil
+}
+
+// maxRunHistory caps how many runs ListRuns returns.
+const maxRunHistory = 50
+
+// ListRuns returns the latest runs of a workflow with their jobs' reaction outcomes.
+func (s *Service) ListRuns(ctx context.Context, workflowID int64, limit int) ([]Run, error) {
+	userID, err := UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Store.GetWorkflowForUser(ctx, workflowID, userID); err != nil {
+		return nil, ErrWorkflowNotFound
+	}
+	if limit <= 0 || limit > maxRunHistory {
+		limit = maxRunHistory
+	}
+	return s.Store.ListRunsForWorkflow(ctx, workflowID, limit)
+}
+
+// CancelRun cancels a pending or running run and interrupts its in-flight send.
+func (s *Service) CancelRun(ctx context.Context, runID int64, now time.Time) (*Run, error) {
+	userID, err := UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	run, err := s.Store.GetRunForUser(ctx, runID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRunNotFound
+		}
+		return nil, err
+	}
+	if run.Status != RunStatusPending && run.Status != RunStatusRunning {
+		return nil, ErrRunFinished
+	}
+	// A run whose workflow is gone has no workspace left to check the caller's role in.
+	if wf, err := s.Store.GetWorkflowForUser(ctx, run.WorkflowID, userID); err == nil {
+		if err := s.requireChange(ctx, wf, userID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.Store.CancelRun(ctx, runID, now); err != nil {
+		return nil, err
+	}
+	if s.Canceller != nil {
+		s.Canceller.CancelRun(runID)
+	}
+	run.Status = RunStatusCancelled
+	run.EndedAt = &now
+	return run, nil
+}
+
+// CancelPendingRuns cancels every not-yet-started run of a workflow, e.g. when a trigger floods the queue.
+func (s *Service) CancelPendingRuns(ctx context.Context, workflowID int64, now time.Time) (int64, error) {
+	userID, err := UserIDFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	wf, err := s.Store.GetWorkflowForUser(ctx, workflowID, userID)
+	if err != nil {
+		return 0, ErrWorkflowNotFound
+	}
+	if err := s.requireChange(ctx, wf, userID); err != nil {
+		return 0, err
+	}
+	return s.Store.CancelPendingRuns(ctx, workflowID, now)
+}
+
+// requireEditor returns ErrWorkspaceNotFound unless the user belongs to the workspace, and
+// ErrWorkflowReadOnly unless they are an owner or editor there.
+func (s *Service) requireEditor(ctx context.Context, workspaceID, userID int64) error {
+	role, err := s.Store.WorkspaceRole(ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrWorkspaceNotFound
+	}
+	if !canEdit(role) {
+		return ErrWorkflowReadOnly
+	}
+	return nil
+}
+
+// requireChange returns ErrWorkflowReadOnly unless the user, who can read wf, can also change it.
+func (s *Service) requireChange(ctx context.Context, wf *Workflow, userID int64) error {
+	if wf.WorkspaceID == nil {
+		return nil
+	}
+	if err := s.requireEditor(ctx, *wf.WorkspaceID, userID); err != nil {
+		if errors.Is(err, ErrWorkspaceNotFound) {
+			return ErrWorkflowNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// audit records a change by userID to a workflow, from before to after. A failure is logged,
+// since the change is already made.
+func (s *Service) audit(ctx context.Context, userID int64, wf *Workflow, action string, before, after any) {
+	if s.Audit == nil {
+		return
+	}
+	var workspaceID int64
+	if wf.WorkspaceID != nil {
+		workspaceID = *wf.WorkspaceID
+	}
+	target := fmt.Sprintf("workflow:%d", wf.ID)
+	if err := s.Audit.RecordChange(ctx, workspaceID, userID, action, target, wf.Name, before, after); err != nil {
+		slog.ErrorContext(ctx, "record audit event", "error", err, "action", action, "workflow_id", wf.ID)
+	}
+}
+
 // IntervalConfigFromJSON exposes interval config parsing to callers (e.g., scheduler).
 func IntervalConfigFromJSON(raw json.RawMessage) (IntervalConfig, error) {
 	return intervalConfigFromJSON(raw)
@@ -352,7 +487,8 @@ func WithUserID(ctx context.Context, userID int64) context.Context {
 	return context.WithValue(ctx, ctxUserIDKey{}, userID)
 }
 
-func userIDFromContext(ctx context.Context) (int64, error) {
+// UserIDFromContext returns the user id WithUserID stored in ctx.
+func UserIDFromContext(ctx context.Context) (int64, error) {
 	val := ctx.Value(ctxUserIDKey{})
 	if val == nil {
 		return 0, fmt.Errorf("missing user id in context")
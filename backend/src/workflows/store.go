@@ -6,9 +6,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"area/src/database"
+	"area/src/logging"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -19,11 +21,13 @@ const (
 	JobStatusProcessing = "processing"
 	JobStatusSucceeded  = "succeeded"
 	JobStatusFailed     = "failed"
+	JobStatusCancelled  = "cancelled"
 
 	RunStatusPending   = "pending"
 	RunStatusRunning   = "running"
 	RunStatusSucceeded = "succeeded"
 	RunStatusFailed    = "failed"
+	RunStatusCancelled = "cancelled"
 )
 
 // Workflow API Response Types (keep existing for API compatibility)
@@ -37,6 +41,8 @@ type Workflow struct {
 	Enabled       bool            `json:"enabled"`
 	NextRunAt     *time.Time      `json:"next_run_at,omitempty"`
 	CreatedAt     time.Time       `json:"created_at"`
+	// WorkspaceID is set on workflows shared in a workspace; UserID is then their creator.
+	WorkspaceID *int64 `json:"workspace_id,omitempty"`
 }
 
 type Run struct {
@@ -47,6 +53,7 @@ type Run struct {
 	StartedAt  *time.Time `json:"started_at,omitempty"`
 	EndedAt    *time.Time `json:"ended_at,omitempty"`
 	Error      string     `json:"error,omitempty"`
+	Jobs       []Job      `json:"jobs,omitempty"`
 }
 
 type Job struct {
@@ -60,6 +67,18 @@ type Job struct {
 	UpdatedAt  time.Time       `json:"updated_at"`
 	StartedAt  *time.Time      `json:"started_at,omitempty"`
 	EndedAt    *time.Time      `json:"ended_at,omitempty"`
+
+	ResponseStatus int    `json:"response_status,omitempty"`
+	ResponseBody   string `json:"response_body,omitempty"`
+	LatencyMs      int64  `json:"latency_ms,omitempty"`
+	RequestID      string `json:"request_id,omitempty"`
+}
+
+// JobResponse captures the outcome of a job's reaction call.
+type JobResponse struct {
+	StatusCode int
+	Body       string
+	Latency    time.Duration
 }
 
 type IntervalConfig struct {
@@ -194,8 +213,64 @@ type AirQualityPM25Config struct {
 	PayloadTemplate map[string]interface{} `json:"payload_template,omitempty"`
 }
 
+// WorkflowStore persists workflows with their runs, jobs, digests and poller states. Store keeps
+// them in the database; MemoryStore keeps them in memory for demos and tests.
+//
+// The ForUser methods, and ListWorkflows, let a user read their personal workflows and those of
+// the workspaces they belong to; changing a workspace workflow takes the owner or editor role,
+// ErrWorkflowReadOnly otherwise.
+type WorkflowStore interface {
+	// CreateWorkflow stores a personal workflow, or one of the workspace when workspaceID is set.
+	CreateWorkflow(ctx context.Context, userID, workspaceID int64, name, triggerType, actionURL string, triggerConfig json.RawMessage) (*Workflow, error)
+	ListWorkflows(ctx context.Context, userID int64) ([]Workflow, error)
+	ListWorkflowsByTrigger(ctx context.Context, triggerType string) ([]Workflow, error)
+	GetWorkflow(ctx context.Context, id int64) (*Workflow, error)
+	DeleteWorkflow(ctx context.Context, id int64) error
+	GetWorkflowForUser(ctx context.Context, id int64, userID int64) (*Workflow, error)
+	DeleteWorkflowForUser(ctx context.Context, id int64, userID int64) error
+	SetEnabledForUser(ctx context.Context, id int64, userID int64, enabled bool, now time.Time) error
+	// WorkspaceRole returns the role of a user in a workspace, empty when they do not belong to it.
+	WorkspaceRole(ctx context.Context, workspaceID, userID int64) (string, error)
+	FindWorkflowByToken(ctx context.Context, token string) (*Workflow, error)
+	ClaimDueIntervalWorkflows(ctx context.Context, now time.Time) ([]Workflow, error)
+
+	CreateRun(ctx context.Context, workflowID int64) (*Run, error)
+	// UpdateRun returns ErrRunCancelled, changing nothing, once the run is cancelled.
+	UpdateRun(ctx context.Context, runID int64, upd RunUpdate) error
+	RunStatus(ctx context.Context, runID int64) (string, error)
+	GetRunForUser(ctx context.Context, runID int64, userID int64) (*Run, error)
+	ListRunsForWorkflow(ctx context.Context, workflowID int64, limit int) ([]Run, error)
+	CancelRun(ctx context.Context, runID int64, now time.Time) error
+	CancelPendingRuns(ctx context.Context, workflowID int64, now time.Time) (int64, error)
+
+	CreateJob(ctx context.Context, workflowID, runID int64, payload json.RawMessage) (*Job, error)
+	FetchNextPendingJob(ctx context.Context) (*Job, error)
+	CountPendingJobs(ctx context.Context) (int64, error)
+	// MarkJobSuccess and MarkJobFailed return ErrRunCancelled, changing nothing, once the job is
+	// cancelled.
+	MarkJobSuccess(ctx context.Context, jobID int64, resp *JobResponse) error
+	MarkJobFailed(ctx context.Context, jobID int64, reason string, resp *JobResponse) error
+	ReleaseJob(ctx context.Context, jobID, runID int64) error
+	RequeueStaleJobs(ctx context.Context, before time.Time) (int64, error)
+
+	BufferDigestItem(ctx context.Context, workflowID int64, payload json.RawMessage) error
+	ListPendingDigests(ctx context.Context) ([]PendingDigest, error)
+	ClaimDigestItems(ctx context.Context, workflowID int64) ([]json.RawMessage, error)
+	// FlushDigest claims the buffered events of a workflow and enqueues the run whose payload build
+	// makes of them at once: when the run cannot be enqueued the events stay buffered. The run is
+	// nil when nothing was buffered.
+	FlushDigest(ctx context.Context, workflowID int64, build func(items []json.RawMessage) (json.RawMessage, error)) (*Run, error)
+
+	PollerState(name string, fields map[string]any) *PollerState
+	// EventBus returns the bus receiving state changes, nil when none is set.
+	EventBus() *EventBus
+}
+
 type Store struct {
 	db *gorm.DB
+
+	// Events, when set, receives run, job and workflow state changes.
+	Events *EventBus
 }
 
 // NewStore builds a Store backed by the provided database handle.
@@ -208,9 +283,79 @@ func NewDefaultStore() *Store {
 	return &Store{db: database.GetDB()}
 }
 
+// EventBus returns s.Events.
+func (s *Store) EventBus() *EventBus {
+	return s.Events
+}
+
+// skipLocked locks the selected rows for the rest of tx, skipping rows other transactions
+// hold. SQLite has no row locks; there the transaction holds the write lock from its start.
+func skipLocked(tx *gorm.DB) *gorm.DB {
+	if database.IsSQLite(tx) {
+		return tx
+	}
+	return tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+}
+
+// jsonText returns the SQL expression of the text value of key in a JSON column.
+func (s *Store) jsonText(column, key string) string {
+	if database.IsSQLite(s.db) {
+		return fmt.Sprintf("json_extract(CAST(%s AS TEXT), '$.%s')", column, key)
+	}
+	return fmt.Sprintf("%s->>'%s'", column, key)
+}
+
+// editorRoles are the workspace roles allowed to change the workflows of the workspace.
+var editorRoles = []string{database.RoleOwner, database.RoleEditor}
+
+// canEdit reports whether a workspace role allows changing the workflows of the workspace.
+func canEdit(role string) bool {
+	return slices.Contains(editorRoles, role)
+}
+
+// accessibleBy restricts a query on workflows to the personal workflows of userID and those of
+// the workspaces where they hold one of roles, or any role when none is given.
+func (s *Store) accessibleBy(userID int64, roles ...string) clause.Expr {
+	members := s.db.Model(&database.WorkspaceMember{}).Select("workspace_id").Where("user_id = ?", userID)
+	if len(roles) > 0 {
+		members = members.Where("role IN ?", roles)
+	}
+	return gorm.Expr("(workflows.workspace_id IS NULL AND workflows.user_id = ?) OR workflows.workspace_id IN (?)", userID, members)
+}
+
+// denied explains why a change of workflow id by userID matched no row: ErrWorkflowReadOnly
+// when they can read it, sql.ErrNoRows otherwise.
+func (s *Store) denied(ctx context.Context, id, userID int64) error {
+	if _, err := s.GetWorkflowForUser(ctx, id, userID); err != nil {
+		return err
+	}
+	return ErrWorkflowReadOnly
+}
+
+// WorkspaceRole returns the role of a user in a workspace, empty when they do not belong to it.
+func (s *Store) WorkspaceRole(ctx context.Context, workspaceID, userID int64) (string, error) {
+	var members []database.WorkspaceMember
+	if err := s.db.WithContext(ctx).
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		Limit(1).
+		Find(&members).Error; err != nil {
+		return "", fmt.Errorf("workspace role: %w", err)
+	}
+	if len(members) == 0 {
+		return "", nil
+	}
+	return members[0].Role, nil
+}
+
 // Helper functions to convert between models and API types
 func workflowModelToAPI(model database.Workflow) Workflow {
+	var workspaceID *int64
+	if model.WorkspaceID != nil {
+		id := int64(*model.WorkspaceID)
+		workspaceID = &id
+	}
 	return Workflow{
+		WorkspaceID:   workspaceID,
 		ID:            int64(model.ID),
 		UserID:        int64(model.UserID),
 		Name:          model.Name,
@@ -249,11 +394,17 @@ func jobModelToAPI(model database.Job) Job {
 		UpdatedAt:  model.UpdatedAt,
 		StartedAt:  model.StartedAt,
 		EndedAt:    model.EndedAt,
+
+		ResponseStatus: model.ResponseStatus,
+		ResponseBody:   model.ResponseBody,
+		LatencyMs:      model.LatencyMs,
+		RequestID:      model.RequestID,
 	}
 }
 
-// CreateWorkflow persists a new workflow with its trigger configuration.
-func (s *Store) CreateWorkflow(ctx context.Context, userID int64, name, triggerType, actionURL string, triggerConfig json.RawMessage) (*Workflow, error) {
+// CreateWorkflow persists a new workflow with its trigger configuration, in the workspace when
+// workspaceID is set.
+func (s *Store) CreateWorkflow(ctx context.Context, userID, workspaceID int64, name, triggerType, actionURL string, triggerConfig json.RawMessage) (*Workflow, error) {
 	initialEnabled := triggerType == "manual"
 
 	model := database.Workflow{
@@ -264,6 +415,10 @@ func (s *Store) CreateWorkflow(ctx context.Context, userID int64, name, triggerT
 		ActionURL:     actionURL,
 		Enabled:       initialEnabled,
 	}
+	if workspaceID != 0 {
+		id := uint(workspaceID)
+		model.WorkspaceID = &id
+	}
 
 	if err := s.db.WithContext(ctx).Create(&model).Error; err != nil {
 		return nil, fmt.Errorf("create workflow: %w", err)
@@ -273,10 +428,10 @@ func (s *Store) CreateWorkflow(ctx context.Context, userID int64, name, triggerT
 	return &workflow, nil
 }
 
-// ListWorkflows returns all workflows for a user ordered by creation date.
+// ListWorkflows returns the workflows a user can read ordered by creation date.
 func (s *Store) ListWorkflows(ctx context.Context, userID int64) ([]Workflow, error) {
 	var models []database.Workflow
-	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&models).Error; err != nil {
+	if err := s.db.WithContext(ctx).Where(s.accessibleBy(userID)).Order("created_at DESC").Find(&models).Error; err != nil {
 		return nil, fmt.Errorf("list workflows: %w", err)
 	}
 
@@ -347,12 +502,13 @@ func (s *Store) DeleteWorkflow(ctx context.Context, id int64) error {
 	return nil
 }
 
-// GetWorkflowForUser fetches a workflow by ID constrained to the owner.
+// GetWorkflowForUser fetches a workflow by ID constrained to those the user can read.
 func (s *Store) GetWorkflowForUser(ctx context.Context, id int64, userID int64) (*Workflow, error) {
 	var model database.Workflow
 
 	if err := s.db.WithContext(ctx).
-		Where("id = ? AND user_id = ?", id, userID).
+		Where("id = ?", id).
+		Where(s.accessibleBy(userID)).
 		First(&model).Error; err != nil {
 
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -372,20 +528,20 @@ func (s *Store) GetWorkflowForUser(ctx context.Context, id int64, userID int64)
 	return &workflow, nil
 }
 
-// DeleteWorkflowForUser deletes a workflow if it belongs to the user.
+// DeleteWorkflowForUser deletes a workflow if the user can change it.
 func (s *Store) DeleteWorkflowForUser(ctx context.Context, id int64, userID int64) error {
-	res := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&database.Workflow{})
+	res := s.db.WithContext(ctx).Where("id = ?", id).Where(s.accessibleBy(userID, editorRoles...)).Delete(&database.Workflow{})
 
 	if res.Error != nil {
 		return fmt.Errorf("delete workflow: %w", res.Error)
 	}
 	if res.RowsAffected == 0 {
-		return sql.ErrNoRows
+		return s.denied(ctx, id, userID)
 	}
 	return nil
 }
 
-// SetEnabledForUser toggles the enabled flag for a user's workflow; interval gets next_run_at.
+// SetEnabledForUser toggles the enabled flag of a workflow the user can change; interval gets next_run_at.
 func (s *Store) SetEnabledForUser(ctx context.Context, id int64, userID int64, enabled bool, now time.Time) error {
 	if enabled {
 		wf, err := s.GetWorkflowForUser(ctx, id, userID)
@@ -406,18 +562,22 @@ func (s *Store) SetEnabledForUser(ctx context.Context, id int64, userID int64, e
 			updates["next_run_at"] = nextRun
 		}
 
-		result := s.db.WithContext(ctx).Model(&database.Workflow{}).Where("id = ? AND user_id = ?", uint(id), userID).Updates(updates)
+		result := s.db.WithContext(ctx).Model(&database.Workflow{}).
+			Where("id = ?", uint(id)).
+			Where(s.accessibleBy(userID, editorRoles...)).
+			Updates(updates)
 		if result.Error != nil {
 			return fmt.Errorf("enable workflow: %w", result.Error)
 		}
 		if result.RowsAffected == 0 {
-			return gorm.ErrRecordNotFound
+			return s.denied(ctx, id, userID)
 		}
+		s.publishWorkflowEvent(ctx, id, EventWorkflowEnabled)
 		return nil
 	}
 
 	// Disable workflow
-	result := s.db.WithContext(ctx).Model(&database.Workflow{}).Where("id = ? AND user_id = ?", uint(id), userID).Updates(map[string]interface{}{
+	result := s.db.WithContext(ctx).Model(&database.Workflow{}).Where("id = ?", uint(id)).Where(s.accessibleBy(userID, editorRoles...)).Updates(map[string]interface{}{
 		"enabled":     false,
 		"next_run_at": nil,
 	})
@@ -425,8 +585,9 @@ func (s *Store) SetEnabledForUser(ctx context.Context, id int64, userID int64, e
 		return fmt.Errorf("disable workflow: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
+		return s.denied(ctx, id, userID)
 	}
+	s.publishWorkflowEvent(ctx, id, EventWorkflowDisabled)
 	return nil
 }
 
@@ -442,6 +603,7 @@ func (s *Store) CreateRun(ctx context.Context, workflowID int64) (*Run, error) {
 	}
 
 	run := runModelToAPI(model)
+	s.publishRunEvent(ctx, run.ID, run.Status, "")
 	return &run, nil
 }
 
@@ -452,7 +614,9 @@ type RunUpdate struct {
 	Error     *string
 }
 
-// UpdateRun updates run metadata such as status or timestamps.
+// UpdateRun updates run metadata such as status or timestamps. A cancelled run is left as is and
+// ErrRunCancelled returned, so the executor cannot overwrite a cancellation; a missing run gives
+// sql.ErrNoRows.
 func (s *Store) UpdateRun(ctx context.Context, runID int64, upd RunUpdate) error {
 	updates := make(map[string]interface{})
 
@@ -469,12 +633,172 @@ func (s *Store) UpdateRun(ctx context.Context, runID int64, upd RunUpdate) error
 		updates["error"] = *upd.Error
 	}
 
-	if err := s.db.WithContext(ctx).Model(&database.Run{}).Where("id = ?", uint(runID)).Updates(updates).Error; err != nil {
-		return fmt.Errorf("update run: %w", err)
+	res := s.db.WithContext(ctx).Model(&database.Run{}).
+		Where("id = ? AND status <> ?", uint(runID), RunStatusCancelled).
+		Updates(updates)
+	if res.Error != nil {
+		return fmt.Errorf("update run: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		status, err := s.RunStatus(ctx, runID)
+		if err != nil {
+			return err
+		}
+		if status == RunStatusCancelled {
+			return ErrRunCancelled
+		}
+		return fmt.Errorf("update run %d: no row updated", runID)
+	}
+	if upd.Status != "" {
+		errMsg := ""
+		if upd.Error != nil {
+			errMsg = *upd.Error
+		}
+		s.publishRunEvent(ctx, runID, upd.Status, errMsg)
 	}
 	return nil
 }
 
+// RunStatus returns the status of a run, sql.ErrNoRows when it does not exist.
+func (s *Store) RunStatus(ctx context.Context, runID int64) (string, error) {
+	var statuses []string
+	if err := s.db.WithContext(ctx).Model(&database.Run{}).Where("id = ?", uint(runID)).Pluck("status", &statuses).Error; err != nil {
+		return "", fmt.Errorf("get run status: %w", err)
+	}
+	if len(statuses) == 0 {
+		return "", sql.ErrNoRows
+	}
+	return statuses[0], nil
+}
+
+// GetRunForUser fetches a run by ID constrained to those of workflows the user can read.
+func (s *Store) GetRunForUser(ctx context.Context, runID int64, userID int64) (*Run, error) {
+	var model database.Run
+	err := s.db.WithContext(ctx).
+		Joins("JOIN workflows ON workflows.id = workflow_runs.workflow_id").
+		Where("workflow_runs.id = ?", runID).
+		Where(s.accessibleBy(userID)).
+		First(&model).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("get run: %w", err)
+	}
+	run := runModelToAPI(model)
+	return &run, nil
+}
+
+// ListRunsForWorkflow returns the most recent runs of a workflow, newest first, with their jobs.
+func (s *Store) ListRunsForWorkflow(ctx context.Context, workflowID int64, limit int) ([]Run, error) {
+	var models []database.Run
+	if err := s.db.WithContext(ctx).
+		Where("workflow_id = ?", uint(workflowID)).
+		Order("id DESC").
+		Limit(limit).
+		Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("list runs: %w", err)
+	}
+	if len(models) == 0 {
+		return []Run{}, nil
+	}
+
+	runIDs := make([]uint, 0, len(models))
+	for _, m := range models {
+		runIDs = append(runIDs, m.ID)
+	}
+	var jobModels []database.Job
+	if err := s.db.WithContext(ctx).
+		Where("run_id IN ?", runIDs).
+		Order("id ASC").
+		Find(&jobModels).Error; err != nil {
+		return nil, fmt.Errorf("list run jobs: %w", err)
+	}
+	jobsByRun := make(map[uint][]Job, len(models))
+	for _, jm := range jobModels {
+		jobsByRun[jm.RunID] = append(jobsByRun[jm.RunID], jobModelToAPI(jm))
+	}
+
+	runs := make([]Run, 0, len(models))
+	for _, m := range models {
+		run := runModelToAPI(m)
+		run.Jobs = jobsByRun[m.ID]
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// CancelRun marks a run and its unfinished jobs as cancelled.
+func (s *Store) CancelRun(ctx context.Context, runID int64, now time.Time) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&database.Job{}).
+			Where("run_id = ? AND status IN ?", uint(runID), []string{JobStatusPending, JobStatusProcessing}).
+			Updates(map[string]interface{}{
+				"status":     JobStatusCancelled,
+				"updated_at": now,
+				"ended_at":   now,
+			}).Error; err != nil {
+			return fmt.Errorf("cancel jobs: %w", err)
+		}
+		if err := tx.Model(&database.Run{}).
+			Where("id = ? AND status IN ?", uint(runID), []string{RunStatusPending, RunStatusRunning}).
+			Updates(map[string]interface{}{
+				"status":   RunStatusCancelled,
+				"ended_at": now,
+			}).Error; err != nil {
+			return fmt.Errorf("cancel run: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.publishRunEvent(ctx, runID, RunStatusCancelled, "")
+	return nil
+}
+
+// CancelPendingRuns cancels every run of a workflow that has not started yet and returns how many were cancelled.
+func (s *Store) CancelPendingRuns(ctx context.Context, workflowID int64, now time.Time) (int64, error) {
+	var cancelled int64
+	var runIDs []int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if s.Events.Active() {
+			if err := tx.Model(&database.Run{}).
+				Where("workflow_id = ? AND status = ?", uint(workflowID), RunStatusPending).
+				Pluck("id", &runIDs).Error; err != nil {
+				return fmt.Errorf("list pending runs: %w", err)
+			}
+		}
+		if err := tx.Model(&database.Job{}).
+			Where("workflow_id = ? AND status = ?", uint(workflowID), JobStatusPending).
+			Updates(map[string]interface{}{
+				"status":     JobStatusCancelled,
+				"updated_at": now,
+				"ended_at":   now,
+			}).Error; err != nil {
+			return fmt.Errorf("cancel pending jobs: %w", err)
+		}
+		res := tx.Model(&database.Run{}).
+			Where("workflow_id = ? AND status = ?", uint(workflowID), RunStatusPending).
+			Updates(map[string]interface{}{
+				"status":   RunStatusCancelled,
+				"ended_at": now,
+			})
+		if res.Error != nil {
+			return fmt.Errorf("cancel pending runs: %w", res.Error)
+		}
+		cancelled = res.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, runID := range runIDs {
+		s.publishRunEvent(ctx, runID, RunStatusCancelled, "")
+	}
+	return cancelled, nil
+}
+
 // CreateJob inserts a pending job belonging to a workflow run.
 func (s *Store) CreateJob(ctx context.Context, workflowID, runID int64, payload json.RawMessage) (*Job, error) {
 	model := database.Job{
@@ -482,6 +806,7 @@ func (s *Store) CreateJob(ctx context.Context, workflowID, runID int64, payload
 		RunID:      uint(runID),
 		Payload:    payload,
 		Status:     JobStatusPending,
+		RequestID:  logging.RequestID(ctx),
 	}
 
 	if err := s.db.WithContext(ctx).Create(&model).Error; err != nil {
@@ -501,7 +826,7 @@ func (s *Store) FetchNextPendingJob(ctx context.Context) (*Job, error) {
 	defer tx.Rollback()
 
 	var model database.Job
-	result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+	result := skipLocked(tx).
 		Where("status = ?", JobStatusPending).
 		Order("created_at, id").
 		Limit(1).
@@ -536,8 +861,17 @@ func (s *Store) FetchNextPendingJob(ctx context.Context) (*Job, error) {
 	return &job, nil
 }
 
-// MarkJobSuccess marks a job as succeeded and closes its timestamps.
-func (s *Store) MarkJobSuccess(ctx context.Context, jobID int64) error {
+// CountPendingJobs returns how many jobs wait for the executor.
+func (s *Store) CountPendingJobs(ctx context.Context) (int64, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&database.Job{}).Where("status = ?", JobStatusPending).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("count pending jobs: %w", err)
+	}
+	return count, nil
+}
+
+// MarkJobSuccess marks a job as succeeded and closes its timestamps, recording resp when non-nil.
+func (s *Store) MarkJobSuccess(ctx context.Context, jobID int64, resp *JobResponse) error {
 	now := time.Now()
 	updates := map[string]interface{}{
 		"status":     JobStatusSucceeded,
@@ -545,15 +879,17 @@ func (s *Store) MarkJobSuccess(ctx context.Context, jobID int64) error {
 		"ended_at":   now,
 		"error":      "",
 	}
+	addJobResponse(updates, resp)
 
-	if err := s.db.WithContext(ctx).Model(&database.Job{}).Where("id = ?", uint(jobID)).Updates(updates).Error; err != nil {
+	if err := s.finishJob(ctx, jobID, updates); err != nil {
 		return fmt.Errorf("mark job success: %w", err)
 	}
+	s.publishJobEvent(ctx, jobID, EventJobSucceeded, JobStatusSucceeded, "")
 	return nil
 }
 
-// MarkJobFailed marks a job as failed with the provided reason.
-func (s *Store) MarkJobFailed(ctx context.Context, jobID int64, reason string) error {
+// MarkJobFailed marks a job as failed with the provided reason, recording resp when non-nil.
+func (s *Store) MarkJobFailed(ctx context.Context, jobID int64, reason string, resp *JobResponse) error {
 	now := time.Now()
 	updates := map[string]interface{}{
 		"status":     JobStatusFailed,
@@ -561,13 +897,105 @@ func (s *Store) MarkJobFailed(ctx context.Context, jobID int64, reason string) e
 		"ended_at":   now,
 		"error":      reason,
 	}
+	addJobResponse(updates, resp)
 
-	if err := s.db.WithContext(ctx).Model(&database.Job{}).Where("id = ?", uint(jobID)).Updates(updates).Error; err != nil {
+	if err := s.finishJob(ctx, jobID, updates); err != nil {
 		return fmt.Errorf("mark job failed: %w", err)
 	}
+	s.publishJobEvent(ctx, jobID, EventJobFailed, JobStatusFailed, reason)
+	return nil
+}
+
+// finishJob applies the final updates of a job unless it was cancelled meanwhile, in which case
+// it returns ErrRunCancelled.
+func (s *Store) finishJob(ctx context.Context, jobID int64, updates map[string]interface{}) error {
+	res := s.db.WithContext(ctx).Model(&database.Job{}).
+		Where("id = ? AND status <> ?", uint(jobID), JobStatusCancelled).
+		Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRunCancelled
+	}
 	return nil
 }
 
+// addJobResponse adds the captured reaction outcome to a job update.
+func addJobResponse(updates map[string]interface{}, resp *JobResponse) {
+	if resp == nil {
+		return
+	}
+	updates["response_status"] = resp.StatusCode
+	updates["response_body"] = resp.Body
+	updates["latency_ms"] = resp.Latency.Milliseconds()
+}
+
+// ReleaseJob hands a claimed job back to the queue, e.g. when the executor shuts down mid-send.
+func (s *Store) ReleaseJob(ctx context.Context, jobID, runID int64) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&database.Job{}).
+			Where("id = ? AND status = ?", uint(jobID), JobStatusProcessing).
+			Updates(map[string]interface{}{
+				"status":     JobStatusPending,
+				"started_at": nil,
+				"updated_at": time.Now(),
+			}).Error; err != nil {
+			return fmt.Errorf("release job: %w", err)
+		}
+		if err := tx.Model(&database.Run{}).
+			Where("id = ? AND status = ?", uint(runID), RunStatusRunning).
+			Updates(map[string]interface{}{
+				"status":     RunStatusPending,
+				"started_at": nil,
+			}).Error; err != nil {
+			return fmt.Errorf("release run: %w", err)
+		}
+		return nil
+	})
+}
+
+// RequeueStaleJobs releases jobs stuck in processing since before, left behind by an executor
+// that died without releasing them, and returns how many were requeued.
+func (s *Store) RequeueStaleJobs(ctx context.Context, before time.Time) (int64, error) {
+	var requeued int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var runIDs []int64
+		if err := tx.Model(&database.Job{}).
+			Where("status = ? AND started_at < ?", JobStatusProcessing, before).
+			Pluck("run_id", &runIDs).Error; err != nil {
+			return fmt.Errorf("list stale jobs: %w", err)
+		}
+		if len(runIDs) == 0 {
+			return nil
+		}
+		result := tx.Model(&database.Job{}).
+			Where("status = ? AND started_at < ?", JobStatusProcessing, before).
+			Updates(map[string]interface{}{
+				"status":     JobStatusPending,
+				"started_at": nil,
+				"updated_at": time.Now(),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("requeue stale jobs: %w", result.Error)
+		}
+		requeued = result.RowsAffected
+		if err := tx.Model(&database.Run{}).
+			Where("id IN ? AND status = ?", runIDs, RunStatusRunning).
+			Updates(map[string]interface{}{
+				"status":     RunStatusPending,
+				"started_at": nil,
+			}).Error; err != nil {
+			return fmt.Errorf("requeue stale runs: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return requeued, nil
+}
+
 // ClaimDueIntervalWorkflows locks and returns interval workflows whose next_run_at <= now, and advances next_run_at.
 func (s *Store) ClaimDueIntervalWorkflows(ctx context.Context, now time.Time) ([]Workflow, error) {
 	tx := s.db.WithContext(ctx).Begin()
@@ -577,7 +1005,7 @@ func (s *Store) ClaimDueIntervalWorkflows(ctx context.Context, now time.Time) ([
 	defer tx.Rollback()
 
 	var models []database.Workflow
-	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+	err := skipLocked(tx).
 		Where("trigger_type = ? AND enabled = ? AND next_run_at IS NOT NULL AND next_run_at <= ?",
 			"interval", true, now).
 		Find(&models).Error
@@ -608,11 +1036,121 @@ func (s *Store) ClaimDueIntervalWorkflows(ctx context.Context, now time.Time) ([
 	return workflows, nil
 }
 
+// PendingDigest summarizes the buffered events of a workflow waiting for a digest flush.
+type PendingDigest struct {
+	WorkflowID int64
+	Count      int64
+	OldestAt   time.Time
+}
+
+// BufferDigestItem stores a trigger event until the workflow's digest is flushed.
+func (s *Store) BufferDigestItem(ctx context.Context, workflowID int64, payload json.RawMessage) error {
+	model := database.DigestItem{
+		WorkflowID: uint(workflowID),
+		Payload:    payload,
+	}
+	if err := s.db.WithContext(ctx).Create(&model).Error; err != nil {
+		return fmt.Errorf("buffer digest item: %w", err)
+	}
+	return nil
+}
+
+// ListPendingDigests returns, per workflow, how many events are buffered and when the oldest arrived.
+func (s *Store) ListPendingDigests(ctx context.Context) ([]PendingDigest, error) {
+	var rows []struct {
+		WorkflowID uint
+		Count      int64
+		OldestAt   time.Time
+	}
+	err := s.db.WithContext(ctx).
+		Model(&database.DigestItem{}).
+		Select("workflow_id, COUNT(*) AS count, MIN(created_at) AS oldest_at").
+		Group("workflow_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("list pending digests: %w", err)
+	}
+	out := make([]PendingDigest, len(rows))
+	for i, row := range rows {
+		out[i] = PendingDigest{WorkflowID: int64(row.WorkflowID), Count: row.Count, OldestAt: row.OldestAt}
+	}
+	return out, nil
+}
+
+// ClaimDigestItems locks, removes and returns the buffered events of a workflow in arrival order.
+func (s *Store) ClaimDigestItems(ctx context.Context, workflowID int64) ([]json.RawMessage, error) {
+	var items []json.RawMessage
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		items, err = claimDigestItems(tx, workflowID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// FlushDigest claims the buffered events of a workflow and enqueues the run built from them in
+// one transaction, so a failed enqueue leaves the events buffered.
+func (s *Store) FlushDigest(ctx context.Context, workflowID int64, build func(items []json.RawMessage) (json.RawMessage, error)) (*Run, error) {
+	var run *Run
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		items, err := claimDigestItems(tx, workflowID)
+		if err != nil || len(items) == 0 {
+			return err
+		}
+		payload, err := build(items)
+		if err != nil {
+			return err
+		}
+		// Events are published once the run is committed.
+		txStore := &Store{db: tx}
+		if run, err = txStore.CreateRun(ctx, workflowID); err != nil {
+			return err
+		}
+		_, err = txStore.CreateJob(ctx, workflowID, run.ID, payload)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if run != nil {
+		s.publishRunEvent(ctx, run.ID, run.Status, "")
+	}
+	return run, nil
+}
+
+// claimDigestItems locks, deletes and returns the buffered events of a workflow within tx.
+func claimDigestItems(tx *gorm.DB, workflowID int64) ([]json.RawMessage, error) {
+	var models []database.DigestItem
+	if err := skipLocked(tx).
+		Where("workflow_id = ?", uint(workflowID)).
+		Order("created_at, id").
+		Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("claim digest items: %w", err)
+	}
+	if len(models) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, len(models))
+	items := make([]json.RawMessage, len(models))
+	for i, model := range models {
+		ids[i] = model.ID
+		items[i] = model.Payload
+	}
+	if err := tx.Unscoped().Where("id IN ?", ids).Delete(&database.DigestItem{}).Error; err != nil {
+		return nil, fmt.Errorf("delete digest items: %w", err)
+	}
+	return items, nil
+}
+
 // FindWorkflowByToken returns a webhook workflow matching the token stored in trigger_config.
 func (s *Store) FindWorkflowByToken(ctx context.Context, token string) (*Workflow, error) {
 	var model database.Workflow
 	err := s.db.WithContext(ctx).
-		Where("trigger_type = ? AND enabled = ? AND trigger_config->>'token' = ?", "webhook", true, token).
+		Where("trigger_type = ? AND enabled = ? AND "+s.jsonText("trigger_config", "token")+" = ?", "webhook", true, token).
 		First(&model).Error
 
 	if err != nil {
@@ -626,6 +1164,87 @@ func (s *Store) FindWorkflowByToken(ctx context.Context, token string) (*Workflo
 	return &workflow, nil
 }
 
+// reencryptBatch is how many workflows ReencryptSecrets loads at once.
+const reencryptBatch = 100
+
+// ReencryptSecrets re-encrypts the sensitive trigger_config fields of every workflow, deleted
+// ones included, with the current key. It returns how many workflows changed.
+func (s *Store) ReencryptSecrets(ctx context.Context) (int, error) {
+	var models []database.Workflow
+	changed := 0
+	err := s.db.WithContext(ctx).Unscoped().Select("id", "trigger_config").
+		FindInBatches(&models, reencryptBatch, func(*gorm.DB, int) error {
+			for _, model := range models {
+				cfg, fields, err := reencryptTriggerConfig(model.TriggerConfig)
+				if err != nil {
+					return fmt.Errorf("workflow %d: %w", model.ID, err)
+				}
+				if fields == 0 {
+					continue
+				}
+				if err := s.db.WithContext(ctx).Unscoped().Model(&database.Workflow{}).
+					Where("id = ?", model.ID).
+					UpdateColumn("trigger_config", cfg).Error; err != nil {
+					return fmt.Errorf("workflow %d: %w", model.ID, err)
+				}
+				changed++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return changed, fmt.Errorf("re-encrypt workflows: %w", err)
+	}
+	return changed, nil
+}
+
+// ReencryptPayloads re-encrypts, with the current key, the sensitive fields that triggers copied
+// into the payloads of the jobs still to run and of the buffered digest events. It returns how
+// many rows changed.
+func (s *Store) ReencryptPayloads(ctx context.Context) (int, error) {
+	jobs, err := s.reencryptPayloads(ctx, &database.Job{},
+		s.db.WithContext(ctx).Model(&database.Job{}).Where("status IN ?", []string{JobStatusPending, JobStatusProcessing}))
+	if err != nil {
+		return jobs, fmt.Errorf("re-encrypt jobs: %w", err)
+	}
+	digests, err := s.reencryptPayloads(ctx, &database.DigestItem{},
+		s.db.WithContext(ctx).Model(&database.DigestItem{}))
+	if err != nil {
+		return jobs + digests, fmt.Errorf("re-encrypt digest items: %w", err)
+	}
+	return jobs + digests, nil
+}
+
+// reencryptPayloads re-encrypts the payload column of the rows query selects from the table of
+// model, returning how many rows changed.
+func (s *Store) reencryptPayloads(ctx context.Context, model any, query *gorm.DB) (int, error) {
+	var rows []struct {
+		ID      uint
+		Payload json.RawMessage
+	}
+	changed := 0
+	err := query.Select("id", "payload").
+		FindInBatches(&rows, reencryptBatch, func(*gorm.DB, int) error {
+			for _, row := range rows {
+				// Payloads hold trigger config fields, encrypted alike.
+				payload, fields, err := reencryptTriggerConfig(row.Payload)
+				if err != nil {
+					return fmt.Errorf("row %d: %w", row.ID, err)
+				}
+				if fields == 0 {
+					continue
+				}
+				if err := s.db.WithContext(ctx).Model(model).
+					Where("id = ?", row.ID).
+					UpdateColumn("payload", payload).Error; err != nil {
+					return fmt.Errorf("row %d: %w", row.ID, err)
+				}
+				changed++
+			}
+			return nil
+		}).Error
+	return changed, err
+}
+
 // intervalConfigFromJSON parses an IntervalConfig from raw JSON.
 func intervalConfigFromJSON(raw json.RawMessage) (IntervalConfig, error) {
 	if len(raw) == 0 {
@@ -8,11 +8,11 @@ import (
 
 // Triggerer creates runs + jobs when an event (manual/webhook/GitHub) happens.
 type Triggerer struct {
-	store *Store
+	store WorkflowStore
 }
 
 // NewTriggerer creates a Triggerer bound to a Store.
-func NewTriggerer(store *Store) *Triggerer {
+func NewTriggerer(store WorkflowStore) *Triggerer {
 	return &Triggerer{store: store}
 }
 
@@ -36,3 +36,18 @@ func (t *Triggerer) EnqueueRun(ctx context.Context, workflowID int64, payload ma
 	}
 	return run, nil
 }
+
+// EnqueueDigest claims the buffered events of a workflow and enqueues one run with the payload
+// build makes of them, see WorkflowStore.FlushDigest. The run is nil when nothing was buffered.
+func (t *Triggerer) EnqueueDigest(ctx context.Context, workflowID int64, build func(items []json.RawMessage) map[string]any) (*Run, error) {
+	if t.store == nil {
+		return nil, fmt.Errorf("triggerer: store is nil")
+	}
+	return t.store.FlushDigest(ctx, workflowID, func(items []json.RawMessage) (json.RawMessage, error) {
+		encoded, err := json.Marshal(build(items))
+		if err != nil {
+			return nil, fmt.Errorf("encode payload: %w", err)
+		}
+		return encoded, nil
+	})
+}
@@ -0,0 +1,88 @@
+package workflows
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+
+	"area/src/areas"
+)
+
+// triggerValidators lists the trigger types a workflow can use. Their catalog fields are checked
+// first, see areas.CheckFields; the validator then decodes the config as the trigger will and
+// adds the rules fields cannot express. A nil validator means the config is not read.
+var triggerValidators = map[string]func(triggerConfig json.RawMessage) []areas.FieldError{
+	"manual":                     nil,
+	"webhook":                    nil,
+	"gmail_inbound":              nil,
+	"interval":                   decodes(intervalConfigFromJSON),
+	"github_commit":              decodes(githubCommitConfigFromJSON),
+	"github_pull_request":        decodes(githubPRConfigFromJSON),
+	"github_issue":               decodes(githubIssueConfigFromJSON),
+	"weather_temp":               decodes(weatherTempConfigFromJSON),
+	"weather_report":             decodes(weatherReportConfigFromJSON),
+	"reddit_new_post":            decodes(redditNewPostConfigFromJSON),
+	"youtube_new_video":          validateYouTubeConfig,
+	"steam_player_online":        decodes(steamPlayerOnlineConfigFromJSON),
+	"steam_game_sale":            decodes(steamGameSaleConfigFromJSON),
+	"steam_price_change":         decodes(steamPriceChangeConfigFromJSON),
+	"crypto_price_threshold":     decodes(cryptoPriceThresholdConfigFromJSON),
+	"crypto_percent_change":      decodes(cryptoPercentChangeConfigFromJSON),
+	"nasa_apod":                  decodes(nasaApodConfigFromJSON),
+	"nasa_mars_photo":            decodes(nasaMarsPhotoConfigFromJSON),
+	"nasa_neo_close_approach":    decodes(nasaNeoConfigFromJSON),
+	"air_quality_aqi_threshold":  decodes(airQualityAQIConfigFromJSON),
+	"air_quality_pm25_threshold": decodes(airQualityPM25ConfigFromJSON),
+}
+
+// TriggerTypes returns the supported trigger types, sorted.
+func TriggerTypes() []string {
+	return slices.Sorted(maps.Keys(triggerValidators))
+}
+
+// validateTriggerConfig checks triggerConfig against the catalog fields of triggerType and its
+// validator, and returns an *areas.ValidationError listing every invalid field.
+func validateTriggerConfig(triggerType string, triggerConfig json.RawMessage) error {
+	validate := triggerValidators[triggerType]
+	var invalid []areas.FieldError
+	if trigger, ok := areas.FindTrigger(triggerType); ok {
+		invalid = areas.CheckFields(trigger.Fields, triggerConfig)
+	}
+	if len(invalid) == 0 && validate != nil {
+		invalid = validate(triggerConfig)
+	}
+	if len(invalid) > 0 {
+		return &areas.ValidationError{Fields: invalid}
+	}
+	return nil
+}
+
+// decodes adapts the config parser of a trigger into a validator: a value the catalog accepts
+// may still not fit the Go type, such as 1.5 for an integer.
+func decodes[T any](parse func(json.RawMessage) (T, error)) func(json.RawMessage) []areas.FieldError {
+	return func(raw json.RawMessage) []areas.FieldError {
+		_, err := parse(raw)
+		if err == nil {
+			return nil
+		}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return []areas.FieldError{{Field: typeErr.Field, Message: "must be a " + typeErr.Type.String()}}
+		}
+		return []areas.FieldError{{Message: "is not a valid config: " + err.Error()}}
+	}
+}
+
+// validateYouTubeConfig requires the channel, given by name or handle or as channel_id.
+func validateYouTubeConfig(raw json.RawMessage) []areas.FieldError {
+	cfg, err := youtubeNewVideoConfigFromJSON(raw)
+	if err != nil {
+		return decodes(youtubeNewVideoConfigFromJSON)(raw)
+	}
+	if strings.TrimSpace(cfg.ChannelID) == "" && strings.TrimSpace(cfg.Channel) == "" {
+		return []areas.FieldError{{Field: "channel", Message: "is required"}}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package areas
+
+import (
+	"area/src/areas"
+	"area/src/config"
+	"area/src/database"
+	"area/src/httpapi"
+	"area/src/workflows"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestDefinitions_MatchImplementation(t *testing.T) {
+	if err := areas.Check(areas.Definitions, workflows.TriggerTypes(), httpapi.ActionRoutes()); err != nil {
+		t.Fatalf("catalog is inconsistent:\n%v", err)
+	}
+}
+
+func TestCheck_ReportsMismatches(t *testing.T) {
+	defs := []areas.Service{{
+		ID: "demo",
+		Triggers: []areas.Capability{
+			{ID: "unknown_trigger"},
+			{ID: "manual", Fields: []areas.Field{{Key: "x", Type: "date"}}},
+		},
+		Reactions: []areas.Capability{{ID: "post", ActionURL: "/actions/demo/post"}},
+	}}
+	err := areas.Check(defs, []string{"manual"}, []string{"/actions/demo/other"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"trigger demo/unknown_trigger has no implementation",
+		`field demo/manual.x has unknown type "date"`,
+		"reaction demo/post points to unknown route /actions/demo/post",
+		"route /actions/demo/other has no catalog entry",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestSync_UpsertsAndRemovesStaleEntries(t *testing.T) {
+	db, err := gorm.Open(database.Dialector(config.Database{
+		Driver:     config.DriverSQLite,
+		SQLitePath: filepath.Join(t.TempDir(), "area.db"),
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	ctx := context.Background()
+
+	// Twice: the second run must update in place.
+	for range 2 {
+		if err := areas.Sync(ctx, db, areas.Definitions); err != nil {
+			t.Fatalf("Sync: %v", err)
+		}
+	}
+	var services int64
+	db.Model(&database.AreaService{}).Count(&services)
+	if int(services) != len(areas.Definitions) {
+		t.Fatalf("area_services = %d, want %d", services, len(areas.Definitions))
+	}
+	// The seed attached the GitHub reaction fields to the triggers of the same id.
+	var stale int64
+	db.Model(&database.AreaField{}).Where("capability_id = ? AND key = ?", "github_issue", "title").Count(&stale)
+	if stale != 0 {
+		t.Fatal("stale field github_issue.title was kept")
+	}
+
+	defs := []areas.Service{{
+		ID:       "demo",
+		Name:     "Demo",
+		Enabled:  true,
+		Triggers: []areas.Capability{{ID: "manual", Name: "Manual", Fields: []areas.Field{{Key: "note", Type: "string", Example: "hi"}}}},
+	}}
+	if err := areas.Sync(ctx, db, defs); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	database.SetDBForTesting(db)
+	got, err := areas.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "demo" || len(got[0].Triggers) != 1 || got[0].Triggers[0].Fields[0].Example != "hi" {
+		t.Fatalf("List = %+v", got)
+	}
+}
@@ -0,0 +1,48 @@
+package areas
+
+import (
+	"area/src/areas"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCheckFields(t *testing.T) {
+	min, max := 1.0, 7.0
+	fields := []areas.Field{
+		{Key: "city", Type: "string", Required: true},
+		{Key: "direction", Type: "string", Enum: []string{"above", "below"}},
+		{Key: "days", Type: "number", Min: &min, Max: &max},
+		{Key: "repo", Type: "string", Pattern: `^[\w.-]+/[\w.-]+$`},
+		{Key: "actions", Type: "array<string>", Enum: []string{"opened", "closed"}},
+		{Key: "digest", Type: "object"},
+	}
+	cases := []struct {
+		name string
+		raw  string
+		want []areas.FieldError
+	}{
+		{"valid", `{"city":"Paris","direction":"Above","days":3,"repo":"o/r","actions":["opened"],"digest":{}}`, nil},
+		{"empty config", ``, []areas.FieldError{{Field: "city", Message: "is required"}}},
+		{"blank string", `{"city":"  "}`, []areas.FieldError{{Field: "city", Message: "is required"}}},
+		{"wrong type", `{"city":3}`, []areas.FieldError{{Field: "city", Message: "must be a string"}}},
+		{"enum", `{"city":"Paris","direction":"sideways"}`, []areas.FieldError{{Field: "direction", Message: "must be one of above, below"}}},
+		{"range", `{"city":"Paris","days":8}`, []areas.FieldError{{Field: "days", Message: "must be at most 7"}}},
+		{"pattern", `{"city":"Paris","repo":"nope"}`, []areas.FieldError{{Field: "repo", Message: `must match ^[\w.-]+/[\w.-]+$`}}},
+		{"array item", `{"city":"Paris","actions":["opened","merged"]}`, []areas.FieldError{{Field: "actions", Message: "item 1 must be one of opened, closed"}}},
+		{"not an object", `[1]`, []areas.FieldError{{Message: "must be a JSON object"}}},
+		{"several", `{"days":0,"digest":"daily"}`, []areas.FieldError{
+			{Field: "city", Message: "is required"},
+			{Field: "days", Message: "must be at least 1"},
+			{Field: "digest", Message: "must be an object"},
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := areas.CheckFields(fields, json.RawMessage(tc.raw))
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("CheckFields(%s) = %+v, want %+v", tc.raw, got, tc.want)
+			}
+		})
+	}
+}
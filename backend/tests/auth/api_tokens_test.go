@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"area/src/auth"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// memAPITokens is an APITokenStore keeping tokens in a map.
+type memAPITokens struct {
+	tokens  map[string]*auth.APIToken
+	touched int
+}
+
+func (m *memAPITokens) CreateAPIToken(_ context.Context, token *auth.APIToken, hash string) error {
+	token.ID = int64(len(m.tokens) + 1)
+	stored := *token
+	m.tokens[hash] = &stored
+	return nil
+}
+
+func (m *memAPITokens) ListAPITokens(_ context.Context, userID int64) ([]auth.APIToken, error) {
+	var out []auth.APIToken
+	for _, token := range m.tokens {
+		if token.UserID == userID {
+			out = append(out, *token)
+		}
+	}
+	return out, nil
+}
+
+func (m *memAPITokens) APITokenByHash(_ context.Context, hash string) (*auth.APIToken, error) {
+	token, ok := m.tokens[hash]
+	if !ok {
+		return nil, auth.ErrInvalidToken
+	}
+	found := *token
+	return &found, nil
+}
+
+func (m *memAPITokens) TouchAPIToken(_ context.Context, id int64, at time.Time) error {
+	m.touched++
+	for _, token := range m.tokens {
+		if token.ID == id {
+			token.LastUsedAt = &at
+		}
+	}
+	return nil
+}
+
+func (m *memAPITokens) DeleteAPIToken(_ context.Context, userID, id int64) error {
+	for hash, token := range m.tokens {
+		if token.ID == id && token.UserID == userID {
+			delete(m.tokens, hash)
+			return nil
+		}
+	}
+	return auth.ErrAPITokenNotFound
+}
+
+func newAPITokenService(now *time.Time) (*auth.Service, *memAPITokens) {
+	store := &memAPITokens{tokens: make(map[string]*auth.APIToken)}
+	svc := auth.NewService(&fakeUserStore{})
+	svc.APITokens = store
+	svc.Now = func() time.Time { return *now }
+	return svc, store
+}
+
+func TestAPIToken_CreateAndVerify(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc, store := newAPITokenService(&now)
+	expires := now.Add(24 * time.Hour)
+
+	secret, token, err := svc.CreateAPIToken(ctx, 7, " ci ", []string{auth.ScopeWorkflowsTrigger, auth.ScopeWorkflowsTrigger}, &expires)
+	if err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+	if token.Name != "ci" || len(token.Scopes) != 1 {
+		t.Fatalf("unexpected token %+v", token)
+	}
+	for hash := range store.tokens {
+		if hash == secret {
+			t.Fatal("token stored in clear")
+		}
+	}
+
+	verified, err := svc.VerifyAPIToken(ctx, secret)
+	if err != nil {
+		t.Fatalf("VerifyAPIToken: %v", err)
+	}
+	if verified.UserID != 7 || !verified.Allows(auth.ScopeWorkflowsTrigger) || verified.Allows(auth.ScopeWorkflowsWrite) {
+		t.Fatalf("unexpected verified token %+v", verified)
+	}
+	if verified.LastUsedAt == nil || !verified.LastUsedAt.Equal(now) {
+		t.Fatalf("last use not recorded: %v", verified.LastUsedAt)
+	}
+	now = now.Add(time.Second)
+	if _, err := svc.VerifyAPIToken(ctx, secret); err != nil || store.touched != 1 {
+		t.Fatalf("second use within a minute: err = %v, touched = %d", err, store.touched)
+	}
+
+	now = expires
+	if _, err := svc.VerifyAPIToken(ctx, secret); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("expired token: err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestAPIToken_RejectsInvalidRequests(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc, _ := newAPITokenService(&now)
+	past := now.Add(-time.Hour)
+	cases := map[string]struct {
+		name      string
+		scopes    []string
+		expiresAt *time.Time
+		field     string
+	}{
+		"no name":     {name: "", scopes: []string{auth.ScopeWorkflowsRead}, field: "name"},
+		"no scopes":   {name: "ci", field: "scopes"},
+		"bad scope":   {name: "ci", scopes: []string{"admin"}, field: "scopes"},
+		"past expiry": {name: "ci", scopes: []string{auth.ScopeWorkflowsRead}, expiresAt: &past, field: "expires_at"},
+	}
+	for label, tc := range cases {
+		_, _, err := svc.CreateAPIToken(context.Background(), 7, tc.name, tc.scopes, tc.expiresAt)
+		var invalid *auth.ValidationError
+		if !errors.As(err, &invalid) || invalid.Field != tc.field {
+			t.Errorf("%s: err = %v, want invalid %s", label, err, tc.field)
+		}
+	}
+}
+
+func TestAPIToken_DeleteOnlyOwn(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc, _ := newAPITokenService(&now)
+	secret, token, err := svc.CreateAPIToken(ctx, 7, "ci", []string{auth.ScopeWorkflowsRead}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+	if err := svc.DeleteAPIToken(ctx, 8, token.ID); !errors.Is(err, auth.ErrAPITokenNotFound) {
+		t.Fatalf("deleting another user's token: err = %v", err)
+	}
+	if err := svc.DeleteAPIToken(ctx, 7, token.ID); err != nil {
+		t.Fatalf("DeleteAPIToken: %v", err)
+	}
+	if _, err := svc.VerifyAPIToken(ctx, secret); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("deleted token: err = %v, want ErrInvalidToken", err)
+	}
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"area/src/auth"
+	"context"
 	"testing"
 )
 
@@ -62,7 +63,7 @@ func TestServiceRegister_Success(t *testing.T) {
 	store := &fakeUserStore{}
 	svc := auth.NewService(store)
 
-	user, err := svc.Register("user@example.com", "password", "Ada", "Lovelace")
+	user, err := svc.Register(context.Background(), "user@example.com", "password", "Ada", "Lovelace")
 	if err != nil {
 		t.Fatalf("Register error: %v", err)
 	}
@@ -78,7 +79,7 @@ func TestServiceRegister_UserExists(t *testing.T) {
 	store := &fakeUserStore{createErr: auth.ErrUserExists}
 	svc := auth.NewService(store)
 
-	if _, err := svc.Register("user@example.com", "password", "Ada", "Lovelace"); err != auth.ErrUserExists {
+	if _, err := svc.Register(context.Background(), "user@example.com", "password", "Ada", "Lovelace"); err != auth.ErrUserExists {
 		t.Fatalf("expected ErrUserExists, got %v", err)
 	}
 }
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"area/src/auth"
+	"context"
 	"errors"
 	"testing"
 )
@@ -79,7 +80,7 @@ func TestAuthenticate_StoreError(t *testing.T) {
 func TestRegister_Success(t *testing.T) {
 	store := &stubStore{}
 	svc := auth.NewService(store)
-	user, err := svc.Register("a@b.com", "pw", "f", "l")
+	user, err := svc.Register(context.Background(), "a@b.com", "pw", "f", "l")
 	if err != nil {
 		t.Fatalf("Register error: %v", err)
 	}
@@ -94,7 +95,7 @@ func TestRegister_Success(t *testing.T) {
 func TestRegister_CreateError(t *testing.T) {
 	store := &stubStore{createErr: errors.New("insert fail")}
 	svc := auth.NewService(store)
-	if _, err := svc.Register("a@b.com", "pw", "f", "l"); err == nil {
+	if _, err := svc.Register(context.Background(), "a@b.com", "pw", "f", "l"); err == nil {
 		t.Fatalf("expected create error")
 	}
 }
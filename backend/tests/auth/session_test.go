@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"area/src/auth"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// memSessions is a SessionStore keeping sessions in maps.
+type memSessions struct {
+	sessions map[int64]*auth.Session
+	access   map[string]int64
+	refresh  map[string]int64
+}
+
+func newMemSessions() *memSessions {
+	return &memSessions{
+		sessions: make(map[int64]*auth.Session),
+		access:   make(map[string]int64),
+		refresh:  make(map[string]int64),
+	}
+}
+
+func (m *memSessions) CreateSession(_ context.Context, session *auth.Session, accessHash, refreshHash string) error {
+	session.ID = int64(len(m.sessions) + 1)
+	stored := *session
+	m.sessions[session.ID] = &stored
+	m.access[accessHash] = session.ID
+	m.refresh[refreshHash] = session.ID
+	return nil
+}
+
+func (m *memSessions) lookup(index map[string]int64, hash string) (*auth.Session, error) {
+	id, ok := index[hash]
+	if !ok {
+		return nil, auth.ErrInvalidToken
+	}
+	session := *m.sessions[id]
+	return &session, nil
+}
+
+func (m *memSessions) SessionByAccessHash(_ context.Context, hash string) (*auth.Session, error) {
+	return m.lookup(m.access, hash)
+}
+
+func (m *memSessions) SessionByRefreshHash(_ context.Context, hash string) (*auth.Session, error) {
+	return m.lookup(m.refresh, hash)
+}
+
+func (m *memSessions) RotateSession(_ context.Context, session *auth.Session, oldRefreshHash, accessHash, refreshHash string) (bool, error) {
+	if m.refresh[oldRefreshHash] != session.ID || m.sessions[session.ID].RevokedAt != nil {
+		return false, nil
+	}
+	for hash, id := range m.access {
+		if id == session.ID {
+			delete(m.access, hash)
+		}
+	}
+	delete(m.refresh, oldRefreshHash)
+	m.access[accessHash] = session.ID
+	m.refresh[refreshHash] = session.ID
+	stored := *session
+	m.sessions[session.ID] = &stored
+	return true, nil
+}
+
+func (m *memSessions) RevokeSession(_ context.Context, id int64, at time.Time) error {
+	m.sessions[id].RevokedAt = &at
+	return nil
+}
+
+func (m *memSessions) RevokeUserSessions(_ context.Context, userID int64, at time.Time) error {
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func newSessionService(now *time.Time) *auth.Service {
+	svc := auth.NewService(&fakeUserStore{})
+	svc.Sessions = newMemSessions()
+	svc.Now = func() time.Time { return *now }
+	return svc
+}
+
+func TestSession_AccessTokenExpires(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc := newSessionService(&now)
+
+	tokens, err := svc.StartSession(ctx, 7)
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	if tokens.TokenType != "Bearer" || tokens.ExpiresIn != int64(auth.DefaultAccessTTL/time.Second) {
+		t.Fatalf("unexpected tokens %+v", tokens)
+	}
+	session, err := svc.VerifyAccessToken(ctx, tokens.AccessToken)
+	if err != nil || session.UserID != 7 {
+		t.Fatalf("VerifyAccessToken = %+v, %v", session, err)
+	}
+	if _, err := svc.VerifyAccessToken(ctx, tokens.RefreshToken); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("refresh token accepted as access token: %v", err)
+	}
+
+	now = now.Add(auth.DefaultAccessTTL)
+	if _, err := svc.VerifyAccessToken(ctx, tokens.AccessToken); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("expired token: err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestSession_RefreshRotatesTokens(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc := newSessionService(&now)
+
+	first, err := svc.StartSession(ctx, 7)
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	now = now.Add(time.Hour)
+	second, err := svc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, err := svc.VerifyAccessToken(ctx, second.AccessToken); err != nil {
+		t.Fatalf("new access token rejected: %v", err)
+	}
+	if _, err := svc.VerifyAccessToken(ctx, first.AccessToken); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("old access token: err = %v, want ErrInvalidToken", err)
+	}
+	if _, err := svc.Refresh(ctx, first.RefreshToken); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("reused refresh token: err = %v, want ErrInvalidToken", err)
+	}
+
+	now = now.Add(auth.DefaultRefreshTTL)
+	if _, err := svc.Refresh(ctx, second.RefreshToken); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("expired refresh token: err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestSession_LogoutRevokes(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc := newSessionService(&now)
+
+	tokens, err := svc.StartSession(ctx, 7)
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	if err := svc.Logout(ctx, tokens.AccessToken); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, err := svc.VerifyAccessToken(ctx, tokens.AccessToken); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("access token after logout: err = %v, want ErrInvalidToken", err)
+	}
+	if _, err := svc.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("refresh token after logout: err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestSession_NoStore(t *testing.T) {
+	svc := auth.NewService(&fakeUserStore{})
+	if _, err := svc.StartSession(context.Background(), 1); !errors.Is(err, auth.ErrNoSessionStore) {
+		t.Fatalf("err = %v, want ErrNoSessionStore", err)
+	}
+}
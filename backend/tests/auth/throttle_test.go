@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"area/src/auth"
+	"area/src/mail"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memThrottle is a LoginThrottleStore keeping counts in maps.
+type memThrottle struct {
+	failures map[string]int
+	last     map[string]time.Time
+	locked   map[string]time.Time
+	logged   int
+}
+
+func newMemThrottle() *memThrottle {
+	return &memThrottle{
+		failures: make(map[string]int),
+		last:     make(map[string]time.Time),
+		locked:   make(map[string]time.Time),
+	}
+}
+
+func (m *memThrottle) LoginLockedUntil(_ context.Context, keys []string) (time.Time, error) {
+	var until time.Time
+	for _, key := range keys {
+		if m.locked[key].After(until) {
+			until = m.locked[key]
+		}
+	}
+	return until, nil
+}
+
+func (m *memThrottle) AddLoginFailure(_ context.Context, key string, at, forgetBefore time.Time) (int, error) {
+	if m.last[key].Before(forgetBefore) {
+		m.failures[key] = 0
+	}
+	m.failures[key]++
+	m.last[key] = at
+	return m.failures[key], nil
+}
+
+func (m *memThrottle) LockLogin(_ context.Context, key string, until time.Time) error {
+	m.locked[key] = until
+	return nil
+}
+
+func (m *memThrottle) ResetLoginFailures(_ context.Context, key string) error {
+	delete(m.failures, key)
+	delete(m.last, key)
+	delete(m.locked, key)
+	return nil
+}
+
+func (m *memThrottle) LogLoginFailure(context.Context, string, string, time.Time) error {
+	m.logged++
+	return nil
+}
+
+// oneUserStore is a UserStore holding a single user.
+type oneUserStore struct {
+	user *auth.User
+	hash string
+}
+
+func (s *oneUserStore) Create(*auth.User, string) error { return auth.ErrUserExists }
+
+func (s *oneUserStore) GetByEmail(email string) (*auth.User, string, error) {
+	if !strings.EqualFold(email, s.user.Email) {
+		return nil, "", auth.ErrInvalidCredentials
+	}
+	return s.user, s.hash, nil
+}
+
+func newThrottledService(t *testing.T, now *time.Time) (*auth.Service, *memThrottle) {
+	t.Helper()
+	hash, err := auth.HashPassword("secret")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	store := &oneUserStore{user: &auth.User{ID: 1, Email: "user@example.com"}, hash: hash}
+	throttle := newMemThrottle()
+	svc := auth.NewService(store)
+	svc.Throttle = throttle
+	svc.ThrottlePolicy = auth.ThrottlePolicy{AccountAttempts: 3, IPAttempts: 5, Lockout: 30 * time.Second, MaxLockout: 2 * time.Minute}
+	svc.Now = func() time.Time { return *now }
+	return svc, throttle
+}
+
+func lockedFor(t *testing.T, err error) time.Duration {
+	t.Helper()
+	var locked *auth.LoginLockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("err = %v, want *LoginLockedError", err)
+	}
+	return locked.RetryAfter
+}
+
+func TestLogin_LocksAccountWithGrowingLockout(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc, throttle := newThrottledService(t, &now)
+
+	for i := range 3 {
+		if _, _, err := svc.Login(ctx, "user@example.com", "wrong", "198.51.100.1"); !errors.Is(err, auth.ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+	if throttle.logged != 3 {
+		t.Fatalf("logged %d failures, want 3", throttle.logged)
+	}
+	// Locked out, even with the right password and from another address.
+	_, _, err := svc.Login(ctx, "User@Example.com", "secret", "203.0.113.9")
+	if got := lockedFor(t, err); got != 30*time.Second {
+		t.Fatalf("first lockout = %s, want 30s", got)
+	}
+
+	now = now.Add(31 * time.Second)
+	if _, _, err := svc.Login(ctx, "user@example.com", "wrong", "198.51.100.1"); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Fatalf("after the lockout: %v", err)
+	}
+	_, _, err = svc.Login(ctx, "user@example.com", "secret", "198.51.100.1")
+	if got := lockedFor(t, err); got != time.Minute {
+		t.Fatalf("second lockout = %s, want 1m", got)
+	}
+	for range 3 {
+		now = now.Add(3 * time.Minute)
+		svc.Login(ctx, "user@example.com", "wrong", "198.51.100.2")
+	}
+	_, _, err = svc.Login(ctx, "user@example.com", "secret", "198.51.100.2")
+	if got := lockedFor(t, err); got != 2*time.Minute {
+		t.Fatalf("lockout = %s, want the 2m cap", got)
+	}
+
+	now = now.Add(3 * time.Minute)
+	if _, _, err := svc.Login(ctx, "user@example.com", "secret", "198.51.100.2"); err != nil {
+		t.Fatalf("login after the lockout: %v", err)
+	}
+	if _, ok := throttle.failures["email:user@example.com"]; ok {
+		t.Fatal("a successful login must forget the account's failures")
+	}
+}
+
+func TestLogin_LocksUnknownEmailsAndAddresses(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc, _ := newThrottledService(t, &now)
+
+	for range 3 {
+		svc.Login(ctx, "nobody@example.com", "guess", "198.51.100.1")
+	}
+	_, _, err := svc.Login(ctx, "nobody@example.com", "guess", "198.51.100.7")
+	lockedFor(t, err)
+
+	// Five failures from one address, spread over accounts, lock the address out.
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		svc.Login(ctx, email, "guess", "198.51.100.1")
+	}
+	_, _, err = svc.Login(ctx, "user@example.com", "secret", "198.51.100.1")
+	lockedFor(t, err)
+	if _, _, err := svc.Login(ctx, "user@example.com", "secret", "198.51.100.2"); err != nil {
+		t.Fatalf("login from another address: %v", err)
+	}
+}
+
+func TestLogin_ForgetsOldFailures(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc, throttle := newThrottledService(t, &now)
+
+	for range 2 {
+		svc.Login(ctx, "user@example.com", "wrong", "")
+	}
+	now = now.Add(25 * time.Hour)
+	svc.Login(ctx, "user@example.com", "wrong", "")
+	if got := throttle.failures["email:user@example.com"]; got != 1 {
+		t.Fatalf("failures = %d, want 1 once the previous ones are a day old", got)
+	}
+}
+
+// failingMailer is a Mailer whose server is down.
+type failingMailer struct {
+	sent chan struct{}
+}
+
+func (m failingMailer) Send(context.Context, mail.Message) error {
+	m.sent <- struct{}{}
+	return errors.New("connection refused")
+}
+
+func TestForgotPassword_HidesMailFailuresAndIsThrottled(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc, throttle := newThrottledService(t, &now)
+	svc.Accounts = &memAccounts{tokens: make(map[string]time.Time)}
+	mailer := failingMailer{sent: make(chan struct{}, 4)}
+	svc.Mailer = mailer
+
+	for _, email := range []string{"user@example.com", "nobody@example.com", "USER@example.com", "user@example.com"} {
+		if err := svc.ForgotPassword(ctx, email, "198.51.100.1"); err != nil {
+			t.Fatalf("ForgotPassword(%s) = %v, want the same answer for every email", email, err)
+		}
+		if err := svc.Wait(ctx); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	}
+	if len(mailer.sent) != 3 {
+		t.Fatalf("%d emails attempted, want 3", len(mailer.sent))
+	}
+
+	err := svc.ForgotPassword(ctx, "user@example.com", "198.51.100.2")
+	var limited *auth.PasswordResetLimitedError
+	if !errors.As(err, &limited) || limited.RetryAfter != 30*time.Second {
+		t.Fatalf("fourth request for the email = %v, want a 30s limit", err)
+	}
+	if _, ok := throttle.failures["email:user@example.com"]; ok {
+		t.Fatal("reset requests must not count as failed logins")
+	}
+	if _, _, err := svc.Login(ctx, "user@example.com", "secret", "198.51.100.1"); err != nil {
+		t.Fatalf("login while resets are limited: %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"area/src/auth"
+	"context"
+	"encoding/base32"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// memTwoFactor is a TwoFactorStore keeping one user's enrollment in memory.
+type memTwoFactor struct {
+	enrollment *auth.TwoFactor
+	recovery   map[string]bool // hash -> used
+}
+
+func (m *memTwoFactor) TwoFactor(_ context.Context, _ int64) (*auth.TwoFactor, error) {
+	if m.enrollment == nil {
+		return nil, auth.ErrTwoFactorNotEnrolled
+	}
+	found := *m.enrollment
+	for _, used := range m.recovery {
+		if !used {
+			found.RecoveryCodesLeft++
+		}
+	}
+	return &found, nil
+}
+
+func (m *memTwoFactor) SaveTwoFactorSecret(_ context.Context, _ int64, secret string) error {
+	m.enrollment = &auth.TwoFactor{Secret: secret}
+	return nil
+}
+
+func (m *memTwoFactor) EnableTwoFactor(_ context.Context, _ int64, _ time.Time, step int64, hashes []string) (bool, error) {
+	if m.enrollment == nil || m.enrollment.Enabled {
+		return false, nil
+	}
+	m.enrollment.Enabled = true
+	m.enrollment.LastUsedStep = step
+	m.recovery = make(map[string]bool)
+	for _, hash := range hashes {
+		m.recovery[hash] = false
+	}
+	return true, nil
+}
+
+func (m *memTwoFactor) UseTwoFactorStep(_ context.Context, _ int64, step int64) (bool, error) {
+	if step <= m.enrollment.LastUsedStep {
+		return false, nil
+	}
+	m.enrollment.LastUsedStep = step
+	return true, nil
+}
+
+func (m *memTwoFactor) UseRecoveryCode(_ context.Context, _ int64, hash string, _ time.Time) (bool, error) {
+	used, ok := m.recovery[hash]
+	if !ok || used {
+		return false, nil
+	}
+	m.recovery[hash] = true
+	return true, nil
+}
+
+func (m *memTwoFactor) DeleteTwoFactor(_ context.Context, _ int64) error {
+	m.enrollment, m.recovery = nil, nil
+	return nil
+}
+
+// memAccounts is an AccountStore for the user with ID 1.
+type memAccounts struct {
+	tokens map[string]time.Time // hash -> expiry; removed once used
+}
+
+func (m *memAccounts) CreateAccountToken(_ context.Context, _ int64, _, hash string, expiresAt time.Time) error {
+	m.tokens[hash] = expiresAt
+	return nil
+}
+
+func (m *memAccounts) ConsumeAccountToken(_ context.Context, _, hash string, now time.Time) (int64, error) {
+	expiresAt, ok := m.tokens[hash]
+	delete(m.tokens, hash)
+	if !ok || !now.Before(expiresAt) {
+		return 0, auth.ErrInvalidToken
+	}
+	return 1, nil
+}
+
+func (m *memAccounts) SetPassword(context.Context, int64, string) error { return nil }
+
+func (m *memAccounts) MarkEmailVerified(context.Context, int64, time.Time) error { return nil }
+
+func (m *memAccounts) UserByID(_ context.Context, id int64) (*auth.User, error) {
+	return &auth.User{ID: id, Email: "a@b.com"}, nil
+}
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	// The RFC lists 8 digits; authenticator apps use the last 6.
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range cases {
+		got, err := auth.TOTPCode(secret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", unix, err)
+		}
+		if got != want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func newTwoFactorService(now *time.Time) (*auth.Service, *memTwoFactor) {
+	store := &memTwoFactor{}
+	svc := auth.NewService(nil)
+	svc.Accounts = &memAccounts{tokens: make(map[string]time.Time)}
+	svc.TwoFactor = store
+	svc.Now = func() time.Time { return *now }
+	return svc, store
+}
+
+func TestTwoFactor_EnrollVerifyAndLogin(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc, _ := newTwoFactorService(&now)
+
+	if challenge, err := svc.LoginChallenge(ctx, 1); err != nil || challenge != "" {
+		t.Fatalf("LoginChallenge before enrollment = %q, %v; want no challenge", challenge, err)
+	}
+	enrollment, err := svc.EnrollTwoFactor(ctx, 1)
+	if err != nil {
+		t.Fatalf("EnrollTwoFactor: %v", err)
+	}
+	uri, err := url.Parse(enrollment.OTPAuthURI)
+	if err != nil || uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Query().Get("secret") != enrollment.Secret {
+		t.Fatalf("otpauth URI = %q", enrollment.OTPAuthURI)
+	}
+	if challenge, _ := svc.LoginChallenge(ctx, 1); challenge != "" {
+		t.Fatal("an enrollment not yet verified must not change login")
+	}
+
+	if _, err := svc.ActivateTwoFactor(ctx, 1, "000000"); !errors.Is(err, auth.ErrInvalidTwoFactorCode) {
+		t.Fatalf("ActivateTwoFactor with a wrong code: %v", err)
+	}
+	code, _ := auth.TOTPCode(enrollment.Secret, now)
+	recovery, err := svc.ActivateTwoFactor(ctx, 1, code)
+	if err != nil {
+		t.Fatalf("ActivateTwoFactor: %v", err)
+	}
+	if len(recovery) != auth.RecoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(recovery), auth.RecoveryCodeCount)
+	}
+
+	challenge, err := svc.LoginChallenge(ctx, 1)
+	if err != nil || challenge == "" {
+		t.Fatalf("LoginChallenge = %q, %v; want a challenge", challenge, err)
+	}
+	if _, err := svc.CompleteLogin(ctx, challenge, code, ""); !errors.Is(err, auth.ErrInvalidTwoFactorCode) {
+		t.Fatalf("reusing the verification code: %v, want ErrInvalidTwoFactorCode", err)
+	}
+	if _, err := svc.CompleteLogin(ctx, challenge, code, ""); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("a challenge must work once: %v", err)
+	}
+
+	now = now.Add(30 * time.Second)
+	code, _ = auth.TOTPCode(enrollment.Secret, now)
+	challenge, _ = svc.LoginChallenge(ctx, 1)
+	user, err := svc.CompleteLogin(ctx, challenge, code, "")
+	if err != nil || user.ID != 1 {
+		t.Fatalf("CompleteLogin = %v, %v", user, err)
+	}
+}
+
+func TestTwoFactor_CodesAcceptOneStepOfSkew(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc, store := newTwoFactorService(&now)
+	enrollment, _ := svc.EnrollTwoFactor(ctx, 1)
+	code, _ := auth.TOTPCode(enrollment.Secret, now)
+	if _, err := svc.ActivateTwoFactor(ctx, 1, code); err != nil {
+		t.Fatalf("ActivateTwoFactor: %v", err)
+	}
+
+	now = now.Add(5 * time.Minute)
+	late, _ := auth.TOTPCode(enrollment.Secret, now.Add(-30*time.Second))
+	challenge, _ := svc.LoginChallenge(ctx, 1)
+	if _, err := svc.CompleteLogin(ctx, challenge, late, ""); err != nil {
+		t.Fatalf("a code one step behind: %v", err)
+	}
+	stale, _ := auth.TOTPCode(enrollment.Secret, now.Add(-90*time.Second))
+	store.enrollment.LastUsedStep = 0
+	challenge, _ = svc.LoginChallenge(ctx, 1)
+	if _, err := svc.CompleteLogin(ctx, challenge, stale, ""); !errors.Is(err, auth.ErrInvalidTwoFactorCode) {
+		t.Fatalf("a code three steps behind: %v, want ErrInvalidTwoFactorCode", err)
+	}
+}
+
+func TestTwoFactor_RecoveryCodes(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc, store := newTwoFactorService(&now)
+	enrollment, _ := svc.EnrollTwoFactor(ctx, 1)
+	code, _ := auth.TOTPCode(enrollment.Secret, now)
+	recovery, err := svc.ActivateTwoFactor(ctx, 1, code)
+	if err != nil {
+		t.Fatalf("ActivateTwoFactor: %v", err)
+	}
+	for hash := range store.recovery {
+		if hash == recovery[0] {
+			t.Fatal("recovery codes must be stored hashed")
+		}
+	}
+
+	challenge, _ := svc.LoginChallenge(ctx, 1)
+	if _, err := svc.CompleteLogin(ctx, challenge, recovery[0], ""); err != nil {
+		t.Fatalf("login with a recovery code: %v", err)
+	}
+	challenge, _ = svc.LoginChallenge(ctx, 1)
+	if _, err := svc.CompleteLogin(ctx, challenge, recovery[0], ""); !errors.Is(err, auth.ErrInvalidTwoFactorCode) {
+		t.Fatalf("reusing a recovery code: %v, want ErrInvalidTwoFactorCode", err)
+	}
+	status, err := svc.TwoFactorStatus(ctx, 1)
+	if err != nil || !status.Enabled || status.RecoveryCodesLeft != auth.RecoveryCodeCount-1 {
+		t.Fatalf("TwoFactorStatus = %+v, %v", status, err)
+	}
+
+	if err := svc.DisableTwoFactor(ctx, 1, recovery[1]); err != nil {
+		t.Fatalf("DisableTwoFactor: %v", err)
+	}
+	if challenge, _ := svc.LoginChallenge(ctx, 1); challenge != "" {
+		t.Fatal("login must not need a code once two-factor authentication is disabled")
+	}
+}
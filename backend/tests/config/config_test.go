@@ -0,0 +1,259 @@
+package config
+
+import (
+	"area/src/config"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setRequiredEnv sets the settings Validate requires.
+func setRequiredEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("POSTGRES_HOST", "db")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "area")
+	t.Setenv("POSTGRES_PASSWORD", "secret")
+	t.Setenv("POSTGRES_DB", "area")
+}
+
+func TestLoad_EnvOverridesDefaults(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("PORT", "9090")
+	t.Setenv("BCRYPT_COST", "12")
+	t.Setenv("GOOGLE_OAUTH_CLIENT_ID", "id")
+	t.Setenv("GOOGLE_OAUTH_CLIENT_SECRET", "shh")
+	t.Setenv("WORKFLOW_MAX_TIMEOUT_SECONDS", "60")
+
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Port != "9090" || cfg.BcryptCost != 12 || cfg.Database.Host != "db" {
+		t.Fatalf("env not applied: %+v", cfg)
+	}
+	if cfg.Database.SSLMode != "disable" || cfg.Log.Format != "json" {
+		t.Fatalf("defaults lost: %+v", cfg)
+	}
+	if !cfg.Google.Enabled() || cfg.GitHub.Enabled() {
+		t.Fatalf("unexpected integrations: google=%v github=%v", cfg.Google.Enabled(), cfg.GitHub.Enabled())
+	}
+	if cfg.Workflows.MaxTimeout().Seconds() != 60 {
+		t.Fatalf("max timeout = %v", cfg.Workflows.MaxTimeout())
+	}
+}
+
+func TestLoad_FileThenEnv(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("LOG_LEVEL", "debug")
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"port":"7000","log":{"level":"warn","format":"text"},"integrations":{"nasa_api_key":"k"}}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Port != "7000" || cfg.Log.Format != "text" || cfg.Integrations.NASAAPIKey != "k" {
+		t.Fatalf("file not applied: %+v", cfg)
+	}
+	if cfg.Log.Level != "debug" {
+		t.Fatalf("env should override the file, got level %q", cfg.Log.Level)
+	}
+}
+
+func TestLoad_UnknownFileKey(t *testing.T) {
+	setRequiredEnv(t)
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"prot":"7000"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), "prot") {
+		t.Fatalf("expected unknown field error, got %v", err)
+	}
+}
+
+func TestLoad_ReportsEveryProblem(t *testing.T) {
+	for _, key := range []string{"POSTGRES_HOST", "POSTGRES_PORT", "POSTGRES_USER", "POSTGRES_PASSWORD", "POSTGRES_DB"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("BCRYPT_COST", "many")
+	t.Setenv("GITHUB_OAUTH_CLIENT_ID", "id")
+
+	_, err := config.Load("")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), "BCRYPT_COST") {
+		t.Fatalf("parse error missing: %v", err)
+	}
+
+	t.Setenv("BCRYPT_COST", "")
+	_, err = config.Load("")
+	for _, want := range []string{"POSTGRES_HOST is required", "POSTGRES_DB is required", "GITHUB_OAUTH_CLIENT_ID and GITHUB_OAUTH_CLIENT_SECRET"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %v does not mention %q", err, want)
+		}
+	}
+}
+
+func TestRedacted_HidesSetSecrets(t *testing.T) {
+	cfg := config.Default()
+	cfg.SecretKey = "0123456789abcdef0123456789abcdef"
+	cfg.GitHub = config.OAuth{ClientID: "id", ClientSecret: "shh"}
+
+	out := cfg.Redacted()
+	if out["secret_key"] != "[redacted]" || out["admin_token"] != "" {
+		t.Fatalf("unexpected secrets: %v / %v", out["secret_key"], out["admin_token"])
+	}
+	github := out["github"].(map[string]any)
+	if github["client_id"] != "id" || github["client_secret"] != "[redacted]" {
+		t.Fatalf("unexpected github section: %v", github)
+	}
+}
+
+func TestLoad_SQLiteNeedsNoPostgres(t *testing.T) {
+	for _, key := range []string{"POSTGRES_HOST", "POSTGRES_PORT", "POSTGRES_USER", "POSTGRES_PASSWORD", "POSTGRES_DB"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("DATABASE_DRIVER", "sqlite")
+	t.Setenv("SQLITE_PATH", "dev.db")
+
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Database.Driver != config.DriverSQLite || cfg.Database.SQLitePath != "dev.db" {
+		t.Fatalf("unexpected database settings: %+v", cfg.Database)
+	}
+
+	t.Setenv("DATABASE_DRIVER", "mysql")
+	if _, err := config.Load(""); err == nil || !strings.Contains(err.Error(), "DATABASE_DRIVER") {
+		t.Fatalf("expected a driver error, got %v", err)
+	}
+}
+
+func TestLoad_AuthSettings(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("AUTH_ACCESS_TOKEN_MINUTES", "5")
+	t.Setenv("AUTH_DEV_USER_HEADER", "true")
+
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Auth.AccessTTL().Minutes() != 5 || cfg.Auth.RefreshTTL().Hours() != 30*24 || !cfg.Auth.DevUserHeader {
+		t.Fatalf("auth settings not applied: %+v", cfg.Auth)
+	}
+
+	t.Setenv("AUTH_DEV_USER_HEADER", "maybe")
+	if _, err := config.Load(""); err == nil || !strings.Contains(err.Error(), "AUTH_DEV_USER_HEADER") {
+		t.Fatalf("error %v does not mention AUTH_DEV_USER_HEADER", err)
+	}
+
+	t.Setenv("AUTH_DEV_USER_HEADER", "")
+	t.Setenv("AUTH_REFRESH_TOKEN_DAYS", "0")
+	if _, err := config.Load(""); err == nil || !strings.Contains(err.Error(), "AUTH_REFRESH_TOKEN_DAYS") {
+		t.Fatalf("error %v does not mention AUTH_REFRESH_TOKEN_DAYS", err)
+	}
+}
+
+func TestLoad_LoginThrottleSettings(t *testing.T) {
+	setRequiredEnv(t)
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Auth.LoginAccountAttempts != 5 || cfg.Auth.LoginIPAttempts != 20 || cfg.Auth.LoginLockout().Seconds() != 30 || cfg.Auth.LoginMaxLockout().Minutes() != 60 {
+		t.Fatalf("default throttle settings: %+v", cfg.Auth)
+	}
+
+	t.Setenv("AUTH_LOGIN_IP_ATTEMPTS", "0")
+	t.Setenv("AUTH_LOGIN_LOCKOUT_SECONDS", "7200")
+	_, err = config.Load("")
+	for _, want := range []string{"AUTH_LOGIN_IP_ATTEMPTS must be positive", "AUTH_LOGIN_MAX_LOCKOUT_MINUTES must not be shorter"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %v does not mention %q", err, want)
+		}
+	}
+}
+
+func TestLoad_MailSettings(t *testing.T) {
+	setRequiredEnv(t)
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Mail.Driver != config.MailLog {
+		t.Fatalf("mail driver = %q, want log by default", cfg.Mail.Driver)
+	}
+
+	t.Setenv("MAIL_DRIVER", "smtp")
+	t.Setenv("MAIL_APP_URL", "app.example.com")
+	_, err = config.Load("")
+	for _, want := range []string{"MAIL_SMTP_HOST is required", "MAIL_APP_URL"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %v does not mention %q", err, want)
+		}
+	}
+}
+
+func TestConfig_TrustedProxies(t *testing.T) {
+	cfg := config.Default()
+	cfg.TrustedProxies = "10.0.0.1, 172.16.0.0/12,,fd00::/8"
+	prefixes, err := cfg.TrustedProxyPrefixes()
+	if err != nil {
+		t.Fatalf("TrustedProxyPrefixes: %v", err)
+	}
+	if fmt.Sprint(prefixes) != "[10.0.0.1/32 172.16.0.0/12 fd00::/8]" {
+		t.Fatalf("prefixes = %v", prefixes)
+	}
+
+	setRequiredEnv(t)
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.1,proxy.local")
+	if _, err := config.Load(""); err == nil || !strings.Contains(err.Error(), `TRUSTED_PROXIES: "proxy.local"`) {
+		t.Fatalf("expected a TRUSTED_PROXIES error, got %v", err)
+	}
+}
+
+func TestConfig_IsServerURL(t *testing.T) {
+	cfg := config.Default()
+	for target, want := range map[string]bool{
+		"http://localhost:8080/actions/google/email":  true,
+		"http://LOCALHOST:8080/actions/google/email":  true,
+		"https://localhost:8080/actions/google/email": false,
+		"http://evil.example/actions/google/email":    false,
+		"http://localhost:9090/actions/google/email":  false,
+	} {
+		u, err := url.Parse(target)
+		if err != nil {
+			t.Fatalf("parse %s: %v", target, err)
+		}
+		if got := cfg.IsServerURL(u); got != want {
+			t.Errorf("IsServerURL(%s) = %v, want %v", target, got, want)
+		}
+	}
+
+	cfg.ServerURL = "https://area.example.com/api/"
+	for target, want := range map[string]bool{
+		"https://area.example.com/api/actions/github/issue":  true,
+		"https://area.example.com/apix/actions/github/issue": false,
+		"https://area.example.com/actions/github/issue":      false,
+	} {
+		u, _ := url.Parse(target)
+		if got := cfg.IsServerURL(u); got != want {
+			t.Errorf("IsServerURL(%s) = %v, want %v", target, got, want)
+		}
+	}
+
+	setRequiredEnv(t)
+	t.Setenv("SERVER_URL", "area.example.com")
+	if _, err := config.Load(""); err == nil || !strings.Contains(err.Error(), "SERVER_URL") {
+		t.Fatalf("expected a SERVER_URL error, got %v", err)
+	}
+}
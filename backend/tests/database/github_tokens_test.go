@@ -42,8 +42,8 @@ func TestInsertGithubToken(t *testing.T) {
 	userID := int64(1)
 
 	mock.ExpectBegin()
-	mock.ExpectQuery(`^INSERT INTO "github_tokens" \("created_at","updated_at","deleted_at","user_id","access_token","token_type","scope"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7\) RETURNING "id"$`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, &userID, "acc", "bearer", "repo").
+	mock.ExpectQuery(`^INSERT INTO "github_tokens" \("created_at","updated_at","deleted_at","user_id","access_token","token_type","scope","workspace_id"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8\) RETURNING "id"$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, &userID, "acc", "bearer", "repo", nil).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
 	mock.ExpectCommit()
 
@@ -65,8 +65,8 @@ func TestInsertGithubToken_NilUserID(t *testing.T) {
 	defer cleanup()
 
 	mock.ExpectBegin()
-	mock.ExpectQuery(`^INSERT INTO "github_tokens" \("created_at","updated_at","deleted_at","user_id","access_token","token_type","scope"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7\) RETURNING "id"$`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, "acc", "bearer", "repo").
+	mock.ExpectQuery(`^INSERT INTO "github_tokens" \("created_at","updated_at","deleted_at","user_id","access_token","token_type","scope","workspace_id"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8\) RETURNING "id"$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, "acc", "bearer", "repo", nil).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
 	mock.ExpectCommit()
 
@@ -145,8 +145,8 @@ func TestGetGithubTokenForUser(t *testing.T) {
 	rows := sqlmock.NewRows([]string{"id", "created_at", "updated_at", "deleted_at", "user_id", "access_token", "token_type", "scope"}).
 		AddRow(tokenID, now, now, nil, userID, "access789", "bearer", "repo")
 
-	mock.ExpectQuery(`^SELECT \* FROM "github_tokens" WHERE \(id = \$1 AND user_id = \$2\) AND "github_tokens"\."deleted_at" IS NULL ORDER BY "github_tokens"\."id" LIMIT \$[0-9]+$`).
-		WithArgs(tokenID, userID, sqlmock.AnyArg()).
+	mock.ExpectQuery(`^SELECT \* FROM "github_tokens" WHERE \(id = \$1 AND \(user_id = \$2 OR workspace_id IN \(SELECT "workspace_id" FROM "workspace_members" WHERE user_id = \$3 AND role IN \(\$4,\$5\)\)\)\) AND "github_tokens"\."deleted_at" IS NULL ORDER BY "github_tokens"\."id" LIMIT \$[0-9]+$`).
+		WithArgs(tokenID, userID, userID, database.RoleOwner, database.RoleEditor, sqlmock.AnyArg()).
 		WillReturnRows(rows)
 
 	token, err := database.GetGithubTokenForUser(tokenID, userID)
@@ -2,6 +2,8 @@ package database
 
 import (
 	"area/src/database"
+	"area/src/security"
+	"database/sql/driver"
 	"testing"
 	"time"
 
@@ -44,8 +46,8 @@ func TestInsertGoogleToken(t *testing.T) {
 	userID := int64(1)
 
 	mock.ExpectBegin()
-	mock.ExpectQuery(`^INSERT INTO "google_tokens" \("created_at","updated_at","deleted_at","user_id","access_token","refresh_token","expiry"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7\) RETURNING "id"$`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, &userID, "acc", "ref", now).
+	mock.ExpectQuery(`^INSERT INTO "google_tokens" \("created_at","updated_at","deleted_at","user_id","access_token","refresh_token","expiry","workspace_id"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8\) RETURNING "id"$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, &userID, "acc", "ref", now, nil).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
 	mock.ExpectCommit()
 
@@ -69,8 +71,8 @@ func TestInsertGoogleToken_NilUserID(t *testing.T) {
 	now := time.Now()
 
 	mock.ExpectBegin()
-	mock.ExpectQuery(`^INSERT INTO "google_tokens" \("created_at","updated_at","deleted_at","user_id","access_token","refresh_token","expiry"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7\) RETURNING "id"$`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, "acc", "ref", now).
+	mock.ExpectQuery(`^INSERT INTO "google_tokens" \("created_at","updated_at","deleted_at","user_id","access_token","refresh_token","expiry","workspace_id"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8\) RETURNING "id"$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, "acc", "ref", now, nil).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
 	mock.ExpectCommit()
 
@@ -149,8 +151,8 @@ func TestGetGoogleTokenForUser(t *testing.T) {
 	rows := sqlmock.NewRows([]string{"id", "created_at", "updated_at", "deleted_at", "user_id", "access_token", "refresh_token", "expiry"}).
 		AddRow(tokenID, now, now, nil, userID, "access789", "refresh101", now.Add(time.Hour))
 
-	mock.ExpectQuery(`^SELECT \* FROM "google_tokens" WHERE \(id = \$1 AND user_id = \$2\) AND "google_tokens"\."deleted_at" IS NULL ORDER BY "google_tokens"\."id" LIMIT \$[0-9]+$`).
-		WithArgs(tokenID, userID, sqlmock.AnyArg()).
+	mock.ExpectQuery(`^SELECT \* FROM "google_tokens" WHERE \(id = \$1 AND \(user_id = \$2 OR workspace_id IN \(SELECT "workspace_id" FROM "workspace_members" WHERE user_id = \$3 AND role IN \(\$4,\$5\)\)\)\) AND "google_tokens"\."deleted_at" IS NULL ORDER BY "google_tokens"\."id" LIMIT \$[0-9]+$`).
+		WithArgs(tokenID, userID, userID, database.RoleOwner, database.RoleEditor, sqlmock.AnyArg()).
 		WillReturnRows(rows)
 
 	token, err := database.GetGoogleTokenForUser(tokenID, userID)
@@ -187,3 +189,68 @@ func TestUpdateGoogleToken(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+// encryptedArg matches a token encrypted with the configured key.
+type encryptedArg string
+
+func (plain encryptedArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	if !ok || !security.IsEncrypted(s) {
+		return false
+	}
+	decrypted, err := security.DecryptString(s)
+	return err == nil && decrypted == string(plain)
+}
+
+func configureSecretKey(t *testing.T) {
+	t.Helper()
+	if err := security.Configure("", "0123456789abcdef0123456789abcdef", ""); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	t.Cleanup(func() { security.Configure("", "", "") })
+}
+
+func TestGoogleToken_EncryptedAtRest(t *testing.T) {
+	_, mock, cleanup := setupGoogleTokenMockDB(t)
+	defer cleanup()
+	configureSecretKey(t)
+
+	now := time.Now()
+	userID := int64(1)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`^INSERT INTO "google_tokens" .* RETURNING "id"$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, &userID, encryptedArg("acc"), encryptedArg("ref"), now, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectCommit()
+	if _, err := database.InsertGoogleToken(&userID, "acc", "ref", now); err != nil {
+		t.Fatalf("InsertGoogleToken error: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`^UPDATE "google_tokens" SET .* WHERE id = \$5 AND "google_tokens"\."deleted_at" IS NULL$`).
+		WithArgs(sqlmock.AnyArg(), encryptedArg("new_access"), encryptedArg("new_refresh"), now, int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := database.UpdateGoogleToken(5, "new_access", "new_refresh", now); err != nil {
+		t.Fatalf("UpdateGoogleToken error: %v", err)
+	}
+
+	access, _ := security.EncryptString("access123")
+	rows := sqlmock.NewRows([]string{"id", "user_id", "access_token", "refresh_token"}).
+		AddRow(5, userID, access, "legacy-plaintext")
+	mock.ExpectQuery(`^SELECT \* FROM "google_tokens" WHERE id = \$1`).
+		WithArgs(int64(5), sqlmock.AnyArg()).
+		WillReturnRows(rows)
+	token, err := database.GetGoogleToken(5)
+	if err != nil {
+		t.Fatalf("GetGoogleToken error: %v", err)
+	}
+	if token.AccessToken != "access123" || token.RefreshToken != "legacy-plaintext" {
+		t.Fatalf("got tokens %q, %q", token.AccessToken, token.RefreshToken)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
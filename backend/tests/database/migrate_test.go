@@ -0,0 +1,237 @@
+package database
+
+import (
+	"area/src/config"
+	"area/src/database"
+	"area/src/security"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(database.Dialector(config.Database{
+		Driver:     config.DriverSQLite,
+		SQLitePath: filepath.Join(t.TempDir(), "area.db"),
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestMigrations_EveryDialectHasBothScripts(t *testing.T) {
+	for _, dialect := range []string{"postgres", "sqlite"} {
+		migrations, err := database.Migrations(dialect)
+		if err != nil {
+			t.Fatalf("Migrations(%s): %v", dialect, err)
+		}
+		if len(migrations) < 2 || migrations[0].Version != 1 || migrations[1].Name != "catalog_seed" {
+			t.Fatalf("Migrations(%s) = %+v", dialect, migrations)
+		}
+		for _, m := range migrations {
+			if (m.Up == "" && m.UpFunc == nil) || (m.Down == "" && m.DownFunc == nil) {
+				t.Fatalf("migration %d_%s lacks a script on %s", m.Version, m.Name, dialect)
+			}
+		}
+	}
+}
+
+func TestMigrateUp_FreshSQLite(t *testing.T) {
+	db := openSQLite(t)
+	if err := database.CheckMigrated(db); !errors.Is(err, database.ErrNotMigrated) {
+		t.Fatalf("CheckMigrated on empty database = %v, want ErrNotMigrated", err)
+	}
+
+	applied, err := database.MigrateUp(db)
+	if err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	all, _ := database.Migrations("sqlite")
+	waiting := 0
+	for _, m := range all {
+		if m.Waiting() {
+			waiting++
+		}
+	}
+	if len(applied) != len(all)-waiting {
+		t.Fatalf("applied %d migrations, want %d", len(applied), len(all)-waiting)
+	}
+	if err := database.CheckMigrated(db); err != nil {
+		t.Fatalf("CheckMigrated after up: %v", err)
+	}
+
+	// Every model column must exist, or GORM queries fail at runtime.
+	models := []any{
+		&database.User{}, &database.GoogleToken{}, &database.GithubToken{},
+		&database.AreaService{}, &database.AreaCapability{}, &database.AreaField{},
+		&database.Workflow{}, &database.Run{}, &database.Job{}, &database.DigestItem{},
+		&database.PollerState{}, &database.Lease{}, &database.Session{}, &database.APIToken{}, &database.AccountToken{},
+		&database.TwoFactor{}, &database.RecoveryCode{}, &database.LoginThrottle{}, &database.LoginFailure{},
+		&database.Workspace{}, &database.WorkspaceMember{}, &database.WorkspaceInvite{}, &database.AuditEvent{},
+		&database.UserSecret{},
+	}
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatalf("parse %T: %v", model, err)
+		}
+		for _, column := range stmt.Schema.DBNames {
+			if !db.Migrator().HasColumn(model, column) {
+				t.Errorf("%s.%s is missing", stmt.Schema.Table, column)
+			}
+		}
+	}
+
+	var services int64
+	db.Model(&database.AreaService{}).Count(&services)
+	if services == 0 {
+		t.Fatal("catalog was not seeded")
+	}
+}
+
+func TestMigrateUp_IsIdempotent(t *testing.T) {
+	db := openSQLite(t)
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	var fields int64
+	db.Model(&database.AreaField{}).Count(&fields)
+
+	applied, err := database.MigrateUp(db)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("second MigrateUp = %v, %v; want nothing applied", applied, err)
+	}
+
+	// The seed itself can run again without duplicating rows.
+	all, _ := database.Migrations("sqlite")
+	if err := db.Exec(all[1].Up).Error; err != nil {
+		t.Fatalf("re-run seed: %v", err)
+	}
+	var again int64
+	db.Model(&database.AreaField{}).Count(&again)
+	if again != fields {
+		t.Fatalf("area_fields = %d after re-seeding, want %d", again, fields)
+	}
+}
+
+func TestMigrateDown_RevertsNewestFirst(t *testing.T) {
+	db := openSQLite(t)
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	all, _ := database.Migrations("sqlite")
+	last := all[len(all)-1]
+	reverted, err := database.MigrateDown(db, 1)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != last.Version {
+		t.Fatalf("MigrateDown(1) = %+v, %v; want %d_%s", reverted, err, last.Version, last.Name)
+	}
+	statuses, err := database.MigrationStatuses(db)
+	if err != nil {
+		t.Fatalf("MigrationStatuses: %v", err)
+	}
+	if n := len(statuses); statuses[n-2].AppliedAt == nil || statuses[n-1].AppliedAt != nil {
+		t.Fatalf("unexpected statuses after one step down: %+v", statuses)
+	}
+
+	if _, err := database.MigrateDown(db, len(all)); err != nil {
+		t.Fatalf("MigrateDown(%d): %v", len(all), err)
+	}
+	if db.Migrator().HasTable("workflows") {
+		t.Fatal("workflows table survived a full down migration")
+	}
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("MigrateUp after down: %v", err)
+	}
+}
+
+func TestMigrateUp_EncryptsOAuthTokens(t *testing.T) {
+	db := openSQLite(t)
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	// Without APP_SECRET_KEY the migration waits rather than being recorded as applied.
+	statuses, _ := database.MigrationStatuses(db)
+	for _, s := range statuses {
+		if s.Version == 10 && (s.AppliedAt != nil || !s.Waiting()) {
+			t.Fatalf("encrypt_oauth_tokens without a key: applied at %v, waiting %v", s.AppliedAt, s.Waiting())
+		}
+	}
+	google := database.GoogleToken{AccessToken: "g-access", RefreshToken: "g-refresh"}
+	github := database.GithubToken{AccessToken: "gh-access"}
+	if err := db.Create(&google).Error; err != nil {
+		t.Fatalf("create google token: %v", err)
+	}
+	if err := db.Create(&github).Error; err != nil {
+		t.Fatalf("create github token: %v", err)
+	}
+
+	configureSecretKey(t)
+	applied, err := database.MigrateUp(db)
+	if err != nil || len(applied) != 1 || applied[0].Version != 10 {
+		t.Fatalf("MigrateUp with a key = %+v, %v; want encrypt_oauth_tokens", applied, err)
+	}
+	var stored database.GoogleToken
+	db.First(&stored, google.ID)
+	var storedGithub database.GithubToken
+	db.First(&storedGithub, github.ID)
+	for _, value := range []string{stored.AccessToken, stored.RefreshToken, storedGithub.AccessToken} {
+		if !security.IsEncrypted(value) {
+			t.Fatalf("token %q was left in plaintext", value)
+		}
+	}
+
+	originalDB := database.Db
+	database.Db = db
+	t.Cleanup(func() { database.Db = originalDB })
+	token, err := database.GetGoogleToken(int64(google.ID))
+	if err != nil || token.AccessToken != "g-access" || token.RefreshToken != "g-refresh" {
+		t.Fatalf("GetGoogleToken = %+v, %v", token, err)
+	}
+
+	// Steps back to just before encrypt_oauth_tokens, whatever came after it.
+	all, _ := database.Migrations("sqlite")
+	steps := 0
+	for _, m := range all {
+		if m.Version >= 10 {
+			steps++
+		}
+	}
+	if _, err := database.MigrateDown(db, steps); err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	db.First(&storedGithub, github.ID)
+	if storedGithub.AccessToken != "gh-access" {
+		t.Fatalf("github token after down = %q, want plaintext", storedGithub.AccessToken)
+	}
+}
+
+func TestMigrateUp_AuditEventsAreAppendOnly(t *testing.T) {
+	db := openSQLite(t)
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	event := database.AuditEvent{Action: "workflow.disabled", Target: "workflow:1", AfterState: []byte(`{"enabled":false}`)}
+	if err := db.Create(&event).Error; err != nil {
+		t.Fatalf("create audit event: %v", err)
+	}
+	if err := db.Model(&event).Update("action", "workflow.enabled").Error; err == nil {
+		t.Fatal("an audit event was updated")
+	}
+	if err := db.Delete(&event).Error; err == nil {
+		t.Fatal("an audit event was deleted")
+	}
+	var stored database.AuditEvent
+	if err := db.First(&stored, event.ID).Error; err != nil || stored.Action != "workflow.disabled" || string(stored.AfterState) != `{"enabled":false}` {
+		t.Fatalf("stored event = %+v, %v", stored, err)
+	}
+}
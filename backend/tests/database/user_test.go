@@ -135,8 +135,8 @@ func TestCreateUser(t *testing.T) {
 	defer cleanup()
 
 	mock.ExpectBegin()
-	mock.ExpectQuery(`^INSERT INTO "users" \("created_at","updated_at","deleted_at","firstname","lastname","email","password_hash"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7\) RETURNING "id"$`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, "Ada", "Lovelace", "ada@example.com", "hash").
+	mock.ExpectQuery(`^INSERT INTO "users" \("created_at","updated_at","deleted_at","firstname","lastname","email","password_hash","email_verified_at"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8\) RETURNING "id"$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, "Ada", "Lovelace", "ada@example.com", "hash", nil).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
 	mock.ExpectCommit()
 
@@ -0,0 +1,132 @@
+package httpapi
+
+import (
+	"area/src/auth"
+	"area/src/config"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+)
+
+var mailedLink = regexp.MustCompile(`https://app\.example\.com(/[a-z-]+)\?token=(\S+)`)
+
+// mailedToken returns the token of the link in the last email, checking it points to page.
+func mailedToken(t *testing.T, mails *outbox, page string) string {
+	t.Helper()
+	if len(mails.sent) == 0 {
+		t.Fatal("no email sent")
+	}
+	match := mailedLink.FindStringSubmatch(mails.sent[len(mails.sent)-1].Body)
+	if match == nil || match[1] != page {
+		t.Fatalf("no %s link in %q", page, mails.sent[len(mails.sent)-1].Body)
+	}
+	token, err := url.QueryUnescape(match[2])
+	if err != nil {
+		t.Fatalf("unescape token: %v", err)
+	}
+	return token
+}
+
+func TestPasswordReset(t *testing.T) {
+	mux, mails := setupAccountMux(t, nil)
+	session := login(t, mux)
+
+	unknown := serve(mux, http.MethodPost, "/password/forgot", `{"email":"nobody@b.com"}`, "")
+	if mails.wait(t); unknown.Code != http.StatusAccepted || len(mails.sent) != 0 {
+		t.Fatalf("unknown email: status = %d, %d emails", unknown.Code, len(mails.sent))
+	}
+	rr := serve(mux, http.MethodPost, "/password/forgot", `{"email":"a@b.com"}`, "")
+	if rr.Code != http.StatusAccepted || rr.Body.String() != unknown.Body.String() {
+		t.Fatalf("forgot status = %d: %s, unknown email got %s", rr.Code, rr.Body, unknown.Body)
+	}
+	mails.wait(t)
+	if mails.sent[0].To != "a@b.com" {
+		t.Fatalf("reset mailed to %q", mails.sent[0].To)
+	}
+	token := mailedToken(t, mails, "/reset-password")
+
+	body := `{"token":"` + token + `","password":"new-pw"}`
+	if rr := serve(mux, http.MethodPost, "/password/reset", body, ""); rr.Code != http.StatusOK {
+		t.Fatalf("reset status = %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(mux, http.MethodPost, "/password/reset", body, ""); rr.Code != http.StatusBadRequest {
+		t.Fatalf("reused token: status = %d, want 400", rr.Code)
+	}
+	if rr := serve(mux, http.MethodGet, "/workflows", "", session); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("session after reset: status = %d, want 401", rr.Code)
+	}
+	if rr := serve(mux, http.MethodPost, "/login", `{"email":"a@b.com","password":"pw"}`, ""); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("old password: status = %d, want 401", rr.Code)
+	}
+	if rr := serve(mux, http.MethodPost, "/login", `{"email":"a@b.com","password":"new-pw"}`, ""); rr.Code != http.StatusOK {
+		t.Fatalf("new password: status = %d: %s", rr.Code, rr.Body)
+	}
+}
+
+func TestPasswordReset_Throttled(t *testing.T) {
+	mux, mails := setupAccountMux(t, nil)
+	policy := auth.DefaultThrottlePolicy
+	forgot := func(email string) *httptest.ResponseRecorder {
+		return serve(mux, http.MethodPost, "/password/forgot", `{"email":"`+email+`"}`, "")
+	}
+
+	for i := range policy.AccountAttempts {
+		if rr := forgot("a@b.com"); rr.Code != http.StatusAccepted {
+			t.Fatalf("request %d: status = %d: %s", i+1, rr.Code, rr.Body)
+		}
+	}
+	rr := forgot("A@b.com")
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") == "" {
+		t.Fatalf("past the email limit: status = %d, Retry-After %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+	mails.wait(t)
+	if len(mails.sent) != policy.AccountAttempts {
+		t.Fatalf("%d emails sent, want %d", len(mails.sent), policy.AccountAttempts)
+	}
+
+	for i := policy.AccountAttempts; i < policy.IPAttempts; i++ {
+		if rr := forgot(fmt.Sprintf("nobody%d@b.com", i)); rr.Code != http.StatusAccepted {
+			t.Fatalf("request %d: status = %d: %s", i+1, rr.Code, rr.Body)
+		}
+	}
+	if rr := forgot("someone@b.com"); rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("past the IP limit: status = %d, want 429", rr.Code)
+	}
+}
+
+func TestEmailVerification_GatesWorkflowCreation(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.RequireVerifiedEmail = true
+	mux, mails := setupAccountMux(t, cfg)
+
+	rr := serve(mux, http.MethodPost, "/register", `{"email":"c@d.com","password":"pw","firstname":"Grace","lastname":"Hopper"}`, "")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("register status = %d: %s", rr.Code, rr.Body)
+	}
+	token := mailedToken(t, mails, "/verify-email")
+	rr = serve(mux, http.MethodPost, "/login", `{"email":"c@d.com","password":"pw"}`, "")
+	session := decodeTokens(t, rr).AccessToken
+
+	workflow := `{"name":"n","trigger_type":"manual","action_url":"http://example.com"}`
+	if rr := serve(mux, http.MethodPost, "/workflows", workflow, session); rr.Code != http.StatusForbidden {
+		t.Fatalf("unverified: status = %d, want 403", rr.Code)
+	}
+	if rr := serve(mux, http.MethodPost, "/email/verify/resend", "", session); rr.Code != http.StatusAccepted || len(mails.sent) != 2 {
+		t.Fatalf("resend: status = %d, %d emails", rr.Code, len(mails.sent))
+	}
+	if rr := serve(mux, http.MethodPost, "/email/verify", `{"token":"`+token+`"}`, ""); rr.Code != http.StatusOK {
+		t.Fatalf("verify status = %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(mux, http.MethodPost, "/email/verify", `{"token":"`+token+`"}`, ""); rr.Code != http.StatusBadRequest {
+		t.Fatalf("reused token: status = %d, want 400", rr.Code)
+	}
+	if rr := serve(mux, http.MethodPost, "/workflows", workflow, session); rr.Code != http.StatusCreated {
+		t.Fatalf("verified: status = %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(mux, http.MethodPost, "/email/verify/resend", "", session); rr.Code != http.StatusAccepted || len(mails.sent) != 2 {
+		t.Fatalf("resend once verified: status = %d, %d emails", rr.Code, len(mails.sent))
+	}
+}
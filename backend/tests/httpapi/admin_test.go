@@ -0,0 +1,83 @@
+package httpapi
+
+import (
+	"area/src/config"
+	"area/src/httpapi"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminConfig_RedactsSecrets(t *testing.T) {
+	cfg := config.Default()
+	cfg.AdminToken = "admin-secret"
+	cfg.Database.Host = "db"
+	cfg.Database.Password = "hunter2"
+	mux := httpapi.NewMux(nil, nil, cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/config", nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "hunter2") || strings.Contains(body, "admin-secret") {
+		t.Fatalf("secrets leaked: %s", body)
+	}
+	var payload struct {
+		Config struct {
+			Database map[string]any `json:"database"`
+		} `json:"config"`
+		Integrations map[string]bool `json:"integrations"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if payload.Config.Database["host"] != "db" || payload.Config.Database["password"] != "[redacted]" {
+		t.Fatalf("unexpected database section: %v", payload.Config.Database)
+	}
+	if payload.Integrations["google"] {
+		t.Fatal("google should be reported disabled")
+	}
+}
+
+func TestAdminConfig_RequiresToken(t *testing.T) {
+	cfg := config.Default()
+	cfg.AdminToken = "admin-secret"
+	mux := httpapi.NewMux(nil, nil, cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/config", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+}
+
+func TestAdminConfig_DisabledWithoutToken(t *testing.T) {
+	mux := httpapi.NewMux(nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/config", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+}
+
+func TestDisabledIntegration_ServiceUnavailable(t *testing.T) {
+	mux := httpapi.NewMux(nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/oauth/google/login", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+}
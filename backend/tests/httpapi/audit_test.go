@@ -0,0 +1,171 @@
+package httpapi
+
+import (
+	"area/src/auth"
+	"area/src/config"
+	"area/src/database"
+	"area/src/workflows"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func auditActions(events []auth.AuditEvent) []string {
+	actions := make([]string, 0, len(events))
+	for _, event := range events {
+		actions = append(actions, event.Action)
+	}
+	return actions
+}
+
+func TestAudit_RecordsTheChangesOfAUser(t *testing.T) {
+	mux, _ := setupAccountMux(t, nil)
+	serve(mux, http.MethodPost, "/login", `{"email":"a@b.com","password":"wrong"}`, "")
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"a@b.com","password":"pw"}`))
+	req.Header.Set("User-Agent", "kikonect-test/1.0")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	session := decodeTokens(t, rr).AccessToken
+
+	rr = serve(mux, http.MethodPost, "/workflows", `{"name":"prod","trigger_type":"manual","action_url":"http://example.com"}`, session)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create workflow status = %d: %s", rr.Code, rr.Body)
+	}
+	wf := decodeJSON[workflows.Workflow](t, rr.Body.Bytes())
+	base := fmt.Sprintf("/workflows/%d", wf.ID)
+	if rr := serve(mux, http.MethodPost, base+"/trigger", `{}`, session); rr.Code != http.StatusAccepted {
+		t.Fatalf("trigger status = %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(mux, http.MethodPost, base+"/enabled?action=disable", "", session); rr.Code != http.StatusOK {
+		t.Fatalf("disable status = %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(mux, http.MethodPut, "/me/secrets/api_key", `{"value":"s3cret"}`, session); rr.Code != http.StatusCreated {
+		t.Fatalf("put secret status = %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(mux, http.MethodDelete, "/me/secrets/api_key", "", session); rr.Code != http.StatusOK {
+		t.Fatalf("delete secret status = %d: %s", rr.Code, rr.Body)
+	}
+
+	rr = serve(mux, http.MethodGet, "/me/audit", "", session)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("audit status = %d: %s", rr.Code, rr.Body)
+	}
+	if strings.Contains(rr.Body.String(), "s3cret") {
+		t.Fatalf("secret value in audit log: %s", rr.Body)
+	}
+	events := decodeJSON[[]auth.AuditEvent](t, rr.Body.Bytes())
+	want := []string{
+		"secret.deleted", "secret.created", "workflow.disabled", "workflow.triggered",
+		"workflow.created", "login.succeeded", "login.failed", "user.registered",
+	}
+	if fmt.Sprint(auditActions(events)) != fmt.Sprint(want) {
+		t.Fatalf("audit actions = %v, want %v", auditActions(events), want)
+	}
+	disabled, login := events[2], events[5]
+	if string(disabled.Before) != `{"enabled":true}` || string(disabled.After) != `{"enabled":false}` || disabled.Target != fmt.Sprintf("workflow:%d", wf.ID) {
+		t.Fatalf("unexpected disable event: %+v", disabled)
+	}
+	if login.IP != "192.0.2.1" || login.UserAgent != "kikonect-test/1.0" || login.Detail != "password" {
+		t.Fatalf("unexpected login event: %+v", login)
+	}
+
+	rr = serve(mux, http.MethodGet, "/me/audit?action=workflow.&limit=2", "", session)
+	if got := auditActions(decodeJSON[[]auth.AuditEvent](t, rr.Body.Bytes())); fmt.Sprint(got) != "[workflow.disabled workflow.triggered]" {
+		t.Fatalf("filtered actions = %v", got)
+	}
+	rr = serve(mux, http.MethodGet, fmt.Sprintf("/me/audit?before=%d&action=login.failed", disabled.ID), "", session)
+	if got := auditActions(decodeJSON[[]auth.AuditEvent](t, rr.Body.Bytes())); fmt.Sprint(got) != "[login.failed]" {
+		t.Fatalf("paged actions = %v", got)
+	}
+	if rr := serve(mux, http.MethodGet, "/me/audit?since=yesterday", "", session); rr.Code != http.StatusBadRequest {
+		t.Fatalf("invalid since: status = %d, want 400", rr.Code)
+	}
+}
+
+func TestAudit_ClientIPFromTrustedProxies(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		trusted string
+		want    string
+	}{
+		{"untrusted peer", "", "192.0.2.1"},
+		{"trusted peer", "192.0.2.1", "10.1.2.3"},
+		{"chain of trusted proxies", "192.0.2.0/24, 10.0.0.0/8", "198.51.100.4"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.TrustedProxies = tc.trusted
+			mux, _ := setupAccountMux(t, cfg)
+			req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"a@b.com","password":"pw"}`))
+			req.Header.Add("X-Forwarded-For", "203.0.113.9, 198.51.100.4")
+			req.Header.Add("X-Forwarded-For", "10.1.2.3")
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+			session := decodeTokens(t, rr).AccessToken
+
+			rr = serve(mux, http.MethodGet, "/me/audit?action=login.succeeded", "", session)
+			events := decodeJSON[[]auth.AuditEvent](t, rr.Body.Bytes())
+			if len(events) != 1 || events[0].IP != tc.want {
+				t.Fatalf("login events = %+v, want one from %s", events, tc.want)
+			}
+		})
+	}
+}
+
+func TestAudit_AdminViewFiltersEveryUser(t *testing.T) {
+	cfg := config.Default()
+	cfg.AdminToken = "admin-secret"
+	mux, _ := setupAccountMux(t, cfg)
+	serve(mux, http.MethodPost, "/login", `{"email":"nobody@b.com","password":"pw"}`, "")
+	login(t, mux)
+
+	if rr := serve(mux, http.MethodGet, "/admin/audit", "", "wrong"); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong admin token: status = %d, want 401", rr.Code)
+	}
+	rr := serve(mux, http.MethodGet, "/admin/audit?action=login.failed", "", "admin-secret")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("admin audit status = %d: %s", rr.Code, rr.Body)
+	}
+	events := decodeJSON[[]auth.AuditEvent](t, rr.Body.Bytes())
+	if len(events) != 1 || events[0].ActorID != nil || events[0].Target != "email:nobody@b.com" {
+		t.Fatalf("unexpected failed logins: %+v", events)
+	}
+	since := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	rr = serve(mux, http.MethodGet, "/admin/audit?actor_id=1&since="+since, "", "admin-secret")
+	if got := auditActions(decodeJSON[[]auth.AuditEvent](t, rr.Body.Bytes())); fmt.Sprint(got) != "[login.succeeded user.registered]" {
+		t.Fatalf("actions of user 1 = %v", got)
+	}
+}
+
+func TestAudit_DisconnectDeletesTheConnection(t *testing.T) {
+	mux, _ := setupAccountMux(t, nil)
+	session := login(t, mux)
+	userID := int64(1)
+	tokenID, err := database.InsertGoogleToken(&userID, "access", "refresh", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("InsertGoogleToken: %v", err)
+	}
+	path := fmt.Sprintf("/oauth/connections/google/%d", tokenID)
+
+	if rr := serve(mux, http.MethodDelete, "/oauth/connections/dropbox/1", "", session); rr.Code != http.StatusBadRequest {
+		t.Fatalf("unknown provider: status = %d, want 400", rr.Code)
+	}
+	if rr := serve(mux, http.MethodDelete, path, "", session); rr.Code != http.StatusOK {
+		t.Fatalf("disconnect status = %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(mux, http.MethodDelete, path, "", session); rr.Code != http.StatusNotFound {
+		t.Fatalf("second disconnect: status = %d, want 404", rr.Code)
+	}
+	if _, err := database.GetGoogleToken(tokenID); err == nil {
+		t.Fatal("disconnected token still stored")
+	}
+
+	rr := serve(mux, http.MethodGet, "/me/audit?action=oauth.", "", session)
+	events := decodeJSON[[]auth.AuditEvent](t, rr.Body.Bytes())
+	if len(events) != 1 || events[0].Action != "oauth.disconnected" || events[0].Target != fmt.Sprintf("google:%d", tokenID) {
+		t.Fatalf("unexpected oauth events: %+v", events)
+	}
+}
@@ -2,6 +2,7 @@ package httpapi
 
 import (
 	"area/src/httpapi"
+	"area/src/logging"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -39,3 +40,33 @@ func TestWithCORS_OptionsShortCircuit(t *testing.T) {
 		t.Fatalf("status = %d, want 204", rec.Code)
 	}
 }
+
+func TestWithRequestID_KeepsClientID(t *testing.T) {
+	var seen string
+	handler := httpapi.WithRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logging.RequestID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/any", nil)
+	req.Header.Set("X-Request-ID", "client-42")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if seen != "client-42" || rec.Header().Get("X-Request-ID") != "client-42" {
+		t.Fatalf("request id = %q, header = %q; want client-42", seen, rec.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestWithRequestID_ReplacesInvalidID(t *testing.T) {
+	handler := httpapi.WithRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/any", nil)
+	req.Header.Set("X-Request-ID", "bad id\nwith newline")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	got := rec.Header().Get("X-Request-ID")
+	if got == "" || got == req.Header.Get("X-Request-ID") {
+		t.Fatalf("expected a generated request id, got %q", got)
+	}
+}
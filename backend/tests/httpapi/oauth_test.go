@@ -0,0 +1,101 @@
+package httpapi
+
+import (
+	"area/src/auth"
+	"area/src/config"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func googleConfig() *config.Config {
+	cfg := config.Default()
+	cfg.Google = config.OAuth{ClientID: "id", ClientSecret: "secret", RedirectURI: "http://localhost:8080/oauth/google/callback"}
+	return cfg
+}
+
+func TestOAuth_LoginBindsTheStateToTheCaller(t *testing.T) {
+	mux, _ := setupAccountMux(t, googleConfig())
+	if rr := serve(mux, http.MethodGet, "/oauth/google/login?user_id=1", "", ""); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous login: status = %d, want 401", rr.Code)
+	}
+	session := login(t, mux)
+	rr := serve(mux, http.MethodGet, "/oauth/google/login?access_token="+session, "", "")
+	if rr.Code != http.StatusFound {
+		t.Fatalf("login status = %d: %s", rr.Code, rr.Body)
+	}
+	var state string
+	for _, cookie := range rr.Result().Cookies() {
+		switch cookie.Name {
+		case "oauthstate":
+			state = cookie.Value
+		case "oauthuserid":
+			t.Fatal("user id stored in a cookie")
+		}
+	}
+	if !strings.HasPrefix(state, "ost_") || !strings.Contains(rr.Header().Get("Location"), "state="+state) {
+		t.Fatalf("unexpected state %q, location %s", state, rr.Header().Get("Location"))
+	}
+
+	// A state the server did not issue is rejected, even with a matching cookie.
+	req := httptest.NewRequest(http.MethodGet, "/oauth/google/callback?state=forged&code=c", nil)
+	req.AddCookie(&http.Cookie{Name: "oauthstate", Value: "forged"})
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("forged state: status = %d, want 400", rr.Code)
+	}
+}
+
+func TestOAuth_ReactionsActAsAVerifiedUser(t *testing.T) {
+	mux, _ := setupAccountMux(t, googleConfig())
+	body := `{"token_id":1,"to":"a@b.com","subject":"hi"}`
+	req := httptest.NewRequest(http.MethodPost, "/actions/google/email", strings.NewReader(body))
+	req.Header.Set("X-User-ID", "1")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("user header: status = %d, want 401", rr.Code)
+	}
+
+	store := auth.NewDBStore()
+	issuer := auth.NewService(store)
+	issuer.Accounts = store
+	token, err := issuer.IssueReactionToken(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("IssueReactionToken: %v", err)
+	}
+	// The user has no Google token 1, so the reaction itself fails once authenticated.
+	if rr := serve(mux, http.MethodPost, "/actions/google/email", body, token); rr.Code != http.StatusInternalServerError {
+		t.Fatalf("reaction token: status = %d, want 500: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(mux, http.MethodPost, "/actions/google/email", body, token); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("reused reaction token: status = %d, want 401", rr.Code)
+	}
+}
+
+func TestOAuth_ReactionsRejectPersonalAccessTokens(t *testing.T) {
+	mux, _ := setupAccountMux(t, googleConfig())
+	session := login(t, mux)
+	rr := serve(mux, http.MethodPost, "/me/tokens", `{"name":"ci","scopes":["workflows:read","workflows:write","workflows:trigger"]}`, session)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create token status = %d: %s", rr.Code, rr.Body)
+	}
+	var created struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil || created.Token == "" {
+		t.Fatalf("decode token: %v in %s", err, rr.Body)
+	}
+
+	body := `{"token_id":1,"to":"a@b.com","subject":"hi"}`
+	if rr := serve(mux, http.MethodPost, "/actions/google/email", body, created.Token); rr.Code != http.StatusForbidden {
+		t.Fatalf("personal access token: status = %d, want 403", rr.Code)
+	}
+	if rr := serve(mux, http.MethodPost, "/actions/google/email", body, session); rr.Code == http.StatusForbidden || rr.Code == http.StatusUnauthorized {
+		t.Fatalf("session: status = %d, want it authenticated", rr.Code)
+	}
+}
@@ -0,0 +1,110 @@
+package httpapi
+
+import (
+	"area/src/areas"
+	"area/src/httpapi"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type openAPISpec struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]json.RawMessage `json:"schemas"`
+	} `json:"components"`
+}
+
+func loadSpec(t *testing.T) openAPISpec {
+	t.Helper()
+	var spec openAPISpec
+	if err := json.Unmarshal(httpapi.OpenAPISpec(), &spec); err != nil {
+		t.Fatalf("embedded spec is not JSON: %v", err)
+	}
+	return spec
+}
+
+// servedBy tells whether a spec path is handled by the mux pattern; patterns ending in a slash
+// match a subtree.
+func servedBy(pattern, path string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(path, pattern)
+	}
+	return path == pattern
+}
+
+func TestOpenAPISpec_UpToDate(t *testing.T) {
+	generated, err := httpapi.BuildOpenAPI()
+	if err != nil {
+		t.Fatalf("BuildOpenAPI: %v", err)
+	}
+	if !bytes.Equal(generated, httpapi.OpenAPISpec()) {
+		t.Fatal("src/httpapi/openapi.json is stale, run go generate ./src/httpapi")
+	}
+}
+
+func TestOpenAPISpec_DocumentsEveryRoute(t *testing.T) {
+	spec := loadSpec(t)
+	routes := httpapi.Routes()
+	for _, pattern := range routes {
+		documented := false
+		for path := range spec.Paths {
+			documented = documented || servedBy(pattern, path)
+		}
+		if !documented {
+			t.Errorf("route %s is missing from the OpenAPI spec", pattern)
+		}
+	}
+	for path := range spec.Paths {
+		served := false
+		for _, pattern := range routes {
+			served = served || servedBy(pattern, path)
+		}
+		if !served {
+			t.Errorf("spec path %s is not served by any route", path)
+		}
+	}
+}
+
+func TestOpenAPISpec_TriggerConfigsFromCatalog(t *testing.T) {
+	spec := loadSpec(t)
+	for _, svc := range areas.Definitions {
+		for _, trigger := range svc.Triggers {
+			raw, ok := spec.Components.Schemas["WorkflowRequest."+trigger.ID]
+			if !ok {
+				t.Errorf("no WorkflowRequest schema for trigger %s", trigger.ID)
+				continue
+			}
+			var variant struct {
+				Properties struct {
+					TriggerConfig struct {
+						Properties map[string]json.RawMessage `json:"properties"`
+					} `json:"trigger_config"`
+				} `json:"properties"`
+			}
+			if err := json.Unmarshal(raw, &variant); err != nil {
+				t.Fatalf("decode %s: %v", trigger.ID, err)
+			}
+			for _, f := range trigger.Fields {
+				if _, ok := variant.Properties.TriggerConfig.Properties[f.Key]; !ok {
+					t.Errorf("trigger_config of %s lacks field %s", trigger.ID, f.Key)
+				}
+			}
+		}
+	}
+}
+
+func TestOpenAPISpec_ServedFromEmbed(t *testing.T) {
+	mux := httpapi.NewMux(nil, nil, nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/resources/openapi.json", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rr.Code)
+	}
+	if !bytes.Equal(rr.Body.Bytes(), httpapi.OpenAPISpec()) {
+		t.Fatal("served spec differs from the embedded one")
+	}
+}
@@ -0,0 +1,44 @@
+package httpapi
+
+import (
+	"area/src/auth"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSecrets_WriteOnly(t *testing.T) {
+	mux, _ := setupAccountMux(t, nil)
+	token := login(t, mux)
+
+	if rr := serve(mux, http.MethodPut, "/me/secrets/slack_bot", `{"value":"xoxb-1"}`, token); rr.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(mux, http.MethodPut, "/me/secrets/slack_bot", `{"value":"xoxb-2"}`, token); rr.Code != http.StatusOK {
+		t.Fatalf("replace status = %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(mux, http.MethodPut, "/me/secrets/slack.bot", `{"value":"x"}`, token); rr.Code != http.StatusBadRequest {
+		t.Fatalf("invalid name: status = %d, want 400", rr.Code)
+	}
+	if rr := serve(mux, http.MethodPut, "/me/secrets/empty", `{"value":""}`, token); rr.Code != http.StatusBadRequest {
+		t.Fatalf("empty value: status = %d, want 400", rr.Code)
+	}
+
+	rr := serve(mux, http.MethodGet, "/me/secrets", "", token)
+	if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), "xoxb") {
+		t.Fatalf("list status = %d: %s", rr.Code, rr.Body)
+	}
+	if secrets := decodeJSON[[]auth.Secret](t, rr.Body.Bytes()); len(secrets) != 1 || secrets[0].Name != "slack_bot" {
+		t.Fatalf("secrets = %+v", secrets)
+	}
+	if rr := serve(mux, http.MethodGet, "/me/secrets", "", ""); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("without session: status = %d, want 401", rr.Code)
+	}
+
+	if rr := serve(mux, http.MethodDelete, "/me/secrets/slack_bot", "", token); rr.Code != http.StatusOK {
+		t.Fatalf("delete status = %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(mux, http.MethodDelete, "/me/secrets/slack_bot", "", token); rr.Code != http.StatusNotFound {
+		t.Fatalf("second delete: status = %d, want 404", rr.Code)
+	}
+}
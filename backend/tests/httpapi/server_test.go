@@ -2,15 +2,18 @@ package httpapi
 
 import (
 	"area/src/auth"
+	"area/src/config"
 	"area/src/database"
 	"area/src/httpapi"
+	"area/src/workflows"
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"gorm.io/driver/postgres"
@@ -55,6 +58,24 @@ func (s *stubStore) GetByEmail(email string) (*auth.User, string, error) {
 	return s.user, s.hash, s.err
 }
 
+// stubSessions accepts every new session and knows no token.
+type stubSessions struct{}
+
+func (stubSessions) CreateSession(context.Context, *auth.Session, string, string) error { return nil }
+func (stubSessions) SessionByAccessHash(context.Context, string) (*auth.Session, error) {
+	return nil, auth.ErrInvalidToken
+}
+func (stubSessions) SessionByRefreshHash(context.Context, string) (*auth.Session, error) {
+	return nil, auth.ErrInvalidToken
+}
+func (stubSessions) RotateSession(context.Context, *auth.Session, string, string, string) (bool, error) {
+	return false, nil
+}
+func (stubSessions) RevokeSession(context.Context, int64, time.Time) error { return nil }
+func (stubSessions) RevokeUserSessions(context.Context, int64, time.Time) error {
+	return nil
+}
+
 func TestHealth(t *testing.T) {
 	h := &httpapi.Handler{}
 	rr := httptest.NewRecorder()
@@ -71,6 +92,7 @@ func TestLogin_Success(t *testing.T) {
 		user: &auth.User{ID: 1, Email: "a@b.com"},
 		hash: hash,
 	})
+	svc.Sessions = &stubSessions{}
 	h := &httpapi.Handler{Auth: svc}
 	body := bytes.NewBufferString(`{"email":"a@b.com","password":"pw"}`)
 	req := httptest.NewRequest(http.MethodPost, "/login", body)
@@ -79,6 +101,17 @@ func TestLogin_Success(t *testing.T) {
 	if rr.Code != http.StatusOK {
 		t.Fatalf("status %d, want 200", rr.Code)
 	}
+	var resp struct {
+		Email        string `json:"email"`
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Email != "a@b.com" || resp.AccessToken == "" || resp.RefreshToken == "" {
+		t.Fatalf("unexpected response %s", rr.Body)
+	}
 }
 
 func TestRegister_MissingFields(t *testing.T) {
@@ -94,36 +127,6 @@ func TestRegister_MissingFields(t *testing.T) {
 }
 
 func TestAbout_OK(t *testing.T) {
-	envs := map[string]string{
-		"GOOGLE_OAUTH_CLIENT_ID":            "x",
-		"GOOGLE_OAUTH_CLIENT_SECRET":        "x",
-		"GOOGLE_OAUTH_REDIRECT_URI":         "http://localhost/callback",
-		"GITHUB_OAUTH_CLIENT_ID":            "x",
-		"GITHUB_OAUTH_CLIENT_SECRET":        "x",
-		"GITHUB_OAUTH_REDIRECT_URI":         "http://localhost/callback",
-		"GITHUB_MOBILE_OAUTH_CLIENT_ID":     "x",
-		"GITHUB_MOBILE_OAUTH_CLIENT_SECRET": "x",
-		"GITHUB_MOBILE_OAUTH_REDIRECT_URI":  "http://localhost/callback",
-	}
-	original := map[string]*string{}
-	for k, v := range envs {
-		if curr, ok := os.LookupEnv(k); ok {
-			original[k] = &curr
-		} else {
-			original[k] = nil
-		}
-		_ = os.Setenv(k, v)
-	}
-	t.Cleanup(func() {
-		for k, v := range original {
-			if v == nil {
-				_ = os.Unsetenv(k)
-				continue
-			}
-			_ = os.Setenv(k, *v)
-		}
-	})
-
 	mockDB, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("sqlmock.New: %v", err)
@@ -149,7 +152,7 @@ func TestAbout_OK(t *testing.T) {
 	mock.ExpectQuery(`^SELECT \* FROM "area_fields" ORDER BY .*id$`).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "service_id", "capability_id", "key", "type", "required", "description", "example", "created_at", "updated_at"}))
 
-	mux := httpapi.NewMux(nil, nil)
+	mux := httpapi.NewMux(nil, nil, nil)
 	req := httptest.NewRequest(http.MethodGet, "/about.json", nil)
 	req.RemoteAddr = "10.0.0.1:1234"
 	rr := httptest.NewRecorder()
@@ -192,3 +195,50 @@ func TestAbout_OK(t *testing.T) {
 		t.Fatalf("unmet expectations: %v", err)
 	}
 }
+
+func TestCreateWorkflow_ListsInvalidFields(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.DevUserHeader = true
+	mux := httpapi.NewMux(nil, workflows.NewService(&workflows.Store{}, nil), cfg)
+	body := `{"name":"n","trigger_type":"github_commit","action_url":"http://example.com","trigger_config":{"token_id":0,"repo":"nope"}}`
+	req := httptest.NewRequest(http.MethodPost, "/workflows", strings.NewReader(body))
+	req.Header.Set("X-User-ID", "1")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", rr.Code, rr.Body)
+	}
+	var resp struct {
+		Error  string `json:"error"`
+		Fields []struct {
+			Field   string `json:"field"`
+			Message string `json:"message"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	var got []string
+	for _, f := range resp.Fields {
+		got = append(got, f.Field)
+	}
+	if resp.Error != "invalid trigger_config" || strings.Join(got, ",") != "token_id,repo,branch" {
+		t.Fatalf("unexpected response %s", rr.Body)
+	}
+}
+
+func TestReactionRoute_RejectsInvalidPayload(t *testing.T) {
+	mux := httpapi.NewMux(nil, nil, nil)
+	req := httptest.NewRequest(http.MethodPost, "/actions/slack/message", strings.NewReader(`{"channel_id":"C1","text":42}`))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), `{"field":"text","message":"must be a string"}`) ||
+		!strings.Contains(rr.Body.String(), `{"field":"bot_token","message":"is required"}`) {
+		t.Fatalf("unexpected body %s", rr.Body)
+	}
+}
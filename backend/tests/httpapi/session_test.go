@@ -0,0 +1,182 @@
+package httpapi
+
+import (
+	"area/src/auth"
+	"area/src/config"
+	"area/src/database"
+	"area/src/httpapi"
+	"area/src/mail"
+	"area/src/workflows"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// setupSessionMux returns a mux over a migrated SQLite database holding the user a@b.com with
+// password "pw", and sessions, personal access tokens and workspaces stored in that database.
+func setupSessionMux(t *testing.T, cfg *config.Config) http.Handler {
+	t.Helper()
+	mux, _ := setupAccountMux(t, cfg)
+	return mux
+}
+
+// outbox is a mail.Mailer keeping the messages it is given.
+type outbox struct {
+	mu   sync.Mutex
+	sent []mail.Message
+	// pending waits for the emails the auth service sends in the background.
+	pending func(context.Context) error
+}
+
+// wait returns once the emails sent in the background are in the outbox.
+func (o *outbox) wait(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := o.pending(ctx); err != nil {
+		t.Fatalf("wait for emails: %v", err)
+	}
+}
+
+func (o *outbox) Send(_ context.Context, msg mail.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.sent = append(o.sent, msg)
+	return nil
+}
+
+// setupAccountMux is setupSessionMux with the emails of the auth service kept in the returned outbox.
+func setupAccountMux(t *testing.T, cfg *config.Config) (http.Handler, *outbox) {
+	t.Helper()
+	db, err := gorm.Open(database.Dialector(config.Database{
+		Driver:     config.DriverSQLite,
+		SQLitePath: filepath.Join(t.TempDir(), "area.db"),
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	originalDB := database.Db
+	database.SetDBForTesting(db)
+	t.Cleanup(func() {
+		database.SetDBForTesting(originalDB)
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	store := auth.NewDBStore()
+	svc := auth.NewService(store)
+	svc.Sessions = store
+	svc.APITokens = store
+	svc.Accounts = store
+	svc.TwoFactor = store
+	svc.Throttle = store
+	svc.Workspaces = store
+	svc.Secrets = store
+	svc.Connections = store
+	svc.AuditLog = store
+	mails := &outbox{pending: svc.Wait}
+	svc.Mailer = mails
+	t.Cleanup(func() { mails.wait(t) })
+	svc.AppURL = "https://app.example.com"
+	if _, err := svc.Register(context.Background(), "a@b.com", "pw", "Ada", "Lovelace"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	workflowStore := workflows.NewMemoryStore()
+	workflowStore.Workspaces = store
+	workflowService := workflows.NewService(workflowStore, workflows.NewTriggerer(workflowStore))
+	workflowService.Audit = svc
+	return httpapi.NewMux(svc, workflowService, cfg), mails
+}
+
+func serve(mux http.Handler, method, path, body, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	return rr
+}
+
+func decodeTokens(t *testing.T, rr *httptest.ResponseRecorder) auth.Tokens {
+	t.Helper()
+	var tokens auth.Tokens
+	if err := json.Unmarshal(rr.Body.Bytes(), &tokens); err != nil {
+		t.Fatalf("decode tokens: %v", err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("missing tokens in %s", rr.Body)
+	}
+	return tokens
+}
+
+func TestSession_LoginRefreshLogout(t *testing.T) {
+	mux := setupSessionMux(t, nil)
+
+	rr := serve(mux, http.MethodPost, "/login", `{"email":"a@b.com","password":"pw"}`, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("login status = %d: %s", rr.Code, rr.Body)
+	}
+	first := decodeTokens(t, rr)
+	if rr := serve(mux, http.MethodGet, "/workflows", "", first.AccessToken); rr.Code != http.StatusOK {
+		t.Fatalf("workflows with access token: status = %d: %s", rr.Code, rr.Body)
+	}
+
+	rr = serve(mux, http.MethodPost, "/refresh", `{"refresh_token":"`+first.RefreshToken+`"}`, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("refresh status = %d: %s", rr.Code, rr.Body)
+	}
+	second := decodeTokens(t, rr)
+	if rr := serve(mux, http.MethodGet, "/workflows", "", first.AccessToken); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("rotated access token: status = %d, want 401", rr.Code)
+	}
+	if rr := serve(mux, http.MethodPost, "/refresh", `{"refresh_token":"`+first.RefreshToken+`"}`, ""); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("reused refresh token: status = %d, want 401", rr.Code)
+	}
+
+	if rr := serve(mux, http.MethodPost, "/logout", "", second.AccessToken); rr.Code != http.StatusOK {
+		t.Fatalf("logout status = %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(mux, http.MethodGet, "/workflows", "", second.AccessToken); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("access token after logout: status = %d, want 401", rr.Code)
+	}
+}
+
+func TestSession_UserHeaderNeedsDevFlag(t *testing.T) {
+	cfg := config.Default()
+	mux := setupSessionMux(t, cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "/workflows", nil)
+	req.Header.Set("X-User-ID", "1")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rr.Code)
+	}
+
+	cfg.Auth.DevUserHeader = true
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("with dev flag: status = %d, want 200: %s", rr.Code, rr.Body)
+	}
+}
+
+func TestSession_EventStreamRejectsMissingToken(t *testing.T) {
+	mux := setupSessionMux(t, nil)
+	if rr := serve(mux, http.MethodGet, "/events/stream?access_token=nope", "", ""); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rr.Code)
+	}
+}
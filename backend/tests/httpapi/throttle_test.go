@@ -0,0 +1,33 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestLogin_ThrottlesFailedAttempts(t *testing.T) {
+	mux := setupSessionMux(t, nil)
+
+	for i := range 5 {
+		rr := serve(mux, http.MethodPost, "/login", `{"email":"a@b.com","password":"wrong"}`, "")
+		if rr.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d status = %d, want 401", i+1, rr.Code)
+		}
+	}
+	rr := serve(mux, http.MethodPost, "/login", `{"email":"a@b.com","password":"pw"}`, "")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("login after 5 failures status = %d, want 429: %s", rr.Code, rr.Body)
+	}
+	if seconds, err := strconv.Atoi(rr.Header().Get("Retry-After")); err != nil || seconds < 1 || seconds > 30 {
+		t.Fatalf("Retry-After = %q, want 1 to 30 seconds", rr.Header().Get("Retry-After"))
+	}
+
+	// Unknown emails are locked out alike, so lockouts do not reveal accounts.
+	for range 5 {
+		serve(mux, http.MethodPost, "/login", `{"email":"nobody@b.com","password":"wrong"}`, "")
+	}
+	if rr := serve(mux, http.MethodPost, "/login", `{"email":"nobody@b.com","password":"wrong"}`, ""); rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("unknown email after 5 failures status = %d, want 429", rr.Code)
+	}
+}
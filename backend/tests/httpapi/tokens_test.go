@@ -0,0 +1,85 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// login returns an access token of a@b.com.
+func login(t *testing.T, mux http.Handler) string {
+	t.Helper()
+	rr := serve(mux, http.MethodPost, "/login", `{"email":"a@b.com","password":"pw"}`, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("login status = %d: %s", rr.Code, rr.Body)
+	}
+	return decodeTokens(t, rr).AccessToken
+}
+
+func TestAPIToken_TriggersWorkflowWithinScopes(t *testing.T) {
+	mux := setupSessionMux(t, nil)
+	session := login(t, mux)
+
+	rr := serve(mux, http.MethodPost, "/workflows", `{"name":"ci","trigger_type":"manual","action_url":"http://example.com"}`, session)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create workflow status = %d: %s", rr.Code, rr.Body)
+	}
+	var wf struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &wf); err != nil {
+		t.Fatalf("decode workflow: %v", err)
+	}
+
+	rr = serve(mux, http.MethodPost, "/me/tokens", `{"name":"ci","scopes":["workflows:trigger"]}`, session)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create token status = %d: %s", rr.Code, rr.Body)
+	}
+	var created struct {
+		ID    int64  `json:"id"`
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil || created.Token == "" {
+		t.Fatalf("decode token: %v, %s", err, rr.Body)
+	}
+
+	trigger := "/workflows/" + strconv.FormatInt(wf.ID, 10) + "/trigger"
+	if rr := serve(mux, http.MethodPost, trigger, `{}`, created.Token); rr.Code != http.StatusAccepted {
+		t.Fatalf("trigger with token: status = %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(mux, http.MethodGet, "/workflows", "", created.Token); rr.Code != http.StatusForbidden {
+		t.Fatalf("read without workflows:read: status = %d, want 403", rr.Code)
+	}
+	if rr := serve(mux, http.MethodGet, "/me/tokens", "", created.Token); rr.Code != http.StatusForbidden {
+		t.Fatalf("token management with a token: status = %d, want 403", rr.Code)
+	}
+
+	rr = serve(mux, http.MethodGet, "/me/tokens", "", session)
+	var listed []struct {
+		Name       string  `json:"name"`
+		Token      string  `json:"token"`
+		LastUsedAt *string `json:"last_used_at"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &listed); err != nil || len(listed) != 1 {
+		t.Fatalf("list tokens: %v, %s", err, rr.Body)
+	}
+	if listed[0].Token != "" || listed[0].LastUsedAt == nil {
+		t.Fatalf("unexpected listed token %s", rr.Body)
+	}
+
+	if rr := serve(mux, http.MethodDelete, "/me/tokens/"+strconv.FormatInt(created.ID, 10), "", session); rr.Code != http.StatusOK {
+		t.Fatalf("delete token status = %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(mux, http.MethodPost, trigger, `{}`, created.Token); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("deleted token: status = %d, want 401", rr.Code)
+	}
+}
+
+func TestAPIToken_RejectsUnknownScope(t *testing.T) {
+	mux := setupSessionMux(t, nil)
+	session := login(t, mux)
+	if rr := serve(mux, http.MethodPost, "/me/tokens", `{"name":"ci","scopes":["admin"]}`, session); rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", rr.Code, rr.Body)
+	}
+}
@@ -0,0 +1,109 @@
+package httpapi
+
+import (
+	"area/src/auth"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTwoFactor_LoginNeedsCode(t *testing.T) {
+	mux := setupSessionMux(t, nil)
+	session := login(t, mux)
+
+	rr := serve(mux, http.MethodPost, "/me/2fa/enroll", "", session)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("enroll status = %d: %s", rr.Code, rr.Body)
+	}
+	var enrollment auth.TwoFactorEnrollment
+	if err := json.Unmarshal(rr.Body.Bytes(), &enrollment); err != nil {
+		t.Fatalf("decode enrollment: %v", err)
+	}
+	if rr := serve(mux, http.MethodPost, "/me/2fa/verify", `{"code":"000000"}`, session); rr.Code != http.StatusBadRequest {
+		t.Fatalf("verify with a wrong code status = %d, want 400", rr.Code)
+	}
+	code, err := auth.TOTPCode(enrollment.Secret, time.Now())
+	if err != nil {
+		t.Fatalf("TOTPCode: %v", err)
+	}
+	rr = serve(mux, http.MethodPost, "/me/2fa/verify", `{"code":"`+code+`"}`, session)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("verify status = %d: %s", rr.Code, rr.Body)
+	}
+	var recovery struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &recovery); err != nil || len(recovery.RecoveryCodes) != auth.RecoveryCodeCount {
+		t.Fatalf("recovery codes = %s (%v)", rr.Body, err)
+	}
+	if rr := serve(mux, http.MethodPost, "/me/2fa/enroll", "", session); rr.Code != http.StatusConflict {
+		t.Fatalf("enroll once enabled status = %d, want 409", rr.Code)
+	}
+
+	challenge := func() string {
+		t.Helper()
+		rr := serve(mux, http.MethodPost, "/login", `{"email":"a@b.com","password":"pw"}`, "")
+		var body struct {
+			TwoFactorRequired bool   `json:"two_factor_required"`
+			TwoFactorToken    string `json:"two_factor_token"`
+			AccessToken       string `json:"access_token"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode login: %v", err)
+		}
+		if rr.Code != http.StatusOK || !body.TwoFactorRequired || body.TwoFactorToken == "" || body.AccessToken != "" {
+			t.Fatalf("login = %d %s, want a two-factor challenge without tokens", rr.Code, rr.Body)
+		}
+		return body.TwoFactorToken
+	}
+
+	// The verification code was used, so it cannot complete a login.
+	rr = serve(mux, http.MethodPost, "/login/2fa", `{"two_factor_token":"`+challenge()+`","code":"`+code+`"}`, "")
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("login with a used code status = %d, want 401", rr.Code)
+	}
+	token := challenge()
+	rr = serve(mux, http.MethodPost, "/login/2fa", `{"two_factor_token":"`+token+`","code":"`+recovery.RecoveryCodes[0]+`"}`, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("login with a recovery code status = %d: %s", rr.Code, rr.Body)
+	}
+	tokens := decodeTokens(t, rr)
+	rr = serve(mux, http.MethodGet, "/me/2fa", "", tokens.AccessToken)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status status = %d: %s", rr.Code, rr.Body)
+	}
+	var status auth.TwoFactorStatus
+	if err := json.Unmarshal(rr.Body.Bytes(), &status); err != nil || !status.Enabled || status.RecoveryCodesLeft != auth.RecoveryCodeCount-1 {
+		t.Fatalf("two-factor status = %s (%v)", rr.Body, err)
+	}
+	if rr := serve(mux, http.MethodPost, "/login/2fa", `{"two_factor_token":"`+token+`","code":"`+recovery.RecoveryCodes[1]+`"}`, ""); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("reusing a challenge status = %d, want 401", rr.Code)
+	}
+
+	rr = serve(mux, http.MethodPost, "/me/2fa/disable", `{"code":"`+recovery.RecoveryCodes[1]+`"}`, tokens.AccessToken)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("disable status = %d: %s", rr.Code, rr.Body)
+	}
+	login(t, mux)
+}
+
+func TestTwoFactor_RoutesNeedSession(t *testing.T) {
+	mux := setupSessionMux(t, nil)
+	session := login(t, mux)
+	rr := serve(mux, http.MethodPost, "/me/tokens", `{"name":"ci","scopes":["workflows:read"]}`, session)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create token status = %d: %s", rr.Code, rr.Body)
+	}
+	var created struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode token: %v", err)
+	}
+	for _, path := range []string{"/me/2fa/enroll", "/me/2fa/disable"} {
+		if rr := serve(mux, http.MethodPost, path, `{"code":"123456"}`, created.Token); rr.Code != http.StatusForbidden {
+			t.Errorf("%s with a personal access token status = %d, want 403", path, rr.Code)
+		}
+	}
+}
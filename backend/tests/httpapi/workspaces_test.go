@@ -0,0 +1,116 @@
+package httpapi
+
+import (
+	"area/src/auth"
+	"area/src/workflows"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func decodeJSON[T any](t *testing.T, body []byte) T {
+	t.Helper()
+	var value T
+	if err := json.Unmarshal(body, &value); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	return value
+}
+
+func TestWorkspace_InviteRolesAndAudit(t *testing.T) {
+	mux, mails := setupAccountMux(t, nil)
+	owner := login(t, mux)
+	rr := serve(mux, http.MethodPost, "/register", `{"email":"c@d.com","password":"pw","firstname":"Grace","lastname":"Hopper"}`, "")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("register status = %d: %s", rr.Code, rr.Body)
+	}
+	member := decodeTokens(t, serve(mux, http.MethodPost, "/login", `{"email":"c@d.com","password":"pw"}`, "")).AccessToken
+
+	rr = serve(mux, http.MethodPost, "/workspaces", `{"name":" Ops "}`, owner)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create workspace status = %d: %s", rr.Code, rr.Body)
+	}
+	workspace := decodeJSON[auth.Workspace](t, rr.Body.Bytes())
+	if workspace.Name != "Ops" || workspace.Role != auth.RoleOwner {
+		t.Fatalf("unexpected workspace: %+v", workspace)
+	}
+	base := fmt.Sprintf("/workspaces/%d", workspace.ID)
+
+	if rr := serve(mux, http.MethodGet, base+"/members", "", member); rr.Code != http.StatusNotFound {
+		t.Fatalf("members for outsider: status = %d, want 404", rr.Code)
+	}
+	if rr := serve(mux, http.MethodPost, base+"/invites", `{"email":"c@d.com","role":"admin"}`, owner); rr.Code != http.StatusBadRequest {
+		t.Fatalf("invite with unknown role: status = %d, want 400", rr.Code)
+	}
+	if rr := serve(mux, http.MethodPost, base+"/invites", `{"email":"C@d.com","role":"viewer"}`, owner); rr.Code != http.StatusCreated {
+		t.Fatalf("invite status = %d: %s", rr.Code, rr.Body)
+	}
+	token := mailedToken(t, mails, "/accept-invite")
+	if rr := serve(mux, http.MethodPost, "/invites/accept", `{"token":"`+token+`"}`, owner); rr.Code != http.StatusBadRequest {
+		t.Fatalf("invite accepted by another address: status = %d, want 400", rr.Code)
+	}
+	rr = serve(mux, http.MethodPost, "/invites/accept", `{"token":"`+token+`"}`, member)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("accept status = %d: %s", rr.Code, rr.Body)
+	}
+	if joined := decodeJSON[auth.Workspace](t, rr.Body.Bytes()); joined.ID != workspace.ID || joined.Role != auth.RoleViewer {
+		t.Fatalf("joined %+v", joined)
+	}
+	if rr := serve(mux, http.MethodPost, "/invites/accept", `{"token":"`+token+`"}`, member); rr.Code != http.StatusBadRequest {
+		t.Fatalf("reused invite: status = %d, want 400", rr.Code)
+	}
+
+	workflow := fmt.Sprintf(`{"name":"shared","trigger_type":"manual","action_url":"http://example.com","workspace_id":%d}`, workspace.ID)
+	if rr := serve(mux, http.MethodPost, "/workflows", workflow, member); rr.Code != http.StatusForbidden {
+		t.Fatalf("viewer creating workflow: status = %d, want 403", rr.Code)
+	}
+	rr = serve(mux, http.MethodPost, "/workflows", workflow, owner)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create workflow status = %d: %s", rr.Code, rr.Body)
+	}
+	created := decodeJSON[workflows.Workflow](t, rr.Body.Bytes())
+
+	listed := decodeJSON[[]workflows.Workflow](t, serve(mux, http.MethodGet, "/workflows", "", member).Body.Bytes())
+	if len(listed) != 1 || listed[0].ID != created.ID {
+		t.Fatalf("viewer lists %+v", listed)
+	}
+	path := fmt.Sprintf("/workflows/%d", created.ID)
+	if rr := serve(mux, http.MethodDelete, path, "", member); rr.Code != http.StatusForbidden {
+		t.Fatalf("viewer deleting workflow: status = %d, want 403", rr.Code)
+	}
+
+	memberPath := fmt.Sprintf("%s/members/%d", base, 2)
+	if rr := serve(mux, http.MethodPut, memberPath, `{"role":"owner"}`, member); rr.Code != http.StatusForbidden {
+		t.Fatalf("viewer promoting themselves: status = %d, want 403", rr.Code)
+	}
+	if rr := serve(mux, http.MethodPut, memberPath, `{"role":"editor"}`, owner); rr.Code != http.StatusOK {
+		t.Fatalf("promote status = %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(mux, http.MethodDelete, path, "", member); rr.Code != http.StatusOK {
+		t.Fatalf("editor deleting workflow: status = %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(mux, http.MethodPut, base+"/members/1", `{"role":"viewer"}`, owner); rr.Code != http.StatusConflict {
+		t.Fatalf("demoting the last owner: status = %d, want 409", rr.Code)
+	}
+
+	rr = serve(mux, http.MethodGet, base+"/audit", "", member)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("audit status = %d: %s", rr.Code, rr.Body)
+	}
+	var actions []string
+	for _, event := range decodeJSON[[]auth.AuditEvent](t, rr.Body.Bytes()) {
+		actions = append(actions, event.Action)
+	}
+	want := []string{"workflow.deleted", "member.role_changed", "workflow.created", "member.joined", "member.invited", "workspace.created"}
+	if fmt.Sprint(actions) != fmt.Sprint(want) {
+		t.Fatalf("audit actions = %v, want %v", actions, want)
+	}
+
+	if rr := serve(mux, http.MethodDelete, memberPath, "", member); rr.Code != http.StatusOK {
+		t.Fatalf("leave status = %d: %s", rr.Code, rr.Body)
+	}
+	if rr := serve(mux, http.MethodGet, base+"/members", "", member); rr.Code != http.StatusNotFound {
+		t.Fatalf("members after leaving: status = %d, want 404", rr.Code)
+	}
+}
@@ -2,12 +2,18 @@ package github
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
+	"area/src/config"
 	gh "area/src/integrations/github"
+	"area/src/workflows"
 )
 
 func TestLogin_MissingRedirectURI(t *testing.T) {
@@ -23,6 +29,7 @@ func TestLogin_MissingRedirectURI(t *testing.T) {
 
 	h := gh.NewHTTPHandlers(&gh.Client{})
 	req := httptest.NewRequest(http.MethodGet, "/oauth/github/login", nil)
+	req = req.WithContext(workflows.WithUserID(req.Context(), 1))
 	rr := httptest.NewRecorder()
 	h.Login().ServeHTTP(rr, req)
 	if rr.Code != http.StatusBadRequest {
@@ -54,6 +61,7 @@ func TestIssue_InvalidRepo(t *testing.T) {
 	h := gh.NewHTTPHandlers(&gh.Client{})
 	body := bytes.NewBufferString(`{"token_id":1,"repo":"bad","title":"t"}`)
 	req := httptest.NewRequest(http.MethodPost, "/actions/github/issue", body)
+	req = req.WithContext(workflows.WithUserID(req.Context(), 1))
 	rr := httptest.NewRecorder()
 	h.Issue().ServeHTTP(rr, req)
 	if rr.Code != http.StatusBadRequest {
@@ -65,6 +73,7 @@ func TestIssue_InvalidLabels(t *testing.T) {
 	h := gh.NewHTTPHandlers(&gh.Client{})
 	body := bytes.NewBufferString(`{"token_id":1,"repo":"o/r","title":"t","labels":{}}`)
 	req := httptest.NewRequest(http.MethodPost, "/actions/github/issue", body)
+	req = req.WithContext(workflows.WithUserID(req.Context(), 1))
 	rr := httptest.NewRecorder()
 	h.Issue().ServeHTTP(rr, req)
 	if rr.Code != http.StatusBadRequest {
@@ -76,9 +85,76 @@ func TestPullRequest_MissingFields(t *testing.T) {
 	h := gh.NewHTTPHandlers(&gh.Client{})
 	body := bytes.NewBufferString(`{"token_id":1,"repo":"o/r","title":"t","head":"feature"}`)
 	req := httptest.NewRequest(http.MethodPost, "/actions/github/pr", body)
+	req = req.WithContext(workflows.WithUserID(req.Context(), 1))
 	rr := httptest.NewRecorder()
 	h.PullRequest().ServeHTTP(rr, req)
 	if rr.Code != http.StatusBadRequest {
 		t.Fatalf("status %d, want 400", rr.Code)
 	}
 }
+
+// stubStates issues "state-<user>" and knows the states it issued.
+type stubStates struct {
+	issued map[string]int64
+}
+
+func (s *stubStates) StartConnection(ctx context.Context, userID int64) (string, error) {
+	state := fmt.Sprintf("state-%d", userID)
+	s.issued[state] = userID
+	return state, nil
+}
+
+func (s *stubStates) FinishConnection(ctx context.Context, state string) (int64, error) {
+	userID, ok := s.issued[state]
+	if !ok {
+		return 0, errors.New("unknown state")
+	}
+	delete(s.issued, state)
+	return userID, nil
+}
+
+func TestLogin_BindsStateToCaller(t *testing.T) {
+	h := gh.NewHTTPHandlers(gh.NewClient(config.OAuth{ClientID: "id", RedirectURI: "http://localhost/cb"}))
+	states := &stubStates{issued: map[string]int64{}}
+	h.States = states
+	req := httptest.NewRequest(http.MethodGet, "/oauth/github/login", nil)
+	rr := httptest.NewRecorder()
+	h.Login().ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous login: status %d, want 401", rr.Code)
+	}
+
+	req = req.WithContext(workflows.WithUserID(req.Context(), 7))
+	rr = httptest.NewRecorder()
+	h.Login().ServeHTTP(rr, req)
+	if rr.Code != http.StatusFound {
+		t.Fatalf("status %d, want 302", rr.Code)
+	}
+	if states.issued["state-7"] != 7 || !strings.Contains(rr.Header().Get("Location"), "state=state-7") {
+		t.Fatalf("state not bound to the caller: %v, %s", states.issued, rr.Header().Get("Location"))
+	}
+	for _, cookie := range rr.Result().Cookies() {
+		if cookie.Name == "oauthuserid" {
+			t.Fatal("user id stored in a cookie")
+		}
+	}
+}
+
+func TestCallback_UnknownState(t *testing.T) {
+	h := gh.NewHTTPHandlers(gh.NewClient(config.OAuth{ClientID: "id", RedirectURI: "http://localhost/cb"}))
+	h.States = &stubStates{issued: map[string]int64{}}
+	req := httptest.NewRequest(http.MethodGet, "/oauth/github/callback?state=forged&code=c", nil)
+	req.AddCookie(&http.Cookie{Name: "oauthstate", Value: "forged"})
+	rr := httptest.NewRecorder()
+	h.Callback().ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status %d, want 400", rr.Code)
+	}
+}
+
+func TestCreateIssue_RequiresUser(t *testing.T) {
+	err := gh.NewClient(config.OAuth{}).CreateIssue(context.Background(), nil, 1, "o", "r", "t", "", nil)
+	if !errors.Is(err, gh.ErrUserRequired) {
+		t.Fatalf("err = %v, want ErrUserRequired", err)
+	}
+}
@@ -1,9 +1,12 @@
 package google
 
 import (
+	"area/src/config"
 	"area/src/integrations/google"
+	"area/src/workflows"
 	"bytes"
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -45,6 +48,7 @@ func TestSendEmail_BadMethod(t *testing.T) {
 func TestSendEmail_MissingFields(t *testing.T) {
 	h := google.NewHTTPHandlers(&google.Client{})
 	req := httptest.NewRequest(http.MethodPost, "/actions/google/email", bytes.NewBufferString(`{"token_id":0}`))
+	req = req.WithContext(workflows.WithUserID(req.Context(), 1))
 	rr := httptest.NewRecorder()
 	h.SendEmail().ServeHTTP(rr, req)
 	if rr.Code != http.StatusBadRequest {
@@ -52,6 +56,16 @@ func TestSendEmail_MissingFields(t *testing.T) {
 	}
 }
 
+func TestSendEmail_RequiresCaller(t *testing.T) {
+	h := google.NewHTTPHandlers(&google.Client{})
+	req := httptest.NewRequest(http.MethodPost, "/actions/google/email", bytes.NewBufferString(`{"token_id":1,"to":"a@b.com","subject":"s"}`))
+	rr := httptest.NewRecorder()
+	h.SendEmail().ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("status %d, want 401", rr.Code)
+	}
+}
+
 func TestCallback_InvalidState(t *testing.T) {
 	h := google.NewHTTPHandlers(&google.Client{})
 	req := httptest.NewRequest(http.MethodGet, "/oauth/google/callback?state=bad", nil)
@@ -61,3 +75,10 @@ func TestCallback_InvalidState(t *testing.T) {
 		t.Fatalf("status %d, want 400", rr.Code)
 	}
 }
+
+func TestSendEmail_RequiresUser(t *testing.T) {
+	err := google.NewClient(config.OAuth{}).SendEmail(context.Background(), nil, 1, "a@b.com", "s", "b")
+	if !errors.Is(err, google.ErrUserRequired) {
+		t.Fatalf("err = %v, want ErrUserRequired", err)
+	}
+}
@@ -8,5 +8,5 @@ import (
 )
 
 func TestStartNasaPoller_NoDeps(t *testing.T) {
-	nasa.StartNasaPoller(context.Background(), nil, nil)
+	nasa.StartNasaPoller(context.Background(), nil, nil, "")
 }
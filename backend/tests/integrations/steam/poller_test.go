@@ -8,5 +8,5 @@ import (
 )
 
 func TestStartSteamPoller_NoDeps(t *testing.T) {
-	steam.StartSteamPoller(context.Background(), nil, nil)
+	steam.StartSteamPoller(context.Background(), nil, nil, "")
 }
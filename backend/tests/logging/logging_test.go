@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"area/src/logging"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew_JSONWithContextAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, "info", "json")
+
+	ctx := logging.WithRequestID(context.Background(), "abc123")
+	ctx = logging.With(ctx, "workflow_id", int64(4), "run_id", int64(7))
+	logger.InfoContext(ctx, "job succeeded", "job_id", 9)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("output is not JSON: %v (%q)", err, buf.String())
+	}
+	for key, want := range map[string]any{"msg": "job succeeded", "request_id": "abc123", "workflow_id": float64(4), "run_id": float64(7), "job_id": float64(9)} {
+		if record[key] != want {
+			t.Fatalf("%s = %v, want %v (record %v)", key, record[key], want, record)
+		}
+	}
+}
+
+func TestNew_LevelAndTextFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, "warn", "text")
+
+	logger.Info("hidden")
+	logger.Warn("shown", "integration", "reddit")
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Fatalf("info record should be filtered at warn level: %q", out)
+	}
+	if !strings.Contains(out, "msg=shown") || !strings.Contains(out, "integration=reddit") {
+		t.Fatalf("unexpected text output: %q", out)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	cases := map[string]slog.Level{"debug": slog.LevelDebug, "WARN": slog.LevelWarn, "error": slog.LevelError, "": slog.LevelInfo, "bogus": slog.LevelInfo}
+	for in, want := range cases {
+		if got := logging.ParseLevel(in); got != want {
+			t.Fatalf("ParseLevel(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	if got := logging.RequestID(context.Background()); got != "" {
+		t.Fatalf("expected empty request id, got %q", got)
+	}
+	ctx := logging.WithRequestID(context.Background(), "r1")
+	if got := logging.RequestID(ctx); got != "r1" {
+		t.Fatalf("RequestID = %q, want r1", got)
+	}
+	if a, b := logging.NewRequestID(), logging.NewRequestID(); a == b || len(a) != 16 {
+		t.Fatalf("unexpected generated ids %q, %q", a, b)
+	}
+}
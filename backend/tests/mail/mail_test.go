@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"area/src/config"
+	"area/src/mail"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer_WritesMessage(t *testing.T) {
+	dir := t.TempDir()
+	mailer, err := mail.New(config.Mail{Driver: config.MailFile, FileDir: dir, From: "KiKonect <no-reply@example.com>"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	err = mailer.Send(context.Background(), mail.Message{To: "a@b.com", Subject: "Héllo", Body: "line 1\nline 2\n"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("files = %v, want one .eml", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(data)
+	for _, want := range []string{"From: KiKonect <no-reply@example.com>\r\n", "To: a@b.com\r\n", "Subject: =?utf-8?q?H=C3=A9llo?=\r\n", "\r\n\r\nline 1\r\nline 2\r\n"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message lacks %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestMailer_RejectsHeaderInjection(t *testing.T) {
+	mailer := &mail.FileMailer{Dir: t.TempDir(), From: "no-reply@example.com"}
+	err := mailer.Send(context.Background(), mail.Message{To: "a@b.com\r\nBcc: victim@example.com", Subject: "hi"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestNew_UnknownDriver(t *testing.T) {
+	if _, err := mail.New(config.Mail{Driver: "pigeon"}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
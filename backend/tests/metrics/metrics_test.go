@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"area/src/metrics"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, reg *metrics.Registry) string {
+	t.Helper()
+	var b strings.Builder
+	if _, err := reg.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	return b.String()
+}
+
+func TestCounterExposition(t *testing.T) {
+	reg := metrics.NewRegistry()
+	c := reg.NewCounter("jobs_total", "Jobs.", "event", "host")
+	c.Inc("claimed", "api:8080")
+	c.Add(2, "failed", `we"ird`)
+	c.Add(-1, "claimed", "api:8080")
+
+	out := render(t, reg)
+	for _, want := range []string{
+		"# HELP jobs_total Jobs.\n",
+		"# TYPE jobs_total counter\n",
+		`jobs_total{event="claimed",host="api:8080"} 1` + "\n",
+		`jobs_total{event="failed",host="we\"ird"} 2` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestHistogramExposition(t *testing.T) {
+	reg := metrics.NewRegistry()
+	h := reg.NewHistogram("cycle_seconds", "Cycles.", []float64{0.1, 1}, "integration")
+	h.Observe(0.05, "reddit")
+	h.Observe(0.5, "reddit")
+	h.Observe(3, "reddit")
+
+	out := render(t, reg)
+	for _, want := range []string{
+		"# TYPE cycle_seconds histogram\n",
+		`cycle_seconds_bucket{integration="reddit",le="0.1"} 1` + "\n",
+		`cycle_seconds_bucket{integration="reddit",le="1"} 2` + "\n",
+		`cycle_seconds_bucket{integration="reddit",le="+Inf"} 3` + "\n",
+		`cycle_seconds_sum{integration="reddit"} 3.55` + "\n",
+		`cycle_seconds_count{integration="reddit"} 3` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestGaugeFunc(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.NewGaugeFunc("queue_depth", "Queue.", func() (float64, error) { return 7, nil })
+	reg.NewGaugeFunc("broken", "Broken.", func() (float64, error) { return 0, errors.New("db down") })
+
+	out := render(t, reg)
+	if !strings.Contains(out, "queue_depth 7\n") {
+		t.Fatalf("missing gauge in:\n%s", out)
+	}
+	if strings.Contains(out, "broken") {
+		t.Fatalf("failed gauge should be omitted:\n%s", out)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.NewCounter("hits_total", "Hits.").Inc()
+
+	rr := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("content type %q", ct)
+	}
+	if !strings.Contains(rr.Body.String(), "hits_total 1\n") {
+		t.Fatalf("unexpected body:\n%s", rr.Body.String())
+	}
+}
+
+func TestInstrumentTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: metrics.InstrumentTransport(http.DefaultTransport)}
+	host := metrics.HostOf(srv.URL)
+	before := metrics.OutboundRequestsTotal.Value(host, "429")
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := metrics.OutboundRequestsTotal.Value(host, "429"); got != before+1 {
+		t.Fatalf("outbound 429 count = %v, want %v", got, before+1)
+	}
+}
@@ -0,0 +1,111 @@
+package security
+
+import (
+	"area/src/security"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const (
+	oldKey = "0123456789abcdef0123456789abcdef"
+	newKey = "fedcba9876543210fedcba9876543210"
+)
+
+func configure(t *testing.T, id, key, previous string) {
+	t.Helper()
+	if err := security.Configure(id, key, previous); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	t.Cleanup(func() { security.Configure("", "", "") })
+}
+
+// legacyCiphertext encrypts plain the way values were stored before keys had IDs.
+func legacyCiphertext(t *testing.T, key, plain string) string {
+	t.Helper()
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	return "enc:" + base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plain), nil))
+}
+
+func TestEncryptString_TagsCurrentKeyID(t *testing.T) {
+	configure(t, "", oldKey, "")
+	encrypted, err := security.EncryptString("hunter2")
+	if err != nil {
+		t.Fatalf("EncryptString: %v", err)
+	}
+	if !strings.HasPrefix(encrypted, "enc:v1:") || security.KeyID(encrypted) != "v1" {
+		t.Fatalf("encrypted = %q, want the default key ID v1", encrypted)
+	}
+	if plain, err := security.DecryptString(encrypted); err != nil || plain != "hunter2" {
+		t.Fatalf("DecryptString = %q, %v", plain, err)
+	}
+	if plain, err := security.DecryptString("not encrypted"); err != nil || plain != "not encrypted" {
+		t.Fatalf("plain text = %q, %v", plain, err)
+	}
+}
+
+func TestDecryptString_AfterRotation(t *testing.T) {
+	configure(t, "", oldKey, "")
+	tagged, err := security.EncryptString("tagged")
+	if err != nil {
+		t.Fatalf("EncryptString: %v", err)
+	}
+	legacy := legacyCiphertext(t, oldKey, "legacy")
+	if security.KeyID(legacy) != "" {
+		t.Fatalf("legacy value has key ID %q", security.KeyID(legacy))
+	}
+
+	configure(t, "v2", newKey, "v1:"+oldKey)
+	for value, want := range map[string]string{tagged: "tagged", legacy: "legacy"} {
+		if plain, err := security.DecryptString(value); err != nil || plain != want {
+			t.Fatalf("DecryptString(%q) = %q, %v; want %q", value, plain, err, want)
+		}
+	}
+	rotated, changed, err := security.Reencrypt(tagged)
+	if err != nil || !changed || security.KeyID(rotated) != "v2" {
+		t.Fatalf("Reencrypt = %q, %v, %v", rotated, changed, err)
+	}
+	if again, changed, err := security.Reencrypt(rotated); err != nil || changed || again != rotated {
+		t.Fatalf("Reencrypt of a current value = %q, %v, %v", again, changed, err)
+	}
+
+	configure(t, "v2", newKey, "")
+	if _, err := security.DecryptString(tagged); !errors.Is(err, security.ErrUnknownKey) {
+		t.Fatalf("value of a dropped key: %v, want ErrUnknownKey", err)
+	}
+	if _, err := security.DecryptString(legacy); !errors.Is(err, security.ErrUnknownKey) {
+		t.Fatalf("legacy value of a dropped key: %v, want ErrUnknownKey", err)
+	}
+	if plain, err := security.DecryptString(rotated); err != nil || plain != "tagged" {
+		t.Fatalf("rotated value = %q, %v", plain, err)
+	}
+}
+
+func TestConfigure_RejectsBadKeyring(t *testing.T) {
+	t.Cleanup(func() { security.Configure("", "", "") })
+	for _, tc := range []struct{ id, key, previous string }{
+		{"v 2", newKey, ""},
+		{"v2", "short", ""},
+		{"v2", newKey, oldKey},
+		{"v2", newKey, "v2:" + oldKey},
+		{"v2", newKey, "v1:short"},
+		{"", "", "v1:" + oldKey},
+	} {
+		if err := security.Configure(tc.id, tc.key, tc.previous); err == nil {
+			t.Errorf("Configure(%q, %q, %q) accepted", tc.id, tc.key, tc.previous)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package workflows
+
+import (
+	"area/src/workflows"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestStoreCancelRun(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(`^UPDATE "jobs" SET .*"status"=\$.* WHERE \(run_id = \$\d+ AND status IN \(\$\d+,\$\d+\)\)`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`^UPDATE "workflow_runs" SET .*"status"=\$.* WHERE \(id = \$\d+ AND status IN \(\$\d+,\$\d+\)\)`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := store.CancelRun(context.Background(), 7, now); err != nil {
+		t.Fatalf("CancelRun error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestStoreCancelPendingRuns(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`^UPDATE "jobs" SET .* WHERE \(workflow_id = \$\d+ AND status = \$\d+\)`).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`^UPDATE "workflow_runs" SET .* WHERE \(workflow_id = \$\d+ AND status = \$\d+\)`).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	cancelled, err := store.CancelPendingRuns(context.Background(), 4, time.Now())
+	if err != nil {
+		t.Fatalf("CancelPendingRuns error: %v", err)
+	}
+	if cancelled != 3 {
+		t.Fatalf("cancelled = %d, want 3", cancelled)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+type recordingCanceller struct {
+	cancelled []int64
+}
+
+func (c *recordingCanceller) CancelRun(runID int64) bool {
+	c.cancelled = append(c.cancelled, runID)
+	return true
+}
+
+func TestServiceCancelRun(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	ctx := workflows.WithUserID(context.Background(), 99)
+	store := workflows.NewStore(gormDB)
+
+	mock.ExpectQuery(`^SELECT "workflow_runs"\..* FROM "workflow_runs" JOIN workflows ON workflows.id = workflow_runs.workflow_id WHERE workflow_runs\.id = \$1 AND \(\(workflows\.workspace_id IS NULL AND workflows\.user_id = \$2\) OR workflows\.workspace_id IN \(SELECT "workspace_id" FROM "workspace_members" WHERE user_id = \$3\)\)`).
+		WithArgs(int64(7), int64(99), int64(99), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_id", "status"}).AddRow(7, 4, "running"))
+	mock.ExpectQuery(`^SELECT \* FROM "workflows" WHERE id = \$1 AND `).
+		WithArgs(int64(4), int64(99), int64(99), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "trigger_type"}).AddRow(4, 99, "manual"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`^UPDATE "jobs"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`^UPDATE "workflow_runs"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	canceller := &recordingCanceller{}
+	svc := workflows.NewService(store, workflows.NewTriggerer(store))
+	svc.Canceller = canceller
+
+	run, err := svc.CancelRun(ctx, 7, time.Now())
+	if err != nil {
+		t.Fatalf("CancelRun error: %v", err)
+	}
+	if run.Status != workflows.RunStatusCancelled || run.EndedAt == nil {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+	if len(canceller.cancelled) != 1 || canceller.cancelled[0] != 7 {
+		t.Fatalf("in-flight send not cancelled: %v", canceller.cancelled)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestServiceCancelRun_Finished(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	ctx := workflows.WithUserID(context.Background(), 99)
+	store := workflows.NewStore(gormDB)
+
+	mock.ExpectQuery(`FROM "workflow_runs"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_id", "status"}).AddRow(7, 4, "succeeded"))
+
+	svc := workflows.NewService(store, workflows.NewTriggerer(store))
+	if _, err := svc.CancelRun(ctx, 7, time.Now()); !errors.Is(err, workflows.ErrRunFinished) {
+		t.Fatalf("expected ErrRunFinished, got %v", err)
+	}
+}
+
+func TestServiceCancelRun_NotFound(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	ctx := workflows.WithUserID(context.Background(), 99)
+	store := workflows.NewStore(gormDB)
+
+	mock.ExpectQuery(`FROM "workflow_runs"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	svc := workflows.NewService(store, workflows.NewTriggerer(store))
+	if _, err := svc.CancelRun(ctx, 7, time.Now()); !errors.Is(err, workflows.ErrRunNotFound) {
+		t.Fatalf("expected ErrRunNotFound, got %v", err)
+	}
+}
+
+func TestExecutorCancelRun_Unknown(t *testing.T) {
+	exec := workflows.NewExecutor(nil, nil, time.Second)
+	if exec.CancelRun(42) {
+		t.Fatal("expected no in-flight send for unknown run")
+	}
+}
+
+func TestStoreUpdateRun_KeepsCancelledRun(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`^UPDATE "workflow_runs" SET .* WHERE \(id = \$\d+ AND status <> \$\d+\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`^SELECT "status" FROM "workflow_runs" WHERE id = \$1`).
+		WithArgs(uint(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(workflows.RunStatusCancelled))
+
+	err := store.UpdateRun(context.Background(), 7, workflows.RunUpdate{Status: workflows.RunStatusSucceeded})
+	if !errors.Is(err, workflows.ErrRunCancelled) {
+		t.Fatalf("expected ErrRunCancelled, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestStoreUpdateRun_MissingRunIsNotACancel(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`^UPDATE "workflow_runs" SET .* WHERE \(id = \$\d+ AND status <> \$\d+\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`^SELECT "status" FROM "workflow_runs" WHERE id = \$1`).
+		WithArgs(uint(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}))
+
+	err := store.UpdateRun(context.Background(), 7, workflows.RunUpdate{Status: workflows.RunStatusSucceeded})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestExecutor_NoticesCancelThroughTheStore(t *testing.T) {
+	store := workflows.NewMemoryStore()
+	svc := workflows.NewService(store, workflows.NewTriggerer(store))
+	ctx := workflows.WithUserID(context.Background(), 42)
+	wf, err := svc.CreateWorkflow(ctx, "slow", "manual", "http://example.com/hook", nil)
+	if err != nil {
+		t.Fatalf("CreateWorkflow: %v", err)
+	}
+	run, err := svc.Trigger(ctx, wf.ID, nil)
+	if err != nil {
+		t.Fatalf("Trigger: %v", err)
+	}
+
+	sender := &blockingSender{started: make(chan struct{})}
+	exec := workflows.NewExecutor(store, sender, 10*time.Millisecond)
+	loopCtx, stop := context.WithCancel(context.Background())
+	go exec.RunLoop(loopCtx)
+	select {
+	case <-sender.started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("job was not sent")
+	}
+
+	// Cancelled by a replica whose executor does not hold the send: only the store knows.
+	if err := store.CancelRun(ctx, run.ID, time.Now()); err != nil {
+		t.Fatalf("CancelRun: %v", err)
+	}
+	stop()
+	if err := exec.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	runs, err := svc.ListRuns(ctx, wf.ID, 10)
+	if err != nil || len(runs) != 1 || len(runs[0].Jobs) != 1 {
+		t.Fatalf("unexpected runs %+v, %v", runs, err)
+	}
+	if runs[0].Status != workflows.RunStatusCancelled || runs[0].Jobs[0].Status != workflows.JobStatusCancelled {
+		t.Fatalf("cancellation overwritten: run %q, job %q", runs[0].Status, runs[0].Jobs[0].Status)
+	}
+	if err := store.UpdateRun(ctx, run.ID, workflows.RunUpdate{Status: workflows.RunStatusSucceeded}); !errors.Is(err, workflows.ErrRunCancelled) {
+		t.Fatalf("expected ErrRunCancelled, got %v", err)
+	}
+}
@@ -0,0 +1,276 @@
+package workflows
+
+import (
+	"area/src/workflows"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestParseCron_NextWeekday(t *testing.T) {
+	sched, err := workflows.ParseCron("0 9 * * 1-5")
+	if err != nil {
+		t.Fatalf("ParseCron: %v", err)
+	}
+	friday := time.Date(2026, time.January, 16, 10, 0, 0, 0, time.UTC)
+	got := sched.Next(friday)
+	want := time.Date(2026, time.January, 19, 9, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Fatalf("Next = %v, want %v", got, want)
+	}
+}
+
+func TestParseCron_Step(t *testing.T) {
+	sched, err := workflows.ParseCron("*/15 * * * *")
+	if err != nil {
+		t.Fatalf("ParseCron: %v", err)
+	}
+	from := time.Date(2026, time.March, 1, 12, 7, 30, 0, time.UTC)
+	if got := sched.Next(from); got.Minute() != 15 || got.Hour() != 12 {
+		t.Fatalf("Next = %v, want 12:15", got)
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "61 * * * *", "a * * * *", "*/0 * * * *"} {
+		if _, err := workflows.ParseCron(expr); err == nil {
+			t.Fatalf("expected error for %q", expr)
+		}
+	}
+}
+
+func TestBuildDigestPayload(t *testing.T) {
+	template := map[string]any{"channel_id": "C1", "bot_token": "enc:abc"}
+	items := []json.RawMessage{
+		[]byte(`{"title":"first","content":"New post: first","channel_id":"C1","bot_token":"enc:abc"}`),
+		[]byte(`{"title":"second","content":"New post: second","channel_id":"C1","bot_token":"enc:abc"}`),
+	}
+
+	payload := workflows.BuildDigestPayload(&workflows.DigestConfig{EveryMinutes: 30}, template, items)
+
+	if payload["channel_id"] != "C1" || payload["count"] != 2 {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+	list, ok := payload["items"].([]any)
+	if !ok || len(list) != 2 {
+		t.Fatalf("expected 2 items, got %#v", payload["items"])
+	}
+	first := list[0].(map[string]any)
+	if _, ok := first["bot_token"]; ok {
+		t.Fatalf("template keys should be stripped from items: %#v", first)
+	}
+	content := payload["content"].(string)
+	if !strings.HasPrefix(content, "2 new events") || !strings.Contains(content, "- New post: second") {
+		t.Fatalf("unexpected content %q", content)
+	}
+}
+
+func TestDigestConfig_DueAt(t *testing.T) {
+	oldest := time.Date(2026, time.May, 4, 8, 0, 0, 0, time.UTC)
+	every := &workflows.DigestConfig{EveryMinutes: 30}
+	if due, _ := every.DueAt(oldest); !due.Equal(oldest.Add(30 * time.Minute)) {
+		t.Fatalf("every DueAt = %v", due)
+	}
+	cron := &workflows.DigestConfig{Cron: "0 9 * * *"}
+	if due, _ := cron.DueAt(oldest); due.Hour() != 9 || due.Day() != 4 {
+		t.Fatalf("cron DueAt = %v", due)
+	}
+}
+
+func TestServiceCreateWorkflow_DigestUnsupportedTrigger(t *testing.T) {
+	gormDB, _, cleanup := setupMockDB(t)
+	defer cleanup()
+	ctx := workflows.WithUserID(context.Background(), 99)
+	store := workflows.NewStore(gormDB)
+	svc := workflows.NewService(store, workflows.NewTriggerer(store))
+
+	_, err := svc.CreateWorkflow(ctx, "wf", "manual", "https://example.com", []byte(`{"digest":{"every_minutes":10}}`))
+	if err == nil || !strings.Contains(err.Error(), "digest") {
+		t.Fatalf("expected digest error, got %v", err)
+	}
+}
+
+func TestServiceCreateWorkflow_DigestRequiresSchedule(t *testing.T) {
+	gormDB, _, cleanup := setupMockDB(t)
+	defer cleanup()
+	ctx := workflows.WithUserID(context.Background(), 99)
+	store := workflows.NewStore(gormDB)
+	svc := workflows.NewService(store, workflows.NewTriggerer(store))
+
+	_, err := svc.CreateWorkflow(ctx, "wf", "reddit_new_post", "https://example.com", []byte(`{"subreddit":"golang","digest":{}}`))
+	if err == nil || !strings.Contains(err.Error(), "every_minutes or cron") {
+		t.Fatalf("expected schedule error, got %v", err)
+	}
+}
+
+func TestServiceTrigger_DigestBuffersEvent(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	ctx := workflows.WithUserID(context.Background(), 99)
+	store := workflows.NewStore(gormDB)
+
+	mock.ExpectQuery(`^SELECT \* FROM "workflows" WHERE "workflows"\."id" = \$1 AND "workflows"\."deleted_at" IS NULL ORDER BY "workflows"\."id" LIMIT \$[0-9]+$`).
+		WithArgs(uint(4), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "trigger_type", "trigger_config", "action_url", "enabled"}).
+			AddRow(4, 99, "wf", "reddit_new_post", []byte(`{"subreddit":"golang","digest":{"every_minutes":30}}`), "https://example.com", true))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`^INSERT INTO "digest_items" \("created_at","updated_at","deleted_at","workflow_id","payload"\)`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, uint(4), []byte(`{"title":"hi"}`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	svc := workflows.NewService(store, workflows.NewTriggerer(store))
+	run, err := svc.Trigger(ctx, 4, map[string]any{"title": "hi"})
+	if err != nil {
+		t.Fatalf("Trigger error: %v", err)
+	}
+	if run != nil {
+		t.Fatalf("expected no run for buffered event, got %+v", run)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDigestFlusher_FlushDue(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	now := time.Now()
+	mock.ExpectQuery(`^SELECT workflow_id, COUNT\(\*\) AS count, MIN\(created_at\) AS oldest_at FROM "digest_items"`).
+		WillReturnRows(sqlmock.NewRows([]string{"workflow_id", "count", "oldest_at"}).AddRow(uint(4), 2, now.Add(-time.Hour)))
+	mock.ExpectQuery(`^SELECT \* FROM "workflows" WHERE "workflows"\."id" = \$1`).
+		WithArgs(uint(4), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "trigger_type", "trigger_config", "action_url", "enabled"}).
+			AddRow(4, 99, "wf", "reddit_new_post", []byte(`{"digest":{"every_minutes":30}}`), "https://example.com", true))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`^SELECT \* FROM "digest_items" WHERE workflow_id = \$1 .* FOR UPDATE SKIP LOCKED$`).
+		WithArgs(uint(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_id", "payload"}).
+			AddRow(1, 4, []byte(`{"content":"a"}`)).
+			AddRow(2, 4, []byte(`{"content":"b"}`)))
+	mock.ExpectExec(`^DELETE FROM "digest_items" WHERE id IN \(\$1,\$2\)$`).
+		WithArgs(uint(1), uint(2)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(`^INSERT INTO "workflow_runs"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery(`^INSERT INTO "jobs"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectCommit()
+
+	flusher := workflows.NewDigestFlusher(store, workflows.NewTriggerer(store), time.Minute)
+	flushed, err := flusher.FlushDue(context.Background(), now)
+	if err != nil {
+		t.Fatalf("FlushDue: %v", err)
+	}
+	if flushed != 1 {
+		t.Fatalf("flushed = %d, want 1", flushed)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDigestFlusher_KeepsItemsWhenEnqueueFails(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	now := time.Now()
+	mock.ExpectQuery(`FROM "digest_items"`).
+		WillReturnRows(sqlmock.NewRows([]string{"workflow_id", "count", "oldest_at"}).AddRow(uint(4), 1, now.Add(-time.Hour)))
+	mock.ExpectQuery(`^SELECT \* FROM "workflows"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "trigger_type", "trigger_config", "action_url", "enabled"}).
+			AddRow(4, 99, "wf", "reddit_new_post", []byte(`{"digest":{"every_minutes":30}}`), "https://example.com", true))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`^SELECT \* FROM "digest_items" WHERE workflow_id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_id", "payload"}).AddRow(1, 4, []byte(`{"content":"a"}`)))
+	mock.ExpectExec(`^DELETE FROM "digest_items"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`^INSERT INTO "workflow_runs"`).WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	flusher := workflows.NewDigestFlusher(store, workflows.NewTriggerer(store), time.Minute)
+	flushed, err := flusher.FlushDue(context.Background(), now)
+	if err != nil || flushed != 0 {
+		t.Fatalf("FlushDue = %d, %v; want 0, nil", flushed, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+// lookupFailingStore is a MemoryStore whose workflow lookups fail.
+type lookupFailingStore struct {
+	*workflows.MemoryStore
+}
+
+func (s lookupFailingStore) GetWorkflow(context.Context, int64) (*workflows.Workflow, error) {
+	return nil, errors.New("connection reset")
+}
+
+func TestDigestFlusher_KeepsItemsOnLookupError(t *testing.T) {
+	store := lookupFailingStore{workflows.NewMemoryStore()}
+	ctx := context.Background()
+	if err := store.BufferDigestItem(ctx, 4, json.RawMessage(`{"content":"a"}`)); err != nil {
+		t.Fatalf("BufferDigestItem: %v", err)
+	}
+
+	flusher := workflows.NewDigestFlusher(store, workflows.NewTriggerer(store), time.Minute)
+	if flushed, err := flusher.FlushDue(ctx, time.Now().Add(time.Hour)); err != nil || flushed != 0 {
+		t.Fatalf("FlushDue = %d, %v; want 0, nil", flushed, err)
+	}
+	pending, err := store.ListPendingDigests(ctx)
+	if err != nil || len(pending) != 1 || pending[0].Count != 1 {
+		t.Fatalf("pending digests = %+v, %v; want the item kept", pending, err)
+	}
+}
+
+func TestDigestFlusher_SkipsDisabledWorkflows(t *testing.T) {
+	store := workflows.NewMemoryStore()
+	svc := workflows.NewService(store, workflows.NewTriggerer(store))
+	ctx := workflows.WithUserID(context.Background(), 42)
+	wf, err := svc.CreateWorkflow(ctx, "digest", "reddit_new_post", "http://example.com/hook", json.RawMessage(`{"subreddit":"golang","digest":{"every_minutes":30}}`))
+	if err != nil {
+		t.Fatalf("CreateWorkflow: %v", err)
+	}
+	if err := store.BufferDigestItem(ctx, wf.ID, json.RawMessage(`{"content":"a"}`)); err != nil {
+		t.Fatalf("BufferDigestItem: %v", err)
+	}
+
+	flusher := workflows.NewDigestFlusher(store, workflows.NewTriggerer(store), time.Minute)
+	later := time.Now().Add(time.Hour)
+	if flushed, err := flusher.FlushDue(ctx, later); err != nil || flushed != 0 {
+		t.Fatalf("FlushDue of a disabled workflow = %d, %v; want 0, nil", flushed, err)
+	}
+	if err := svc.SetEnabled(ctx, wf.ID, true, time.Now()); err != nil {
+		t.Fatalf("SetEnabled: %v", err)
+	}
+	if flushed, err := flusher.FlushDue(ctx, later); err != nil || flushed != 1 {
+		t.Fatalf("FlushDue once enabled = %d, %v; want 1, nil", flushed, err)
+	}
+}
+
+func TestDigestFlusher_NotDueYet(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	now := time.Now()
+	mock.ExpectQuery(`FROM "digest_items"`).
+		WillReturnRows(sqlmock.NewRows([]string{"workflow_id", "count", "oldest_at"}).AddRow(uint(4), 1, now.Add(-time.Minute)))
+	mock.ExpectQuery(`^SELECT \* FROM "workflows"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "trigger_type", "trigger_config", "action_url", "enabled"}).
+			AddRow(4, 99, "wf", "reddit_new_post", []byte(`{"digest":{"every_minutes":30}}`), "https://example.com", true))
+
+	flusher := workflows.NewDigestFlusher(store, workflows.NewTriggerer(store), time.Minute)
+	flushed, err := flusher.FlushDue(context.Background(), now)
+	if err != nil || flushed != 0 {
+		t.Fatalf("FlushDue = %d, %v; want 0, nil", flushed, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package workflows
+
+import (
+	"area/src/workflows"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestEventBus_ScopedToUser(t *testing.T) {
+	bus := workflows.NewEventBus()
+	mine, cancelMine := bus.Subscribe(1)
+	defer cancelMine()
+	other, cancelOther := bus.Subscribe(2)
+	defer cancelOther()
+
+	bus.Publish(workflows.Event{Type: workflows.EventRunStarted, UserID: 1, RunID: 5})
+
+	select {
+	case ev := <-mine:
+		if ev.RunID != 5 || ev.At.IsZero() {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+	default:
+		t.Fatal("subscriber did not receive its event")
+	}
+	select {
+	case ev := <-other:
+		t.Fatalf("other user received %+v", ev)
+	default:
+	}
+}
+
+func TestEventBus_Unsubscribe(t *testing.T) {
+	bus := workflows.NewEventBus()
+	events, cancel := bus.Subscribe(1)
+	if !bus.Active() {
+		t.Fatal("bus should be active with a subscriber")
+	}
+	cancel()
+	cancel()
+	if bus.Active() {
+		t.Fatal("bus should be inactive after unsubscribe")
+	}
+	if _, ok := <-events; ok {
+		t.Fatal("channel should be closed after unsubscribe")
+	}
+	bus.Publish(workflows.Event{Type: workflows.EventRunStarted, UserID: 1})
+}
+
+func TestEventBus_SlowSubscriberDoesNotBlock(t *testing.T) {
+	bus := workflows.NewEventBus()
+	_, cancel := bus.Subscribe(1)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			bus.Publish(workflows.Event{Type: workflows.EventRunCreated, UserID: 1})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a full subscriber")
+	}
+}
+
+func TestStoreUpdateRun_PublishesEvent(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+	store.Events = workflows.NewEventBus()
+	events, cancel := store.Events.Subscribe(99)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`^UPDATE "workflow_runs" SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`^SELECT workflow_runs.workflow_id, workflows.user_id, workflows.workspace_id FROM "workflow_runs" JOIN workflows ON workflows.id = workflow_runs.workflow_id WHERE workflow_runs.id = \$1`).
+		WithArgs(uint(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"workflow_id", "user_id", "workspace_id"}).AddRow(4, 99, nil))
+
+	msg := "boom"
+	if err := store.UpdateRun(context.Background(), 7, workflows.RunUpdate{Status: workflows.RunStatusFailed, Error: &msg}); err != nil {
+		t.Fatalf("UpdateRun: %v", err)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.Type != workflows.EventRunFailed || ev.WorkflowID != 4 || ev.RunID != 7 || ev.Error != "boom" {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+	default:
+		t.Fatal("no event published")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestStoreSetEnabled_PublishesEvent(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+	store.Events = workflows.NewEventBus()
+	events, cancel := store.Events.Subscribe(99)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`^UPDATE "workflows" SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`^SELECT user_id, workspace_id FROM "workflows" WHERE id = \$1`).
+		WithArgs(uint(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "workspace_id"}).AddRow(99, nil))
+
+	if err := store.SetEnabledForUser(context.Background(), 2, 99, false, time.Now()); err != nil {
+		t.Fatalf("SetEnabledForUser: %v", err)
+	}
+	select {
+	case ev := <-events:
+		if ev.Type != workflows.EventWorkflowDisabled || ev.WorkflowID != 2 {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+	default:
+		t.Fatal("no event published")
+	}
+}
+
+func TestStoreUpdateRun_PublishesEventToWorkspaceMembers(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+	store.Events = workflows.NewEventBus()
+	member, cancelMember := store.Events.Subscribe(5)
+	defer cancelMember()
+	creator, cancelCreator := store.Events.Subscribe(99)
+	defer cancelCreator()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`^UPDATE "workflow_runs" SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`^SELECT workflow_runs.workflow_id, workflows.user_id, workflows.workspace_id FROM "workflow_runs"`).
+		WithArgs(uint(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"workflow_id", "user_id", "workspace_id"}).AddRow(4, 99, 3))
+	mock.ExpectQuery(`^SELECT "user_id" FROM "workspace_members" WHERE workspace_id = \$1`).
+		WithArgs(uint(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(5))
+
+	if err := store.UpdateRun(context.Background(), 7, workflows.RunUpdate{Status: workflows.RunStatusSucceeded}); err != nil {
+		t.Fatalf("UpdateRun: %v", err)
+	}
+
+	select {
+	case ev := <-member:
+		if ev.Type != workflows.EventRunSucceeded || ev.RunID != 7 {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+	default:
+		t.Fatal("no event published to the member")
+	}
+	select {
+	case ev := <-creator:
+		t.Fatalf("creator who left the workspace got %+v", ev)
+	default:
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestServiceSubscribeEvents_Unavailable(t *testing.T) {
+	store, _, cleanup := setupMockStore(t)
+	defer cleanup()
+	svc := workflows.NewService(store, workflows.NewTriggerer(store))
+
+	ctx := workflows.WithUserID(context.Background(), 99)
+	if _, _, err := svc.SubscribeEvents(ctx); !errors.Is(err, workflows.ErrEventsUnavailable) {
+		t.Fatalf("expected ErrEventsUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package workflows
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+const acquireLeaseSQL = `^INSERT INTO "leases" \("name","holder","expires_at"\) VALUES \(\$1,\$2,\$3\) ON CONFLICT \("name"\) DO UPDATE SET "holder"="excluded"."holder","expires_at"="excluded"."expires_at" WHERE leases.holder = \$4 OR leases.expires_at < \$5`
+
+func TestStoreAcquireLease(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(acquireLeaseSQL).
+		WithArgs("poller:reddit", "replica-a", now.Add(time.Minute), "replica-a", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ok, err := store.AcquireLease(context.Background(), "poller:reddit", "replica-a", time.Minute, now)
+	if err != nil {
+		t.Fatalf("AcquireLease error: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected lease to be acquired")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestStoreAcquireLease_HeldElsewhere(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(acquireLeaseSQL).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	ok, err := store.AcquireLease(context.Background(), "poller:reddit", "replica-b", time.Minute, time.Now())
+	if err != nil {
+		t.Fatalf("AcquireLease error: %v", err)
+	}
+	if ok {
+		t.Fatal("lease held by another replica should not be acquired")
+	}
+}
+
+func TestStoreReleaseLease(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`^DELETE FROM "leases" WHERE name = \$1 AND holder = \$2`).
+		WithArgs("poller:reddit", "replica-a").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := store.ReleaseLease(context.Background(), "poller:reddit", "replica-a"); err != nil {
+		t.Fatalf("ReleaseLease error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPollerStateLead_LoadsStateOnElection(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(acquireLeaseSQL).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`^SELECT \* FROM "poller_states" WHERE name = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "state", "updated_at"}).
+			AddRow("reddit", []byte(`{"last_seen":{"4":"abc"}}`), time.Now()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lastSeen := make(map[int64]string)
+	state := store.PollerState("reddit", map[string]any{"last_seen": &lastSeen})
+	if !state.Lead(ctx) {
+		t.Fatal("expected to lead")
+	}
+	if lastSeen[4] != "abc" {
+		t.Fatalf("state not loaded on election: %v", lastSeen)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPollerStateLead_Follower(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(acquireLeaseSQL).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lastSeen := make(map[int64]string)
+	state := store.PollerState("reddit", map[string]any{"last_seen": &lastSeen})
+	if state.Lead(ctx) {
+		t.Fatal("follower should not lead")
+	}
+	// A follower neither saves state nor releases the lease on exit.
+	cancel()
+	state.Stop(ctx)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+package workflows
+
+import (
+	"area/src/workflows"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// recordingSender delivers every payload sent to it on a channel.
+type recordingSender struct {
+	sent chan []byte
+}
+
+func (s *recordingSender) Send(ctx context.Context, url string, payload []byte) (*workflows.SendResult, error) {
+	s.sent <- payload
+	return &workflows.SendResult{StatusCode: 200, Body: []byte("ok")}, nil
+}
+
+func TestMemoryStore_TriggerToSend(t *testing.T) {
+	store := workflows.NewMemoryStore()
+	store.Events = workflows.NewEventBus()
+	svc := workflows.NewService(store, workflows.NewTriggerer(store))
+	ctx := workflows.WithUserID(context.Background(), 42)
+
+	events, unsubscribe, err := svc.SubscribeEvents(ctx)
+	if err != nil {
+		t.Fatalf("SubscribeEvents: %v", err)
+	}
+	defer unsubscribe()
+
+	wf, err := svc.CreateWorkflow(ctx, "demo", "manual", "http://example.com/hook", nil)
+	if err != nil {
+		t.Fatalf("CreateWorkflow: %v", err)
+	}
+	run, err := svc.Trigger(ctx, wf.ID, map[string]any{"hello": "world"})
+	if err != nil {
+		t.Fatalf("Trigger: %v", err)
+	}
+
+	sender := &recordingSender{sent: make(chan []byte, 1)}
+	exec := workflows.NewExecutor(store, sender, 10*time.Millisecond)
+	loopCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go exec.RunLoop(loopCtx)
+
+	select {
+	case payload := <-sender.sent:
+		var got map[string]any
+		if err := json.Unmarshal(payload, &got); err != nil || got["hello"] != "world" {
+			t.Fatalf("unexpected payload %s", payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("job was not sent")
+	}
+
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type != workflows.EventRunSucceeded {
+				continue
+			}
+			if ev.RunID != run.ID {
+				t.Fatalf("event for run %d, want %d", ev.RunID, run.ID)
+			}
+			runs, err := svc.ListRuns(ctx, wf.ID, 10)
+			if err != nil || len(runs) != 1 || len(runs[0].Jobs) != 1 {
+				t.Fatalf("unexpected runs %+v, %v", runs, err)
+			}
+			if job := runs[0].Jobs[0]; job.Status != workflows.JobStatusSucceeded || job.ResponseStatus != 200 {
+				t.Fatalf("unexpected job %+v", job)
+			}
+			return
+		case <-deadline:
+			t.Fatal("run did not succeed")
+		}
+	}
+}
+
+func TestMemoryStore_OwnershipAndTokens(t *testing.T) {
+	store := workflows.NewMemoryStore()
+	ctx := context.Background()
+
+	wf, err := store.CreateWorkflow(ctx, 1, 0, "hook", "webhook", "http://example.com", json.RawMessage(`{"token":"abc"}`))
+	if err != nil {
+		t.Fatalf("CreateWorkflow: %v", err)
+	}
+	if _, err := store.GetWorkflowForUser(ctx, wf.ID, 2); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows for another user, got %v", err)
+	}
+	if _, err := store.FindWorkflowByToken(ctx, "abc"); err == nil {
+		t.Fatal("disabled webhook should not be found")
+	}
+	if err := store.SetEnabledForUser(ctx, wf.ID, 1, true, time.Now()); err != nil {
+		t.Fatalf("SetEnabledForUser: %v", err)
+	}
+	found, err := store.FindWorkflowByToken(ctx, "abc")
+	if err != nil || found.ID != wf.ID {
+		t.Fatalf("FindWorkflowByToken = %v, %v", found, err)
+	}
+	if err := store.DeleteWorkflowForUser(ctx, wf.ID, 1); err != nil {
+		t.Fatalf("DeleteWorkflowForUser: %v", err)
+	}
+	if _, err := store.GetWorkflow(ctx, wf.ID); err == nil {
+		t.Fatal("deleted workflow still found")
+	}
+}
+
+// staticRoles gives users fixed workspace roles.
+type staticRoles map[int64]map[int64]string
+
+func (r staticRoles) WorkspaceRoles(_ context.Context, userID int64) (map[int64]string, error) {
+	return r[userID], nil
+}
+
+// recordingAuditor keeps the actions of the workflow changes it is given.
+type recordingAuditor struct {
+	actions []string
+}
+
+func (a *recordingAuditor) RecordChange(_ context.Context, _, _ int64, action, _, _ string, _, _ any) error {
+	a.actions = append(a.actions, action)
+	return nil
+}
+
+func TestMemoryStore_WorkspaceRoles(t *testing.T) {
+	store := workflows.NewMemoryStore()
+	store.Workspaces = staticRoles{1: {7: "owner"}, 2: {7: "viewer"}}
+	auditor := &recordingAuditor{}
+	svc := workflows.NewService(store, workflows.NewTriggerer(store))
+	svc.Audit = auditor
+	owner := workflows.WithUserID(context.Background(), 1)
+	viewer := workflows.WithUserID(context.Background(), 2)
+	outsider := workflows.WithUserID(context.Background(), 3)
+
+	if _, err := svc.CreateWorkspaceWorkflow(viewer, 7, "shared", "manual", "http://example.com", nil); !errors.Is(err, workflows.ErrWorkflowReadOnly) {
+		t.Fatalf("viewer create: %v, want ErrWorkflowReadOnly", err)
+	}
+	if _, err := svc.CreateWorkspaceWorkflow(outsider, 7, "shared", "manual", "http://example.com", nil); !errors.Is(err, workflows.ErrWorkspaceNotFound) {
+		t.Fatalf("outsider create: %v, want ErrWorkspaceNotFound", err)
+	}
+	wf, err := svc.CreateWorkspaceWorkflow(owner, 7, "shared", "manual", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("CreateWorkspaceWorkflow: %v", err)
+	}
+
+	if listed, err := svc.ListWorkflows(viewer); err != nil || len(listed) != 1 {
+		t.Fatalf("viewer lists %v, %v", listed, err)
+	}
+	if _, err := svc.TriggerManually(viewer, wf.ID, nil); !errors.Is(err, workflows.ErrWorkflowReadOnly) {
+		t.Fatalf("viewer trigger: %v, want ErrWorkflowReadOnly", err)
+	}
+	if err := svc.DeleteWorkflow(outsider, wf.ID); !errors.Is(err, workflows.ErrWorkflowNotFound) {
+		t.Fatalf("outsider delete: %v, want ErrWorkflowNotFound", err)
+	}
+	if err := svc.SetEnabled(viewer, wf.ID, false, time.Now()); !errors.Is(err, workflows.ErrWorkflowReadOnly) {
+		t.Fatalf("viewer disable: %v, want ErrWorkflowReadOnly", err)
+	}
+	if err := svc.DeleteWorkflow(owner, wf.ID); err != nil {
+		t.Fatalf("owner delete: %v", err)
+	}
+	if want := []string{"workflow.created", "workflow.deleted"}; fmt.Sprint(auditor.actions) != fmt.Sprint(want) {
+		t.Fatalf("audited %v, want %v", auditor.actions, want)
+	}
+}
+
+func TestMemoryStore_WorkspaceEventsReachMembers(t *testing.T) {
+	store := workflows.NewMemoryStore()
+	store.Events = workflows.NewEventBus()
+	store.Workspaces = staticRoles{1: {7: "owner"}, 2: {7: "viewer"}}
+	svc := workflows.NewService(store, workflows.NewTriggerer(store))
+	owner := workflows.WithUserID(context.Background(), 1)
+
+	viewerEvents, cancelViewer := store.Events.Subscribe(2)
+	defer cancelViewer()
+	outsiderEvents, cancelOutsider := store.Events.Subscribe(3)
+	defer cancelOutsider()
+
+	wf, err := svc.CreateWorkspaceWorkflow(owner, 7, "shared", "manual", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("CreateWorkspaceWorkflow: %v", err)
+	}
+	run, err := svc.TriggerManually(owner, wf.ID, nil)
+	if err != nil {
+		t.Fatalf("TriggerManually: %v", err)
+	}
+
+	select {
+	case ev := <-viewerEvents:
+		if ev.Type != workflows.EventRunCreated || ev.RunID != run.ID {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+	default:
+		t.Fatal("no event published to the viewer")
+	}
+	select {
+	case ev := <-outsiderEvents:
+		t.Fatalf("outsider got %+v", ev)
+	default:
+	}
+}
+
+func TestMemoryStore_AutomatedTriggersOutliveTheCreatorRole(t *testing.T) {
+	store := workflows.NewMemoryStore()
+	roles := staticRoles{1: {7: "owner"}}
+	store.Workspaces = roles
+	svc := workflows.NewService(store, workflows.NewTriggerer(store))
+	creator := workflows.WithUserID(context.Background(), 1)
+	wf, err := svc.CreateWorkspaceWorkflow(creator, 7, "shared", "manual", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("CreateWorkspaceWorkflow: %v", err)
+	}
+	personal, err := svc.CreateWorkflow(creator, "mine", "manual", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("CreateWorkflow: %v", err)
+	}
+
+	roles[1][7] = "viewer"
+	if _, err := svc.TriggerManually(creator, wf.ID, nil); !errors.Is(err, workflows.ErrWorkflowReadOnly) {
+		t.Fatalf("downgraded manual trigger: %v, want ErrWorkflowReadOnly", err)
+	}
+	if run, err := svc.Trigger(creator, wf.ID, nil); err != nil || run == nil {
+		t.Fatalf("downgraded automated trigger: %v, %v", run, err)
+	}
+	delete(roles, 1)
+	if run, err := svc.Trigger(creator, wf.ID, nil); err != nil || run == nil {
+		t.Fatalf("automated trigger after the creator left: %v, %v", run, err)
+	}
+	if _, err := svc.Trigger(workflows.WithUserID(context.Background(), 3), personal.ID, nil); !errors.Is(err, workflows.ErrWorkflowNotFound) {
+		t.Fatalf("trigger of another user's workflow: %v, want ErrWorkflowNotFound", err)
+	}
+}
+
+func TestMemoryStore_FetchReleaseAndRequeue(t *testing.T) {
+	store := workflows.NewMemoryStore()
+	ctx := context.Background()
+
+	run, _ := store.CreateRun(ctx, 1)
+	job, _ := store.CreateJob(ctx, 1, run.ID, json.RawMessage(`{}`))
+	claimed, err := store.FetchNextPendingJob(ctx)
+	if err != nil || claimed.ID != job.ID || claimed.Status != workflows.JobStatusProcessing {
+		t.Fatalf("FetchNextPendingJob = %+v, %v", claimed, err)
+	}
+	if _, err := store.FetchNextPendingJob(ctx); err == nil {
+		t.Fatal("a processing job was claimed twice")
+	}
+
+	if err := store.ReleaseJob(ctx, job.ID, run.ID); err != nil {
+		t.Fatalf("ReleaseJob: %v", err)
+	}
+	if n, _ := store.CountPendingJobs(ctx); n != 1 {
+		t.Fatalf("pending = %d after release, want 1", n)
+	}
+
+	if _, err := store.FetchNextPendingJob(ctx); err != nil {
+		t.Fatalf("FetchNextPendingJob after release: %v", err)
+	}
+	n, err := store.RequeueStaleJobs(ctx, time.Now().Add(time.Second))
+	if err != nil || n != 1 {
+		t.Fatalf("RequeueStaleJobs = %d, %v", n, err)
+	}
+}
+
+func TestMemoryStore_Digests(t *testing.T) {
+	store := workflows.NewMemoryStore()
+	ctx := context.Background()
+
+	for _, p := range []string{`{"n":1}`, `{"n":2}`} {
+		if err := store.BufferDigestItem(ctx, 7, json.RawMessage(p)); err != nil {
+			t.Fatalf("BufferDigestItem: %v", err)
+		}
+	}
+	pending, err := store.ListPendingDigests(ctx)
+	if err != nil || len(pending) != 1 || pending[0].WorkflowID != 7 || pending[0].Count != 2 {
+		t.Fatalf("ListPendingDigests = %+v, %v", pending, err)
+	}
+	items, err := store.ClaimDigestItems(ctx, 7)
+	if err != nil || len(items) != 2 || string(items[0]) != `{"n":1}` {
+		t.Fatalf("ClaimDigestItems = %s, %v", items, err)
+	}
+	if pending, _ := store.ListPendingDigests(ctx); len(pending) != 0 {
+		t.Fatalf("digest not cleared: %+v", pending)
+	}
+}
@@ -0,0 +1,183 @@
+package workflows
+
+import (
+	"area/src/workflows"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// memBroker stands in for Postgres between the event relays of several buses.
+type memBroker struct {
+	mu        sync.Mutex
+	listeners []chan []byte
+	sent      [][]byte
+}
+
+// relay returns a relay of the broker, already subscribed so no notification is missed.
+func (b *memBroker) relay() *memRelay {
+	ch := make(chan []byte, 64)
+	b.mu.Lock()
+	b.listeners = append(b.listeners, ch)
+	b.mu.Unlock()
+	return &memRelay{broker: b, ch: ch}
+}
+
+type memRelay struct {
+	broker *memBroker
+	ch     chan []byte
+}
+
+func (r *memRelay) Notify(_ context.Context, payload []byte) error {
+	r.broker.mu.Lock()
+	defer r.broker.mu.Unlock()
+	r.broker.sent = append(r.broker.sent, payload)
+	for _, ch := range r.broker.listeners {
+		ch <- payload
+	}
+	return nil
+}
+
+func (r *memRelay) Listen(ctx context.Context, deliver func([]byte)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case payload := <-r.ch:
+			deliver(payload)
+		}
+	}
+}
+
+// failingRelay is a relay whose database is down.
+type failingRelay struct{}
+
+func (failingRelay) Notify(context.Context, []byte) error { return errors.New("connection refused") }
+
+func (failingRelay) Listen(ctx context.Context, _ func([]byte)) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func receiveEvent(t *testing.T, events <-chan workflows.Event) workflows.Event {
+	t.Helper()
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return workflows.Event{}
+	}
+}
+
+func TestEventBus_RelayReachesOtherReplicas(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	broker := &memBroker{}
+	publisher, replica := workflows.NewEventBus(), workflows.NewEventBus()
+	publisher.Relay(ctx, broker.relay())
+	replica.Relay(ctx, broker.relay())
+	if !publisher.Active() {
+		t.Fatal("a relayed bus must be active without local subscribers")
+	}
+	local, cancelLocal := publisher.Subscribe(1)
+	defer cancelLocal()
+	remote, cancelRemote := replica.Subscribe(1)
+	defer cancelRemote()
+	other, cancelOther := replica.Subscribe(2)
+	defer cancelOther()
+
+	publisher.Publish(workflows.Event{Type: workflows.EventRunSucceeded, UserID: 1, WorkflowID: 3, RunID: 5})
+
+	for _, events := range []<-chan workflows.Event{local, remote} {
+		ev := receiveEvent(t, events)
+		if ev.Type != workflows.EventRunSucceeded || ev.UserID != 1 || ev.WorkflowID != 3 || ev.RunID != 5 || ev.At.IsZero() {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+	}
+	select {
+	case ev := <-local:
+		t.Fatalf("event delivered twice: %+v", ev)
+	case ev := <-other:
+		t.Fatalf("other user received %+v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestEventBus_RelaySplitsLargeAudiences(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	broker := &memBroker{}
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+	store.Events = workflows.NewEventBus()
+	store.Events.Relay(ctx, broker.relay())
+	last, cancelLast := store.Events.Subscribe(3000)
+	defer cancelLast()
+
+	members := sqlmock.NewRows([]string{"user_id"})
+	for id := 1; id <= 3000; id++ {
+		members.AddRow(id)
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(`^UPDATE "workflows" SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`^SELECT user_id, workspace_id FROM "workflows" WHERE id = \$1`).
+		WithArgs(uint(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "workspace_id"}).AddRow(1, 8))
+	mock.ExpectQuery(`^SELECT "user_id" FROM "workspace_members" WHERE workspace_id = \$1`).
+		WithArgs(uint(8)).
+		WillReturnRows(members)
+
+	if err := store.SetEnabledForUser(context.Background(), 2, 1, false, time.Now()); err != nil {
+		t.Fatalf("SetEnabledForUser: %v", err)
+	}
+	if ev := receiveEvent(t, last); ev.Type != workflows.EventWorkflowDisabled || ev.UserID != 3000 {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	if len(broker.sent) < 2 {
+		t.Fatalf("%d notifications, want the audience split over several", len(broker.sent))
+	}
+	for _, payload := range broker.sent {
+		if len(payload) >= 8000 {
+			t.Fatalf("notification of %d bytes, Postgres takes under 8000", len(payload))
+		}
+	}
+}
+
+func TestEventBus_RelayFailureDeliversLocally(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := workflows.NewEventBus()
+	bus.Relay(ctx, failingRelay{})
+	events, cancelEvents := bus.Subscribe(1)
+	defer cancelEvents()
+
+	bus.Publish(workflows.Event{Type: workflows.EventRunStarted, UserID: 1, RunID: 5})
+
+	if ev := receiveEvent(t, events); ev.RunID != 5 {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+func TestPostgresRelay_Notify(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectExec(`^SELECT pg_notify\(\$1, \$2\)`).
+		WithArgs("workflow_events", `{"event":{}}`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := workflows.NewPostgresRelay(db).Notify(context.Background(), []byte(`{"event":{}}`)); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package workflows
+
+import (
+	"area/src/workflows"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestTimeoutFromJSON(t *testing.T) {
+	if got := workflows.TimeoutFromJSON([]byte(`{"timeout_seconds":45}`)); got != 45*time.Second {
+		t.Fatalf("TimeoutFromJSON = %v, want 45s", got)
+	}
+	for _, raw := range []string{``, `{}`, `{"timeout_seconds":0}`, `not json`} {
+		if got := workflows.TimeoutFromJSON([]byte(raw)); got != 0 {
+			t.Fatalf("TimeoutFromJSON(%q) = %v, want 0", raw, got)
+		}
+	}
+}
+
+func TestServiceCreateWorkflow_InvalidTimeout(t *testing.T) {
+	gormDB, _, cleanup := setupMockDB(t)
+	defer cleanup()
+	ctx := workflows.WithUserID(context.Background(), 99)
+	store := workflows.NewStore(gormDB)
+	svc := workflows.NewService(store, workflows.NewTriggerer(store))
+
+	for _, cfg := range []string{`{"timeout_seconds":-5}`, `{"timeout_seconds":1.5}`} {
+		_, err := svc.CreateWorkflow(ctx, "wf", "manual", "https://example.com", []byte(cfg))
+		if err == nil || !strings.Contains(err.Error(), "timeout_seconds") {
+			t.Fatalf("expected timeout_seconds error for %s, got %v", cfg, err)
+		}
+	}
+}
+
+func TestMarkJobFailed_RecordsResponse(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`^UPDATE "jobs" SET "ended_at"=\$1,"error"=\$2,"latency_ms"=\$3,"response_body"=\$4,"response_status"=\$5,"status"=\$6,"updated_at"=\$7 WHERE \(id = \$8 AND status <> \$9\) AND "jobs"\."deleted_at" IS NULL$`).
+		WithArgs(sqlmock.AnyArg(), "status 502", int64(1500), "bad gateway", 502, workflows.JobStatusFailed, sqlmock.AnyArg(), uint(51), workflows.JobStatusCancelled).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	resp := &workflows.JobResponse{StatusCode: 502, Body: "bad gateway", Latency: 1500 * time.Millisecond}
+	if err := store.MarkJobFailed(context.Background(), 51, "status 502", resp); err != nil {
+		t.Fatalf("MarkJobFailed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestListRunsForWorkflow(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`^SELECT \* FROM "workflow_runs" WHERE workflow_id = \$1 AND "workflow_runs"\."deleted_at" IS NULL ORDER BY id DESC LIMIT \$2$`).
+		WithArgs(uint(4), 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_id", "status"}).
+			AddRow(8, 4, "failed").
+			AddRow(7, 4, "succeeded"))
+	mock.ExpectQuery(`^SELECT \* FROM "jobs" WHERE run_id IN \(\$1,\$2\) AND "jobs"\."deleted_at" IS NULL ORDER BY id ASC$`).
+		WithArgs(uint(8), uint(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_id", "run_id", "status", "response_status", "response_body", "latency_ms"}).
+			AddRow(20, 4, 7, "succeeded", 200, "ok", 120).
+			AddRow(21, 4, 8, "failed", 502, "bad gateway", 900))
+
+	runs, err := store.ListRunsForWorkflow(context.Background(), 4, 10)
+	if err != nil {
+		t.Fatalf("ListRunsForWorkflow: %v", err)
+	}
+	if len(runs) != 2 || runs[0].ID != 8 || len(runs[0].Jobs) != 1 {
+		t.Fatalf("unexpected runs: %+v", runs)
+	}
+	job := runs[0].Jobs[0]
+	if job.ResponseStatus != 502 || job.ResponseBody != "bad gateway" || job.LatencyMs != 900 {
+		t.Fatalf("unexpected job response: %+v", job)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
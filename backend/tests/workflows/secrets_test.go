@@ -0,0 +1,104 @@
+package workflows
+
+import (
+	"area/src/workflows"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mapSecrets resolves the secrets of user 42 from a map.
+type mapSecrets map[string]string
+
+func (m mapSecrets) ResolveSecret(ctx context.Context, userID int64, name string) (string, error) {
+	value, ok := m[name]
+	if !ok || userID != 42 {
+		return "", errors.New("secret not found")
+	}
+	return value, nil
+}
+
+func TestExecutor_ResolvesSecretsOfTheWorkflowConfig(t *testing.T) {
+	store := workflows.NewMemoryStore()
+	svc := workflows.NewService(store, workflows.NewTriggerer(store))
+	ctx := workflows.WithUserID(context.Background(), 42)
+
+	cfg := json.RawMessage(`{"bot_token":"{{secrets.slack_bot}}","payload_template":{"auth":"Bearer {{ secrets.slack_bot }}"}}`)
+	wf, err := store.CreateWorkflow(ctx, 42, 0, "bot", "manual", "http://example.com/hook", cfg)
+	if err != nil {
+		t.Fatalf("CreateWorkflow: %v", err)
+	}
+	if _, err := svc.Trigger(ctx, wf.ID, map[string]any{
+		"bot_token": "{{secrets.slack_bot}}",
+		"auth":      "Bearer {{ secrets.slack_bot }}",
+		// A payload cannot read a secret into a field the config does not put it in.
+		"text": "{{secrets.slack_bot}}",
+	}); err != nil {
+		t.Fatalf("Trigger: %v", err)
+	}
+
+	sender := &recordingSender{sent: make(chan []byte, 1)}
+	exec := workflows.NewExecutor(store, sender, 10*time.Millisecond)
+	exec.Secrets = mapSecrets{"slack_bot": "xoxb-1"}
+	loopCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go exec.RunLoop(loopCtx)
+
+	select {
+	case payload := <-sender.sent:
+		var got map[string]string
+		if err := json.Unmarshal(payload, &got); err != nil {
+			t.Fatalf("decode payload %s: %v", payload, err)
+		}
+		if got["bot_token"] != "xoxb-1" || got["auth"] != "Bearer xoxb-1" || got["text"] != "{{secrets.slack_bot}}" {
+			t.Fatalf("unexpected payload %s", payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("job was not sent")
+	}
+}
+
+func TestExecutor_MissingSecretFailsTheRun(t *testing.T) {
+	store := workflows.NewMemoryStore()
+	svc := workflows.NewService(store, workflows.NewTriggerer(store))
+	ctx := workflows.WithUserID(context.Background(), 42)
+
+	wf, err := store.CreateWorkflow(ctx, 42, 0, "bot", "manual", "http://example.com/hook", json.RawMessage(`{"api_key":"{{secrets.gone}}"}`))
+	if err != nil {
+		t.Fatalf("CreateWorkflow: %v", err)
+	}
+	if _, err := svc.Trigger(ctx, wf.ID, map[string]any{"api_key": "{{secrets.gone}}"}); err != nil {
+		t.Fatalf("Trigger: %v", err)
+	}
+
+	sender := &recordingSender{sent: make(chan []byte, 1)}
+	exec := workflows.NewExecutor(store, sender, 10*time.Millisecond)
+	exec.Secrets = mapSecrets{}
+	loopCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go exec.RunLoop(loopCtx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		runs, err := svc.ListRuns(ctx, wf.ID, 10)
+		if err != nil {
+			t.Fatalf("ListRuns: %v", err)
+		}
+		if len(runs) == 1 && runs[0].Status == workflows.RunStatusFailed {
+			if !strings.Contains(runs[0].Error, `secret "gone"`) {
+				t.Fatalf("run error = %q", runs[0].Error)
+			}
+			select {
+			case payload := <-sender.sent:
+				t.Fatalf("payload %s was sent", payload)
+			default:
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("run did not fail")
+}
@@ -1,8 +1,11 @@
 package workflows
 
 import (
+	"area/src/areas"
 	"area/src/workflows"
 	"context"
+	"errors"
+	"reflect"
 	"time"
 
 	"testing"
@@ -51,8 +54,8 @@ func TestServiceTrigger_Success(t *testing.T) {
 		true, nil,
 	)
 
-	mock.ExpectQuery(`^SELECT \* FROM "workflows" WHERE \(id = \$1 AND user_id = \$2\) AND "workflows"\."deleted_at" IS NULL ORDER BY "workflows"\."id" LIMIT \$[0-9]+$`).
-		WithArgs(int64(2), int64(99), sqlmock.AnyArg()).
+	mock.ExpectQuery(`^SELECT \* FROM "workflows" WHERE "workflows"\."id" = \$1 AND "workflows"\."deleted_at" IS NULL ORDER BY "workflows"\."id" LIMIT \$[0-9]+$`).
+		WithArgs(uint(2), sqlmock.AnyArg()).
 		WillReturnRows(rowsWF)
 
 	// Triggerer.EnqueueRun -> Store.CreateRun (gorm Create => begin/insert/commit)
@@ -64,8 +67,8 @@ func TestServiceTrigger_Success(t *testing.T) {
 
 	// Triggerer.EnqueueRun -> Store.CreateJob (gorm Create => begin/insert/commit)
 	mock.ExpectBegin()
-	mock.ExpectQuery(`^INSERT INTO "jobs" \("created_at","updated_at","deleted_at","workflow_id","run_id","payload","status","error","started_at","ended_at"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9,\$10\) RETURNING "id"$`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, uint(2), uint(7), []byte(`{"k":"v"}`), workflows.JobStatusPending, "", nil, nil).
+	mock.ExpectQuery(`^INSERT INTO "jobs" \("created_at","updated_at","deleted_at","workflow_id","run_id","payload","status","error","started_at","ended_at","response_status","response_body","latency_ms","request_id"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9,\$10,\$11,\$12,\$13,\$14\) RETURNING "id"$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, uint(2), uint(7), []byte(`{"k":"v"}`), workflows.JobStatusPending, "", nil, nil, 0, "", int64(0), sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
 	mock.ExpectCommit()
 
@@ -87,8 +90,8 @@ func TestServiceTrigger_NotFound(t *testing.T) {
 
 	store := workflows.NewStore(gormDB)
 
-	mock.ExpectQuery(`^SELECT \* FROM "workflows" WHERE \(id = \$1 AND user_id = \$2\) AND "workflows"\."deleted_at" IS NULL ORDER BY "workflows"\."id" LIMIT \$[0-9]+$`).
-		WithArgs(int64(99), int64(99), sqlmock.AnyArg()).
+	mock.ExpectQuery(`^SELECT \* FROM "workflows" WHERE "workflows"\."id" = \$1 AND "workflows"\."deleted_at" IS NULL ORDER BY "workflows"\."id" LIMIT \$[0-9]+$`).
+		WithArgs(uint(99), sqlmock.AnyArg()).
 		WillReturnError(gorm.ErrRecordNotFound)
 
 	svc := workflows.NewService(store, workflows.NewTriggerer(store))
@@ -109,8 +112,8 @@ func TestServiceCreateWorkflow_ManualDefaultsConfig(t *testing.T) {
 
 	svc := workflows.NewService(store, workflows.NewTriggerer(store))
 	mock.ExpectBegin()
-	mock.ExpectQuery(`^INSERT INTO "workflows" \("created_at","updated_at","deleted_at","user_id","name","trigger_type","trigger_config","action_url","enabled","next_run_at"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9,\$10\) RETURNING "id"$`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, uint(99), "name", "manual", []byte(`{}`), "https://example.com", true, nil).
+	mock.ExpectQuery(`^INSERT INTO "workflows" \("created_at","updated_at","deleted_at","user_id","name","trigger_type","trigger_config","action_url","enabled","next_run_at","workspace_id"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9,\$10,\$11\) RETURNING "id"$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, uint(99), "name", "manual", []byte(`{}`), "https://example.com", true, nil, nil).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	mock.ExpectCommit()
 
@@ -154,6 +157,31 @@ func TestServiceCreateWorkflow_InvalidWeatherReport(t *testing.T) {
 	}
 }
 
+func TestServiceCreateWorkflow_FieldErrors(t *testing.T) {
+	svc := workflows.NewService(&workflows.Store{}, nil)
+	_, err := svc.CreateWorkflow(context.Background(), "name", "weather_temp", "https://example.com", []byte(`{"city":"Paris","threshold":"20","direction":"sideways"}`))
+	var invalid *areas.ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected *areas.ValidationError, got %v", err)
+	}
+	want := []areas.FieldError{
+		{Field: "threshold", Message: "must be a number"},
+		{Field: "direction", Message: "must be one of above, below"},
+	}
+	if !reflect.DeepEqual(invalid.Fields, want) {
+		t.Fatalf("fields = %+v, want %+v", invalid.Fields, want)
+	}
+}
+
+func TestServiceCreateWorkflow_ConfigMustDecode(t *testing.T) {
+	svc := workflows.NewService(&workflows.Store{}, nil)
+	_, err := svc.CreateWorkflow(context.Background(), "name", "interval", "https://example.com", []byte(`{"interval_minutes":1.5}`))
+	var invalid *areas.ValidationError
+	if !errors.As(err, &invalid) || len(invalid.Fields) != 1 || invalid.Fields[0].Field != "interval_minutes" {
+		t.Fatalf("expected an interval_minutes field error, got %v", err)
+	}
+}
+
 func TestServiceCreateWorkflow_Unsupported(t *testing.T) {
 	svc := workflows.NewService(&workflows.Store{}, nil)
 	if _, err := svc.CreateWorkflow(context.Background(), "name", "unknown", "url", []byte(`{}`)); err == nil {
@@ -176,8 +204,8 @@ func TestServiceListWorkflows(t *testing.T) {
 		AddRow(1, time.Now(), time.Now(), nil, 99, "wf1", "manual", []byte(`{}`), "url1", true, nil).
 		AddRow(2, time.Now(), time.Now(), nil, 99, "wf2", "manual", []byte(`{}`), "url2", true, nil)
 
-	mock.ExpectQuery(`^SELECT \* FROM "workflows" WHERE user_id = \$1 AND "workflows"\."deleted_at" IS NULL ORDER BY created_at DESC$`).
-		WithArgs(int64(99)).
+	mock.ExpectQuery(`^SELECT \* FROM "workflows" WHERE \(\(workflows\.workspace_id IS NULL AND workflows\.user_id = \$1\) OR workflows\.workspace_id IN \(SELECT "workspace_id" FROM "workspace_members" WHERE user_id = \$2\)\) AND "workflows"\."deleted_at" IS NULL ORDER BY created_at DESC$`).
+		WithArgs(int64(99), int64(99)).
 		WillReturnRows(rows)
 
 	svc := workflows.NewService(store, workflows.NewTriggerer(store))
@@ -200,8 +228,8 @@ func TestServiceGetWorkflow_ErrorMapping(t *testing.T) {
 
 	store := workflows.NewStore(gormDB)
 
-	mock.ExpectQuery(`^SELECT \* FROM "workflows" WHERE \(id = \$1 AND user_id = \$2\) AND "workflows"\."deleted_at" IS NULL ORDER BY "workflows"\."id" LIMIT \$[0-9]+$`).
-		WithArgs(int64(1), int64(99), sqlmock.AnyArg()).
+	mock.ExpectQuery(`^SELECT \* FROM "workflows" WHERE id = \$1 AND \(\(workflows\.workspace_id IS NULL AND workflows\.user_id = \$2\) OR workflows\.workspace_id IN \(SELECT "workspace_id" FROM "workspace_members" WHERE user_id = \$3\)\) AND "workflows"\."deleted_at" IS NULL ORDER BY "workflows"\."id" LIMIT \$[0-9]+$`).
+		WithArgs(int64(1), int64(99), int64(99), sqlmock.AnyArg()).
 		WillReturnError(gorm.ErrRecordNotFound)
 
 	svc := workflows.NewService(store, workflows.NewTriggerer(store))
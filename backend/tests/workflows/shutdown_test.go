@@ -0,0 +1,183 @@
+package workflows
+
+import (
+	"area/src/workflows"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestStoreReleaseJob(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`^UPDATE "jobs" SET .*"started_at"=\$.*"status"=\$.* WHERE \(id = \$\d+ AND status = \$\d+\)`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`^UPDATE "workflow_runs" SET .* WHERE \(id = \$\d+ AND status = \$\d+\)`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := store.ReleaseJob(context.Background(), 3, 7); err != nil {
+		t.Fatalf("ReleaseJob error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestStoreRequeueStaleJobs(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	before := time.Now().Add(-time.Hour)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`^SELECT "run_id" FROM "jobs" WHERE \(status = \$1 AND started_at < \$2\)`).
+		WithArgs(workflows.JobStatusProcessing, before).
+		WillReturnRows(sqlmock.NewRows([]string{"run_id"}).AddRow(7).AddRow(8))
+	mock.ExpectExec(`^UPDATE "jobs" SET .* WHERE \(status = \$\d+ AND started_at < \$\d+\)`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`^UPDATE "workflow_runs" SET .* WHERE \(id IN \(\$\d+,\$\d+\) AND status = \$\d+\)`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	n, err := store.RequeueStaleJobs(context.Background(), before)
+	if err != nil {
+		t.Fatalf("RequeueStaleJobs error: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("requeued = %d, want 2", n)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPollerState_LoadMergesSavedFields(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`^SELECT \* FROM "poller_states" WHERE name = \$1 LIMIT \$2`).
+		WithArgs("reddit", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "state", "updated_at"}).
+			AddRow("reddit", []byte(`{"last_seen":{"4":"abc"},"unknown":true}`), time.Now()))
+
+	lastSeen := map[int64]string{5: "def"}
+	lastCheck := make(map[int64]time.Time)
+	state := store.PollerState("reddit", map[string]any{
+		"last_seen":  &lastSeen,
+		"last_check": &lastCheck,
+	})
+	if err := state.Load(context.Background()); err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if lastSeen[4] != "abc" || lastSeen[5] != "def" {
+		t.Fatalf("unexpected last_seen: %v", lastSeen)
+	}
+	if len(lastCheck) != 0 {
+		t.Fatalf("unexpected last_check: %v", lastCheck)
+	}
+}
+
+func TestPollerState_SaveUpserts(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`^INSERT INTO "poller_states" \("name","state","updated_at"\) VALUES \(\$1,\$2,\$3\) ON CONFLICT \("name"\) DO UPDATE SET "state"="excluded"."state","updated_at"="excluded"."updated_at"`).
+		WithArgs("reddit", []byte(`{"last_seen":{"4":"abc"}}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	lastSeen := map[int64]string{4: "abc"}
+	state := store.PollerState("reddit", map[string]any{"last_seen": &lastSeen})
+	if err := state.Save(context.Background()); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+// blockingSender holds every send until its context ends.
+type blockingSender struct {
+	started chan struct{}
+}
+
+func (s *blockingSender) Send(ctx context.Context, url string, payload []byte) (*workflows.SendResult, error) {
+	close(s.started)
+	<-ctx.Done()
+	return nil, context.Cause(ctx)
+}
+
+func TestExecutorShutdown_ReleasesJobAfterGrace(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`^SELECT "run_id" FROM "jobs"`).WillReturnRows(sqlmock.NewRows([]string{"run_id"}))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`^SELECT \* FROM "jobs" WHERE status = \$1 .*FOR UPDATE SKIP LOCKED`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_id", "run_id", "status"}).AddRow(3, 4, 7, "pending"))
+	mock.ExpectExec(`^UPDATE "jobs" SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`^SELECT \* FROM "workflows" WHERE "workflows"."id" = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "trigger_type", "action_url"}).
+			AddRow(4, 99, "manual", "http://example.com/hook"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`^UPDATE "workflow_runs" SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`^UPDATE "jobs" SET .* WHERE \(id = \$\d+ AND status = \$\d+\)`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`^UPDATE "workflow_runs" SET .* WHERE \(id = \$\d+ AND status = \$\d+\)`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	sender := &blockingSender{started: make(chan struct{})}
+	exec := workflows.NewExecutor(store, sender, time.Hour)
+	ctx, stop := context.WithCancel(context.Background())
+	go exec.RunLoop(ctx)
+
+	select {
+	case <-sender.started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("job was not sent")
+	}
+	stop()
+
+	grace, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := exec.Shutdown(grace); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestExecutorShutdown_Idle(t *testing.T) {
+	exec := workflows.NewExecutor(nil, nil, time.Second)
+	if err := exec.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown error: %v", err)
+	}
+}
+
+func TestEventBusClose_EndsSubscriptions(t *testing.T) {
+	bus := workflows.NewEventBus()
+	events, cancel := bus.Subscribe(1)
+	bus.Close()
+	if _, ok := <-events; ok {
+		t.Fatal("channel should be closed by Close")
+	}
+	cancel()
+	if bus.Active() {
+		t.Fatal("bus should be inactive after Close")
+	}
+	late, _ := bus.Subscribe(1)
+	if _, ok := <-late; ok {
+		t.Fatal("subscribing to a closed bus should return a closed channel")
+	}
+}
@@ -0,0 +1,362 @@
+package workflows
+
+import (
+	"area/src/config"
+	"area/src/database"
+	"area/src/security"
+	"area/src/workflows"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// setupSQLiteStore returns a store backed by a migrated SQLite database in a temp dir, with user 1.
+func setupSQLiteStore(t *testing.T) *workflows.Store {
+	t.Helper()
+	return workflows.NewStore(setupSQLiteDB(t))
+}
+
+// setupSQLiteDB returns the migrated SQLite database of setupSQLiteStore.
+func setupSQLiteDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(database.Dialector(config.Database{
+		Driver:     config.DriverSQLite,
+		SQLitePath: filepath.Join(t.TempDir(), "area.db"),
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	user := database.User{Model: gorm.Model{ID: 1}, Email: "owner@example.com"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestSQLite_FindWorkflowByToken(t *testing.T) {
+	store := setupSQLiteStore(t)
+	ctx := context.Background()
+
+	wf, err := store.CreateWorkflow(ctx, 1, 0, "hook", "webhook", "http://example.com", json.RawMessage(`{"token":"abc"}`))
+	if err != nil {
+		t.Fatalf("CreateWorkflow: %v", err)
+	}
+	if err := store.SetEnabledForUser(ctx, wf.ID, 1, true, time.Now()); err != nil {
+		t.Fatalf("SetEnabledForUser: %v", err)
+	}
+
+	found, err := store.FindWorkflowByToken(ctx, "abc")
+	if err != nil {
+		t.Fatalf("FindWorkflowByToken: %v", err)
+	}
+	if found.ID != wf.ID {
+		t.Fatalf("found workflow %d, want %d", found.ID, wf.ID)
+	}
+	if _, err := store.FindWorkflowByToken(ctx, "nope"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestSQLite_FetchNextPendingJobClaimsEachJobOnce(t *testing.T) {
+	store := setupSQLiteStore(t)
+	ctx := context.Background()
+
+	wf, err := store.CreateWorkflow(ctx, 1, 0, "manual", "manual", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("CreateWorkflow: %v", err)
+	}
+	const jobs = 5
+	for i := 0; i < jobs; i++ {
+		run, err := store.CreateRun(ctx, wf.ID)
+		if err != nil {
+			t.Fatalf("CreateRun: %v", err)
+		}
+		if _, err := store.CreateJob(ctx, wf.ID, run.ID, json.RawMessage(`{}`)); err != nil {
+			t.Fatalf("CreateJob: %v", err)
+		}
+	}
+
+	var (
+		mu      sync.Mutex
+		claimed = make(map[int64]int)
+		wg      sync.WaitGroup
+	)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				job, err := store.FetchNextPendingJob(ctx)
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return
+				}
+				if err != nil {
+					t.Errorf("FetchNextPendingJob: %v", err)
+					return
+				}
+				mu.Lock()
+				claimed[job.ID]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(claimed) != jobs {
+		t.Fatalf("claimed %d jobs, want %d", len(claimed), jobs)
+	}
+	for id, n := range claimed {
+		if n != 1 {
+			t.Fatalf("job %d claimed %d times", id, n)
+		}
+	}
+}
+
+func TestSQLite_ClaimDueIntervalWorkflows(t *testing.T) {
+	store := setupSQLiteStore(t)
+	ctx := context.Background()
+
+	wf, err := store.CreateWorkflow(ctx, 1, 0, "tick", "interval", "http://example.com", json.RawMessage(`{"interval_minutes":5}`))
+	if err != nil {
+		t.Fatalf("CreateWorkflow: %v", err)
+	}
+	now := time.Now()
+	if err := store.SetEnabledForUser(ctx, wf.ID, 1, true, now); err != nil {
+		t.Fatalf("SetEnabledForUser: %v", err)
+	}
+
+	due, err := store.ClaimDueIntervalWorkflows(ctx, now)
+	if err != nil || len(due) != 0 {
+		t.Fatalf("nothing should be due yet, got %v, %v", due, err)
+	}
+	later := now.Add(6 * time.Minute)
+	due, err = store.ClaimDueIntervalWorkflows(ctx, later)
+	if err != nil || len(due) != 1 || due[0].ID != wf.ID {
+		t.Fatalf("expected the workflow to be due, got %v, %v", due, err)
+	}
+	due, err = store.ClaimDueIntervalWorkflows(ctx, later)
+	if err != nil || len(due) != 0 {
+		t.Fatalf("claim should advance next_run_at, got %v, %v", due, err)
+	}
+}
+
+func TestSQLite_LeaseAndPollerState(t *testing.T) {
+	store := setupSQLiteStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	if ok, err := store.AcquireLease(ctx, "poller:reddit", "a", time.Minute, now); err != nil || !ok {
+		t.Fatalf("first acquire: %v, %v", ok, err)
+	}
+	if ok, err := store.AcquireLease(ctx, "poller:reddit", "b", time.Minute, now); err != nil || ok {
+		t.Fatalf("held lease taken over: %v, %v", ok, err)
+	}
+	if ok, err := store.AcquireLease(ctx, "poller:reddit", "b", time.Minute, now.Add(2*time.Minute)); err != nil || !ok {
+		t.Fatalf("expired lease not taken over: %v, %v", ok, err)
+	}
+
+	lastSeen := map[int64]string{4: "abc"}
+	if err := store.PollerState("reddit", map[string]any{"last_seen": &lastSeen}).Save(ctx); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	lastSeen[4] = "def"
+	if err := store.PollerState("reddit", map[string]any{"last_seen": &lastSeen}).Save(ctx); err != nil {
+		t.Fatalf("second Save: %v", err)
+	}
+	loaded := make(map[int64]string)
+	if err := store.PollerState("reddit", map[string]any{"last_seen": &loaded}).Load(ctx); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded[4] != "def" {
+		t.Fatalf("unexpected state: %v", loaded)
+	}
+}
+
+func TestSQLite_WorkspaceRoles(t *testing.T) {
+	db := setupSQLiteDB(t)
+	store := workflows.NewStore(db)
+	ctx := context.Background()
+	for _, row := range []any{
+		&database.User{Model: gorm.Model{ID: 2}, Email: "viewer@example.com"},
+		&database.User{Model: gorm.Model{ID: 3}, Email: "outsider@example.com"},
+		&database.Workspace{ID: 1, Name: "Ops"},
+		&database.WorkspaceMember{WorkspaceID: 1, UserID: 1, Role: database.RoleOwner},
+		&database.WorkspaceMember{WorkspaceID: 1, UserID: 2, Role: database.RoleViewer},
+	} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("create %T: %v", row, err)
+		}
+	}
+
+	wf, err := store.CreateWorkflow(ctx, 1, 1, "shared", "manual", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("CreateWorkflow: %v", err)
+	}
+	if wf.WorkspaceID == nil || *wf.WorkspaceID != 1 {
+		t.Fatalf("workspace_id = %v, want 1", wf.WorkspaceID)
+	}
+	if role, err := store.WorkspaceRole(ctx, 1, 2); err != nil || role != database.RoleViewer {
+		t.Fatalf("WorkspaceRole = %q, %v", role, err)
+	}
+
+	listed, err := store.ListWorkflows(ctx, 2)
+	if err != nil || len(listed) != 1 || listed[0].ID != wf.ID {
+		t.Fatalf("viewer lists %+v, %v", listed, err)
+	}
+	if _, err := store.GetWorkflowForUser(ctx, wf.ID, 3); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("outsider read: %v, want sql.ErrNoRows", err)
+	}
+	if err := store.SetEnabledForUser(ctx, wf.ID, 2, false, time.Now()); !errors.Is(err, workflows.ErrWorkflowReadOnly) {
+		t.Fatalf("viewer disable: %v, want ErrWorkflowReadOnly", err)
+	}
+	if err := store.DeleteWorkflowForUser(ctx, wf.ID, 3); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("outsider delete: %v, want sql.ErrNoRows", err)
+	}
+
+	if err := db.Model(&database.WorkspaceMember{}).Where("user_id = ?", 2).Update("role", database.RoleEditor).Error; err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	if err := store.DeleteWorkflowForUser(ctx, wf.ID, 2); err != nil {
+		t.Fatalf("editor delete: %v", err)
+	}
+}
+
+func TestSQLite_ReencryptSecrets(t *testing.T) {
+	db := setupSQLiteDB(t)
+	store := workflows.NewStore(db)
+	ctx := context.Background()
+	t.Cleanup(func() { security.Configure("", "", "") })
+
+	if err := security.Configure("v1", "0123456789abcdef0123456789abcdef", ""); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	botToken, err := security.EncryptString("xoxb")
+	if err != nil {
+		t.Fatalf("EncryptString: %v", err)
+	}
+	cfg := fmt.Sprintf(`{"bot_token":%q,"nested":{"api_key":"plain"},"channel":"ops"}`, botToken)
+	wf, err := store.CreateWorkflow(ctx, 1, 0, "bot", "manual", "http://example.com", json.RawMessage(cfg))
+	if err != nil {
+		t.Fatalf("CreateWorkflow: %v", err)
+	}
+	if err := security.Configure("v2", "fedcba9876543210fedcba9876543210", "v1:0123456789abcdef0123456789abcdef"); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	if changed, err := store.ReencryptSecrets(ctx); err != nil || changed != 1 {
+		t.Fatalf("ReencryptSecrets = %d, %v; want 1 workflow", changed, err)
+	}
+	if changed, err := store.ReencryptSecrets(ctx); err != nil || changed != 0 {
+		t.Fatalf("second ReencryptSecrets = %d, %v; want 0", changed, err)
+	}
+
+	var model database.Workflow
+	if err := db.First(&model, wf.ID).Error; err != nil {
+		t.Fatalf("load workflow: %v", err)
+	}
+	var stored struct {
+		BotToken string `json:"bot_token"`
+		Nested   struct {
+			APIKey string `json:"api_key"`
+		} `json:"nested"`
+		Channel string `json:"channel"`
+	}
+	if err := json.Unmarshal(model.TriggerConfig, &stored); err != nil {
+		t.Fatalf("decode trigger_config: %v", err)
+	}
+	if security.KeyID(stored.BotToken) != "v2" || stored.Nested.APIKey != "plain" || stored.Channel != "ops" {
+		t.Fatalf("trigger_config after rotation = %s", model.TriggerConfig)
+	}
+
+	// The old key is no longer needed.
+	if err := security.Configure("v2", "fedcba9876543210fedcba9876543210", ""); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	if plain, err := security.DecryptString(stored.BotToken); err != nil || plain != "xoxb" {
+		t.Fatalf("bot_token = %q, %v", plain, err)
+	}
+}
+
+func TestSQLite_ReencryptPayloads(t *testing.T) {
+	db := setupSQLiteDB(t)
+	store := workflows.NewStore(db)
+	ctx := context.Background()
+	t.Cleanup(func() { security.Configure("", "", "") })
+
+	if err := security.Configure("v1", "0123456789abcdef0123456789abcdef", ""); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	botToken, err := security.EncryptString("xoxb")
+	if err != nil {
+		t.Fatalf("EncryptString: %v", err)
+	}
+	payload := json.RawMessage(fmt.Sprintf(`{"bot_token":%q,"text":"hi"}`, botToken))
+	wf, err := store.CreateWorkflow(ctx, 1, 0, "bot", "manual", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("CreateWorkflow: %v", err)
+	}
+	run, err := store.CreateRun(ctx, wf.ID)
+	if err != nil {
+		t.Fatalf("CreateRun: %v", err)
+	}
+	pending, err := store.CreateJob(ctx, wf.ID, run.ID, payload)
+	if err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	done, err := store.CreateJob(ctx, wf.ID, run.ID, payload)
+	if err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	if err := db.Model(&database.Job{}).Where("id = ?", done.ID).Update("status", workflows.JobStatusSucceeded).Error; err != nil {
+		t.Fatalf("finish job: %v", err)
+	}
+	if err := store.BufferDigestItem(ctx, wf.ID, payload); err != nil {
+		t.Fatalf("BufferDigestItem: %v", err)
+	}
+
+	if err := security.Configure("v2", "fedcba9876543210fedcba9876543210", "v1:0123456789abcdef0123456789abcdef"); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	if changed, err := store.ReencryptPayloads(ctx); err != nil || changed != 2 {
+		t.Fatalf("ReencryptPayloads = %d, %v; want the pending job and the digest item", changed, err)
+	}
+
+	keyOf := func(raw json.RawMessage) string {
+		var stored struct {
+			BotToken string `json:"bot_token"`
+		}
+		if err := json.Unmarshal(raw, &stored); err != nil {
+			t.Fatalf("decode payload: %v", err)
+		}
+		return security.KeyID(stored.BotToken)
+	}
+	var jobs []database.Job
+	if err := db.Order("id").Find(&jobs).Error; err != nil {
+		t.Fatalf("load jobs: %v", err)
+	}
+	if keyOf(jobs[0].Payload) != "v2" || jobs[0].ID != uint(pending.ID) || keyOf(jobs[1].Payload) != "v1" {
+		t.Fatalf("job payloads after rotation: %s / %s", jobs[0].Payload, jobs[1].Payload)
+	}
+	var item database.DigestItem
+	if err := db.First(&item).Error; err != nil {
+		t.Fatalf("load digest item: %v", err)
+	}
+	if keyOf(item.Payload) != "v2" {
+		t.Fatalf("digest payload after rotation: %s", item.Payload)
+	}
+}
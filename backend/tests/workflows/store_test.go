@@ -1,8 +1,11 @@
 package workflows
 
 import (
+	"area/src/logging"
 	"area/src/workflows"
 	"context"
+	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
@@ -84,15 +87,15 @@ func TestSetEnabled_EnableIntervalSetsNextRun(t *testing.T) {
 	triggerCfg := []byte(`{"interval_minutes":2}`)
 
 	// Mock GetWorkflow call
-	mock.ExpectQuery(`^SELECT \* FROM "workflows" WHERE \(id = \$1 AND user_id = \$2\) AND "workflows"\."deleted_at" IS NULL ORDER BY "workflows"\."id" LIMIT \$[0-9]+$`).
-		WithArgs(int64(1), int64(99), sqlmock.AnyArg()).
+	mock.ExpectQuery(`^SELECT \* FROM "workflows" WHERE id = \$1 AND \(\(workflows\.workspace_id IS NULL AND workflows\.user_id = \$2\) OR workflows\.workspace_id IN \(SELECT "workspace_id" FROM "workspace_members" WHERE user_id = \$3\)\) AND "workflows"\."deleted_at" IS NULL ORDER BY "workflows"\."id" LIMIT \$[0-9]+$`).
+		WithArgs(int64(1), int64(99), int64(99), sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "trigger_type", "trigger_config", "action_url", "enabled", "next_run_at", "created_at", "user_id"}).
 			AddRow(uint(1), "wf", "interval", triggerCfg, "url", false, nil, now, 99))
 
 	// Mock Updates call
 	mock.ExpectBegin()
-	mock.ExpectExec(`^UPDATE "workflows" SET "enabled"=\$1,"next_run_at"=\$2,"updated_at"=\$3 WHERE \(id = \$4 AND user_id = \$5\) AND "workflows"\."deleted_at" IS NULL$`).
-		WithArgs(true, sqlmock.AnyArg(), sqlmock.AnyArg(), uint(1), int64(99)).
+	mock.ExpectExec(`^UPDATE "workflows" SET "enabled"=\$1,"next_run_at"=\$2,"updated_at"=\$3 WHERE id = \$4 AND \(\(workflows\.workspace_id IS NULL AND workflows\.user_id = \$5\) OR workflows\.workspace_id IN \(SELECT "workspace_id" FROM "workspace_members" WHERE user_id = \$6 AND role IN \(\$7,\$8\)\)\) AND "workflows"\."deleted_at" IS NULL$`).
+		WithArgs(true, sqlmock.AnyArg(), sqlmock.AnyArg(), uint(1), int64(99), int64(99), "owner", "editor").
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
@@ -109,8 +112,8 @@ func TestSetEnabled_Disable(t *testing.T) {
 	defer cleanup()
 
 	mock.ExpectBegin()
-	mock.ExpectExec(`^UPDATE "workflows" SET "enabled"=\$1,"next_run_at"=\$2,"updated_at"=\$3 WHERE \(id = \$4 AND user_id = \$5\) AND "workflows"\."deleted_at" IS NULL$`).
-		WithArgs(false, nil, sqlmock.AnyArg(), uint(2), int64(99)).
+	mock.ExpectExec(`^UPDATE "workflows" SET "enabled"=\$1,"next_run_at"=\$2,"updated_at"=\$3 WHERE id = \$4 AND \(\(workflows\.workspace_id IS NULL AND workflows\.user_id = \$5\) OR workflows\.workspace_id IN \(SELECT "workspace_id" FROM "workspace_members" WHERE user_id = \$6 AND role IN \(\$7,\$8\)\)\) AND "workflows"\."deleted_at" IS NULL$`).
+		WithArgs(false, nil, sqlmock.AnyArg(), uint(2), int64(99), int64(99), "owner", "editor").
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
@@ -127,14 +130,21 @@ func TestSetEnabled_NotFound(t *testing.T) {
 	defer cleanup()
 
 	mock.ExpectBegin()
-	mock.ExpectExec(`^UPDATE "workflows" SET "enabled"=\$1,"next_run_at"=\$2,"updated_at"=\$3 WHERE \(id = \$4 AND user_id = \$5\) AND "workflows"\."deleted_at" IS NULL$`).
-		WithArgs(false, nil, sqlmock.AnyArg(), uint(42), int64(99)).
+	mock.ExpectExec(`^UPDATE "workflows" SET "enabled"=\$1,"next_run_at"=\$2,"updated_at"=\$3 WHERE id = \$4 AND \(\(workflows\.workspace_id IS NULL AND workflows\.user_id = \$5\) OR workflows\.workspace_id IN \(SELECT "workspace_id" FROM "workspace_members" WHERE user_id = \$6 AND role IN \(\$7,\$8\)\)\) AND "workflows"\."deleted_at" IS NULL$`).
+		WithArgs(false, nil, sqlmock.AnyArg(), uint(42), int64(99), int64(99), "owner", "editor").
 		WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectCommit()
+	// Nothing changed: the store looks the workflow up to tell a missing one from a read-only one.
+	mock.ExpectQuery(`^SELECT \* FROM "workflows" WHERE id = \$1 AND `).
+		WithArgs(int64(42), int64(99), int64(99), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
 
 	err := store.SetEnabledForUser(context.Background(), 42, 99, false, time.Now())
-	if err != gorm.ErrRecordNotFound {
-		t.Fatalf("expected gorm.ErrRecordNotFound, got %v", err)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
 	}
 }
 
@@ -164,12 +174,12 @@ func TestCreateWorkflow(t *testing.T) {
 	triggerCfg := []byte(`{"interval_minutes":10}`)
 
 	mock.ExpectBegin()
-	mock.ExpectQuery(`^INSERT INTO "workflows" \("created_at","updated_at","deleted_at","user_id","name","trigger_type","trigger_config","action_url","enabled","next_run_at"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9,\$10\) RETURNING "id"$`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, uint(99), "test-workflow", "interval", triggerCfg, "http://example.com/action", false, nil).
+	mock.ExpectQuery(`^INSERT INTO "workflows" \("created_at","updated_at","deleted_at","user_id","name","trigger_type","trigger_config","action_url","enabled","next_run_at","workspace_id"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9,\$10,\$11\) RETURNING "id"$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, uint(99), "test-workflow", "interval", triggerCfg, "http://example.com/action", false, nil, nil).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uint(1)))
 	mock.ExpectCommit()
 
-	wf, err := store.CreateWorkflow(context.Background(), 99, "test-workflow", "interval", "http://example.com/action", triggerCfg)
+	wf, err := store.CreateWorkflow(context.Background(), 99, 0, "test-workflow", "interval", "http://example.com/action", triggerCfg)
 	if err != nil {
 		t.Fatalf("CreateWorkflow: %v", err)
 	}
@@ -190,8 +200,8 @@ func TestListWorkflows(t *testing.T) {
 	defer cleanup()
 
 	now := time.Now()
-	mock.ExpectQuery(`^SELECT \* FROM "workflows" WHERE user_id = \$1 AND "workflows"\."deleted_at" IS NULL ORDER BY created_at DESC$`).
-		WithArgs(int64(99)).
+	mock.ExpectQuery(`^SELECT \* FROM "workflows" WHERE \(\(workflows\.workspace_id IS NULL AND workflows\.user_id = \$1\) OR workflows\.workspace_id IN \(SELECT "workspace_id" FROM "workspace_members" WHERE user_id = \$2\)\) AND "workflows"\."deleted_at" IS NULL ORDER BY created_at DESC$`).
+		WithArgs(int64(99), int64(99)).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "trigger_type", "trigger_config", "action_url", "enabled", "next_run_at", "created_at", "user_id"}).
 			AddRow(uint(1), "wf1", "manual", []byte(`{}`), "url1", true, nil, now, 99).
 			AddRow(uint(2), "wf2", "interval", []byte(`{"interval_minutes":5}`), "url2", true, nil, now, 99))
@@ -271,8 +281,8 @@ func TestCreateJob(t *testing.T) {
 	payload := []byte(`{"key":"value"}`)
 
 	mock.ExpectBegin()
-	mock.ExpectQuery(`^INSERT INTO "jobs" \("created_at","updated_at","deleted_at","workflow_id","run_id","payload","status","error","started_at","ended_at"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9,\$10\) RETURNING "id"$`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, uint(1), uint(10), payload, workflows.JobStatusPending, "", nil, nil).
+	mock.ExpectQuery(`^INSERT INTO "jobs" \("created_at","updated_at","deleted_at","workflow_id","run_id","payload","status","error","started_at","ended_at","response_status","response_body","latency_ms","request_id"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9,\$10,\$11,\$12,\$13,\$14\) RETURNING "id"$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, uint(1), uint(10), payload, workflows.JobStatusPending, "", nil, nil, 0, "", int64(0), "").
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uint(200)))
 	mock.ExpectCommit()
 
@@ -292,17 +302,40 @@ func TestCreateJob(t *testing.T) {
 	}
 }
 
+func TestCreateJob_StoresRequestID(t *testing.T) {
+	store, mock, cleanup := setupMockStore(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`^INSERT INTO "jobs"`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, uint(1), uint(10), []byte(`{}`), workflows.JobStatusPending, "", nil, nil, 0, "", int64(0), "req-123").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uint(201)))
+	mock.ExpectCommit()
+
+	ctx := logging.WithRequestID(context.Background(), "req-123")
+	job, err := store.CreateJob(ctx, 1, 10, []byte(`{}`))
+	if err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	if job.RequestID != "req-123" {
+		t.Fatalf("expected request id req-123, got %q", job.RequestID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
 func TestMarkJobSuccess(t *testing.T) {
 	store, mock, cleanup := setupMockStore(t)
 	defer cleanup()
 
 	mock.ExpectBegin()
-	mock.ExpectExec(`^UPDATE "jobs" SET "ended_at"=\$1,"error"=\$2,"status"=\$3,"updated_at"=\$4 WHERE id = \$5 AND "jobs"\."deleted_at" IS NULL$`).
-		WithArgs(sqlmock.AnyArg(), "", workflows.JobStatusSucceeded, sqlmock.AnyArg(), uint(50)).
+	mock.ExpectExec(`^UPDATE "jobs" SET "ended_at"=\$1,"error"=\$2,"status"=\$3,"updated_at"=\$4 WHERE \(id = \$5 AND status <> \$6\) AND "jobs"\."deleted_at" IS NULL$`).
+		WithArgs(sqlmock.AnyArg(), "", workflows.JobStatusSucceeded, sqlmock.AnyArg(), uint(50), workflows.JobStatusCancelled).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
-	if err := store.MarkJobSuccess(context.Background(), 50); err != nil {
+	if err := store.MarkJobSuccess(context.Background(), 50, nil); err != nil {
 		t.Fatalf("MarkJobSuccess: %v", err)
 	}
 
@@ -316,12 +349,12 @@ func TestMarkJobFailed(t *testing.T) {
 	defer cleanup()
 
 	mock.ExpectBegin()
-	mock.ExpectExec(`^UPDATE "jobs" SET "ended_at"=\$1,"error"=\$2,"status"=\$3,"updated_at"=\$4 WHERE id = \$5 AND "jobs"\."deleted_at" IS NULL$`).
-		WithArgs(sqlmock.AnyArg(), "test error", workflows.JobStatusFailed, sqlmock.AnyArg(), uint(51)).
+	mock.ExpectExec(`^UPDATE "jobs" SET "ended_at"=\$1,"error"=\$2,"status"=\$3,"updated_at"=\$4 WHERE \(id = \$5 AND status <> \$6\) AND "jobs"\."deleted_at" IS NULL$`).
+		WithArgs(sqlmock.AnyArg(), "test error", workflows.JobStatusFailed, sqlmock.AnyArg(), uint(51), workflows.JobStatusCancelled).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
-	if err := store.MarkJobFailed(context.Background(), 51, "test error"); err != nil {
+	if err := store.MarkJobFailed(context.Background(), 51, "test error", nil); err != nil {
 		t.Fatalf("MarkJobFailed: %v", err)
 	}
 
@@ -25,8 +25,8 @@ func TestTriggererEnqueueRun_Success(t *testing.T) {
 
 	// Mock CreateJob
 	mock.ExpectBegin()
-	mock.ExpectQuery(`^INSERT INTO "jobs" \("created_at","updated_at","deleted_at","workflow_id","run_id","payload","status","error","started_at","ended_at"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9,\$10\) RETURNING "id"$`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, uint(5), uint(10), []byte(`{"foo":"bar"}`), workflows.JobStatusPending, "", nil, nil).
+	mock.ExpectQuery(`^INSERT INTO "jobs" \("created_at","updated_at","deleted_at","workflow_id","run_id","payload","status","error","started_at","ended_at","response_status","response_body","latency_ms","request_id"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9,\$10,\$11,\$12,\$13,\$14\) RETURNING "id"$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, uint(5), uint(10), []byte(`{"foo":"bar"}`), workflows.JobStatusPending, "", nil, nil, 0, "", int64(0), "").
 		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(uint(3), now, now))
 	mock.ExpectCommit()
 
@@ -86,8 +86,8 @@ func TestTriggererEnqueueRun_CreateJobError(t *testing.T) {
 
 	// Mock CreateJob failure
 	mock.ExpectBegin()
-	mock.ExpectQuery(`^INSERT INTO "jobs" \("created_at","updated_at","deleted_at","workflow_id","run_id","payload","status","error","started_at","ended_at"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9,\$10\) RETURNING "id"$`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, uint(1), uint(2), []byte(`{}`), workflows.JobStatusPending, "", nil, nil).
+	mock.ExpectQuery(`^INSERT INTO "jobs" \("created_at","updated_at","deleted_at","workflow_id","run_id","payload","status","error","started_at","ended_at","response_status","response_body","latency_ms","request_id"\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9,\$10,\$11,\$12,\$13,\$14\) RETURNING "id"$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, uint(1), uint(2), []byte(`{}`), workflows.JobStatusPending, "", nil, nil, 0, "", int64(0), "").
 		WillReturnError(errors.New("job insert fail"))
 	mock.ExpectRollback()
 
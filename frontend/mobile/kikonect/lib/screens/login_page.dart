@@ -171,10 +171,16 @@ class _LoginPageState extends State<LoginPage> {
 
       if (res.statusCode == 200) {
         try {
-          final body = jsonDecode(res.body);
+          var body = jsonDecode(res.body);
+          if (body is Map && body['two_factor_required'] == true) {
+            body = await _completeTwoFactor(body['two_factor_token'].toString());
+            if (body == null) return;
+          }
           if (body is Map) {
-            if (body.containsKey('token')) {
-              await _saveToken(body['token'].toString());
+            if (body.containsKey('access_token')) {
+              await _saveToken(body['access_token'].toString());
+              await _storage.write(
+                  key: 'refresh_token', value: body['refresh_token'].toString());
             }
             if (body.containsKey('id')) {
               await _saveUserId(body['id']);
@@ -196,6 +202,42 @@ class _LoginPageState extends State<LoginPage> {
     }
   }
 
+  /// Asks for a code of the authenticator app, or a recovery code, and finishes the login.
+  /// Returns the login response, or null when the user cancels or the code is refused.
+  Future<Map?> _completeTwoFactor(String twoFactorToken) async {
+    final codeController = TextEditingController();
+    final code = await showDialog<String>(
+      context: context,
+      builder: (ctx) => AlertDialog(
+        title: const Text("Two-factor authentication"),
+        content: TextField(
+          controller: codeController,
+          decoration: const InputDecoration(
+              hintText: "Authenticator or recovery code"),
+        ),
+        actions: [
+          TextButton(
+              onPressed: () => Navigator.pop(ctx), child: const Text("Cancel")),
+          TextButton(
+              onPressed: () => Navigator.pop(ctx, codeController.text.trim()),
+              child: const Text("Verify")),
+        ],
+      ),
+    );
+    if (code == null || code.isEmpty) return null;
+
+    final res = await http.post(Uri.parse("${AppConfig.baseUrl}/login/2fa"),
+        headers: {"Content-Type": "application/json"},
+        body: jsonEncode({"two_factor_token": twoFactorToken, "code": code}));
+    if (res.statusCode != 200) {
+      if (mounted) {
+        showErrorDialog(context, "Invalid code, please log in again");
+      }
+      return null;
+    }
+    return jsonDecode(res.body) as Map;
+  }
+
   void _loginOAuth(String provider) async {
     try {
       await _authService.signInWith(provider);
@@ -13,11 +13,9 @@ class ApiService {
 
   Future<Map<String, String>> _getHeaders() async {
     final token = await _storage.read(key: 'jwt_token');
-    final userId = await _storage.read(key: 'user_id');
     return {
       'Content-Type': 'application/json',
       'Authorization': 'Bearer $token',
-      if (userId != null && userId.isNotEmpty) 'X-User-ID': userId,
     };
   }
 
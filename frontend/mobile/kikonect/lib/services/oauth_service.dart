@@ -21,10 +21,10 @@ class OAuthService {
   }) async {
     try {
       final url = Uri.parse("$_backendBaseUrl/oauth/google/exchange");
-      final userId = await _storage.read(key: 'user_id');
+      final accessToken = await _storage.read(key: 'jwt_token');
       final headers = <String, String>{'Content-Type': 'application/json'};
-      if (userId != null && userId.isNotEmpty) {
-        headers['X-User-ID'] = userId;
+      if (accessToken != null && accessToken.isNotEmpty) {
+        headers['Authorization'] = 'Bearer $accessToken';
       }
       final response = await http.post(
         url,
@@ -55,16 +55,16 @@ class OAuthService {
     }
 
     try {
-      final userId = await _storage.read(key: 'user_id');
+      final accessToken = await _storage.read(key: 'jwt_token');
       if (provider == 'google') {
         final query = <String, String>{'redirect_uri': _redirectUri};
-        if (userId != null && userId.isNotEmpty) {
-          query['user_id'] = userId;
-        }
         final url = Uri.parse("$_backendBaseUrl/oauth/google/start")
             .replace(queryParameters: query);
 
-        final response = await http.get(url);
+        final response = await http.get(url, headers: {
+          if (accessToken != null && accessToken.isNotEmpty)
+            'Authorization': 'Bearer $accessToken',
+        });
         if (response.statusCode != 200) {
           throw Exception(
               "Backend error ${response.statusCode}: ${response.body}");
@@ -80,8 +80,8 @@ class OAuthService {
       }
 
       final query = <String, String>{'ui_redirect': _redirectUri};
-      if (userId != null && userId.isNotEmpty) {
-        query['user_id'] = userId;
+      if (accessToken != null && accessToken.isNotEmpty) {
+        query['access_token'] = accessToken;
       }
       final url = Uri.parse("$_backendBaseUrl/oauth/github/mobile/login")
           .replace(queryParameters: query);
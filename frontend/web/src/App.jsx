@@ -15,6 +15,8 @@ import Register from "./components/Register/Register";
 import HomePage from "./components/Homepage/HomePage.jsx";
 import CreateAcc from "./components/CreateAcc/CreateAcc";
 import WelcomePage from "./components/WelcomePage/WelcomePage.jsx";
+import ResetPassword from "./components/ResetPassword/ResetPassword";
+import VerifyEmail from "./components/VerifyEmail/VerifyEmail";
 
 
 function App() {
@@ -26,6 +28,8 @@ function App() {
                 <Route path="/login" element={<Login/>}/>
                 <Route path="/register" element={<Register/>}/>
                 <Route path="/createacc" element={<CreateAcc/>}/>
+                <Route path="/reset-password" element={<ResetPassword/>}/>
+                <Route path="/verify-email" element={<VerifyEmail/>}/>
             </Routes>
         </BrowserRouter>
     );
@@ -49,14 +49,14 @@ export default function CreateAcc() {
 
     /**
      * Redirects user to backend Google OAuth endpoint.
-     * If a user_id exists, it is attached to link accounts.
+     * The session access token, when present, is attached to link accounts.
      */
     const handleGoogleLogin = () => {
-        const id = Number(localStorage.getItem("user_id"));
+        const accessToken = localStorage.getItem("access_token");
         // URL the backend will redirect to after successful auth
         const uiRedirect = encodeURIComponent("http://localhost:8081/home");
         const baseUrl = `${API_BASE}/oauth/google/login?ui_redirect=${uiRedirect}`;
-        const url = id && id > 0 ? `${baseUrl}&user_id=${id}` : baseUrl;
+        const url = accessToken ? `${baseUrl}&access_token=${encodeURIComponent(accessToken)}` : baseUrl;
         // Full page redirect required for OAuth
         window.location.href = url;
     };
@@ -65,10 +65,10 @@ export default function CreateAcc() {
      * Same OAuth flow as Google, but for GitHub.
      */
     const handleGithubLogin = () => {
-        const id = Number(localStorage.getItem("user_id"));
+        const accessToken = localStorage.getItem("access_token");
         const uiRedirect = encodeURIComponent("http://localhost:8081/home");
         const baseUrl = `${API_BASE}/oauth/github/login?ui_redirect=${uiRedirect}`;
-        const url = id && id > 0 ? `${baseUrl}&user_id=${id}` : baseUrl;
+        const url = accessToken ? `${baseUrl}&access_token=${encodeURIComponent(accessToken)}` : baseUrl;
 
         window.location.href = url;
     };
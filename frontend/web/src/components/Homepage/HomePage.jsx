@@ -55,6 +55,11 @@ export default function HomePage() {
 
     // Get user ID from localStorage
     const getUserId = () => Number(localStorage.getItem("user_id") || "");
+
+    // Authorization header carrying the session access token from /login
+    const authHeaders = () => ({
+        Authorization: `Bearer ${localStorage.getItem("access_token") || ""}`,
+    });
     
     // Get definition of the selected reaction
     const selectedReactionDef = useMemo(
@@ -172,7 +177,7 @@ export default function HomePage() {
             return;
         }
         try {
-            const res = await fetch(`${API_BASE}/oauth/status?user_id=${resolvedId}`);
+            const res = await fetch(`${API_BASE}/oauth/status`, {headers: authHeaders()});
             if (!res.ok) {
                 return;
             }
@@ -219,7 +224,7 @@ export default function HomePage() {
                 throw new Error("Missing user id, please login again");
             }
             const res = await fetch(`${API_BASE}/workflows`, {
-                headers: {"X-User-ID": String(userId)},
+                headers: authHeaders(),
             });
             if (!res.ok) throw new Error("failed to load workflows");
             const data = await res.json();
@@ -361,7 +366,7 @@ export default function HomePage() {
                 method: "POST",
                 headers: {
                     "Content-Type": "application/json",
-                    "X-User-ID": String(userId),
+                    ...authHeaders(),
                 },
                 body: JSON.stringify(body),
             });
@@ -406,7 +411,7 @@ export default function HomePage() {
                     method: "POST",
                     headers: {
                         "Content-Type": "application/json",
-                        "X-User-ID": String(userId),
+                        ...authHeaders(),
                     },
                     body: JSON.stringify(payload),
                 }
@@ -437,7 +442,7 @@ export default function HomePage() {
         try {
             const res = await fetch(`${API_BASE}/workflows/${selectedWorkflow.id}`, {
                 method: "DELETE",
-                headers: {"X-User-ID": String(userId)},
+                headers: authHeaders(),
             });
             if (!res.ok) {
                 const text = await res.text();
@@ -467,7 +472,7 @@ export default function HomePage() {
         try {
             const res = await fetch(
                 `${API_BASE}/workflows/${selectedWorkflow.id}/enabled?action=${action}`,
-                {method: "POST", headers: {"X-User-ID": String(userId)}}
+                {method: "POST", headers: authHeaders()}
             );
             if (!res.ok) {
                 const text = await res.text();
@@ -30,16 +30,33 @@ export default function Login() {
     const [password, setPassword] = useState("");
 
     /**
-     * Initialize userId from localStorage if present.
-     * Used to link OAuth accounts when logging in again.
+     * Requests a password reset link for the typed email.
+     * The backend answers the same whether or not the account exists.
      */
-    const [userId, setUserId] = useState(() => {
-        const stored = localStorage.getItem("user_id");
-        return stored ? Number(stored) : null;
-    });
+    const handleForgotPassword = async () => {
+        const emailRegex = /^[\w.-]+@[\w.-]+\.\w+$/;
+        if (!emailRegex.test(email)) {
+            alert("Enter your email address first.");
+            return;
+        }
+
+        try {
+            const res = await fetch(`${API_BASE}/password/forgot`, {
+                method: "POST",
+                headers: { "Content-Type": "application/json" },
+                body: JSON.stringify({ email }),
+            });
+
+            if (!res.ok) {
+                alert("Server error. Please try again later.");
+                return;
+            }
 
-    const handleForgotPassword = () => {
-        alert("Forgot password clicked. Implement password reset flow.");
+            alert("If an account exists for this email, a reset link was sent.");
+        } catch (err) {
+            console.error("Network or fetch error:", err);
+            alert("Network error.");
+        }
     };
 
     /**
@@ -73,17 +90,39 @@ export default function Login() {
                 return;
             }
 
+            if (res.status === 429) {
+                alert("Too many failed attempts. Please try again later.");
+                return;
+            }
+
             if (!res.ok) {
                 alert("Server error. Please try again later.");
                 return;
             }
 
-            const data = await res.json();
+            let data = await res.json();
+
+            // Second step for users with two-factor authentication
+            if (data?.two_factor_required) {
+                const code = window.prompt("Enter the code of your authenticator app, or a recovery code:");
+                if (!code) {
+                    return;
+                }
+                const second = await fetch(`${API_BASE}/login/2fa`, {
+                    method: "POST",
+                    headers: { "Content-Type": "application/json" },
+                    body: JSON.stringify({ two_factor_token: data.two_factor_token, code: code.trim() }),
+                });
+                if (!second.ok) {
+                    alert("Invalid code. Please log in again.");
+                    return;
+                }
+                data = await second.json();
+            }
             console.log("Login success:", data);
 
             // Persist user identity for session continuity
             if (data?.id) {
-                setUserId(data.id);
                 localStorage.setItem("user_id", data.id);
             }
 
@@ -91,6 +130,11 @@ export default function Login() {
                 localStorage.setItem("user_email", data.email);
             }
 
+            if (data?.access_token) {
+                localStorage.setItem("access_token", data.access_token);
+                localStorage.setItem("refresh_token", data.refresh_token);
+            }
+
             navigate("/home");
         } catch (err) {
             console.error("Network or fetch error:", err);
@@ -116,15 +160,14 @@ export default function Login() {
                     onGoogleLogin={() => {
                         /**
                          * Redirects to Google OAuth.
-                         * Attaches user_id when available to link accounts.
+                         * Attaches the session access token, which the account is linked to.
                          */
-                        const id = userId || Number(localStorage.getItem("user_id"));
+                        const accessToken = localStorage.getItem("access_token");
                         const uiRedirect = encodeURIComponent(`${window.location.origin}/home`);
                         const baseUrl = `${API_BASE}/oauth/google/login?ui_redirect=${uiRedirect}`;
-                        const url =
-                            id && id > 0
-                                ? `${baseUrl}&user_id=${id}`
-                                : baseUrl;
+                        const url = accessToken
+                            ? `${baseUrl}&access_token=${encodeURIComponent(accessToken)}`
+                            : baseUrl;
 
                         window.location.href = url;
                     }}
@@ -132,13 +175,12 @@ export default function Login() {
                         /**
                          * Same OAuth flow as Google, but for GitHub.
                          */
-                        const id = userId || Number(localStorage.getItem("user_id"));
+                        const accessToken = localStorage.getItem("access_token");
                         const uiRedirect = encodeURIComponent(`${window.location.origin}/home`);
                         const baseUrl = `${API_BASE}/oauth/github/login?ui_redirect=${uiRedirect}`;
-                        const url =
-                            id && id > 0
-                                ? `${baseUrl}&user_id=${id}`
-                                : baseUrl;
+                        const url = accessToken
+                            ? `${baseUrl}&access_token=${encodeURIComponent(accessToken)}`
+                            : baseUrl;
 
                         window.location.href = url;
                     }}
@@ -48,7 +48,18 @@ export default function Navbar() {
     const googleTokenId = authInfo.googleTokenId;
     const githubTokenId = authInfo.githubTokenId;
     const isLoggedIn = Number.isFinite(userId) && userId > 0;
-    const logout = () => {
+    const logout = async () => {
+        const accessToken = localStorage.getItem("access_token");
+        if (accessToken) {
+            try {
+                await fetch(`${API_BASE}/logout`, {
+                    method: "POST",
+                    headers: {Authorization: `Bearer ${accessToken}`},
+                });
+            } catch (err) {
+                console.error("logout error:", err);
+            }
+        }
         localStorage.clear();
         window.dispatchEvent(new Event("auth-updated"));
         window.location.href = "/";
@@ -56,12 +67,14 @@ export default function Navbar() {
     const oauthRedirect = encodeURIComponent(`${window.location.origin}/home`);
     const connectGoogle = () => {
         const baseUrl = `${API_BASE}/oauth/google/login?ui_redirect=${oauthRedirect}`;
-        const url = userId && userId > 0 ? `${baseUrl}&user_id=${userId}` : baseUrl;
+        const accessToken = localStorage.getItem("access_token");
+        const url = accessToken ? `${baseUrl}&access_token=${encodeURIComponent(accessToken)}` : baseUrl;
         window.location.href = url;
     };
     const connectGithub = () => {
         const baseUrl = `${API_BASE}/oauth/github/login?ui_redirect=${oauthRedirect}`;
-        const url = userId && userId > 0 ? `${baseUrl}&user_id=${userId}` : baseUrl;
+        const accessToken = localStorage.getItem("access_token");
+        const url = accessToken ? `${baseUrl}&access_token=${encodeURIComponent(accessToken)}` : baseUrl;
         window.location.href = url;
     };
 
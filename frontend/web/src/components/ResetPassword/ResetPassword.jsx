@@ -0,0 +1,129 @@
+/**
+ * @file ResetPassword.jsx
+ * @description
+ * Page opened from the password reset email.
+ *
+ * Allows users to:
+ *  -  Choose a new password with the token of the link
+ *  -  Go back to login once it is set
+ */
+
+import React, { useState } from "react";
+import { useNavigate, useSearchParams } from "react-router-dom";
+import logo from "../../../lib/assets/Kikonect_logo_no_text.png";
+import "../Register/register.css";
+
+/**
+ * Resolve backend API base URL.
+ */
+const API_BASE =
+    import.meta.env.VITE_API_URL ||
+    import.meta.env.API_URL ||
+    `${window.location.protocol}//${window.location.hostname}:8080`;
+
+export default function ResetPassword() {
+    const navigate = useNavigate();
+    const [searchParams] = useSearchParams();
+    const token = searchParams.get("token") || "";
+    const [password, setPassword] = useState("");
+    const [confirm, setConfirm] = useState("");
+    const [formError, setFormError] = useState(token ? "" : "This reset link is incomplete.");
+
+    /**
+     * Handles reset submission.
+     * Applies the same password rules as registration.
+     */
+    const handleSubmit = async (e) => {
+        e.preventDefault();
+        setFormError("");
+        const passwordRules = /^(?=.*[0-9])(?=.*[!@#$%^&*()_+\-=[\]{};':"\\|,.<>/?]).{8,}$/;
+        if (!passwordRules.test(password)) {
+            setFormError("Password must be at least 8 characters, include a number and a special character.");
+            return;
+        }
+        if (password !== confirm) {
+            setFormError("Passwords do not match.");
+            return;
+        }
+
+        try {
+            const res = await fetch(`${API_BASE}/password/reset`, {
+                method: "POST",
+                headers: { "Content-Type": "application/json" },
+                body: JSON.stringify({ token, password }),
+            });
+
+            if (res.status === 400) {
+                setFormError("This reset link is invalid or has expired.");
+                return;
+            }
+
+            if (!res.ok) {
+                setFormError("Server error. Please try again later.");
+                return;
+            }
+
+            navigate("/login");
+        } catch (err) {
+            console.error("Network or fetch error:", err);
+            setFormError("Network error. Please check your connection or backend.");
+        }
+    };
+
+    return (
+        <div className="reg-page">
+            <div className="reg-card">
+                <div className="logo-container">
+                    <img src={logo} alt="KiKoNect logo" className="logo-img" />
+                    <h1>KiKoNect</h1>
+                </div>
+                <h2 className="title">Reset your password</h2>
+
+                {formError && (
+                    <div
+                        className="error-popup"
+                        style={{
+                            marginBottom: 30,
+                            color: "#b91818ff",
+                            fontStyle: "italic",
+                            fontWeight: 300,
+                            fontSize: 15,
+                        }}
+                    >
+                        {formError}
+                    </div>
+                )}
+
+                <form onSubmit={handleSubmit} className="reg-form">
+                    <div className="floating-input">
+                        <input
+                            id="reset-password"
+                            type="password"
+                            value={password}
+                            onChange={(e) => setPassword(e.target.value)}
+                            autoComplete="new-password"
+                            placeholder="New password"
+                            required
+                        />
+                        <label htmlFor="reset-password" className="sr-only">New password</label>
+                    </div>
+                    <div className="floating-input">
+                        <input
+                            id="reset-confirm"
+                            type="password"
+                            value={confirm}
+                            onChange={(e) => setConfirm(e.target.value)}
+                            autoComplete="new-password"
+                            placeholder="Confirm password"
+                            required
+                        />
+                        <label htmlFor="reset-confirm" className="sr-only">Confirm password</label>
+                    </div>
+                    <button type="submit" className="reg-btn" disabled={!token}>
+                        Reset password
+                    </button>
+                </form>
+            </div>
+        </div>
+    );
+}
@@ -0,0 +1,68 @@
+/**
+ * @file VerifyEmail.jsx
+ * @description
+ * Page opened from the email verification link.
+ *
+ * Sends the token of the link to the backend on load and shows the outcome.
+ */
+
+import React, { useEffect, useState } from "react";
+import { useNavigate, useSearchParams } from "react-router-dom";
+import logo from "../../../lib/assets/Kikonect_logo_no_text.png";
+import "../Register/register.css";
+
+/**
+ * Resolve backend API base URL.
+ */
+const API_BASE =
+    import.meta.env.VITE_API_URL ||
+    import.meta.env.API_URL ||
+    `${window.location.protocol}//${window.location.hostname}:8080`;
+
+export default function VerifyEmail() {
+    const navigate = useNavigate();
+    const [searchParams] = useSearchParams();
+    const token = searchParams.get("token") || "";
+    const [message, setMessage] = useState("Verifying your email address...");
+
+    useEffect(() => {
+        if (!token) {
+            setMessage("This verification link is incomplete.");
+            return;
+        }
+        fetch(`${API_BASE}/email/verify`, {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ token }),
+        })
+            .then((res) => {
+                if (res.ok) {
+                    setMessage("Your email address is verified.");
+                } else if (res.status === 400) {
+                    setMessage("This verification link is invalid or has expired.");
+                } else {
+                    setMessage("Server error. Please try again later.");
+                }
+            })
+            .catch((err) => {
+                console.error("Network or fetch error:", err);
+                setMessage("Network error. Please check your connection or backend.");
+            });
+    }, [token]);
+
+    return (
+        <div className="reg-page">
+            <div className="reg-card">
+                <div className="logo-container">
+                    <img src={logo} alt="KiKoNect logo" className="logo-img" />
+                    <h1>KiKoNect</h1>
+                </div>
+                <h2 className="title">Email verification</h2>
+                <p>{message}</p>
+                <button type="button" className="reg-btn" onClick={() => navigate("/login")}>
+                    Go to login
+                </button>
+            </div>
+        </div>
+    );
+}